@@ -41,3 +41,23 @@ func (s *Scope) String() string {
 	}
 	return s.Parent.String() + "/" + s.Label
 }
+
+type compositePayloadKey struct{}
+
+// WithCompositePayload returns a copy of ctx that marks the scope it's attached
+// to as a composite transform with the given URN and payload. The transforms in
+// the scope are its default expansion, used by runners that don't replace the
+// URN with their own implementation.
+func WithCompositePayload(ctx context.Context, payload *Payload) context.Context {
+	return context.WithValue(ctx, compositePayloadKey{}, payload)
+}
+
+// CompositePayload returns the payload set on ctx by WithCompositePayload, or
+// nil if there is none.
+func CompositePayload(ctx context.Context) *Payload {
+	if ctx == nil {
+		return nil
+	}
+	payload, _ := ctx.Value(compositePayloadKey{}).(*Payload)
+	return payload
+}
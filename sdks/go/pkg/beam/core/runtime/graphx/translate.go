@@ -308,6 +308,9 @@ func (m *marshaller) addScopeTree(s *ScopeTree) (string, error) {
 	if err := m.updateIfCombineComposite(s, transform); err != nil {
 		return "", errors.Wrapf(err, "failed to add scope tree: %v", s)
 	}
+	if payload := graph.CompositePayload(s.Scope.Scope.Context); payload != nil {
+		transform.Spec = &pipepb.FunctionSpec{Urn: payload.URN, Payload: payload.Data}
+	}
 
 	m.transforms[id] = transform
 	return id, nil
@@ -266,6 +266,49 @@ func TestMarshal_PTransformAnnotations(t *testing.T) {
 	}
 }
 
+func TestMarshal_CompositePayload(t *testing.T) {
+	const urn = "beam:transform:test_composite:v1"
+	data := []byte{42, 42, 42}
+
+	g := graph.New()
+	in := newIntInput(g)
+	s := g.NewScope(g.Root(), "composite")
+	s.Context = graph.WithCompositePayload(context.Background(), &graph.Payload{URN: urn, Data: data})
+	addDoFn(t, g, pickFn, s, []*graph.Node{in}, []*coder.Coder{intCoder(), intCoder()}, nil)
+
+	edges, _, err := g.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(&pipepb.DockerPayload{ContainerImage: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := graphx.Marshal(edges,
+		&graphx.Options{Environment: &pipepb.Environment{Urn: "beam:env:docker:v1", Payload: payload}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var composites int
+	for _, pt := range p.GetComponents().GetTransforms() {
+		if len(pt.GetSubtransforms()) == 0 {
+			if got := pt.GetSpec().GetUrn(); got == urn {
+				t.Errorf("leaf transform %v has composite URN %v", pt.GetUniqueName(), got)
+			}
+			continue
+		}
+		composites++
+		want := &pipepb.FunctionSpec{Urn: urn, Payload: data}
+		if d := cmp.Diff(want, pt.GetSpec(), protocmp.Transform()); d != "" {
+			t.Errorf("composite spec diff (-want, +got):\n%v", d)
+		}
+	}
+	if composites != 1 {
+		t.Errorf("got %d composite transforms, want 1: %v", composites, p)
+	}
+}
+
 // testRT's methods can all be no-ops, we just need it to implement sdf.RTracker.
 type testRT struct {
 }
@@ -0,0 +1,657 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package arrowio contains transforms for reading and writing files in the
+// Apache Arrow IPC formats: the random access file format, also known as
+// Feather V2, and the streaming format.
+package arrowio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx/schema"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(beam.X), error](&readFn{})
+	register.Emitter1[beam.X]()
+
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
+}
+
+// Read reads a set of Arrow IPC files indicated by the glob pattern and
+// returns the rows as a PCollection<t>. Files may be in the file format or
+// the streaming format, which is detected from their first bytes. Columns are
+// matched to the exported fields of t by the name in their beam tag or their
+// field name, ignoring case if there's no exact match. Columns without a field
+// are ignored. Types with map fields must be registered with beam.RegisterType.
+//
+// If t is nil, the type is inferred from the Arrow schema of the first file
+// matching the glob when the pipeline is constructed, and the rows are
+// returned as schema rows: structs with a field for each column, named after
+// it, of the Go type of its Beam schema type. Nullable columns are pointers,
+// nested structs are nested rows, lists are slices and maps are maps.
+// Characters of column names other than ASCII letters, digits and underscores
+// are replaced by underscores. Dates, times, timestamps and durations are
+// represented by the integers Arrow stores, and dictionary-encoded columns by
+// their values. All files must have the same schema.
+//
+// Files are split by record batch, so that the batches of large files can be
+// read in parallel. Files in the file format are read with ranged reads of
+// their footer and batches. Files in the streaming format have no index of
+// their batches, so they are split only dynamically, while being read.
+func Read(s beam.Scope, glob string, t reflect.Type) beam.PCollection {
+	s = s.Scope("arrowio.Read")
+	filesystem.ValidateScheme(glob)
+
+	fn := &readFn{}
+	if t == nil {
+		bs, err := inferSchema(context.Background(), glob)
+		if err != nil {
+			panic(fmt.Sprintf("arrowio.Read: %v", err))
+		}
+		if t, err = schema.ToType(bs); err != nil {
+			panic(fmt.Sprintf("arrowio.Read: %v", err))
+		}
+		// Row types may have maps, which can't be encoded as types, so the
+		// schema is encoded instead.
+		if fn.Schema, err = proto.Marshal(bs); err != nil {
+			panic(fmt.Sprintf("arrowio.Read: %v", err))
+		}
+	} else {
+		if _, err := arrowSchema(t); err != nil {
+			panic(fmt.Sprintf("arrowio.Read: %v", err))
+		}
+		fn.Type = &beam.EncodedType{T: t}
+	}
+
+	matches := fileio.MatchAll(s, beam.Create(s, glob), fileio.MatchEmptyAllow())
+	files := fileio.ReadMatches(s, matches, fileio.ReadUncompressed())
+	return beam.ParDo(s, fn, files, beam.TypeDefinition{Var: beam.XType, T: t})
+}
+
+// inferSchema returns the Beam schema of the rows of the first file matching
+// the glob.
+func inferSchema(ctx context.Context, glob string) (*pipepb.Schema, error) {
+	fs, err := filesystem.New(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %v to infer the schema from", glob)
+	}
+	sort.Strings(files)
+
+	size, err := fs.Size(ctx, files[0])
+	if err != nil {
+		return nil, err
+	}
+	r, err := openReader(ctx, fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: files[0], Size: size}})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	bs, err := toBeamSchema(r.Schema().Fields())
+	if err != nil {
+		return nil, fmt.Errorf("error inferring schema of %v: %v", files[0], err)
+	}
+	return bs, nil
+}
+
+// readFn is an SDF that reads the rows of Arrow IPC files. Its restriction is
+// a range of record batch indices, and it reads the batches in the range. The
+// number of batches of a file in the streaming format isn't known until it's
+// read, so its restriction is growable, ending at math.MaxInt64.
+type readFn struct {
+	// Type is the type of the rows, unless they are inferred from the schema
+	// of the files.
+	Type *beam.EncodedType
+	// Schema is the serialized Beam schema of the rows if they are inferred
+	// from the schema of the files.
+	Schema []byte
+}
+
+// CreateInitialRestriction creates an offset range restriction representing
+// the record batches of the file, which are counted from the footer of files
+// in the file format. Files in the streaming format get a growable
+// restriction, so that they don't need to be read in advance. Files that can't
+// be opened fail the bundle, instead of being read partially.
+func (fn *readFn) CreateInitialRestriction(ctx context.Context, file fileio.ReadableFile) (offsetrange.Restriction, error) {
+	n, err := countBatches(ctx, file)
+	if err != nil {
+		return offsetrange.Restriction{}, fmt.Errorf("error counting the record batches of %v: %v", file.Metadata.Path, err)
+	}
+	return offsetrange.Restriction{
+		Start: 0,
+		End:   n,
+	}, nil
+}
+
+// countBatches returns the number of record batches of a file in the file
+// format, or math.MaxInt64 for a file in the streaming format.
+func countBatches(ctx context.Context, file fileio.ReadableFile) (int64, error) {
+	r, err := openReader(ctx, file)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	if r.file == nil {
+		return math.MaxInt64, nil
+	}
+	return int64(r.file.NumRecords()), nil
+}
+
+// blockSize is the desired size of each block for initial splits.
+var blockSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits each file restriction into blocks of record batches
+// of about a predetermined size in total, assuming that batches are of similar
+// size. Restrictions of streams are left whole, to be split dynamically as
+// they're read.
+func (fn *readFn) SplitRestriction(file fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	n := rest.End - rest.Start
+	if n <= 1 || rest.End == math.MaxInt64 || file.Metadata.Size <= 0 {
+		return []offsetrange.Restriction{rest}
+	}
+	batches := max(1, n*blockSize/file.Metadata.Size)
+	return rest.SizedSplits(batches)
+}
+
+// RestrictionSize returns the size of each restriction as its range. Growable
+// restrictions of streams are sized as a single batch, since the number of
+// batches is unknown.
+func (fn *readFn) RestrictionSize(_ fileio.ReadableFile, rest offsetrange.Restriction) float64 {
+	if rest.End == math.MaxInt64 {
+		return 1
+	}
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping offsetRange.Trackers for
+// each restriction, which are growable for the restrictions of streams.
+func (fn *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	if rest.End < math.MaxInt64 {
+		return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+	}
+	est := &streamEstimator{}
+	rt, err := offsetrange.NewGrowableTracker(rest, est)
+	if err != nil {
+		panic(err)
+	}
+	return sdf.NewLockRTracker(&streamTracker{GrowableTracker: rt, est: est})
+}
+
+// streamTracker is the tracker of a growable restriction of a stream, with the
+// estimator of its number of record batches.
+type streamTracker struct {
+	*offsetrange.GrowableTracker
+	est *streamEstimator
+}
+
+// streamEstimator estimates the number of record batches of a stream from the
+// batches read so far and the share of the file they take up.
+type streamEstimator struct {
+	mu sync.Mutex
+	r  *reader
+	// size is the size of the file in bytes.
+	size int64
+}
+
+// Estimate returns the estimated end of the growable restriction, which is 0
+// until the first batch has been read.
+func (e *streamEstimator) Estimate() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.r == nil {
+		return 0
+	}
+	next, read, schemaSize := e.r.progress()
+	if next == 0 || read <= 0 || e.size <= schemaSize {
+		return 0
+	}
+	return int64(float64(next) * float64(e.size-schemaSize) / float64(read))
+}
+
+// observe makes the estimator estimate from the progress of r, a reader of a
+// file of the given size.
+func (e *streamEstimator) observe(r *reader, size int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.r, e.size = r, size
+}
+
+// ProcessElement reads the record batches of the file within the restriction.
+func (fn *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X)) error {
+	log.Infof(ctx, "Reading Arrow from %v", file.Metadata.Path)
+	r, err := openReader(ctx, file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var t reflect.Type
+	rows := fn.Type == nil
+	if rows {
+		var bs pipepb.Schema
+		if err := proto.Unmarshal(fn.Schema, &bs); err != nil {
+			return err
+		}
+		if t, err = schema.ToType(&bs); err != nil {
+			return err
+		}
+		ft, err := rowType(r.Schema())
+		if err != nil {
+			return fmt.Errorf("error inferring type of %v: %v", file.Metadata.Path, err)
+		}
+		if ft != t {
+			return fmt.Errorf("schema of %v doesn't match the inferred type %v", file.Metadata.Path, t)
+		}
+	} else {
+		t = fn.Type.T
+	}
+
+	if st, ok := rt.Rt.(*streamTracker); ok {
+		st.est.observe(r, file.Metadata.Size)
+	}
+
+	dec := newDecoder(rows)
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+	for i := rest.Start; ; i++ {
+		rec, err := r.Record(int(i))
+		if err == io.EOF {
+			// Finish claiming restriction before returning to avoid errors.
+			// Growable restrictions are done once math.MaxInt64-1 is claimed.
+			end := rt.GetRestriction().(offsetrange.Restriction).End
+			if end == math.MaxInt64 {
+				end--
+			}
+			rt.TryClaim(end)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+		if !rt.TryClaim(i) {
+			rec.Release()
+			return nil
+		}
+		values, err := dec.decodeRecord(t, rec)
+		rec.Release()
+		if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+		for _, v := range values {
+			emit(v.Interface())
+		}
+	}
+}
+
+// magic is the start of files in the Arrow IPC file format.
+const magic = "ARROW1"
+
+// reader reads the record batches of a file in either Arrow IPC format.
+type reader struct {
+	file   *ipc.FileReader
+	stream *ipc.Reader
+	rc     io.Closer
+
+	// next is the index of the next batch of the stream, which is read
+	// concurrently to estimate the number of batches.
+	next atomic.Int64
+	// read counts the bytes of the stream decoded so far.
+	read *countingReader
+	// schemaSize is the size of the schema at the start of the stream.
+	schemaSize int64
+}
+
+// openReader opens a file for reading record batches. Files in the file
+// format are read with ranged reads of their footer and the batches read,
+// if the file system doesn't support seeking.
+func openReader(ctx context.Context, file fileio.ReadableFile) (*reader, error) {
+	rs, err := file.OpenSeekable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(rs)
+	if err != nil {
+		rs.Close()
+		return nil, fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+	}
+	r.rc = rs
+	return r, nil
+}
+
+func newReader(rs io.ReadSeeker) (*reader, error) {
+	br := bufio.NewReader(rs)
+	head, err := br.Peek(len(magic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(head) == magic {
+		fr, err := ipc.NewFileReader(&readerAt{ReadSeeker: rs})
+		if err != nil {
+			return nil, err
+		}
+		return &reader{file: fr}, nil
+	}
+	cr := &countingReader{r: br}
+	sr, err := ipc.NewReader(cr)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{stream: sr, read: cr, schemaSize: cr.n.Load()}, nil
+}
+
+// progress returns the index of the next batch of the stream, the number of
+// bytes of batches decoded so far and the size of the schema before them.
+func (r *reader) progress() (int64, int64, int64) {
+	return r.next.Load(), r.read.n.Load() - r.schemaSize, r.schemaSize
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// readerAt is an ipc.ReadAtSeeker that reads at an offset by seeking.
+type readerAt struct {
+	mu sync.Mutex
+	io.ReadSeeker
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.ReadSeeker, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Schema returns the schema of the record batches.
+func (r *reader) Schema() *arrow.Schema {
+	if r.file != nil {
+		return r.file.Schema()
+	}
+	return r.stream.Schema()
+}
+
+// Record returns the i-th record batch, which the caller must release, or
+// io.EOF if there are no more. The batches of a stream must be read in
+// increasing order.
+func (r *reader) Record(i int) (arrow.Record, error) {
+	if r.file != nil {
+		if i >= r.file.NumRecords() {
+			return nil, io.EOF
+		}
+		return r.file.RecordAt(i)
+	}
+	if int64(i) < r.next.Load() {
+		return nil, fmt.Errorf("record batch %v was already read", i)
+	}
+	for ; r.next.Load() <= int64(i); r.next.Add(1) {
+		if !r.stream.Next() {
+			if err := r.stream.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	}
+	rec := r.stream.Record()
+	rec.Retain()
+	return rec, nil
+}
+
+func (r *reader) Close() error {
+	if r.file != nil {
+		r.file.Close()
+	} else {
+		r.stream.Release()
+	}
+	if r.rc == nil {
+		return nil
+	}
+	return r.rc.Close()
+}
+
+type writeOption struct {
+	Stream    bool
+	BatchSize int
+	Codec     string
+}
+
+// WriteOptionFn is a function that can be passed to Write or NewSink to
+// configure options for writing files.
+type WriteOptionFn func(*writeOption)
+
+// WriteStream specifies that files are written in the Arrow IPC streaming
+// format rather than the file format.
+func WriteStream() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Stream = true
+	}
+}
+
+// WriteBatchSize specifies the number of rows in each record batch, which is
+// 65536 by default.
+func WriteBatchSize(n int) WriteOptionFn {
+	return func(o *writeOption) {
+		o.BatchSize = n
+	}
+}
+
+// WriteZstd specifies that record batches are compressed with Zstandard.
+func WriteZstd() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = "zstd"
+	}
+}
+
+// WriteLZ4 specifies that record batches are compressed with LZ4 frames.
+func WriteLZ4() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = "lz4"
+	}
+}
+
+// Write writes a PCollection<T> of schema rows to an Arrow IPC file, with a
+// column for each field of the Beam schema of T. It returns a
+// PCollection<string> with the filename once the file has been written, which
+// can be used to sequence later steps with wait.On.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename string, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("arrowio.Write")
+
+	return fileio.WriteFiles(s, filename, NewSink(col.Type().Type(), opts...), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
+}
+
+// NewSink returns a fileio.Sink that writes schema rows of type t as Arrow
+// record batches. Go integer types without a Beam atomic type are written as
+// the Arrow integer types of the same size and sign.
+func NewSink(t reflect.Type, opts ...WriteOptionFn) fileio.Sink {
+	bs, err := schema.FromType(t)
+	if err == nil {
+		_, err = toArrowFields(bs)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("arrowio.NewSink: %v", err))
+	}
+	// The type isn't kept, since types with maps can't be encoded.
+	data, err := proto.Marshal(bs)
+	if err != nil {
+		panic(fmt.Sprintf("arrowio.NewSink: %v", err))
+	}
+	option := &writeOption{BatchSize: 1 << 16}
+	for _, opt := range opts {
+		opt(option)
+	}
+	if option.BatchSize <= 0 {
+		panic(fmt.Sprintf("arrowio.NewSink: invalid batch size %v", option.BatchSize))
+	}
+	return &sink{Schema: data, Stream: option.Stream, BatchSize: option.BatchSize, Codec: option.Codec}
+}
+
+type sink struct {
+	// Schema is the serialized Beam schema of the rows.
+	Schema    []byte
+	Stream    bool
+	BatchSize int
+	Codec     string
+
+	buf    *bufio.Writer
+	w      recordWriter
+	b      *array.RecordBuilder
+	t      reflect.Type
+	fields []int
+	n      int
+}
+
+// recordWriter is implemented by the writers of both Arrow IPC formats.
+type recordWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+func (s *sink) Open(_ context.Context, w io.Writer) error {
+	var bs pipepb.Schema
+	if err := proto.Unmarshal(s.Schema, &bs); err != nil {
+		return err
+	}
+	fields, err := toArrowFields(&bs)
+	if err != nil {
+		return err
+	}
+	as := arrow.NewSchema(fields, nil)
+	s.buf = bufio.NewWriterSize(w, 1<<20) // use 1MB buffer
+	opts := []ipc.Option{ipc.WithSchema(as)}
+	switch s.Codec {
+	case "zstd":
+		opts = append(opts, ipc.WithZstd())
+	case "lz4":
+		opts = append(opts, ipc.WithLZ4())
+	}
+	if s.Stream {
+		s.w = ipc.NewWriter(s.buf, opts...)
+	} else {
+		// The file writer only seeks to find its position.
+		s.w, err = ipc.NewFileWriter(&posWriter{w: s.buf}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	s.b = array.NewRecordBuilder(memory.DefaultAllocator, as)
+	s.n = 0
+	return nil
+}
+
+func (s *sink) Write(_ context.Context, elm any) error {
+	v := reflect.ValueOf(elm)
+	if v.Type() != s.t {
+		s.t, s.fields = v.Type(), schemaFields(v.Type())
+	}
+	for j, f := range s.fields {
+		if err := appendValue(s.b.Field(j), v.Field(f)); err != nil {
+			return err
+		}
+	}
+	s.n++
+	if s.n < s.BatchSize {
+		return nil
+	}
+	return s.writeBatch()
+}
+
+func (s *sink) writeBatch() error {
+	rec := s.b.NewRecord()
+	defer rec.Release()
+	s.n = 0
+	return s.w.Write(rec)
+}
+
+func (s *sink) Flush(_ context.Context) error {
+	defer s.b.Release()
+	if s.n > 0 {
+		if err := s.writeBatch(); err != nil {
+			return err
+		}
+	}
+	if err := s.w.Close(); err != nil {
+		return err
+	}
+	return s.buf.Flush()
+}
+
+// posWriter is an io.WriteSeeker that only supports seeking to find the
+// current position.
+type posWriter struct {
+	w   io.Writer
+	pos int64
+}
+
+func (w *posWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.pos += int64(n)
+	return n, err
+}
+
+func (w *posWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, errors.New("arrowio: seeking isn't supported when writing")
+	}
+	return w.pos, nil
+}
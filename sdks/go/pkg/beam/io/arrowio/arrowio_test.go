@@ -0,0 +1,389 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arrowio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/memfs"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	register.Function1x2(formatJSON)
+	beam.RegisterType(reflect.TypeOf((*Measurement)(nil)).Elem())
+}
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+type Person struct {
+	Name string
+	Age  *int64
+	Tags []string
+}
+
+var personSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "age", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+}, nil)
+
+func people(n int) []Person {
+	var list []Person
+	for i := 0; i < n; i++ {
+		p := Person{Name: fmt.Sprintf("person%02d", i), Tags: []string{}}
+		if i%3 != 0 {
+			age := int64(20 + i)
+			p.Age = &age
+		}
+		for j := 0; j < i%3; j++ {
+			p.Tags = append(p.Tags, fmt.Sprintf("t%d", j))
+		}
+		list = append(list, p)
+	}
+	return list
+}
+
+// writeBatches writes an Arrow IPC file with a record batch for each group of
+// people, in the streaming format if stream is true.
+func writeBatches(t *testing.T, stream bool, groups ...[]Person) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "people.arrow")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var w recordWriter
+	if stream {
+		w = ipc.NewWriter(f, ipc.WithSchema(personSchema))
+	} else if w, err = ipc.NewFileWriter(f, ipc.WithSchema(personSchema)); err != nil {
+		t.Fatal(err)
+	}
+	b := array.NewRecordBuilder(memory.DefaultAllocator, personSchema)
+	defer b.Release()
+	for _, group := range groups {
+		for _, p := range group {
+			b.Field(0).(*array.StringBuilder).Append(p.Name)
+			if p.Age == nil {
+				b.Field(1).AppendNull()
+			} else {
+				b.Field(1).(*array.Int64Builder).Append(*p.Age)
+			}
+			lb := b.Field(2).(*array.ListBuilder)
+			lb.Append(true)
+			for _, tag := range p.Tags {
+				lb.ValueBuilder().(*array.StringBuilder).Append(tag)
+			}
+		}
+		rec := b.NewRecord()
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		rec.Release()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func toAny(list []Person) []any {
+	var out []any
+	for _, p := range list {
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestRead(t *testing.T) {
+	list := people(10)
+	for _, stream := range []bool{false, true} {
+		path := writeBatches(t, stream, list[:4], list[4:])
+
+		p, s := beam.NewPipelineWithRoot()
+		passert.Equals(s, Read(s, path, reflect.TypeOf(Person{})), toAny(list)...)
+		ptest.RunAndValidate(t, p)
+	}
+}
+
+func formatJSON(row beam.X) (string, error) {
+	b, err := json.Marshal(row)
+	return string(b), err
+}
+
+func TestRead_rows(t *testing.T) {
+	list := people(3)
+	path := writeBatches(t, false, list)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, path, nil)
+	passert.Equals(s, beam.ParDo(s, formatJSON, rows),
+		`{"Name":"person00","Age":null,"Tags":[]}`,
+		`{"Name":"person01","Age":21,"Tags":["t0"]}`,
+		`{"Name":"person02","Age":22,"Tags":["t0","t1"]}`,
+	)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRowType(t *testing.T) {
+	s := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "event time", Type: arrow.FixedWidthTypes.Timestamp_us, Nullable: true},
+		{Name: "1st", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}},
+		{Name: "loc", Type: arrow.StructOf(
+			arrow.Field{Name: "lat", Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: "lng", Type: arrow.PrimitiveTypes.Float32},
+		)},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32)},
+	}, nil)
+	got, err := rowType(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := reflect.TypeOf(struct {
+		Id         uint32 `beam:"id"`
+		Event_time *int64 `beam:"event_time"`
+		X1st       string
+		Loc        struct {
+			Lat float64 `beam:"lat"`
+			Lng float32 `beam:"lng"`
+		} `beam:"loc"`
+		Attrs map[string]int32 `beam:"attrs"`
+	}{})
+	if got != want {
+		t.Errorf("rowType() = %v, want %v", got, want)
+	}
+}
+
+// collect collects the output of readFn.
+type collect struct {
+	records []Person
+}
+
+func (c *collect) emit(x beam.X) {
+	c.records = append(c.records, x.(Person))
+}
+
+// readSplits reads the file with fn, processing each restriction in order.
+func readSplits(t *testing.T, fn *readFn, file fileio.ReadableFile, splits []offsetrange.Restriction) []Person {
+	t.Helper()
+	var c collect
+	for _, r := range splits {
+		rt := fn.CreateTracker(r)
+		if err := fn.ProcessElement(context.Background(), rt, file, c.emit); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", r, err)
+		}
+		if !rt.IsDone() {
+			t.Errorf("ProcessElement(%v) didn't finish restriction", r)
+		}
+	}
+	sort.Slice(c.records, func(i, j int) bool { return c.records[i].Name < c.records[j].Name })
+	return c.records
+}
+
+func TestReadFn_split(t *testing.T) {
+	list := people(20)
+	path := writeBatches(t, false, list[:5], list[5:10], list[10:15], list[15:])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Split into blocks of two batches.
+	defer func(size int64) { blockSize = size }(blockSize)
+	blockSize = int64(len(data)) / 2
+
+	// Files on file systems that can't seek are read with ranged reads.
+	memfs.Write("memfs://people.arrow", data)
+	for _, path := range []string{path, "memfs://people.arrow"} {
+		file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(len(data))}}
+		fn := &readFn{Type: &beam.EncodedType{T: reflect.TypeOf(Person{})}}
+		rest, err := fn.CreateInitialRestriction(context.Background(), file)
+		if want := (offsetrange.Restriction{Start: 0, End: 4}); err != nil || rest != want {
+			t.Errorf("CreateInitialRestriction(%v) = %v, %v, want %v, nil", path, rest, err, want)
+		}
+		splits := fn.SplitRestriction(file, rest)
+		if got, want := len(splits), 2; got != want {
+			t.Errorf("SplitRestriction(%v) returned %v splits, want %v", path, got, want)
+		}
+		if got := readSplits(t, fn, file, splits); !reflect.DeepEqual(got, list) {
+			t.Errorf("%v: got %v, want %v", path, got, list)
+		}
+	}
+}
+
+func TestReadFn_CreateInitialRestriction_error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.arrow")
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: 100}}
+	fn := &readFn{Type: &beam.EncodedType{T: reflect.TypeOf(Person{})}}
+
+	// Files whose batches can't be counted aren't read as a single batch.
+	if rest, err := fn.CreateInitialRestriction(context.Background(), file); err == nil {
+		t.Errorf("CreateInitialRestriction(%v) = %v, nil, want error", path, rest)
+	}
+}
+
+func TestReadFn_splitStream(t *testing.T) {
+	list := people(20)
+	path := writeBatches(t, true, list[:5], list[5:10], list[10:15], list[15:])
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: info.Size()}}
+	fn := &readFn{Type: &beam.EncodedType{T: reflect.TypeOf(Person{})}}
+
+	// Streams aren't read to count their batches, and are only split
+	// dynamically.
+	rest, err := fn.CreateInitialRestriction(context.Background(), file)
+	if want := (offsetrange.Restriction{Start: 0, End: math.MaxInt64}); err != nil || rest != want {
+		t.Errorf("CreateInitialRestriction(%v) = %v, %v, want %v, nil", path, rest, err, want)
+	}
+	if splits := fn.SplitRestriction(file, rest); len(splits) != 1 {
+		t.Errorf("SplitRestriction(%v) = %v, want a single restriction", path, splits)
+	}
+	if got := readSplits(t, fn, file, []offsetrange.Restriction{rest}); !reflect.DeepEqual(got, list) {
+		t.Errorf("%v: got %v, want %v", path, got, list)
+	}
+
+	rt := fn.CreateTracker(rest)
+	var c collect
+	emit := func(x beam.X) {
+		// Split after reading the first batch, when the estimator has
+		// something to go by.
+		if len(c.records) == 0 {
+			if _, _, err := rt.TrySplit(0.5); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c.emit(x)
+	}
+	if err := fn.ProcessElement(context.Background(), rt, file, emit); err != nil {
+		t.Fatalf("ProcessElement(%v) failed: %v", rest, err)
+	}
+	primary := rt.GetRestriction().(offsetrange.Restriction)
+	if want := (offsetrange.Restriction{Start: 0, End: 2}); primary != want {
+		t.Errorf("TrySplit(0.5) after the first of 4 batches left primary %v, want %v", primary, want)
+	}
+	residual := offsetrange.Restriction{Start: primary.End, End: math.MaxInt64}
+	got := append(c.records, readSplits(t, fn, file, []offsetrange.Restriction{residual})...)
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("%v split dynamically: got %v, want %v", path, got, list)
+	}
+}
+
+type Measurement struct {
+	Sensor string  `beam:"sensor"`
+	Value  float64 `beam:"value"`
+	Count  uint16  `beam:"count"`
+	Note   *string `beam:"note"`
+	Labels map[string]int32
+	Loc    Location
+}
+
+type Location struct {
+	Lat, Lng float32
+}
+
+func TestWrite(t *testing.T) {
+	note := "calibrated"
+	list := []Measurement{
+		{Sensor: "a", Value: 1.5, Count: 3, Note: &note, Labels: map[string]int32{"x": 1, "y": 2}, Loc: Location{1, 2}},
+		{Sensor: "b", Value: 2.5, Count: 4, Labels: map[string]int32{}},
+		{Sensor: "c", Value: 3.5, Count: 5, Labels: map[string]int32{"z": 3}, Loc: Location{3, 4}},
+	}
+	var want []any
+	for _, m := range list {
+		want = append(want, m)
+	}
+
+	tests := []struct {
+		name string
+		opts []WriteOptionFn
+	}{
+		{"file", nil},
+		{"stream", []WriteOptionFn{WriteStream()}},
+		{"zstd", []WriteOptionFn{WriteZstd()}},
+		{"lz4", []WriteOptionFn{WriteStream(), WriteLZ4()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "measurements.arrow")
+
+			p, s := beam.NewPipelineWithRoot()
+			Write(s, path, beam.Create(s, want...), append(test.opts, WriteBatchSize(2))...)
+			ptest.RunAndValidate(t, p)
+
+			r, err := openReader(context.Background(), fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			var n int
+			for ; ; n++ {
+				rec, err := r.Record(n)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				rec.Release()
+			}
+			if n != 2 {
+				t.Errorf("Write() wrote %v record batches, want 2", n)
+			}
+
+			p, s = beam.NewPipelineWithRoot()
+			passert.Equals(s, Read(s, path, reflect.TypeOf(Measurement{})), want...)
+			// Rows with maps are read without registering their type.
+			passert.Count(s, Read(s, path, nil), "rows", len(list))
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestNewSink_invalidType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewSink() didn't panic for a type without a schema")
+		}
+	}()
+	NewSink(reflect.TypeOf(0))
+}
@@ -0,0 +1,375 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arrowio
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+)
+
+// schemaFields returns the indices of the fields of a struct type that are
+// part of its Beam schema, in order.
+func schemaFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("beam") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// fieldNames returns the name of each schema field of a struct type: the
+// name in its beam tag, or else the field name.
+func fieldNames(t reflect.Type) []string {
+	var names []string
+	for _, i := range schemaFields(t) {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("beam"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// decoder sets Go values from Arrow arrays.
+type decoder struct {
+	// byPosition is true if the fields of structs match the fields of Arrow
+	// structs by position rather than by name, as for types inferred from
+	// the schema.
+	byPosition bool
+	// fields caches the indices of the fields of struct types matching the
+	// fields of Arrow struct types.
+	fields map[[2]any][]int
+}
+
+func newDecoder(byPosition bool) *decoder {
+	return &decoder{byPosition: byPosition, fields: make(map[[2]any][]int)}
+}
+
+// structFields returns the index of the field of t matching each Arrow field,
+// or -1 for Arrow fields without a matching field.
+func (d *decoder) structFields(t reflect.Type, at *arrow.StructType) []int {
+	key := [2]any{t, at.Fingerprint()}
+	if fields, ok := d.fields[key]; ok {
+		return fields
+	}
+	indices := schemaFields(t)
+	names := fieldNames(t)
+	fields := make([]int, at.NumFields())
+	for j, af := range at.Fields() {
+		fields[j] = -1
+		if d.byPosition {
+			if j < len(indices) {
+				fields[j] = indices[j]
+			}
+			continue
+		}
+		for k, name := range names {
+			if name == af.Name {
+				fields[j] = indices[k]
+				break
+			}
+		}
+		if fields[j] >= 0 {
+			continue
+		}
+		for k, name := range names {
+			if strings.EqualFold(name, af.Name) {
+				fields[j] = indices[k]
+				break
+			}
+		}
+	}
+	d.fields[key] = fields
+	return fields
+}
+
+// decodeRecord returns the rows of a record batch as values of type t.
+func (d *decoder) decodeRecord(t reflect.Type, rec arrow.Record) ([]reflect.Value, error) {
+	st := arrow.StructOf(rec.Schema().Fields()...)
+	fields := d.structFields(t, st)
+	rows := make([]reflect.Value, rec.NumRows())
+	for i := range rows {
+		rows[i] = reflect.New(t).Elem()
+	}
+	for j, col := range rec.Columns() {
+		if fields[j] < 0 {
+			continue
+		}
+		for i, row := range rows {
+			if err := d.setValue(row.Field(fields[j]), col, i); err != nil {
+				return nil, fmt.Errorf("column %v: %v", st.Field(j).Name, err)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// setValue sets v to the i-th value of arr. Nulls are nil pointers or zero
+// values.
+func (d *decoder) setValue(v reflect.Value, arr arrow.Array, i int) error {
+	if arr.IsNull(i) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := d.setValue(p.Elem(), arr, i); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Boolean:
+		if v.Kind() != reflect.Bool {
+			return mismatch(v, arr)
+		}
+		v.SetBool(a.Value(i))
+	case *array.Int8:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Int16:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Int32:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Int64:
+		return setInt(v, arr, a.Value(i))
+	case *array.Date32:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Date64:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Time32:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Time64:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Timestamp:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Duration:
+		return setInt(v, arr, int64(a.Value(i)))
+	case *array.Uint8:
+		return setUint(v, arr, uint64(a.Value(i)))
+	case *array.Uint16:
+		return setUint(v, arr, uint64(a.Value(i)))
+	case *array.Uint32:
+		return setUint(v, arr, uint64(a.Value(i)))
+	case *array.Uint64:
+		return setUint(v, arr, a.Value(i))
+	case *array.Float16:
+		return setFloat(v, arr, float64(a.Value(i).Float32()))
+	case *array.Float32:
+		return setFloat(v, arr, float64(a.Value(i)))
+	case *array.Float64:
+		return setFloat(v, arr, a.Value(i))
+	case *array.String:
+		return setString(v, arr, a.Value(i))
+	case *array.LargeString:
+		return setString(v, arr, a.Value(i))
+	case *array.Binary:
+		return setBytes(v, arr, a.Value(i))
+	case *array.LargeBinary:
+		return setBytes(v, arr, a.Value(i))
+	case *array.FixedSizeBinary:
+		return setBytes(v, arr, a.Value(i))
+	case *array.Dictionary:
+		return d.setValue(v, a.Dictionary(), a.GetValueIndex(i))
+	case *array.Map:
+		if v.Kind() != reflect.Map {
+			return mismatch(v, arr)
+		}
+		start, end := a.ValueOffsets(i)
+		m := reflect.MakeMapWithSize(v.Type(), int(end-start))
+		for k := int(start); k < int(end); k++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.setValue(key, a.Keys(), k); err != nil {
+				return err
+			}
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := d.setValue(item, a.Items(), k); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, item)
+		}
+		v.Set(m)
+	case array.ListLike:
+		if v.Kind() != reflect.Slice {
+			return mismatch(v, arr)
+		}
+		start, end := a.ValueOffsets(i)
+		s := reflect.MakeSlice(v.Type(), int(end-start), int(end-start))
+		for k := 0; k < s.Len(); k++ {
+			if err := d.setValue(s.Index(k), a.ListValues(), int(start)+k); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case *array.Struct:
+		if v.Kind() != reflect.Struct {
+			return mismatch(v, arr)
+		}
+		fields := d.structFields(v.Type(), a.DataType().(*arrow.StructType))
+		for j := 0; j < a.NumField(); j++ {
+			if fields[j] < 0 {
+				continue
+			}
+			if err := d.setValue(v.Field(fields[j]), a.Field(j), i); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported arrow type %v", arr.DataType())
+	}
+	return nil
+}
+
+func mismatch(v reflect.Value, arr arrow.Array) error {
+	return fmt.Errorf("can't set %v from arrow type %v", v.Type(), arr.DataType())
+}
+
+func setInt(v reflect.Value, arr arrow.Array, n int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return mismatch(v, arr)
+	}
+	return nil
+}
+
+func setUint(v reflect.Value, arr arrow.Array, n uint64) error {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return mismatch(v, arr)
+	}
+	return nil
+}
+
+func setFloat(v reflect.Value, arr arrow.Array, f float64) error {
+	if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return mismatch(v, arr)
+	}
+	v.SetFloat(f)
+	return nil
+}
+
+func setString(v reflect.Value, arr arrow.Array, s string) error {
+	if v.Kind() != reflect.String {
+		return mismatch(v, arr)
+	}
+	v.SetString(s)
+	return nil
+}
+
+func setBytes(v reflect.Value, arr arrow.Array, b []byte) error {
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte(nil), b...))
+	case v.Kind() == reflect.String:
+		v.SetString(string(b))
+	default:
+		return mismatch(v, arr)
+	}
+	return nil
+}
+
+// appendValue appends a Go value to a builder of the Arrow type of its Beam
+// schema type.
+func appendValue(b array.Builder, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			b.AppendNull()
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		b.Append(v.Bool())
+	case *array.Int8Builder:
+		b.Append(int8(v.Int()))
+	case *array.Int16Builder:
+		b.Append(int16(v.Int()))
+	case *array.Int32Builder:
+		b.Append(int32(v.Int()))
+	case *array.Int64Builder:
+		b.Append(v.Int())
+	case *array.Uint8Builder:
+		b.Append(uint8(v.Uint()))
+	case *array.Uint16Builder:
+		b.Append(uint16(v.Uint()))
+	case *array.Uint32Builder:
+		b.Append(uint32(v.Uint()))
+	case *array.Uint64Builder:
+		b.Append(v.Uint())
+	case *array.Float32Builder:
+		b.Append(float32(v.Float()))
+	case *array.Float64Builder:
+		b.Append(v.Float())
+	case *array.StringBuilder:
+		b.Append(v.String())
+	case *array.BinaryBuilder:
+		b.Append(v.Bytes())
+	case *array.MapBuilder:
+		b.Append(true)
+		keys := v.MapKeys()
+		// Sort the keys, so that the output is deterministic.
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			if err := appendValue(b.KeyBuilder(), k); err != nil {
+				return err
+			}
+			if err := appendValue(b.ItemBuilder(), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case *array.ListBuilder:
+		b.Append(true)
+		for k := 0; k < v.Len(); k++ {
+			if err := appendValue(b.ValueBuilder(), v.Index(k)); err != nil {
+				return err
+			}
+		}
+	case *array.StructBuilder:
+		b.Append(true)
+		for j, f := range schemaFields(v.Type()) {
+			if err := appendValue(b.FieldBuilder(j), v.Field(f)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported arrow builder %T", b)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arrowio
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx/schema"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+)
+
+// This file maps Arrow schemas to and from Beam schemas, which
+// graphx/schema relates to Go types.
+
+// rowType returns the Go type of the rows of an Arrow schema.
+func rowType(s *arrow.Schema) (reflect.Type, error) {
+	bs, err := toBeamSchema(s.Fields())
+	if err != nil {
+		return nil, err
+	}
+	return schema.ToType(bs)
+}
+
+// toBeamSchema returns the Beam schema of a row with the given Arrow fields.
+// Field names that aren't Go identifiers are changed to be.
+func toBeamSchema(fields []arrow.Field) (*pipepb.Schema, error) {
+	s := &pipepb.Schema{}
+	used := make(map[string]bool)
+	for _, f := range fields {
+		ft, err := toFieldType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", f.Name, err)
+		}
+		ft.Nullable = f.Nullable
+		name := fieldName(f.Name)
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		s.Fields = append(s.Fields, &pipepb.Field{Name: name, Type: ft})
+	}
+	return s, nil
+}
+
+// fieldName returns the name with characters other than ASCII letters, digits
+// and underscores replaced by underscores, starting with a letter, so that
+// graphx/schema can turn it into an exported Go identifier.
+func fieldName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isLetter(c) && !('0' <= c && c <= '9') && c != '_' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || !isLetter(b[0]) {
+		return "X" + string(b)
+	}
+	return string(b)
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// logicalTypes are the Arrow types represented by the logical types that
+// graphx/schema uses for Go integer types without an atomic type.
+var logicalTypes = map[arrow.Type]struct {
+	urn            string
+	representation pipepb.AtomicType
+}{
+	arrow.INT8:   {"int8", pipepb.AtomicType_INT64},
+	arrow.UINT16: {"uint16", pipepb.AtomicType_INT16},
+	arrow.UINT32: {"uint32", pipepb.AtomicType_INT32},
+	arrow.UINT64: {"uint64", pipepb.AtomicType_INT64},
+}
+
+var atomicTypes = map[arrow.Type]pipepb.AtomicType{
+	arrow.BOOL:              pipepb.AtomicType_BOOLEAN,
+	arrow.UINT8:             pipepb.AtomicType_BYTE,
+	arrow.INT16:             pipepb.AtomicType_INT16,
+	arrow.INT32:             pipepb.AtomicType_INT32,
+	arrow.INT64:             pipepb.AtomicType_INT64,
+	arrow.FLOAT16:           pipepb.AtomicType_FLOAT,
+	arrow.FLOAT32:           pipepb.AtomicType_FLOAT,
+	arrow.FLOAT64:           pipepb.AtomicType_DOUBLE,
+	arrow.STRING:            pipepb.AtomicType_STRING,
+	arrow.LARGE_STRING:      pipepb.AtomicType_STRING,
+	arrow.BINARY:            pipepb.AtomicType_BYTES,
+	arrow.LARGE_BINARY:      pipepb.AtomicType_BYTES,
+	arrow.FIXED_SIZE_BINARY: pipepb.AtomicType_BYTES,
+	// Temporal types are represented by their stored integers.
+	arrow.DATE32:    pipepb.AtomicType_INT32,
+	arrow.TIME32:    pipepb.AtomicType_INT32,
+	arrow.DATE64:    pipepb.AtomicType_INT64,
+	arrow.TIME64:    pipepb.AtomicType_INT64,
+	arrow.TIMESTAMP: pipepb.AtomicType_INT64,
+	arrow.DURATION:  pipepb.AtomicType_INT64,
+}
+
+// toFieldType returns the Beam type of an Arrow type. The elements of lists
+// and the values of maps aren't nullable, so null elements are zero values.
+func toFieldType(t arrow.DataType) (*pipepb.FieldType, error) {
+	if at, ok := atomicTypes[t.ID()]; ok {
+		return atomicFieldType(at), nil
+	}
+	if lt, ok := logicalTypes[t.ID()]; ok {
+		return &pipepb.FieldType{
+			TypeInfo: &pipepb.FieldType_LogicalType{
+				LogicalType: &pipepb.LogicalType{
+					Urn:            lt.urn,
+					Representation: atomicFieldType(lt.representation),
+				},
+			},
+		}, nil
+	}
+
+	switch t := t.(type) {
+	case *arrow.DictionaryType:
+		return toFieldType(t.ValueType)
+	case *arrow.MapType:
+		kt, err := toFieldType(t.KeyType())
+		if err != nil {
+			return nil, err
+		}
+		vt, err := toFieldType(t.ItemType())
+		if err != nil {
+			return nil, err
+		}
+		return &pipepb.FieldType{
+			TypeInfo: &pipepb.FieldType_MapType{
+				MapType: &pipepb.MapType{KeyType: kt, ValueType: vt},
+			},
+		}, nil
+	case arrow.ListLikeType:
+		et, err := toFieldType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &pipepb.FieldType{
+			TypeInfo: &pipepb.FieldType_ArrayType{
+				ArrayType: &pipepb.ArrayType{ElementType: et},
+			},
+		}, nil
+	case *arrow.StructType:
+		s, err := toBeamSchema(t.Fields())
+		if err != nil {
+			return nil, err
+		}
+		return &pipepb.FieldType{
+			TypeInfo: &pipepb.FieldType_RowType{
+				RowType: &pipepb.RowType{Schema: s},
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported arrow type %v", t)
+	}
+}
+
+func atomicFieldType(t pipepb.AtomicType) *pipepb.FieldType {
+	return &pipepb.FieldType{
+		TypeInfo: &pipepb.FieldType_AtomicType{AtomicType: t},
+	}
+}
+
+// arrowSchema returns the Arrow schema of a Go struct type.
+func arrowSchema(t reflect.Type) (*arrow.Schema, error) {
+	bs, err := schema.FromType(t)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := toArrowFields(bs)
+	if err != nil {
+		return nil, err
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+func toArrowFields(s *pipepb.Schema) ([]arrow.Field, error) {
+	var fields []arrow.Field
+	for _, f := range s.GetFields() {
+		t, err := toArrowType(f.GetType())
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", f.GetName(), err)
+		}
+		fields = append(fields, arrow.Field{Name: f.GetName(), Type: t, Nullable: f.GetType().GetNullable()})
+	}
+	return fields, nil
+}
+
+var arrowAtomicTypes = map[pipepb.AtomicType]arrow.DataType{
+	pipepb.AtomicType_BYTE:    arrow.PrimitiveTypes.Uint8,
+	pipepb.AtomicType_INT16:   arrow.PrimitiveTypes.Int16,
+	pipepb.AtomicType_INT32:   arrow.PrimitiveTypes.Int32,
+	pipepb.AtomicType_INT64:   arrow.PrimitiveTypes.Int64,
+	pipepb.AtomicType_FLOAT:   arrow.PrimitiveTypes.Float32,
+	pipepb.AtomicType_DOUBLE:  arrow.PrimitiveTypes.Float64,
+	pipepb.AtomicType_STRING:  arrow.BinaryTypes.String,
+	pipepb.AtomicType_BOOLEAN: arrow.FixedWidthTypes.Boolean,
+	pipepb.AtomicType_BYTES:   arrow.BinaryTypes.Binary,
+}
+
+var arrowLogicalTypes = map[string]arrow.DataType{
+	"int":    arrow.PrimitiveTypes.Int64,
+	"int8":   arrow.PrimitiveTypes.Int8,
+	"uint":   arrow.PrimitiveTypes.Uint64,
+	"uint16": arrow.PrimitiveTypes.Uint16,
+	"uint32": arrow.PrimitiveTypes.Uint32,
+	"uint64": arrow.PrimitiveTypes.Uint64,
+}
+
+// toArrowType returns the Arrow type of a Beam type.
+func toArrowType(t *pipepb.FieldType) (arrow.DataType, error) {
+	switch ti := t.GetTypeInfo().(type) {
+	case *pipepb.FieldType_AtomicType:
+		if at, ok := arrowAtomicTypes[ti.AtomicType]; ok {
+			return at, nil
+		}
+		return nil, fmt.Errorf("unsupported atomic type %v", ti.AtomicType)
+	case *pipepb.FieldType_LogicalType:
+		if at, ok := arrowLogicalTypes[ti.LogicalType.GetUrn()]; ok {
+			return at, nil
+		}
+		return toArrowType(ti.LogicalType.GetRepresentation())
+	case *pipepb.FieldType_ArrayType:
+		et, err := toArrowType(ti.ArrayType.GetElementType())
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOf(et), nil
+	case *pipepb.FieldType_MapType:
+		kt, err := toArrowType(ti.MapType.GetKeyType())
+		if err != nil {
+			return nil, err
+		}
+		vt, err := toArrowType(ti.MapType.GetValueType())
+		if err != nil {
+			return nil, err
+		}
+		return arrow.MapOf(kt, vt), nil
+	case *pipepb.FieldType_RowType:
+		fields, err := toArrowFields(ti.RowType.GetSchema())
+		if err != nil {
+			return nil, err
+		}
+		return arrow.StructOf(fields...), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %v", strings.TrimPrefix(fmt.Sprintf("%T", ti), "*pipeline_v1.FieldType_"))
+	}
+}
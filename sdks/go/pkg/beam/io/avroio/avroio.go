@@ -19,159 +19,483 @@ package avroio
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
+	"sort"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/linkedin/goavro/v2"
 )
 
 func init() {
-	register.DoFn3x1[context.Context, fileio.ReadableFile, func(beam.X), error]((*avroReadFn)(nil))
-	register.DoFn3x1[context.Context, int, func(*string) bool, error]((*writeAvroFn)(nil))
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(beam.X), error]((*avroReadFn)(nil))
 	register.Emitter1[beam.X]()
-	register.Iter1[string]()
+
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
+}
+
+type readOption struct {
+	Schema string
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for
+// reading avro files.
+type ReadOptionFn func(*readOption)
+
+// ReadSchema specifies the reader schema. Records are converted from the schema of
+// each file to the reader schema following the Avro schema resolution rules: fields
+// are matched by name or alias, fields missing from a file take their default value,
+// numeric types are promoted, and unions are resolved to their matching branch.
+func ReadSchema(schema string) ReadOptionFn {
+	return func(o *readOption) {
+		o.Schema = schema
+	}
 }
 
+var recordType = reflect.TypeOf(Record(nil))
+
 // Read reads a set of files and returns lines as a PCollection<elem>
 // based on the internal avro schema of the file.
 // A type - reflect.TypeOf( YourType{} ) -  with
 // JSON tags can be defined or if you wish to return the raw JSON string,
 // use - reflect.TypeOf("") -
-func Read(s beam.Scope, glob string, t reflect.Type) beam.PCollection {
+// Files that are compressed as a whole, such as "data.avro.gz", are
+// decompressed based on their extension or first bytes.
+//
+// With reflect.TypeOf(Record{}), records are returned as generic maps from field
+// names to values.
+//
+// If t is nil, records are returned as schema rows: structs with a field for each
+// record field, tagged with its name, using the reader schema or, if there's none,
+// the schema of the first file matching the glob when the pipeline is constructed.
+// Unions must be of null and another type, which are represented by pointers, and
+// logical types are represented by their underlying types.
+//
+// Files are split at their sync markers, so that the blocks of large files can be
+// read in parallel, unless they are compressed as a whole.
+func Read(s beam.Scope, glob string, t reflect.Type, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("avroio.Read")
 	filesystem.ValidateScheme(glob)
-	return read(s, t, beam.Create(s, glob))
+
+	option := &readOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	fn := &avroReadFn{Schema: option.Schema}
+	if option.Schema != "" {
+		if _, err := parseSchema(option.Schema); err != nil {
+			panic(fmt.Sprintf("avroio.Read: %v", err))
+		}
+	}
+	if t == nil {
+		var err error
+		if t, fn.Schema, err = inferRowType(context.Background(), glob, option.Schema); err != nil {
+			panic(fmt.Sprintf("avroio.Read: %v", err))
+		}
+		fn.Rows = true
+	}
+	fn.Type = beam.EncodedType{T: t}
+	return read(s, fn, beam.Create(s, glob))
 }
 
-func read(s beam.Scope, t reflect.Type, col beam.PCollection) beam.PCollection {
+func read(s beam.Scope, fn *avroReadFn, col beam.PCollection) beam.PCollection {
 	matches := fileio.MatchAll(s, col, fileio.MatchEmptyAllow())
 	files := fileio.ReadMatches(s, matches, fileio.ReadUncompressed())
 	return beam.ParDo(s,
-		&avroReadFn{Type: beam.EncodedType{T: t}},
+		fn,
 		files,
-		beam.TypeDefinition{Var: beam.XType, T: t},
+		beam.TypeDefinition{Var: beam.XType, T: fn.Type.T},
 	)
 }
 
+// inferRowType returns the row type and the reader schema, without logical types,
+// for the given reader schema or else the schema of the first file matching the glob.
+func inferRowType(ctx context.Context, glob, spec string) (reflect.Type, string, error) {
+	if spec == "" {
+		var err error
+		if spec, err = firstSchema(ctx, glob); err != nil {
+			return nil, "", err
+		}
+	}
+	spec, err := stripLogicalTypes(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	sch, err := parseSchema(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	t, err := rowType(sch)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, spec, nil
+}
+
+// firstSchema returns the schema of the first file matching the glob.
+func firstSchema(ctx context.Context, glob string) (string, error) {
+	fs, err := filesystem.New(ctx, glob)
+	if err != nil {
+		return "", err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files match %v to infer the schema from", glob)
+	}
+	sort.Strings(files)
+
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: files[0]}}
+	fd, err := file.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	h, err := newBlockReader(fd).readHeader()
+	if err != nil {
+		return "", fmt.Errorf("error reading %v: %v", files[0], err)
+	}
+	return h.schema, nil
+}
+
 type avroReadFn struct {
 	// Avro schema type
 	Type beam.EncodedType
+	// Schema is the reader schema, if any.
+	Schema string
+	// Rows is true if Type was inferred from the schema.
+	Rows bool
+
+	decoders map[string]*decoder
+}
+
+// decoder decodes the records of files with a writer schema.
+type decoder struct {
+	codec  *goavro.Codec
+	writer *schema
+	// reader is the reader schema, or nil to read records as written.
+	reader *schema
+}
+
+// decoder returns the decoder for files with the writer schema.
+func (f *avroReadFn) decoder(spec string) (*decoder, error) {
+	if d, ok := f.decoders[spec]; ok {
+		return d, nil
+	}
+	writerSpec := spec
+	if f.Rows {
+		var err error
+		if writerSpec, err = stripLogicalTypes(spec); err != nil {
+			return nil, err
+		}
+	}
+	codec, err := goavro.NewCodec(writerSpec)
+	if err != nil {
+		return nil, fmt.Errorf("error creating avro codec: %v", err)
+	}
+	d := &decoder{codec: codec}
+	if d.writer, err = parseSchema(writerSpec); err != nil {
+		return nil, err
+	}
+	if f.Schema != "" {
+		if d.reader, err = parseSchema(f.Schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.decoders == nil {
+		f.decoders = make(map[string]*decoder)
+	}
+	f.decoders[spec] = d
+	return d, nil
+}
+
+// CreateInitialRestriction creates an offset range restriction representing
+// the file's size in bytes.
+func (f *avroReadFn) CreateInitialRestriction(file fileio.ReadableFile) offsetrange.Restriction {
+	return offsetrange.Restriction{
+		Start: 0,
+		End:   file.Metadata.Size,
+	}
+}
+
+// splitSize is the desired size of each block for initial splits.
+const splitSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits each file restriction into blocks of a predetermined size.
+func (f *avroReadFn) SplitRestriction(_ fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return rest.SizedSplits(splitSize)
+}
+
+// RestrictionSize returns the size of each restriction as its range.
+func (f *avroReadFn) RestrictionSize(_ fileio.ReadableFile, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping offsetRange.Trackers for
+// each restriction.
+func (f *avroReadFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
 }
 
-func (f *avroReadFn) ProcessElement(ctx context.Context, file fileio.ReadableFile, emit func(beam.X)) (err error) {
+// ProcessElement reads the blocks of the file that begin within the restriction. A
+// block begins right after the sync marker ending the header or the previous block.
+// Files that are compressed as a whole are read by the restriction starting at 0.
+func (f *avroReadFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X)) error {
 	log.Infof(ctx, "Reading AVRO from %v", file.Metadata.Path)
+	rest := rt.GetRestriction().(offsetrange.Restriction)
 
 	fd, err := file.Open(ctx)
 	if err != nil {
-		return
+		return err
 	}
 	defer fd.Close()
+	br := newBlockReader(fd)
+	h, err := br.readHeader()
+	if err != nil {
+		// The file may be compressed as a whole, in which case it can't be split.
+		if rest.Start != 0 || !rt.TryClaim(0) {
+			rt.TryClaim(rest.End)
+			return nil
+		}
+		return f.readCompressed(ctx, rt, file, emit)
+	}
 
-	ar, err := goavro.NewOCFReader(fd)
+	if rest.Start > br.pos {
+		// Find the first block beginning at or after the restriction start, after
+		// the sync marker ending at or after it.
+		if err := br.seek(rest.Start - syncSize); err != nil {
+			return err
+		}
+		if err := br.skipToSync(h.sync); err == io.EOF {
+			rt.TryClaim(rest.End)
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	for rt.TryClaim(br.pos) {
+		if err := f.readBlock(br, h, emit); err == io.EOF {
+			// Finish claiming restriction before breaking to avoid errors.
+			rt.TryClaim(rest.End)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+	}
+	return nil
+}
+
+// readCompressed reads all blocks of a file that is compressed as a whole.
+func (f *avroReadFn) readCompressed(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X)) error {
+	fd, err := (fileio.ReadableFile{Metadata: file.Metadata}).Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	br := newBlockReader(fd)
+	h, err := br.readHeader()
 	if err != nil {
-		log.Errorf(ctx, "error reading avro: %v", err)
-		return
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
 	}
+	for {
+		if err := f.readBlock(br, h, emit); err == io.EOF {
+			rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).End)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+	}
+}
 
-	for ar.Scan() {
-		val := reflect.New(f.Type.T).Interface()
-		var i any
-		i, err = ar.Read()
+// readBlock reads the next block and emits its records. It returns io.EOF at the
+// end of the file.
+func (f *avroReadFn) readBlock(br *blockReader, h *header, emit func(beam.X)) error {
+	count, data, err := br.readBlock(h)
+	if err != nil {
+		return err
+	}
+	d, err := f.decoder(h.schema)
+	if err != nil {
+		return err
+	}
+	for i := int64(0); i < count; i++ {
+		var native any
+		native, data, err = d.codec.NativeFromBinary(data)
 		if err != nil {
-			log.Errorf(ctx, "error reading avro row: %v", err)
-			continue
+			return fmt.Errorf("error decoding avro record: %v", err)
 		}
-
-		// marshal interface to bytes
-		var b []byte
-		b, err = json.Marshal(i)
+		v, err := f.convert(d, native)
 		if err != nil {
-			log.Errorf(ctx, "error unmarshalling avro data: %v", err)
-			return
+			return err
 		}
+		emit(v)
+	}
+	return nil
+}
 
-		switch reflect.New(f.Type.T).Interface().(type) {
-		case *string:
-			emit(string(b))
-		default:
-			if err = json.Unmarshal(b, val); err != nil {
-				log.Errorf(ctx, "error unmashalling avro to type: %v", err)
-				return
-			}
-			emit(reflect.ValueOf(val).Elem().Interface())
+// convert converts a native record to the output type.
+func (f *avroReadFn) convert(d *decoder, native any) (any, error) {
+	sch := d.writer
+	if d.reader != nil {
+		var err error
+		if native, err = resolve(native, d.writer, d.reader); err != nil {
+			return nil, fmt.Errorf("error resolving avro record: %v", err)
+		}
+		sch = d.reader
+	}
+
+	switch {
+	case f.Rows:
+		row, err := toRow(native, sch, f.Type.T)
+		if err != nil {
+			return nil, err
+		}
+		return row.Interface(), nil
+	case f.Type.T == recordType:
+		v, err := generic(native, sch)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("avro value %v isn't a record", v)
 		}
+		return Record(m), nil
+	}
+
+	// marshal interface to bytes
+	b, err := json.Marshal(native)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling avro data: %v", err)
+	}
+	if f.Type.T.Kind() == reflect.String {
+		return string(b), nil
+	}
+	val := reflect.New(f.Type.T)
+	if err := json.Unmarshal(b, val.Interface()); err != nil {
+		return nil, fmt.Errorf("error unmarshalling avro to type: %v", err)
+	}
+	return val.Elem().Interface(), nil
+}
+
+type writeOption struct {
+	Codec string
+}
+
+// WriteOptionFn is a function that can be passed to Write or NewSink to configure
+// options for writing avro files.
+type WriteOptionFn func(*writeOption)
+
+// WriteUncompressed specifies that the blocks of the file should not be compressed.
+func WriteUncompressed() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codecNull
+	}
+}
+
+// WriteDeflate specifies that the blocks of the file should be compressed with deflate.
+func WriteDeflate() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codecDeflate
+	}
+}
+
+// WriteSnappy specifies that the blocks of the file should be compressed with snappy.
+// This is the default.
+func WriteSnappy() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codecSnappy
 	}
+}
 
-	return ar.Err()
+// WriteZstd specifies that the blocks of the file should be compressed with zstandard.
+func WriteZstd() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codecZstandard
+	}
 }
 
 // Write writes a PCollection<string> to an AVRO file.
 // Write expects a JSON string with a matching AVRO schema.
 // the process will fail if the schema does not match the JSON
-// provided
-func Write(s beam.Scope, filename, schema string, col beam.PCollection) {
+// provided. It returns a PCollection<string> with the filename once the
+// file has been written. Write accepts a variadic number of WriteOptionFn
+// to configure the codec used to compress the blocks of the file.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename, schema string, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
 	s = s.Scope("avroio.Write")
-	filesystem.ValidateScheme(filename)
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeAvroFn{Schema: schema, Filename: filename}, post)
+	return fileio.WriteFiles(s, filename, NewSink(schema, opts...), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
 }
 
-type writeAvroFn struct {
-	Schema   string `json:"schema"`
-	Filename string `json:"filename"`
-}
-
-func (w *writeAvroFn) ProcessElement(ctx context.Context, _ int, lines func(*string) bool) (err error) {
-	log.Infof(ctx, "writing AVRO to %s", w.Filename)
-	fs, err := filesystem.New(ctx, w.Filename)
-	if err != nil {
-		return
+// NewSink returns a fileio.Sink that writes a PCollection<string> of JSON
+// strings as AVRO records with the given schema, using snappy compression
+// unless another codec is specified.
+func NewSink(schema string, opts ...WriteOptionFn) fileio.Sink {
+	option := &writeOption{Codec: codecSnappy}
+	for _, opt := range opts {
+		opt(option)
 	}
-	defer fs.Close()
+	return &sink{Schema: schema, Codec: option.Codec}
+}
 
-	fd, err := fs.OpenWrite(ctx, w.Filename)
-	if err != nil {
-		return
-	}
+type sink struct {
+	Schema string `json:"schema"`
+	Codec  string `json:"codec"`
 
-	defer fd.Close()
+	codec *goavro.Codec
+	bw    *blockWriter
+}
 
-	codec, err := goavro.NewCodec(w.Schema)
+func (s *sink) Open(ctx context.Context, w io.Writer) error {
+	codec, err := goavro.NewCodec(s.Schema)
 	if err != nil {
 		log.Errorf(ctx, "error creating avro codec: %v", err)
-		return
+		return err
 	}
-
-	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
-		Codec:           codec,
-		CompressionName: goavro.CompressionSnappyLabel,
-		Schema:          w.Schema,
-		W:               fd,
-	})
-
+	bw, err := newBlockWriter(w, s.Schema, s.Codec)
 	if err != nil {
 		log.Errorf(ctx, "error creating avro writer: %v", err)
-		return
+		return err
 	}
+	s.codec, s.bw = codec, bw
+	return nil
+}
 
-	var j string
-	for lines(&j) {
-		native, _, err := codec.NativeFromTextual([]byte(j))
-		if err != nil {
-			log.Errorf(ctx, "error reading native avro: %v", err)
-			return err
-		}
-
-		if err := ocfw.Append([]any{native}); err != nil {
-			log.Errorf(ctx, "error writing avro: %v", err)
-			return err
-		}
+func (s *sink) Write(ctx context.Context, elm any) error {
+	native, _, err := s.codec.NativeFromTextual([]byte(elm.(string)))
+	if err != nil {
+		log.Errorf(ctx, "error reading native avro: %v", err)
+		return err
 	}
+	record, err := s.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		log.Errorf(ctx, "error encoding avro: %v", err)
+		return err
+	}
+	if err := s.bw.append(record); err != nil {
+		log.Errorf(ctx, "error writing avro: %v", err)
+		return err
+	}
+	return nil
+}
 
-	return
+// Flush writes the last block of the file.
+func (s *sink) Flush(_ context.Context) error {
+	return s.bw.flush()
 }
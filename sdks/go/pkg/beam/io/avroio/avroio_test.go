@@ -17,14 +17,23 @@ package avroio
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
@@ -42,6 +51,12 @@ func init() {
 	beam.RegisterType(reflect.TypeOf((*NullableString)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*NullableTweet)(nil)).Elem())
 	register.Function2x0(toJSONString)
+	register.Function1x1(formatJSON)
+}
+
+func formatJSON(v beam.X) string {
+	b, _ := json.Marshal(v)
+	return string(b)
 }
 
 func toJSONString(user TwitterUser, emit func(string)) {
@@ -149,7 +164,8 @@ func TestWrite(t *testing.T) {
 		Info: testInfo,
 	}})
 	format := beam.ParDo(s, toJSONString, sequence)
-	Write(s, avroFile, userSchema, format)
+	written := Write(s, avroFile, userSchema, format)
+	passert.Equals(s, written, avroFile)
 	t.Cleanup(func() {
 		os.Remove(avroFile)
 	})
@@ -189,3 +205,365 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("User.User=%v, want %v", got, want)
 	}
 }
+
+// writeFile writes the JSON records to a new avro file with the schema, and
+// returns its path.
+func writeFile(t *testing.T, schema string, records []string, opts ...WriteOptionFn) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "records.avro")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	sink := NewSink(schema, opts...).(*sink)
+	if err := sink.Open(ctx, f); err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	for _, r := range records {
+		if err := sink.Write(ctx, r); err != nil {
+			t.Fatalf("Write(%v) failed: %v", r, err)
+		}
+	}
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	return path
+}
+
+func userRecords(n int) []string {
+	var records []string
+	for i := 0; i < n; i++ {
+		records = append(records, fmt.Sprintf(`{"username":"user%d","info":"info%d"}`, i, i))
+	}
+	return records
+}
+
+func TestWrite_codecs(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   WriteOptionFn
+		codec string
+	}{
+		{"uncompressed", WriteUncompressed(), goavro.CompressionNullLabel},
+		{"deflate", WriteDeflate(), goavro.CompressionDeflateLabel},
+		{"snappy", WriteSnappy(), goavro.CompressionSnappyLabel},
+		{"zstd", WriteZstd(), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			avroFile := filepath.Join(t.TempDir(), "user.avro")
+			users := []TwitterUser{{User: "user1", Info: "info1"}, {User: "user2", Info: "info2"}}
+			p, s, sequence := ptest.CreateList(users)
+			Write(s, avroFile, userSchema, beam.ParDo(s, toJSONString, sequence), test.opt)
+			ptest.RunAndValidate(t, p)
+
+			p, s = beam.NewPipelineWithRoot()
+			passert.Equals(s, Read(s, avroFile, reflect.TypeOf(TwitterUser{})), users[0], users[1])
+			ptest.RunAndValidate(t, p)
+
+			if test.codec == "" {
+				// Not supported by goavro.
+				return
+			}
+			f, err := os.Open(avroFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			ocf, err := goavro.NewOCFReader(f)
+			if err != nil {
+				t.Fatalf("Failed to make OCF Reader: %v", err)
+			}
+			if got := ocf.CompressionName(); got != test.codec {
+				t.Errorf("CompressionName() = %v, want %v", got, test.codec)
+			}
+			n := 0
+			for ocf.Scan() {
+				if _, err := ocf.Read(); err != nil {
+					t.Fatalf("Error decoding avro data: %v", err)
+				}
+				n++
+			}
+			if n != len(users) {
+				t.Errorf("Avro data, got %v records, want %v", n, len(users))
+			}
+		})
+	}
+}
+
+func TestReadFn_split(t *testing.T) {
+	defer func(size int) { blockSize = size }(blockSize)
+	blockSize = 64
+
+	records := userRecords(50)
+	path := writeFile(t, userSchema, records, WriteDeflate())
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: info.Size()}}
+	fn := &avroReadFn{Type: beam.EncodedType{T: reflect.TypeOf("")}}
+	rest := fn.CreateInitialRestriction(file)
+
+	want := append([]string(nil), records...)
+	sort.Strings(want)
+
+	// Every split point must read each record exactly once.
+	for split := int64(0); split <= rest.End; split += 7 {
+		var got []string
+		emit := func(v beam.X) {
+			var m map[string]string
+			json.Unmarshal([]byte(v.(string)), &m)
+			got = append(got, fmt.Sprintf(`{"username":"%s","info":"%s"}`, m["username"], m["info"]))
+		}
+		for _, r := range []offsetrange.Restriction{{Start: 0, End: split}, {Start: split, End: rest.End}} {
+			rt := fn.CreateTracker(r)
+			if err := fn.ProcessElement(context.Background(), rt, file, emit); err != nil {
+				t.Fatalf("ProcessElement(%v) failed: %v", r, err)
+			}
+			if !rt.IsDone() {
+				t.Errorf("ProcessElement(%v) didn't finish restriction", r)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("split at %v: got %v records, want %v", split, len(got), len(want))
+		}
+	}
+}
+
+const userSchemaV2 = `{
+	"type": "record",
+	"name": "user",
+	"namespace": "twitter.v2",
+	"fields": [
+		{ "name": "name", "type": "string", "aliases": ["username"] },
+		{ "name": "age", "type": ["null", "long"], "default": null },
+		{ "name": "country", "type": "string", "default": "unknown" },
+		{ "name": "level", "type": { "type": "enum", "name": "level", "symbols": ["LOW", "HIGH", "UNKNOWN"], "default": "UNKNOWN" } }
+	]
+}`
+
+const userSchemaV1 = `{
+	"type": "record",
+	"name": "user",
+	"namespace": "twitter",
+	"fields": [
+		{ "name": "username", "type": "string" },
+		{ "name": "info", "type": "string" },
+		{ "name": "age", "type": "int" },
+		{ "name": "level", "type": { "type": "enum", "name": "level", "symbols": ["LOW", "MEDIUM", "HIGH"] } }
+	]
+}`
+
+func TestRead_readerSchema(t *testing.T) {
+	path := writeFile(t, userSchemaV1, []string{
+		`{"username":"user1","info":"info1","age":30,"level":"HIGH"}`,
+		`{"username":"user2","info":"info2","age":40,"level":"MEDIUM"}`,
+	})
+
+	p, s := beam.NewPipelineWithRoot()
+	users := Read(s, path, reflect.TypeOf(""), ReadSchema(userSchemaV2))
+	passert.Equals(s, users,
+		`{"age":{"long":30},"country":"unknown","level":"HIGH","name":"user1"}`,
+		`{"age":{"long":40},"country":"unknown","level":"UNKNOWN","name":"user2"}`)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_generic(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	tweets := Read(s, "../../../../data/tweetwithnulls.avro", reflect.TypeOf(Record{}))
+	passert.Equals(s, beam.ParDo(s, formatJSON, tweets),
+		`{"timestamp":20,"tweet":"Hello twitter","username":"user1"}`,
+		`{"timestamp":21,"tweet":"Hello twitter again","username":null}`)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_rows(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	tweets := Read(s, "../../../../data/tweetwithnulls.avro", nil)
+	passert.Equals(s, beam.ParDo(s, formatJSON, tweets),
+		`{"Timestamp":20,"Tweet":"Hello twitter","Username":"user1"}`,
+		`{"Timestamp":21,"Tweet":"Hello twitter again","Username":null}`)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_rowsWithReaderSchema(t *testing.T) {
+	path := writeFile(t, userSchemaV1, []string{
+		`{"username":"user1","info":"info1","age":30,"level":"LOW"}`,
+	})
+
+	p, s := beam.NewPipelineWithRoot()
+	users := Read(s, path, nil, ReadSchema(userSchemaV2))
+	passert.Equals(s, beam.ParDo(s, formatJSON, users),
+		`{"Name":"user1","Age":30,"Country":"unknown","Level":"LOW"}`)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRowType(t *testing.T) {
+	sch, err := parseSchema(`{
+		"type": "record", "name": "r",
+		"fields": [
+			{ "name": "id", "type": "long" },
+			{ "name": "_tags", "type": { "type": "array", "items": "string" } },
+			{ "name": "nested", "type": ["null", { "type": "record", "name": "n", "fields": [ { "name": "f", "type": "float" } ] }] },
+			{ "name": "counts", "type": { "type": "map", "values": "int" } },
+			{ "name": "hash", "type": { "type": "fixed", "name": "md5", "size": 16 } }
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rowType(sch)
+	if err != nil {
+		t.Fatalf("rowType() failed: %v", err)
+	}
+	want := reflect.TypeOf(struct {
+		Id     int64    `beam:"id"`
+		X_tags []string `beam:"_tags"`
+		Nested *struct {
+			F float32 `beam:"f"`
+		} `beam:"nested"`
+		Counts map[string]int32 `beam:"counts"`
+		Hash   []byte           `beam:"hash"`
+	}{})
+	if got != want {
+		t.Errorf("rowType() = %v, want %v", got, want)
+	}
+
+	for _, spec := range []string{
+		`{"type": "record", "name": "r", "fields": [{"name": "u", "type": ["int", "string"]}]}`,
+		`{"type": "record", "name": "r", "fields": [{"name": "next", "type": ["null", "r"]}]}`,
+		`"string"`,
+	} {
+		sch, err := parseSchema(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rowType(sch); err == nil {
+			t.Errorf("rowType(%v) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		writer, reader string
+		value          any
+		want           any
+		wantErr        bool
+	}{
+		{writer: `"int"`, reader: `"long"`, value: int32(1), want: int64(1)},
+		{writer: `"int"`, reader: `"double"`, value: int32(1), want: float64(1)},
+		{writer: `"float"`, reader: `"double"`, value: float32(1.5), want: float64(1.5)},
+		{writer: `"string"`, reader: `"bytes"`, value: "a", want: []byte("a")},
+		{writer: `"long"`, reader: `"int"`, value: int64(1), wantErr: true},
+		{writer: `"int"`, reader: `["null", "string", "long"]`, value: int32(2), want: map[string]any{"long": int64(2)}},
+		{writer: `"int"`, reader: `["long", "int"]`, value: int32(4), want: map[string]any{"int": int32(4)}},
+		{writer: `"string"`, reader: `["bytes", "string"]`, value: "b", want: map[string]any{"string": "b"}},
+		{writer: `["null", "int"]`, reader: `"long"`, value: map[string]any{"int": int32(3)}, want: int64(3)},
+		{writer: `["null", "int"]`, reader: `"long"`, value: nil, wantErr: true},
+		{writer: `["null", "int"]`, reader: `["long", "null"]`, value: nil, want: nil},
+		{
+			writer: `{"type": "array", "items": "int"}`,
+			reader: `{"type": "array", "items": "long"}`,
+			value:  []any{int32(1), int32(2)},
+			want:   []any{int64(1), int64(2)},
+		},
+		{
+			writer:  `{"type": "enum", "name": "e", "symbols": ["A", "B"]}`,
+			reader:  `{"type": "enum", "name": "e", "symbols": ["A"]}`,
+			value:   "B",
+			wantErr: true,
+		},
+		{
+			writer:  `{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}]}`,
+			reader:  `{"type": "record", "name": "r", "fields": [{"name": "b", "type": "int"}]}`,
+			value:   map[string]any{"a": int32(1)},
+			wantErr: true,
+		},
+		{
+			writer:  `{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}]}`,
+			reader:  `{"type": "record", "name": "s", "fields": [{"name": "a", "type": "int"}]}`,
+			value:   map[string]any{"a": int32(1)},
+			wantErr: true,
+		},
+		{
+			writer: `{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}]}`,
+			reader: `{"type": "record", "name": "s", "aliases": ["r"], "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "bytes", "default": "\u00ff"}]}`,
+			value:  map[string]any{"a": int32(1)},
+			want:   map[string]any{"a": int32(1), "b": []byte{0xff}},
+		},
+	}
+	for _, test := range tests {
+		w, err := parseSchema(test.writer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := parseSchema(test.reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := resolve(test.value, w, r)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("resolve(%v, %v, %v) = %v, want error", test.value, test.writer, test.reader, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolve(%v, %v, %v) failed: %v", test.value, test.writer, test.reader, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("resolve(%v, %v, %v) = %#v, want %#v", test.value, test.writer, test.reader, got, test.want)
+		}
+	}
+}
+
+func TestBlockReader_invalidLength(t *testing.T) {
+	// A header with one entry, whose key has a length far beyond the file.
+	data := binary.AppendVarint([]byte(ocfMagic), 1)
+	data = binary.AppendVarint(data, 1<<30)
+	data = append(data, "avro"...)
+
+	readers := map[string]io.Reader{
+		"seekable":     bytes.NewReader(data),
+		"not seekable": io.MultiReader(bytes.NewReader(data)),
+	}
+	for name, r := range readers {
+		if _, err := newBlockReader(r).readHeader(); err == nil {
+			t.Errorf("readHeader() of %v header with invalid length succeeded, want error", name)
+		}
+	}
+}
+
+func TestRecordCoder(t *testing.T) {
+	r := Record{
+		"null":   nil,
+		"int":    int32(1),
+		"long":   int64(2),
+		"float":  float32(1.5),
+		"bytes":  []byte("b"),
+		"array":  []any{"a", nil},
+		"nested": map[string]any{"time": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	b, err := encodeRecord(r)
+	if err != nil {
+		t.Fatalf("encodeRecord(%v) failed: %v", r, err)
+	}
+	got, err := decodeRecord(b)
+	if err != nil {
+		t.Fatalf("decodeRecord() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("decodeRecord(encodeRecord(%v)) = %v", r, got)
+	}
+}
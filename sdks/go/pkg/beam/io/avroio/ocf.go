@@ -0,0 +1,394 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// This file implements the block structure of Avro object container files, so
+// that files can be split at sync markers and written with any codec.
+
+const (
+	ocfMagic = "Obj\x01"
+	syncSize = 16
+
+	metaSchema = "avro.schema"
+	metaCodec  = "avro.codec"
+
+	codecNull      = "null"
+	codecDeflate   = "deflate"
+	codecSnappy    = "snappy"
+	codecZstandard = "zstandard"
+)
+
+// header is the header of an object container file.
+type header struct {
+	schema string
+	codec  string
+	sync   [syncSize]byte
+}
+
+// maxBytesSize is the maximum length of the byte strings of the header and
+// blocks of a file.
+const maxBytesSize = math.MaxInt32
+
+// blockReader reads the header and blocks of an object container file,
+// keeping track of its position in the file.
+type blockReader struct {
+	r   io.Reader
+	br  *bufio.Reader
+	pos int64
+	// size is the size of the file, or -1 if it's unknown.
+	size int64
+}
+
+func newBlockReader(r io.Reader) *blockReader {
+	return &blockReader{r: r, br: bufio.NewReader(r), size: readerSize(r)}
+}
+
+// readerSize returns the number of bytes remaining in r, if it's an io.Seeker,
+// and -1 otherwise.
+func readerSize(r io.Reader) int64 {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+	return end - cur
+}
+
+func (b *blockReader) ReadByte() (byte, error) {
+	c, err := b.br.ReadByte()
+	if err == nil {
+		b.pos++
+	}
+	return c, err
+}
+
+func (b *blockReader) readFull(p []byte) error {
+	n, err := io.ReadFull(b.br, p)
+	b.pos += int64(n)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (b *blockReader) readLong() (int64, error) {
+	return binary.ReadVarint(b)
+}
+
+func (b *blockReader) readBytes() ([]byte, error) {
+	n, err := b.readLong()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > maxBytesSize {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	if b.size >= 0 {
+		if n > b.size-b.pos {
+			return nil, fmt.Errorf("length %d exceeds the remaining %d bytes", n, b.size-b.pos)
+		}
+		p := make([]byte, n)
+		return p, b.readFull(p)
+	}
+	// Read incrementally when the size is unknown, so that an invalid length
+	// doesn't allocate more than the remaining input.
+	var buf bytes.Buffer
+	m, err := io.CopyN(&buf, b.br, n)
+	b.pos += m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+// seek moves the reader forward to the given position, seeking if the
+// underlying reader supports it, and discarding bytes otherwise.
+func (b *blockReader) seek(pos int64) error {
+	if pos <= b.pos {
+		return nil
+	}
+	if s, ok := b.r.(io.Seeker); ok {
+		if _, err := s.Seek(pos, io.SeekStart); err == nil {
+			b.br.Reset(b.r)
+			b.pos = pos
+			return nil
+		}
+	}
+	n, err := b.br.Discard(int(pos - b.pos))
+	b.pos += int64(n)
+	return err
+}
+
+// readHeader reads the header at the start of the file.
+func (b *blockReader) readHeader() (*header, error) {
+	magic := make([]byte, len(ocfMagic))
+	if err := b.readFull(magic); err != nil {
+		return nil, fmt.Errorf("error reading avro header: %v", err)
+	}
+	if string(magic) != ocfMagic {
+		return nil, errors.New("not an avro object container file")
+	}
+
+	h := &header{codec: codecNull}
+	for {
+		n, err := b.readLong()
+		if err != nil {
+			return nil, fmt.Errorf("error reading avro header: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+		if n < 0 {
+			// A negative count is followed by the size of the entries.
+			n = -n
+			if _, err := b.readLong(); err != nil {
+				return nil, fmt.Errorf("error reading avro header: %v", err)
+			}
+		}
+		for i := int64(0); i < n; i++ {
+			key, err := b.readBytes()
+			if err != nil {
+				return nil, fmt.Errorf("error reading avro header: %v", err)
+			}
+			value, err := b.readBytes()
+			if err != nil {
+				return nil, fmt.Errorf("error reading avro header: %v", err)
+			}
+			switch string(key) {
+			case metaSchema:
+				h.schema = string(value)
+			case metaCodec:
+				if len(value) > 0 {
+					h.codec = string(value)
+				}
+			}
+		}
+	}
+	if h.schema == "" {
+		return nil, errors.New("avro header has no schema")
+	}
+	if err := b.readFull(h.sync[:]); err != nil {
+		return nil, fmt.Errorf("error reading avro header: %v", err)
+	}
+	return h, nil
+}
+
+// skipToSync reads up to and including the next sync marker. It returns
+// io.EOF if there is no further marker.
+func (b *blockReader) skipToSync(sync [syncSize]byte) error {
+	var window [syncSize]byte
+	n := 0
+	for {
+		c, err := b.ReadByte()
+		if err != nil {
+			return err
+		}
+		if n < syncSize {
+			window[n] = c
+			n++
+		} else {
+			copy(window[:], window[1:])
+			window[syncSize-1] = c
+		}
+		if n == syncSize && window == sync {
+			return nil
+		}
+	}
+}
+
+// readBlock reads the next block, returning its record count and
+// decompressed data. It returns io.EOF at the end of the file.
+func (b *blockReader) readBlock(h *header) (int64, []byte, error) {
+	count, err := b.readLong()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := b.readBytes()
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading avro block: %v", err)
+	}
+	var sync [syncSize]byte
+	if err := b.readFull(sync[:]); err != nil {
+		return 0, nil, fmt.Errorf("error reading avro block: %v", err)
+	}
+	if sync != h.sync {
+		return 0, nil, errors.New("avro block doesn't end with the sync marker")
+	}
+	data, err = decompress(h.codec, data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decompressing avro block: %v", err)
+	}
+	return count, data, nil
+}
+
+func decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case codecNull:
+		return data, nil
+	case codecDeflate:
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	case codecSnappy:
+		if len(data) < 4 {
+			return nil, errors.New("snappy block is too short")
+		}
+		out, err := snappy.Decode(nil, data[:len(data)-4])
+		if err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(out) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+			return nil, errors.New("snappy block checksum mismatch")
+		}
+		return out, nil
+	case codecZstandard:
+		d, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return d.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported codec %q", codec)
+	}
+}
+
+func compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case codecNull:
+		return data, nil
+	case codecDeflate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case codecSnappy:
+		out := snappy.Encode(nil, data)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(data)), nil
+	case codecZstandard:
+		e, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer e.Close()
+		return e.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported codec %q", codec)
+	}
+}
+
+// blockSize is the size of the uncompressed data of a block, above which
+// the block is written.
+var blockSize = 64 * 1024
+
+// blockWriter writes an object container file.
+type blockWriter struct {
+	w     io.Writer
+	codec string
+	sync  [syncSize]byte
+
+	buf   bytes.Buffer
+	count int64
+}
+
+// newBlockWriter writes the header of a file with the schema and codec.
+func newBlockWriter(w io.Writer, schema, codec string) (*blockWriter, error) {
+	b := &blockWriter{w: w, codec: codec}
+	if _, err := rand.Read(b.sync[:]); err != nil {
+		return nil, err
+	}
+
+	var h []byte
+	h = append(h, ocfMagic...)
+	h = binary.AppendVarint(h, 2)
+	for _, kv := range [][2]string{{metaSchema, schema}, {metaCodec, codec}} {
+		for _, s := range kv {
+			h = binary.AppendVarint(h, int64(len(s)))
+			h = append(h, s...)
+		}
+	}
+	h = binary.AppendVarint(h, 0)
+	h = append(h, b.sync[:]...)
+	if _, err := w.Write(h); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// append adds a binary encoded record to the current block, writing the
+// block if it's full.
+func (b *blockWriter) append(record []byte) error {
+	b.buf.Write(record)
+	b.count++
+	if b.buf.Len() >= blockSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush writes the current block, if it has any records.
+func (b *blockWriter) flush() error {
+	if b.count == 0 {
+		return nil
+	}
+	data, err := compress(b.codec, b.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	var block []byte
+	block = binary.AppendVarint(block, b.count)
+	block = binary.AppendVarint(block, int64(len(data)))
+	block = append(block, data...)
+	block = append(block, b.sync[:]...)
+	if _, err := b.w.Write(block); err != nil {
+		return err
+	}
+	b.buf.Reset()
+	b.count = 0
+	return nil
+}
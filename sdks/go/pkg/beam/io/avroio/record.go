@@ -0,0 +1,63 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+)
+
+func init() {
+	// Types of the values of generic records, other than the basic types
+	// known to gob.
+	gob.Register(map[string]any(nil))
+	gob.Register([]any(nil))
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register((*big.Rat)(nil))
+
+	beam.RegisterCoder(reflect.TypeOf((*Record)(nil)).Elem(), encodeRecord, decodeRecord)
+}
+
+// Record is a generic Avro record, mapping field names to values. Values are
+// nil, bool, int32, int64, float32, float64, string, []byte, []any for
+// arrays, and map[string]any for maps and nested records, or the values of
+// logical types: time.Time for dates and timestamps, time.Duration for
+// times, and *big.Rat for decimals. Unions are represented by the value of
+// their branch, and enums by their symbol.
+type Record map[string]any
+
+func encodeRecord(r Record) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(map[string]any(r)); err != nil {
+		return nil, fmt.Errorf("error encoding avro record: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRecord(b []byte) (Record, error) {
+	var m map[string]any
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("error decoding avro record: %v", err)
+	}
+	return Record(m), nil
+}
@@ -0,0 +1,551 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// resolve converts a goavro native value written with the writer schema to a
+// native value of the reader schema, following the Avro schema resolution
+// rules.
+func resolve(v any, w, r *schema) (any, error) {
+	if w.Type == "union" {
+		b, inner, err := unionBranch(v, w)
+		if err != nil {
+			return nil, err
+		}
+		return resolve(inner, b, r)
+	}
+	if r.Type == "union" {
+		b := readerBranch(w, r)
+		if b == nil {
+			return nil, fmt.Errorf("no branch of the reader union matches writer type %v", w.unionKey())
+		}
+		rv, err := resolve(v, w, b)
+		if err != nil || b.Type == "null" {
+			return nil, err
+		}
+		return map[string]any{b.unionKey(): rv}, nil
+	}
+	if !matches(w, r) {
+		return nil, fmt.Errorf("writer type %v doesn't match reader type %v", w.unionKey(), r.unionKey())
+	}
+
+	switch r.Type {
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for avro record %v", v, w.Name)
+		}
+		out := make(map[string]any, len(r.Fields))
+		for _, rf := range r.Fields {
+			wf := w.field(rf)
+			if wf == nil {
+				if !rf.HasDefault {
+					return nil, fmt.Errorf("field %v of avro record %v has no value and no default", rf.Name, r.Name)
+				}
+				d, err := defaultValue(rf.Default, rf.Type)
+				if err != nil {
+					return nil, fmt.Errorf("invalid default of field %v of avro record %v: %v", rf.Name, r.Name, err)
+				}
+				out[rf.Name] = d
+				continue
+			}
+			fv, err := resolve(m[wf.Name], wf.Type, rf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %v of avro record %v: %v", rf.Name, r.Name, err)
+			}
+			out[rf.Name] = fv
+		}
+		return out, nil
+	case "enum":
+		sym, _ := v.(string)
+		if slices.Contains(r.Symbols, sym) {
+			return sym, nil
+		}
+		if r.EnumDefault != nil {
+			return *r.EnumDefault, nil
+		}
+		return nil, fmt.Errorf("symbol %v isn't in avro enum %v", sym, r.Name)
+	case "array":
+		items, _ := v.([]any)
+		out := make([]any, len(items))
+		for i, item := range items {
+			iv, err := resolve(item, w.Items, r.Items)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = iv
+		}
+		return out, nil
+	case "map":
+		values, _ := v.(map[string]any)
+		out := make(map[string]any, len(values))
+		for k, value := range values {
+			vv, err := resolve(value, w.Values, r.Values)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = vv
+		}
+		return out, nil
+	default:
+		return promote(v, w, r)
+	}
+}
+
+// unionBranch returns the branch of the union schema of a goavro native union
+// value, and the value of the branch.
+func unionBranch(v any, s *schema) (*schema, any, error) {
+	if v == nil {
+		for _, b := range s.Branches {
+			if b.Type == "null" {
+				return b, nil, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("null value for avro union without null")
+	}
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		for k, inner := range m {
+			for _, b := range s.Branches {
+				if b.unionKey() == k {
+					return b, inner, nil
+				}
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("invalid value %v for avro union", v)
+}
+
+// field returns the field of the record schema s that the reader field reads,
+// matching by name or by the aliases of the reader field.
+func (s *schema) field(rf *field) *field {
+	for _, f := range s.Fields {
+		if f.Name == rf.Name || slices.Contains(rf.Aliases, f.Name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// readerBranch returns the branch of the reader union r that reads values of
+// the writer schema w: the first branch of the same type, or else the first
+// branch that the writer type can be promoted to. It returns nil if no branch
+// matches.
+func readerBranch(w, r *schema) *schema {
+	for _, b := range r.Branches {
+		if b.Type == w.Type && matches(w, b) {
+			return b
+		}
+	}
+	for _, b := range r.Branches {
+		if matches(w, b) {
+			return b
+		}
+	}
+	return nil
+}
+
+// matches returns whether values of the writer schema can be read with the
+// reader schema, without looking into the fields and items of the schemas.
+func matches(w, r *schema) bool {
+	switch {
+	case w.Type == "union" || r.Type == "union":
+		return true
+	case w.Type == r.Type:
+		if w.Name == "" {
+			return true
+		}
+		if shortName(w.Name) == shortName(r.Name) {
+			return true
+		}
+		for _, a := range r.Aliases {
+			if shortName(a) == shortName(w.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	switch w.Type + ">" + r.Type {
+	case "int>long", "int>float", "int>double", "long>float", "long>double",
+		"float>double", "string>bytes", "bytes>string":
+		return true
+	}
+	return false
+}
+
+// promote converts a primitive or fixed value to the reader type.
+func promote(v any, w, r *schema) (any, error) {
+	if w.Type == r.Type {
+		if r.Type == "fixed" && w.Size != r.Size {
+			return nil, fmt.Errorf("avro fixed %v has size %d, not %d", r.Name, r.Size, w.Size)
+		}
+		return v, nil
+	}
+	switch v := v.(type) {
+	case int32:
+		switch r.Type {
+		case "long":
+			return int64(v), nil
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+	case int64:
+		switch r.Type {
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+	case float32:
+		if r.Type == "double" {
+			return float64(v), nil
+		}
+	case string:
+		if r.Type == "bytes" {
+			return []byte(v), nil
+		}
+	case []byte:
+		if r.Type == "string" {
+			return string(v), nil
+		}
+	}
+	return nil, fmt.Errorf("can't promote %v value %v to %v", w.Type, v, r.Type)
+}
+
+// defaultValue returns the goavro native value of the JSON default of a
+// field of the schema.
+func defaultValue(d any, s *schema) (any, error) {
+	switch s.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		if b, ok := d.(bool); ok {
+			return b, nil
+		}
+	case "int", "long", "float", "double":
+		if n, ok := d.(float64); ok {
+			switch s.Type {
+			case "int":
+				return int32(n), nil
+			case "long":
+				return int64(n), nil
+			case "float":
+				return float32(n), nil
+			default:
+				return n, nil
+			}
+		}
+	case "string", "enum":
+		if str, ok := d.(string); ok {
+			return str, nil
+		}
+	case "bytes", "fixed":
+		if str, ok := d.(string); ok {
+			// Code points 0-255 map to byte values.
+			b := make([]byte, 0, len(str))
+			for _, c := range str {
+				b = append(b, byte(c))
+			}
+			return b, nil
+		}
+	case "array":
+		if items, ok := d.([]any); ok {
+			out := make([]any, len(items))
+			for i, item := range items {
+				iv, err := defaultValue(item, s.Items)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = iv
+			}
+			return out, nil
+		}
+	case "map":
+		if values, ok := d.(map[string]any); ok {
+			out := make(map[string]any, len(values))
+			for k, value := range values {
+				vv, err := defaultValue(value, s.Values)
+				if err != nil {
+					return nil, err
+				}
+				out[k] = vv
+			}
+			return out, nil
+		}
+	case "record":
+		if values, ok := d.(map[string]any); ok {
+			out := make(map[string]any, len(s.Fields))
+			for _, f := range s.Fields {
+				fd, ok := values[f.Name]
+				if !ok {
+					fd = f.Default
+				}
+				fv, err := defaultValue(fd, f.Type)
+				if err != nil {
+					return nil, err
+				}
+				out[f.Name] = fv
+			}
+			return out, nil
+		}
+	case "union":
+		// The default of a union is a value of its first branch.
+		b := s.Branches[0]
+		bv, err := defaultValue(d, b)
+		if err != nil || b.Type == "null" {
+			return nil, err
+		}
+		return map[string]any{b.unionKey(): bv}, nil
+	}
+	return nil, fmt.Errorf("invalid default %v for avro type %v", d, s.unionKey())
+}
+
+// generic converts a goavro native value of the schema to a generic value,
+// replacing union values by the value of their branch.
+func generic(v any, s *schema) (any, error) {
+	switch s.Type {
+	case "union":
+		b, inner, err := unionBranch(v, s)
+		if err != nil {
+			return nil, err
+		}
+		return generic(inner, b)
+	case "record":
+		m, _ := v.(map[string]any)
+		out := make(map[string]any, len(s.Fields))
+		for _, f := range s.Fields {
+			fv, err := generic(m[f.Name], f.Type)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = fv
+		}
+		return out, nil
+	case "array":
+		items, _ := v.([]any)
+		out := make([]any, len(items))
+		for i, item := range items {
+			iv, err := generic(item, s.Items)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = iv
+		}
+		return out, nil
+	case "map":
+		values, _ := v.(map[string]any)
+		out := make(map[string]any, len(values))
+		for k, value := range values {
+			vv, err := generic(value, s.Values)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = vv
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// rowType returns the Go struct type of the rows of a record schema, with
+// beam tags naming the fields after the Avro fields. Logical types are
+// represented by their underlying types.
+func rowType(s *schema) (reflect.Type, error) {
+	if s.Type != "record" {
+		return nil, fmt.Errorf("avro schema of type %v isn't a record", s.unionKey())
+	}
+	return goType(s, nil)
+}
+
+func goType(s *schema, records []string) (reflect.Type, error) {
+	switch s.Type {
+	case "boolean":
+		return reflect.TypeOf(false), nil
+	case "int":
+		return reflect.TypeOf(int32(0)), nil
+	case "long":
+		return reflect.TypeOf(int64(0)), nil
+	case "float":
+		return reflect.TypeOf(float32(0)), nil
+	case "double":
+		return reflect.TypeOf(float64(0)), nil
+	case "string", "enum":
+		return reflect.TypeOf(""), nil
+	case "bytes", "fixed":
+		return reflect.TypeOf([]byte(nil)), nil
+	case "array":
+		t, err := goType(s.Items, records)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(t), nil
+	case "map":
+		t, err := goType(s.Values, records)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(reflect.TypeOf(""), t), nil
+	case "union":
+		b := nullableBranch(s)
+		if b == nil {
+			return nil, fmt.Errorf("unsupported avro union %v: only unions of null and another type are supported", unionKeys(s))
+		}
+		t, err := goType(b, records)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			return t, nil
+		}
+		return reflect.PointerTo(t), nil
+	case "record":
+		if slices.Contains(records, s.Name) {
+			return nil, fmt.Errorf("unsupported recursive avro record %v", s.Name)
+		}
+		records = append(records, s.Name)
+		fields := make([]reflect.StructField, len(s.Fields))
+		names := make(map[string]bool)
+		for i, f := range s.Fields {
+			t, err := goType(f.Type, records)
+			if err != nil {
+				return nil, fmt.Errorf("field %v of avro record %v: %v", f.Name, s.Name, err)
+			}
+			name := exportedName(f.Name)
+			if names[name] {
+				return nil, fmt.Errorf("fields of avro record %v have the same Go name %v", s.Name, name)
+			}
+			names[name] = true
+			fields[i] = reflect.StructField{
+				Name: name,
+				Type: t,
+				Tag:  reflect.StructTag(fmt.Sprintf("beam:%q", f.Name)),
+			}
+		}
+		return reflect.StructOf(fields), nil
+	default:
+		return nil, fmt.Errorf("unsupported avro type %v", s.Type)
+	}
+}
+
+// nullableBranch returns the non-null branch of a union of null and another
+// type, or nil for other unions.
+func nullableBranch(s *schema) *schema {
+	if len(s.Branches) != 2 {
+		return nil
+	}
+	switch {
+	case s.Branches[0].Type == "null" && s.Branches[1].Type != "null":
+		return s.Branches[1]
+	case s.Branches[1].Type == "null" && s.Branches[0].Type != "null":
+		return s.Branches[0]
+	default:
+		return nil
+	}
+}
+
+func unionKeys(s *schema) []string {
+	var keys []string
+	for _, b := range s.Branches {
+		keys = append(keys, b.unionKey())
+	}
+	return keys
+}
+
+// exportedName returns an exported Go field name for an Avro field name.
+func exportedName(name string) string {
+	if strings.HasPrefix(name, "_") {
+		return "X" + name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// toRow converts a goavro native value of the schema, decoded without
+// logical types, to a value of the Go type returned by goType.
+func toRow(v any, s *schema, t reflect.Type) (reflect.Value, error) {
+	switch s.Type {
+	case "union":
+		b, inner, err := unionBranch(v, s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if b.Type == "null" {
+			return reflect.Zero(t), nil
+		}
+		if t.Kind() != reflect.Pointer {
+			return toRow(inner, b, t)
+		}
+		ev, err := toRow(inner, b, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(ev)
+		return p, nil
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("invalid value %v for avro record %v", v, s.Name)
+		}
+		out := reflect.New(t).Elem()
+		for i, f := range s.Fields {
+			fv, err := toRow(m[f.Name], f.Type, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %v of avro record %v: %v", f.Name, s.Name, err)
+			}
+			out.Field(i).Set(fv)
+		}
+		return out, nil
+	case "array":
+		items, _ := v.([]any)
+		out := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			iv, err := toRow(item, s.Items, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(iv)
+		}
+		return out, nil
+	case "map":
+		values, _ := v.(map[string]any)
+		out := reflect.MakeMapWithSize(t, len(values))
+		for k, value := range values {
+			vv, err := toRow(value, s.Values, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(reflect.ValueOf(k), vv)
+		}
+		return out, nil
+	default:
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || rv.Type() != t {
+			return reflect.Value{}, fmt.Errorf("invalid value %v for avro type %v", v, s.Type)
+		}
+		return rv, nil
+	}
+}
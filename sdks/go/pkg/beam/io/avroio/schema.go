@@ -0,0 +1,278 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schema is a parsed Avro schema.
+type schema struct {
+	// Type is a primitive type name, or one of "record", "enum", "array",
+	// "map", "fixed" and "union".
+	Type string
+	// Name is the full name of named types.
+	Name    string
+	Aliases []string
+	Logical string
+
+	Fields      []*field
+	Symbols     []string
+	EnumDefault *string
+	Items       *schema
+	Values      *schema
+	Branches    []*schema
+	Size        int
+}
+
+// field is a field of a record schema.
+type field struct {
+	Name       string
+	Aliases    []string
+	Type       *schema
+	Default    any
+	HasDefault bool
+}
+
+var primitiveTypes = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// goavroLogicalTypes are the logical types that goavro decodes to native
+// values other than those of the underlying type.
+var goavroLogicalTypes = map[string]bool{
+	"long.timestamp-millis":   true,
+	"long.timestamp-micros":   true,
+	"int.time-millis":         true,
+	"long.time-micros":        true,
+	"int.date":                true,
+	"bytes.decimal":           true,
+	"string.validated-string": true,
+}
+
+// parseSchema parses a JSON Avro schema.
+func parseSchema(spec string) (*schema, error) {
+	var v any
+	if err := json.Unmarshal([]byte(spec), &v); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+	p := &schemaParser{named: make(map[string]*schema)}
+	return p.parse(v, "")
+}
+
+type schemaParser struct {
+	named map[string]*schema
+}
+
+func (p *schemaParser) parse(v any, namespace string) (*schema, error) {
+	switch v := v.(type) {
+	case string:
+		return p.lookup(v, namespace)
+	case []any:
+		s := &schema{Type: "union"}
+		for _, b := range v {
+			branch, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.Branches = append(s.Branches, branch)
+		}
+		return s, nil
+	case map[string]any:
+		return p.parseMap(v, namespace)
+	default:
+		return nil, fmt.Errorf("invalid avro schema %v", v)
+	}
+}
+
+func (p *schemaParser) lookup(name, namespace string) (*schema, error) {
+	if primitiveTypes[name] {
+		return &schema{Type: name}, nil
+	}
+	if s, ok := p.named[fullName(name, "", namespace)]; ok {
+		return s, nil
+	}
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown avro type %q", name)
+}
+
+func (p *schemaParser) parseMap(m map[string]any, namespace string) (*schema, error) {
+	t, ok := m["type"].(string)
+	if !ok {
+		if m["type"] == nil {
+			return nil, fmt.Errorf("avro schema %v has no type", m)
+		}
+		return p.parse(m["type"], namespace)
+	}
+
+	switch t {
+	case "record", "error", "enum", "fixed":
+		name, _ := m["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("avro %v schema has no name", t)
+		}
+		ns, _ := m["namespace"].(string)
+		s := &schema{Type: t, Name: fullName(name, ns, namespace)}
+		if t == "error" {
+			s.Type = "record"
+		}
+		if aliases, ok := m["aliases"].([]any); ok {
+			for _, a := range aliases {
+				if a, ok := a.(string); ok {
+					s.Aliases = append(s.Aliases, fullName(a, "", namespaceOf(s.Name)))
+				}
+			}
+		}
+		p.named[s.Name] = s
+		return s, p.parseNamed(s, m)
+	case "array":
+		items, err := p.parse(m["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: t, Items: items}, nil
+	case "map":
+		values, err := p.parse(m["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: t, Values: values}, nil
+	}
+
+	s, err := p.lookup(t, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if lt, ok := m["logicalType"].(string); ok && primitiveTypes[t] {
+		return &schema{Type: t, Logical: lt}, nil
+	}
+	return s, nil
+}
+
+// parseNamed parses the rest of a record, enum or fixed schema.
+func (p *schemaParser) parseNamed(s *schema, m map[string]any) error {
+	switch s.Type {
+	case "record":
+		fields, _ := m["fields"].([]any)
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid field %v of avro record %v", f, s.Name)
+			}
+			name, _ := fm["name"].(string)
+			ft, err := p.parse(fm["type"], namespaceOf(s.Name))
+			if err != nil {
+				return fmt.Errorf("field %v of avro record %v: %v", name, s.Name, err)
+			}
+			fd := &field{Name: name, Type: ft}
+			fd.Default, fd.HasDefault = fm["default"]
+			if aliases, ok := fm["aliases"].([]any); ok {
+				for _, a := range aliases {
+					if a, ok := a.(string); ok {
+						fd.Aliases = append(fd.Aliases, a)
+					}
+				}
+			}
+			s.Fields = append(s.Fields, fd)
+		}
+	case "enum":
+		symbols, _ := m["symbols"].([]any)
+		for _, sym := range symbols {
+			if sym, ok := sym.(string); ok {
+				s.Symbols = append(s.Symbols, sym)
+			}
+		}
+		if d, ok := m["default"].(string); ok {
+			s.EnumDefault = &d
+		}
+	case "fixed":
+		size, _ := m["size"].(float64)
+		s.Size = int(size)
+	}
+	return nil
+}
+
+// fullName returns the full name of a type defined with a name and
+// namespace within an enclosing namespace.
+func fullName(name, namespace, enclosing string) string {
+	switch {
+	case strings.Contains(name, "."):
+		return name
+	case namespace != "":
+		return namespace + "." + name
+	case enclosing != "":
+		return enclosing + "." + name
+	default:
+		return name
+	}
+}
+
+func namespaceOf(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func shortName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// unionKey returns the key of a value of the schema in the goavro native
+// representation of a union.
+func (s *schema) unionKey() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Logical != "" && goavroLogicalTypes[s.Type+"."+s.Logical]:
+		return s.Type + "." + s.Logical
+	default:
+		return s.Type
+	}
+}
+
+// stripLogicalTypes returns the schema without logical type annotations, so
+// that values are decoded as their underlying types.
+func stripLogicalTypes(spec string) (string, error) {
+	var v any
+	if err := json.Unmarshal([]byte(spec), &v); err != nil {
+		return "", fmt.Errorf("invalid avro schema: %v", err)
+	}
+	var strip func(v any)
+	strip = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			delete(v, "logicalType")
+			for k, e := range v {
+				if k != "default" {
+					strip(e)
+				}
+			}
+		case []any:
+			for _, e := range v {
+				strip(e)
+			}
+		}
+	}
+	strip(v)
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -222,19 +222,32 @@ func mustParseTable(table string) QualifiedTableName {
 	return qn
 }
 
-// TODO(herohde) 7/14/2017: allow WriteDispositions. The default
-// is not quite what the Dataflow examples do.
-
 // writeOptions represents additional options for executing a write
 type writeOptions struct {
 	// CreateDisposition specifies the circumstances under which destination table will be created
 	CreateDisposition bigquery.TableCreateDisposition
+	// WriteDisposition specifies how existing rows of the destination table are handled
+	WriteDisposition bigquery.TableWriteDisposition
+	// AllowFieldAddition allows adding fields missing from the destination table
+	AllowFieldAddition bool
+	// AllowFieldRelaxation allows relaxing required fields of the destination table
+	AllowFieldRelaxation bool
+	// WriteMode specifies the streams written with the Storage Write API
+	WriteMode StorageWriteMode
+	// Streams is the number of streams written in parallel with the Storage Write API
+	Streams int
 }
 
 // newWriteOptions creates a new instance of WriteOptions
-// "CreateIfNeeded" is set as the default write disposition
+// "CreateIfNeeded" is set as the default create disposition and "WriteAppend"
+// as the default write disposition
 func newWriteOptions() writeOptions {
-	return writeOptions{CreateDisposition: bigquery.CreateIfNeeded}
+	return writeOptions{
+		CreateDisposition: bigquery.CreateIfNeeded,
+		WriteDisposition:  bigquery.WriteAppend,
+		WriteMode:         DefaultStream,
+		Streams:           defaultWriteStreams,
+	}
 }
 
 // WriteOption represents a function that sets options for executing a write
@@ -248,9 +261,47 @@ func WithCreateDisposition(cd bigquery.TableCreateDisposition) WriteOption {
 	}
 }
 
+// WithWriteDisposition specifies how existing rows of the destination table are
+// handled: they are kept with "WriteAppend", replaced by the rows written with
+// "WriteTruncate", and fail the write with "WriteEmpty". Only WriteStorage
+// supports write dispositions other than "WriteAppend"
+func WithWriteDisposition(wd bigquery.TableWriteDisposition) WriteOption {
+	return func(wo *writeOptions) error {
+		switch wd {
+		case bigquery.WriteAppend, bigquery.WriteTruncate, bigquery.WriteEmpty:
+			wo.WriteDisposition = wd
+			return nil
+		default:
+			return fmt.Errorf("invalid write disposition: %v", wd)
+		}
+	}
+}
+
+// WithAllowFieldAddition allows adding the fields missing from the destination
+// table to its schema, as nullable fields. Only WriteStorage supports it
+func WithAllowFieldAddition() WriteOption {
+	return func(wo *writeOptions) error {
+		wo.AllowFieldAddition = true
+		return nil
+	}
+}
+
+// WithAllowFieldRelaxation allows relaxing the required fields of the destination
+// table that are nullable in the written type to nullable fields. Only
+// WriteStorage supports it
+func WithAllowFieldRelaxation() WriteOption {
+	return func(wo *writeOptions) error {
+		wo.AllowFieldRelaxation = true
+		return nil
+	}
+}
+
 // Write writes the elements of the given PCollection<T> to bigquery. T is required
-// to be the schema type.
-func Write(s beam.Scope, project, table string, col beam.PCollection, options ...func(*writeOptions) error) {
+// to be the schema type. It returns a PCollection<int> with the number of rows
+// written once the write has completed.
+// Write supports only create dispositions: use WriteStorage for write
+// dispositions and schema updates.
+func Write(s beam.Scope, project, table string, col beam.PCollection, options ...func(*writeOptions) error) beam.PCollection {
 	t := col.Type().Type()
 	mustInferSchema(t)
 	qn := mustParseTable(table)
@@ -263,11 +314,14 @@ func Write(s beam.Scope, project, table string, col beam.PCollection, options ..
 			panic(err)
 		}
 	}
+	if writeOptions.WriteDisposition != bigquery.WriteAppend || writeOptions.AllowFieldAddition || writeOptions.AllowFieldRelaxation {
+		panic("bigqueryio.Write supports only create dispositions: use WriteStorage for write dispositions and schema updates")
+	}
 
 	// TODO(BEAM-3860) 3/15/2018: use side input instead of GBK.
 	pre := beam.AddFixedKey(s, col)
 	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Project: project, Table: qn, Type: beam.EncodedType{T: t}, Options: writeOptions}, post)
+	return beam.ParDo(s, &writeFn{Project: project, Table: qn, Type: beam.EncodedType{T: t}, Options: writeOptions}, post)
 }
 
 // Add in additional field (CreateDisposition), Bool
@@ -309,7 +363,7 @@ func getInsertSize(v any, schema bigquery.Schema) (int, error) {
 	return len(data) + 1, err
 }
 
-func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
+func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, emit func(int)) error {
 	client, err := bigquery.NewClient(ctx, f.Project)
 	if err != nil {
 		return err
@@ -340,6 +394,7 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	var data []reflect.Value
 	// This stores the running byte size estimate of a BQ request.
 	size := writeOverheadBytes
+	count := 0
 
 	var val beam.X
 	for iter(&val) {
@@ -352,18 +407,20 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 			if err := put(ctx, table, f.Type.T, data); err != nil {
 				return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
 			}
+			count += len(data)
 			data = nil
 			size = writeOverheadBytes
 		}
 		data = append(data, reflect.ValueOf(val.(any)))
 		size += current
 	}
-	if len(data) == 0 {
-		return nil
-	}
-	if err := put(ctx, table, f.Type.T, data); err != nil {
-		return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
+	if len(data) > 0 {
+		if err := put(ctx, table, f.Type.T, data); err != nil {
+			return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
+		}
+		count += len(data)
 	}
+	emit(count)
 	return nil
 }
 
@@ -381,3 +438,8 @@ func isNotFound(err error) bool {
 	e, ok := err.(*googleapi.Error)
 	return ok && e.Code == http.StatusNotFound
 }
+
+func isAlreadyExists(err error) bool {
+	e, ok := err.(*googleapi.Error)
+	return ok && e.Code == http.StatusConflict
+}
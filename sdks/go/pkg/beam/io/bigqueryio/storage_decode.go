@@ -0,0 +1,471 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/linkedin/goavro/v2"
+)
+
+// This file decodes the rows read with the Storage Read API into values of
+// Go types. Rows are first decoded into a slice of the values of their
+// fields: int64, float64, bool, string, []byte, time.Time, civil.Date,
+// civil.Time, civil.DateTime and *big.Rat values, slices of values for
+// repeated fields and slices of field values for records, and nil for nulls.
+
+// rowDecoder decodes the serialized rows of a read session.
+type rowDecoder interface {
+	// fields returns the fields of the rows.
+	fields() []field
+	// decode returns the rows of serialized rows.
+	decode(data []byte) ([][]any, error)
+}
+
+// arrowDecoder decodes Arrow record batches.
+type arrowDecoder struct {
+	schema []byte
+	fs     []field
+}
+
+func newArrowDecoder(schema []byte) (*arrowDecoder, error) {
+	r, err := ipc.NewReader(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid arrow schema: %v", err)
+	}
+	defer r.Release()
+	fs, err := arrowFields(r.Schema().Fields())
+	if err != nil {
+		return nil, err
+	}
+	return &arrowDecoder{schema: schema, fs: fs}, nil
+}
+
+func (d *arrowDecoder) fields() []field {
+	return d.fs
+}
+
+func (d *arrowDecoder) decode(data []byte) ([][]any, error) {
+	// A serialized record batch is the message following the schema in an
+	// Arrow IPC stream.
+	r, err := ipc.NewReader(io.MultiReader(bytes.NewReader(d.schema), bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Release()
+
+	var rows [][]any
+	for r.Next() {
+		rec := r.Record()
+		start := len(rows)
+		for i := int64(0); i < rec.NumRows(); i++ {
+			rows = append(rows, make([]any, rec.NumCols()))
+		}
+		for j, col := range rec.Columns() {
+			for i := range rows[start:] {
+				v, err := arrowValue(col, i, d.fs[j])
+				if err != nil {
+					return nil, fmt.Errorf("field %v: %v", d.fs[j].Name, err)
+				}
+				rows[start+i][j] = v
+			}
+		}
+	}
+	if err := r.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func arrowValue(arr arrow.Array, i int, f field) (any, error) {
+	if arr.IsNull(i) {
+		return nil, nil
+	}
+	switch a := arr.(type) {
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.String:
+		return a.Value(i), nil
+	case *array.Binary:
+		return append([]byte(nil), a.Value(i)...), nil
+	case *array.Timestamp:
+		t := a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+		if f.Type == dateTimeType {
+			return civil.DateTimeOf(t), nil
+		}
+		return t, nil
+	case *array.Date32:
+		return civil.DateOf(a.Value(i).ToTime()), nil
+	case *array.Time64:
+		return civil.TimeOf(a.Value(i).ToTime(a.DataType().(*arrow.Time64Type).Unit)), nil
+	case *array.Decimal128:
+		return decimal(a.Value(i).BigInt(), f.Scale), nil
+	case *array.Decimal256:
+		return decimal(a.Value(i).BigInt(), f.Scale), nil
+	case *array.List:
+		start, end := a.ValueOffsets(i)
+		elem := f
+		elem.Repeated = false
+		list := make([]any, 0, end-start)
+		for k := int(start); k < int(end); k++ {
+			v, err := arrowValue(a.ListValues(), k, elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case *array.Struct:
+		record := make([]any, a.NumField())
+		for j := range record {
+			v, err := arrowValue(a.Field(j), i, f.Fields[j])
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %v", f.Fields[j].Name, err)
+			}
+			record[j] = v
+		}
+		return record, nil
+	default:
+		return nil, fmt.Errorf("unsupported arrow type %v", arr.DataType())
+	}
+}
+
+// decimal returns the value of an unscaled decimal.
+func decimal(n *big.Int, scale int) *big.Rat {
+	return new(big.Rat).SetFrac(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+}
+
+// avroDecoder decodes Avro rows.
+type avroDecoder struct {
+	codec *goavro.Codec
+	fs    []field
+}
+
+func newAvroDecoder(schema string) (*avroDecoder, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+	fs, err := avroFields(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &avroDecoder{codec: codec, fs: fs}, nil
+}
+
+func (d *avroDecoder) fields() []field {
+	return d.fs
+}
+
+func (d *avroDecoder) decode(data []byte) ([][]any, error) {
+	var rows [][]any
+	for len(data) > 0 {
+		native, rest, err := d.codec.NativeFromBinary(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		row, err := avroRecord(native, d.fs)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func avroRecord(native any, fs []field) ([]any, error) {
+	m, ok := native.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid avro record %v", native)
+	}
+	record := make([]any, len(fs))
+	for j, f := range fs {
+		v, err := avroValue(m[f.Name], f)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", f.Name, err)
+		}
+		record[j] = v
+	}
+	return record, nil
+}
+
+func avroValue(native any, f field) (any, error) {
+	// Values of unions are maps from their type to the value.
+	if m, ok := native.(map[string]any); ok && f.Type != recordType || ok && len(m) == 1 && f.Nullable {
+		for _, v := range m {
+			native = v
+		}
+	}
+	if native == nil {
+		return nil, nil
+	}
+	if f.Repeated {
+		items, ok := native.([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid avro array %v", native)
+		}
+		elem := f
+		elem.Repeated = false
+		list := make([]any, len(items))
+		for k, item := range items {
+			v, err := avroValue(item, elem)
+			if err != nil {
+				return nil, err
+			}
+			list[k] = v
+		}
+		return list, nil
+	}
+
+	switch v := native.(type) {
+	case time.Time:
+		switch f.Type {
+		case dateType:
+			return civil.DateOf(v), nil
+		case timestampType:
+			return v.UTC(), nil
+		}
+	case time.Duration:
+		if f.Type == timeType {
+			return civil.TimeOf(time.Unix(0, 0).UTC().Add(v)), nil
+		}
+	case string:
+		if f.Type == dateTimeType {
+			return civil.ParseDateTime(strings.Replace(v, " ", "T", 1))
+		}
+		return v, nil
+	case map[string]any:
+		return avroRecord(v, f.Fields)
+	case int64, float64, bool, []byte, *big.Rat:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported avro value %v of type %T", native, native)
+}
+
+// rowLoader loads decoded rows into values of a struct type.
+type rowLoader struct {
+	t reflect.Type
+	// indices caches the indices of the struct fields matching the fields of
+	// records, or -1 for fields without a matching struct field.
+	indices map[loaderKey][]int
+}
+
+type loaderKey struct {
+	t      reflect.Type
+	fields *field
+}
+
+func newRowLoader(t reflect.Type) *rowLoader {
+	return &rowLoader{t: t, indices: make(map[loaderKey][]int)}
+}
+
+// load returns a decoded row as a value of the type of the loader.
+func (l *rowLoader) load(row []any, fs []field) (any, error) {
+	v := reflect.New(l.t).Elem()
+	if err := l.setRecord(v, row, fs); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// structFields returns the index of the field of t matching each field, by
+// the name in its bigquery tag, its beam tag or its name ignoring case.
+func (l *rowLoader) structFields(t reflect.Type, fs []field) []int {
+	if len(fs) == 0 {
+		return nil
+	}
+	key := loaderKey{t, &fs[0]}
+	if indices, ok := l.indices[key]; ok {
+		return indices
+	}
+	indices := make([]int, len(fs))
+	for j, f := range fs {
+		indices[j] = -1
+		for _, tag := range []string{"bigquery", "beam"} {
+			for i := 0; i < t.NumField() && indices[j] < 0; i++ {
+				name, _, _ := strings.Cut(t.Field(i).Tag.Get(tag), ",")
+				if name == f.Name && t.Field(i).IsExported() {
+					indices[j] = i
+				}
+			}
+		}
+		for i := 0; i < t.NumField() && indices[j] < 0; i++ {
+			sf := t.Field(i)
+			if sf.IsExported() && sf.Tag.Get("bigquery") != "-" && strings.EqualFold(sf.Name, f.Name) {
+				indices[j] = i
+			}
+		}
+	}
+	l.indices[key] = indices
+	return indices
+}
+
+func (l *rowLoader) setRecord(v reflect.Value, record []any, fs []field) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("can't set %v from a record", v.Type())
+	}
+	for j, i := range l.structFields(v.Type(), fs) {
+		if i < 0 {
+			continue
+		}
+		if err := l.setValue(v.Field(i), record[j], fs[j]); err != nil {
+			return fmt.Errorf("field %v: %v", fs[j].Name, err)
+		}
+	}
+	return nil
+}
+
+// setValue sets v to the decoded value of a field. Nulls are nil pointers,
+// invalid bigquery.Null* values or zero values.
+func (l *rowLoader) setValue(v reflect.Value, val any, f field) error {
+	if f.Repeated {
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 && f.Type == bytesType && !isBytesSlice(v.Type()) {
+			return fmt.Errorf("can't set %v from a repeated field", v.Type())
+		}
+		items, _ := val.([]any)
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		elem := f
+		elem.Repeated = false
+		for k, item := range items {
+			if err := l.setValue(s.Index(k), item, elem); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	if val == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && v.Type() != bigRatType {
+		p := reflect.New(v.Type().Elem())
+		if err := l.setValue(p.Elem(), val, f); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if f.Type == recordType {
+		record, _ := val.([]any)
+		return l.setRecord(v, record, f.Fields)
+	}
+	if valid, ok := nullValid(v); ok {
+		// bigquery.Null* types hold the value in their first field.
+		if err := setScalar(v.Field(0), val, f); err != nil {
+			return err
+		}
+		valid.SetBool(true)
+		return nil
+	}
+	return setScalar(v, val, f)
+}
+
+func isBytesSlice(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Slice && t.Elem().Elem().Kind() == reflect.Uint8
+}
+
+// nullValid returns the Valid field of a value of a bigquery.Null* type.
+func nullValid(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct || v.NumField() != 2 || v.Type().Field(1).Name != "Valid" || v.Field(1).Kind() != reflect.Bool {
+		return reflect.Value{}, false
+	}
+	return v.Field(1), true
+}
+
+func setScalar(v reflect.Value, val any, f field) error {
+	switch x := val.(type) {
+	case int64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(x)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.SetUint(uint64(x))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(x))
+			return nil
+		}
+	case float64:
+		if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			v.SetFloat(x)
+			return nil
+		}
+	case bool:
+		if v.Kind() == reflect.Bool {
+			v.SetBool(x)
+			return nil
+		}
+	case string:
+		if v.Kind() == reflect.String {
+			v.SetString(x)
+			return nil
+		}
+	case []byte:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(x)
+			return nil
+		}
+	case time.Time:
+		if v.Type() == timeTimeType {
+			v.Set(reflect.ValueOf(x))
+			return nil
+		}
+	case civil.Date, civil.Time, civil.DateTime:
+		switch {
+		case v.Type() == civilTypes[f.Type]:
+			v.Set(reflect.ValueOf(x))
+			return nil
+		case v.Kind() == reflect.String:
+			v.SetString(fmt.Sprint(x))
+			return nil
+		}
+	case *big.Rat:
+		switch {
+		case v.Type() == bigRatType:
+			v.Set(reflect.ValueOf(x))
+			return nil
+		case v.Type() == bigRatType.Elem():
+			v.Set(reflect.ValueOf(*x))
+			return nil
+		case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+			fv, _ := x.Float64()
+			v.SetFloat(fv)
+			return nil
+		case v.Kind() == reflect.String:
+			v.SetString(x.FloatString(f.Scale))
+			return nil
+		}
+	}
+	return fmt.Errorf("can't set %v from %v", v.Type(), val)
+}
@@ -0,0 +1,167 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+var testFields = []field{
+	{Name: "id", Type: intType},
+	{Name: "name", Type: stringType, Nullable: true},
+	{Name: "scores", Type: floatType, Repeated: true},
+	{Name: "created", Type: timestampType},
+	{Name: "day", Type: dateType, Nullable: true},
+	{Name: "at", Type: dateTimeType},
+	{Name: "price", Type: numericType, Scale: 2},
+	{Name: "data", Type: bytesType, Nullable: true},
+	{Name: "item-count", Type: recordType, Fields: []field{{Name: "n", Type: intType}}},
+}
+
+func TestAvroFields(t *testing.T) {
+	schema := `{"type": "record", "name": "__root__", "fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": ["null", "string"]},
+		{"name": "scores", "type": {"type": "array", "items": "double"}},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "day", "type": ["null", {"type": "int", "logicalType": "date"}]},
+		{"name": "at", "type": {"type": "string", "sqlType": "DATETIME"}},
+		{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 38, "scale": 2}},
+		{"name": "data", "type": ["null", "bytes"]},
+		{"name": "item-count", "type": {"type": "record", "name": "item", "fields": [{"name": "n", "type": "long"}]}}
+	]}`
+	got, err := avroFields(schema)
+	if err != nil {
+		t.Fatalf("avroFields() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, testFields) {
+		t.Errorf("avroFields() = %+v, want %+v", got, testFields)
+	}
+
+	if _, err := avroFields(`{"fields": [{"name": "u", "type": ["null", "long", "string"]}]}`); err == nil {
+		t.Error("avroFields() of a union of several types succeeded, want error")
+	}
+}
+
+func TestArrowFields(t *testing.T) {
+	fs := []arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "scores", Type: arrow.ListOf(arrow.PrimitiveTypes.Float64), Nullable: true},
+		{Name: "created", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Microsecond}},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 38, Scale: 2}},
+		{Name: "data", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "item-count", Type: arrow.StructOf(arrow.Field{Name: "n", Type: arrow.PrimitiveTypes.Int64})},
+	}
+	got, err := arrowFields(fs)
+	if err != nil {
+		t.Fatalf("arrowFields() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, testFields) {
+		t.Errorf("arrowFields() = %+v, want %+v", got, testFields)
+	}
+}
+
+func TestRowType(t *testing.T) {
+	want := reflect.TypeOf(struct {
+		Id         int64     `beam:"id" bigquery:"id"`
+		Name       *string   `beam:"name" bigquery:"name"`
+		Scores     []float64 `beam:"scores" bigquery:"scores"`
+		Created    time.Time `beam:"created" bigquery:"created"`
+		Day        *string   `beam:"day" bigquery:"day"`
+		At         string    `beam:"at" bigquery:"at"`
+		Price      string    `beam:"price" bigquery:"price"`
+		Data       []byte    `beam:"data" bigquery:"data"`
+		Item_count struct {
+			N int64 `beam:"n" bigquery:"n"`
+		} `beam:"item_count" bigquery:"item-count"`
+	}{})
+	if got := rowType(testFields); got != want {
+		t.Errorf("rowType() = %v, want %v", got, want)
+	}
+}
+
+func TestRowLoader(t *testing.T) {
+	type item struct {
+		N int
+	}
+	type row struct {
+		ID      int64 `bigquery:"id"`
+		Name    bigquery.NullString
+		Scores  []float32
+		Created time.Time
+		Day     bigquery.NullDate `bigquery:"day"`
+		At      string
+		Price   *big.Rat
+		Data    []byte
+		Count   item   `bigquery:"item-count"`
+		Skipped string `bigquery:"-"`
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	values := []any{
+		int64(1),
+		"a",
+		[]any{1.5, 2.5},
+		created,
+		civil.Date{Year: 2024, Month: 1, Day: 2},
+		civil.DateTime{Date: civil.Date{Year: 2024, Month: 1, Day: 2}, Time: civil.Time{Hour: 3}},
+		big.NewRat(314, 100),
+		nil,
+		[]any{int64(7)},
+	}
+	want := row{
+		ID:      1,
+		Name:    bigquery.NullString{StringVal: "a", Valid: true},
+		Scores:  []float32{1.5, 2.5},
+		Created: created,
+		Day:     bigquery.NullDate{Date: civil.Date{Year: 2024, Month: 1, Day: 2}, Valid: true},
+		At:      "2024-01-02T03:00:00",
+		Price:   big.NewRat(314, 100),
+		Count:   item{N: 7},
+	}
+
+	l := newRowLoader(reflect.TypeOf(row{}))
+	got, err := l.load(values, testFields)
+	if err != nil {
+		t.Fatalf("load() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("load() = %+v, want %+v", got, want)
+	}
+
+	values[1], values[4] = nil, nil
+	want.Name, want.Day = bigquery.NullString{}, bigquery.NullDate{}
+	if got, err := l.load(values, testFields); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("load() of nulls = %+v, %v, want %+v", got, err, want)
+	}
+
+	type invalid struct {
+		ID time.Time `bigquery:"id"`
+	}
+	if _, err := newRowLoader(reflect.TypeOf(invalid{})).load(values, testFields); err == nil {
+		t.Error("load() of an int64 into a time.Time succeeded, want error")
+	}
+}
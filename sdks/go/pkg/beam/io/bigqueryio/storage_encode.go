@@ -0,0 +1,275 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// This file encodes the rows written with the Storage Write API as protocol
+// buffer messages, described by a descriptor derived from the schema of the
+// rows. TIMESTAMP values are int64 microseconds and DATE values int32 days
+// since the epoch, while TIME, DATETIME and numeric values are strings.
+
+// rowEncoder encodes values of a struct type as protocol buffer messages.
+type rowEncoder struct {
+	schema     bigquery.Schema
+	descriptor *descriptorpb.DescriptorProto
+	message    protoreflect.MessageDescriptor
+	// indices caches the index paths of the struct fields of each schema
+	// field, by struct type.
+	indices map[reflect.Type][][]int
+}
+
+func newRowEncoder(t reflect.Type) (*rowEncoder, error) {
+	schema, err := bigquery.InferSchema(reflect.Zero(t).Interface())
+	if err != nil {
+		return nil, err
+	}
+	dp := messageDescriptor("root", ".root", schema)
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("root.proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor of %v: %v", t, err)
+	}
+	return &rowEncoder{
+		schema:     schema,
+		descriptor: dp,
+		message:    fd.Messages().Get(0),
+		indices:    make(map[reflect.Type][][]int),
+	}, nil
+}
+
+// messageDescriptor returns a descriptor of the rows of a schema, with a
+// nested message type for each record field. The full name of the message
+// is its scope.
+func messageDescriptor(name, scope string, schema bigquery.Schema) *descriptorpb.DescriptorProto {
+	dp := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for i, f := range schema {
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(f.Name),
+			Number: proto.Int32(int32(i + 1)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if f.Repeated {
+			fdp.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}
+		switch f.Type {
+		case bigquery.IntegerFieldType, bigquery.TimestampFieldType:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		case bigquery.DateFieldType:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()
+		case bigquery.FloatFieldType:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+		case bigquery.BooleanFieldType:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+		case bigquery.BytesFieldType:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()
+		case bigquery.RecordFieldType:
+			name := fmt.Sprintf("F%d", i+1)
+			dp.NestedType = append(dp.NestedType, messageDescriptor(name, scope+"."+name, f.Schema))
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			fdp.TypeName = proto.String(scope + "." + name)
+		default:
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		}
+		dp.Field = append(dp.Field, fdp)
+	}
+	return dp
+}
+
+// encode returns the serialized message of a value.
+func (e *rowEncoder) encode(v any) ([]byte, error) {
+	m := dynamicpb.NewMessage(e.message)
+	if err := e.setMessage(m, reflect.ValueOf(v), e.schema); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(m)
+}
+
+// structFields returns the index paths of the fields of t named after the
+// schema fields, by their bigquery tag or their name.
+func (e *rowEncoder) structFields(t reflect.Type, schema bigquery.Schema) [][]int {
+	if indices, ok := e.indices[t]; ok {
+		return indices
+	}
+	indices := make([][]int, len(schema))
+	for _, sf := range reflect.VisibleFields(t) {
+		if sf.Anonymous || !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get(bigQueryTag), ",")
+		if name == "" {
+			name = sf.Name
+		}
+		for i, f := range schema {
+			if indices[i] == nil && strings.EqualFold(f.Name, name) {
+				indices[i] = sf.Index
+			}
+		}
+	}
+	e.indices[t] = indices
+	return indices
+}
+
+func (e *rowEncoder) setMessage(m protoreflect.Message, v reflect.Value, schema bigquery.Schema) error {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("can't encode %v as a record", v.Type())
+	}
+	fields := m.Descriptor().Fields()
+	for i, index := range e.structFields(v.Type(), schema) {
+		if index == nil {
+			continue
+		}
+		if err := e.setField(m, fields.Get(i), v.FieldByIndex(index), schema[i]); err != nil {
+			return fmt.Errorf("field %v: %v", schema[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (e *rowEncoder) setField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v reflect.Value, f *bigquery.FieldSchema) error {
+	if f.Repeated {
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("can't encode %v as a repeated field", v.Type())
+		}
+		list := m.Mutable(fd).List()
+		for i := 0; i < v.Len(); i++ {
+			if f.Type == bigquery.RecordFieldType {
+				elem := list.NewElement()
+				if err := e.setMessage(elem.Message(), v.Index(i), f.Schema); err != nil {
+					return err
+				}
+				list.Append(elem)
+				continue
+			}
+			val, err := scalarValue(v.Index(i), f)
+			if err != nil {
+				return err
+			}
+			if val.IsValid() {
+				list.Append(val)
+			}
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type() != bigRatType {
+			v = v.Elem()
+		}
+	}
+	if f.Type == bigquery.RecordFieldType {
+		return e.setMessage(m.Mutable(fd).Message(), v, f.Schema)
+	}
+	val, err := scalarValue(v, f)
+	if err != nil {
+		return err
+	}
+	if val.IsValid() {
+		m.Set(fd, val)
+	}
+	return nil
+}
+
+// scalarValue returns the value of a field, or an invalid value for nulls.
+func scalarValue(v reflect.Value, f *bigquery.FieldSchema) (protoreflect.Value, error) {
+	if valid, ok := nullValid(v); ok {
+		// bigquery.Null* types hold the value in their first field.
+		if !valid.Bool() {
+			return protoreflect.Value{}, nil
+		}
+		v = v.Field(0)
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return protoreflect.Value{}, nil
+	}
+
+	switch f.Type {
+	case bigquery.IntegerFieldType:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return protoreflect.ValueOfInt64(v.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			return protoreflect.ValueOfInt64(int64(v.Uint())), nil
+		}
+	case bigquery.FloatFieldType:
+		if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			return protoreflect.ValueOfFloat64(v.Float()), nil
+		}
+	case bigquery.BooleanFieldType:
+		if v.Kind() == reflect.Bool {
+			return protoreflect.ValueOfBool(v.Bool()), nil
+		}
+	case bigquery.StringFieldType, bigquery.GeographyFieldType, bigquery.JSONFieldType:
+		if v.Kind() == reflect.String {
+			return protoreflect.ValueOfString(v.String()), nil
+		}
+	case bigquery.BytesFieldType:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.IsNil() {
+				return protoreflect.Value{}, nil
+			}
+			return protoreflect.ValueOfBytes(v.Bytes()), nil
+		}
+	case bigquery.TimestampFieldType:
+		if t, ok := v.Interface().(time.Time); ok {
+			return protoreflect.ValueOfInt64(t.UnixMicro()), nil
+		}
+	case bigquery.DateFieldType:
+		if d, ok := v.Interface().(civil.Date); ok {
+			return protoreflect.ValueOfInt32(int32(d.DaysSince(civil.Date{Year: 1970, Month: 1, Day: 1}))), nil
+		}
+	case bigquery.TimeFieldType:
+		if t, ok := v.Interface().(civil.Time); ok {
+			return protoreflect.ValueOfString(bigquery.CivilTimeString(t)), nil
+		}
+	case bigquery.DateTimeFieldType:
+		if dt, ok := v.Interface().(civil.DateTime); ok {
+			return protoreflect.ValueOfString(bigquery.CivilDateTimeString(dt)), nil
+		}
+	case bigquery.NumericFieldType:
+		if r, ok := v.Interface().(*big.Rat); ok {
+			return protoreflect.ValueOfString(bigquery.NumericString(r)), nil
+		}
+	case bigquery.BigNumericFieldType:
+		if r, ok := v.Interface().(*big.Rat); ok {
+			return protoreflect.ValueOfString(bigquery.BigNumericString(r)), nil
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("can't encode %v as %v", v.Type(), f.Type)
+}
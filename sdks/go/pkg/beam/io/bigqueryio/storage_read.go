@@ -0,0 +1,395 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	storage "cloud.google.com/go/bigquery/storage/apiv1"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"google.golang.org/api/option"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*createSessionFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*readStreamFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*readStream)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*streamRestriction)(nil)).Elem())
+}
+
+// storageClientOptions are the options of the Storage API clients, which
+// can be set to use another endpoint.
+var storageClientOptions []option.ClientOption
+
+// readStorageOptions represents additional options for reading with the
+// Storage Read API.
+type readStorageOptions struct {
+	// SelectedFields are the names of the fields to read, or all fields if empty.
+	SelectedFields []string `json:"selectedFields"`
+	// RowRestriction is a SQL filter on the rows to read.
+	RowRestriction string `json:"rowRestriction"`
+	// MaxStreams is the maximum number of streams to read in parallel, or 0
+	// to let the service choose.
+	MaxStreams int `json:"maxStreams"`
+	// Avro reads the rows as Avro instead of Arrow.
+	Avro bool `json:"avro"`
+}
+
+// ReadStorageOption represents a function that sets options for reading with
+// the Storage Read API.
+type ReadStorageOption func(*readStorageOptions) error
+
+// WithSelectedFields reads only the given fields of the table. Nested fields
+// are selected with their dotted path.
+func WithSelectedFields(fields ...string) ReadStorageOption {
+	return func(o *readStorageOptions) error {
+		o.SelectedFields = append(o.SelectedFields, fields...)
+		return nil
+	}
+}
+
+// WithRowRestriction reads only the rows matching a SQL filter, such as
+// "age > 18 AND country = 'NL'".
+func WithRowRestriction(restriction string) ReadStorageOption {
+	return func(o *readStorageOptions) error {
+		o.RowRestriction = restriction
+		return nil
+	}
+}
+
+// WithMaxStreams sets the maximum number of streams the table is initially
+// split into. The service may return fewer streams.
+func WithMaxStreams(n int) ReadStorageOption {
+	return func(o *readStorageOptions) error {
+		if n < 0 {
+			return fmt.Errorf("invalid number of streams: %v", n)
+		}
+		o.MaxStreams = n
+		return nil
+	}
+}
+
+// WithAvro reads the rows serialized as Avro instead of Arrow.
+func WithAvro() ReadStorageOption {
+	return func(o *readStorageOptions) error {
+		o.Avro = true
+		return nil
+	}
+}
+
+// ReadStorage reads the rows of the given table with the BigQuery Storage
+// Read API and returns them as a PCollection<t>. The read session is created
+// when the pipeline runs, and its streams are read in parallel and split
+// dynamically as the runner requests it.
+//
+// Fields of t are matched with the fields of the rows by their bigquery tag,
+// their beam tag or their name ignoring case, and fields of the rows with no
+// matching field are dropped. Nullable fields may be pointers or
+// bigquery.Null* types, DATE, TIME and DATETIME fields civil types or strings,
+// and NUMERIC and BIGNUMERIC fields *big.Rat values, floats or strings.
+//
+// If t is nil, the type is inferred from the schema of a read session when
+// the pipeline is constructed, and the rows are returned as schema rows:
+// structs with a field for each field of the rows, with beam and bigquery
+// tags naming them after it, where nullable fields are pointers, repeated
+// fields slices and records nested rows. DATE, TIME, DATETIME and numeric
+// fields are strings.
+func ReadStorage(s beam.Scope, project, table string, t reflect.Type, options ...ReadStorageOption) beam.PCollection {
+	qn := mustParseTable(table)
+
+	s = s.Scope("bigquery.ReadStorage")
+
+	opts := readStorageOptions{}
+	for _, opt := range options {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+
+	if t == nil {
+		var err error
+		if t, err = inferStorageType(context.Background(), project, qn, opts); err != nil {
+			panic(fmt.Sprintf("bigqueryio.ReadStorage: %v", err))
+		}
+	} else {
+		if t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("schema type must be struct: %v", t))
+		}
+		checkTypeRegistered(t)
+	}
+
+	imp := beam.Impulse(s)
+	streams := beam.ParDo(s, &createSessionFn{Project: project, Table: qn, Options: opts}, imp)
+	streams = beam.Reshuffle(s, streams)
+	return beam.ParDo(s, &readStreamFn{Type: beam.EncodedType{T: t}}, streams, beam.TypeDefinition{Var: beam.XType, T: t})
+}
+
+// inferStorageType returns the row type of a read session of the table.
+func inferStorageType(ctx context.Context, project string, table QualifiedTableName, opts readStorageOptions) (reflect.Type, error) {
+	client, err := storage.NewBigQueryReadClient(ctx, storageClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	opts.MaxStreams = 1
+	session, err := createReadSession(ctx, client, project, table, opts)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := newRowDecoder(sessionSchema(session), opts.Avro)
+	if err != nil {
+		return nil, err
+	}
+	return rowType(dec.fields()), nil
+}
+
+func createReadSession(ctx context.Context, client *storage.BigQueryReadClient, project string, table QualifiedTableName, opts readStorageOptions) (*storagepb.ReadSession, error) {
+	format := storagepb.DataFormat_ARROW
+	if opts.Avro {
+		format = storagepb.DataFormat_AVRO
+	}
+	return client.CreateReadSession(ctx, &storagepb.CreateReadSessionRequest{
+		Parent: fmt.Sprintf("projects/%v", project),
+		ReadSession: &storagepb.ReadSession{
+			Table:      fmt.Sprintf("projects/%v/datasets/%v/tables/%v", table.Project, table.Dataset, table.Table),
+			DataFormat: format,
+			ReadOptions: &storagepb.ReadSession_TableReadOptions{
+				SelectedFields: opts.SelectedFields,
+				RowRestriction: opts.RowRestriction,
+			},
+		},
+		MaxStreamCount: int32(opts.MaxStreams),
+	})
+}
+
+// sessionSchema returns the serialized Arrow schema or the Avro schema of a
+// read session.
+func sessionSchema(session *storagepb.ReadSession) []byte {
+	if avro := session.GetAvroSchema(); avro != nil {
+		return []byte(avro.GetSchema())
+	}
+	return session.GetArrowSchema().GetSerializedSchema()
+}
+
+func newRowDecoder(schema []byte, avro bool) (rowDecoder, error) {
+	if avro {
+		return newAvroDecoder(string(schema))
+	}
+	return newArrowDecoder(schema)
+}
+
+// readStream is a stream of a read session.
+type readStream struct {
+	// Stream is the name of the stream.
+	Stream string
+	// Schema is the serialized Arrow schema or the Avro schema of the rows.
+	Schema []byte
+	// Avro is whether the rows are serialized as Avro.
+	Avro bool
+	// Rows is the estimated number of rows of the stream.
+	Rows int64
+}
+
+type createSessionFn struct {
+	// Project is the project billed for the read.
+	Project string `json:"project"`
+	// Table is the qualified table identifier.
+	Table QualifiedTableName `json:"table"`
+	// Options specifies additional read options.
+	Options readStorageOptions `json:"options"`
+}
+
+func (f *createSessionFn) ProcessElement(ctx context.Context, _ []byte, emit func(readStream)) error {
+	client, err := storage.NewBigQueryReadClient(ctx, storageClientOptions...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	session, err := createReadSession(ctx, client, f.Project, f.Table, f.Options)
+	if err != nil {
+		return err
+	}
+	schema := sessionSchema(session)
+	for _, stream := range session.GetStreams() {
+		emit(readStream{
+			Stream: stream.GetName(),
+			Schema: schema,
+			Avro:   f.Options.Avro,
+			Rows:   session.GetEstimatedRowCount() / int64(len(session.GetStreams())),
+		})
+	}
+	return nil
+}
+
+// readStreamFn is an SDF that reads the rows of a stream. Its restriction
+// is a range of row offsets of the stream, which is split dynamically by
+// splitting the stream.
+type readStreamFn struct {
+	// Type is the encoded schema type.
+	Type beam.EncodedType `json:"type"`
+
+	client *storage.BigQueryReadClient
+	loader *rowLoader
+	// decoders are the decoders of the schemas of the streams.
+	decoders map[string]rowDecoder
+}
+
+func (f *readStreamFn) Setup(ctx context.Context) error {
+	client, err := storage.NewBigQueryReadClient(ctx, storageClientOptions...)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	f.loader = newRowLoader(f.Type.T)
+	f.decoders = make(map[string]rowDecoder)
+	return nil
+}
+
+func (f *readStreamFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
+
+// CreateInitialRestriction returns an unbounded restriction of the stream.
+func (f *readStreamFn) CreateInitialRestriction(stream readStream) streamRestriction {
+	return streamRestriction{Stream: stream.Stream, End: -1}
+}
+
+// SplitRestriction returns the restriction, as the streams of the session
+// are the initial splits.
+func (f *readStreamFn) SplitRestriction(_ readStream, rest streamRestriction) []streamRestriction {
+	return []streamRestriction{rest}
+}
+
+// RestrictionSize returns the number of rows of the restriction, or an
+// estimate of it for unbounded restrictions.
+func (f *readStreamFn) RestrictionSize(stream readStream, rest streamRestriction) float64 {
+	if rest.End >= 0 {
+		return float64(rest.End - rest.Offset)
+	}
+	if n := stream.Rows - rest.Offset; n > 0 && rest.Stream == stream.Stream {
+		return float64(n)
+	}
+	return 1
+}
+
+// CreateTracker creates a tracker that splits the stream of the restriction
+// with the Storage Read API.
+func (f *readStreamFn) CreateTracker(rest streamRestriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(newStreamTracker(rest, f.splitStream))
+}
+
+func (f *readStreamFn) splitStream(stream string, fraction float64) (string, string, error) {
+	if f.client == nil {
+		return "", "", fmt.Errorf("client is not set up")
+	}
+	resp, err := f.client.SplitReadStream(context.Background(), &storagepb.SplitReadStreamRequest{Name: stream, Fraction: fraction})
+	if err != nil {
+		return "", "", err
+	}
+	return resp.GetPrimaryStream().GetName(), resp.GetRemainderStream().GetName(), nil
+}
+
+func (f *readStreamFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, stream readStream, emit func(beam.X)) error {
+	dec, ok := f.decoders[string(stream.Schema)]
+	if !ok {
+		var err error
+		if dec, err = newRowDecoder(stream.Schema, stream.Avro); err != nil {
+			return err
+		}
+		f.decoders[string(stream.Schema)] = dec
+	}
+
+	rest := rt.GetRestriction().(streamRestriction)
+	name, offset := rest.Stream, rest.Offset
+	for {
+		next, err := f.readRows(ctx, rt, dec, name, &offset, emit)
+		if err != nil || next == "" {
+			return err
+		}
+		// The stream was split, so the rest of the rows of the restriction
+		// are read from its primary stream.
+		name = next
+	}
+}
+
+// readRows reads the rows of a stream from the offset, until the end of the
+// stream or of the restriction, or until the stream of the restriction is
+// split, in which case it returns the name of the primary stream.
+func (f *readStreamFn) readRows(ctx context.Context, rt *sdf.LockRTracker, dec rowDecoder, name string, offset *int64, emit func(beam.X)) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, err := f.client.ReadRows(ctx, &storagepb.ReadRowsRequest{ReadStream: name, Offset: *offset})
+	if err != nil {
+		return "", err
+	}
+	for {
+		resp, err := rows.Recv()
+		if err == io.EOF {
+			rt.TryClaim(streamEnd{})
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// The stream may have been split before the rows of the response, in
+		// which case they're read again from the primary stream. Otherwise
+		// it's split after them at the earliest.
+		rt.Mu.Lock()
+		tracker := rt.Rt.(*streamTracker)
+		if s := tracker.GetRestriction().(streamRestriction).Stream; s != name {
+			rt.Mu.Unlock()
+			return s, nil
+		}
+		tracker.setProgress(resp.GetStats().GetProgress().GetAtResponseEnd())
+		rt.Mu.Unlock()
+
+		data := resp.GetArrowRecordBatch().GetSerializedRecordBatch()
+		if avro := resp.GetAvroRows(); avro != nil {
+			data = avro.GetSerializedBinaryRows()
+		}
+		batch, err := dec.decode(data)
+		if err != nil {
+			return "", fmt.Errorf("stream %v at offset %v: %v", name, *offset, err)
+		}
+		for _, row := range batch {
+			if !rt.TryClaim(*offset) {
+				return "", nil
+			}
+			v, err := f.loader.load(row, dec.fields())
+			if err != nil {
+				return "", fmt.Errorf("stream %v at offset %v: %v", name, *offset, err)
+			}
+			emit(v)
+			*offset++
+		}
+
+		if s := rt.GetRestriction().(streamRestriction).Stream; s != name {
+			return s, nil
+		}
+	}
+}
@@ -0,0 +1,652 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/civil"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/decimal128"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*storageRow)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*storageAddress)(nil)).Elem())
+	beam.RegisterFunction(toJSON)
+}
+
+var (
+	fake      *fakeReadServer
+	fakesOnce sync.Once
+)
+
+// startFakes starts the fake servers of the Storage Read and Write APIs and
+// of the tables they serve, and points the clients of the package at them.
+// The servers are shared by the tests, which reset them as they need. It also
+// initializes Beam to run pipelines, after which types can't be registered,
+// so it's called by the tests that need it rather than from TestMain, leaving
+// the other tests of the package to run uninitialized.
+func startFakes(t *testing.T) {
+	t.Helper()
+
+	fakesOnce.Do(func() {
+		beam.Init()
+
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		srv := grpc.NewServer()
+		fake = &fakeReadServer{}
+		storagepb.RegisterBigQueryReadServer(srv, fake)
+		fakeWrite = newFakeWriteServer()
+		storagepb.RegisterBigQueryWriteServer(srv, fakeWrite)
+		go srv.Serve(lis)
+
+		rest := httptest.NewServer(fakeWrite)
+		bigqueryClientOptions = []option.ClientOption{
+			option.WithEndpoint(rest.URL + "/"),
+			option.WithoutAuthentication(),
+		}
+
+		storageClientOptions = []option.ClientOption{
+			option.WithEndpoint(lis.Addr().String()),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		}
+	})
+	if fake == nil {
+		t.Fatal("fake servers failed to start")
+	}
+}
+
+type storageAddress struct {
+	City *string `bigquery:"city"`
+}
+
+type storageRow struct {
+	Name    string         `bigquery:"name"`
+	Age     *int64         `bigquery:"age"`
+	Tags    []string       `bigquery:"tags"`
+	Created time.Time      `bigquery:"created"`
+	Birth   string         `bigquery:"birth"`
+	Balance string         `bigquery:"balance"`
+	Address storageAddress `bigquery:"address"`
+}
+
+// fakeColumn is a column of the table of the fake server.
+type fakeColumn struct {
+	arrow arrow.Field
+	avro  string
+	// values are the values of the column, appended to Arrow builders.
+	values []any
+	// toAvro converts values to Avro native values.
+	toAvro func(v any) any
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func date(s string) civil.Date {
+	d, err := civil.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func rat(s string) *big.Rat {
+	r, _ := new(big.Rat).SetString(s)
+	return r
+}
+
+var created = time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+func union(branch string) func(v any) any {
+	return func(v any) any {
+		if v == nil {
+			return nil
+		}
+		return goavro.Union(branch, v)
+	}
+}
+
+func identity(v any) any {
+	return v
+}
+
+// testColumns returns the columns of a table of 5 rows.
+func testColumns() []fakeColumn {
+	return []fakeColumn{
+		{
+			arrow:  arrow.Field{Name: "name", Type: arrow.BinaryTypes.String},
+			avro:   `"string"`,
+			values: []any{"a", "b", "c", "d", "e"},
+			toAvro: identity,
+		},
+		{
+			arrow:  arrow.Field{Name: "age", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+			avro:   `["null", "long"]`,
+			values: []any{int64(30), nil, int64(50), int64(60), nil},
+			toAvro: union("long"),
+		},
+		{
+			arrow:  arrow.Field{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+			avro:   `{"type": "array", "items": "string"}`,
+			values: []any{[]any{"x", "y"}, []any{}, []any{"z"}, []any{}, []any{"x"}},
+			toAvro: identity,
+		},
+		{
+			arrow:  arrow.Field{Name: "created", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+			avro:   `{"type": "long", "logicalType": "timestamp-micros"}`,
+			values: []any{created, created.Add(time.Hour), created.Add(2 * time.Hour), created.Add(3 * time.Hour), created.Add(4 * time.Hour)},
+			toAvro: identity,
+		},
+		{
+			arrow:  arrow.Field{Name: "birth", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+			avro:   `["null", {"type": "int", "logicalType": "date"}]`,
+			values: []any{date("2000-01-02"), date("1990-12-31"), nil, date("1980-06-15"), date("1970-01-01")},
+			toAvro: func(v any) any {
+				if v == nil {
+					return nil
+				}
+				return goavro.Union("int.date", v.(civil.Date).In(time.UTC))
+			},
+		},
+		{
+			arrow:  arrow.Field{Name: "balance", Type: &arrow.Decimal128Type{Precision: 38, Scale: 9}},
+			avro:   `{"type": "bytes", "logicalType": "decimal", "precision": 38, "scale": 9}`,
+			values: []any{rat("1.5"), rat("-2.25"), rat("0"), rat("1000"), rat("0.000000001")},
+			toAvro: identity,
+		},
+		{
+			arrow: arrow.Field{Name: "address", Type: arrow.StructOf(arrow.Field{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true})},
+			avro:  `{"type": "record", "name": "address", "fields": [{"name": "city", "type": ["null", "string"]}]}`,
+			values: []any{
+				[]any{"Paris"}, []any{"Berlin"}, []any{nil}, []any{"Rome"}, []any{"Oslo"},
+			},
+			toAvro: func(v any) any {
+				return map[string]any{"city": union("string")(v.([]any)[0])}
+			},
+		},
+	}
+}
+
+// wantRows are the rows of the table of testColumns.
+var wantRows = []storageRow{
+	{Name: "a", Age: ptr(int64(30)), Tags: []string{"x", "y"}, Created: created, Birth: "2000-01-02", Balance: "1.500000000", Address: storageAddress{City: ptr("Paris")}},
+	{Name: "b", Tags: []string{}, Created: created.Add(time.Hour), Birth: "1990-12-31", Balance: "-2.250000000", Address: storageAddress{City: ptr("Berlin")}},
+	{Name: "c", Age: ptr(int64(50)), Tags: []string{"z"}, Created: created.Add(2 * time.Hour), Balance: "0.000000000"},
+	{Name: "d", Age: ptr(int64(60)), Tags: []string{}, Created: created.Add(3 * time.Hour), Birth: "1980-06-15", Balance: "1000.000000000", Address: storageAddress{City: ptr("Rome")}},
+	{Name: "e", Tags: []string{"x"}, Created: created.Add(4 * time.Hour), Birth: "1970-01-01", Balance: "0.000000001", Address: storageAddress{City: ptr("Oslo")}},
+}
+
+// fakeReadServer is a fake of the Storage Read API serving a single table.
+// Sessions have the selected columns of the table, and their streams are
+// contiguous ranges of its rows.
+type fakeReadServer struct {
+	storagepb.UnimplementedBigQueryReadServer
+
+	mu       sync.Mutex
+	columns  []fakeColumn
+	requests []*storagepb.CreateReadSessionRequest
+	sessions map[string]*fakeSession
+	// streams are the rows of the streams.
+	streams map[string][]int
+	splits  int
+}
+
+type fakeSession struct {
+	columns []fakeColumn
+	avro    bool
+	schema  *arrow.Schema
+	codec   *goavro.Codec
+}
+
+// reset sets the table of the server.
+func (s *fakeReadServer) reset(columns []fakeColumn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.columns = columns
+	s.requests = nil
+	s.sessions = make(map[string]*fakeSession)
+	s.streams = make(map[string][]int)
+	s.splits = 0
+}
+
+func (s *fakeReadServer) numRows() int {
+	if len(s.columns) == 0 {
+		return 0
+	}
+	return len(s.columns[0].values)
+}
+
+func (s *fakeReadServer) CreateReadSession(_ context.Context, req *storagepb.CreateReadSessionRequest) (*storagepb.ReadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	name := fmt.Sprintf("projects/p/locations/us/sessions/%v", len(s.requests))
+	session := &fakeSession{avro: req.GetReadSession().GetDataFormat() == storagepb.DataFormat_AVRO}
+	selected := req.GetReadSession().GetReadOptions().GetSelectedFields()
+	for _, c := range s.columns {
+		if len(selected) == 0 || contains(selected, c.arrow.Name) {
+			session.columns = append(session.columns, c)
+		}
+	}
+
+	resp := &storagepb.ReadSession{Name: name, EstimatedRowCount: int64(s.numRows())}
+	if session.avro {
+		var fields []string
+		for _, c := range session.columns {
+			fields = append(fields, fmt.Sprintf(`{"name": %q, "type": %v}`, c.arrow.Name, c.avro))
+		}
+		schema := fmt.Sprintf(`{"type": "record", "name": "__root__", "fields": [%v]}`, strings.Join(fields, ", "))
+		codec, err := goavro.NewCodec(schema)
+		if err != nil {
+			return nil, err
+		}
+		session.codec = codec
+		resp.Schema = &storagepb.ReadSession_AvroSchema{AvroSchema: &storagepb.AvroSchema{Schema: schema}}
+	} else {
+		var fields []arrow.Field
+		for _, c := range session.columns {
+			fields = append(fields, c.arrow)
+		}
+		session.schema = arrow.NewSchema(fields, nil)
+		schema, _, err := session.arrowBatch(nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.Schema = &storagepb.ReadSession_ArrowSchema{ArrowSchema: &storagepb.ArrowSchema{SerializedSchema: schema}}
+	}
+	s.sessions[name] = session
+
+	n := int(req.GetMaxStreamCount())
+	if n == 0 || n > 3 {
+		n = 3
+	}
+	rows := s.numRows()
+	for i := 0; i < n; i++ {
+		stream := fmt.Sprintf("%v/streams/%v", name, i)
+		for r := i * rows / n; r < (i+1)*rows/n; r++ {
+			s.streams[stream] = append(s.streams[stream], r)
+		}
+		if len(s.streams[stream]) > 0 {
+			resp.Streams = append(resp.Streams, &storagepb.ReadStream{Name: stream})
+		}
+	}
+	return resp, nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// arrowBatch returns the serialized schema and record batch of the rows.
+func (s *fakeSession) arrowBatch(rows []int) ([]byte, []byte, error) {
+	var schema bytes.Buffer
+	w := ipc.NewWriter(&schema, ipc.WithSchema(s.schema))
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, s.schema)
+	defer b.Release()
+	for j, c := range s.columns {
+		for _, r := range rows {
+			appendArrow(b.Field(j), c.values[r])
+		}
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	var full bytes.Buffer
+	w = ipc.NewWriter(&full, ipc.WithSchema(s.schema))
+	if err := w.Write(rec); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	// Both streams end with an 8 byte end-of-stream marker.
+	n := schema.Len() - 8
+	return schema.Bytes()[:n], full.Bytes()[n : full.Len()-8], nil
+}
+
+func appendArrow(b array.Builder, v any) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		b.Append(v.(string))
+	case *array.Int64Builder:
+		b.Append(v.(int64))
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(v.(time.Time).UnixMicro()))
+	case *array.Date32Builder:
+		b.Append(arrow.Date32FromTime(v.(civil.Date).In(time.UTC)))
+	case *array.Decimal128Builder:
+		r := new(big.Rat).Mul(v.(*big.Rat), big.NewRat(1e9, 1))
+		b.Append(decimal128.FromBigInt(r.Num()))
+	case *array.ListBuilder:
+		b.Append(true)
+		for _, e := range v.([]any) {
+			appendArrow(b.ValueBuilder(), e)
+		}
+	case *array.StructBuilder:
+		b.Append(true)
+		for j, e := range v.([]any) {
+			appendArrow(b.FieldBuilder(j), e)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported builder %T", b))
+	}
+}
+
+func (s *fakeReadServer) session(stream string) *fakeSession {
+	name, _, _ := strings.Cut(stream, "/streams/")
+	return s.sessions[name]
+}
+
+// batchSize is the number of rows of the responses of the fake server.
+const batchSize = 2
+
+func (s *fakeReadServer) ReadRows(req *storagepb.ReadRowsRequest, srv storagepb.BigQueryRead_ReadRowsServer) error {
+	s.mu.Lock()
+	session := s.session(req.GetReadStream())
+	rows, ok := s.streams[req.GetReadStream()]
+	s.mu.Unlock()
+	if session == nil || !ok {
+		return fmt.Errorf("stream %v not found", req.GetReadStream())
+	}
+
+	for start := int(req.GetOffset()); start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		resp := &storagepb.ReadRowsResponse{
+			RowCount: int64(end - start),
+			Stats: &storagepb.StreamStats{Progress: &storagepb.StreamStats_Progress{
+				AtResponseStart: float64(start) / float64(len(rows)),
+				AtResponseEnd:   float64(end) / float64(len(rows)),
+			}},
+		}
+		if session.avro {
+			var data []byte
+			for _, r := range rows[start:end] {
+				record := make(map[string]any)
+				for _, c := range session.columns {
+					record[c.arrow.Name] = c.toAvro(c.values[r])
+				}
+				var err error
+				if data, err = session.codec.BinaryFromNative(data, record); err != nil {
+					return err
+				}
+			}
+			resp.Rows = &storagepb.ReadRowsResponse_AvroRows{AvroRows: &storagepb.AvroRows{SerializedBinaryRows: data}}
+		} else {
+			_, data, err := session.arrowBatch(rows[start:end])
+			if err != nil {
+				return err
+			}
+			resp.Rows = &storagepb.ReadRowsResponse_ArrowRecordBatch{ArrowRecordBatch: &storagepb.ArrowRecordBatch{SerializedRecordBatch: data}}
+		}
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeReadServer) SplitReadStream(_ context.Context, req *storagepb.SplitReadStreamRequest) (*storagepb.SplitReadStreamResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := s.streams[req.GetName()]
+	k := int(math.Ceil(req.GetFraction() * float64(len(rows))))
+	if k <= 0 || k >= len(rows) {
+		return &storagepb.SplitReadStreamResponse{}, nil
+	}
+	s.splits++
+	primary := fmt.Sprintf("%v.%v", req.GetName(), 0)
+	remainder := fmt.Sprintf("%v.%v", req.GetName(), 1)
+	s.streams[primary], s.streams[remainder] = rows[:k], rows[k:]
+	s.streams[req.GetName()] = rows[:k]
+	return &storagepb.SplitReadStreamResponse{
+		PrimaryStream:   &storagepb.ReadStream{Name: primary},
+		RemainderStream: &storagepb.ReadStream{Name: remainder},
+	}, nil
+}
+
+func TestReadStorage(t *testing.T) {
+	startFakes(t)
+
+	for _, avro := range []bool{false, true} {
+		t.Run(fmt.Sprintf("avro=%v", avro), func(t *testing.T) {
+			fake.reset(testColumns())
+
+			var opts []ReadStorageOption
+			if avro {
+				opts = append(opts, WithAvro())
+			}
+			p, s := beam.NewPipelineWithRoot()
+			rows := ReadStorage(s, "project", "p:d.t", reflect.TypeOf(storageRow{}), opts...)
+			passert.Equals(s, rows, toAny(wantRows)...)
+			ptest.RunAndValidate(t, p)
+
+			req := fake.requests[len(fake.requests)-1]
+			if got, want := req.GetReadSession().GetTable(), "projects/p/datasets/d/tables/t"; got != want {
+				t.Errorf("table = %v, want %v", got, want)
+			}
+			if got, want := req.GetParent(), "projects/project"; got != want {
+				t.Errorf("parent = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadStorage_projection(t *testing.T) {
+	startFakes(t)
+
+	fake.reset(testColumns())
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := ReadStorage(s, "project", "p:d.t", reflect.TypeOf(storageRow{}),
+		WithSelectedFields("name", "age"), WithRowRestriction("age > 18"), WithMaxStreams(2))
+	var want []storageRow
+	for _, r := range wantRows {
+		want = append(want, storageRow{Name: r.Name, Age: r.Age})
+	}
+	passert.Equals(s, rows, toAny(want)...)
+	ptest.RunAndValidate(t, p)
+
+	opts := fake.requests[len(fake.requests)-1].GetReadSession().GetReadOptions()
+	if got, want := opts.GetSelectedFields(), []string{"name", "age"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selected fields = %v, want %v", got, want)
+	}
+	if got, want := opts.GetRowRestriction(), "age > 18"; got != want {
+		t.Errorf("row restriction = %v, want %v", got, want)
+	}
+	if got, want := fake.requests[len(fake.requests)-1].GetMaxStreamCount(), int32(2); got != want {
+		t.Errorf("max stream count = %v, want %v", got, want)
+	}
+}
+
+func toJSON(row beam.X) (string, error) {
+	b, err := json.Marshal(row)
+	return string(b), err
+}
+
+func TestReadStorage_inferred(t *testing.T) {
+	startFakes(t)
+
+	for _, avro := range []bool{false, true} {
+		t.Run(fmt.Sprintf("avro=%v", avro), func(t *testing.T) {
+			fake.reset(testColumns())
+
+			var opts []ReadStorageOption
+			if avro {
+				opts = append(opts, WithAvro())
+			}
+			p, s := beam.NewPipelineWithRoot()
+			rows := ReadStorage(s, "project", "p:d.t", nil, opts...)
+			passert.Equals(s, beam.ParDo(s, toJSON, rows),
+				`{"Name":"a","Age":30,"Tags":["x","y"],"Created":"2024-01-02T03:04:05.000006Z","Birth":"2000-01-02","Balance":"1.500000000","Address":{"City":"Paris"}}`,
+				`{"Name":"b","Age":null,"Tags":[],"Created":"2024-01-02T04:04:05.000006Z","Birth":"1990-12-31","Balance":"-2.250000000","Address":{"City":"Berlin"}}`,
+				`{"Name":"c","Age":50,"Tags":["z"],"Created":"2024-01-02T05:04:05.000006Z","Birth":null,"Balance":"0.000000000","Address":{"City":null}}`,
+				`{"Name":"d","Age":60,"Tags":[],"Created":"2024-01-02T06:04:05.000006Z","Birth":"1980-06-15","Balance":"1000.000000000","Address":{"City":"Rome"}}`,
+				`{"Name":"e","Age":null,"Tags":["x"],"Created":"2024-01-02T07:04:05.000006Z","Birth":"1970-01-01","Balance":"0.000000001","Address":{"City":"Oslo"}}`,
+			)
+			ptest.RunAndValidate(t, p)
+
+			if got, want := fake.requests[0].GetMaxStreamCount(), int32(1); got != want {
+				t.Errorf("max stream count of the inference session = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadStorage_empty(t *testing.T) {
+	startFakes(t)
+
+	fake.reset(nil)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := ReadStorage(s, "project", "p:d.t", reflect.TypeOf(storageRow{}))
+	passert.Empty(s, rows)
+	ptest.RunAndValidate(t, p)
+}
+
+func toAny(rows []storageRow) []any {
+	var out []any
+	for _, r := range rows {
+		out = append(out, r)
+	}
+	return out
+}
+
+// readAll reads a stream with readStreamFn, splitting the restriction with
+// the fraction after the first row and reading the residual restriction.
+func readAll(t *testing.T, stream readStream, fraction float64) []storageRow {
+	t.Helper()
+	ctx := context.Background()
+	fn := &readStreamFn{Type: beam.EncodedType{T: reflect.TypeOf(storageRow{})}}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	defer fn.Teardown()
+
+	var rows []storageRow
+	rests := []streamRestriction{fn.CreateInitialRestriction(stream)}
+	split := false
+	for len(rests) > 0 {
+		rt := fn.CreateTracker(rests[0])
+		rests = rests[1:]
+		emit := func(v beam.X) {
+			rows = append(rows, v.(storageRow))
+			if !split {
+				split = true
+				_, res, err := rt.TrySplit(fraction)
+				if err != nil {
+					t.Fatalf("TrySplit(%v) failed: %v", fraction, err)
+				}
+				if res == nil {
+					t.Fatalf("TrySplit(%v) declined the split", fraction)
+				}
+				rests = append(rests, res.(streamRestriction))
+			}
+		}
+		if err := fn.ProcessElement(ctx, rt, stream, emit); err != nil {
+			t.Fatalf("ProcessElement() failed: %v", err)
+		}
+		if err := rt.GetError(); err != nil {
+			t.Fatalf("tracker failed: %v", err)
+		}
+		if !rt.IsDone() {
+			t.Fatalf("restriction %v isn't done", rt.GetRestriction())
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+func TestReadStreamFn_split(t *testing.T) {
+	startFakes(t)
+
+	for _, fraction := range []float64{0, 0.5} {
+		for _, avro := range []bool{false, true} {
+			t.Run(fmt.Sprintf("fraction=%v,avro=%v", fraction, avro), func(t *testing.T) {
+				fake.reset(testColumns())
+				opts := readStorageOptions{MaxStreams: 1, Avro: avro}
+				ctx := context.Background()
+				fn := &createSessionFn{Project: "project", Table: QualifiedTableName{"p", "d", "t"}, Options: opts}
+				var streams []readStream
+				if err := fn.ProcessElement(ctx, nil, func(s readStream) { streams = append(streams, s) }); err != nil {
+					t.Fatalf("ProcessElement() failed: %v", err)
+				}
+				if len(streams) != 1 {
+					t.Fatalf("got %v streams, want 1", len(streams))
+				}
+
+				if got, want := readAll(t, streams[0], fraction), wantRows; !reflect.DeepEqual(got, want) {
+					t.Errorf("read rows %+v, want %+v", got, want)
+				}
+				if got, want := fake.splits, int(math.Ceil(fraction)); got != want {
+					t.Errorf("got %v stream splits, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+var _ sdf.RTracker = (*streamTracker)(nil)
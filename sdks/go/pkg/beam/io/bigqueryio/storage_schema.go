@@ -0,0 +1,295 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// This file describes the fields of the rows read with the Storage Read API,
+// from their Arrow or Avro schemas, and relates them to Go types.
+
+// fieldType is the BigQuery type of a field.
+type fieldType int
+
+const (
+	intType fieldType = iota
+	floatType
+	boolType
+	stringType // Also GEOGRAPHY and JSON.
+	bytesType
+	timestampType
+	dateType
+	timeType
+	dateTimeType
+	numericType // NUMERIC and BIGNUMERIC.
+	recordType
+)
+
+// field is a field of the rows of a read session.
+type field struct {
+	Name     string
+	Type     fieldType
+	Nullable bool
+	Repeated bool
+	// Scale is the number of digits after the decimal point of numerics.
+	Scale int
+	// Fields are the fields of records.
+	Fields []field
+}
+
+// arrowFields returns the fields of an Arrow schema.
+func arrowFields(fs []arrow.Field) ([]field, error) {
+	var fields []field
+	for _, af := range fs {
+		f, err := arrowField(af.Name, af.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", af.Name, err)
+		}
+		f.Nullable = af.Nullable && !f.Repeated
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func arrowField(name string, t arrow.DataType) (field, error) {
+	f := field{Name: name}
+	switch t := t.(type) {
+	case *arrow.Int64Type:
+		f.Type = intType
+	case *arrow.Float64Type:
+		f.Type = floatType
+	case *arrow.BooleanType:
+		f.Type = boolType
+	case *arrow.StringType:
+		f.Type = stringType
+	case *arrow.BinaryType:
+		f.Type = bytesType
+	case *arrow.TimestampType:
+		// DATETIME values have no time zone.
+		f.Type = timestampType
+		if t.TimeZone == "" {
+			f.Type = dateTimeType
+		}
+	case *arrow.Date32Type:
+		f.Type = dateType
+	case *arrow.Time64Type:
+		f.Type = timeType
+	case *arrow.Decimal128Type:
+		f.Type, f.Scale = numericType, int(t.Scale)
+	case *arrow.Decimal256Type:
+		f.Type, f.Scale = numericType, int(t.Scale)
+	case *arrow.ListType:
+		elem, err := arrowField(name, t.Elem())
+		if err != nil {
+			return field{}, err
+		}
+		elem.Repeated = true
+		return elem, nil
+	case *arrow.StructType:
+		fields, err := arrowFields(t.Fields())
+		if err != nil {
+			return field{}, err
+		}
+		f.Type, f.Fields = recordType, fields
+	default:
+		return field{}, fmt.Errorf("unsupported arrow type %v", t)
+	}
+	return f, nil
+}
+
+// avroFields returns the fields of the Avro schema of a record.
+func avroFields(schema string) ([]field, error) {
+	var record struct {
+		Fields []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &record); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+	var fields []field
+	for _, af := range record.Fields {
+		f, err := avroField(af.Name, af.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", af.Name, err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func avroField(name string, schema json.RawMessage) (field, error) {
+	f := field{Name: name}
+
+	// Nullable fields are unions with null.
+	var union []json.RawMessage
+	if json.Unmarshal(schema, &union) == nil {
+		var types []json.RawMessage
+		for _, t := range union {
+			if string(t) != `"null"` {
+				types = append(types, t)
+			}
+		}
+		if len(types) != 1 {
+			return field{}, fmt.Errorf("unsupported avro union %s", schema)
+		}
+		f, err := avroField(name, types[0])
+		f.Nullable = len(union) > 1
+		return f, err
+	}
+
+	var t struct {
+		Type        json.RawMessage `json:"type"`
+		LogicalType string          `json:"logicalType"`
+		SQLType     string          `json:"sqlType"`
+		Scale       int             `json:"scale"`
+		Items       json.RawMessage `json:"items"`
+		Fields      json.RawMessage `json:"fields"`
+	}
+	var primitive string
+	if json.Unmarshal(schema, &primitive) == nil {
+		t.Type = schema
+	} else if err := json.Unmarshal(schema, &t); err != nil {
+		return field{}, fmt.Errorf("invalid avro type %s", schema)
+	} else if json.Unmarshal(t.Type, &primitive) != nil {
+		// The type is a complex type itself.
+		return avroField(name, t.Type)
+	}
+
+	switch {
+	case t.LogicalType == "timestamp-micros":
+		f.Type = timestampType
+	case t.LogicalType == "date":
+		f.Type = dateType
+	case t.LogicalType == "time-micros":
+		f.Type = timeType
+	case t.LogicalType == "decimal":
+		f.Type, f.Scale = numericType, t.Scale
+	case t.SQLType == "DATETIME":
+		f.Type = dateTimeType
+	case primitive == "long":
+		f.Type = intType
+	case primitive == "double":
+		f.Type = floatType
+	case primitive == "boolean":
+		f.Type = boolType
+	case primitive == "string":
+		f.Type = stringType
+	case primitive == "bytes":
+		f.Type = bytesType
+	case primitive == "array":
+		elem, err := avroField(name, t.Items)
+		if err != nil {
+			return field{}, err
+		}
+		elem.Nullable, elem.Repeated = false, true
+		return elem, nil
+	case primitive == "record":
+		fields, err := avroFields(string(schema))
+		if err != nil {
+			return field{}, err
+		}
+		f.Type, f.Fields = recordType, fields
+	default:
+		return field{}, fmt.Errorf("unsupported avro type %s", schema)
+	}
+	return f, nil
+}
+
+var (
+	timeTimeType = reflect.TypeOf(time.Time{})
+	bigRatType   = reflect.TypeOf((*big.Rat)(nil))
+	civilTypes   = map[fieldType]reflect.Type{
+		dateType:     reflect.TypeOf(civil.Date{}),
+		timeType:     reflect.TypeOf(civil.Time{}),
+		dateTimeType: reflect.TypeOf(civil.DateTime{}),
+	}
+)
+
+// rowType returns the type of schema rows with the given fields. Nullable
+// fields are pointers and repeated fields are slices. DATE, TIME, DATETIME
+// and numeric values are strings, as they have no Beam schema types.
+func rowType(fields []field) reflect.Type {
+	var sfs []reflect.StructField
+	used := make(map[string]bool)
+	for _, f := range fields {
+		var t reflect.Type
+		switch f.Type {
+		case intType:
+			t = reflect.TypeOf(int64(0))
+		case floatType:
+			t = reflect.TypeOf(float64(0))
+		case boolType:
+			t = reflect.TypeOf(false)
+		case bytesType:
+			t = reflect.TypeOf([]byte(nil))
+		case timestampType:
+			t = timeTimeType
+		case recordType:
+			t = rowType(f.Fields)
+		default:
+			t = reflect.TypeOf("")
+		}
+		switch {
+		case f.Repeated:
+			t = reflect.SliceOf(t)
+		case f.Nullable && f.Type != bytesType:
+			t = reflect.PtrTo(t)
+		}
+
+		name := fieldName(f.Name)
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		sfs = append(sfs, reflect.StructField{
+			Name: strings.ToUpper(name[:1]) + name[1:],
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf(`beam:"%v" bigquery:"%v"`, name, f.Name)),
+		})
+	}
+	return reflect.StructOf(sfs)
+}
+
+// fieldName returns the name with characters other than ASCII letters, digits
+// and underscores replaced by underscores, starting with a letter, so that it
+// can be the name of an exported Go field.
+func fieldName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isLetter(c) && !('0' <= c && c <= '9') && c != '_' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || !isLetter(b[0]) {
+		return "X" + string(b)
+	}
+	return string(b)
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
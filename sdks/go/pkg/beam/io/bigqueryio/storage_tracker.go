@@ -0,0 +1,156 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"fmt"
+)
+
+// streamRestriction is a range of row offsets of a read stream to read. The
+// range is unbounded if End is negative, in which case the stream is read to
+// its end.
+type streamRestriction struct {
+	Stream string
+	Offset int64
+	End    int64
+}
+
+// streamEnd is claimed once the end of a stream is reached.
+type streamEnd struct{}
+
+// streamTracker tracks a streamRestriction. Unbounded restrictions are split
+// dynamically by splitting their stream with the Storage Read API, so that
+// the primary restriction is the beginning of the stream, which keeps the
+// offsets of the original stream, and the residual restriction is a new
+// stream for the rest of the rows.
+type streamTracker struct {
+	rest    streamRestriction
+	claimed int64
+	stopped bool
+	err     error
+	// progress is the fraction of the stream read at the end of the
+	// response being processed, as reported by the API.
+	progress float64
+	// split splits a stream at a fraction of its rows.
+	split func(stream string, fraction float64) (primary, residual string, err error)
+}
+
+func newStreamTracker(rest streamRestriction, split func(string, float64) (string, string, error)) *streamTracker {
+	return &streamTracker{rest: rest, claimed: rest.Offset - 1, split: split}
+}
+
+// TryClaim claims the row at an int64 offset, or the end of the stream with
+// streamEnd. Offsets must be claimed in increasing order.
+func (t *streamTracker) TryClaim(pos any) bool {
+	if t.stopped {
+		return false
+	}
+	switch pos := pos.(type) {
+	case int64:
+		if pos <= t.claimed {
+			t.err = fmt.Errorf("cannot claim offset %v, already claimed offset %v", pos, t.claimed)
+			t.stopped = true
+			return false
+		}
+		if t.rest.End >= 0 && pos >= t.rest.End {
+			t.stopped = true
+			return false
+		}
+		t.claimed = pos
+		return true
+	case streamEnd:
+		t.stopped = true
+		return false
+	default:
+		t.err = fmt.Errorf("invalid position %v of type %T", pos, pos)
+		t.stopped = true
+		return false
+	}
+}
+
+// GetError returns the error of an invalid claim.
+func (t *streamTracker) GetError() error {
+	return t.err
+}
+
+// TrySplit checkpoints the restriction for a fraction of 0, and otherwise
+// splits its stream at the fraction of the remaining rows. Restrictions
+// that are bounded are only checkpointed, as their stream can't be split
+// past their end.
+func (t *streamTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	if t.stopped || t.IsDone() {
+		return t.rest, nil, nil
+	}
+	if fraction <= 0 {
+		res := streamRestriction{Stream: t.rest.Stream, Offset: t.claimed + 1, End: t.rest.End}
+		t.rest.End = t.claimed + 1
+		return t.rest, res, nil
+	}
+	if t.rest.End >= 0 || t.split == nil {
+		return t.rest, nil, nil
+	}
+
+	f := t.progress + fraction*(1-t.progress)
+	if f >= 1 {
+		return t.rest, nil, nil
+	}
+	p, r, err := t.split(t.rest.Stream, f)
+	if err != nil || p == "" || r == "" {
+		// The stream is too small to split or already read to the split
+		// point, so the split is declined.
+		return t.rest, nil, nil
+	}
+	t.rest.Stream = p
+	return t.rest, streamRestriction{Stream: r, End: -1}, nil
+}
+
+// GetProgress returns the number of rows read and an estimate of the number
+// of rows left, from the fraction of the stream read.
+func (t *streamTracker) GetProgress() (done, remaining float64) {
+	done = float64(t.claimed + 1 - t.rest.Offset)
+	switch {
+	case t.rest.End >= 0:
+		remaining = float64(t.rest.End - t.claimed - 1)
+	case t.stopped:
+		remaining = 0
+	case t.progress > 0:
+		remaining = float64(t.claimed+1) * (1 - t.progress) / t.progress
+	default:
+		remaining = 1
+	}
+	return done, remaining
+}
+
+// IsDone returns whether all the rows of the restriction were claimed.
+func (t *streamTracker) IsDone() bool {
+	return t.err == nil && (t.stopped || t.rest.End >= 0 && t.claimed+1 >= t.rest.End)
+}
+
+// GetRestriction returns the restriction being tracked.
+func (t *streamTracker) GetRestriction() any {
+	return t.rest
+}
+
+// IsBounded returns true, as streams have a finite number of rows.
+func (t *streamTracker) IsBounded() bool {
+	return true
+}
+
+// setProgress sets the fraction of the stream read at the end of the
+// response being processed, which bounds the fraction it can be split at.
+func (t *streamTracker) setProgress(fraction float64) {
+	t.progress = fraction
+}
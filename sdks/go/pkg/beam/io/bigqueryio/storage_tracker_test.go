@@ -0,0 +1,189 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStreamTracker_TryClaim(t *testing.T) {
+	tests := []struct {
+		rest    streamRestriction
+		claims  []any
+		want    []bool
+		done    bool
+		wantErr bool
+	}{
+		{
+			rest:   streamRestriction{Stream: "s", End: -1},
+			claims: []any{int64(0), int64(1), streamEnd{}},
+			want:   []bool{true, true, false},
+			done:   true,
+		},
+		{
+			rest:   streamRestriction{Stream: "s", Offset: 2, End: 4},
+			claims: []any{int64(2), int64(3), int64(4)},
+			want:   []bool{true, true, false},
+			done:   true,
+		},
+		{
+			rest:   streamRestriction{Stream: "s", Offset: 2, End: 4},
+			claims: []any{int64(2)},
+			want:   []bool{true},
+			done:   false,
+		},
+		{
+			rest:    streamRestriction{Stream: "s", End: -1},
+			claims:  []any{int64(1), int64(1)},
+			want:    []bool{true, false},
+			wantErr: true,
+		},
+		{
+			rest:    streamRestriction{Stream: "s", End: -1},
+			claims:  []any{"1"},
+			want:    []bool{false},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		tracker := newStreamTracker(test.rest, nil)
+		for i, pos := range test.claims {
+			if got, want := tracker.TryClaim(pos), test.want[i]; got != want {
+				t.Errorf("TryClaim(%v) of %v = %v, want %v", pos, test.claims[:i], got, want)
+			}
+		}
+		if got, want := tracker.IsDone(), test.done; got != want {
+			t.Errorf("IsDone() after claims %v = %v, want %v", test.claims, got, want)
+		}
+		if got, want := tracker.GetError() != nil, test.wantErr; got != want {
+			t.Errorf("GetError() after claims %v = %v, want error %v", test.claims, tracker.GetError(), want)
+		}
+	}
+}
+
+func TestStreamTracker_TrySplit(t *testing.T) {
+	type split struct {
+		stream   string
+		fraction float64
+	}
+	tests := []struct {
+		name      string
+		rest      streamRestriction
+		claimed   int64
+		progress  float64
+		fraction  float64
+		splitErr  error
+		primary   any
+		residual  any
+		wantSplit *split
+	}{
+		{
+			name:     "checkpoint",
+			rest:     streamRestriction{Stream: "s", Offset: 1, End: -1},
+			claimed:  2,
+			fraction: 0,
+			primary:  streamRestriction{Stream: "s", Offset: 1, End: 3},
+			residual: streamRestriction{Stream: "s", Offset: 3, End: -1},
+		},
+		{
+			name:     "checkpoint bounded",
+			rest:     streamRestriction{Stream: "s", End: 5},
+			claimed:  2,
+			fraction: 0,
+			primary:  streamRestriction{Stream: "s", End: 3},
+			residual: streamRestriction{Stream: "s", Offset: 3, End: 5},
+		},
+		{
+			name:      "split",
+			rest:      streamRestriction{Stream: "s", End: -1},
+			claimed:   2,
+			progress:  0.5,
+			fraction:  0.5,
+			primary:   streamRestriction{Stream: "s/p", End: -1},
+			residual:  streamRestriction{Stream: "s/r", End: -1},
+			wantSplit: &split{"s", 0.75},
+		},
+		{
+			name:      "split failed",
+			rest:      streamRestriction{Stream: "s", End: -1},
+			claimed:   2,
+			progress:  0.5,
+			fraction:  0.5,
+			splitErr:  errors.New("failed"),
+			primary:   streamRestriction{Stream: "s", End: -1},
+			wantSplit: &split{"s", 0.75},
+		},
+		{
+			name:     "split bounded",
+			rest:     streamRestriction{Stream: "s", End: 5},
+			claimed:  2,
+			fraction: 0.5,
+			primary:  streamRestriction{Stream: "s", End: 5},
+		},
+		{
+			name:     "split read",
+			rest:     streamRestriction{Stream: "s", End: -1},
+			claimed:  2,
+			progress: 1,
+			fraction: 0.5,
+			primary:  streamRestriction{Stream: "s", End: -1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got *split
+			tracker := newStreamTracker(test.rest, func(stream string, fraction float64) (string, string, error) {
+				got = &split{stream, fraction}
+				return stream + "/p", stream + "/r", test.splitErr
+			})
+			for i := test.rest.Offset; i <= test.claimed; i++ {
+				tracker.TryClaim(i)
+			}
+			tracker.setProgress(test.progress)
+
+			primary, residual, err := tracker.TrySplit(test.fraction)
+			if err != nil {
+				t.Fatalf("TrySplit(%v) failed: %v", test.fraction, err)
+			}
+			if !reflect.DeepEqual(primary, test.primary) || !reflect.DeepEqual(residual, test.residual) {
+				t.Errorf("TrySplit(%v) = (%v, %v), want (%v, %v)", test.fraction, primary, residual, test.primary, test.residual)
+			}
+			if !reflect.DeepEqual(got, test.wantSplit) {
+				t.Errorf("TrySplit(%v) split the stream with %+v, want %+v", test.fraction, got, test.wantSplit)
+			}
+			if got, want := tracker.GetRestriction(), test.primary; !reflect.DeepEqual(got, want) {
+				t.Errorf("GetRestriction() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStreamTracker_GetProgress(t *testing.T) {
+	tracker := newStreamTracker(streamRestriction{Stream: "s", Offset: 2, End: -1}, nil)
+	tracker.TryClaim(int64(2))
+	tracker.TryClaim(int64(3))
+	tracker.setProgress(0.5)
+	if done, remaining := tracker.GetProgress(); done != 2 || remaining != 4 {
+		t.Errorf("GetProgress() = (%v, %v), want (2, 4)", done, remaining)
+	}
+
+	tracker.TrySplit(0)
+	if done, remaining := tracker.GetProgress(); done != 2 || remaining != 0 {
+		t.Errorf("GetProgress() after checkpoint = (%v, %v), want (2, 0)", done, remaining)
+	}
+}
@@ -0,0 +1,515 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	storage "cloud.google.com/go/bigquery/storage/apiv1"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*prepareTableFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*shardFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeStorageFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*commitFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeResult)(nil)).Elem())
+}
+
+// bigqueryClientOptions are the options of the BigQuery API clients used to
+// prepare tables for the Storage Write API, which can be set to use another
+// endpoint.
+var bigqueryClientOptions []option.ClientOption
+
+// defaultWriteStreams is the default number of streams written in parallel.
+const defaultWriteStreams = 4
+
+// appendRetries is the number of times failed appends are retried.
+const appendRetries = 5
+
+// appendBackoff is the delay before the first retry of a failed append, which
+// doubles with each retry.
+var appendBackoff = time.Second
+
+// StorageWriteMode specifies the streams written with the Storage Write API.
+type StorageWriteMode string
+
+const (
+	// DefaultStream writes rows to the default stream of the table, where
+	// they're visible as soon as they're written. Appends are retried, so
+	// rows are written at least once.
+	DefaultStream StorageWriteMode = "DefaultStream"
+	// CommittedStreams writes rows to streams created for each shard, where
+	// they're visible as soon as they're written. Appends are retried at
+	// their offset in the stream, so they're written exactly once, but rows
+	// of retried bundles are written again.
+	CommittedStreams StorageWriteMode = "CommittedStreams"
+	// PendingStreams writes rows to pending streams created for each shard,
+	// which are committed at once when all of them are written, so that the
+	// rows are written exactly once and visible only if the whole write
+	// succeeds. This mode is for bounded PCollections.
+	PendingStreams StorageWriteMode = "PendingStreams"
+)
+
+// WithStorageWriteMode specifies the streams written by WriteStorage. The
+// default is DefaultStream.
+func WithStorageWriteMode(mode StorageWriteMode) WriteOption {
+	return func(wo *writeOptions) error {
+		switch mode {
+		case DefaultStream, CommittedStreams, PendingStreams:
+			wo.WriteMode = mode
+			return nil
+		default:
+			return fmt.Errorf("invalid storage write mode: %v", mode)
+		}
+	}
+}
+
+// WithStorageWriteStreams specifies the number of shards of the rows written
+// in parallel by WriteStorage, each with its own stream unless DefaultStream
+// is used.
+func WithStorageWriteStreams(n int) WriteOption {
+	return func(wo *writeOptions) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid number of streams: %v", n)
+		}
+		wo.Streams = n
+		return nil
+	}
+}
+
+// WriteStorage writes the elements of the given PCollection<T> to bigquery
+// with the BigQuery Storage Write API. T is required to be the schema type.
+//
+// The table is prepared once before any row is written: it's created
+// according to the create disposition, checked to be empty if the write
+// disposition is WriteEmpty, and its schema is updated according to the schema
+// update options. The rows are then sharded and written in parallel in the
+// given storage write mode.
+//
+// With WriteTruncate, the rows are written to a staging table in the dataset
+// of the table, with the settings of the table, which is copied over the table
+// by a single copy job with WriteTruncate once all the rows are written. The
+// table keeps its policies, and isn't changed if the write fails. Staging
+// tables expire after a day if they aren't deleted.
+//
+// WriteStorage returns a PCollection<int> with the number of rows written once
+// the write has completed, and a PCollection<KV<T,string>> of the rows that
+// couldn't be written with the error message, such as rows with values not
+// matching the schema of the table.
+func WriteStorage(s beam.Scope, project, table string, col beam.PCollection, options ...WriteOption) (beam.PCollection, beam.PCollection) {
+	t := col.Type().Type()
+	mustInferSchema(t)
+	qn := mustParseTable(table)
+
+	s = s.Scope("bigquery.WriteStorage")
+
+	writeOptions := newWriteOptions()
+	for _, opt := range options {
+		if err := opt(&writeOptions); err != nil {
+			panic(err)
+		}
+	}
+
+	// Rows written with WriteTruncate are written to a staging table.
+	var staging string
+	target := qn
+	if writeOptions.WriteDisposition == bigquery.WriteTruncate {
+		staging = fmt.Sprintf("%v_beam_staging_%016x", qn.Table, rand.Uint64())
+		target.Table = staging
+	}
+
+	imp := beam.Impulse(s)
+	prepared := beam.ParDo(s, &prepareTableFn{Project: project, Table: qn, Staging: staging, Type: beam.EncodedType{T: t}, Options: writeOptions}, imp)
+
+	sharded := beam.ParDo(s, &shardFn{Streams: writeOptions.Streams}, col)
+	grouped := beam.GroupByKey(s, sharded)
+	results, failed := beam.ParDo2(s, &writeStorageFn{Table: target, Type: beam.EncodedType{T: t}, Options: writeOptions}, grouped, beam.SideInput{Input: prepared})
+
+	pre := beam.AddFixedKey(s, results)
+	post := beam.GroupByKey(s, pre)
+	return beam.ParDo(s, &commitFn{Project: project, Table: qn, Staging: staging, Options: writeOptions}, post), failed
+}
+
+// tablePath returns the resource name of a table in the Storage API.
+func tablePath(qn QualifiedTableName) string {
+	return fmt.Sprintf("projects/%v/datasets/%v/tables/%v", qn.Project, qn.Dataset, qn.Table)
+}
+
+type prepareTableFn struct {
+	// Project is the project.
+	Project string `json:"project"`
+	// Table is the qualified table identifier.
+	Table QualifiedTableName `json:"table"`
+	// Staging is the staging table of the rows, if any.
+	Staging string `json:"staging"`
+	// Type is the encoded schema type.
+	Type beam.EncodedType `json:"type"`
+	// Options specifies additional write options.
+	Options writeOptions `json:"options"`
+}
+
+func (f *prepareTableFn) ProcessElement(ctx context.Context, imp []byte) ([]byte, error) {
+	client, err := bigquery.NewClient(ctx, f.Project, bigqueryClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	dataset := client.DatasetInProject(f.Table.Project, f.Table.Dataset)
+	var staging *bigquery.Table
+	if f.Staging != "" {
+		staging = dataset.Table(f.Staging)
+	}
+	if err := prepareTable(ctx, dataset.Table(f.Table.Table), staging, mustInferSchema(f.Type.T), f.Options); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// shardFn assigns the rows to random shards.
+type shardFn struct {
+	// Streams is the number of shards.
+	Streams int `json:"streams"`
+}
+
+func (f *shardFn) ProcessElement(row beam.X) (int, beam.X) {
+	return rand.Intn(f.Streams), row
+}
+
+// writeResult is the result of writing a shard.
+type writeResult struct {
+	// Stream is the name of the pending stream written, if any.
+	Stream string
+	// Rows is the number of rows written.
+	Rows int64
+}
+
+type writeStorageFn struct {
+	// Table is the qualified table identifier.
+	Table QualifiedTableName `json:"table"`
+	// Type is the encoded schema type.
+	Type beam.EncodedType `json:"type"`
+	// Options specifies additional write options.
+	Options writeOptions `json:"options"`
+
+	client  *storage.BigQueryWriteClient
+	encoder *rowEncoder
+}
+
+func (f *writeStorageFn) Setup(ctx context.Context) error {
+	encoder, err := newRowEncoder(f.Type.T)
+	if err != nil {
+		return err
+	}
+	client, err := storage.NewBigQueryWriteClient(ctx, storageClientOptions...)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	f.encoder = encoder
+	return nil
+}
+
+func (f *writeStorageFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
+
+func (f *writeStorageFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, _ []byte, emit func(writeResult), failed func(beam.X, string)) error {
+	w := &streamWriter{client: f.client, descriptor: f.encoder.descriptor}
+	switch f.Options.WriteMode {
+	case CommittedStreams, PendingStreams:
+		typ := storagepb.WriteStream_COMMITTED
+		if f.Options.WriteMode == PendingStreams {
+			typ = storagepb.WriteStream_PENDING
+		}
+		stream, err := f.client.CreateWriteStream(ctx, &storagepb.CreateWriteStreamRequest{
+			Parent:      tablePath(f.Table),
+			WriteStream: &storagepb.WriteStream{Type: typ},
+		})
+		if err != nil {
+			return err
+		}
+		w.stream, w.offsets = stream.GetName(), true
+	default:
+		w.stream = tablePath(f.Table) + "/streams/_default"
+	}
+	defer w.close()
+
+	var rows [][]byte
+	var elems []beam.X
+	size := writeOverheadBytes + proto.Size(w.descriptor)
+	flush := func() error {
+		err := w.append(ctx, rows, func(i int, msg string) {
+			failed(elems[i], msg)
+		})
+		rows, elems, size = nil, nil, writeOverheadBytes+proto.Size(w.descriptor)
+		return err
+	}
+
+	var val beam.X
+	for iter(&val) {
+		row, err := f.encoder.encode(val)
+		if err != nil {
+			failed(val, err.Error())
+			continue
+		}
+		if len(rows)+1 > writeRowLimit || size+len(row) > writeSizeLimit {
+			// Append rows in batches to comply with the limits of requests.
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		rows = append(rows, row)
+		elems = append(elems, val)
+		// Add bytes for the tag and length of each row.
+		size += len(row) + 8
+	}
+	if len(rows) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	result := writeResult{Rows: w.rows}
+	if w.offsets {
+		if _, err := f.client.FinalizeWriteStream(ctx, &storagepb.FinalizeWriteStreamRequest{Name: w.stream}); err != nil {
+			return err
+		}
+		if f.Options.WriteMode == PendingStreams {
+			result.Stream = w.stream
+		}
+	}
+	emit(result)
+	return nil
+}
+
+// streamWriter appends rows to a write stream over a connection, which is
+// opened again when appends are retried.
+type streamWriter struct {
+	client     *storage.BigQueryWriteClient
+	descriptor *descriptorpb.DescriptorProto
+	stream     string
+	// offsets is whether appends are made at offsets of the stream, so that
+	// retried appends are written once.
+	offsets bool
+	rows    int64
+
+	conn   storagepb.BigQueryWrite_AppendRowsClient
+	cancel context.CancelFunc
+}
+
+// append appends the rows to the stream, retrying failed appends, and calls
+// failed with the index and error of the rows with errors, which are dropped.
+func (w *streamWriter) append(ctx context.Context, rows [][]byte, failed func(i int, msg string)) error {
+	indices := make([]int, len(rows))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	for attempt := 0; len(rows) > 0; {
+		resp, err := w.send(ctx, rows)
+		if err == nil && len(resp.GetRowErrors()) > 0 {
+			// Rows with errors are reported, and the others appended again.
+			bad := make(map[int]bool)
+			for _, e := range resp.GetRowErrors() {
+				i := int(e.GetIndex())
+				if i < 0 || i >= len(rows) || bad[i] {
+					continue
+				}
+				bad[i] = true
+				failed(indices[i], e.GetMessage())
+			}
+			if len(bad) == 0 {
+				return fmt.Errorf("append to %v failed with invalid row errors: %v", w.stream, resp.GetRowErrors())
+			}
+			var keptRows [][]byte
+			var kept []int
+			for i, row := range rows {
+				if !bad[i] {
+					keptRows, kept = append(keptRows, row), append(kept, indices[i])
+				}
+			}
+			rows, indices = keptRows, kept
+			continue
+		}
+		if err == nil && resp.GetError() != nil {
+			err = status.ErrorProto(resp.GetError())
+			if w.offsets && status.Code(err) == codes.AlreadyExists {
+				// The rows were appended by a previous attempt.
+				err = nil
+			}
+		}
+		if err == nil {
+			w.rows += int64(len(rows))
+			return nil
+		}
+
+		w.close()
+		if attempt >= appendRetries || !retryable(err) {
+			return fmt.Errorf("append to %v failed: %w", w.stream, err)
+		}
+		log.Warnf(ctx, "Retrying append to %v: %v", w.stream, err)
+		select {
+		case <-time.After(appendBackoff << attempt):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		attempt++
+	}
+	return nil
+}
+
+// send appends the rows and returns the response, opening a connection and
+// sending the schema of the rows first if needed.
+func (w *streamWriter) send(ctx context.Context, rows [][]byte) (*storagepb.AppendRowsResponse, error) {
+	req := &storagepb.AppendRowsRequest{
+		WriteStream: w.stream,
+	}
+	data := &storagepb.AppendRowsRequest_ProtoData{Rows: &storagepb.ProtoRows{SerializedRows: rows}}
+	if w.conn == nil {
+		ctx, cancel := context.WithCancel(ctx)
+		conn, err := w.client.AppendRows(ctx)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		w.conn, w.cancel = conn, cancel
+		data.WriterSchema = &storagepb.ProtoSchema{ProtoDescriptor: w.descriptor}
+	}
+	req.Rows = &storagepb.AppendRowsRequest_ProtoRows{ProtoRows: data}
+	if w.offsets {
+		req.Offset = wrapperspb.Int64(w.rows)
+	}
+	if err := w.conn.Send(req); err != nil {
+		return nil, err
+	}
+	return w.conn.Recv()
+}
+
+func (w *streamWriter) close() {
+	if w.conn == nil {
+		return
+	}
+	w.conn.CloseSend()
+	w.cancel()
+	w.conn, w.cancel = nil, nil
+}
+
+// retryable returns whether an append failed with a transient error, or
+// because the stream doesn't have the updated schema of the table yet.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	case codes.InvalidArgument:
+		return strings.Contains(strings.ToLower(status.Convert(err).Message()), "schema")
+	default:
+		return false
+	}
+}
+
+// commitFn commits the pending streams written and copies the staging table
+// over the table, if any, and returns the number of rows written.
+type commitFn struct {
+	// Project is the project.
+	Project string `json:"project"`
+	// Table is the qualified table identifier.
+	Table QualifiedTableName `json:"table"`
+	// Staging is the staging table of the rows, if any.
+	Staging string `json:"staging"`
+	// Options specifies additional write options.
+	Options writeOptions `json:"options"`
+}
+
+func (f *commitFn) ProcessElement(ctx context.Context, _ int, iter func(*writeResult) bool, emit func(int)) error {
+	var streams []string
+	var rows int64
+	var result writeResult
+	for iter(&result) {
+		if result.Stream != "" {
+			streams = append(streams, result.Stream)
+		}
+		rows += result.Rows
+	}
+
+	target := f.Table
+	var table, staging *bigquery.Table
+	if f.Staging != "" {
+		client, err := bigquery.NewClient(ctx, f.Project, bigqueryClientOptions...)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		dataset := client.DatasetInProject(f.Table.Project, f.Table.Dataset)
+		table, staging = dataset.Table(f.Table.Table), dataset.Table(f.Staging)
+		if _, err := staging.Metadata(ctx); isNotFound(err) {
+			// The staging table is deleted once it's copied, by a previous
+			// attempt.
+			emit(int(rows))
+			return nil
+		}
+		target.Table = f.Staging
+	}
+
+	if len(streams) > 0 {
+		client, err := storage.NewBigQueryWriteClient(ctx, storageClientOptions...)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		resp, err := client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+			Parent:       tablePath(target),
+			WriteStreams: streams,
+		})
+		if err != nil {
+			return err
+		}
+		for _, e := range resp.GetStreamErrors() {
+			// Streams may have been committed by a previous attempt.
+			if e.GetCode() != storagepb.StorageError_STREAM_ALREADY_COMMITTED {
+				return fmt.Errorf("commit of %v failed: %v", e.GetEntity(), e.GetErrorMessage())
+			}
+		}
+	}
+	if staging != nil {
+		if err := replaceWithStaging(ctx, table, staging); err != nil {
+			return err
+		}
+	}
+	emit(int(rows))
+	return nil
+}
@@ -0,0 +1,788 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/civil"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	bq "google.golang.org/api/bigquery/v2"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*writeRow)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeAddress)(nil)).Elem())
+	beam.RegisterFunction(failedName)
+}
+
+var fakeWrite *fakeWriteServer
+
+type writeAddress struct {
+	City string `bigquery:"city"`
+}
+
+type writeRow struct {
+	Name    string             `bigquery:"name"`
+	Age     bigquery.NullInt64 `bigquery:"age"`
+	Scores  []float64          `bigquery:"scores"`
+	Created time.Time          `bigquery:"created"`
+	Day     civil.Date         `bigquery:"day"`
+	Address writeAddress       `bigquery:"address"`
+}
+
+var writeRows = []writeRow{
+	{Name: "a", Age: bigquery.NullInt64{Int64: 30, Valid: true}, Scores: []float64{1.5}, Created: created, Day: civil.Date{Year: 2024, Month: 1, Day: 2}, Address: writeAddress{City: "Paris"}},
+	{Name: "b", Scores: []float64{}, Created: created.Add(time.Hour), Day: civil.Date{Year: 1970, Month: 1, Day: 1}, Address: writeAddress{City: "Rome"}},
+	{Name: "c", Age: bigquery.NullInt64{Int64: 50, Valid: true}, Scores: []float64{2, 3}, Created: created.Add(2 * time.Hour), Day: civil.Date{Year: 1969, Month: 12, Day: 31}},
+}
+
+// writtenRows are writeRows as stored by the fake server.
+var writtenRows = []map[string]any{
+	{"name": "a", "age": int64(30), "scores": []any{1.5}, "created": created.UnixMicro(), "day": int32(19724), "address": map[string]any{"city": "Paris"}},
+	{"name": "b", "created": created.Add(time.Hour).UnixMicro(), "day": int32(0), "address": map[string]any{"city": "Rome"}},
+	{"name": "c", "age": int64(50), "scores": []any{2.0, 3.0}, "created": created.Add(2 * time.Hour).UnixMicro(), "day": int32(-1), "address": map[string]any{"city": ""}},
+}
+
+// fakeTable is a table of the fake server.
+type fakeTable struct {
+	schema *bq.TableSchema
+	rows   []map[string]any
+	etag   int
+}
+
+// fakeStream is a write stream of the fake server.
+type fakeStream struct {
+	table     string
+	typ       storagepb.WriteStream_Type
+	rows      []map[string]any
+	finalized bool
+	committed bool
+}
+
+// fakeWriteServer is a fake of the Storage Write API and of the tables and
+// jobs resources of the BigQuery API. Rows of default and committed streams
+// are added to their table when appended, and rows of pending streams when
+// committed. Jobs are copy jobs, which are done when they're inserted.
+type fakeWriteServer struct {
+	storagepb.UnimplementedBigQueryWriteServer
+
+	mu      sync.Mutex
+	tables  map[string]*fakeTable
+	streams map[string]*fakeStream
+	jobs    map[string]*bq.Job
+	// lostResponses is the number of appends that succeed but fail with an
+	// unavailable error.
+	lostResponses int
+	// staleSchemas is the number of appends that fail because the schema of
+	// the stream isn't updated yet.
+	staleSchemas int
+	appends      int
+}
+
+func newFakeWriteServer() *fakeWriteServer {
+	s := &fakeWriteServer{}
+	s.reset()
+	return s
+}
+
+func (s *fakeWriteServer) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables = make(map[string]*fakeTable)
+	s.streams = make(map[string]*fakeStream)
+	s.jobs = make(map[string]*bq.Job)
+	s.lostResponses, s.staleSchemas, s.appends = 0, 0, 0
+}
+
+// createTable creates a table with the fields and rows.
+func (s *fakeWriteServer) createTable(path string, fields []*bq.TableFieldSchema, rows ...map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables[path] = &fakeTable{schema: &bq.TableSchema{Fields: fields}, rows: rows}
+}
+
+func (s *fakeWriteServer) table(path string) *fakeTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tables[path]
+}
+
+// ServeHTTP serves the tables and jobs resources of the BigQuery API.
+func (s *fakeWriteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/bigquery/v2"), "/")
+	writeError := func(code int, msg string) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": code, "message": msg}})
+	}
+	writeTable := func(path string, t *fakeTable) {
+		parts := strings.Split(path, "/")
+		json.NewEncoder(w).Encode(&bq.Table{
+			TableReference: &bq.TableReference{ProjectId: parts[1], DatasetId: parts[3], TableId: parts[5]},
+			Schema:         t.schema,
+			NumRows:        uint64(len(t.rows)),
+			Etag:           strconv.Itoa(t.etag),
+		})
+	}
+
+	if strings.Contains(path, "/jobs") {
+		s.serveJob(w, r, path)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var table bq.Table
+		if err := json.NewDecoder(r.Body).Decode(&table); err != nil {
+			writeError(http.StatusBadRequest, err.Error())
+			return
+		}
+		path := fmt.Sprintf("%v/%v", path, table.TableReference.TableId)
+		if _, ok := s.tables[path]; ok {
+			writeError(http.StatusConflict, "Already Exists")
+			return
+		}
+		s.tables[path] = &fakeTable{schema: table.Schema}
+		writeTable(path, s.tables[path])
+		return
+	}
+
+	t, ok := s.tables[path]
+	if !ok {
+		writeError(http.StatusNotFound, "Not found: Table "+path)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeTable(path, t)
+	case http.MethodDelete:
+		delete(s.tables, path)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		if etag := r.Header.Get("If-Match"); etag != "" && etag != strconv.Itoa(t.etag) {
+			writeError(http.StatusPreconditionFailed, "Precondition Failed")
+			return
+		}
+		var table bq.Table
+		if err := json.NewDecoder(r.Body).Decode(&table); err != nil {
+			writeError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if table.Schema != nil {
+			t.schema = table.Schema
+		}
+		t.etag++
+		writeTable(path, t)
+	default:
+		writeError(http.StatusMethodNotAllowed, r.Method)
+	}
+}
+
+// serveJob inserts and gets copy jobs.
+func (s *fakeWriteServer) serveJob(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method == http.MethodGet {
+		parts := strings.Split(path, "/")
+		job, ok := s.jobs[parts[len(parts)-1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	var job bq.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	tablePath := func(ref *bq.TableReference) string {
+		return fmt.Sprintf("projects/%v/datasets/%v/tables/%v", ref.ProjectId, ref.DatasetId, ref.TableId)
+	}
+	cp := job.Configuration.Copy
+	src, dst := s.tables[tablePath(cp.SourceTables[0])], s.tables[tablePath(cp.DestinationTable)]
+	job.Status = &bq.JobStatus{State: "DONE"}
+	switch {
+	case src == nil || dst == nil:
+		job.Status.ErrorResult = &bq.ErrorProto{Reason: "notFound", Message: "Not found: Table"}
+	case cp.WriteDisposition != "WRITE_TRUNCATE":
+		job.Status.ErrorResult = &bq.ErrorProto{Reason: "invalid", Message: "unsupported write disposition " + cp.WriteDisposition}
+	default:
+		dst.schema = src.schema
+		dst.rows = append([]map[string]any(nil), src.rows...)
+		dst.etag++
+	}
+	s.jobs[job.JobReference.JobId] = &job
+	json.NewEncoder(w).Encode(&job)
+}
+
+func (s *fakeWriteServer) CreateWriteStream(_ context.Context, req *storagepb.CreateWriteStreamRequest) (*storagepb.WriteStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tables[req.GetParent()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "table %v not found", req.GetParent())
+	}
+	name := fmt.Sprintf("%v/streams/s%v", req.GetParent(), len(s.streams))
+	s.streams[name] = &fakeStream{table: req.GetParent(), typ: req.GetWriteStream().GetType()}
+	return &storagepb.WriteStream{Name: name, Type: req.GetWriteStream().GetType()}, nil
+}
+
+func (s *fakeWriteServer) AppendRows(srv storagepb.BigQueryWrite_AppendRowsServer) error {
+	var md protoreflect.MessageDescriptor
+	for {
+		req, err := srv.Recv()
+		if err != nil {
+			return nil
+		}
+		if dp := req.GetProtoRows().GetWriterSchema().GetProtoDescriptor(); dp != nil {
+			fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+				Name:        proto.String("fake.proto"),
+				Syntax:      proto.String("proto2"),
+				MessageType: []*descriptorpb.DescriptorProto{dp},
+			}, nil)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid descriptor: %v", err)
+			}
+			md = fd.Messages().Get(0)
+		}
+		if md == nil {
+			return status.Error(codes.InvalidArgument, "missing writer schema")
+		}
+		if err := srv.Send(s.append(req, md)); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *fakeWriteServer) append(req *storagepb.AppendRowsRequest, md protoreflect.MessageDescriptor) *storagepb.AppendRowsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appends++
+	fail := func(code codes.Code, msg string) *storagepb.AppendRowsResponse {
+		return &storagepb.AppendRowsResponse{Response: &storagepb.AppendRowsResponse_Error{
+			Error: &statuspb.Status{Code: int32(code), Message: msg},
+		}}
+	}
+
+	name := req.GetWriteStream()
+	stream, ok := s.streams[name]
+	if strings.HasSuffix(name, "/streams/_default") {
+		stream, ok = &fakeStream{table: strings.TrimSuffix(name, "/streams/_default"), typ: storagepb.WriteStream_COMMITTED}, true
+	}
+	if !ok {
+		return fail(codes.NotFound, "stream not found")
+	}
+	if stream.finalized {
+		return fail(codes.InvalidArgument, "stream is finalized")
+	}
+	table, ok := s.tables[stream.table]
+	if !ok {
+		return fail(codes.NotFound, "table not found")
+	}
+	if s.staleSchemas > 0 {
+		s.staleSchemas--
+		return fail(codes.InvalidArgument, "Input schema has more fields than BigQuery schema")
+	}
+	if !fieldsMatch(md, table.schema.Fields) {
+		return fail(codes.InvalidArgument, "Input schema has more fields than BigQuery schema")
+	}
+	if off := req.GetOffset(); off != nil && strings.Contains(name, "/streams/s") {
+		switch {
+		case off.GetValue() < int64(len(stream.rows)):
+			return fail(codes.AlreadyExists, "offset already exists")
+		case off.GetValue() > int64(len(stream.rows)):
+			return fail(codes.OutOfRange, "offset out of range")
+		}
+	}
+
+	var rows []map[string]any
+	var rowErrors []*storagepb.RowError
+	for i, data := range req.GetProtoRows().GetRows().GetSerializedRows() {
+		m := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(data, m); err != nil {
+			return fail(codes.InvalidArgument, err.Error())
+		}
+		row := messageRow(m)
+		if missing := missingField(row, table.schema.Fields); missing != "" {
+			rowErrors = append(rowErrors, &storagepb.RowError{
+				Index:   int64(i),
+				Code:    storagepb.RowError_FIELDS_ERROR,
+				Message: "missing required field " + missing,
+			})
+		}
+		rows = append(rows, row)
+	}
+	if len(rowErrors) > 0 {
+		return &storagepb.AppendRowsResponse{
+			Response:  &storagepb.AppendRowsResponse_Error{Error: &statuspb.Status{Code: int32(codes.InvalidArgument), Message: "row errors"}},
+			RowErrors: rowErrors,
+		}
+	}
+
+	offset := int64(len(stream.rows))
+	stream.rows = append(stream.rows, rows...)
+	if stream.typ != storagepb.WriteStream_PENDING {
+		table.rows = append(table.rows, rows...)
+	}
+	if s.lostResponses > 0 {
+		s.lostResponses--
+		return fail(codes.Unavailable, "connection reset")
+	}
+	return &storagepb.AppendRowsResponse{Response: &storagepb.AppendRowsResponse_AppendResult_{
+		AppendResult: &storagepb.AppendRowsResponse_AppendResult{Offset: wrapperspb.Int64(offset)},
+	}}
+}
+
+// fieldsMatch returns whether the fields of the descriptor are fields of
+// the table.
+func fieldsMatch(md protoreflect.MessageDescriptor, fields []*bq.TableFieldSchema) bool {
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		var tf *bq.TableFieldSchema
+		for _, f := range fields {
+			if strings.EqualFold(f.Name, string(fd.Name())) {
+				tf = f
+			}
+		}
+		if tf == nil || fd.Message() != nil && !fieldsMatch(fd.Message(), tf.Fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// missingField returns the name of a required field missing from the row.
+func missingField(row map[string]any, fields []*bq.TableFieldSchema) string {
+	for _, f := range fields {
+		v, ok := row[f.Name]
+		if f.Mode == "REQUIRED" && !ok {
+			return f.Name
+		}
+		if m, ok := v.(map[string]any); ok {
+			if missing := missingField(m, f.Fields); missing != "" {
+				return f.Name + "." + missing
+			}
+		}
+	}
+	return ""
+}
+
+// messageRow returns the fields set in a message.
+func messageRow(m protoreflect.Message) map[string]any {
+	row := make(map[string]any)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			var list []any
+			for i := 0; i < v.List().Len(); i++ {
+				if fd.Message() != nil {
+					list = append(list, messageRow(v.List().Get(i).Message()))
+				} else {
+					list = append(list, v.List().Get(i).Interface())
+				}
+			}
+			row[string(fd.Name())] = list
+		case fd.Message() != nil:
+			row[string(fd.Name())] = messageRow(v.Message())
+		default:
+			row[string(fd.Name())] = v.Interface()
+		}
+		return true
+	})
+	return row
+}
+
+func (s *fakeWriteServer) FinalizeWriteStream(_ context.Context, req *storagepb.FinalizeWriteStreamRequest) (*storagepb.FinalizeWriteStreamResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "stream %v not found", req.GetName())
+	}
+	stream.finalized = true
+	return &storagepb.FinalizeWriteStreamResponse{RowCount: int64(len(stream.rows))}, nil
+}
+
+func (s *fakeWriteServer) BatchCommitWriteStreams(_ context.Context, req *storagepb.BatchCommitWriteStreamsRequest) (*storagepb.BatchCommitWriteStreamsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &storagepb.BatchCommitWriteStreamsResponse{}
+	for _, name := range req.GetWriteStreams() {
+		stream, ok := s.streams[name]
+		switch {
+		case !ok || stream.typ != storagepb.WriteStream_PENDING:
+			resp.StreamErrors = append(resp.StreamErrors, &storagepb.StorageError{Code: storagepb.StorageError_INVALID_STREAM_TYPE, Entity: name})
+		case stream.committed:
+			resp.StreamErrors = append(resp.StreamErrors, &storagepb.StorageError{Code: storagepb.StorageError_STREAM_ALREADY_COMMITTED, Entity: name})
+		case !stream.finalized:
+			resp.StreamErrors = append(resp.StreamErrors, &storagepb.StorageError{Code: storagepb.StorageError_STREAM_NOT_FOUND, Entity: name})
+		}
+	}
+	if len(resp.StreamErrors) > 0 {
+		return resp, nil
+	}
+	for _, name := range req.GetWriteStreams() {
+		stream := s.streams[name]
+		stream.committed = true
+		s.tables[stream.table].rows = append(s.tables[stream.table].rows, stream.rows...)
+	}
+	resp.CommitTime = timestamppb.Now()
+	return resp, nil
+}
+
+const writeTable = "projects/p/datasets/d/tables/t"
+
+// sortedRows returns the rows of the table sorted by name.
+func sortedRows(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	table := fakeWrite.table(path)
+	if table == nil {
+		t.Fatalf("table %v doesn't exist", path)
+	}
+	rows := append([]map[string]any(nil), table.rows...)
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i]["name"].(string) < rows[j]["name"].(string)
+	})
+	return rows
+}
+
+func writeStorage(t *testing.T, want int, options ...WriteOption) error {
+	t.Helper()
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.CreateList(s, writeRows)
+	written, failed := WriteStorage(s, "project", "p:d.t", col, options...)
+	passert.Equals(s, written, want)
+	passert.Empty(s, failed)
+	return ptest.Run(p)
+}
+
+func TestWriteStorage(t *testing.T) {
+	startFakes(t)
+
+	appendBackoff = time.Millisecond
+	for _, mode := range []StorageWriteMode{DefaultStream, CommittedStreams, PendingStreams} {
+		t.Run(string(mode), func(t *testing.T) {
+			fakeWrite.reset()
+			if err := writeStorage(t, len(writeRows), WithStorageWriteMode(mode), WithStorageWriteStreams(2)); err != nil {
+				t.Fatalf("pipeline failed: %v", err)
+			}
+			if got, want := sortedRows(t, writeTable), writtenRows; !reflect.DeepEqual(got, want) {
+				t.Errorf("written rows = %v, want %v", got, want)
+			}
+
+			schema := fakeWrite.table(writeTable).schema
+			var names []string
+			for _, f := range schema.Fields {
+				names = append(names, f.Name+":"+f.Type+":"+f.Mode)
+			}
+			want := []string{"name:STRING:REQUIRED", "age:INTEGER:", "scores:FLOAT:REPEATED", "created:TIMESTAMP:REQUIRED", "day:DATE:REQUIRED", "address:RECORD:REQUIRED"}
+			if !reflect.DeepEqual(names, want) {
+				t.Errorf("created schema = %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+func TestWriteStorage_retry(t *testing.T) {
+	startFakes(t)
+
+	appendBackoff = time.Millisecond
+	for _, mode := range []StorageWriteMode{CommittedStreams, PendingStreams} {
+		t.Run(string(mode), func(t *testing.T) {
+			fakeWrite.reset()
+			fakeWrite.lostResponses = 1
+			fakeWrite.staleSchemas = 1
+			if err := writeStorage(t, len(writeRows), WithStorageWriteMode(mode), WithStorageWriteStreams(1)); err != nil {
+				t.Fatalf("pipeline failed: %v", err)
+			}
+			if got, want := sortedRows(t, writeTable), writtenRows; !reflect.DeepEqual(got, want) {
+				t.Errorf("written rows = %v, want %v", got, want)
+			}
+			if got, want := fakeWrite.appends, 3; got != want {
+				t.Errorf("got %v appends, want %v", got, want)
+			}
+		})
+	}
+}
+
+// tableFields are the fields of the schema of writeRow.
+func tableFields() []*bq.TableFieldSchema {
+	return []*bq.TableFieldSchema{
+		{Name: "name", Type: "STRING", Mode: "REQUIRED"},
+		{Name: "age", Type: "INTEGER"},
+		{Name: "scores", Type: "FLOAT", Mode: "REPEATED"},
+		{Name: "created", Type: "TIMESTAMP", Mode: "REQUIRED"},
+		{Name: "day", Type: "DATE", Mode: "REQUIRED"},
+		{Name: "address", Type: "RECORD", Mode: "REQUIRED", Fields: []*bq.TableFieldSchema{{Name: "city", Type: "STRING", Mode: "REQUIRED"}}},
+	}
+}
+
+func TestWriteStorage_writeDisposition(t *testing.T) {
+	startFakes(t)
+
+	appendBackoff = time.Millisecond
+	existing := map[string]any{"name": "0"}
+	tests := []struct {
+		disposition bigquery.TableWriteDisposition
+		mode        StorageWriteMode
+		want        []map[string]any
+		wantErr     bool
+	}{
+		{disposition: bigquery.WriteAppend, want: append([]map[string]any{existing}, writtenRows...)},
+		{disposition: bigquery.WriteTruncate, want: writtenRows},
+		{disposition: bigquery.WriteTruncate, mode: PendingStreams, want: writtenRows},
+		{disposition: bigquery.WriteEmpty, want: []map[string]any{existing}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(string(test.disposition)+string(test.mode), func(t *testing.T) {
+			fakeWrite.reset()
+			fakeWrite.createTable(writeTable, tableFields(), existing)
+			table := fakeWrite.table(writeTable)
+
+			opts := []WriteOption{WithWriteDisposition(test.disposition)}
+			if test.mode != "" {
+				opts = append(opts, WithStorageWriteMode(test.mode))
+			}
+			err := writeStorage(t, len(writeRows), opts...)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("pipeline error = %v, want error %v", err, test.wantErr)
+			}
+			if got := sortedRows(t, writeTable); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("table rows = %v, want %v", got, test.want)
+			}
+			if fakeWrite.table(writeTable) != table {
+				t.Error("table was recreated, want its rows replaced")
+			}
+			if got := len(fakeWrite.tables); got != 1 {
+				t.Errorf("got %v tables, want the staging table to be deleted", got)
+			}
+		})
+	}
+
+	// Tables aren't truncated if the write fails.
+	fakeWrite.reset()
+	fakeWrite.createTable(writeTable, tableFields(), existing)
+	fakeWrite.staleSchemas = math.MaxInt
+	if err := writeStorage(t, len(writeRows), WithWriteDisposition(bigquery.WriteTruncate)); err == nil {
+		t.Fatal("pipeline with failed appends succeeded, want error")
+	}
+	if got, want := sortedRows(t, writeTable), []map[string]any{existing}; !reflect.DeepEqual(got, want) {
+		t.Errorf("table rows after failed write = %v, want %v", got, want)
+	}
+
+	fakeWrite.reset()
+	if err := writeStorage(t, len(writeRows), WithCreateDisposition(bigquery.CreateNever)); err == nil {
+		t.Error("pipeline with CreateNever and no table succeeded, want error")
+	}
+}
+
+func TestWrite_unsupportedOptions(t *testing.T) {
+	for _, opt := range []WriteOption{WithWriteDisposition(bigquery.WriteTruncate), WithAllowFieldAddition()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Write with unsupported option succeeded, want panic")
+				}
+			}()
+			_, s := beam.NewPipelineWithRoot()
+			Write(s, "project", "p:d.t", beam.CreateList(s, writeRows), opt)
+		}()
+	}
+}
+
+func TestWriteStorage_schemaUpdate(t *testing.T) {
+	startFakes(t)
+
+	fakeWrite.reset()
+	fakeWrite.createTable(writeTable, tableFields()[:1])
+	if err := writeStorage(t, len(writeRows)); err == nil {
+		t.Fatal("pipeline with missing fields succeeded, want error")
+	}
+
+	fakeWrite.reset()
+	fields := tableFields()
+	fields[1].Mode = "REQUIRED"
+	fakeWrite.createTable(writeTable, fields[:2])
+	if err := writeStorage(t, len(writeRows), WithAllowFieldAddition(), WithAllowFieldRelaxation()); err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+	if got, want := sortedRows(t, writeTable), writtenRows; !reflect.DeepEqual(got, want) {
+		t.Errorf("written rows = %v, want %v", got, want)
+	}
+	var got []string
+	for _, f := range fakeWrite.table(writeTable).schema.Fields {
+		got = append(got, f.Name+":"+f.Mode)
+	}
+	want := []string{"name:REQUIRED", "age:", "scores:REPEATED", "created:", "day:", "address:"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("updated schema = %v, want %v", got, want)
+	}
+}
+
+func failedName(row writeRow, msg string) string {
+	return row.Name + ": " + msg
+}
+
+func TestWriteStorage_failedRows(t *testing.T) {
+	startFakes(t)
+
+	for _, mode := range []StorageWriteMode{DefaultStream, PendingStreams} {
+		t.Run(string(mode), func(t *testing.T) {
+			fakeWrite.reset()
+			fields := tableFields()
+			fields[1].Mode = "REQUIRED"
+			fakeWrite.createTable(writeTable, fields)
+
+			p, s := beam.NewPipelineWithRoot()
+			col := beam.CreateList(s, writeRows)
+			written, failed := WriteStorage(s, "project", "p:d.t", col, WithStorageWriteMode(mode), WithStorageWriteStreams(1))
+			passert.Equals(s, written, 2)
+			passert.Equals(s, beam.ParDo(s, failedName, failed), "b: missing required field age")
+			ptest.RunAndValidate(t, p)
+
+			want := []map[string]any{writtenRows[0], writtenRows[2]}
+			if got := sortedRows(t, writeTable); !reflect.DeepEqual(got, want) {
+				t.Errorf("written rows = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRowEncoder(t *testing.T) {
+	type nested struct {
+		N int `bigquery:"n"`
+	}
+	type row struct {
+		Int      int                   `bigquery:"int"`
+		Float    float32               `bigquery:"float"`
+		Bool     bool                  `bigquery:"bool"`
+		Bytes    []byte                `bigquery:"bytes"`
+		Time     civil.Time            `bigquery:"time"`
+		DateTime bigquery.NullDateTime `bigquery:"datetime"`
+		Numeric  *big.Rat              `bigquery:"numeric"`
+		Nested   []nested              `bigquery:"nested"`
+		Optional *nested               `bigquery:"optional"`
+		Skipped  string                `bigquery:"-"`
+		Renamed  string                `bigquery:"field_name"`
+	}
+	enc, err := newRowEncoder(reflect.TypeOf(row{}))
+	if err != nil {
+		t.Fatalf("newRowEncoder() failed: %v", err)
+	}
+
+	data, err := enc.encode(row{
+		Int:      -1,
+		Float:    0.5,
+		Bool:     true,
+		Bytes:    []byte("b"),
+		Time:     civil.Time{Hour: 1, Minute: 2, Second: 3},
+		DateTime: bigquery.NullDateTime{DateTime: civil.DateTime{Date: civil.Date{Year: 2024, Month: 1, Day: 2}}, Valid: true},
+		Numeric:  big.NewRat(1, 4),
+		Nested:   []nested{{1}, {2}},
+		Skipped:  "skipped",
+		Renamed:  "renamed",
+	})
+	if err != nil {
+		t.Fatalf("encode() failed: %v", err)
+	}
+	m := dynamicpb.NewMessage(enc.message)
+	if err := proto.Unmarshal(data, m); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	got := messageRow(m)
+	want := map[string]any{
+		"int":        int64(-1),
+		"float":      0.5,
+		"bool":       true,
+		"bytes":      []byte("b"),
+		"time":       "01:02:03",
+		"datetime":   "2024-01-02 00:00:00",
+		"numeric":    "0.250000000",
+		"nested":     []any{map[string]any{"n": int64(1)}, map[string]any{"n": int64(2)}},
+		"field_name": "renamed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encode() = %v, want %v", got, want)
+	}
+
+	if _, err := enc.encode(struct{ Int string }{}); err == nil {
+		t.Error("encode() of another type succeeded, want error")
+	}
+}
+
+func TestUpdateSchema(t *testing.T) {
+	current := bigquery.Schema{
+		{Name: "a", Type: bigquery.StringFieldType, Required: true},
+		{Name: "r", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "x", Type: bigquery.IntegerFieldType}}},
+	}
+	schema := bigquery.Schema{
+		{Name: "A", Type: bigquery.StringFieldType},
+		{Name: "r", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "x", Type: bigquery.IntegerFieldType}, {Name: "y", Type: bigquery.IntegerFieldType, Required: true}}},
+		{Name: "b", Type: bigquery.BooleanFieldType, Required: true},
+	}
+
+	if _, _, err := updateSchema(current, schema, writeOptions{}); err == nil {
+		t.Error("updateSchema() without field addition succeeded, want error")
+	}
+	got, changed, err := updateSchema(current, schema, writeOptions{AllowFieldAddition: true, AllowFieldRelaxation: true})
+	if err != nil {
+		t.Fatalf("updateSchema() failed: %v", err)
+	}
+	want := bigquery.Schema{
+		{Name: "a", Type: bigquery.StringFieldType},
+		{Name: "r", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "x", Type: bigquery.IntegerFieldType}, {Name: "y", Type: bigquery.IntegerFieldType}}},
+		{Name: "b", Type: bigquery.BooleanFieldType},
+	}
+	if !changed || !reflect.DeepEqual(got, want) {
+		t.Errorf("updateSchema() = %v, %v, want %v, true", got, changed, want)
+	}
+	if !current[0].Required || len(current[1].Schema) != 1 {
+		t.Errorf("updateSchema() modified the current schema: %v", current)
+	}
+
+	if _, changed, err := updateSchema(want, schema, writeOptions{}); err != nil || changed {
+		t.Errorf("updateSchema() of an updated schema = %v, %v, want false, nil", changed, err)
+	}
+}
@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigqueryio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+)
+
+// stagingExpiration is how long staging tables are kept if a write fails
+// before they're copied over the table written.
+const stagingExpiration = 24 * time.Hour
+
+// prepareTable prepares a table to be written with rows of the schema before
+// any row is written, according to the create disposition and the schema
+// update options, and checks that it's empty if the write disposition is
+// WriteEmpty. If staging isn't nil, the rows are written to it instead, to
+// truncate the table atomically: the staging table is created with the
+// settings of the table and the updated schema, and the table is left as is
+// until replaceWithStaging copies the staging table over it.
+func prepareTable(ctx context.Context, table, staging *bigquery.Table, schema bigquery.Schema, opts writeOptions) error {
+	md, err := table.Metadata(ctx)
+	switch {
+	case isNotFound(err):
+		if opts.CreateDisposition == bigquery.CreateNever {
+			return fmt.Errorf("table does not exist and create disposition is 'CreateNever': %v", err)
+		}
+		md = &bigquery.TableMetadata{Schema: schema}
+		if err := table.Create(ctx, md); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case opts.WriteDisposition == bigquery.WriteEmpty:
+		rows := md.NumRows
+		if md.StreamingBuffer != nil {
+			rows += md.StreamingBuffer.EstimatedRows
+		}
+		if rows > 0 {
+			return fmt.Errorf("table %v is not empty and write disposition is 'WriteEmpty'", table.FullyQualifiedName())
+		}
+	}
+
+	updated, changed, err := updateSchema(md.Schema, schema, opts)
+	if err != nil {
+		return fmt.Errorf("schema of table %v: %v", table.FullyQualifiedName(), err)
+	}
+	if staging != nil {
+		err := staging.Create(ctx, &bigquery.TableMetadata{
+			Schema:                 updated,
+			TimePartitioning:       md.TimePartitioning,
+			RangePartitioning:      md.RangePartitioning,
+			RequirePartitionFilter: md.RequirePartitionFilter,
+			Clustering:             md.Clustering,
+			EncryptionConfig:       md.EncryptionConfig,
+			ExpirationTime:         time.Now().Add(stagingExpiration),
+		})
+		if isAlreadyExists(err) {
+			// The staging table was created by a previous attempt.
+			return nil
+		}
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	_, err = table.Update(ctx, bigquery.TableMetadataToUpdate{Schema: updated}, md.ETag)
+	return err
+}
+
+// replaceWithStaging replaces the rows and schema of a table with those of its
+// staging table in a single copy job, so that the table keeps its policies
+// and readers see either all of its previous rows or all of the rows written,
+// and then deletes the staging table.
+func replaceWithStaging(ctx context.Context, table, staging *bigquery.Table) error {
+	copier := table.CopierFrom(staging)
+	copier.CreateDisposition = bigquery.CreateNever
+	copier.WriteDisposition = bigquery.WriteTruncate
+	job, err := copier.Run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	if err := status.Err(); err != nil {
+		return fmt.Errorf("copy of %v to %v failed: %v", staging.FullyQualifiedName(), table.FullyQualifiedName(), err)
+	}
+
+	if err := staging.Delete(ctx); err != nil && !isNotFound(err) {
+		log.Warnf(ctx, "Failed to delete staging table %v, which expires in a day: %v", staging.FullyQualifiedName(), err)
+	}
+	return nil
+}
+
+// updateSchema returns the schema of a table updated to have the fields of
+// the schema of the rows, by adding missing fields if AllowFieldAddition is
+// set and relaxing required fields that are nullable in the rows if
+// AllowFieldRelaxation is set. Missing fields are an error otherwise.
+func updateSchema(current, schema bigquery.Schema, opts writeOptions) (bigquery.Schema, bool, error) {
+	updated := make(bigquery.Schema, len(current))
+	changed := false
+	for i, c := range current {
+		u := *c
+		updated[i] = &u
+	}
+	for _, f := range schema {
+		var c *bigquery.FieldSchema
+		for _, u := range updated {
+			if strings.EqualFold(u.Name, f.Name) {
+				c = u
+			}
+		}
+		switch {
+		case c == nil:
+			if !opts.AllowFieldAddition {
+				return nil, false, fmt.Errorf("field %v is missing", f.Name)
+			}
+			// Added fields can't be required.
+			added := *f
+			added.Required = false
+			updated = append(updated, &added)
+			changed = true
+		case c.Type == bigquery.RecordFieldType && f.Type == bigquery.RecordFieldType:
+			fields, ok, err := updateSchema(c.Schema, f.Schema, opts)
+			if err != nil {
+				return nil, false, fmt.Errorf("field %v: %v", f.Name, err)
+			}
+			c.Schema = fields
+			changed = changed || ok
+		}
+		if c != nil && c.Required && !f.Required && opts.AllowFieldRelaxation {
+			c.Required = false
+			changed = true
+		}
+	}
+	return updated, changed, nil
+}
@@ -0,0 +1,323 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtableio
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/bytekeyrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, keyRange, func(Row), error](&readFn{})
+	register.Emitter1[Row]()
+	beam.RegisterType(reflect.TypeOf((*Row)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*keyRange)(nil)).Elem())
+}
+
+// Row is a row read from Bigtable, with the cells of each of its column
+// families.
+type Row struct {
+	Key string
+	// Families are the column families of the row with cells, ordered by name.
+	Families []Family
+}
+
+// Family is a column family of a Row.
+type Family struct {
+	Name string
+	// Cells are ordered by column, and by decreasing timestamp within columns.
+	Cells []Cell
+}
+
+// Cell is the value of a column at a timestamp.
+type Cell struct {
+	// Column is the column qualifier, without the family.
+	Column string
+	Ts     bigtable.Timestamp
+	Value  []byte
+}
+
+// Cells returns the cells of the row in the given column family.
+func (r Row) Cells(family string) []Cell {
+	for _, f := range r.Families {
+		if f.Name == family {
+			return f.Cells
+		}
+	}
+	return nil
+}
+
+// keyRange is a range of row keys [Start, End) to read, where an empty End is
+// the end of the table.
+type keyRange struct {
+	Start, End string
+}
+
+// Read reads the rows of a Bigtable table and returns them as a
+// PCollection<bigtableio.Row>. By default, every row and cell is read; the
+// options restrict the row keys and cells read.
+//
+// Each row range is split at row keys sampled from the table, so that it can be
+// read in parallel, and runners can split it further as it is read.
+func Read(s beam.Scope, project, instanceID, table string, opts ...ReadOptionFn) beam.PCollection {
+	s = s.Scope("bigtableio.Read")
+
+	option := &readOption{}
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("bigtableio.Read: invalid option: %v", err))
+		}
+	}
+
+	ranges := mergeRanges(option.Ranges)
+	fn := &readFn{
+		Project:    project,
+		InstanceID: instanceID,
+		TableName:  table,
+		Families:   option.Families,
+		Columns:    option.Columns,
+		Latest:     option.Latest,
+		StartTime:  option.StartTime,
+		EndTime:    option.EndTime,
+	}
+	return beam.ParDo(s, fn, beam.CreateList(s, ranges))
+}
+
+// mergeRanges sorts the ranges and merges the ones that overlap or are
+// adjacent, so that no row is read twice. No ranges mean the whole table.
+func mergeRanges(ranges []keyRange) []keyRange {
+	if len(ranges) == 0 {
+		return []keyRange{{}}
+	}
+	sorted := append([]keyRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if last.End != "" && r.Start > last.End {
+			merged = append(merged, r)
+			continue
+		}
+		if last.End != "" && (r.End == "" || r.End > last.End) {
+			last.End = r.End
+		}
+	}
+	return merged
+}
+
+// prefixEnd returns the smallest key greater than the keys beginning with the
+// prefix, or an empty key if there is none.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return ""
+	}
+	end[len(end)-1]++
+	return string(end)
+}
+
+// readFn is an SDF that reads the rows of a range of row keys. Its restriction
+// is a byte key range within it.
+type readFn struct {
+	// Project is the project
+	Project string `json:"project"`
+	// InstanceID is the bigtable instanceID
+	InstanceID string `json:"instanceId"`
+	// TableName is the qualified table identifier.
+	TableName string `json:"tableName"`
+	// Families are the column families to read, or all if empty.
+	Families []string `json:"families"`
+	// Columns is a pattern matching the column qualifiers to read.
+	Columns string `json:"columns"`
+	// Latest is the number of cells to read per column, or all if 0.
+	Latest int `json:"latest"`
+	// StartTime and EndTime restrict the timestamps of the cells read.
+	StartTime bigtable.Timestamp `json:"startTime"`
+	EndTime   bigtable.Timestamp `json:"endTime"`
+
+	client *bigtable.Client
+	table  *bigtable.Table
+}
+
+// Setup creates the client, if it hasn't been already, as splitting
+// restrictions may happen before it's called.
+func (f *readFn) Setup(ctx context.Context) error {
+	if f.client != nil {
+		return nil
+	}
+	client, err := bigtable.NewClient(ctx, f.Project, f.InstanceID)
+	if err != nil {
+		return fmt.Errorf("could not create data operations client: %v", err)
+	}
+	f.client, f.table = client, client.Open(f.TableName)
+	return nil
+}
+
+func (f *readFn) Teardown() error {
+	if f.client == nil {
+		return nil
+	}
+	if err := f.client.Close(); err != nil {
+		return fmt.Errorf("could not close data operations client: %v", err)
+	}
+	f.client, f.table = nil, nil
+	return nil
+}
+
+// CreateInitialRestriction creates a byte key range restriction covering the
+// range of row keys.
+func (f *readFn) CreateInitialRestriction(r keyRange) bytekeyrange.Restriction {
+	return bytekeyrange.Restriction{Start: keyBytes(r.Start), End: keyBytes(r.End)}
+}
+
+// SplitRestriction splits each restriction at the row keys sampled from the
+// table, which delimit sections of roughly equal size.
+func (f *readFn) SplitRestriction(ctx context.Context, _ keyRange, rest bytekeyrange.Restriction) ([]bytekeyrange.Restriction, error) {
+	if err := f.Setup(ctx); err != nil {
+		return nil, err
+	}
+	sampled, err := f.table.SampleRowKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not sample row keys of %v: %v", f.TableName, err)
+	}
+	keys := make([][]byte, len(sampled))
+	for i, k := range sampled {
+		keys[i] = []byte(k)
+	}
+	return rest.SplitAt(keys), nil
+}
+
+// RestrictionSize returns the size of each restriction as the fraction of the
+// key space it covers, as the sizes of the rows in it are unknown.
+func (f *readFn) RestrictionSize(_ keyRange, rest bytekeyrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping bytekeyrange.Trackers for
+// each restriction.
+func (f *readFn) CreateTracker(rest bytekeyrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(bytekeyrange.NewTracker(rest))
+}
+
+// ProcessElement reads the rows with keys in the restriction, claiming each
+// row key before emitting the row.
+func (f *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, _ keyRange, emit func(Row)) error {
+	rest := rt.GetRestriction().(bytekeyrange.Restriction)
+	log.Infof(ctx, "Reading rows of %v in [%q, %q)", f.TableName, rest.Start, rest.End)
+
+	rows := bigtable.InfiniteRange(string(rest.Start))
+	if len(rest.End) > 0 {
+		rows = bigtable.NewRange(string(rest.Start), string(rest.End))
+	}
+	var opts []bigtable.ReadOption
+	if filter := f.filter(); filter != nil {
+		opts = append(opts, bigtable.RowFilter(filter))
+	}
+
+	stopped := false
+	err := f.table.ReadRows(ctx, rows, func(r bigtable.Row) bool {
+		if !rt.TryClaim([]byte(r.Key())) {
+			stopped = true
+			return false
+		}
+		emit(newRow(r))
+		return true
+	}, opts...)
+	if err != nil {
+		return fmt.Errorf("could not read rows of %v: %v", f.TableName, err)
+	}
+	if !stopped {
+		// Claim the end of the restriction, since the last row read may be
+		// before it.
+		rt.TryClaim([]byte(nil))
+	}
+	return nil
+}
+
+// filter returns the filter of the cells read, or nil to read every cell.
+func (f *readFn) filter() bigtable.Filter {
+	var filters []bigtable.Filter
+	if len(f.Families) > 0 {
+		quoted := make([]string, len(f.Families))
+		for i, family := range f.Families {
+			quoted[i] = regexp.QuoteMeta(family)
+		}
+		filters = append(filters, bigtable.FamilyFilter(strings.Join(quoted, "|")))
+	}
+	if f.Columns != "" {
+		filters = append(filters, bigtable.ColumnFilter(f.Columns))
+	}
+	if f.StartTime != 0 || f.EndTime != 0 {
+		filters = append(filters, bigtable.TimestampRangeFilterMicros(f.StartTime, f.EndTime))
+	}
+	if f.Latest > 0 {
+		filters = append(filters, bigtable.LatestNFilter(f.Latest))
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return filters[0]
+	default:
+		return bigtable.ChainFilters(filters...)
+	}
+}
+
+// newRow converts a row read with the client to a Row.
+func newRow(r bigtable.Row) Row {
+	row := Row{Key: r.Key()}
+	for name, items := range r {
+		family := Family{Name: name}
+		for _, item := range items {
+			family.Cells = append(family.Cells, Cell{
+				Column: strings.TrimPrefix(item.Column, name+":"),
+				Ts:     item.Timestamp,
+				Value:  item.Value,
+			})
+		}
+		row.Families = append(row.Families, family)
+	}
+	sort.Slice(row.Families, func(i, j int) bool {
+		return row.Families[i].Name < row.Families[j].Name
+	})
+	return row
+}
+
+// keyBytes returns the key as bytes, or nil if it's empty.
+func keyBytes(key string) []byte {
+	if key == "" {
+		return nil
+	}
+	return []byte(key)
+}
@@ -0,0 +1,112 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtableio
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+)
+
+type readOption struct {
+	Ranges    []keyRange
+	Families  []string
+	Columns   string
+	Latest    int
+	StartTime bigtable.Timestamp
+	EndTime   bigtable.Timestamp
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for reading
+// from Bigtable.
+type ReadOptionFn func(option *readOption) error
+
+// ReadRange restricts the rows read to those with keys in the range [start, end). An empty
+// end means the end of the table. Several ranges and prefixes can be read, and rows in
+// more than one of them are only read once. By default, the whole table is read.
+func ReadRange(start, end string) ReadOptionFn {
+	return func(o *readOption) error {
+		if end != "" && start >= end {
+			return errors.New("range start must be before its end")
+		}
+		o.Ranges = append(o.Ranges, keyRange{Start: start, End: end})
+		return nil
+	}
+}
+
+// ReadPrefix restricts the rows read to those with keys beginning with the prefix. Several
+// ranges and prefixes can be read, and rows in more than one of them are only read once.
+func ReadPrefix(prefix string) ReadOptionFn {
+	return func(o *readOption) error {
+		o.Ranges = append(o.Ranges, keyRange{Start: prefix, End: prefixEnd(prefix)})
+		return nil
+	}
+}
+
+// ReadFamilies restricts the cells read to those in the given column families. Rows with
+// no cells in them aren't read.
+func ReadFamilies(families ...string) ReadOptionFn {
+	return func(o *readOption) error {
+		if len(families) == 0 {
+			return errors.New("families must not be empty")
+		}
+		o.Families = append(o.Families, families...)
+		return nil
+	}
+}
+
+// ReadColumns restricts the cells read to those in columns whose qualifiers fully match the
+// RE2 regular expression pattern. Rows with no cells in them aren't read.
+func ReadColumns(pattern string) ReadOptionFn {
+	return func(o *readOption) error {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+		o.Columns = pattern
+		return nil
+	}
+}
+
+// ReadLatest restricts the cells read to the n latest cells of each column.
+func ReadLatest(n int) ReadOptionFn {
+	return func(o *readOption) error {
+		if n <= 0 {
+			return errors.New("number of cells must be greater than 0")
+		}
+		o.Latest = n
+		return nil
+	}
+}
+
+// ReadTimestampRange restricts the cells read to those with timestamps in the range
+// [start, end). A zero end means no upper bound.
+func ReadTimestampRange(start, end time.Time) ReadOptionFn {
+	return func(o *readOption) error {
+		if !end.IsZero() && !start.Before(end) {
+			return errors.New("timestamp range start must be before its end")
+		}
+		o.StartTime = bigtable.Time(start)
+		if start.IsZero() {
+			o.StartTime = 0
+		}
+		if !end.IsZero() {
+			o.EndTime = bigtable.Time(end)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,288 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtableio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/bytekeyrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testProject  = "project"
+	testInstance = "instance"
+	testTable    = "table"
+	numTestRows  = 200
+)
+
+// newTestTable starts an in-memory Bigtable server, which the clients of the
+// test connect to, with a table of numTestRows rows. Each row has a column in
+// family "a" and two versions of a column in family "b".
+func newTestTable(t *testing.T) {
+	t.Helper()
+	srv, err := bttest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	t.Setenv("BIGTABLE_EMULATOR_HOST", srv.Addr)
+
+	ctx := context.Background()
+	admin, err := bigtable.NewAdminClient(ctx, testProject, testInstance)
+	if err != nil {
+		t.Fatalf("failed to create admin client: %v", err)
+	}
+	defer admin.Close()
+	if err := admin.CreateTableFromConf(ctx, &bigtable.TableConf{
+		TableID:  testTable,
+		Families: map[string]bigtable.GCPolicy{"a": bigtable.NoGcPolicy(), "b": bigtable.NoGcPolicy()},
+	}); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	client, err := bigtable.NewClient(ctx, testProject, testInstance)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+	var keys []string
+	var muts []*bigtable.Mutation
+	for i := 0; i < numTestRows; i++ {
+		m := bigtable.NewMutation()
+		m.Set("a", "x", 1000, []byte(fmt.Sprint(i)))
+		m.Set("b", "y", 1000, []byte("old"))
+		m.Set("b", "y", 2000, []byte("new"))
+		keys = append(keys, testKey(i))
+		muts = append(muts, m)
+	}
+	if _, err := client.Open(testTable).ApplyBulk(ctx, keys, muts); err != nil {
+		t.Fatalf("failed to write rows: %v", err)
+	}
+}
+
+func testKey(i int) string {
+	return fmt.Sprintf("row%03d", i)
+}
+
+func testRow(i int) Row {
+	return Row{
+		Key: testKey(i),
+		Families: []Family{
+			{Name: "a", Cells: []Cell{{Column: "x", Ts: 1000, Value: []byte(fmt.Sprint(i))}}},
+			{Name: "b", Cells: []Cell{
+				{Column: "y", Ts: 2000, Value: []byte("new")},
+				{Column: "y", Ts: 1000, Value: []byte("old")},
+			}},
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	newTestTable(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, testProject, testInstance, testTable)
+	var want []any
+	for i := 0; i < numTestRows; i++ {
+		want = append(want, testRow(i))
+	}
+	passert.Equals(s, rows, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_ranges(t *testing.T) {
+	newTestTable(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, testProject, testInstance, testTable,
+		ReadPrefix("row01"),
+		ReadRange("row015", "row025"),
+		ReadRange("row150", ""))
+	var want []any
+	for i := 10; i < 25; i++ {
+		want = append(want, testRow(i))
+	}
+	for i := 150; i < numTestRows; i++ {
+		want = append(want, testRow(i))
+	}
+	passert.Equals(s, rows, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_filters(t *testing.T) {
+	newTestTable(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, testProject, testInstance, testTable,
+		ReadPrefix("row00"),
+		ReadFamilies("b"),
+		ReadColumns("y"),
+		ReadLatest(1))
+	var want []any
+	for i := 0; i < 10; i++ {
+		want = append(want, Row{
+			Key:      testKey(i),
+			Families: []Family{{Name: "b", Cells: []Cell{{Column: "y", Ts: 2000, Value: []byte("new")}}}},
+		})
+	}
+	passert.Equals(s, rows, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_timestampRange(t *testing.T) {
+	newTestTable(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, testProject, testInstance, testTable,
+		ReadRange("row000", "row002"),
+		ReadTimestampRange(time.Time{}, time.UnixMilli(2)))
+	var want []any
+	for i := 0; i < 2; i++ {
+		want = append(want, Row{
+			Key: testKey(i),
+			Families: []Family{
+				{Name: "a", Cells: []Cell{{Column: "x", Ts: 1000, Value: []byte(fmt.Sprint(i))}}},
+				{Name: "b", Cells: []Cell{{Column: "y", Ts: 1000, Value: []byte("old")}}},
+			},
+		})
+	}
+	passert.Equals(s, rows, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_badOption(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Read with an invalid option did not panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	Read(s, testProject, testInstance, testTable, ReadRange("b", "a"))
+}
+
+// TestReadFn_SplitRestriction tests that restrictions are split into
+// contiguous restrictions covering them.
+func TestReadFn_SplitRestriction(t *testing.T) {
+	newTestTable(t)
+
+	ctx := context.Background()
+	fn := &readFn{Project: testProject, InstanceID: testInstance, TableName: testTable}
+	defer fn.Teardown()
+
+	for _, r := range []keyRange{{}, {Start: "row050", End: "row100"}} {
+		rest := fn.CreateInitialRestriction(r)
+		splits, err := fn.SplitRestriction(ctx, r, rest)
+		if err != nil {
+			t.Fatalf("SplitRestriction(%v) failed: %v", r, err)
+		}
+		if !bytes.Equal(splits[0].Start, rest.Start) || !bytes.Equal(splits[len(splits)-1].End, rest.End) {
+			t.Errorf("SplitRestriction(%v) = %v, want splits from %q to %q", r, splits, rest.Start, rest.End)
+		}
+		for i := 1; i < len(splits); i++ {
+			if !bytes.Equal(splits[i-1].End, splits[i].Start) {
+				t.Errorf("SplitRestriction(%v) = %v, want contiguous splits", r, splits)
+			}
+		}
+	}
+}
+
+// TestReadFn_ProcessElement tests that only the rows in the restriction are
+// read, and that the restriction is done afterwards.
+func TestReadFn_ProcessElement(t *testing.T) {
+	newTestTable(t)
+
+	ctx := context.Background()
+	fn := &readFn{Project: testProject, InstanceID: testInstance, TableName: testTable}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	defer fn.Teardown()
+
+	for _, r := range []keyRange{{Start: "row190"}, {Start: "row010", End: "row012"}} {
+		rt := fn.CreateTracker(fn.CreateInitialRestriction(r))
+		var got []string
+		if err := fn.ProcessElement(ctx, rt, r, func(row Row) { got = append(got, row.Key) }); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", r, err)
+		}
+		if !rt.IsDone() {
+			t.Errorf("ProcessElement(%v) didn't finish the restriction: %v", r, rt.GetRestriction())
+		}
+		if want := len(got); want == 0 || got[0] != r.Start {
+			t.Errorf("ProcessElement(%v) read %v, want rows from %v", r, got, r.Start)
+		}
+	}
+
+	// Rows after the restriction is split aren't read.
+	rest := bytekeyrange.Restriction{Start: []byte("row000")}
+	rt := fn.CreateTracker(rest)
+	var got []string
+	err := fn.ProcessElement(ctx, rt, keyRange{}, func(row Row) {
+		got = append(got, row.Key)
+		if len(got) == 1 {
+			rt.TrySplit(0)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ProcessElement() failed: %v", err)
+	}
+	if want := []string{"row000"}; !cmp.Equal(got, want) {
+		t.Errorf("ProcessElement() split after the first row read %v, want %v", got, want)
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		ranges []keyRange
+		want   []keyRange
+	}{
+		{nil, []keyRange{{}}},
+		{[]keyRange{{"c", "d"}, {"a", "b"}}, []keyRange{{"a", "b"}, {"c", "d"}}},
+		{[]keyRange{{"a", "c"}, {"b", "d"}, {"d", "e"}}, []keyRange{{"a", "e"}}},
+		{[]keyRange{{"a", "c"}, {"b", ""}, {"x", "y"}}, []keyRange{{"a", ""}}},
+		{[]keyRange{{"a", "z"}, {"b", "c"}}, []keyRange{{"a", "z"}}},
+	}
+	for _, test := range tests {
+		if got := mergeRanges(test.ranges); !cmp.Equal(got, test.want) {
+			t.Errorf("mergeRanges(%v) = %v, want %v", test.ranges, got, test.want)
+		}
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix, want string
+	}{
+		{"", ""},
+		{"abc", "abd"},
+		{"ab\xff", "ac"},
+		{"\xff\xff", ""},
+	}
+	for _, test := range tests {
+		if got := prefixEnd(test.prefix); got != test.want {
+			t.Errorf("prefixEnd(%q) = %q, want %q", test.prefix, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package csvio contains transforms for reading and writing CSV files.
+package csvio
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn5x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(beam.X), func(ParseError), error](&readFn{})
+	register.Emitter1[beam.X]()
+	register.Emitter1[ParseError]()
+
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
+}
+
+// ParseError is a record that couldn't be read, output to the dead-letter
+// PCollection returned by Read.
+type ParseError = fileio.ParseError
+
+type readOption struct {
+	NoHeader   bool
+	Delimiter  rune
+	SingleLine bool
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for
+// reading files.
+type ReadOptionFn func(*readOption)
+
+// ReadNoHeader specifies that files have no header line. The columns are then
+// matched to the fields of the struct type in order.
+func ReadNoHeader() ReadOptionFn {
+	return func(o *readOption) {
+		o.NoHeader = true
+	}
+}
+
+// ReadDelimiter specifies the field delimiter, which is a comma by default.
+func ReadDelimiter(r rune) ReadOptionFn {
+	return func(o *readOption) {
+		o.Delimiter = r
+	}
+}
+
+// ReadSingleLineRecords specifies that quoted fields don't contain newlines,
+// so that files can be split at any line, without scanning for the end of a
+// quoted field spanning it.
+func ReadSingleLineRecords() ReadOptionFn {
+	return func(o *readOption) {
+		o.SingleLine = true
+	}
+}
+
+// Read reads a set of CSV files indicated by the glob pattern and returns the
+// records as a PCollection<t>, along with a PCollection<ParseError> of the
+// records that couldn't be read. For example:
+//
+//	type Trip struct {
+//	  Vendor   string   `csv:"vendor_id"`
+//	  Distance float64  `csv:"trip_distance"`
+//	  Tip      *float64 `csv:"tip_amount"`
+//	}
+//
+//	trips, errors := csvio.Read(s, "gs://bucket/trips/*.csv", reflect.TypeOf(Trip{}))
+//
+// The first line of each file is a header naming the columns, unless
+// ReadNoHeader is given. Columns are matched to the exported fields of t by
+// the name in their csv tag, their beam tag or their field name, in that
+// order, ignoring case if there's no exact match. Columns without a field are
+// ignored. Fields may be strings, booleans, integers, floats, types
+// implementing encoding.TextUnmarshaler such as time.Time, or pointers to
+// those, which are nil for empty values.
+//
+// If t is nil, the type is inferred from the header and first records of the
+// first file matching the glob when the pipeline is constructed, and the
+// records are returned as schema rows: structs with a field for each column,
+// with beam tags naming them after the header. Columns holding only booleans,
+// integers or numbers are typed as such, and are pointers if some values are
+// empty. Other columns are strings.
+//
+// Uncompressed files are split and read in parallel. Quoted fields may span
+// lines, so a split that starts inside a line looks for the start of the next
+// record by scanning forward until only one of the line being the start of a
+// record or the line being inside a quoted field leads to valid CSV. The scan
+// gives up after 1 MB, which fails the read if a quoted field runs on for
+// longer than that without quotes and a split lands inside it. Files compressed
+// as a whole are decompressed based on their extension or first bytes, and
+// read by a single worker.
+func Read(s beam.Scope, glob string, t reflect.Type, opts ...ReadOptionFn) (beam.PCollection, beam.PCollection) {
+	s = s.Scope("csvio.Read")
+	filesystem.ValidateScheme(glob)
+
+	option := &readOption{Delimiter: ','}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	var err error
+	if t == nil {
+		t, err = inferType(context.Background(), glob, option)
+	} else {
+		err = checkType(t)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("csvio.Read: %v", err))
+	}
+
+	fn := &readFn{Type: beam.EncodedType{T: t}, NoHeader: option.NoHeader, Delimiter: option.Delimiter, SingleLine: option.SingleLine}
+	matches := fileio.MatchAll(s, beam.Create(s, glob), fileio.MatchEmptyAllow())
+	files := fileio.ReadMatches(s, matches)
+	return beam.ParDo2(s, fn, files, beam.TypeDefinition{Var: beam.XType, T: t})
+}
+
+type writeOption struct {
+	NoHeader  bool
+	Delimiter rune
+}
+
+// WriteOptionFn is a function that can be passed to Write or NewSink to
+// configure options for writing files.
+type WriteOptionFn func(*writeOption)
+
+// WriteNoHeader specifies that files should not start with a header line.
+func WriteNoHeader() WriteOptionFn {
+	return func(o *writeOption) {
+		o.NoHeader = true
+	}
+}
+
+// WriteDelimiter specifies the field delimiter, which is a comma by default.
+func WriteDelimiter(r rune) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Delimiter = r
+	}
+}
+
+// Write writes a PCollection<T> of structs to a CSV file, with a column for
+// each exported field named as for Read. It returns a PCollection<string>
+// with the filename once the file has been written, which can be used to
+// sequence later steps with wait.On.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename string, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("csvio.Write")
+
+	return fileio.WriteFiles(s, filename, NewSink(col.Type().Type(), opts...), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
+}
+
+// NewSink returns a fileio.Sink that writes structs of type t as CSV records,
+// starting each file with a header unless WriteNoHeader is given.
+func NewSink(t reflect.Type, opts ...WriteOptionFn) fileio.Sink {
+	if err := checkType(t); err != nil {
+		panic(fmt.Sprintf("csvio.NewSink: %v", err))
+	}
+	option := &writeOption{Delimiter: ','}
+	for _, opt := range opts {
+		opt(option)
+	}
+	return &sink{Type: beam.EncodedType{T: t}, NoHeader: option.NoHeader, Delimiter: option.Delimiter}
+}
+
+type sink struct {
+	Type      beam.EncodedType
+	NoHeader  bool
+	Delimiter rune
+
+	buf    *bufio.Writer
+	w      *csv.Writer
+	fields []int
+	record []string
+}
+
+func (s *sink) Open(_ context.Context, w io.Writer) error {
+	s.buf = bufio.NewWriterSize(w, 1<<20) // use 1MB buffer
+	s.w = csv.NewWriter(s.buf)
+	s.w.Comma = s.Delimiter
+	var names []string
+	s.fields, names = columns(s.Type.T)
+	s.record = make([]string, len(s.fields))
+	if s.NoHeader {
+		return nil
+	}
+	return s.w.Write(names)
+}
+
+func (s *sink) Write(_ context.Context, elm any) error {
+	v := reflect.ValueOf(elm)
+	for i, f := range s.fields {
+		s.record[i] = formatValue(v.Field(f))
+	}
+	return s.w.Write(s.record)
+}
+
+func (s *sink) Flush(_ context.Context) error {
+	s.w.Flush()
+	if err := s.w.Error(); err != nil {
+		return err
+	}
+	return s.buf.Flush()
+}
@@ -0,0 +1,378 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvio
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	register.Function1x1(formatRow)
+	register.Function1x1(formatError)
+}
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+type Trip struct {
+	Vendor   string   `csv:"vendor_id"`
+	Distance float64  `csv:"trip_distance"`
+	Tip      *float64 `csv:"tip_amount"`
+	Note     string
+	Pickup   time.Time `beam:"pickup"`
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const trips = `vendor_id,trip_distance,tip_amount,note,pickup,extra
+1,2.5,1,plain,2024-01-02T03:04:05Z,x
+2,10,,"quoted, with comma",2024-01-02T03:04:05Z,x
+3,0.5,2,"spans
+two lines",2024-01-02T03:04:05Z,x
+4,bad,0,,2024-01-02T03:04:05Z,x
+5,1,0,"unterminated
+`
+
+func tip(f float64) *float64 {
+	return &f
+}
+
+func TestRead(t *testing.T) {
+	path := writeFile(t, "trips.csv", trips)
+	pickup := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	p, s := beam.NewPipelineWithRoot()
+	records, errors := Read(s, path, reflect.TypeOf(Trip{}))
+	passert.Equals(s, records,
+		Trip{Vendor: "1", Distance: 2.5, Tip: tip(1), Note: "plain", Pickup: pickup},
+		Trip{Vendor: "2", Distance: 10, Note: "quoted, with comma", Pickup: pickup},
+		Trip{Vendor: "3", Distance: 0.5, Tip: tip(2), Note: "spans\ntwo lines", Pickup: pickup},
+	)
+	passert.Equals(s, beam.ParDo(s, formatError, errors),
+		fmt.Sprintf(`%d: "4,bad,0,,2024-01-02T03:04:05Z,x\n": column trip_distance`, strings.Index(trips, "4,bad")),
+		fmt.Sprintf(`%d: "5,1,0,\"unterminated\n": parse error on line 7, column 21`, strings.Index(trips, "5,1,0")),
+	)
+	ptest.RunAndValidate(t, p)
+}
+
+// formatError formats the position, text and start of the error of a ParseError.
+func formatError(e ParseError) string {
+	msg, _, _ := strings.Cut(e.Error, ";")
+	msg, _, _ = strings.Cut(msg, ":")
+	return fmt.Sprintf("%d: %q: %v", e.Offset, e.Record, msg)
+}
+
+type Point struct {
+	X, Y int
+}
+
+func TestRead_noHeader(t *testing.T) {
+	path := writeFile(t, "points.tsv", "1\t2\n3\t4\n5\n")
+
+	p, s := beam.NewPipelineWithRoot()
+	records, errors := Read(s, path, reflect.TypeOf(Point{}), ReadNoHeader(), ReadDelimiter('\t'))
+	passert.Equals(s, records, Point{1, 2}, Point{3, 4})
+	passert.Count(s, errors, "errors", 1)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_rows(t *testing.T) {
+	path := writeFile(t, "rows.csv", "name,count,score,ok\na,1,1.5,true\nb,,2,false\n")
+
+	p, s := beam.NewPipelineWithRoot()
+	records, _ := Read(s, path, nil)
+	passert.Equals(s, beam.ParDo(s, formatRow, records), "a 1 1.5 true", "b <nil> 2 false")
+	ptest.RunAndValidate(t, p)
+}
+
+func formatRow(row beam.X) string {
+	v := reflect.ValueOf(row)
+	var fields []string
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr && !f.IsNil() {
+			f = f.Elem()
+		}
+		fields = append(fields, fmt.Sprint(f.Interface()))
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestRowType(t *testing.T) {
+	path := writeFile(t, "rows.csv", "name,count,score,ok,2nd col\na,1,1.5,true,x\nb,,2,false,1\n")
+	got, err := inferType(context.Background(), path, &readOption{Delimiter: ','})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := reflect.TypeOf(struct {
+		Name    string  `beam:"name" csv:"name"`
+		Count   *int64  `beam:"count" csv:"count"`
+		Score   float64 `beam:"score" csv:"score"`
+		Ok      bool    `beam:"ok" csv:"ok"`
+		X2ndCol string  `beam:"2nd col" csv:"2nd col"`
+	}{})
+	if got != want {
+		t.Errorf("inferType() = %v, want %v", got, want)
+	}
+}
+
+type Line struct {
+	N    int
+	Text string
+}
+
+// collect collects the output of readFn.
+type collect struct {
+	records []Line
+	errors  []ParseError
+}
+
+func (c *collect) emit(x beam.X) {
+	c.records = append(c.records, x.(Line))
+}
+
+func (c *collect) emitErr(e ParseError) {
+	c.errors = append(c.errors, e)
+}
+
+// writeGzip writes a gzip compressed file with the given data to a temporary
+// directory, and returns its path.
+func writeGzip(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write([]byte(data))
+	w.Close()
+	f.Close()
+	return path
+}
+
+// readSplits reads the file with fn, processing each restriction in order.
+func readSplits(t *testing.T, fn *readFn, file fileio.ReadableFile, splits []offsetrange.Restriction) collect {
+	t.Helper()
+	var c collect
+	for _, r := range splits {
+		rt := fn.CreateTracker(r)
+		if err := fn.ProcessElement(context.Background(), rt, file, c.emit, c.emitErr); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", r, err)
+		}
+		if !rt.IsDone() {
+			t.Errorf("ProcessElement(%v) didn't finish restriction", r)
+		}
+	}
+	if len(c.errors) != 0 {
+		t.Errorf("%v: got errors %v", file.Metadata.Path, c.errors)
+	}
+	sort.Slice(c.records, func(i, j int) bool { return c.records[i].N < c.records[j].N })
+	return c
+}
+
+func TestReadFn_split(t *testing.T) {
+	defer func(size int64) { blockSize = size }(blockSize)
+	blockSize = 16
+
+	var data strings.Builder
+	var want []Line
+	data.WriteString("N,Text\n")
+	for i := 0; i < 20; i++ {
+		// Some quoted fields have lines that look like records, or quotes.
+		text := fmt.Sprintf("line %d", i)
+		switch i % 4 {
+		case 0:
+			text = fmt.Sprintf("line\n%d", i)
+		case 1:
+			text = fmt.Sprintf("line,\n%d,x\n%d", i, i)
+		case 2:
+			text = fmt.Sprintf("\"line\"\n%d", i)
+		}
+		fmt.Fprintf(&data, "%d,\"%s\"\n", i, strings.ReplaceAll(text, `"`, `""`))
+		want = append(want, Line{i, text})
+	}
+	fn := &readFn{Type: beam.EncodedType{T: reflect.TypeOf(Line{})}, Delimiter: ','}
+
+	path := writeFile(t, "lines.csv", data.String())
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(data.Len())}}
+	rest := fn.CreateInitialRestriction(file)
+	splits := fn.SplitRestriction(file, rest)
+	if len(splits) < 2 {
+		t.Errorf("SplitRestriction(%v) = %v, want several splits", path, splits)
+	}
+	if c := readSplits(t, fn, file, splits); !reflect.DeepEqual(c.records, want) {
+		t.Errorf("%v: got %q, want %q", path, c.records, want)
+	}
+	// Every split point must read each record exactly once.
+	for split := int64(0); split <= rest.End; split++ {
+		c := readSplits(t, fn, file, []offsetrange.Restriction{{Start: 0, End: split}, {Start: split, End: rest.End}})
+		if !reflect.DeepEqual(c.records, want) {
+			t.Errorf("split at %v: got %q, want %q", split, c.records, want)
+		}
+	}
+
+	// Compressed files are read whole by the first restriction.
+	gz := writeGzip(t, "lines.csv.gz", data.String())
+	info, err := os.Stat(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file = fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: gz, Size: info.Size()}}
+	if splits := fn.SplitRestriction(file, fn.CreateInitialRestriction(file)); len(splits) != 1 {
+		t.Errorf("SplitRestriction(%v) = %v, want a single restriction", gz, splits)
+	}
+	if c := readSplits(t, fn, file, []offsetrange.Restriction{{Start: 0, End: 10}, {Start: 10, End: info.Size()}}); !reflect.DeepEqual(c.records, want) {
+		t.Errorf("%v: got %q, want %q", gz, c.records, want)
+	}
+}
+
+func TestReadFn_splitLongField(t *testing.T) {
+	defer func(size int) { resyncWindow = size }(resyncWindow)
+	resyncWindow = 16
+
+	data := "N,Text\n1,\"a\n1,b\n1,c\n1,d\n1,e\n1,f\n1,g\"\n2,h\n"
+	fn := &readFn{Type: beam.EncodedType{T: reflect.TypeOf(Line{})}, Delimiter: ','}
+	path := writeFile(t, "lines.csv", data)
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(len(data))}}
+
+	// Splitting near the end of the quoted field finds its end, while
+	// splitting too far from it is an error rather than a wrong read.
+	for _, test := range []struct {
+		split int64
+		fail  bool
+	}{
+		{split: 35, fail: false},
+		{split: 12, fail: true},
+	} {
+		var c collect
+		var err error
+		for _, r := range []offsetrange.Restriction{{Start: 0, End: test.split}, {Start: test.split, End: int64(len(data))}} {
+			if err = fn.ProcessElement(context.Background(), fn.CreateTracker(r), file, c.emit, c.emitErr); err != nil {
+				break
+			}
+		}
+		if got := err != nil; got != test.fail {
+			t.Errorf("split at %v: got error %v, want failure %v", test.split, err, test.fail)
+		}
+		if err == nil && len(c.records) != 2 {
+			t.Errorf("split at %v: got %q, want 2 records", test.split, c.records)
+		}
+	}
+}
+
+func TestReadFn_splitSingleLine(t *testing.T) {
+	defer func(size int64) { blockSize = size }(blockSize)
+	blockSize = 16
+
+	var data strings.Builder
+	var want []Line
+	data.WriteString("N,Text\n")
+	for i := 0; i < 20; i++ {
+		text := fmt.Sprintf("line, %d", i)
+		fmt.Fprintf(&data, "%d,\"%s\"\n", i, text)
+		want = append(want, Line{i, text})
+	}
+	fn := &readFn{Type: beam.EncodedType{T: reflect.TypeOf(Line{})}, Delimiter: ',', SingleLine: true}
+
+	path := writeFile(t, "lines.csv", data.String())
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(data.Len())}}
+	rest := fn.CreateInitialRestriction(file)
+	if splits := fn.SplitRestriction(file, rest); len(splits) < 2 {
+		t.Errorf("SplitRestriction(%v) = %v, want several splits", path, splits)
+	}
+	// Every split point must read each record exactly once.
+	for split := int64(0); split <= rest.End; split++ {
+		c := readSplits(t, fn, file, []offsetrange.Restriction{{Start: 0, End: split}, {Start: split, End: rest.End}})
+		if !reflect.DeepEqual(c.records, want) {
+			t.Errorf("split at %v: got %q, want %q", split, c.records, want)
+		}
+	}
+
+	// Compressed files are read whole by the first restriction.
+	gz := writeGzip(t, "lines.csv.gz", data.String())
+	info, err := os.Stat(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file = fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: gz, Size: info.Size()}}
+	splits := fn.SplitRestriction(file, fn.CreateInitialRestriction(file))
+	if c := readSplits(t, fn, file, splits); !reflect.DeepEqual(c.records, want) {
+		t.Errorf("%v: got %q, want %q", gz, c.records, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trips.csv")
+	pickup := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s,
+		Trip{Vendor: "1", Distance: 2.5, Tip: tip(1), Note: "plain", Pickup: pickup},
+		Trip{Vendor: "2", Distance: 10, Note: "quoted, with comma", Pickup: pickup},
+	)
+	Write(s, path, col)
+	ptest.RunAndValidate(t, p)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	sort.Strings(lines[1:])
+	want := []string{
+		"vendor_id,trip_distance,tip_amount,Note,pickup",
+		"1,2.5,1,plain,2024-01-02T03:04:05Z",
+		`2,10,,"quoted, with comma",2024-01-02T03:04:05Z`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Write() wrote %q, want %q", lines, want)
+	}
+}
+
+func TestNewSink_invalidType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewSink with a map field didn't panic")
+		}
+	}()
+	NewSink(reflect.TypeOf(struct{ M map[string]int }{}))
+}
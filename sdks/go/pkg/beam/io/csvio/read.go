@@ -0,0 +1,300 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvio
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+)
+
+// recordReader reads CSV records, keeping track of their position and text.
+type recordReader struct {
+	csv   *csv.Reader
+	raw   *rawReader
+	start int64
+}
+
+// newRecordReader returns a reader of the records of r, which is at the given
+// position in the file.
+func newRecordReader(r io.Reader, start int64, delimiter rune) *recordReader {
+	raw := &rawReader{r: r}
+	cr := csv.NewReader(raw)
+	cr.Comma = delimiter
+	cr.FieldsPerRecord = -1
+	return &recordReader{csv: cr, raw: raw, start: start}
+}
+
+// offset returns the position in the file of the next record.
+func (r *recordReader) offset() int64 {
+	return r.start + r.csv.InputOffset()
+}
+
+// next returns the next record and its text. It returns io.EOF at the end of
+// the file, and a *csv.ParseError for records that aren't valid CSV, after
+// which reading can continue with the next record.
+func (r *recordReader) next() ([]string, string, error) {
+	begin := r.csv.InputOffset()
+	record, err := r.csv.Read()
+	end := r.csv.InputOffset()
+	text := string(r.raw.buf[begin-r.raw.base : end-r.raw.base])
+	r.raw.discard(end)
+	return record, text, err
+}
+
+// rawReader keeps the data read from r that hasn't been discarded yet.
+type rawReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64 // the position of buf[0] in the data read
+}
+
+func (r *rawReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// discard drops the data before the given position.
+func (r *rawReader) discard(pos int64) {
+	n := copy(r.buf, r.buf[pos-r.base:])
+	r.buf = r.buf[:n]
+	r.base = pos
+}
+
+// readFn is an SDF that reads the records of CSV files. Its restriction is a
+// range of byte offsets in the file, and it reads the records beginning in the
+// range. Compressed files aren't split, and are read whole by the restriction
+// starting at 0.
+type readFn struct {
+	Type       beam.EncodedType
+	NoHeader   bool
+	Delimiter  rune
+	SingleLine bool
+}
+
+// CreateInitialRestriction creates an offset range restriction representing
+// the file's size in bytes.
+func (fn *readFn) CreateInitialRestriction(file fileio.ReadableFile) offsetrange.Restriction {
+	return offsetrange.Restriction{
+		Start: 0,
+		End:   file.Metadata.Size,
+	}
+}
+
+// blockSize is the desired size of each block for initial splits.
+var blockSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits the restriction of uncompressed files into blocks
+// of a predetermined size. The restriction of compressed files, and of files
+// that can't be opened, is left whole.
+func (fn *readFn) SplitRestriction(file fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	if compressed, err := file.IsCompressed(context.Background()); err != nil || compressed {
+		return []offsetrange.Restriction{rest}
+	}
+	splits := rest.SizedSplits(blockSize)
+	numSplits := len(splits)
+	if numSplits > 1 {
+		last := splits[numSplits-1]
+		if last.End-last.Start <= blockSize/4 {
+			// Last restriction is too small, so merge it with previous one.
+			splits[numSplits-2].End = last.End
+			splits = splits[:numSplits-1]
+		}
+	}
+	return splits
+}
+
+// RestrictionSize returns the size of each restriction as its range.
+func (fn *readFn) RestrictionSize(_ fileio.ReadableFile, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping offsetRange.Trackers for
+// each restriction.
+func (fn *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+// ProcessElement reads the records beginning within the restriction, emitting
+// the records that can't be read to emitErr. Compressed files are read whole
+// by the restriction starting at 0, since the offsets of their records may lie
+// beyond the size of the file. Restrictions split from it are left empty.
+func (fn *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X), emitErr func(ParseError)) error {
+	log.Infof(ctx, "Reading CSV from %v", file.Metadata.Path)
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+
+	whole, err := file.IsCompressed(ctx)
+	if err != nil {
+		return err
+	}
+	if whole && (rest.Start != 0 || !rt.TryClaim(int64(0))) {
+		rt.TryClaim(rest.End)
+		return nil
+	}
+
+	var header []string
+	if !fn.NoHeader && rest.Start > 0 {
+		if header, err = fn.readHeader(ctx, file); err != nil {
+			return err
+		}
+	}
+
+	fd, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	br, start, err := fn.seekRecord(fd, rest.Start)
+	if err == io.EOF {
+		rt.TryClaim(rest.End)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+	}
+	r := newRecordReader(br, start, fn.Delimiter)
+
+	var lastPos int64
+	var lastText string
+	if !fn.NoHeader && rest.Start == 0 {
+		header, lastText, err = r.next()
+		if err == io.EOF {
+			rt.TryClaim(rest.End)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading header of %v: %v", file.Metadata.Path, err)
+		}
+	}
+	dec := newDecoder(fn.Type.T, header)
+
+	for {
+		pos := r.offset()
+		if !whole && !rt.TryClaim(pos) {
+			end := rt.GetRestriction().(offsetrange.Restriction).End
+			return fn.checkBoundary(ctx, file, end, pos, lastPos, lastText)
+		}
+		record, text, err := r.next()
+		if err == io.EOF {
+			// Finish claiming restriction before returning to avoid errors.
+			rt.TryClaim(rest.End)
+			return nil
+		}
+		lastPos, lastText = pos, text
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			emitErr(ParseError{File: file.Metadata.Path, Offset: pos, Record: text, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+		v, err := dec.decode(record)
+		if err != nil {
+			emitErr(ParseError{File: file.Metadata.Path, Offset: pos, Record: text, Error: err.Error()})
+			continue
+		}
+		emit(v.Interface())
+	}
+}
+
+// seekRecord moves r, a reader of a file positioned at its start, to the first
+// record beginning at or after the given offset. It returns a buffered reader
+// positioned at that record and the offset of the record, or io.EOF if no
+// record begins at or after the offset.
+//
+// Since a newline may be part of a quoted field, the record is found by
+// scanning forward from the first line at or after the offset, unless records
+// are single lines. See recordStart.
+func (fn *readFn) seekRecord(r io.Reader, offset int64) (*bufio.Reader, int64, error) {
+	br, start, err := fileio.SeekLine(r, offset)
+	if err != nil || offset == 0 || fn.SingleLine {
+		return br, start, err
+	}
+	br = bufio.NewReaderSize(br, resyncWindow)
+	n, err := recordStart(br, fn.Delimiter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return br, start + n, nil
+}
+
+// checkBoundary checks that the restriction following the one read, which
+// begins at end, begins reading at next, the first record at or after end.
+// That restriction finds its first record by scanning forward from end, which
+// can mistake the remainder of a quoted field for records, if the quoted field
+// continues for more than resyncWindow bytes past end without any quotes. Such
+// files are reported as errors, rather than reading records twice or not at
+// all.
+//
+// The last record read, or the header, at lastPos contains the newline from
+// which the scan starts. If it's the newline ending that record, the scan can't go wrong, so
+// the check doesn't need to read the file again.
+func (fn *readFn) checkBoundary(ctx context.Context, file fileio.ReadableFile, end, next, lastPos int64, lastText string) error {
+	if fn.SingleLine || end == 0 {
+		return nil
+	}
+	if lastText != "" {
+		i := strings.IndexByte(lastText[end-1-lastPos:], '\n')
+		if i < 0 || end+int64(i) == next {
+			return nil
+		}
+	}
+
+	fd, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	_, start, err := fn.seekRecord(fd, end)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+	}
+	if start != next {
+		return fmt.Errorf("unable to split %v at offset %v: the record beginning at %v contains a quoted "+
+			"field with newlines, which continues for more than %v bytes without quotes", file.Metadata.Path, end, lastPos, resyncWindow)
+	}
+	return nil
+}
+
+// readHeader reads the header at the start of the file.
+func (fn *readFn) readHeader(ctx context.Context, file fileio.ReadableFile) ([]string, error) {
+	fd, err := file.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	header, _, err := newRecordReader(fd, 0, fn.Delimiter).next()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header of %v: %v", file.Metadata.Path, err)
+	}
+	return header, nil
+}
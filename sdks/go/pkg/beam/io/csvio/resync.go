@@ -0,0 +1,203 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// resyncWindow is the number of bytes scanned to decide whether a line lies
+// inside a quoted field.
+var resyncWindow = 1 << 20 // 1 MB
+
+// quoteState is the state of a scan of CSV data, as far as quoting goes.
+type quoteState int
+
+const (
+	// stateFieldStart is the start of a field.
+	stateFieldStart quoteState = iota
+	// stateUnquoted is inside an unquoted field.
+	stateUnquoted
+	// stateQuoted is inside a quoted field.
+	stateQuoted
+	// stateQuote follows a quote inside a quoted field, which either ends the
+	// field or escapes another quote.
+	stateQuote
+	// stateQuoteCR follows a carriage return after the end of a quoted field.
+	stateQuoteCR
+	// stateInvalid follows data that isn't valid CSV.
+	stateInvalid
+)
+
+// step advances the scan in state s over the start of p, which begins with a
+// whole delimiter if it begins with a delimiter at all. It returns the new
+// state, the number of bytes consumed and whether they ended a record.
+func (s quoteState) step(p, delim []byte) (quoteState, int, bool) {
+	if bytes.HasPrefix(p, delim) {
+		switch s {
+		case stateQuoted:
+			return stateQuoted, len(delim), false
+		case stateQuoteCR, stateInvalid:
+			return stateInvalid, len(delim), false
+		default:
+			return stateFieldStart, len(delim), false
+		}
+	}
+	c := p[0]
+	switch s {
+	case stateFieldStart, stateUnquoted:
+		switch c {
+		case '"':
+			if s == stateUnquoted {
+				return stateInvalid, 1, false
+			}
+			return stateQuoted, 1, false
+		case '\n':
+			return stateFieldStart, 1, true
+		default:
+			return stateUnquoted, 1, false
+		}
+	case stateQuoted:
+		if c == '"' {
+			return stateQuote, 1, false
+		}
+		return stateQuoted, 1, false
+	case stateQuote:
+		switch c {
+		case '"':
+			return stateQuoted, 1, false
+		case '\n':
+			return stateFieldStart, 1, true
+		case '\r':
+			return stateQuoteCR, 1, false
+		}
+	case stateQuoteCR:
+		if c == '\n' {
+			return stateFieldStart, 1, true
+		}
+	}
+	return stateInvalid, 1, false
+}
+
+// atEOF returns the state of a scan in state s that reaches the end of the
+// data.
+func (s quoteState) atEOF() quoteState {
+	if s == stateQuoted {
+		return stateInvalid
+	}
+	return s
+}
+
+// recordStart moves br, which is positioned at the start of a line, to the
+// start of the first record at or after it, and returns the number of bytes
+// skipped. As in Java's CsvIO, the line is either the start of a record or
+// inside a quoted field, and which it is gets decided by scanning forward
+// under both assumptions until one of them runs into data that isn't valid
+// CSV, which is bound to happen at a quote of the field. If neither does
+// within resyncWindow bytes, the line is taken to start a record.
+func recordStart(br *bufio.Reader, delimiter rune) (int64, error) {
+	delim := []byte(string(delimiter))
+	buf, eof, err := peekWindow(br, len(delim))
+	if err != nil {
+		return 0, err
+	}
+
+	// a assumes the line starts a record, and b that it's inside a quoted
+	// field. bEnd is the end of the record that b is inside.
+	a, b := stateFieldStart, stateQuoted
+	bEnd := -1
+	i := 0
+	for i < len(buf) && b != stateInvalid && (a != stateInvalid || bEnd < 0) {
+		var n int
+		var end bool
+		if a != stateInvalid {
+			a, _, _ = a.step(buf[i:], delim)
+		}
+		b, n, end = b.step(buf[i:], delim)
+		i += n
+		if end && bEnd < 0 {
+			bEnd = i
+		}
+	}
+	if i == len(buf) && eof {
+		a, b = a.atEOF(), b.atEOF()
+		if bEnd < 0 {
+			bEnd = i
+		}
+	}
+	switch {
+	case b == stateInvalid || a != stateInvalid:
+		return 0, nil
+	case bEnd >= 0:
+		_, err := br.Discard(bEnd)
+		return int64(bEnd), err
+	}
+
+	// The quoted field continues beyond the window, so skip to its end.
+	skipped := int64(i)
+	if _, err := br.Discard(i); err != nil {
+		return 0, err
+	}
+	for {
+		buf, eof, err := peekWindow(br, len(delim))
+		if err != nil {
+			return 0, err
+		}
+		for i = 0; i < len(buf); {
+			var n int
+			var end bool
+			b, n, end = b.step(buf[i:], delim)
+			i += n
+			if b == stateInvalid {
+				return 0, errQuote
+			}
+			if end {
+				_, err := br.Discard(i)
+				return skipped + int64(i), err
+			}
+		}
+		if eof {
+			if b.atEOF() == stateInvalid {
+				return 0, errQuote
+			}
+			_, err := br.Discard(i)
+			return skipped + int64(i), err
+		}
+		if _, err := br.Discard(i); err != nil {
+			return 0, err
+		}
+		skipped += int64(i)
+	}
+}
+
+var errQuote = errors.New("unable to find the start of a record: neither the start of the line nor the end of a quoted field spanning it leads to valid CSV")
+
+// peekWindow returns the next resyncWindow bytes of br, without the bytes that
+// may be the start of a delimiter of n bytes cut off by the end of the window,
+// and whether they reach the end of the data.
+func peekWindow(br *bufio.Reader, n int) ([]byte, bool, error) {
+	buf, err := br.Peek(resyncWindow)
+	switch {
+	case err == io.EOF:
+		return buf, true, nil
+	case err != nil:
+		return nil, false, err
+	}
+	return buf[:len(buf)-(n-1)], false, nil
+}
@@ -0,0 +1,415 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvio
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// checkType returns an error if t isn't a struct type with fields that can be
+// read from and written to CSV.
+func checkType(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("type %v isn't a struct", t)
+	}
+	fields, _ := columns(t)
+	for _, i := range fields {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if reflect.PointerTo(ft).Implements(textUnmarshalerType) {
+			continue
+		}
+		switch ft.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("field %v of %v has unsupported type %v", f.Name, t, f.Type)
+		}
+	}
+	return nil
+}
+
+// columns returns the indices of the exported fields of a struct type, and the
+// names of their columns.
+func columns(t reflect.Type) ([]int, []string) {
+	var fields []int
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := columnName(f)
+		if name == "-" {
+			continue
+		}
+		fields = append(fields, i)
+		names = append(names, name)
+	}
+	return fields, names
+}
+
+// columnName returns the name of the column of a field: the name in its csv
+// tag, or else its beam tag, or else the field name.
+func columnName(f reflect.StructField) string {
+	for _, key := range []string{"csv", "beam"} {
+		if tag, ok := f.Tag.Lookup(key); ok {
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				return name
+			}
+		}
+	}
+	return f.Name
+}
+
+// decoder converts records to structs.
+type decoder struct {
+	t reflect.Type
+	// fields holds the index of the field of each column, or -1 for columns
+	// without a field.
+	fields []int
+}
+
+// newDecoder returns a decoder of records with the given header. If header is
+// nil, columns are matched to the fields of t in order.
+func newDecoder(t reflect.Type, header []string) *decoder {
+	fields, names := columns(t)
+	if header == nil {
+		return &decoder{t: t, fields: fields}
+	}
+	d := &decoder{t: t, fields: make([]int, len(header))}
+	for i, h := range header {
+		d.fields[i] = -1
+		for j, name := range names {
+			if name == h {
+				d.fields[i] = fields[j]
+				break
+			}
+		}
+		if d.fields[i] >= 0 {
+			continue
+		}
+		for j, name := range names {
+			if strings.EqualFold(name, h) {
+				d.fields[i] = fields[j]
+				break
+			}
+		}
+	}
+	return d
+}
+
+// decode returns a new struct holding the values of the record.
+func (d *decoder) decode(record []string) (reflect.Value, error) {
+	if len(record) != len(d.fields) {
+		return reflect.Value{}, fmt.Errorf("record has %d fields, want %d", len(record), len(d.fields))
+	}
+	v := reflect.New(d.t).Elem()
+	for i, s := range record {
+		if d.fields[i] < 0 {
+			continue
+		}
+		if err := parseValue(v.Field(d.fields[i]), s); err != nil {
+			f := d.t.Field(d.fields[i])
+			return reflect.Value{}, fmt.Errorf("column %v: %v", columnName(f), err)
+		}
+	}
+	return v, nil
+}
+
+// parseValue sets v to the value of s. Empty values are nil for pointers and
+// zero for other types.
+func parseValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := parseValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if s == "" {
+			return nil
+		}
+		return u.UnmarshalText([]byte(s))
+	}
+	if s == "" || v.Kind() == reflect.String {
+		if v.Kind() == reflect.String {
+			v.SetString(s)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// formatValue returns the text of a value, which is empty for nil pointers.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// inferRecords is the number of records used to infer the types of columns.
+const inferRecords = 1000
+
+// inferType returns the row type of the first file matching the glob.
+func inferType(ctx context.Context, glob string, option *readOption) (reflect.Type, error) {
+	fs, err := filesystem.New(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %v to infer the schema from", glob)
+	}
+	sort.Strings(files)
+
+	fd, err := (fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: files[0]}}).Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	r := newRecordReader(fd, 0, option.Delimiter)
+
+	var header []string
+	if !option.NoHeader {
+		header, _, err = r.next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%v has no header to infer the schema from", files[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading header of %v: %v", files[0], err)
+		}
+	}
+	var kinds []columnKind
+	if header != nil {
+		kinds = make([]columnKind, len(header))
+	}
+	for i := 0; i < inferRecords; i++ {
+		record, _, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Records that can't be parsed are sent to the dead-letter
+			// output when reading.
+			continue
+		}
+		if kinds == nil {
+			kinds = make([]columnKind, len(record))
+		}
+		if len(record) != len(kinds) {
+			continue
+		}
+		for j, s := range record {
+			kinds[j].observe(s)
+		}
+	}
+	if kinds == nil {
+		return nil, fmt.Errorf("%v has no records to infer the schema from", files[0])
+	}
+	return rowType(header, kinds), nil
+}
+
+// columnKind accumulates the kind of the values of a column.
+type columnKind struct {
+	kind  reflect.Kind
+	empty bool
+}
+
+// observe updates the kind with a value of the column.
+func (c *columnKind) observe(s string) {
+	if s == "" {
+		c.empty = true
+		return
+	}
+	var k reflect.Kind
+	switch {
+	case strings.EqualFold(s, "true") || strings.EqualFold(s, "false"):
+		k = reflect.Bool
+	case isInt(s):
+		k = reflect.Int64
+	case isFloat(s):
+		k = reflect.Float64
+	default:
+		k = reflect.String
+	}
+	switch {
+	case c.kind == reflect.Invalid || c.kind == k:
+		c.kind = k
+	case (c.kind == reflect.Int64 && k == reflect.Float64) || (c.kind == reflect.Float64 && k == reflect.Int64):
+		c.kind = reflect.Float64
+	default:
+		c.kind = reflect.String
+	}
+}
+
+func isInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+func isFloat(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// rowType returns a struct type with a field of each kind, named after the
+// header. Columns of files without a header are named column1, column2, etc.
+func rowType(header []string, kinds []columnKind) reflect.Type {
+	fields := make([]reflect.StructField, len(kinds))
+	used := make(map[string]bool)
+	for i, c := range kinds {
+		name := fmt.Sprintf("column%d", i+1)
+		if header != nil {
+			name = header[i]
+		}
+		var t reflect.Type
+		switch c.kind {
+		case reflect.Bool:
+			t = reflect.TypeOf(false)
+		case reflect.Int64:
+			t = reflect.TypeOf(int64(0))
+		case reflect.Float64:
+			t = reflect.TypeOf(float64(0))
+		default:
+			t = reflect.TypeOf("")
+		}
+		if c.empty && t.Kind() != reflect.String {
+			t = reflect.PointerTo(t)
+		}
+
+		goName := fieldName(name, i)
+		for used[goName] {
+			goName += "_"
+		}
+		used[goName] = true
+		fields[i] = reflect.StructField{
+			Name: goName,
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf("beam:%q csv:%q", name, name)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// fieldName returns an exported Go identifier for the i-th column.
+func fieldName(name string, i int) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" {
+		return fmt.Sprintf("Column%d", i+1)
+	}
+	if !unicode.IsUpper([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
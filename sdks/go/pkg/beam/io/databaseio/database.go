@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
@@ -32,6 +33,8 @@ import (
 func init() {
 	beam.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*queryAllFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeWithFailuresFn)(nil)).Elem())
 }
 
 // writeSizeLimit is the maximum number of rows allowed to a write.
@@ -66,16 +69,29 @@ type queryFn struct {
 	Query string `json:"query"`
 	// Type is the encoded schema type.
 	Type beam.EncodedType `json:"type"`
+
+	db *sql.DB
 }
 
-func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X)) error {
-	//TODO move DB Open and Close to Setup and Teardown methods or StartBundle and FinishBundle
-	db, err := sql.Open(f.Driver, f.Dsn)
+func (f *queryFn) Setup() error {
+	db, err := openDB(f.Driver, f.Dsn)
 	if err != nil {
-		return errors.Wrapf(err, "failed to open database: %v", f.Driver)
+		return err
+	}
+	f.db = db
+	return nil
+}
+
+func (f *queryFn) Teardown() error {
+	if f.db == nil {
+		return nil
 	}
-	defer db.Close()
-	statement, err := db.PrepareContext(ctx, f.Query)
+	f.db = nil
+	return closeDB(f.Driver, f.Dsn)
+}
+
+func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X)) error {
+	statement, err := f.db.PrepareContext(ctx, f.Query)
 	if err != nil {
 		return errors.Wrapf(err, "failed to prepare query: %v", f.Query)
 	}
@@ -85,57 +101,206 @@ func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X
 		return errors.Wrapf(err, "failed to run query: %v", f.Query)
 	}
 	defer rows.Close()
-	var mapper rowMapper
-	var columns []string
+	reader := &rowReader{t: f.Type.T}
 	for rows.Next() {
-		reflectRow := reflect.New(f.Type.T)
-		row := reflectRow.Interface() // row : *T
-		if mapper == nil {
-			columns, err = rows.Columns()
-			if err != nil {
-				return err
-			}
-			columnsTypes, _ := rows.ColumnTypes()
-			if mapper, err = newQueryMapper(columns, columnsTypes, f.Type.T); err != nil {
-				return errors.WithContext(err, "creating rowValues mapper")
-			}
-		}
-		rowValues, err := mapper(reflectRow)
+		row, _, err := reader.read(rows)
 		if err != nil {
-			return err
+			return errors.Wrapf(err, "failed to scan %v", f.Query)
 		}
-		err = rows.Scan(rowValues...)
+		emit(row)
+	}
+	return rows.Err()
+}
+
+// ReadAll executes a parameterized query for each element of the given
+// PCollection, and returns a PCollection<t> with the rows of all queries. The
+// arguments of the query are the exported fields of struct elements in order,
+// the elements of slice elements other than []byte, or else the element
+// itself. The query is prepared once per worker and must use the placeholders
+// of the driver, such as $1 for postgres or ? for mysql.
+func ReadAll(s beam.Scope, driver, dsn, q string, t reflect.Type, col beam.PCollection) beam.PCollection {
+	s = s.Scope(driver + ".ReadAll")
+	return beam.ParDo(s, &queryAllFn{Driver: driver, Dsn: dsn, Query: q, Type: beam.EncodedType{T: t}}, col, beam.TypeDefinition{Var: beam.YType, T: t})
+}
+
+type queryAllFn struct {
+	// Driver is the database driver name.
+	Driver string `json:"driver"`
+	// Dsn is the data source name.
+	Dsn string `json:"dsn"`
+	// Query is the parameterized query.
+	Query string `json:"query"`
+	// Type is the encoded schema type.
+	Type beam.EncodedType `json:"type"`
+
+	db        *sql.DB
+	statement *sql.Stmt
+}
+
+func (f *queryAllFn) Setup(ctx context.Context) error {
+	db, err := openDB(f.Driver, f.Dsn)
+	if err != nil {
+		return err
+	}
+	f.db = db
+	if f.statement, err = db.PrepareContext(ctx, f.Query); err != nil {
+		return errors.Wrapf(err, "failed to prepare query: %v", f.Query)
+	}
+	return nil
+}
+
+func (f *queryAllFn) Teardown() error {
+	if f.statement != nil {
+		f.statement.Close()
+		f.statement = nil
+	}
+	if f.db == nil {
+		return nil
+	}
+	f.db = nil
+	return closeDB(f.Driver, f.Dsn)
+}
+
+func (f *queryAllFn) ProcessElement(ctx context.Context, elem beam.X, emit func(beam.Y)) error {
+	args := queryArgs(elem)
+	rows, err := f.statement.QueryContext(ctx, args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run query: %v with %v", f.Query, args)
+	}
+	defer rows.Close()
+	reader := &rowReader{t: f.Type.T}
+	for rows.Next() {
+		row, _, err := reader.read(rows)
 		if err != nil {
 			return errors.Wrapf(err, "failed to scan %v", f.Query)
 		}
-		if loader, ok := row.(MapLoader); ok {
-			asDereferenceSlice(rowValues)
-			loader.LoadMap(asMap(columns, rowValues))
-		} else if loader, ok := row.(SliceLoader); ok {
-			asDereferenceSlice(rowValues)
-			loader.LoadSlice(rowValues)
+		emit(row)
+	}
+	return rows.Err()
+}
+
+// queryArgs returns the query arguments for an element: the exported fields
+// of a struct in order, the elements of a slice other than []byte, or else
+// the element itself.
+func queryArgs(elem any) []any {
+	v := reflect.ValueOf(elem)
+	switch {
+	case v.Kind() == reflect.Struct:
+		var args []any
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				args = append(args, v.Field(i).Interface())
+			}
 		}
-		emit(reflect.ValueOf(row).Elem().Interface()) // emit(*row)
+		return args
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		args := make([]any, v.Len())
+		for i := range args {
+			args[i] = v.Index(i).Interface()
+		}
+		return args
 	}
-	return nil
+	return []any{elem}
+}
+
+// rowReader reads the rows of a query result as values of a type.
+type rowReader struct {
+	t       reflect.Type
+	columns []string
+	mapper  rowMapper
+}
+
+// read scans the current row, and returns it as a value of the type along
+// with its column values.
+func (r *rowReader) read(rows *sql.Rows) (any, []any, error) {
+	if r.mapper == nil {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, nil, err
+		}
+		columnsTypes, _ := rows.ColumnTypes()
+		mapper, err := newQueryMapper(columns, columnsTypes, r.t)
+		if err != nil {
+			return nil, nil, errors.WithContext(err, "creating rowValues mapper")
+		}
+		r.columns, r.mapper = columns, mapper
+	}
+	reflectRow := reflect.New(r.t)
+	row := reflectRow.Interface() // row : *T
+	rowValues, err := r.mapper(reflectRow)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := rows.Scan(rowValues...); err != nil {
+		return nil, nil, err
+	}
+	if loader, ok := row.(MapLoader); ok {
+		asDereferenceSlice(rowValues)
+		loader.LoadMap(asMap(r.columns, rowValues))
+	} else if loader, ok := row.(SliceLoader); ok {
+		asDereferenceSlice(rowValues)
+		loader.LoadSlice(rowValues)
+	}
+	return reflectRow.Elem().Interface(), rowValues, nil // *row
 }
 
-// Write writes the elements of the given PCollection<T> to database, if columns left empty all table columns are used to insert into, otherwise selected
-func Write(s beam.Scope, driver, dsn, table string, columns []string, col beam.PCollection) {
+// Write writes the elements of the given PCollection<T> to database, if columns left empty all table columns are used to insert into, otherwise selected.
+// It returns a PCollection<int> with the number of rows written once the write has completed.
+func Write(s beam.Scope, driver, dsn, table string, columns []string, col beam.PCollection) beam.PCollection {
 	t := col.Type().Type()
 	s = s.Scope(driver + ".Write")
 	pre := beam.AddFixedKey(s, col)
 	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: writeRowLimit, Type: beam.EncodedType{T: t}}, post)
+	return beam.ParDo(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: writeRowLimit, Type: beam.EncodedType{T: t}}, post)
 }
 
 // WriteWithBatchSize writes the elements of the given PCollection<T> to database with custom batch size. Batch size control number of elements in the batch INSERT statement.
-func WriteWithBatchSize(s beam.Scope, batchSize int, driver, dsn, table string, columns []string, col beam.PCollection) {
+// It returns a PCollection<int> with the number of rows written once the write has completed.
+func WriteWithBatchSize(s beam.Scope, batchSize int, driver, dsn, table string, columns []string, col beam.PCollection) beam.PCollection {
+	t := col.Type().Type()
+	s = s.Scope(driver + ".Write")
+	pre := beam.AddFixedKey(s, col)
+	post := beam.GroupByKey(s, pre)
+	return beam.ParDo(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: batchSize, Type: beam.EncodedType{T: t}}, post)
+}
+
+// WriteWithOptions writes the elements of the given PCollection<T> to database like Write,
+// configured by the given options. Rows are written in batches, each in a transaction that is
+// retried on errors if WriteRetries is given.
+//
+// It returns a PCollection<int> with the number of rows written once the write has completed, and
+// a PCollection<KV<T, string>> with the rows that couldn't be written along with the errors of the
+// driver. Rows of batches that fail are written one by one to find them.
+func WriteWithOptions(s beam.Scope, driver, dsn, table string, columns []string, col beam.PCollection, opts ...WriteOptionFn) (beam.PCollection, beam.PCollection) {
 	t := col.Type().Type()
 	s = s.Scope(driver + ".Write")
+
+	option := &writeOption{BatchSize: writeRowLimit, Backoff: defaultBackoff}
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("databaseio.WriteWithOptions: invalid option: %v", err))
+		}
+	}
+	if len(option.Upsert) > 0 {
+		if _, err := upsertClause(driver, columns, option.Upsert); err != nil {
+			panic(fmt.Sprintf("databaseio.WriteWithOptions: %v", err))
+		}
+	}
+
 	pre := beam.AddFixedKey(s, col)
 	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: batchSize, Type: beam.EncodedType{T: t}}, post)
+	fn := &writeWithFailuresFn{writeFn: writeFn{
+		Driver:    driver,
+		Dsn:       dsn,
+		Table:     table,
+		Columns:   columns,
+		BatchSize: option.BatchSize,
+		Upsert:    option.Upsert,
+		Retries:   option.Retries,
+		Backoff:   option.Backoff,
+		Type:      beam.EncodedType{T: t},
+	}}
+	return beam.ParDo2(s, fn, post)
 }
 
 type writeFn struct {
@@ -149,17 +314,56 @@ type writeFn struct {
 	Columns []string `json:"columns"`
 	//BatchSize size
 	BatchSize int `json:"batchSize"`
+	// Upsert are the key columns of upserts, if not empty.
+	Upsert []string `json:"upsert"`
+	// Retries is the number of times a failed batch is retried.
+	Retries int `json:"retries"`
+	// Backoff is the time before the first retry of a batch.
+	Backoff time.Duration `json:"backoff"`
 	// Type is the encoded schema type.
 	Type beam.EncodedType `json:"type"`
+
+	db *sql.DB
 }
 
-func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
-	//TODO move DB Open and Close to Setup and Teardown methods or StartBundle and FinishBundle
-	db, err := sql.Open(f.Driver, f.Dsn)
+func (f *writeFn) Setup() error {
+	db, err := openDB(f.Driver, f.Dsn)
 	if err != nil {
-		return errors.Wrapf(err, "failed to open database: %v", f.Driver)
+		return err
 	}
-	defer db.Close()
+	f.db = db
+	return nil
+}
+
+func (f *writeFn) Teardown() error {
+	if f.db == nil {
+		return nil
+	}
+	f.db = nil
+	return closeDB(f.Driver, f.Dsn)
+}
+
+func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, emit func(int)) error {
+	return f.write(ctx, iter, emit, nil)
+}
+
+// writeWithFailuresFn is a writeFn that outputs the rows it can't write with
+// the errors of the driver, rather than failing.
+type writeWithFailuresFn struct {
+	writeFn
+}
+
+func (f *writeWithFailuresFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, emit func(int), failed func(beam.X, string)) error {
+	return f.write(ctx, iter, emit, func(elem any, err error) {
+		failed(elem, err.Error())
+	})
+}
+
+// write writes the rows of iter, and emits the number of rows written. If
+// failed is set, it's called with the rows that can't be written, and
+// otherwise the write fails.
+func (f *writeFn) write(ctx context.Context, iter func(*beam.X) bool, emit func(int), failed func(elem any, err error)) error {
+	db := f.db
 	projection := "*"
 	if len(f.Columns) > 0 {
 		projection = strings.Join(f.Columns, ",")
@@ -175,6 +379,7 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 		return errors.Wrapf(err, "failed to query: %v", f.Table)
 	}
 	columns, err := rows.Columns()
+	rows.Close()
 	if err != nil {
 		return errors.Wrapf(err, "failed to discover column: %v", f.Table)
 	}
@@ -183,10 +388,16 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	if err != nil {
 		return errors.WithContext(err, "creating row mapper")
 	}
-	writer, err := newWriter(f.Driver, f.BatchSize, f.Table, columns)
+	var writer *writer
+	if len(f.Upsert) > 0 {
+		writer, err = newUpsertWriter(f.Driver, f.BatchSize, f.Table, columns, f.Upsert)
+	} else {
+		writer, err = newWriter(f.Driver, f.BatchSize, f.Table, columns)
+	}
 	if err != nil {
 		return err
 	}
+	writer.retries, writer.backoff, writer.failed = f.Retries, f.Backoff, failed
 	var val beam.X
 	for iter(&val) {
 		var row []any
@@ -201,11 +412,16 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 		} else {
 			row, err = mapper(reflect.ValueOf(val))
 		}
-		if err != nil {
-			return errors.Wrapf(err, "failed to map row %T", val)
+		if err == nil {
+			err = writer.add(val, row)
 		}
-		if err = writer.add(row); err != nil {
-			return err
+		if err != nil {
+			err = errors.Wrapf(err, "failed to map row %T", val)
+			if failed == nil {
+				return err
+			}
+			failed(val, err)
+			continue
 		}
 		if err := writer.writeBatchIfNeeded(ctx, db); err != nil {
 			return err
@@ -217,5 +433,6 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	}
 
 	log.Infof(ctx, "written %v row(s) into %v", writer.totalCount, f.Table)
+	emit(writer.totalCount)
 	return nil
 }
@@ -89,3 +89,25 @@ func insertTestData(db *sql.DB) error {
 	}
 	return nil
 }
+
+func TestQueryArgs(t *testing.T) {
+	type key struct {
+		Street string
+		Number int
+		note   string
+	}
+	tests := []struct {
+		elem any
+		want []any
+	}{
+		{elem: key{Street: "morris st", Number: 200, note: "x"}, want: []any{"morris st", 200}},
+		{elem: []string{"a", "b"}, want: []any{"a", "b"}},
+		{elem: []byte("raw"), want: []any{[]byte("raw")}},
+		{elem: 42, want: []any{42}},
+	}
+	for _, test := range tests {
+		if got := queryArgs(test.elem); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("queryArgs(%v) = %v, want %v", test.elem, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*partitionedQueryFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*columnRange)(nil)).Elem())
+}
+
+// ReadPartitioned reads all rows from the given table like Read, but splits
+// them into ranges of the values of a numeric or timestamp column that are
+// read in parallel. The ranges are initially even splits of the values
+// between the minimum and the maximum of the column, and runners may split
+// them further, so the column should be indexed. Rows where the column is
+// NULL are read by a separate query. It returns a PCollection<t>.
+//
+// Fractional numbers are split at integers, and timestamps at nanoseconds.
+// Timestamps must be scanned as time.Time, which some drivers need to be
+// configured for, such as with parseTime=true for mysql.
+func ReadPartitioned(s beam.Scope, driver, dsn, table, column string, t reflect.Type, opts ...ReadOptionFn) beam.PCollection {
+	s = s.Scope(driver + ".ReadPartitioned")
+
+	option := &readOption{Partitions: defaultPartitions}
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("databaseio.ReadPartitioned: invalid option: %v", err))
+		}
+	}
+
+	imp := beam.Impulse(s)
+	fn := &partitionedQueryFn{
+		Driver:     driver,
+		Dsn:        dsn,
+		Table:      table,
+		Column:     column,
+		Where:      option.Where,
+		Partitions: option.Partitions,
+		Type:       beam.EncodedType{T: t},
+	}
+	ranges := beam.ParDo(s, fn, imp, beam.TypeDefinition{Var: beam.XType, T: t})
+	nulls := query(s, driver, dsn, fmt.Sprintf("SELECT * FROM %v%v", table, fn.where(column+" IS NULL")), t)
+	return beam.Flatten(s, ranges, nulls)
+}
+
+// partitionedQueryFn is an SDF that reads the rows of a table with values of
+// a column in a columnRange, in the order of the column.
+type partitionedQueryFn struct {
+	// Driver is the database driver name.
+	Driver string `json:"driver"`
+	// Dsn is the data source name.
+	Dsn string `json:"dsn"`
+	// Table is the table identifier.
+	Table string `json:"table"`
+	// Column is the column the table is partitioned by.
+	Column string `json:"column"`
+	// Where is an optional condition on the rows read.
+	Where string `json:"where"`
+	// Partitions is the number of initial splits.
+	Partitions int `json:"partitions"`
+	// Type is the encoded schema type.
+	Type beam.EncodedType `json:"type"`
+
+	db *sql.DB
+}
+
+func (f *partitionedQueryFn) Setup() error {
+	if f.db != nil {
+		return nil
+	}
+	db, err := openDB(f.Driver, f.Dsn)
+	if err != nil {
+		return err
+	}
+	f.db = db
+	return nil
+}
+
+func (f *partitionedQueryFn) Teardown() error {
+	if f.db == nil {
+		return nil
+	}
+	f.db = nil
+	return closeDB(f.Driver, f.Dsn)
+}
+
+// where returns the WHERE clause of the queries with the given conditions
+// and the configured one.
+func (f *partitionedQueryFn) where(conditions ...string) string {
+	if f.Where != "" {
+		conditions = append(conditions, "("+f.Where+")")
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// CreateInitialRestriction returns the range from the minimum to the maximum
+// value of the column, or an empty range if the table has no rows.
+func (f *partitionedQueryFn) CreateInitialRestriction(ctx context.Context, _ []byte) (columnRange, error) {
+	if err := f.Setup(); err != nil {
+		return columnRange{}, err
+	}
+	q := fmt.Sprintf("SELECT MIN(%v), MAX(%v) FROM %v%v", f.Column, f.Column, f.Table, f.where())
+	var lo, hi any
+	if err := f.db.QueryRowContext(ctx, q).Scan(&lo, &hi); err != nil {
+		return columnRange{}, errors.Wrapf(err, "failed to run query: %v", q)
+	}
+	if lo == nil || hi == nil {
+		log.Infof(ctx, "No rows in %v to read", f.Table)
+		return columnRange{}, nil
+	}
+	start, isTime, err := position(lo)
+	if err != nil {
+		return columnRange{}, errors.Wrapf(err, "invalid minimum of column %v", f.Column)
+	}
+	end, _, err := position(hi)
+	if err != nil {
+		return columnRange{}, errors.Wrapf(err, "invalid maximum of column %v", f.Column)
+	}
+	if end < math.MaxInt64 {
+		end++
+	}
+	return columnRange{Start: start, End: end, Time: isTime}, nil
+}
+
+// SplitRestriction splits the range evenly into the configured number of
+// partitions.
+func (f *partitionedQueryFn) SplitRestriction(_ []byte, rest columnRange) []columnRange {
+	return rest.evenSplits(int64(f.Partitions))
+}
+
+// RestrictionSize returns the size of the range of values, which is an
+// estimate of the number of rows in it relative to other ranges.
+func (f *partitionedQueryFn) RestrictionSize(_ []byte, rest columnRange) float64 {
+	return rest.offsets().Size()
+}
+
+func (f *partitionedQueryFn) CreateTracker(rest columnRange) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(&columnRangeTracker{
+		BoundableRTracker: offsetrange.NewTracker(rest.offsets()),
+		time:              rest.Time,
+	})
+}
+
+// ProcessElement reads the rows in the range in the order of the column.
+// Each distinct value of the column is claimed once, so rows with the same
+// value are never split between ranges.
+func (f *partitionedQueryFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, _ []byte, emit func(beam.X)) error {
+	rest := rt.GetRestriction().(columnRange)
+	if rest.Start >= rest.End {
+		return nil
+	}
+
+	gen := &valueTemplateGenerator{f.Driver}
+	q := fmt.Sprintf("SELECT * FROM %v%v ORDER BY %v", f.Table, f.where(
+		fmt.Sprintf("%v >= %v", f.Column, gen.placeholder(1)),
+		fmt.Sprintf("%v < %v", f.Column, gen.placeholder(2)),
+	), f.Column)
+	rows, err := f.db.QueryContext(ctx, q, rest.bound(rest.Start), rest.bound(rest.End))
+	if err != nil {
+		return errors.Wrapf(err, "failed to run query: %v", q)
+	}
+	defer rows.Close()
+
+	reader := &rowReader{t: f.Type.T}
+	index := -1
+	claimed := false
+	var last int64
+	for rows.Next() {
+		row, values, err := reader.read(rows)
+		if err != nil {
+			return errors.Wrapf(err, "failed to scan %v", q)
+		}
+		if index < 0 {
+			if index = columnIndex(reader.columns, f.Column); index < 0 {
+				return errors.Errorf("column %v not found in %v", f.Column, reader.columns)
+			}
+		}
+		pos, _, err := position(values[index])
+		if err != nil {
+			return errors.Wrapf(err, "invalid value of column %v", f.Column)
+		}
+		if !claimed || pos != last {
+			if !rt.TryClaim(pos) {
+				return rt.GetError()
+			}
+			claimed, last = true, pos
+		}
+		emit(row)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %v", q)
+	}
+	rt.TryClaim(rt.GetRestriction().(columnRange).End)
+	return rt.GetError()
+}
+
+// columnIndex returns the index of a column, ignoring case, or -1 if it's
+// missing.
+func columnIndex(columns []string, column string) int {
+	for i, c := range columns {
+		if strings.EqualFold(c, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// position returns the position in a columnRange of a value of a column, and
+// whether the value is a timestamp. Fractional numbers are rounded down.
+func position(value any) (int64, bool, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0, false, errors.New("value is null")
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UnixNano(), true, nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, false, errors.Errorf("value %v is out of range", v.Uint())
+		}
+		return int64(v.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return floor(v.Float())
+	case reflect.String:
+		return parsePosition(v.String())
+	case reflect.Slice:
+		if b, ok := v.Interface().([]byte); ok {
+			// Some drivers scan numeric and decimal values as text.
+			return parsePosition(string(b))
+		}
+	}
+	return 0, false, errors.Errorf("unsupported value %v of type %v", value, v.Type())
+}
+
+func parsePosition(s string) (int64, bool, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, false, nil
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, errors.Errorf("value %q is not a number", s)
+	}
+	return floor(x)
+}
+
+func floor(x float64) (int64, bool, error) {
+	x = math.Floor(x)
+	if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, false, errors.Errorf("value %v is out of range", x)
+	}
+	return int64(x), false, nil
+}
@@ -0,0 +1,75 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPosition(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	n := int32(7)
+	var iface any = uint16(9)
+	tests := []struct {
+		value  any
+		want   int64
+		isTime bool
+	}{
+		{value: int64(-3), want: -3},
+		{value: &n, want: 7},
+		{value: &iface, want: 9},
+		{value: 2.5, want: 2},
+		{value: -2.5, want: -3},
+		{value: []byte("123"), want: 123},
+		{value: "12.75", want: 12},
+		{value: ts, want: ts.UnixNano(), isTime: true},
+		{value: &ts, want: ts.UnixNano(), isTime: true},
+	}
+	for _, test := range tests {
+		got, isTime, err := position(test.value)
+		if err != nil {
+			t.Errorf("position(%v) failed: %v", test.value, err)
+			continue
+		}
+		if got != test.want || isTime != test.isTime {
+			t.Errorf("position(%v) = %v, %v, want %v, %v", test.value, got, isTime, test.want, test.isTime)
+		}
+	}
+}
+
+func TestPosition_invalid(t *testing.T) {
+	var null *int64
+	for _, value := range []any{"abc", null, true, 1e30} {
+		if _, _, err := position(value); err == nil {
+			t.Errorf("position(%v) succeeded, want error", value)
+		}
+	}
+}
+
+func TestPartitionedQueryFn_where(t *testing.T) {
+	fn := &partitionedQueryFn{}
+	if got, want := fn.where(), ""; got != want {
+		t.Errorf("where() = %q, want %q", got, want)
+	}
+	if got, want := fn.where("id >= $1", "id < $2"), " WHERE id >= $1 AND id < $2"; got != want {
+		t.Errorf("where() = %q, want %q", got, want)
+	}
+	fn.Where = "a = 1 OR b = 2"
+	if got, want := fn.where("id >= ?"), " WHERE id >= ? AND (a = 1 OR b = 2)"; got != want {
+		t.Errorf("where() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+// pools holds the database handles shared by the DoFns of a worker, by
+// driver and data source name. A *sql.DB is itself a pool of connections,
+// so sharing it bounds the connections a worker opens to a database.
+var pools = struct {
+	mu  sync.Mutex
+	dbs map[poolKey]*pool
+}{dbs: make(map[poolKey]*pool)}
+
+type poolKey struct {
+	driver, dsn string
+}
+
+type pool struct {
+	db   *sql.DB
+	refs int
+}
+
+// openDB returns the shared database handle for the driver and data source
+// name, opening it if needed. Each call must be followed by a call to
+// closeDB once the handle isn't used anymore.
+func openDB(driver, dsn string) (*sql.DB, error) {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	key := poolKey{driver, dsn}
+	if p, ok := pools.dbs[key]; ok {
+		p.refs++
+		return p.db, nil
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database: %v", driver)
+	}
+	pools.dbs[key] = &pool{db: db, refs: 1}
+	return db, nil
+}
+
+// closeDB releases a handle returned by openDB, and closes it when it is
+// released by all its users.
+func closeDB(driver, dsn string) error {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	key := poolKey{driver, dsn}
+	p, ok := pools.dbs[key]
+	if !ok {
+		return nil
+	}
+	if p.refs--; p.refs > 0 {
+		return nil
+	}
+	delete(pools.dbs, key)
+	return p.db.Close()
+}
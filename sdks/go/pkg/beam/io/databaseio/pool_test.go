@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"testing"
+)
+
+func TestOpenDB(t *testing.T) {
+	const dsn = "user:password@/pooldb"
+	db1, err := openDB("ramsql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2, err := openDB("ramsql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db1 != db2 {
+		t.Error("openDB() returned different handles for the same data source")
+	}
+	other, err := openDB("ramsql", dsn+"2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == db1 {
+		t.Error("openDB() returned the same handle for different data sources")
+	}
+	if err := closeDB("ramsql", dsn+"2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := closeDB("ramsql", dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := db1.Ping(); err != nil {
+		t.Errorf("handle closed while still in use: %v", err)
+	}
+	if err := closeDB("ramsql", dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := db1.Ping(); err == nil {
+		t.Error("handle not closed after its last use")
+	}
+}
@@ -0,0 +1,53 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+// defaultPartitions is the default number of partitions of ReadPartitioned.
+const defaultPartitions = 16
+
+type readOption struct {
+	Partitions int
+	Where      string
+}
+
+// ReadOptionFn is a function that can be passed to ReadPartitioned to configure options for
+// reading from a database.
+type ReadOptionFn func(option *readOption) error
+
+// ReadPartitions sets the number of ranges of the partition column the table is initially
+// split into. Runners may split them further. Defaults to 16.
+func ReadPartitions(n int) ReadOptionFn {
+	return func(o *readOption) error {
+		if n <= 0 {
+			return errors.New("partitions must be greater than 0")
+		}
+		o.Partitions = n
+		return nil
+	}
+}
+
+// ReadWhere filters the rows read with a SQL condition, such as "status = 'active'". It
+// must not use query parameters.
+func ReadWhere(condition string) ReadOptionFn {
+	return func(o *readOption) error {
+		o.Where = condition
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+// columnRange is a range [Start, End) of the values of the column a table is
+// partitioned by. Timestamps are represented as nanoseconds since the Unix
+// epoch.
+type columnRange struct {
+	Start int64
+	End   int64
+	Time  bool
+}
+
+func (r columnRange) offsets() offsetrange.Restriction {
+	return offsetrange.Restriction{Start: r.Start, End: r.End}
+}
+
+// bound returns a position as a query argument.
+func (r columnRange) bound(pos int64) any {
+	if r.Time {
+		return time.Unix(0, pos).UTC()
+	}
+	return pos
+}
+
+// evenSplits splits the range into up to n ranges of about the same size.
+// Unlike offsetrange.Restriction.EvenSplits, it doesn't overflow for ranges
+// of timestamps decades apart.
+func (r columnRange) evenSplits(n int64) []columnRange {
+	size := r.End - r.Start
+	if n <= 1 || size <= 1 {
+		return []columnRange{r}
+	}
+	if n > size {
+		n = size
+	}
+	step, rem := size/n, size%n
+	var splits []columnRange
+	start := r.Start
+	for i := int64(0); i < n; i++ {
+		end := start + step
+		if i < rem {
+			end++
+		}
+		splits = append(splits, columnRange{Start: start, End: end, Time: r.Time})
+		start = end
+	}
+	return splits
+}
+
+// columnRangeTracker tracks a columnRange with an offset range tracker.
+type columnRangeTracker struct {
+	sdf.BoundableRTracker
+	time bool
+}
+
+func (t *columnRangeTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	primary, residual, err = t.BoundableRTracker.TrySplit(fraction)
+	return t.wrap(primary), t.wrap(residual), err
+}
+
+func (t *columnRangeTracker) GetRestriction() any {
+	return t.wrap(t.BoundableRTracker.GetRestriction())
+}
+
+func (t *columnRangeTracker) wrap(rest any) any {
+	r, ok := rest.(offsetrange.Restriction)
+	if !ok {
+		return rest
+	}
+	return columnRange{Start: r.Start, End: r.End, Time: t.time}
+}
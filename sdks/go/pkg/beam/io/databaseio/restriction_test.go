@@ -0,0 +1,112 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+func TestColumnRange_evenSplits(t *testing.T) {
+	year := int64(365 * 24 * time.Hour)
+	tests := []struct {
+		name string
+		rest columnRange
+		n    int64
+		want []columnRange
+	}{
+		{
+			name: "even",
+			rest: columnRange{Start: 0, End: 8},
+			n:    4,
+			want: []columnRange{{0, 2, false}, {2, 4, false}, {4, 6, false}, {6, 8, false}},
+		},
+		{
+			name: "remainder",
+			rest: columnRange{Start: -5, End: 5},
+			n:    3,
+			want: []columnRange{{-5, -1, false}, {-1, 2, false}, {2, 5, false}},
+		},
+		{
+			name: "more splits than values",
+			rest: columnRange{Start: 1, End: 3},
+			n:    16,
+			want: []columnRange{{1, 2, false}, {2, 3, false}},
+		},
+		{
+			name: "empty",
+			rest: columnRange{},
+			n:    16,
+			want: []columnRange{{}},
+		},
+		{
+			name: "timestamps decades apart",
+			rest: columnRange{Start: 0, End: 60 * year, Time: true},
+			n:    3,
+			want: []columnRange{{0, 20 * year, true}, {20 * year, 40 * year, true}, {40 * year, 60 * year, true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.rest.evenSplits(test.n); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("evenSplits(%v) = %v, want %v", test.n, got, test.want)
+			}
+		})
+	}
+}
+
+func TestColumnRange_bound(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	if got, want := (columnRange{Time: true}).bound(ts.UnixNano()), any(ts); got != want {
+		t.Errorf("bound() = %v, want %v", got, want)
+	}
+	if got, want := (columnRange{}).bound(42), any(int64(42)); got != want {
+		t.Errorf("bound() = %v, want %v", got, want)
+	}
+}
+
+func TestColumnRangeTracker(t *testing.T) {
+	var rt sdf.RTracker = &columnRangeTracker{
+		BoundableRTracker: offsetrange.NewTracker(offsetrange.Restriction{Start: 0, End: 100}),
+		time:              true,
+	}
+	if !rt.TryClaim(int64(10)) {
+		t.Fatal("TryClaim(10) = false, want true")
+	}
+	primary, residual, err := rt.TrySplit(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (columnRange{Start: 0, End: 55, Time: true}); primary != want {
+		t.Errorf("TrySplit() primary = %v, want %v", primary, want)
+	}
+	if want := (columnRange{Start: 55, End: 100, Time: true}); residual != want {
+		t.Errorf("TrySplit() residual = %v, want %v", residual, want)
+	}
+	if got, want := rt.GetRestriction(), primary; got != want {
+		t.Errorf("GetRestriction() = %v, want %v", got, want)
+	}
+	if rt.TryClaim(int64(55)) {
+		t.Error("TryClaim(55) = true after split, want false")
+	}
+	if !rt.IsDone() {
+		t.Error("IsDone() = false, want true")
+	}
+}
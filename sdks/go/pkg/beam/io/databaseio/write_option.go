@@ -0,0 +1,80 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+// defaultBackoff is the default time before the first retry of a batch.
+const defaultBackoff = time.Second
+
+type writeOption struct {
+	BatchSize int
+	Upsert    []string
+	Retries   int
+	Backoff   time.Duration
+}
+
+// WriteOptionFn is a function that can be passed to WriteWithOptions to configure options for
+// writing to a database.
+type WriteOptionFn func(option *writeOption) error
+
+// WriteBatchSize sets the maximum number of rows of the batch INSERT statements. Defaults to
+// 1000.
+func WriteBatchSize(n int) WriteOptionFn {
+	return func(o *writeOption) error {
+		if n <= 0 {
+			return errors.New("batch size must be greater than 0")
+		}
+		o.BatchSize = n
+		return nil
+	}
+}
+
+// WriteUpsert specifies that rows conflicting with the rows written on the given key columns,
+// which must have a unique index, are updated with the values of the other columns. It uses
+// ON CONFLICT for the postgres, pgx, sqlite and sqlite3 drivers, and ON DUPLICATE KEY UPDATE
+// for the mysql driver, which updates rows conflicting on any unique index. Upserts make writes
+// idempotent, so that rows aren't duplicated when bundles are retried.
+func WriteUpsert(keys ...string) WriteOptionFn {
+	return func(o *writeOption) error {
+		if len(keys) == 0 {
+			return errors.New("upsert key columns must not be empty")
+		}
+		o.Upsert = keys
+		return nil
+	}
+}
+
+// WriteRetries sets the number of times the transaction of a batch is retried if it fails,
+// waiting for backoff before the first retry and doubling it for each following one. Batches
+// aren't retried by default.
+func WriteRetries(n int, backoff time.Duration) WriteOptionFn {
+	return func(o *writeOption) error {
+		if n < 0 {
+			return errors.New("retries must not be negative")
+		}
+		if backoff < 0 {
+			return errors.New("backoff must not be negative")
+		}
+		o.Retries = n
+		o.Backoff = backoff
+		return nil
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -37,41 +38,107 @@ type writer struct {
 	batchSize              int
 	table                  string
 	sqlTemplate            string
+	sqlSuffix              string
 	valueTemplateGenerator *valueTemplateGenerator
 	binding                []any
+	elements               []any
 	columnCount            int
 	rowCount               int
 	totalCount             int
+	// checkCount is whether the number of rows affected by a write must be
+	// the number of rows written, which isn't the case for upserts.
+	checkCount bool
+	retries    int
+	backoff    time.Duration
+	// failed is called with each row that couldn't be written, if set.
+	// Otherwise writes fail if a row can't be written.
+	failed func(elem any, err error)
 }
 
-func (w *writer) add(row []any) error {
-	w.rowCount++
-	w.totalCount++
+func (w *writer) add(elem any, row []any) error {
 	if len(row) != w.columnCount {
 		return errors.Errorf("expected %v row values, but had: %v", w.columnCount, len(row))
 	}
+	w.rowCount++
+	w.totalCount++
 	w.binding = append(w.binding, row...)
+	w.elements = append(w.elements, elem)
 	return nil
 }
 
+// write writes the pending rows in a transaction, retrying it on errors. If
+// it still fails and failed rows are reported, each row is then written in
+// its own transaction, and the rows that fail are reported.
 func (w *writer) write(ctx context.Context, db *sql.DB) error {
 	values := w.valueTemplateGenerator.generate(w.rowCount, w.columnCount)
 	if len(values) == 0 {
 		log.Info(ctx, "No value(s) to be written....")
 		return nil
 	}
-	SQL := w.sqlTemplate + values
-	resultSet, err := db.ExecContext(ctx, SQL, w.binding...)
+	defer w.reset()
+	err := w.retry(ctx, func() error {
+		return w.exec(ctx, db, values, w.rowCount, w.binding)
+	})
+	if err == nil || w.failed == nil {
+		return err
+	}
+	log.Warnf(ctx, "failed to write %v row(s) into %v, writing them one by one: %v", w.rowCount, w.table, err)
+	values = w.valueTemplateGenerator.generate(1, w.columnCount)
+	for i, elem := range w.elements {
+		row := w.binding[i*w.columnCount : (i+1)*w.columnCount]
+		if err := w.exec(ctx, db, values, 1, row); err != nil {
+			w.totalCount--
+			w.failed(elem, err)
+		}
+	}
+	return nil
+}
+
+// exec executes an insert of rowCount rows in a transaction.
+func (w *writer) exec(ctx context.Context, db *sql.DB, values string, rowCount int, binding []any) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	affected, _ := resultSet.RowsAffected()
-	if int(affected) != w.rowCount {
-		return errors.Errorf("expected to write: %v, but written: %v", w.rowCount, affected)
+	SQL := w.sqlTemplate + values + w.sqlSuffix
+	resultSet, err := tx.ExecContext(ctx, SQL, binding...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if w.checkCount {
+		affected, _ := resultSet.RowsAffected()
+		if int(affected) != rowCount {
+			tx.Rollback()
+			return errors.Errorf("expected to write: %v, but written: %v", rowCount, affected)
+		}
 	}
+	return tx.Commit()
+}
+
+// retry calls fn until it succeeds or has been retried w.retries times,
+// doubling the backoff between attempts.
+func (w *writer) retry(ctx context.Context, fn func() error) error {
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= w.retries {
+			return err
+		}
+		log.Warnf(ctx, "failed to write %v row(s) into %v, retrying in %v: %v", w.rowCount, w.table, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func (w *writer) reset() {
 	w.binding = []any{}
+	w.elements = nil
 	w.rowCount = 0
-	return nil
 }
 
 func (w *writer) writeBatchIfNeeded(ctx context.Context, db *sql.DB) error {
@@ -99,9 +166,60 @@ func newWriter(driver string, batchSize int, table string, columns []string) (*w
 		binding:                make([]any, 0),
 		sqlTemplate:            fmt.Sprintf("INSERT INTO %v(%v) VALUES", table, strings.Join(columns, ",")),
 		valueTemplateGenerator: &valueTemplateGenerator{driver},
+		checkCount:             true,
 	}, nil
 }
 
+// newUpsertWriter returns a writer that updates the rows that conflict with
+// the rows written on the given key columns.
+func newUpsertWriter(driver string, batchSize int, table string, columns, keys []string) (*writer, error) {
+	w, err := newWriter(driver, batchSize, table, columns)
+	if err != nil {
+		return nil, err
+	}
+	if w.sqlSuffix, err = upsertClause(driver, columns, keys); err != nil {
+		return nil, err
+	}
+	w.checkCount = false
+	return w, nil
+}
+
+// upsertClause returns the clause of an insert statement of the dialect of
+// the driver that updates the columns other than the key columns of the rows
+// that conflict with the rows inserted.
+func upsertClause(driver string, columns, keys []string) (string, error) {
+	if len(keys) == 0 {
+		return "", errors.New("upsert key columns were empty")
+	}
+	var updates []string
+	for _, column := range columns {
+		if columnIndex(keys, column) < 0 {
+			updates = append(updates, column)
+		}
+	}
+	switch driver {
+	case "postgres", "pgx", "sqlite", "sqlite3":
+		if len(updates) == 0 {
+			return fmt.Sprintf(" ON CONFLICT (%v) DO NOTHING", strings.Join(keys, ",")), nil
+		}
+		for i, column := range updates {
+			updates[i] = fmt.Sprintf("%v=EXCLUDED.%v", column, column)
+		}
+		return fmt.Sprintf(" ON CONFLICT (%v) DO UPDATE SET %v", strings.Join(keys, ","), strings.Join(updates, ",")), nil
+	case "mysql":
+		if len(updates) == 0 {
+			// Updating a key to itself leaves the row unchanged.
+			updates = []string{keys[0]}
+		}
+		for i, column := range updates {
+			updates[i] = fmt.Sprintf("%v=VALUES(%v)", column, column)
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ","), nil
+	default:
+		return "", errors.Errorf("upserts are not supported for driver %v", driver)
+	}
+}
+
 type valueTemplateGenerator struct {
 	driver string
 }
@@ -131,3 +249,14 @@ func (v *valueTemplateGenerator) generate(rowCount int, columnColunt int) string
 		return values[:len(values)-1]
 	}
 }
+
+// placeholder returns the placeholder of the n-th parameter of a query,
+// starting at 1.
+func (v *valueTemplateGenerator) placeholder(n int) string {
+	switch v.driver {
+	case "postgres", "pgx":
+		return fmt.Sprintf("$%d", n)
+	default:
+		return "?"
+	}
+}
@@ -16,8 +16,11 @@
 package databaseio
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestValueTemplateGenerator_generate(t *testing.T) {
@@ -71,3 +74,86 @@ func TestValueTemplateGenerator_generate(t *testing.T) {
 		})
 	}
 }
+
+func TestUpsertClause(t *testing.T) {
+	tests := []struct {
+		driver   string
+		columns  []string
+		keys     []string
+		expected string
+	}{
+		{
+			driver:   "postgres",
+			columns:  []string{"id", "name", "age"},
+			keys:     []string{"id"},
+			expected: " ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age",
+		},
+		{
+			driver:   "sqlite3",
+			columns:  []string{"a", "b", "c"},
+			keys:     []string{"a", "B"},
+			expected: " ON CONFLICT (a,B) DO UPDATE SET c=EXCLUDED.c",
+		},
+		{
+			driver:   "pgx",
+			columns:  []string{"id"},
+			keys:     []string{"id"},
+			expected: " ON CONFLICT (id) DO NOTHING",
+		},
+		{
+			driver:   "mysql",
+			columns:  []string{"id", "name", "age"},
+			keys:     []string{"id"},
+			expected: " ON DUPLICATE KEY UPDATE name=VALUES(name),age=VALUES(age)",
+		},
+		{
+			driver:   "mysql",
+			columns:  []string{"id"},
+			keys:     []string{"id"},
+			expected: " ON DUPLICATE KEY UPDATE id=VALUES(id)",
+		},
+	}
+	for _, test := range tests {
+		result, err := upsertClause(test.driver, test.columns, test.keys)
+		if err != nil {
+			t.Errorf("upsertClause(%v, %v, %v) failed: %v", test.driver, test.columns, test.keys, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("upsertClause(%v, %v, %v) = \"%v\", want \"%v\"", test.driver, test.columns, test.keys, result, test.expected)
+		}
+	}
+}
+
+func TestUpsertClause_invalid(t *testing.T) {
+	if _, err := upsertClause("ramsql", []string{"id"}, []string{"id"}); err == nil {
+		t.Error("upsertClause() succeeded for an unsupported driver, want error")
+	}
+	if _, err := upsertClause("postgres", []string{"id"}, nil); err == nil {
+		t.Error("upsertClause() succeeded without keys, want error")
+	}
+}
+
+func TestWriter_retry(t *testing.T) {
+	w := &writer{retries: 2, backoff: time.Millisecond}
+	var attempts int
+	err := w.retry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("retry() = %v after %v attempts, want success after 3", err, attempts)
+	}
+
+	attempts = 0
+	err = w.retry(context.Background(), func() error {
+		attempts++
+		return errors.New("permanent")
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("retry() = %v after %v attempts, want error after 3", err, attempts)
+	}
+}
@@ -20,6 +20,7 @@ package datastoreio
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"math"
 	"reflect"
 	"sort"
@@ -37,6 +38,8 @@ import (
 
 type clientType interface {
 	Run(context.Context, *datastore.Query) *datastore.Iterator
+	PutMulti(context.Context, []*datastore.Key, any) ([]*datastore.Key, error)
+	DeleteMulti(context.Context, []*datastore.Key) error
 	Close() error
 }
 
@@ -61,28 +64,45 @@ func init() {
 // itemKey = runtime.RegisterType(reflect.TypeOf((*Item)(nil)).Elem())
 //
 // datastoreio.Read(s, "project", "Item", 256, reflect.TypeOf(Item{}), itemKey)
-func Read(s beam.Scope, project, kind string, shards int, t reflect.Type, typeKey string) beam.PCollection {
+//
+// The entities read can be restricted with ReadFilter and ReadNamespace options. Reads with
+// an inequality filter are not sharded; see ReadFilter.
+func Read(s beam.Scope, project, kind string, shards int, t reflect.Type, typeKey string, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("datastore.Read")
 	// for portable runner consideration, set newClient to nil for now
 	// which will be initialized in DoFn's Setup() method
-	return query(s, project, kind, shards, t, typeKey, nil)
+	return query(s, project, kind, shards, t, typeKey, nil, opts...)
 }
 
 func datastoreNewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (clientType, error) {
 	return datastore.NewClient(ctx, projectID, opts...)
 }
 
-func query(s beam.Scope, project, kind string, shards int, t reflect.Type, typeKey string, newClient newClientFuncType) beam.PCollection {
+func query(s beam.Scope, project, kind string, shards int, t reflect.Type, typeKey string, newClient newClientFuncType, opts ...ReadOptionFn) beam.PCollection {
+	option := &readOption{}
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("datastoreio.Read: invalid option: %v", err))
+		}
+	}
+
+	if option.hasInequality() {
+		// Shards are bounded by inequality filters on __key__, which can't be
+		// combined with inequality filters on other properties.
+		shards = 1
+	}
+
 	imp := beam.Impulse(s)
-	ex := beam.ParDo(s, &splitQueryFn{Project: project, Kind: kind, Shards: shards, newClientFunc: newClient}, imp)
+	ex := beam.ParDo(s, &splitQueryFn{Project: project, Kind: kind, Shards: shards, Namespace: option.Namespace, newClientFunc: newClient}, imp)
 	g := beam.GroupByKey(s, ex)
-	return beam.ParDo(s, &queryFn{Project: project, Kind: kind, Type: typeKey, newClientFunc: newClient}, g, beam.TypeDefinition{Var: beam.XType, T: t})
+	return beam.ParDo(s, &queryFn{Project: project, Kind: kind, Type: typeKey, Namespace: option.Namespace, Filters: option.Filters, newClientFunc: newClient}, g, beam.TypeDefinition{Var: beam.XType, T: t})
 }
 
 type splitQueryFn struct {
 	Project       string `json:"project"`
 	Kind          string `json:"kind"`
 	Shards        int    `json:"shards"`
+	Namespace     string `json:"namespace"`
 	newClientFunc newClientFuncType
 }
 
@@ -119,7 +139,7 @@ func (s *splitQueryFn) ProcessElement(ctx context.Context, _ []byte, emit func(k
 	defer client.Close()
 
 	splits := []*datastore.Key{}
-	iter := client.Run(ctx, datastore.NewQuery(s.Kind).Order(scatterPropertyName).Limit((s.Shards-1)*32).KeysOnly())
+	iter := client.Run(ctx, datastore.NewQuery(s.Kind).Namespace(s.Namespace).Order(scatterPropertyName).Limit((s.Shards-1)*32).KeysOnly())
 	for {
 		k, err := iter.Next(nil)
 		if err != nil {
@@ -209,7 +229,11 @@ type queryFn struct {
 	// Kind is the datastore kind
 	Kind string `json:"kind"`
 	// Type is the name of the global schema type
-	Type          string `json:"type"`
+	Type string `json:"type"`
+	// Namespace is the namespace of the entities
+	Namespace string `json:"namespace"`
+	// Filters are the property filters of the query
+	Filters       []filter `json:"filters"`
 	newClientFunc newClientFuncType
 }
 
@@ -244,7 +268,14 @@ func (s *queryFn) ProcessElement(ctx context.Context, _ string, v func(*string)
 	}
 
 	// Translate BoundedQuery to datastore.Query
-	dq := datastore.NewQuery(s.Kind)
+	dq := datastore.NewQuery(s.Kind).Namespace(s.Namespace)
+	for _, f := range s.Filters {
+		v, err := f.Value.value()
+		if err != nil {
+			return err
+		}
+		dq = dq.FilterField(f.Field, f.Operator, v)
+	}
 	if q.Start != nil {
 		dq = dq.Filter("__key__ >=", q.Start)
 	}
@@ -44,6 +44,11 @@ func init() {
 type fakeClient struct {
 	runCounter   int
 	closeCounter int
+
+	// commits are the keys of each successful PutMulti or DeleteMulti call,
+	// and errs are the errors returned by the first calls.
+	commits [][]*datastore.Key
+	errs    []error
 }
 
 func (client *fakeClient) Run(context.Context, *datastore.Query) *datastore.Iterator {
@@ -52,6 +57,27 @@ func (client *fakeClient) Run(context.Context, *datastore.Query) *datastore.Iter
 	return new(datastore.Iterator)
 }
 
+func (client *fakeClient) PutMulti(_ context.Context, keys []*datastore.Key, src any) ([]*datastore.Key, error) {
+	if len(keys) != reflect.ValueOf(src).Len() {
+		return nil, errors.New("mismatched number of keys and entities")
+	}
+	return keys, client.commit(keys)
+}
+
+func (client *fakeClient) DeleteMulti(_ context.Context, keys []*datastore.Key) error {
+	return client.commit(keys)
+}
+
+func (client *fakeClient) commit(keys []*datastore.Key) error {
+	if len(client.errs) > 0 {
+		err := client.errs[0]
+		client.errs = client.errs[1:]
+		return err
+	}
+	client.commits = append(client.commits, keys)
+	return nil
+}
+
 func (client *fakeClient) Close() error {
 	client.closeCounter += 1
 	return nil
@@ -75,11 +101,16 @@ func Test_query(t *testing.T) {
 		shard       int
 		expectRun   int
 		expectClose int
+		opts        []ReadOptionFn
 	}{
 		// case 1: shard=1, without split query
-		{Foo{}, 1, 1, 1},
+		{Foo{}, 1, 1, 1, nil},
 		// case 2: shard=2 (>1), with split query
-		{Bar{}, 2, 2, 2},
+		{Bar{}, 2, 2, 2, nil},
+		// case 3: shard=2 (>1) with an equality filter, with split query
+		{Bar{}, 2, 2, 2, []ReadOptionFn{ReadFilter("Count", "=", 5)}},
+		// case 4: shard=2 (>1) with an inequality filter, without split query
+		{Bar{}, 2, 1, 1, []ReadOptionFn{ReadFilter("Count", ">=", 5)}},
 	}
 	for _, tc := range testCases {
 		// setup a fake newClient caller
@@ -94,7 +125,7 @@ func Test_query(t *testing.T) {
 		itemKey, _ := runtime.TypeKey(itemType)
 
 		p, s := beam.NewPipelineWithRoot()
-		query(s, "project", "Item", tc.shard, itemType, itemKey, newClient)
+		query(s, "project", "Item", tc.shard, itemType, itemKey, newClient, tc.opts...)
 
 		ptest.RunAndValidate(t, p)
 
@@ -158,7 +189,7 @@ func Test_query_Bad(t *testing.T) {
 }
 
 func Test_splitQueryFn_Setup(t *testing.T) {
-	s := splitQueryFn{"project", "kind", 1, nil}
+	s := splitQueryFn{Project: "project", Kind: "kind", Shards: 1}
 	err := s.Setup()
 	if nil != err {
 		t.Errorf("failed to call Setup, got error: %v", err)
@@ -169,7 +200,7 @@ func Test_splitQueryFn_Setup(t *testing.T) {
 }
 
 func Test_queryFn_Setup(t *testing.T) {
-	s := queryFn{"project", "kind", "type", nil}
+	s := queryFn{Project: "project", Kind: "kind", Type: "type"}
 	err := s.Setup()
 	if nil != err {
 		t.Errorf("failed to call Setup, got error: %v", err)
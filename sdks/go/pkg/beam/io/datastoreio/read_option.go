@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+type readOption struct {
+	Namespace string
+	Filters   []filter
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for reading
+// from Datastore.
+type ReadOptionFn func(option *readOption) error
+
+// ReadNamespace sets the namespace of the entities read. Defaults to the default namespace.
+func ReadNamespace(namespace string) ReadOptionFn {
+	return func(o *readOption) error {
+		o.Namespace = namespace
+		return nil
+	}
+}
+
+// ReadFilter restricts the entities read to those whose property satisfies the comparison
+// with the value, as with datastore.Query.FilterField. The operator is one of "=", "!=",
+// "<", "<=", ">" or ">=". The value must be a string, an integer, a float, a bool, a
+// time.Time or a *datastore.Key. Several filters can be given, which must all be satisfied.
+// Inequality filters may require a composite index.
+//
+// Read splits the kind into shards by key range, and Datastore doesn't allow combining the
+// inequality filters on __key__ bounding each shard with inequality filters on other
+// properties, so reads with an inequality filter ("!=", "<", "<=", ">" or ">=") are not
+// sharded and read all the entities in a single query. GQL queries are not supported, as
+// the Datastore client doesn't run them.
+func ReadFilter(field, operator string, value any) ReadOptionFn {
+	return func(o *readOption) error {
+		switch operator {
+		case "=", "!=", "<", "<=", ">", ">=":
+		default:
+			return errors.Errorf("invalid filter operator %q", operator)
+		}
+		v, err := newFilterValue(value)
+		if err != nil {
+			return err
+		}
+		o.Filters = append(o.Filters, filter{Field: field, Operator: operator, Value: v})
+		return nil
+	}
+}
+
+// hasInequality reports whether any of the filters is an inequality filter.
+func (o *readOption) hasInequality() bool {
+	for _, f := range o.Filters {
+		if f.Operator != "=" {
+			return true
+		}
+	}
+	return false
+}
+
+// filter is a property filter of a query.
+type filter struct {
+	Field    string      `json:"field"`
+	Operator string      `json:"operator"`
+	Value    filterValue `json:"value"`
+}
+
+// filterValue is the value of a filter, in the field of its type, so that it
+// keeps its type once the DoFns are serialized.
+type filterValue struct {
+	String *string    `json:"string,omitempty"`
+	Int    *int64     `json:"int,omitempty"`
+	Float  *float64   `json:"float,omitempty"`
+	Bool   *bool      `json:"bool,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+	// Key is an encoded key.
+	Key *string `json:"key,omitempty"`
+}
+
+func newFilterValue(v any) (filterValue, error) {
+	var fv filterValue
+	switch v := v.(type) {
+	case string:
+		fv.String = &v
+	case int:
+		i := int64(v)
+		fv.Int = &i
+	case int32:
+		i := int64(v)
+		fv.Int = &i
+	case int64:
+		fv.Int = &v
+	case float32:
+		f := float64(v)
+		fv.Float = &f
+	case float64:
+		fv.Float = &v
+	case bool:
+		fv.Bool = &v
+	case time.Time:
+		fv.Time = &v
+	case *datastore.Key:
+		k := v.Encode()
+		fv.Key = &k
+	default:
+		return fv, errors.Errorf("unsupported filter value type %T", v)
+	}
+	return fv, nil
+}
+
+func (fv filterValue) value() (any, error) {
+	switch {
+	case fv.String != nil:
+		return *fv.String, nil
+	case fv.Int != nil:
+		return *fv.Int, nil
+	case fv.Float != nil:
+		return *fv.Float, nil
+	case fv.Bool != nil:
+		return *fv.Bool, nil
+	case fv.Time != nil:
+		return *fv.Time, nil
+	case fv.Key != nil:
+		return datastore.DecodeKey(*fv.Key)
+	}
+	return nil, errors.New("filter has no value")
+}
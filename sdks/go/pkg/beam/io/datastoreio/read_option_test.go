@@ -0,0 +1,79 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestReadFilter tests that filter values keep their types once serialized.
+func TestReadFilter(t *testing.T) {
+	tests := []struct {
+		value any
+		want  any
+	}{
+		{"a", "a"},
+		{3, int64(3)},
+		{int32(3), int64(3)},
+		{int64(1) << 60, int64(1) << 60},
+		{1.5, 1.5},
+		{true, true},
+		{time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)},
+		{datastore.NameKey("A", "a", datastore.IDKey("B", 1, nil)), datastore.NameKey("A", "a", datastore.IDKey("B", 1, nil))},
+	}
+	for _, test := range tests {
+		o := &readOption{}
+		if err := ReadFilter("f", ">=", test.value)(o); err != nil {
+			t.Fatalf("ReadFilter(%v) failed: %v", test.value, err)
+		}
+		b, err := json.Marshal(o.Filters)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) failed: %v", o.Filters, err)
+		}
+		var filters []filter
+		if err := json.Unmarshal(b, &filters); err != nil {
+			t.Fatalf("json.Unmarshal(%s) failed: %v", b, err)
+		}
+		got, err := filters[0].Value.value()
+		if err != nil {
+			t.Fatalf("value() of %v failed: %v", test.value, err)
+		}
+		if !cmp.Equal(got, test.want) {
+			t.Errorf("value() of %v = %#v, want %#v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestReadFilter_bad(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    any
+	}{
+		{"~", "a"},
+		{"=", []string{"a"}},
+		{"=", nil},
+	}
+	for _, test := range tests {
+		if err := ReadFilter("f", test.operator, test.value)(&readOption{}); err == nil {
+			t.Errorf("ReadFilter(%q, %v) succeeded, want error", test.operator, test.value)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// movingSum is a sum of values added over a sliding window of time, kept in
+// buckets of a fixed duration.
+type movingSum struct {
+	bucket time.Duration
+	sums   []float64
+	starts []int64 // Tracks the start of the bucket each sum is for.
+}
+
+func newMovingSum(window, bucket time.Duration) *movingSum {
+	n := int(window / bucket)
+	return &movingSum{bucket: bucket, sums: make([]float64, n), starts: make([]int64, n)}
+}
+
+func (m *movingSum) index(now time.Time) (int, int64) {
+	start := now.UnixNano() / int64(m.bucket)
+	return int(start % int64(len(m.sums))), start
+}
+
+// add adds the value to the bucket of the time.
+func (m *movingSum) add(now time.Time, v float64) {
+	i, start := m.index(now)
+	if m.starts[i] != start {
+		m.starts[i], m.sums[i] = start, 0
+	}
+	m.sums[i] += v
+}
+
+// sum returns the sum of the values added within the window before the time.
+func (m *movingSum) sum(now time.Time) float64 {
+	_, start := m.index(now)
+	var sum float64
+	for i, s := range m.starts {
+		if d := start - s; d >= 0 && d < int64(len(m.sums)) {
+			sum += m.sums[i]
+		}
+	}
+	return sum
+}
+
+const (
+	// throttleWindow is the window over which requests are counted.
+	throttleWindow = 60 * time.Second
+	// overloadRatio is the ratio of requests to successful requests above
+	// which requests are throttled.
+	overloadRatio = 2
+)
+
+// adaptiveThrottler throttles requests client-side when the backend rejects
+// too many of them, following the "Handling Overload" section of
+// https://sre.google/sre-book/handling-overload/. The probability of
+// throttling a request grows with the ratio of requests to successful
+// requests over the last minute, once it exceeds overloadRatio.
+type adaptiveThrottler struct {
+	requests  *movingSum
+	successes *movingSum
+	random    func() float64
+}
+
+func newAdaptiveThrottler() *adaptiveThrottler {
+	return &adaptiveThrottler{
+		requests:  newMovingSum(throttleWindow, time.Second),
+		successes: newMovingSum(throttleWindow, time.Second),
+		random:    rand.Float64,
+	}
+}
+
+// throttleProbability returns the probability of throttling a request at the
+// time.
+func (t *adaptiveThrottler) throttleProbability(now time.Time) float64 {
+	requests := t.requests.sum(now)
+	successes := t.successes.sum(now)
+	return math.Max(0, (requests-overloadRatio*successes)/(requests+1))
+}
+
+// throttleRequest returns whether to throttle a request made at the time,
+// counting it.
+func (t *adaptiveThrottler) throttleRequest(now time.Time) bool {
+	throttle := t.random() < t.throttleProbability(now)
+	t.requests.add(now, 1)
+	return throttle
+}
+
+// successfulRequest counts a successful request made at the time.
+func (t *adaptiveThrottler) successfulRequest(now time.Time) {
+	t.successes.add(now, 1)
+}
+
+const (
+	// batchSizeStart is the size of batches until the latency of writes is
+	// known.
+	batchSizeStart = 200
+	// batchSizeMin is the minimum size of batches.
+	batchSizeMin = 5
+	// batchSizeLimit is the maximum number of mutations in a commit.
+	batchSizeLimit = 500
+	// batchTargetLatency is the desired latency of writing a batch.
+	batchTargetLatency = 6 * time.Second
+	// batchLatencyWindow is the window over which write latencies are
+	// averaged.
+	batchLatencyWindow = 2 * time.Minute
+)
+
+// batcher sizes batches of writes so that they take about
+// batchTargetLatency, based on the recent latency of writes per entity.
+type batcher struct {
+	maxSize   int
+	latencies *movingSum // Tracks the latency of writes, in milliseconds.
+	entities  *movingSum // Tracks the number of entities written.
+}
+
+func newBatcher(maxSize int) *batcher {
+	return &batcher{
+		maxSize:   maxSize,
+		latencies: newMovingSum(batchLatencyWindow, 10*time.Second),
+		entities:  newMovingSum(batchLatencyWindow, 10*time.Second),
+	}
+}
+
+// nextBatchSize returns the size of the next batch at the time.
+func (b *batcher) nextBatchSize(now time.Time) int {
+	size := batchSizeStart
+	if entities := b.entities.sum(now); entities > 0 {
+		perEntity := math.Max(b.latencies.sum(now)/entities, 1)
+		size = int(float64(batchTargetLatency.Milliseconds()) / perEntity)
+	}
+	if size < batchSizeMin {
+		size = batchSizeMin
+	}
+	if size > b.maxSize {
+		size = b.maxSize
+	}
+	return size
+}
+
+// addRequestLatency records the latency of writing a batch of the number of
+// entities at the time.
+func (b *batcher) addRequestLatency(now time.Time, latency time.Duration, entities int) {
+	b.latencies.add(now, float64(latency.Milliseconds()))
+	b.entities.add(now, float64(entities))
+}
@@ -0,0 +1,102 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_movingSum(t *testing.T) {
+	m := newMovingSum(10*time.Second, time.Second)
+	m.add(testNow, 1)
+	m.add(testNow.Add(500*time.Millisecond), 2)
+	m.add(testNow.Add(5*time.Second), 3)
+
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{0, 3},
+		{5 * time.Second, 6},
+		{9 * time.Second, 6},
+		{10 * time.Second, 3},
+		{15 * time.Second, 0},
+	}
+	for _, test := range tests {
+		if got := m.sum(testNow.Add(test.at)); got != test.want {
+			t.Errorf("sum() after %v = %v, want %v", test.at, got, test.want)
+		}
+	}
+}
+
+func Test_adaptiveThrottler(t *testing.T) {
+	th := newAdaptiveThrottler()
+	th.random = func() float64 { return 0.1 }
+
+	// Requests aren't throttled while enough of them succeed.
+	for i := 0; i < 10; i++ {
+		if th.throttleRequest(testNow) {
+			t.Fatalf("request %v throttled with no failures", i)
+		}
+		th.successfulRequest(testNow)
+	}
+	if p := th.throttleProbability(testNow); p != 0 {
+		t.Errorf("throttleProbability() with no failures = %v, want 0", p)
+	}
+
+	// Requests are throttled once too many fail.
+	for i := 0; i < 30; i++ {
+		th.throttleRequest(testNow)
+	}
+	if p, want := th.throttleProbability(testNow), (40.0-2*10)/41; p != want {
+		t.Errorf("throttleProbability() with failures = %v, want %v", p, want)
+	}
+	if !th.throttleRequest(testNow) {
+		t.Error("request not throttled with failures")
+	}
+
+	// Failures are forgotten after a minute.
+	if p := th.throttleProbability(testNow.Add(throttleWindow)); p != 0 {
+		t.Errorf("throttleProbability() a minute after failures = %v, want 0", p)
+	}
+}
+
+func Test_batcher(t *testing.T) {
+	b := newBatcher(batchSizeLimit)
+	if got := b.nextBatchSize(testNow); got != batchSizeStart {
+		t.Errorf("nextBatchSize() before writes = %v, want %v", got, batchSizeStart)
+	}
+
+	// 60ms per entity gives batches taking 6s.
+	b.addRequestLatency(testNow, 6*time.Second, 100)
+	if got := b.nextBatchSize(testNow); got != 100 {
+		t.Errorf("nextBatchSize() at 60ms per entity = %v, want 100", got)
+	}
+
+	// Sizes are clamped.
+	b.addRequestLatency(testNow, 10*time.Minute, 100)
+	if got := b.nextBatchSize(testNow); got != batchSizeMin {
+		t.Errorf("nextBatchSize() with slow writes = %v, want %v", got, batchSizeMin)
+	}
+	b = newBatcher(50)
+	b.addRequestLatency(testNow, time.Millisecond, 100)
+	if got := b.nextBatchSize(testNow); got != 50 {
+		t.Errorf("nextBatchSize() with fast writes = %v, want 50", got)
+	}
+}
@@ -0,0 +1,291 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*deleteFn)(nil)).Elem())
+}
+
+var (
+	// throttleDelay is how long commits are delayed when throttled.
+	throttleDelay = 5 * time.Second
+	// retryBackoff is the delay before the first retry of a failed commit,
+	// doubled for each following retry.
+	retryBackoff = 1 * time.Second
+	// maxRetries is the number of times a failed commit is retried.
+	maxRetries = 5
+
+	throttlingMsecs = beam.NewCounter("datastoreio", "throttlingMsecs")
+)
+
+// Write writes the entities of a PCollection<KV<string, T>> to Datastore, where
+// the keys are the encoded keys of the entities, as returned by
+// datastore.Key.Encode, and T is a struct or a type whose pointer implements
+// datastore.PropertyLoadSaver. The keys must be complete, so that writes can be
+// retried, and existing entities with the keys are replaced.
+//
+// Example:
+//
+//	entities := beam.ParDo(s, func(item Item) (string, Item) {
+//		return datastore.NameKey("Item", item.ID, nil).Encode(), item
+//	}, items)
+//	datastoreio.Write(s, "project", entities)
+//
+// Entities are written in batches sized so that commits take a few seconds.
+// Commits failing with transient errors are retried, and are throttled
+// client-side when many of them fail, such as when Datastore is overloaded.
+func Write(s beam.Scope, project string, col beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("datastore.Write")
+	write(s, project, col, nil, opts...)
+}
+
+func write(s beam.Scope, project string, col beam.PCollection, newClient newClientFuncType, opts ...WriteOptionFn) {
+	t := col.Type()
+	if !typex.IsKV(t) || t.Components()[0].Type() != reflectx.String {
+		panic(fmt.Sprintf("datastoreio.Write: collection must be a PCollection<KV<string, T>>, but is %v", t))
+	}
+	if v := t.Components()[1].Type(); v.Kind() != reflect.Struct && !reflect.PtrTo(v).Implements(propertyLoadSaverType) {
+		panic(fmt.Sprintf("datastoreio.Write: entities must be structs or implement datastore.PropertyLoadSaver, but are %v", v))
+	}
+	beam.ParDo0(s, &writeFn{mutateFn: newMutateFn(project, newClient, opts)}, col)
+}
+
+var propertyLoadSaverType = reflect.TypeOf((*datastore.PropertyLoadSaver)(nil)).Elem()
+
+// DeleteKeys deletes the entities with the keys of a PCollection<string> from
+// Datastore, where the keys are encoded keys, as returned by
+// datastore.Key.Encode. Keys of missing entities are ignored.
+//
+// Like Write, the entities are deleted in batches, with retries and
+// throttling.
+func DeleteKeys(s beam.Scope, project string, keys beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("datastore.DeleteKeys")
+	deleteKeys(s, project, keys, nil, opts...)
+}
+
+func deleteKeys(s beam.Scope, project string, keys beam.PCollection, newClient newClientFuncType, opts ...WriteOptionFn) {
+	if t := keys.Type().Type(); t != reflectx.String {
+		panic(fmt.Sprintf("datastoreio.DeleteKeys: collection must be a PCollection<string>, but is %v", t))
+	}
+	beam.ParDo0(s, &deleteFn{mutateFn: newMutateFn(project, newClient, opts)}, keys)
+}
+
+// DeleteEntities deletes the entities of a PCollection<KV<string, T>> from
+// Datastore, as passed to Write, by their keys.
+func DeleteEntities(s beam.Scope, project string, col beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("datastore.DeleteEntities")
+	if !typex.IsKV(col.Type()) {
+		panic(fmt.Sprintf("datastoreio.DeleteEntities: collection must be a PCollection<KV<string, T>>, but is %v", col.Type()))
+	}
+	deleteKeys(s, project, beam.DropValue(s, col), nil, opts...)
+}
+
+// mutateFn batches the keys, and the entities for writes, of the mutations
+// of writeFn and deleteFn, and commits the batches.
+type mutateFn struct {
+	// Project is the project
+	Project string `json:"project"`
+	// MaxBatchSize is the maximum number of mutations in a commit
+	MaxBatchSize int `json:"maxBatchSize"`
+	// NoThrottling disables the throttling of commits
+	NoThrottling  bool `json:"noThrottling"`
+	newClientFunc newClientFuncType
+
+	client    clientType
+	batcher   *batcher
+	throttler *adaptiveThrottler
+	keys      []*datastore.Key
+	values    []any
+	batched   map[string]bool // Tracks the keys in the batch.
+}
+
+func newMutateFn(project string, newClient newClientFuncType, opts []WriteOptionFn) mutateFn {
+	option := &writeOption{MaxBatchSize: batchSizeLimit}
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("datastoreio: invalid option: %v", err))
+		}
+	}
+	return mutateFn{
+		Project:       project,
+		MaxBatchSize:  option.MaxBatchSize,
+		NoThrottling:  option.NoThrottling,
+		newClientFunc: newClient,
+	}
+}
+
+func (fn *mutateFn) Setup(ctx context.Context) error {
+	if nil == fn.newClientFunc {
+		// setup default newClientFunc for DoFns
+		fn.newClientFunc = datastoreNewClient
+	}
+	client, err := fn.newClientFunc(ctx, fn.Project)
+	if err != nil {
+		return err
+	}
+	fn.client = client
+	fn.batcher = newBatcher(fn.MaxBatchSize)
+	fn.throttler = newAdaptiveThrottler()
+	fn.batched = make(map[string]bool)
+	return nil
+}
+
+func (fn *mutateFn) Teardown() error {
+	if fn.client == nil {
+		return nil
+	}
+	return fn.client.Close()
+}
+
+// add adds a mutation to the batch, committing the batch before if it already
+// has a mutation of the key, and after if it's full.
+func (fn *mutateFn) add(ctx context.Context, encoded string, value any, commit func(context.Context) error) error {
+	key, err := datastore.DecodeKey(encoded)
+	if err != nil {
+		return errors.Wrapf(err, "invalid key %q", encoded)
+	}
+	if key.Incomplete() {
+		return errors.Errorf("key %v is incomplete", key)
+	}
+
+	// Datastore rejects commits with several mutations of an entity.
+	if fn.batched[key.String()] {
+		if err := fn.flush(ctx, commit); err != nil {
+			return err
+		}
+	}
+	fn.keys = append(fn.keys, key)
+	if value != nil {
+		fn.values = append(fn.values, value)
+	}
+	fn.batched[key.String()] = true
+
+	if len(fn.keys) >= fn.batcher.nextBatchSize(time.Now()) {
+		return fn.flush(ctx, commit)
+	}
+	return nil
+}
+
+// flush commits the batch, retrying it if it fails with a transient error.
+func (fn *mutateFn) flush(ctx context.Context, commit func(context.Context) error) error {
+	if len(fn.keys) == 0 {
+		return nil
+	}
+
+	for retries := 0; ; {
+		start := time.Now()
+		if !fn.NoThrottling && fn.throttler.throttleRequest(start) {
+			log.Infof(ctx, "Delaying commit of %d entities due to previous failures", len(fn.keys))
+			throttlingMsecs.Inc(ctx, throttleDelay.Milliseconds())
+			if err := sleep(ctx, throttleDelay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := commit(ctx)
+		end := time.Now()
+		fn.batcher.addRequestLatency(end, end.Sub(start), len(fn.keys))
+		if err == nil {
+			fn.throttler.successfulRequest(start)
+			break
+		}
+		if !retryable(err) || retries >= maxRetries {
+			return errors.Wrapf(err, "failed to commit %d entities", len(fn.keys))
+		}
+		log.Warnf(ctx, "Retrying commit of %d entities: %v", len(fn.keys), err)
+		if err := sleep(ctx, retryBackoff<<retries); err != nil {
+			return err
+		}
+		retries++
+	}
+
+	fn.keys, fn.values = nil, nil
+	fn.batched = make(map[string]bool)
+	return nil
+}
+
+func (fn *mutateFn) put(ctx context.Context) error {
+	_, err := fn.client.PutMulti(ctx, fn.keys, fn.values)
+	return err
+}
+
+func (fn *mutateFn) delete(ctx context.Context) error {
+	return fn.client.DeleteMulti(ctx, fn.keys)
+}
+
+// retryable returns whether a commit failing with the error may succeed if
+// retried.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted, codes.Internal:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+type writeFn struct {
+	mutateFn
+}
+
+func (fn *writeFn) ProcessElement(ctx context.Context, key string, value beam.X) error {
+	// Entities are saved from pointers, which PropertyLoadSavers may require.
+	v := reflect.New(reflect.TypeOf(value))
+	v.Elem().Set(reflect.ValueOf(value))
+	return fn.add(ctx, key, v.Interface(), fn.put)
+}
+
+func (fn *writeFn) FinishBundle(ctx context.Context) error {
+	return fn.flush(ctx, fn.put)
+}
+
+type deleteFn struct {
+	mutateFn
+}
+
+func (fn *deleteFn) ProcessElement(ctx context.Context, key string) error {
+	return fn.add(ctx, key, nil, fn.delete)
+}
+
+func (fn *deleteFn) FinishBundle(ctx context.Context) error {
+	return fn.flush(ctx, fn.delete)
+}
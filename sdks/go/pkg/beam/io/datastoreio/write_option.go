@@ -0,0 +1,51 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+type writeOption struct {
+	MaxBatchSize int
+	NoThrottling bool
+}
+
+// WriteOptionFn is a function that can be passed to Write, DeleteKeys and DeleteEntities to
+// configure options for writing to Datastore.
+type WriteOptionFn func(option *writeOption) error
+
+// WriteMaxBatchSize sets the maximum number of entities written or deleted in a commit.
+// Batches are sized so that commits take a few seconds, up to this size. Defaults to and
+// must not exceed 500, the limit of Datastore.
+func WriteMaxBatchSize(n int) WriteOptionFn {
+	return func(o *writeOption) error {
+		if n <= 0 || n > batchSizeLimit {
+			return errors.Errorf("batch size must be between 1 and %d", batchSizeLimit)
+		}
+		o.MaxBatchSize = n
+		return nil
+	}
+}
+
+// WriteNoThrottling disables the client-side throttling of commits when Datastore rejects
+// many of them, such as when it's overloaded.
+func WriteNoThrottling() WriteOptionFn {
+	return func(o *writeOption) error {
+		o.NoThrottling = true
+		return nil
+	}
+}
@@ -0,0 +1,266 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Item struct {
+	Name string
+	N    int
+}
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*Item)(nil)).Elem())
+	register.Function1x2(keyItem)
+}
+
+func keyItem(item Item) (string, Item) {
+	return itemKey(item.Name), item
+}
+
+func itemKey(name string) string {
+	return datastore.NameKey("Item", name, nil).Encode()
+}
+
+func items(n int) []Item {
+	var items []Item
+	for i := 0; i < n; i++ {
+		items = append(items, Item{Name: fmt.Sprintf("item%d", i), N: i})
+	}
+	return items
+}
+
+// keyNames returns the names of the keys of each commit.
+func keyNames(commits [][]*datastore.Key) [][]string {
+	var names [][]string
+	for _, keys := range commits {
+		var batch []string
+		for _, k := range keys {
+			batch = append(batch, k.Name)
+		}
+		names = append(names, batch)
+	}
+	return names
+}
+
+func newFakeClientFunc(client *fakeClient) newClientFuncType {
+	return func(context.Context, string, ...option.ClientOption) (clientType, error) {
+		return client, nil
+	}
+}
+
+func Test_write(t *testing.T) {
+	client := &fakeClient{}
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyItem, beam.CreateList(s, items(10)))
+	write(s, "project", col, newFakeClientFunc(client), WriteMaxBatchSize(4))
+	ptest.RunAndValidate(t, p)
+
+	var sizes []int
+	written := 0
+	for _, keys := range client.commits {
+		sizes = append(sizes, len(keys))
+		written += len(keys)
+	}
+	if written != 10 {
+		t.Errorf("got %v entities written, want 10", written)
+	}
+	for _, size := range sizes {
+		if size > 4 {
+			t.Errorf("got commits of %v entities, want at most 4", sizes)
+		}
+	}
+	if client.closeCounter == 0 {
+		t.Error("client was not closed")
+	}
+}
+
+func Test_deleteKeys(t *testing.T) {
+	client := &fakeClient{}
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyItem, beam.CreateList(s, items(3)))
+	deleteKeys(s, "project", beam.DropValue(s, col), newFakeClientFunc(client))
+	ptest.RunAndValidate(t, p)
+
+	deleted := 0
+	for _, keys := range client.commits {
+		deleted += len(keys)
+	}
+	if deleted != 3 {
+		t.Errorf("got %v entities deleted, want 3", deleted)
+	}
+}
+
+func Test_write_badType(t *testing.T) {
+	tests := []struct {
+		name string
+		col  func(s beam.Scope) beam.PCollection
+	}{
+		{
+			name: "NotKV",
+			col:  func(s beam.Scope) beam.PCollection { return beam.CreateList(s, items(1)) },
+		},
+		{
+			name: "NotStruct",
+			col:  func(s beam.Scope) beam.PCollection { return beam.ParDo(s, keyInt, beam.Create(s, 1)) },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("write did not panic")
+				}
+			}()
+			_, s := beam.NewPipelineWithRoot()
+			write(s, "project", test.col(s), nil)
+		})
+	}
+}
+
+func keyInt(i int) (string, int) {
+	return fmt.Sprint(i), i
+}
+
+func newTestWriteFn(t *testing.T, client *fakeClient, opts ...WriteOptionFn) *writeFn {
+	t.Helper()
+	fn := &writeFn{mutateFn: newMutateFn("project", newFakeClientFunc(client), opts)}
+	if err := fn.Setup(context.Background()); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	return fn
+}
+
+// Test_writeFn_duplicateKeys tests that batches are committed before they'd
+// have several mutations of an entity.
+func Test_writeFn_duplicateKeys(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{}
+	fn := newTestWriteFn(t, client)
+	for _, name := range []string{"a", "b", "a", "c"} {
+		if err := fn.ProcessElement(ctx, itemKey(name), Item{Name: name}); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", name, err)
+		}
+	}
+	if err := fn.FinishBundle(ctx); err != nil {
+		t.Fatalf("FinishBundle() failed: %v", err)
+	}
+	if got, want := keyNames(client.commits), [][]string{{"a", "b"}, {"a", "c"}}; !cmp.Equal(got, want) {
+		t.Errorf("got commits %v, want %v", got, want)
+	}
+}
+
+func Test_writeFn_retry(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name    string
+		errs    []error
+		wantErr bool
+	}{
+		{
+			name: "Transient",
+			errs: []error{status.Error(codes.Unavailable, "unavailable"), status.Error(codes.Aborted, "contention")},
+		},
+		{
+			name:    "Permanent",
+			errs:    []error{status.Error(codes.InvalidArgument, "invalid")},
+			wantErr: true,
+		},
+		{
+			name: "TooManyRetries",
+			errs: []error{
+				status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""),
+				status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""),
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := &fakeClient{errs: test.errs}
+			fn := newTestWriteFn(t, client, WriteNoThrottling())
+			if err := fn.ProcessElement(ctx, itemKey("a"), Item{Name: "a"}); err != nil {
+				t.Fatalf("ProcessElement() failed: %v", err)
+			}
+			err := fn.FinishBundle(ctx)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("FinishBundle() = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && len(client.commits) != 1 {
+				t.Errorf("got %v commits, want 1", len(client.commits))
+			}
+		})
+	}
+}
+
+func Test_writeFn_badKey(t *testing.T) {
+	ctx := context.Background()
+	fn := newTestWriteFn(t, &fakeClient{})
+	for _, key := range []string{"not a key", datastore.IncompleteKey("Item", nil).Encode()} {
+		if err := fn.ProcessElement(ctx, key, Item{}); err == nil {
+			t.Errorf("ProcessElement(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func Test_deleteFn(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{}
+	fn := &deleteFn{mutateFn: newMutateFn("project", newFakeClientFunc(client), []WriteOptionFn{WriteMaxBatchSize(2)})}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := fn.ProcessElement(ctx, itemKey(name)); err != nil {
+			t.Fatalf("ProcessElement(%v) failed: %v", name, err)
+		}
+	}
+	if err := fn.FinishBundle(ctx); err != nil {
+		t.Fatalf("FinishBundle() failed: %v", err)
+	}
+	if got, want := keyNames(client.commits), [][]string{{"a", "b"}, {"c"}}; !cmp.Equal(got, want) {
+		t.Errorf("got commits %v, want %v", got, want)
+	}
+	if err := fn.Teardown(); err != nil || client.closeCounter != 1 {
+		t.Errorf("Teardown() = %v, closed the client %v times, want 1", err, client.closeCounter)
+	}
+}
+
+func TestWriteMaxBatchSize(t *testing.T) {
+	for _, n := range []int{0, batchSizeLimit + 1} {
+		if err := WriteMaxBatchSize(n)(&writeOption{}); err == nil {
+			t.Errorf("WriteMaxBatchSize(%v) succeeded, want error", n)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	dsbzip2 "github.com/dsnet/compress/bzip2"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	snappyMagic = []byte{0xff, 0x06, 0x00, 0x00, 's', 'N', 'a', 'P', 'p', 'Y'}
+	// bzip2 streams start with "BZh", the block size and the magic number of
+	// either a block or the end of the stream.
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// compressionFromMagic detects the compression of a file based on its first bytes, without
+// consuming them. Deflate can't be reliably told apart from uncompressed data, so it is never
+// detected. If no compression is detected, compressionUncompressed is returned.
+func compressionFromMagic(br *bufio.Reader) compressionType {
+	header, _ := br.Peek(10)
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return compressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return compressionZstd
+	case bytes.HasPrefix(header, snappyMagic):
+		return compressionSnappy
+	case len(header) == 10 && bytes.HasPrefix(header, []byte("BZh")) && header[3] >= '1' && header[3] <= '9' &&
+		(bytes.Equal(header[4:], bzip2BlockMagic) || bytes.Equal(header[4:], bzip2EndMagic)):
+		return compressionBzip2
+	default:
+		return compressionUncompressed
+	}
+}
+
+// bufferedReadCloser is a bufio.Reader that also closes the underlying io.ReadCloser.
+type bufferedReadCloser struct {
+	*bufio.Reader
+	rc io.ReadCloser
+}
+
+// Close closes the underlying io.ReadCloser.
+func (r *bufferedReadCloser) Close() error {
+	return r.rc.Close()
+}
+
+// Seek seeks the underlying io.ReadCloser, if it implements io.Seeker, and
+// discards the buffered data.
+func (r *bufferedReadCloser) Seek(offset int64, whence int) (int64, error) {
+	s, ok := r.rc.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("%T doesn't implement io.Seeker", r.rc)
+	}
+	if whence == io.SeekCurrent {
+		offset -= int64(r.Buffered())
+	}
+	pos, err := s.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	r.Reset(r.rc)
+	return pos, nil
+}
+
+// decompressionReader is a wrapper around a decompressing io.Reader that also closes the
+// decompressor, if needed, and the underlying io.ReadCloser.
+type decompressionReader struct {
+	r     io.Reader
+	rc    io.ReadCloser
+	close func() error
+}
+
+// Read reads from the decompressor.
+func (r *decompressionReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+// Close closes the decompressor and the underlying io.ReadCloser.
+func (r *decompressionReader) Close() error {
+	var err error
+	if r.close != nil {
+		err = r.close()
+	}
+	if rcErr := r.rc.Close(); err == nil {
+		err = rcErr
+	}
+	return err
+}
+
+// newZstdReader creates a new io.ReadCloser that decompresses zstd data from an io.ReadCloser.
+func newZstdReader(rc io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(rc, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &decompressionReader{
+		r:  zr,
+		rc: rc,
+		close: func() error {
+			zr.Close()
+			return nil
+		},
+	}, nil
+}
+
+// newBzip2Reader creates a new io.ReadCloser that decompresses bzip2 data from an io.ReadCloser.
+func newBzip2Reader(rc io.ReadCloser) io.ReadCloser {
+	return &decompressionReader{r: bzip2.NewReader(rc), rc: rc}
+}
+
+// newSnappyReader creates a new io.ReadCloser that decompresses data in the snappy framing format
+// from an io.ReadCloser.
+func newSnappyReader(rc io.ReadCloser) io.ReadCloser {
+	return &decompressionReader{r: snappy.NewReader(rc), rc: rc}
+}
+
+// newDeflateReader creates a new io.ReadCloser that decompresses zlib-wrapped deflate data from an
+// io.ReadCloser.
+func newDeflateReader(rc io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := zlib.NewReader(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &decompressionReader{r: zr, rc: rc, close: zr.Close}, nil
+}
+
+// nopWriteCloser is an io.WriteCloser whose Close does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newCompressionWriter returns an io.WriteCloser that compresses data written to it with the
+// specified compression and writes it to w. Closing the returned writer flushes all data to w,
+// but doesn't close w.
+func newCompressionWriter(w io.Writer, compression compressionType) (io.WriteCloser, error) {
+	switch compression {
+	case compressionUncompressed:
+		return nopWriteCloser{w}, nil
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case compressionSnappy:
+		return snappy.NewBufferedWriter(w), nil
+	case compressionBzip2:
+		return dsbzip2.NewWriter(w, nil)
+	case compressionDeflate:
+		return zlib.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("compression type %v isn't supported for writing", compression)
+	}
+}
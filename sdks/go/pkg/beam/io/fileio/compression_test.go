@@ -0,0 +1,173 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	register.Function2x2(readStringFn)
+}
+
+func readStringFn(ctx context.Context, file ReadableFile) (string, error) {
+	return file.ReadString(ctx)
+}
+
+// compress compresses data with the given compression.
+func compress(t *testing.T, data []byte, comp compressionType) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	cw, err := newCompressionWriter(&buf, comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var codecs = []struct {
+	name string
+	comp compressionType
+	ext  string
+}{
+	{"gzip", compressionGzip, ".gz"},
+	{"zstd", compressionZstd, ".zst"},
+	{"bzip2", compressionBzip2, ".bz2"},
+	{"snappy", compressionSnappy, ".sz"},
+	{"deflate", compressionDeflate, ".deflate"},
+}
+
+func TestCompression_roundTrip(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			compressed := compress(t, []byte("test"), c.comp)
+			if bytes.Equal(compressed, []byte("test")) {
+				t.Fatalf("newCompressionWriter(%v) didn't compress", c.name)
+			}
+
+			dr, err := newDecompressionReader(io.NopCloser(bytes.NewReader(compressed)), c.comp)
+			if err != nil {
+				t.Fatalf("newDecompressionReader(%v) error = %v, want nil", c.name, err)
+			}
+			defer dr.Close()
+
+			if err := iotest.TestReader(dr, []byte("test")); err != nil {
+				t.Errorf("TestReader() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCompressionFromExt_codecs(t *testing.T) {
+	for _, c := range codecs {
+		if got := compressionFromExt("file" + c.ext); got != c.comp {
+			t.Errorf("compressionFromExt(%q) = %v, want %v", "file"+c.ext, got, c.comp)
+		}
+	}
+}
+
+func TestCompressionFromMagic(t *testing.T) {
+	for _, c := range codecs {
+		if c.comp == compressionDeflate {
+			continue
+		}
+		br := bufio.NewReader(bytes.NewReader(compress(t, []byte("test"), c.comp)))
+		if got := compressionFromMagic(br); got != c.comp {
+			t.Errorf("compressionFromMagic(%v data) = %v, want %v", c.name, got, c.comp)
+		}
+	}
+
+	for _, data := range []string{"", "BZh", "BZh9 is not bzip2", "plain text"} {
+		br := bufio.NewReader(bytes.NewReader([]byte(data)))
+		if got := compressionFromMagic(br); got != compressionUncompressed {
+			t.Errorf("compressionFromMagic(%q) = %v, want %v", data, got, compressionUncompressed)
+		}
+	}
+}
+
+func TestReadableFile_Open_magic(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range codecs {
+		if c.comp == compressionDeflate {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(dir, c.name)
+			write(t, path, compress(t, []byte("test"), c.comp))
+
+			file := ReadableFile{Metadata: FileMetadata{Path: path}, Compression: compressionAuto}
+			got, err := file.ReadString(context.Background())
+			if err != nil {
+				t.Fatalf("ReadString() error = %v, want nil", err)
+			}
+			if got != "test" {
+				t.Errorf("ReadString() = %q, want %q", got, "test")
+			}
+		})
+	}
+}
+
+func TestWriteFiles_compression(t *testing.T) {
+	writes := []struct {
+		name string
+		opt  WriteOptionFn
+		ext  string
+	}{
+		{"gzip", WriteGzip(), ".gz"},
+		{"zstd", WriteZstd(), ".zst"},
+		{"bzip2", WriteBzip2(), ".bz2"},
+		{"snappy", WriteSnappy(), ".sz"},
+		{"deflate", WriteDeflate(), ".deflate"},
+	}
+	for _, w := range writes {
+		t.Run(w.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "out"+w.ext)
+
+			p, s := beam.NewPipelineWithRoot()
+			WriteFiles(s, filename, &lineSink{}, beam.Create(s, "a"),
+				WriteNumShards(1), WriteShardTemplate(""), w.opt)
+			ptest.RunAndValidate(t, p)
+
+			if data, err := os.ReadFile(filename); err != nil || bytes.Equal(data, []byte("a\n")) {
+				t.Fatalf("WriteFiles() wrote %q, %v, want compressed data", data, err)
+			}
+
+			p, s = beam.NewPipelineWithRoot()
+			files := ReadMatches(s, MatchFiles(s, filename))
+			passert.Equals(s, beam.ParDo(s, readStringFn, files), "a\n")
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
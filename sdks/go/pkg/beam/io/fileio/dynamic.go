@@ -0,0 +1,106 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"fmt"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+)
+
+// Destinations determines where the elements of a PCollection are written by WriteDynamic. Every
+// element is assigned a destination, and the elements of each destination are written to their
+// own set of files, with their own prefix and Sink.
+//
+// As for Sink, WriteDynamic serializes the Destinations as JSON, so implementations must be
+// pointers to structs with exported, JSON-serializable fields holding their configuration. The
+// struct type must be registered with beam.RegisterType.
+type Destinations interface {
+	// Destination returns the destination of an element in the given window. The same element
+	// and window must always map to the same destination.
+	Destination(w typex.Window, elm any) string
+	// Prefix returns the filename prefix of the files of a destination.
+	Prefix(dest string) string
+	// Sink returns a new Sink for a file of a destination.
+	Sink(dest string) Sink
+}
+
+// WriteDynamic writes the elements of a PCollection<T> to files that depend on the elements
+// themselves, and returns a PCollection<string> with the names of the written files. For every
+// element, dests determines a destination, and the elements of a destination are written with
+// the prefix and Sink of that destination. For example, the following writes the lines of every
+// tenant and day to gs://bucket/{tenant}/{date}/part-*.csv, where lines are daily windowed CSV
+// records starting with the tenant:
+//
+//	type tenantDestinations struct{}
+//
+//	func (*tenantDestinations) Destination(w typex.Window, elm any) string {
+//		tenant, _, _ := strings.Cut(elm.(string), ",")
+//		start := w.(window.IntervalWindow).Start.ToTime().UTC()
+//		return tenant + "/" + start.Format("2006-01-02")
+//	}
+//
+//	func (*tenantDestinations) Prefix(dest string) string {
+//		return "gs://bucket/" + dest + "/part"
+//	}
+//
+//	func (*tenantDestinations) Sink(dest string) fileio.Sink {
+//		return textio.NewSink()
+//	}
+//
+//	fileio.WriteDynamic(s, &tenantDestinations{}, lines, fileio.WriteSuffix(".csv"))
+//
+// The options are the same as for WriteFiles, and apply to each destination. In particular,
+// WriteNumShards sets the number of files per destination, window and pane. Since a worker may
+// write the files of many destinations at once, WriteMaxOpenWriters can be used to bound the
+// resources used by the Sinks.
+//
+// The file system of a destination can't be validated at pipeline construction time, so
+// unregistered schemes are only reported when the files are written.
+func WriteDynamic(s beam.Scope, dests Destinations, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("fileio.WriteDynamic")
+
+	if dests == nil {
+		panic("fileio.WriteDynamic: destinations must not be nil")
+	}
+	return writeDynamic(s, dests, col, opts)
+}
+
+// singleDestination writes all elements with a single prefix and Sink. It is
+// used by WriteFiles.
+type singleDestination struct {
+	FilePrefix string           `json:"prefix"`
+	SinkType   beam.EncodedType `json:"sinkType"`
+	SinkConfig []byte           `json:"sinkConfig"`
+}
+
+func (d *singleDestination) Destination(_ typex.Window, _ any) string {
+	return ""
+}
+
+func (d *singleDestination) Prefix(_ string) string {
+	return d.FilePrefix
+}
+
+func (d *singleDestination) Sink(_ string) Sink {
+	v, err := decode(d.SinkType, d.SinkConfig)
+	if err != nil {
+		// The configuration was encoded from a valid Sink by WriteFiles.
+		panic(fmt.Sprintf("fileio.WriteFiles: %v", err))
+	}
+	return v.(Sink)
+}
@@ -0,0 +1,202 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*tenantDestinations)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*countingSink)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*flatDestinations)(nil)).Elem())
+	register.Function1x1(relFn)
+	register.Function1x1(tenantAFn)
+}
+
+// tenantDestinations writes elements of the form "tenant:value" to a
+// directory per tenant and window.
+type tenantDestinations struct {
+	Dir     string
+	Counted bool
+}
+
+func (d *tenantDestinations) Destination(w typex.Window, elm any) string {
+	tenant, _, _ := strings.Cut(elm.(string), ":")
+	if iw, ok := w.(window.IntervalWindow); ok {
+		return tenant + "/" + iw.Start.ToTime().UTC().Format("1504")
+	}
+	return tenant + "/all"
+}
+
+func (d *tenantDestinations) Prefix(dest string) string {
+	return d.Dir + "/" + dest + "/part"
+}
+
+func (d *tenantDestinations) Sink(_ string) Sink {
+	if d.Counted {
+		return &countingSink{}
+	}
+	return &lineSink{}
+}
+
+// flatDestinations writes elements of the form "tenant:value" to a file per
+// tenant, all in the same directory.
+type flatDestinations struct {
+	Dir string
+}
+
+func (d *flatDestinations) Destination(_ typex.Window, elm any) string {
+	tenant, _, _ := strings.Cut(elm.(string), ":")
+	return tenant
+}
+
+func (d *flatDestinations) Prefix(dest string) string {
+	return d.Dir + "/" + dest
+}
+
+func (d *flatDestinations) Sink(_ string) Sink {
+	return &lineSink{}
+}
+
+func tenantAFn(v int) string {
+	return fmt.Sprintf("a:%v", v)
+}
+
+// relFn returns the path relative to the temporary test directory.
+func relFn(path string) string {
+	dir := filepath.Dir(filepath.Dir(filepath.Dir(path)))
+	rel, _ := filepath.Rel(dir, path)
+	return filepath.ToSlash(rel)
+}
+
+func TestWriteDynamic(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, "a:1", "b:2", "a:3")
+	files := WriteDynamic(s, &tenantDestinations{Dir: dir}, col,
+		WriteNumShards(1), WriteSuffix(".txt"))
+
+	passert.Equals(s, beam.ParDo(s, relFn, files),
+		"a/all/part-00000-of-00001.txt", "b/all/part-00000-of-00001.txt")
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filepath.Join(dir, "a", "all", "*")), []string{"a:1", "a:3"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteDynamic() wrote %v to a, want %v", got, want)
+	}
+	if got, want := readLines(t, filepath.Join(dir, "b", "all", "*")), []string{"b:2"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteDynamic() wrote %v to b, want %v", got, want)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, "*", "*", ".temp-beam-*")); len(temps) != 0 {
+		t.Errorf("WriteDynamic() left temporary files %v", temps)
+	}
+}
+
+func TestWriteDynamic_sharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, "a:1", "b:2", "c:3", "a:4")
+	files := WriteDynamic(s, &flatDestinations{Dir: dir}, col,
+		WriteNumShards(1), WriteShardTemplate(""), WriteSuffix(".txt"))
+
+	passert.Equals(s, beam.ParDo(s, baseFn, files), "a.txt", "b.txt", "c.txt")
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filepath.Join(dir, "*.txt")), []string{"a:1", "a:4", "b:2", "c:3"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteDynamic() wrote %v, want %v", got, want)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".temp-beam-*")); len(temps) != 0 {
+		t.Errorf("WriteDynamic() left temporary files %v", temps)
+	}
+}
+
+func TestWriteDynamic_windowed(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 61))
+	lines := beam.ParDo(s, tenantAFn, col)
+	windowed := beam.WindowInto(s, window.NewFixedWindows(time.Minute), lines)
+	files := WriteDynamic(s, &tenantDestinations{Dir: dir}, windowed,
+		WriteNumShards(1), WriteShardTemplate(""))
+
+	passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindows(), beam.ParDo(s, relFn, files)),
+		"a/0000/part", "a/0001/part")
+	ptest.RunAndValidate(t, p)
+}
+
+// openSinks and maxOpenSinks track the number of concurrently open
+// countingSinks. The test pipelines run in process, so the counts are shared.
+var openSinks, maxOpenSinks atomic.Int64
+
+// countingSink is a lineSink that tracks the number of open sinks.
+type countingSink struct {
+	lineSink
+}
+
+func (s *countingSink) Open(ctx context.Context, w io.Writer) error {
+	n := openSinks.Add(1)
+	for {
+		max := maxOpenSinks.Load()
+		if n <= max || maxOpenSinks.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	// Keep the sink open for a while to give other writers a chance to run.
+	time.Sleep(10 * time.Millisecond)
+	return s.lineSink.Open(ctx, w)
+}
+
+func (s *countingSink) Flush(ctx context.Context) error {
+	openSinks.Add(-1)
+	return s.lineSink.Flush(ctx)
+}
+
+func TestWriteDynamic_maxOpenWriters(t *testing.T) {
+	dir := t.TempDir()
+	openSinks.Store(0)
+	maxOpenSinks.Store(0)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, "a:1", "b:2", "c:3", "d:4", "e:5")
+	files := WriteDynamic(s, &tenantDestinations{Dir: dir, Counted: true}, col,
+		WriteNumShards(2), WriteMaxOpenWriters(1))
+
+	passert.Count(s, files, "files", 10)
+	ptest.RunAndValidate(t, p)
+
+	if got := maxOpenSinks.Load(); got != 1 {
+		t.Errorf("WriteDynamic() had %v open writers at once, want 1", got)
+	}
+}
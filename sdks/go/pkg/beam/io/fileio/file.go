@@ -16,8 +16,10 @@
 package fileio
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
 	"reflect"
@@ -26,11 +28,13 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/fsx"
 )
 
 func init() {
 	beam.RegisterType(reflect.TypeOf((*FileMetadata)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*ReadableFile)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*ParseError)(nil)).Elem())
 }
 
 // FileMetadata contains metadata about a file, namely its path, size in bytes and last modified
@@ -41,6 +45,20 @@ type FileMetadata struct {
 	LastModified time.Time
 }
 
+// ParseError is a record of a file that couldn't be read, output to the
+// dead-letter PCollections of sources of records such as csvio and jsonio.
+type ParseError struct {
+	// File is the path of the file containing the record.
+	File string
+	// Offset is the position of the record in the file, in bytes after
+	// decompression.
+	Offset int64
+	// Record is the text of the record.
+	Record string
+	// Error describes why the record couldn't be read.
+	Error string
+}
+
 // compressionType is the type of compression used to compress a file.
 type compressionType int
 
@@ -51,6 +69,14 @@ const (
 	compressionGzip
 	// compressionUncompressed indicates that the file is not compressed.
 	compressionUncompressed
+	// compressionZstd indicates that the file is compressed using zstd.
+	compressionZstd
+	// compressionBzip2 indicates that the file is compressed using bzip2.
+	compressionBzip2
+	// compressionSnappy indicates that the file is compressed using the snappy framing format.
+	compressionSnappy
+	// compressionDeflate indicates that the file is compressed using deflate with a zlib header.
+	compressionDeflate
 )
 
 // ReadableFile is a wrapper around a FileMetadata and compressionType that can be used to obtain a
@@ -62,25 +88,103 @@ type ReadableFile struct {
 
 // Open opens the file for reading. The compression type is determined by the Compression field of
 // the ReadableFile. If Compression is compressionAuto, the compression type is auto-detected from
-// the file extension. It is the caller's responsibility to close the returned reader.
+// the file extension or, if the extension isn't recognized, from the first bytes of the file. It
+// is the caller's responsibility to close the returned reader.
 func (f ReadableFile) Open(ctx context.Context) (io.ReadCloser, error) {
+	rc, _, err := f.open(ctx)
+	return rc, err
+}
+
+// open opens the file for reading like Open, and also returns the compression type it resolved.
+func (f ReadableFile) open(ctx context.Context) (io.ReadCloser, compressionType, error) {
 	fs, err := filesystem.New(ctx, f.Metadata.Path)
 	if err != nil {
-		return nil, err
+		return nil, compressionAuto, err
 	}
 	defer fs.Close()
 
 	rc, err := fs.OpenRead(ctx, f.Metadata.Path)
+	if err != nil {
+		return nil, compressionAuto, err
+	}
+
+	comp := f.Compression
+	if comp == compressionAuto {
+		comp = compressionFromExt(f.Metadata.Path)
+	}
+	if comp == compressionUncompressed && f.Compression == compressionAuto {
+		br := bufio.NewReader(rc)
+		comp = compressionFromMagic(br)
+		rc = &bufferedReadCloser{Reader: br, rc: rc}
+	}
+
+	r, err := newDecompressionReader(rc, comp)
+	return r, comp, err
+}
+
+// OpenSeekable opens the file for reading like Open, and returns a reader that can seek, for
+// reading formats such as Parquet and Arrow that are read from their end. If the file system can't
+// seek in the file, or the file is decompressed, seeking makes the next read reopen the file and
+// skip to the offset. Seeking relative to the end of a decompressed file isn't supported. It is
+// the caller's responsibility to close the returned reader.
+func (f ReadableFile) OpenSeekable(ctx context.Context) (io.ReadSeekCloser, error) {
+	rc, comp, err := f.open(ctx)
 	if err != nil {
 		return nil, err
 	}
+	// Readers may implement io.Seeker even if the underlying reader can't seek.
+	if rs, ok := rc.(io.ReadSeekCloser); ok && comp == compressionUncompressed {
+		if _, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			return rs, nil
+		}
+	}
+	size := f.Metadata.Size
+	if comp != compressionUncompressed {
+		size = -1
+	}
+	return fsx.NewOpenedRangeReader(ctx, f.Metadata.Path, size, f.openRange, rc), nil
+}
 
+// openRange opens the file like Open, skipping to the given offset.
+func (f ReadableFile) openRange(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+	rc, comp, err := f.open(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	if _, err := io.CopyN(io.Discard, rc, offset); err != nil && err != io.EOF {
+		rc.Close()
+		return nil, -1, fmt.Errorf("error reading %v: %v", f.Metadata.Path, err)
+	}
+	if comp != compressionUncompressed {
+		return rc, -1, nil
+	}
+	return rc, f.Metadata.Size, nil
+}
+
+// IsCompressed reports whether the file is decompressed when it's opened, in which case offsets
+// in the data read don't correspond to offsets in the file, so that the file can't be split. If
+// Compression is compressionAuto and the extension isn't recognized, the file is opened to detect
+// the compression from its first bytes.
+func (f ReadableFile) IsCompressed(ctx context.Context) (bool, error) {
 	comp := f.Compression
 	if comp == compressionAuto {
 		comp = compressionFromExt(f.Metadata.Path)
 	}
+	if comp == compressionUncompressed && f.Compression == compressionAuto {
+		fs, err := filesystem.New(ctx, f.Metadata.Path)
+		if err != nil {
+			return false, err
+		}
+		defer fs.Close()
 
-	return newDecompressionReader(rc, comp)
+		rc, err := fs.OpenRead(ctx, f.Metadata.Path)
+		if err != nil {
+			return false, err
+		}
+		defer rc.Close()
+		comp = compressionFromMagic(bufio.NewReader(rc))
+	}
+	return comp != compressionUncompressed, nil
 }
 
 // compressionFromExt detects the compression of a file based on its extension. If the extension is
@@ -89,6 +193,14 @@ func compressionFromExt(path string) compressionType {
 	switch filepath.Ext(path) {
 	case ".gz":
 		return compressionGzip
+	case ".zst", ".zstd":
+		return compressionZstd
+	case ".bz2":
+		return compressionBzip2
+	case ".sz", ".snappy":
+		return compressionSnappy
+	case ".deflate", ".zz":
+		return compressionDeflate
 	default:
 		return compressionUncompressed
 	}
@@ -108,6 +220,14 @@ func newDecompressionReader(
 		)
 	case compressionGzip:
 		return newGzipReader(reader)
+	case compressionZstd:
+		return newZstdReader(reader)
+	case compressionBzip2:
+		return newBzip2Reader(reader), nil
+	case compressionSnappy:
+		return newSnappyReader(reader), nil
+	case compressionDeflate:
+		return newDeflateReader(reader)
 	default:
 		return reader, nil
 	}
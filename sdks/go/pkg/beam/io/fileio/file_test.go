@@ -18,9 +18,12 @@ package fileio
 import (
 	"bytes"
 	"context"
+	"io"
 	"path/filepath"
 	"testing"
 	"testing/iotest"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/memfs"
 )
 
 func TestReadableFile_Open(t *testing.T) {
@@ -252,3 +255,73 @@ func TestReadableFile_ReadString(t *testing.T) {
 		})
 	}
 }
+
+func TestReadableFile_IsCompressed(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "plain.txt"), []byte("test"))
+	writeGzip(t, filepath.Join(dir, "file.gz"), []byte("test"))
+	writeGzip(t, filepath.Join(dir, "gzip.txt"), []byte("test"))
+
+	tests := []struct {
+		file ReadableFile
+		want bool
+	}{
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "plain.txt")}}, false},
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "file.gz")}}, true},
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "gzip.txt")}}, true},
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "gzip.txt")}, Compression: compressionUncompressed}, false},
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "missing.txt")}, Compression: compressionZstd}, true},
+	}
+	for _, test := range tests {
+		got, err := test.file.IsCompressed(context.Background())
+		if err != nil {
+			t.Fatalf("IsCompressed(%v) error = %v, want nil", test.file.Metadata.Path, err)
+		}
+		if got != test.want {
+			t.Errorf("IsCompressed(%v, %v) = %v, want %v", test.file.Metadata.Path, test.file.Compression, got, test.want)
+		}
+	}
+}
+
+func TestReadableFile_OpenSeekable(t *testing.T) {
+	data := []byte("0123456789")
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "file.txt"), data)
+	writeGzip(t, filepath.Join(dir, "file.gz"), data)
+	memfs.Write("memfs://file.txt", data)
+
+	tests := []struct {
+		file   ReadableFile
+		seekTo int64
+		whence int
+		want   string
+	}{
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "file.txt"), Size: 10}}, -3, io.SeekEnd, "789"},
+		{ReadableFile{Metadata: FileMetadata{Path: "memfs://file.txt", Size: 10}}, -3, io.SeekEnd, "789"},
+		{ReadableFile{Metadata: FileMetadata{Path: "memfs://file.txt", Size: 10}}, 4, io.SeekStart, "456789"},
+		{ReadableFile{Metadata: FileMetadata{Path: filepath.Join(dir, "file.gz")}}, 4, io.SeekStart, "456789"},
+	}
+	for _, test := range tests {
+		rs, err := test.file.OpenSeekable(context.Background())
+		if err != nil {
+			t.Fatalf("OpenSeekable(%v) error = %v, want nil", test.file.Metadata.Path, err)
+		}
+		// Seeking relative to the end requires the size, which decompressed files lack.
+		if test.file.Metadata.Size > 0 {
+			if err := iotest.TestReader(rs, data); err != nil {
+				t.Errorf("OpenSeekable(%v) reader: %v", test.file.Metadata.Path, err)
+			}
+		}
+		if _, err := rs.Seek(test.seekTo, test.whence); err != nil {
+			t.Fatalf("Seek(%v, %v) on %v error = %v, want nil", test.seekTo, test.whence, test.file.Metadata.Path, err)
+		}
+		got, err := io.ReadAll(rs)
+		if err != nil {
+			t.Fatalf("ReadAll(%v) error = %v, want nil", test.file.Metadata.Path, err)
+		}
+		if string(got) != test.want {
+			t.Errorf("ReadAll(%v) after Seek(%v, %v) = %q, want %q", test.file.Metadata.Path, test.seekTo, test.whence, got, test.want)
+		}
+		rs.Close()
+	}
+}
@@ -13,7 +13,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package fileio provides transforms for matching and reading files.
+// Package fileio provides transforms for matching, reading and writing files.
 package fileio
 
 import (
@@ -29,12 +29,10 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
-	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/periodic"
 )
 
 func init() {
 	register.DoFn3x1[context.Context, string, func(FileMetadata), error](&matchFn{})
-	register.DoFn2x0[[]byte, func(string)](&matchContFn{})
 	register.DoFn4x1[state.Provider, string, FileMetadata, func(FileMetadata), error](
 		&dedupFn{},
 	)
@@ -42,7 +40,6 @@ func init() {
 		&dedupUnmodifiedFn{},
 	)
 	register.Emitter1[FileMetadata]()
-	register.Emitter1[string]()
 	register.Function1x2[FileMetadata, string, FileMetadata](keyByPath)
 }
 
@@ -241,6 +238,8 @@ type matchContOption struct {
 	Start              time.Time
 	End                time.Time
 	DuplicateTreatment duplicateTreatment
+	DuplicateTTL       time.Duration
+	MaxIdlePolls       int
 	ApplyWindow        bool
 }
 
@@ -284,6 +283,24 @@ func MatchDuplicateSkip() MatchContOptionFn {
 	}
 }
 
+// MatchDuplicateTTL specifies that observed files are forgotten once they haven't been matched for
+// the given duration of event time, so that the state of the deduplication, which is keyed by path,
+// stays bounded. Files matched again after being forgotten are emitted again. By default, observed
+// files are never forgotten.
+func MatchDuplicateTTL(ttl time.Duration) MatchContOptionFn {
+	return func(o *matchContOption) {
+		o.DuplicateTTL = ttl
+	}
+}
+
+// MatchStopAfterIdle specifies that matching stops after n consecutive polls that found no new,
+// modified or removed files.
+func MatchStopAfterIdle(n int) MatchContOptionFn {
+	return func(o *matchContOption) {
+		o.MaxIdlePolls = n
+	}
+}
+
 // MatchApplyWindow specifies that each element will be assigned to an individual window.
 func MatchApplyWindow() MatchContOptionFn {
 	return func(o *matchContOption) {
@@ -301,8 +318,18 @@ func MatchApplyWindow() MatchContOptionFn {
 //   - DuplicateAllowIfModified: allow emitting matches that have already been observed if the file
 //     has been modified since the last observation. Defaults to false
 //   - DuplicateSkip: skip emitting matches that have already been observed. Defaults to true
+//   - DuplicateTTL: forget observed matches after a duration without being matched. Defaults to
+//     never forgetting them
+//   - StopAfterIdle: stop after a number of consecutive polls without changes. Defaults to polling
+//     until the end time
 //   - ApplyWindow: assign each element to an individual window with a fixed size equivalent to the
 //     interval. Defaults to false, i.e. all elements will reside in the global window
+//
+// Each file is timestamped with its modification time, or the time of the poll that found it if
+// the file system doesn't provide modification times. The watermark advances to the latest
+// modification time found, or to the time of the poll if it found no file modified since, so files
+// that appear with a modification time earlier than the watermark are timestamped with the
+// watermark instead.
 func MatchContinuously(
 	s beam.Scope,
 	glob string,
@@ -324,11 +351,21 @@ func MatchContinuously(
 		opt(option)
 	}
 
-	imp := periodic.Impulse(s, option.Start, option.End, interval, false)
-	globs := beam.ParDo(s, &matchContFn{Glob: glob}, imp)
-	matches := MatchAll(s, globs, MatchEmptyAllow())
+	start := mtime.Normalize(mtime.FromTime(option.Start))
+	end := mtime.Normalize(mtime.FromTime(option.End))
+	if start > end {
+		start = end
+	}
 
-	out := dedupIfRequired(s, matches, option.DuplicateTreatment)
+	fn := &watchFn{
+		Glob:     glob,
+		Interval: interval,
+		Start:    int64(start),
+		End:      int64(end),
+		MaxIdle:  int64(option.MaxIdlePolls),
+	}
+	matches := beam.ParDo(s, fn, beam.Impulse(s))
+	out := dedupIfRequired(s, matches, option.DuplicateTreatment, option.DuplicateTTL)
 
 	if option.ApplyWindow {
 		return beam.WindowInto(s, window.NewFixedWindows(interval), out)
@@ -340,6 +377,7 @@ func dedupIfRequired(
 	s beam.Scope,
 	col beam.PCollection,
 	treatment duplicateTreatment,
+	ttl time.Duration,
 ) beam.PCollection {
 	if treatment == duplicateAllow {
 		return col
@@ -347,6 +385,10 @@ func dedupIfRequired(
 
 	keyed := beam.ParDo(s, keyByPath, col)
 
+	if ttl > 0 {
+		return beam.ParDo(s, newDedupTTLFn(ttl, treatment == duplicateAllowIfModified), keyed)
+	}
+
 	if treatment == duplicateAllowIfModified {
 		return beam.ParDo(s, &dedupUnmodifiedFn{}, keyed)
 	}
@@ -354,14 +396,6 @@ func dedupIfRequired(
 	return beam.ParDo(s, &dedupFn{}, keyed)
 }
 
-type matchContFn struct {
-	Glob string
-}
-
-func (fn *matchContFn) ProcessElement(_ []byte, emit func(string)) {
-	emit(fn.Glob)
-}
-
 func keyByPath(md FileMetadata) (string, FileMetadata) {
 	return md.Path, md
 }
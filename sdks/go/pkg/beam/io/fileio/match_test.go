@@ -19,11 +19,14 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/teststream"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -306,3 +309,131 @@ func Test_metadataFromFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchContinuously(t *testing.T) {
+	dir := t.TempDir()
+	for _, tf := range testFiles {
+		write(t, filepath.Join(dir, tf.filename), tf.data)
+	}
+
+	fp1 := filepath.Join(dir, "file1.txt")
+	fp2 := filepath.Join(dir, "file2.txt")
+	want := []any{
+		FileMetadata{Path: fp1, Size: 5, LastModified: modTime(t, fp1)},
+		FileMetadata{Path: fp2, Size: 0, LastModified: modTime(t, fp2)},
+	}
+
+	tests := []struct {
+		name string
+		opts []MatchContOptionFn
+		want []any
+	}{
+		{
+			name: "Skip duplicates",
+			want: want,
+		},
+		{
+			name: "Skip duplicates with TTL",
+			opts: []MatchContOptionFn{MatchDuplicateTTL(time.Hour)},
+			want: want,
+		},
+		{
+			name: "Allow duplicates",
+			opts: []MatchContOptionFn{MatchDuplicateAllow()},
+			// The first poll finds the files, and the two idle polls find them again.
+			want: append(append(append([]any{}, want...), want...), want...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, s := beam.NewPipelineWithRoot()
+
+			opts := append([]MatchContOptionFn{MatchStopAfterIdle(2)}, tt.opts...)
+			got := MatchContinuously(s, filepath.Join(dir, "*.txt"), 10*time.Millisecond, opts...)
+
+			passert.Equals(s, got, tt.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func init() {
+	register.Function1x1(unmodifiedFile)
+}
+
+// unmodifiedFile returns the metadata of a file that's never modified.
+func unmodifiedFile(path string) FileMetadata {
+	return FileMetadata{Path: path, Size: 1, LastModified: time.UnixMilli(0)}
+}
+
+func TestDedupTTLFn(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+
+	// With a TTL of a minute, a is found again before it expires, which
+	// postpones its expiry, while b is found again after it expires.
+	c := teststream.NewConfig()
+	c.AddElements(1000, "a", "b")
+	c.AdvanceWatermark(30000)
+	c.AddElements(30000, "a")
+	c.AdvanceWatermark(70000)
+	c.AddElements(70000, "a", "b")
+	c.AdvanceWatermarkToInfinity()
+
+	files := beam.ParDo(s, unmodifiedFile, teststream.Create(s, c))
+	keyed := beam.ParDo(s, keyByPath, files)
+	got := beam.ParDo(s, newDedupTTLFn(time.Minute, false), keyed)
+
+	passert.Equals(s, got, unmodifiedFile("a"), unmodifiedFile("b"), unmodifiedFile("b"))
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWatchFn_poll(t *testing.T) {
+	start := time.UnixMilli(1000000)
+	fn := &watchFn{Interval: time.Second, Start: start.UnixMilli(), End: start.Add(time.Hour).UnixMilli(), MaxIdle: 2}
+	a := FileMetadata{Path: "a", Size: 1, LastModified: start.Add(-time.Minute)}
+	b := FileMetadata{Path: "b", Size: 2, LastModified: start.Add(-time.Second)}
+	bModified := FileMetadata{Path: "b", Size: 3, LastModified: start.Add(1500 * time.Millisecond)}
+
+	polls := []struct {
+		files         []FileMetadata
+		wantIdle      int64
+		wantWatermark time.Time
+	}{
+		{files: []FileMetadata{a}, wantWatermark: a.LastModified},
+		{files: []FileMetadata{a, b}, wantWatermark: b.LastModified},
+		// Polls that find no file modified after the watermark advance it
+		// to the poll time.
+		{files: []FileMetadata{a, b}, wantIdle: 1, wantWatermark: start.Add(2 * time.Second)},
+		{files: []FileMetadata{a, bModified}, wantWatermark: start.Add(3 * time.Second)},
+		{files: []FileMetadata{a, bModified}, wantIdle: 1, wantWatermark: start.Add(4 * time.Second)},
+		{files: []FileMetadata{a, bModified}, wantIdle: 2, wantWatermark: start.Add(5 * time.Second)},
+	}
+
+	rest := fn.CreateInitialRestriction(nil)
+	rt := newWatchTracker(rest)
+	for i, poll := range polls {
+		next := fn.poll(rest, poll.files, fn.pollTime(rest.Next))
+		if !rt.TryClaim(next) {
+			t.Fatalf("poll %v: TryClaim() failed: %v", i, rt.GetError())
+		}
+		if next.Idle != poll.wantIdle {
+			t.Errorf("poll %v: idle polls = %v, want %v", i, next.Idle, poll.wantIdle)
+		}
+		if got := time.UnixMilli(next.Watermark); !got.Equal(poll.wantWatermark) {
+			t.Errorf("poll %v: watermark = %v, want %v", i, got, poll.wantWatermark)
+		}
+		// Checkpoint after each poll, as when resuming.
+		_, residual, err := rt.TrySplit(0)
+		if i == len(polls)-1 {
+			if residual != nil || !rt.IsDone() {
+				t.Errorf("poll %v: tracker isn't done after the idle polls", i)
+			}
+			break
+		}
+		if err != nil || residual == nil {
+			t.Fatalf("poll %v: TrySplit(0) = %v, %v, want a residual", i, residual, err)
+		}
+		rest = residual.(watchRestriction)
+		rt = newWatchTracker(rest)
+	}
+}
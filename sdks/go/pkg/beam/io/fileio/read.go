@@ -61,6 +61,34 @@ func ReadGzip() ReadOptionFn {
 	}
 }
 
+// ReadZstd specifies that files have been compressed using zstd.
+func ReadZstd() ReadOptionFn {
+	return func(o *readOption) {
+		o.Compression = compressionZstd
+	}
+}
+
+// ReadBzip2 specifies that files have been compressed using bzip2.
+func ReadBzip2() ReadOptionFn {
+	return func(o *readOption) {
+		o.Compression = compressionBzip2
+	}
+}
+
+// ReadSnappy specifies that files have been compressed using the snappy framing format.
+func ReadSnappy() ReadOptionFn {
+	return func(o *readOption) {
+		o.Compression = compressionSnappy
+	}
+}
+
+// ReadDeflate specifies that files have been compressed using deflate with a zlib header.
+func ReadDeflate() ReadOptionFn {
+	return func(o *readOption) {
+		o.Compression = compressionDeflate
+	}
+}
+
 // ReadUncompressed specifies that files have not been compressed.
 func ReadUncompressed() ReadOptionFn {
 	return func(o *readOption) {
@@ -0,0 +1,68 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// SeekTo moves r, a reader of a file positioned at its start, to the given
+// offset. It seeks r if it supports it, such as uncompressed files on most
+// file systems, and otherwise reads and discards the data before the offset.
+func SeekTo(r io.Reader, offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(offset, io.SeekStart); err == nil {
+			return nil
+		}
+	}
+	n, err := io.CopyN(io.Discard, r, offset)
+	if err == io.EOF {
+		return fmt.Errorf("offset %v lies outside the file, which is only %v bytes", offset, n)
+	}
+	return err
+}
+
+// SeekLine moves r, a reader of a file positioned at its start, to the first
+// line beginning at or after the given offset, which is found by reading from
+// the byte just before the offset until the next newline. It returns a
+// buffered reader positioned at that line and the offset of the line, or
+// io.EOF if no line begins at or after the offset.
+//
+// Sources that split files into ranges of offsets read the lines beginning
+// within their range, so that every line is read exactly once.
+func SeekLine(r io.Reader, offset int64) (*bufio.Reader, int64, error) {
+	if offset == 0 {
+		return bufio.NewReader(r), 0, nil
+	}
+	if err := SeekTo(r, offset-1); err != nil {
+		return nil, 0, err
+	}
+	br := bufio.NewReader(r)
+	line, err := br.ReadSlice('\n')
+	for err == bufio.ErrBufferFull {
+		offset += int64(len(line))
+		line, err = br.ReadSlice('\n')
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return br, offset - 1 + int64(len(line)), nil
+}
@@ -0,0 +1,62 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSeekLine(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	data := "a\nbb\n" + long + "\nccc"
+	tests := []struct {
+		offset   int64
+		want     int64
+		wantLine string
+	}{
+		{0, 0, "a\n"},
+		{1, 2, "bb\n"},
+		{2, 2, "bb\n"},
+		{3, 5, long + "\n"},
+		{6, 10006, "ccc"},
+	}
+	for _, test := range tests {
+		// Readers are tested both with and without support for seeking.
+		for _, r := range []io.Reader{strings.NewReader(data), io.MultiReader(strings.NewReader(data))} {
+			br, got, err := SeekLine(r, test.offset)
+			if err != nil {
+				t.Fatalf("SeekLine(%T, %v) error = %v, want nil", r, test.offset, err)
+			}
+			if got != test.want {
+				t.Errorf("SeekLine(%T, %v) = %v, want %v", r, test.offset, got, test.want)
+			}
+			if line, _ := br.ReadString('\n'); line != test.wantLine {
+				t.Errorf("SeekLine(%T, %v) read %.10q, want %.10q", r, test.offset, line, test.wantLine)
+			}
+		}
+	}
+
+	for _, offset := range []int64{10007, int64(len(data))} {
+		if _, _, err := SeekLine(strings.NewReader(data), offset); err != io.EOF {
+			t.Errorf("SeekLine(%v) error = %v, want io.EOF", offset, err)
+		}
+	}
+	if _, _, err := SeekLine(io.MultiReader(strings.NewReader(data)), int64(len(data))+2); err == nil || err == io.EOF {
+		t.Errorf("SeekLine() past the end error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,357 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn5x2[context.Context, *sdf.ManualWatermarkEstimator, *sdf.LockRTracker, []byte, func(beam.EventTime, FileMetadata), sdf.ProcessContinuation, error](&watchFn{})
+	register.DoFn6x1[beam.EventTime, state.Provider, timers.Provider, string, FileMetadata, func(FileMetadata), error](&dedupTTLFn{})
+	register.Emitter2[beam.EventTime, FileMetadata]()
+	beam.RegisterType(reflect.TypeOf((*watchRestriction)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*watchTracker)(nil)))
+}
+
+// watchRestriction is the restriction of watchFn: the polls from Next up to
+// End, along with a summary of what previous polls observed.
+type watchRestriction struct {
+	// Next is the index of the next poll and End the index after the last.
+	Next, End int64
+	// Idle is the number of consecutive polls that found no new or modified
+	// files.
+	Idle int64
+	// Fingerprint is a hash of the files found by the last poll.
+	Fingerprint uint64
+	// Watermark is the latest modification time of the files found so far,
+	// in milliseconds since the Unix epoch.
+	Watermark int64
+}
+
+// watchTracker tracks a watchRestriction. A claimed position is the
+// restriction remaining after the poll, which carries what it observed into
+// checkpoints. Only checkpoints are supported as splits.
+type watchTracker struct {
+	rest watchRestriction
+	// claimed is the restriction remaining after the last claimed poll.
+	claimed watchRestriction
+	stopped bool
+	err     error
+}
+
+func newWatchTracker(rest watchRestriction) *watchTracker {
+	return &watchTracker{rest: rest, claimed: rest}
+}
+
+// TryClaim claims the poll before pos.Next, where pos is the restriction
+// remaining after the poll. Setting pos.End to pos.Next stops the tracker
+// after the poll.
+func (t *watchTracker) TryClaim(rawPos any) bool {
+	if t.stopped {
+		return false
+	}
+	pos, ok := rawPos.(watchRestriction)
+	if !ok {
+		t.err = fmt.Errorf("invalid position type: %T", rawPos)
+		t.stopped = true
+		return false
+	}
+	if pos.Next <= t.claimed.Next {
+		t.err = fmt.Errorf("poll %v was already claimed", pos.Next-1)
+		t.stopped = true
+		return false
+	}
+	if pos.Next > t.rest.End {
+		t.stopped = true
+		return false
+	}
+	pos.End = min(pos.End, t.rest.End)
+	t.claimed = pos
+	t.rest.End = pos.End
+	return true
+}
+
+func (t *watchTracker) GetError() error {
+	return t.err
+}
+
+// TrySplit only splits at fraction 0, checkpointing the remaining polls.
+func (t *watchTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	if fraction < 0 || fraction > 1 {
+		return nil, nil, errors.New("fraction must be between 0 and 1")
+	}
+	if t.stopped || fraction > 0 || t.IsDone() {
+		return t.rest, nil, nil
+	}
+	residual = t.claimed
+	t.rest.End = t.claimed.Next
+	t.stopped = true
+	return t.rest, residual, nil
+}
+
+func (t *watchTracker) GetProgress() (done, remaining float64) {
+	return float64(t.claimed.Next - t.rest.Next), float64(t.rest.End - t.claimed.Next)
+}
+
+func (t *watchTracker) IsDone() bool {
+	return t.stopped || t.claimed.Next >= t.rest.End
+}
+
+func (t *watchTracker) GetRestriction() any {
+	return t.rest
+}
+
+func (t *watchTracker) IsBounded() bool {
+	return t.IsDone()
+}
+
+// watchFn is an SDF that lists the files matching a glob at an interval and
+// emits all files found by each poll, timestamped with their modification
+// time. Files found by earlier polls are deduplicated downstream, in state
+// keyed by path.
+type watchFn struct {
+	Glob     string
+	Interval time.Duration
+	// Start is the time of the first poll, in milliseconds since the Unix
+	// epoch.
+	Start int64
+	// End is the time after which there are no polls, in milliseconds since
+	// the Unix epoch.
+	End int64
+	// MaxIdle is the number of consecutive idle polls after which polling
+	// stops, if positive.
+	MaxIdle int64
+}
+
+func (fn *watchFn) pollTime(i int64) time.Time {
+	return mtime.Time(fn.Start).ToTime().Add(time.Duration(i) * fn.Interval)
+}
+
+func (fn *watchFn) CreateInitialRestriction(_ []byte) watchRestriction {
+	end := int64(1)
+	if fn.Interval > 0 {
+		end = int64(mtime.Time(fn.End).ToTime().Sub(mtime.Time(fn.Start).ToTime())/fn.Interval) + 1
+	}
+	return watchRestriction{Next: 0, End: end}
+}
+
+func (fn *watchFn) SplitRestriction(_ []byte, rest watchRestriction) []watchRestriction {
+	return []watchRestriction{rest}
+}
+
+// RestrictionSize returns the number of polls that are due.
+func (fn *watchFn) RestrictionSize(_ []byte, rest watchRestriction) float64 {
+	if fn.Interval <= 0 {
+		return float64(rest.End - rest.Next)
+	}
+	due := int64(time.Since(fn.pollTime(rest.Next))/fn.Interval) + 1
+	return float64(max(0, min(due, rest.End-rest.Next)))
+}
+
+func (fn *watchFn) CreateTracker(rest watchRestriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(newWatchTracker(rest))
+}
+
+// TruncateRestriction immediately truncates the entire restriction.
+func (fn *watchFn) TruncateRestriction(_ *sdf.LockRTracker, _ []byte) watchRestriction {
+	return watchRestriction{}
+}
+
+func (fn *watchFn) CreateWatermarkEstimator() *sdf.ManualWatermarkEstimator {
+	return &sdf.ManualWatermarkEstimator{}
+}
+
+// ProcessElement polls the file system once the next poll is due, and
+// resumes when the one after is due.
+func (fn *watchFn) ProcessElement(
+	ctx context.Context,
+	we *sdf.ManualWatermarkEstimator,
+	rt *sdf.LockRTracker,
+	_ []byte,
+	emit func(beam.EventTime, FileMetadata),
+) (sdf.ProcessContinuation, error) {
+	rest := rt.GetRestriction().(watchRestriction)
+	if rest.Watermark > 0 {
+		we.UpdateWatermark(mtime.Time(rest.Watermark).ToTime())
+	}
+	if rest.Next >= rest.End {
+		return sdf.StopProcessing(), nil
+	}
+	pollTime := fn.pollTime(rest.Next)
+	if wait := time.Until(pollTime); wait > 0 {
+		return sdf.ResumeProcessingIn(wait), nil
+	}
+
+	files, err := fn.list(ctx)
+	if err != nil {
+		return sdf.StopProcessing(), err
+	}
+	next := fn.poll(rest, files, pollTime)
+	if !rt.TryClaim(next) {
+		return sdf.StopProcessing(), rt.GetError()
+	}
+	for _, md := range files {
+		emit(mtime.FromTime(timestamp(md, pollTime, rest.Watermark)), md)
+	}
+	we.UpdateWatermark(mtime.Time(next.Watermark).ToTime())
+
+	if next.Next >= next.End {
+		return sdf.StopProcessing(), nil
+	}
+	return sdf.ResumeProcessingIn(time.Until(fn.pollTime(next.Next))), nil
+}
+
+func (fn *watchFn) list(ctx context.Context) ([]FileMetadata, error) {
+	fs, err := filesystem.New(ctx, fn.Glob)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, fn.Glob)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return metadataFromFiles(ctx, fs, files)
+}
+
+// poll returns the restriction remaining after a poll that found the given
+// files.
+func (fn *watchFn) poll(rest watchRestriction, files []FileMetadata, pollTime time.Time) watchRestriction {
+	next := rest
+	next.Next++
+
+	h := fnv.New64a()
+	for _, md := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", md.Path, md.Size, md.LastModified.UnixMilli())
+		next.Watermark = max(next.Watermark, effectiveModTime(md, pollTime))
+	}
+	if next.Watermark <= rest.Watermark {
+		// No file was modified after the watermark, so it advances to the
+		// poll time. Files found later with an earlier modification time are
+		// timestamped with the watermark, so they aren't late.
+		next.Watermark = pollTime.UnixMilli()
+	}
+	// The watermark doesn't pass the poll time, in case of clock skew.
+	next.Watermark = min(next.Watermark, pollTime.UnixMilli())
+
+	fingerprint := h.Sum64()
+	if rest.Next > 0 && fingerprint == rest.Fingerprint {
+		next.Idle++
+	} else {
+		next.Idle = 0
+	}
+	next.Fingerprint = fingerprint
+	if fn.MaxIdle > 0 && next.Idle >= fn.MaxIdle {
+		next.End = next.Next
+	}
+	return next
+}
+
+// effectiveModTime returns the modification time of a file in milliseconds since the
+// Unix epoch, or the poll time if the file system doesn't provide it.
+func effectiveModTime(md FileMetadata, pollTime time.Time) int64 {
+	if md.LastModified.IsZero() {
+		return pollTime.UnixMilli()
+	}
+	return md.LastModified.UnixMilli()
+}
+
+// timestamp returns the event time of a file: its modification time, but no
+// earlier than the watermark before the poll, so that it isn't late.
+func timestamp(md FileMetadata, pollTime time.Time, watermark int64) time.Time {
+	return time.UnixMilli(max(effectiveModTime(md, pollTime), watermark))
+}
+
+// dedupTTLFn deduplicates files keyed by path, forgetting those that haven't
+// been found for TTL in event time, so that its state stays bounded.
+type dedupTTLFn struct {
+	TTL        time.Duration
+	IfModified bool
+
+	State  state.Value[int64]
+	Expiry timers.EventTime
+}
+
+func newDedupTTLFn(ttl time.Duration, ifModified bool) *dedupTTLFn {
+	return &dedupTTLFn{
+		TTL:        ttl,
+		IfModified: ifModified,
+		State:      state.MakeValueState[int64]("lastModified"),
+		Expiry:     timers.InEventTime("expiry"),
+	}
+}
+
+func (fn *dedupTTLFn) ProcessElement(
+	ts beam.EventTime,
+	sp state.Provider,
+	tp timers.Provider,
+	_ string,
+	md FileMetadata,
+	emit func(FileMetadata),
+) error {
+	prevMTime, ok, err := fn.State.Read(sp)
+	if err != nil {
+		return fmt.Errorf("error reading state: %v", err)
+	}
+
+	mTime := md.LastModified.UnixMilli()
+
+	if !ok || (fn.IfModified && mTime > prevMTime) {
+		emit(md)
+	}
+	if !ok || mTime != prevMTime {
+		if err := fn.State.Write(sp, mTime); err != nil {
+			return fmt.Errorf("error writing state: %v", err)
+		}
+	}
+	// Each observation postpones the expiry.
+	fn.Expiry.Set(tp, ts.ToTime().Add(fn.TTL))
+
+	return nil
+}
+
+func (fn *dedupTTLFn) OnTimer(
+	sp state.Provider,
+	_ timers.Provider,
+	_ string,
+	timer timers.Context,
+	_ func(FileMetadata),
+) error {
+	if timer.Family != fn.Expiry.Family {
+		return nil
+	}
+	if err := fn.State.Clear(sp); err != nil {
+		return fmt.Errorf("error clearing state: %v", err)
+	}
+	return nil
+}
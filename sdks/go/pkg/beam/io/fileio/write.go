@@ -0,0 +1,725 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/google/uuid"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*tempFile)(nil)).Elem())
+
+	beam.RegisterType(reflect.TypeOf((*shardKey)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*singleDestination)(nil)).Elem())
+
+	register.DoFn2x2[typex.Window, beam.X, shardKey, beam.X](&assignShardFn{})
+	register.DoFn5x1[context.Context, typex.Window, shardKey, func(*beam.X) bool, func(tempFile), error](&writeTempFn{})
+	register.DoFn6x1[context.Context, typex.PaneInfo, typex.Window, int, func(*tempFile) bool, func(string), error](
+		&finalizeFn{},
+	)
+	register.Emitter1[tempFile]()
+	register.Iter1[beam.X]()
+	register.Iter1[tempFile]()
+}
+
+// Sink writes the elements of a PCollection to a single file in a particular
+// format. A new Sink is used for every file.
+//
+// WriteFiles serializes the Sink as JSON, so implementations must be
+// pointers to structs with exported, JSON-serializable fields holding the
+// configuration of the sink. The struct type must be registered with
+// beam.RegisterType.
+type Sink interface {
+	// Open prepares the sink for writing to w. It is called once per file,
+	// before any elements are written.
+	Open(ctx context.Context, w io.Writer) error
+	// Write writes a single element to the file.
+	Write(ctx context.Context, elm any) error
+	// Flush writes any buffered data and footers to the file. It is called
+	// once per file, after all elements have been written. The underlying
+	// writer is closed by WriteFiles.
+	Flush(ctx context.Context) error
+}
+
+const (
+	// DefaultShardTemplate is the shard template used for files in the
+	// global window, if it only has a single pane.
+	DefaultShardTemplate = "-SSSSS-of-NNNNN"
+	// DefaultTriggeredShardTemplate is the shard template used for files in
+	// the global window, if it's triggered to have several panes.
+	DefaultTriggeredShardTemplate = "-P-SSSSS-of-NNNNN"
+	// DefaultWindowedShardTemplate is the shard template used for files in
+	// any other window.
+	DefaultWindowedShardTemplate = "-W-P-SSSSS-of-NNNNN"
+)
+
+type writeOption struct {
+	NumShards      int
+	ShardTemplate  *string
+	Suffix         string
+	TempDirectory  string
+	MaxOpenWriters int
+	Compression    compressionType
+}
+
+// WriteOptionFn is a function that can be passed to WriteFiles to configure options for writing
+// files.
+type WriteOptionFn func(*writeOption)
+
+// WriteNumShards specifies the number of files written per window and pane and destination. Shards
+// that receive no elements are written as empty files. By default, or if n is 0, the number of
+// files is determined by the runner, which writes one file per bundle.
+func WriteNumShards(n int) WriteOptionFn {
+	if n < 0 {
+		panic(fmt.Sprintf("WriteNumShards: number of shards must be non-negative, got %v", n))
+	}
+	return func(o *writeOption) {
+		o.NumShards = n
+	}
+}
+
+// WriteShardTemplate specifies the template that is inserted between the prefix and suffix of the
+// filenames. In the template, a run of S characters is replaced by the zero-padded shard index, a
+// run of N characters by the zero-padded number of shards, a run of W characters by the window
+// and a run of P characters by the pane of the file. By default, DefaultShardTemplate is used for
+// the global window, DefaultTriggeredShardTemplate for the panes of a triggered global window and
+// DefaultWindowedShardTemplate for other windows. Custom templates for triggered input should
+// include the pane, since files of different panes are otherwise given the same names.
+//
+// An empty template can be used together with WriteNumShards(1) to write a single file named
+// prefix+suffix.
+func WriteShardTemplate(template string) WriteOptionFn {
+	return func(o *writeOption) {
+		o.ShardTemplate = &template
+	}
+}
+
+// WriteSuffix specifies the suffix of the filenames, such as a file extension.
+func WriteSuffix(suffix string) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Suffix = suffix
+	}
+}
+
+// WriteTempDirectory specifies the directory in which files are written before they are renamed
+// to their final names. It must be on the same file system as the output. By default, a hidden
+// directory next to the output is used for every window, which is removed once the last pane of
+// the window has been written. A custom directory is left in place.
+func WriteTempDirectory(dir string) WriteOptionFn {
+	return func(o *writeOption) {
+		o.TempDirectory = dir
+	}
+}
+
+// WriteUncompressed specifies that files are written without compression. This is the default.
+func WriteUncompressed() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionUncompressed
+	}
+}
+
+// WriteGzip specifies that files are compressed using gzip.
+//
+// As for the other compression options, the filenames are not changed, so WriteSuffix should be
+// used to add a matching extension such as ".gz", which lets reads detect the compression.
+func WriteGzip() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionGzip
+	}
+}
+
+// WriteZstd specifies that files are compressed using zstd.
+func WriteZstd() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionZstd
+	}
+}
+
+// WriteBzip2 specifies that files are compressed using bzip2.
+func WriteBzip2() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionBzip2
+	}
+}
+
+// WriteSnappy specifies that files are compressed using the snappy framing format.
+func WriteSnappy() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionSnappy
+	}
+}
+
+// WriteDeflate specifies that files are compressed using deflate with a zlib header.
+func WriteDeflate() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionDeflate
+	}
+}
+
+// WriteMaxOpenWriters specifies the maximum number of files that are written concurrently by
+// each worker. By default, the number of files is only limited by the parallelism of the worker.
+func WriteMaxOpenWriters(n int) WriteOptionFn {
+	if n < 0 {
+		panic(fmt.Sprintf("WriteMaxOpenWriters: number of writers must be non-negative, got %v", n))
+	}
+	return func(o *writeOption) {
+		o.MaxOpenWriters = n
+	}
+}
+
+// WriteFiles writes the elements of a PCollection<T> to files using the given Sink and returns a
+// PCollection<string> with the names of the written files. The files are named prefix, followed
+// by the shard template and suffix. For example:
+//
+//	files := fileio.WriteFiles(s, "gs://bucket/out/part", textio.NewSink(), lines,
+//		fileio.WriteNumShards(10), fileio.WriteSuffix(".txt"))
+//
+// writes gs://bucket/out/part-00000-of-00010.txt through gs://bucket/out/part-00009-of-00010.txt.
+//
+// The files of each window and pane are written independently, so WriteFiles can be used on
+// unbounded input. Every file is first written to a temporary location, and the files of a
+// window and pane are only renamed to their final names once all of them have been written. The
+// filenames are output in the window of the files, once they have been renamed.
+//
+// No files are written for a window without elements.
+func WriteFiles(s beam.Scope, prefix string, sink Sink, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("fileio.WriteFiles")
+
+	filesystem.ValidateScheme(prefix)
+	sinkType, sinkConfig, err := encode(sink)
+	if err != nil {
+		panic(fmt.Sprintf("fileio.WriteFiles: invalid sink: %v", err))
+	}
+	return writeDynamic(s, &singleDestination{FilePrefix: prefix, SinkType: sinkType, SinkConfig: sinkConfig}, col, opts)
+}
+
+// writeDynamic writes the elements of col to the files of their destinations.
+func writeDynamic(s beam.Scope, dests Destinations, col beam.PCollection, opts []WriteOptionFn) beam.PCollection {
+	beam.ValidateNonCompositeType(col)
+
+	option := &writeOption{Compression: compressionUncompressed}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	destsType, destsConfig, err := encode(dests)
+	if err != nil {
+		panic(fmt.Sprintf("fileio.WriteDynamic: invalid destinations: %v", err))
+	}
+	cfg := writeConfig{
+		DestinationsType:   destsType,
+		DestinationsConfig: destsConfig,
+		WriteID:            uuid.NewString(),
+		TempDirectory:      option.TempDirectory,
+	}
+
+	keyed := beam.ParDo(s, &assignShardFn{writeConfig: cfg, NumShards: option.NumShards}, col)
+	grouped := beam.GroupByKey(s, keyed)
+	temps := beam.ParDo(s, &writeTempFn{
+		writeConfig:    cfg,
+		MaxOpenWriters: option.MaxOpenWriters,
+		Compression:    option.Compression,
+	}, grouped)
+
+	all := beam.GroupByKey(s, beam.AddFixedKey(s, temps))
+	return beam.ParDo(s, &finalizeFn{
+		writeConfig:   cfg,
+		Suffix:        option.Suffix,
+		ShardTemplate: option.ShardTemplate,
+		NumShards:     option.NumShards,
+		Compression:   option.Compression,
+	}, all)
+}
+
+// dirOf returns the directory part of a path, using / as the separator for
+// all file systems.
+func dirOf(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return "."
+}
+
+// encode returns the type and JSON configuration of a Sink or Destinations.
+func encode(v any) (beam.EncodedType, []byte, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return beam.EncodedType{}, nil, fmt.Errorf("must be a pointer to a struct, got %v", t)
+	}
+	config, err := json.Marshal(v)
+	if err != nil {
+		return beam.EncodedType{}, nil, fmt.Errorf("error encoding %v: %v", t, err)
+	}
+	return beam.EncodedType{T: t.Elem()}, config, nil
+}
+
+// decode returns a new pointer to a value of the given type with the given
+// JSON configuration.
+func decode(t beam.EncodedType, config []byte) (any, error) {
+	v := reflect.New(t.T).Interface()
+	if err := json.Unmarshal(config, v); err != nil {
+		return nil, fmt.Errorf("error decoding %v: %v", t.T, err)
+	}
+	return v, nil
+}
+
+// writeConfig holds the configuration shared by the DoFns of a write.
+type writeConfig struct {
+	DestinationsType   beam.EncodedType `json:"destinationsType"`
+	DestinationsConfig []byte           `json:"destinationsConfig"`
+	WriteID            string           `json:"writeID"`
+	TempDirectory      string           `json:"tempDirectory"`
+
+	dests Destinations
+}
+
+func (c *writeConfig) Setup() error {
+	v, err := decode(c.DestinationsType, c.DestinationsConfig)
+	if err != nil {
+		return err
+	}
+	dests, ok := v.(Destinations)
+	if !ok {
+		return fmt.Errorf("type %v doesn't implement fileio.Destinations", c.DestinationsType.T)
+	}
+	c.dests = dests
+	return nil
+}
+
+// tempDirectory returns the temporary directory for the files of a
+// destination in a window. It must be on the same file system as the final
+// files. Every destination and window has its own default directory, even if
+// it shares its parent directory with others, so that removing it after
+// finalizing the last pane of the window doesn't remove the temporary files
+// of the others.
+func (c *writeConfig) tempDirectory(w typex.Window, dest string) string {
+	if c.TempDirectory != "" {
+		return c.TempDirectory
+	}
+	h := fnv.New64a()
+	h.Write([]byte(dest))
+	h.Write([]byte{0})
+	h.Write([]byte(windowName(w)))
+	return fmt.Sprintf("%v/.temp-beam-%v-%016x", dirOf(c.dests.Prefix(dest)), c.WriteID, h.Sum64())
+}
+
+// shardKey identifies the elements written to a single file.
+type shardKey struct {
+	Destination string
+	Shard       int
+}
+
+// tempFile is a file written to the temporary directory by writeTempFn.
+type tempFile struct {
+	Destination string
+	Shard       int
+	Path        string
+}
+
+// assignShardFn keys every element with its destination and shard. With a
+// fixed number of shards, the elements of each destination are assigned
+// round-robin starting from a random shard in every bundle. Otherwise, all
+// elements of a destination in a bundle share a random shard, so that one
+// file is written per destination and bundle.
+type assignShardFn struct {
+	writeConfig
+	NumShards int `json:"numShards"`
+
+	next map[string]int
+}
+
+func (fn *assignShardFn) StartBundle() {
+	fn.next = make(map[string]int)
+}
+
+func (fn *assignShardFn) ProcessElement(w typex.Window, elm beam.X) (shardKey, beam.X) {
+	dest := fn.dests.Destination(w, elm)
+	shard, ok := fn.next[dest]
+	if !ok {
+		if fn.NumShards > 0 {
+			shard = rand.Intn(fn.NumShards)
+		} else {
+			shard = rand.Int()
+		}
+	}
+	if fn.NumShards > 0 {
+		fn.next[dest] = (shard + 1) % fn.NumShards
+	} else {
+		fn.next[dest] = shard
+	}
+	return shardKey{Destination: dest, Shard: shard}, elm
+}
+
+// openWriters limits the number of files written concurrently in this
+// process. It maps the ID of a write to its limit, which is removed once the
+// last writeTempFn of the write is torn down.
+var (
+	openWritersMu sync.Mutex
+	openWriters   = make(map[string]*writerLimit)
+)
+
+// writerLimit is a semaphore shared by the writeTempFns of a write that are
+// set up in this process.
+type writerLimit struct {
+	sem  chan struct{}
+	refs int
+}
+
+// writeTempFn writes the elements of a shard to a uniquely named file in the
+// temporary directory of its destination.
+type writeTempFn struct {
+	writeConfig
+	MaxOpenWriters int             `json:"maxOpenWriters"`
+	Compression    compressionType `json:"compression"`
+
+	sem chan struct{}
+}
+
+func (fn *writeTempFn) Setup() error {
+	if err := fn.writeConfig.Setup(); err != nil {
+		return err
+	}
+	if fn.MaxOpenWriters > 0 {
+		fn.sem = acquireWriterLimit(fn.WriteID, fn.MaxOpenWriters)
+	}
+	return nil
+}
+
+func (fn *writeTempFn) Teardown() {
+	if fn.sem != nil {
+		releaseWriterLimit(fn.WriteID)
+		fn.sem = nil
+	}
+}
+
+// acquireWriterLimit returns the semaphore of a write, creating it with n
+// slots if it doesn't exist yet. Each call must be paired with a call to
+// releaseWriterLimit.
+func acquireWriterLimit(writeID string, n int) chan struct{} {
+	openWritersMu.Lock()
+	defer openWritersMu.Unlock()
+	limit, ok := openWriters[writeID]
+	if !ok {
+		limit = &writerLimit{sem: make(chan struct{}, n)}
+		openWriters[writeID] = limit
+	}
+	limit.refs++
+	return limit.sem
+}
+
+// releaseWriterLimit releases the semaphore of a write, removing it once it
+// has been released as many times as it was acquired.
+func releaseWriterLimit(writeID string) {
+	openWritersMu.Lock()
+	defer openWritersMu.Unlock()
+	limit, ok := openWriters[writeID]
+	if !ok {
+		return
+	}
+	if limit.refs--; limit.refs == 0 {
+		delete(openWriters, writeID)
+	}
+}
+
+func (fn *writeTempFn) ProcessElement(
+	ctx context.Context,
+	w typex.Window,
+	key shardKey,
+	iter func(*beam.X) bool,
+	emit func(tempFile),
+) error {
+	if fn.sem != nil {
+		select {
+		case fn.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-fn.sem }()
+	}
+
+	path := fn.tempDirectory(w, key.Destination) + "/" + uuid.NewString()
+	if err := writeFile(ctx, path, fn.dests.Sink(key.Destination), fn.Compression, iter); err != nil {
+		return err
+	}
+
+	emit(tempFile{Destination: key.Destination, Shard: key.Shard, Path: path})
+	return nil
+}
+
+// writeFile writes all elements of iter to the file at path using sink and
+// the given compression.
+func writeFile(ctx context.Context, path string, sink Sink, compression compressionType, iter func(*beam.X) bool) error {
+	fs, err := filesystem.New(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	fd, err := fs.OpenWrite(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := writeElements(ctx, fd, sink, compression, iter); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// writeElements writes the elements to w with the sink, compressed with the
+// compression type. It doesn't close w.
+func writeElements(ctx context.Context, w io.Writer, sink Sink, compression compressionType, iter func(*beam.X) bool) error {
+	cw, err := newCompressionWriter(w, compression)
+	if err != nil {
+		return err
+	}
+	if err := sink.Open(ctx, cw); err != nil {
+		return err
+	}
+	var elm beam.X
+	for iter(&elm) {
+		if err := sink.Write(ctx, elm); err != nil {
+			return err
+		}
+	}
+	if err := sink.Flush(ctx); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// finalizeFn renames the temporary files of a window and pane to their final
+// names and emits the final names. With a fixed number of shards, it writes
+// empty files for the shards of a destination that received no elements.
+type finalizeFn struct {
+	writeConfig
+	Suffix        string          `json:"suffix"`
+	ShardTemplate *string         `json:"shardTemplate"`
+	NumShards     int             `json:"numShards"`
+	Compression   compressionType `json:"compression"`
+}
+
+func (fn *finalizeFn) ProcessElement(
+	ctx context.Context,
+	pane typex.PaneInfo,
+	w typex.Window,
+	_ int,
+	iter func(*tempFile) bool,
+	emit func(string),
+) error {
+	byDest := make(map[string][]tempFile)
+	var temp tempFile
+	for iter(&temp) {
+		byDest[temp.Destination] = append(byDest[temp.Destination], temp)
+	}
+
+	var names []string
+	for dest, temps := range byDest {
+		destNames, err := fn.finalize(ctx, pane, w, dest, temps)
+		if err != nil {
+			return err
+		}
+		names = append(names, destNames...)
+	}
+
+	for _, name := range names {
+		emit(name)
+	}
+	return nil
+}
+
+// finalize renames the temporary files of a single destination and returns
+// their final names.
+func (fn *finalizeFn) finalize(ctx context.Context, pane typex.PaneInfo, w typex.Window, dest string, temps []tempFile) ([]string, error) {
+	temps, err := fn.addEmptyShards(ctx, w, dest, temps)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(temps, func(i, j int) bool {
+		if temps[i].Shard != temps[j].Shard {
+			return temps[i].Shard < temps[j].Shard
+		}
+		return temps[i].Path < temps[j].Path
+	})
+
+	tempDir := fn.tempDirectory(w, dest)
+	fs, err := filesystem.New(ctx, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	prefix := fn.dests.Prefix(dest)
+	numShards := fn.NumShards
+	if numShards == 0 {
+		numShards = len(temps)
+	}
+	names := make([]string, len(temps))
+	for i, temp := range temps {
+		index := i
+		if fn.NumShards > 0 {
+			index = temp.Shard
+		}
+		names[i] = prefix + fn.shardName(w, pane, index, numShards) + fn.Suffix
+		if err := commit(ctx, fs, temp.Path, names[i]); err != nil {
+			return nil, err
+		}
+	}
+	log.Infof(ctx, "Wrote %v files to %v*%v", len(names), prefix, fn.Suffix)
+
+	if pane.IsLast && fn.TempDirectory == "" {
+		cleanup(ctx, fs, tempDir)
+	}
+	return names, nil
+}
+
+// addEmptyShards writes an empty temporary file for every shard of the
+// destination without a temporary file, so that all NumShards files exist.
+func (fn *finalizeFn) addEmptyShards(ctx context.Context, w typex.Window, dest string, temps []tempFile) ([]tempFile, error) {
+	if fn.NumShards == 0 {
+		return temps, nil
+	}
+	written := make(map[int]bool)
+	for _, temp := range temps {
+		written[temp.Shard] = true
+	}
+	for shard := 0; shard < fn.NumShards; shard++ {
+		if written[shard] {
+			continue
+		}
+		path := fn.tempDirectory(w, dest) + "/" + uuid.NewString()
+		empty := func(*beam.X) bool { return false }
+		if err := writeFile(ctx, path, fn.dests.Sink(dest), fn.Compression, empty); err != nil {
+			return nil, err
+		}
+		temps = append(temps, tempFile{Destination: dest, Shard: shard, Path: path})
+	}
+	return temps, nil
+}
+
+// commit renames a temporary file to its final name. If the temporary file no
+// longer exists but the final file does, the file was already committed by a
+// previous attempt.
+func commit(ctx context.Context, fs filesystem.Interface, temp, final string) error {
+	err := filesystem.Rename(ctx, fs, temp, final)
+	if err == nil {
+		return nil
+	}
+	if matches, listErr := fs.List(ctx, temp); listErr == nil && len(matches) == 0 {
+		if _, sizeErr := fs.Size(ctx, final); sizeErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("error renaming %q to %q: %v", temp, final, err)
+}
+
+// cleanup removes leftover files of failed attempts and the temporary
+// directory itself. Errors are logged and otherwise ignored.
+func cleanup(ctx context.Context, fs filesystem.Interface, dir string) {
+	rm, ok := fs.(filesystem.Remover)
+	if !ok {
+		return
+	}
+	leftovers, err := fs.List(ctx, dir+"/*")
+	if err != nil {
+		log.Warnf(ctx, "Failed to list temporary files in %v: %v", dir, err)
+		return
+	}
+	for _, path := range leftovers {
+		if err := rm.Remove(ctx, path); err != nil {
+			log.Warnf(ctx, "Failed to remove temporary file %v: %v", path, err)
+		}
+	}
+	// Only file systems with directories have anything to remove here.
+	_ = rm.Remove(ctx, dir)
+}
+
+// shardName expands the shard template for a file.
+func (fn *finalizeFn) shardName(w typex.Window, pane typex.PaneInfo, index, numShards int) string {
+	template := DefaultWindowedShardTemplate
+	if _, ok := w.(window.GlobalWindow); ok {
+		template = DefaultShardTemplate
+		if !pane.IsFirst || !pane.IsLast {
+			template = DefaultTriggeredShardTemplate
+		}
+	}
+	if fn.ShardTemplate != nil {
+		template = *fn.ShardTemplate
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(template); {
+		c := template[i]
+		n := 1
+		for i+n < len(template) && template[i+n] == c {
+			n++
+		}
+		switch c {
+		case 'S':
+			fmt.Fprintf(&sb, "%0*d", n, index)
+		case 'N':
+			fmt.Fprintf(&sb, "%0*d", n, numShards)
+		case 'W':
+			sb.WriteString(windowName(w))
+		case 'P':
+			sb.WriteString(paneName(pane))
+		default:
+			sb.WriteString(template[i : i+n])
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+// windowName returns the name of a window for use in filenames.
+func windowName(w typex.Window) string {
+	switch w := w.(type) {
+	case window.IntervalWindow:
+		const layout = "2006-01-02T15:04:05.000Z"
+		return w.Start.ToTime().UTC().Format(layout) + "-" + w.End.ToTime().UTC().Format(layout)
+	case window.GlobalWindow:
+		return "global"
+	default:
+		return w.MaxTimestamp().ToTime().UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// paneName returns the name of a pane for use in filenames.
+func paneName(pane typex.PaneInfo) string {
+	name := fmt.Sprintf("pane-%d", pane.Index)
+	if pane.IsLast {
+		name += "-last"
+	}
+	return name
+}
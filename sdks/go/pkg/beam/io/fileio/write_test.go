@@ -0,0 +1,255 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*lineSink)(nil)).Elem())
+	register.Function1x2(stampFn)
+	register.Function1x1(baseFn)
+}
+
+// lineSink writes every element on a separate line, followed by Footer.
+type lineSink struct {
+	Footer string
+
+	w *bufio.Writer
+}
+
+func (s *lineSink) Open(_ context.Context, w io.Writer) error {
+	s.w = bufio.NewWriter(w)
+	return nil
+}
+
+func (s *lineSink) Write(_ context.Context, elm any) error {
+	_, err := fmt.Fprintln(s.w, elm)
+	return err
+}
+
+func (s *lineSink) Flush(_ context.Context) error {
+	if _, err := s.w.WriteString(s.Footer); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// stampFn sets the event timestamp of every element to its value in seconds.
+func stampFn(v int) (typex.EventTime, int) {
+	return mtime.FromMilliseconds(int64(v) * 1000), v
+}
+
+func baseFn(path string) string {
+	return filepath.Base(path)
+}
+
+// readLines returns the sorted lines of all files matching glob.
+func readLines(t *testing.T, glob string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	var input []int
+	var want []string
+	for i := 0; i < 30; i++ {
+		input = append(input, i)
+		want = append(want, fmt.Sprint(i))
+	}
+	want = append(want, "end", "end", "end")
+	sort.Strings(want)
+
+	p, s := beam.NewPipelineWithRoot()
+	files := WriteFiles(s, filepath.Join(dir, "out"), &lineSink{Footer: "end"}, beam.CreateList(s, input),
+		WriteNumShards(3), WriteSuffix(".txt"))
+
+	passert.Equals(s, beam.ParDo(s, baseFn, files),
+		"out-00000-of-00003.txt", "out-00001-of-00003.txt", "out-00002-of-00003.txt")
+	ptest.RunAndValidate(t, p)
+
+	if got := readLines(t, filepath.Join(dir, "out-*.txt")); !cmp.Equal(got, want) {
+		t.Errorf("WriteFiles() wrote %v, want %v", got, want)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".temp-beam-*")); len(temps) != 0 {
+		t.Errorf("WriteFiles() left temporary files %v", temps)
+	}
+}
+
+func TestWriteFiles_emptyShards(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	files := WriteFiles(s, filepath.Join(dir, "out"), &lineSink{Footer: "end"}, beam.Create(s, "a"),
+		WriteNumShards(3))
+
+	passert.Equals(s, beam.ParDo(s, baseFn, files),
+		"out-00000-of-00003", "out-00001-of-00003", "out-00002-of-00003")
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filepath.Join(dir, "out-*")), []string{"a", "end", "end", "end"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteFiles() wrote %v, want %v", got, want)
+	}
+}
+
+func TestWriteFiles_runnerSharding(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	files := WriteFiles(s, filepath.Join(dir, "out"), &lineSink{}, beam.Create(s, "a", "b", "c"))
+
+	passert.NonEmpty(s, files)
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filepath.Join(dir, "out-*-of-*")), []string{"a", "b", "c"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteFiles() wrote %v, want %v", got, want)
+	}
+}
+
+func TestWriteFiles_singleFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.txt")
+
+	p, s := beam.NewPipelineWithRoot()
+	files := WriteFiles(s, filename, &lineSink{}, beam.Create(s, "a", "b"),
+		WriteNumShards(1), WriteShardTemplate(""))
+
+	passert.Equals(s, files, filename)
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filename), []string{"a", "b"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteFiles() wrote %v, want %v", got, want)
+	}
+}
+
+func TestWriteFiles_windowed(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 2, 61))
+	windowed := beam.WindowInto(s, window.NewFixedWindows(time.Minute), col)
+	files := WriteFiles(s, filepath.Join(dir, "out"), &lineSink{}, windowed,
+		WriteNumShards(1), WriteShardTemplate("-W-SS"))
+
+	passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindows(), beam.ParDo(s, baseFn, files)),
+		"out-1970-01-01T00:00:00.000Z-1970-01-01T00:01:00.000Z-00",
+		"out-1970-01-01T00:01:00.000Z-1970-01-01T00:02:00.000Z-00")
+	ptest.RunAndValidate(t, p)
+
+	if got, want := readLines(t, filepath.Join(dir, "out-1970-01-01T00:00:00.000Z-*")), []string{"1", "2"}; !cmp.Equal(got, want) {
+		t.Errorf("WriteFiles() wrote %v to first window, want %v", got, want)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".temp-beam-*")); len(temps) != 0 {
+		t.Errorf("WriteFiles() left temporary files %v", temps)
+	}
+}
+
+func TestWriteFiles_badSink(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("WriteFiles() with non-pointer sink succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	WriteFiles(s, "out", nonPointerSink{}, beam.Create(s, "a"))
+}
+
+type nonPointerSink struct{}
+
+func (nonPointerSink) Open(context.Context, io.Writer) error { return nil }
+func (nonPointerSink) Write(context.Context, any) error      { return nil }
+func (nonPointerSink) Flush(context.Context) error           { return nil }
+
+func TestShardName(t *testing.T) {
+	interval := window.IntervalWindow{Start: 0, End: mtime.FromMilliseconds(60000)}
+	pane := typex.PaneInfo{Index: 2, IsLast: true}
+	tests := []struct {
+		template *string
+		w        typex.Window
+		pane     typex.PaneInfo
+		want     string
+	}{
+		{nil, window.GlobalWindow{}, typex.NoFiringPane(), "-00003-of-00010"},
+		{nil, window.GlobalWindow{}, pane, "-pane-2-last-00003-of-00010"},
+		{nil, window.GlobalWindow{}, typex.PaneInfo{IsFirst: true}, "-pane-0-00003-of-00010"},
+		{nil, interval, pane, "-1970-01-01T00:00:00.000Z-1970-01-01T00:01:00.000Z-pane-2-last-00003-of-00010"},
+		{ptr("_S_of_NN"), window.GlobalWindow{}, pane, "_3_of_10"},
+		{ptr("-W-P"), window.GlobalWindow{}, pane, "-global-pane-2-last"},
+		{ptr(""), interval, pane, ""},
+	}
+	for _, test := range tests {
+		fn := &finalizeFn{ShardTemplate: test.template}
+		if got := fn.shardName(test.w, test.pane, 3, 10); got != test.want {
+			t.Errorf("shardName(%v, %v) = %q, want %q", test.template, test.w, got, test.want)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestWriterLimit(t *testing.T) {
+	a := acquireWriterLimit("write", 2)
+	b := acquireWriterLimit("write", 3)
+	if a != b || cap(a) != 2 {
+		t.Errorf("acquireWriterLimit() returned different semaphores or capacity %v, want the same one with capacity 2", cap(b))
+	}
+
+	releaseWriterLimit("write")
+	if _, ok := openWriters["write"]; !ok {
+		t.Error("writer limit removed while still acquired")
+	}
+	releaseWriterLimit("write")
+	if _, ok := openWriters["write"]; ok {
+		t.Error("writer limit not removed after it was released")
+	}
+}
@@ -0,0 +1,263 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package azblob contains an Azure Blob Storage implementation of the Beam
+// file system, registered for URIs of the form az://account/container/blob
+// and wasbs://container@account.blob.core.windows.net/blob.
+//
+// The account is configured with environment variables: either a connection
+// string in AZURE_STORAGE_CONNECTION_STRING, or the account name in
+// AZURE_STORAGE_ACCOUNT with its shared key in AZURE_STORAGE_KEY or a SAS
+// token in AZURE_STORAGE_SAS_TOKEN. A connection string with a BlobEndpoint,
+// or "UseDevelopmentStorage=true", allows using the Azurite emulator. Blobs
+// of other accounts are accessed anonymously. Requests are made with the
+// Azure SDK for Go.
+package azblob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/fsx"
+)
+
+func init() {
+	filesystem.Register("az", New)
+	filesystem.Register("wasbs", New)
+}
+
+type fs struct {
+	client *client
+}
+
+// New creates a new Azure Blob Storage filesystem using the account
+// configured with environment variables.
+func New(_ context.Context) filesystem.Interface {
+	a, err := accountFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("error loading Azure Storage config: %v", err))
+	}
+	return &fs{client: newClient(a, nil)}
+}
+
+// Close closes the filesystem.
+func (f *fs) Close() error {
+	return nil
+}
+
+// List returns a slice of the files in the filesystem that match the glob pattern.
+func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
+	p, err := parseURI(glob)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri: %v", err)
+	}
+	cc, err := f.client.container(p)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := listPrefix(p.blob)
+	pager := cc.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	var uris []string
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing blobs: %v", err)
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			match, err := filepath.Match(p.blob, *item.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid blob pattern: %s", p.blob)
+			}
+			if match {
+				uris = append(uris, p.uri(*item.Name))
+			}
+		}
+	}
+	return uris, nil
+}
+
+// OpenRead returns a new io.ReadCloser to read contents from the file. The returned reader also
+// implements io.Seeker, and reads the file with ranged requests, starting with the first read.
+// The caller must call Close on the returned io.ReadCloser when done reading.
+func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
+	bc, err := f.blob(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail early for missing blobs, rather than on the first read.
+	props, err := bc.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting properties of blob %s: %w", filename, err)
+	}
+	size := int64(-1)
+	if props.ContentLength != nil {
+		size = *props.ContentLength
+	}
+	return fsx.NewRangeReader(ctx, filename, size, openRange(bc)), nil
+}
+
+// OpenWrite returns a new io.WriteCloser to write contents to the file. The data is uploaded in
+// blocks, which are committed when the writer is closed. The caller must call Close on the
+// returned io.WriteCloser when done writing.
+func (f *fs) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error) {
+	p, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+	bbc, err := f.client.blockBlob(p)
+	if err != nil {
+		return nil, err
+	}
+	return newWriter(ctx, bbc, p), nil
+}
+
+// blob returns the client of the blob with the given URI.
+func (f *fs) blob(filename string) (*blob.Client, error) {
+	p, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+	return f.client.blob(p)
+}
+
+// properties returns the properties of the blob with the given URI.
+func (f *fs) properties(ctx context.Context, filename string) (blob.GetPropertiesResponse, error) {
+	bc, err := f.blob(filename)
+	if err != nil {
+		return blob.GetPropertiesResponse{}, err
+	}
+	props, err := bc.GetProperties(ctx, nil)
+	if err != nil {
+		return blob.GetPropertiesResponse{}, fmt.Errorf("error getting properties of blob %s: %w", filename, err)
+	}
+	return props, nil
+}
+
+// Size returns the size of the file.
+func (f *fs) Size(ctx context.Context, filename string) (int64, error) {
+	props, err := f.properties(ctx, filename)
+	if err != nil {
+		return -1, err
+	}
+
+	if props.ContentLength == nil {
+		return -1, fmt.Errorf("content length for blob %s is unknown", filename)
+	}
+	return *props.ContentLength, nil
+}
+
+// LastModified returns the time at which the file was last modified.
+func (f *fs) LastModified(ctx context.Context, filename string) (time.Time, error) {
+	props, err := f.properties(ctx, filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if props.LastModified == nil {
+		return time.Time{}, fmt.Errorf("last modification time of blob %s is unknown", filename)
+	}
+	return *props.LastModified, nil
+}
+
+// Remove removes the file from the filesystem.
+func (f *fs) Remove(ctx context.Context, filename string) error {
+	bc, err := f.blob(filename)
+	if err != nil {
+		return err
+	}
+
+	if _, err := bc.Delete(ctx, nil); err != nil {
+		return fmt.Errorf("error deleting blob %s: %w", filename, err)
+	}
+	return nil
+}
+
+// copyPollInterval is the interval at which the status of pending copies is checked.
+var copyPollInterval = time.Second
+
+// Copy copies the file from the old path to the new path. Copies to another account are only
+// possible if the source blob is public, or the source account is authorized with a SAS token.
+func (f *fs) Copy(ctx context.Context, oldpath, newpath string) error {
+	src, err := f.blob(oldpath)
+	if err != nil {
+		return err
+	}
+	dst, err := f.blob(newpath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := dst.StartCopyFromURL(ctx, src.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("error copying blob %s: %w", oldpath, err)
+	}
+
+	// Copies within an account usually complete synchronously, but others
+	// are asynchronous and must be waited for.
+	status, description := resp.CopyStatus, (*string)(nil)
+	for {
+		switch {
+		case status == nil || *status == blob.CopyStatusTypeSuccess:
+			return nil
+		case *status != blob.CopyStatusTypePending:
+			msg := ""
+			if description != nil {
+				msg = *description
+			}
+			return fmt.Errorf("error copying blob %s: copy %s: %s", oldpath, *status, msg)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		props, err := f.properties(ctx, newpath)
+		if err != nil {
+			return fmt.Errorf("error copying blob %s: %w", oldpath, err)
+		}
+		status, description = props.CopyStatus, props.CopyStatusDescription
+	}
+}
+
+// Rename moves the file from the old path to the new path, by copying and removing it.
+func (f *fs) Rename(ctx context.Context, oldpath, newpath string) error {
+	if err := f.Copy(ctx, oldpath, newpath); err != nil {
+		return err
+	}
+	return f.Remove(ctx, oldpath)
+}
+
+// Compile time check for interface implementations.
+var (
+	_ filesystem.LastModifiedGetter = (*fs)(nil)
+	_ filesystem.Remover            = (*fs)(nil)
+	_ filesystem.Copier             = (*fs)(nil)
+	_ filesystem.Renamer            = (*fs)(nil)
+)
@@ -0,0 +1,261 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAzblob_FilesystemNew(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "UseDevelopmentStorage=true")
+	ctx := context.Background()
+
+	for _, path := range []string{"az://account/container/blob", "wasbs://container@account.blob.core.windows.net/blob"} {
+		c, err := filesystem.New(ctx, path)
+		if err != nil {
+			t.Fatalf("filesystem.New(ctx, %q) == %v, want nil", path, err)
+		}
+		f, ok := c.(*fs)
+		if !ok {
+			t.Fatalf("filesystem.New(ctx, %q) type == %T, want *azblob.fs", path, c)
+		}
+		if got, want := f.client.account.endpoint, devStoreEndpoint; got != want {
+			t.Errorf("filesystem.New(ctx, %q) endpoint = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func Test_fs_List(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	for _, blob := range []string{"dir/a.txt", "dir/b.txt", "dir/c.csv", "dir/sub/d.txt", "other/e.txt"} {
+		svc.createBlob("container", blob, []byte(blob))
+	}
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{
+			glob: "az://devstoreaccount1/container/dir/*.txt",
+			want: []string{"az://devstoreaccount1/container/dir/a.txt", "az://devstoreaccount1/container/dir/b.txt"},
+		},
+		{
+			glob: "az://devstoreaccount1/container/*/*.txt",
+			want: []string{
+				"az://devstoreaccount1/container/dir/a.txt",
+				"az://devstoreaccount1/container/dir/b.txt",
+				"az://devstoreaccount1/container/other/e.txt",
+			},
+		},
+		{
+			glob: "wasbs://container@devstoreaccount1.blob.core.windows.net/dir/?.csv",
+			want: []string{"wasbs://container@devstoreaccount1.blob.core.windows.net/dir/c.csv"},
+		},
+		{
+			glob: "az://devstoreaccount1/container/dir/sub/d.txt",
+			want: []string{"az://devstoreaccount1/container/dir/sub/d.txt"},
+		},
+		{
+			glob: "az://devstoreaccount1/container/missing/*",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		got, err := f.List(ctx, tt.glob)
+		if err != nil {
+			t.Fatalf("List(%q) error = %v, want nil", tt.glob, err)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("List(%q) = %v, want %v", tt.glob, got, tt.want)
+		}
+	}
+
+	if _, err := f.List(ctx, "az://devstoreaccount1/missing/*"); err == nil {
+		t.Errorf("List() of missing container succeeded, want error")
+	}
+}
+
+func Test_fs_OpenRead(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	svc.createBlob("container", "dir/file name.txt", []byte("0123456789"))
+
+	rc, err := f.OpenRead(ctx, "az://devstoreaccount1/container/dir/file name.txt")
+	if err != nil {
+		t.Fatalf("OpenRead() error = %v, want nil", err)
+	}
+	defer rc.Close()
+	rs := rc.(io.ReadSeeker)
+
+	seeks := []struct {
+		offset int64
+		whence int
+		want   string
+	}{
+		{0, io.SeekStart, "0123456789"},
+		{4, io.SeekStart, "456789"},
+		{-3, io.SeekEnd, "789"},
+		{10, io.SeekStart, ""},
+	}
+	for _, s := range seeks {
+		if _, err := rs.Seek(s.offset, s.whence); err != nil {
+			t.Fatalf("Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+		}
+		got, err := io.ReadAll(rs)
+		if err != nil {
+			t.Fatalf("ReadAll() after Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+		}
+		if string(got) != s.want {
+			t.Errorf("ReadAll() after Seek(%v, %v) = %q, want %q", s.offset, s.whence, got, s.want)
+		}
+	}
+
+	if _, err := f.OpenRead(ctx, "az://devstoreaccount1/container/missing.txt"); !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		t.Errorf("OpenRead() of missing blob error = %v, want not found", err)
+	}
+}
+
+func Test_fs_OpenWrite(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+
+	wc, err := f.OpenWrite(ctx, "wasbs://container@devstoreaccount1.blob.core.windows.net/dir/file.txt")
+	if err != nil {
+		t.Fatalf("OpenWrite() error = %v, want nil", err)
+	}
+	if _, err := wc.Write([]byte("content")); err != nil {
+		t.Fatalf("Write() error = %v, want nil", err)
+	}
+	if err := wc.Close(); err != nil {
+		t.Fatalf("Close() error = %v, want nil", err)
+	}
+
+	if got, _ := svc.getBlob("container", "dir/file.txt"); string(got) != "content" {
+		t.Errorf("blob content = %q, want %q", got, "content")
+	}
+}
+
+func Test_fs_Size(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	svc.createBlob("container", "file.txt", []byte("content"))
+
+	size, err := f.Size(ctx, "az://devstoreaccount1/container/file.txt")
+	if err != nil || size != 7 {
+		t.Errorf("Size() = %v, %v, want 7, nil", size, err)
+	}
+	if _, err := f.Size(ctx, "az://devstoreaccount1/container/missing.txt"); !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		t.Errorf("Size() of missing blob error = %v, want not found", err)
+	}
+}
+
+func Test_fs_LastModified(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	svc.createBlob("container", "file.txt", []byte("content"))
+
+	got, err := f.LastModified(ctx, "az://devstoreaccount1/container/file.txt")
+	if err != nil || !got.Equal(fakeModTime) {
+		t.Errorf("LastModified() = %v, %v, want %v, nil", got, err, fakeModTime)
+	}
+}
+
+func Test_fs_Remove(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	svc.createBlob("container", "file.txt", []byte("content"))
+
+	if err := f.Remove(ctx, "az://devstoreaccount1/container/file.txt"); err != nil {
+		t.Fatalf("Remove() error = %v, want nil", err)
+	}
+	if _, ok := svc.getBlob("container", "file.txt"); ok {
+		t.Errorf("blob exists after Remove()")
+	}
+	if err := f.Remove(ctx, "az://devstoreaccount1/container/file.txt"); err == nil {
+		t.Errorf("Remove() of missing blob succeeded, want error")
+	}
+}
+
+func Test_fs_Copy(t *testing.T) {
+	defer func(d time.Duration) { copyPollInterval = d }(copyPollInterval)
+	copyPollInterval = time.Millisecond
+
+	for _, pending := range []bool{false, true} {
+		ctx := context.Background()
+		server, svc := newServer(t)
+		f := newFS(t, server)
+
+		svc.pendingCopies = pending
+		svc.createContainer("src")
+		svc.createContainer("dst")
+		svc.createBlob("src", "file.txt", []byte("content"))
+
+		if err := f.Copy(ctx, "az://devstoreaccount1/src/file.txt", "az://devstoreaccount1/dst/copy.txt"); err != nil {
+			t.Fatalf("Copy() with pending copies %v error = %v, want nil", pending, err)
+		}
+		if got, _ := svc.getBlob("dst", "copy.txt"); string(got) != "content" {
+			t.Errorf("copied blob content = %q, want %q", got, "content")
+		}
+		if _, ok := svc.getBlob("src", "file.txt"); !ok {
+			t.Errorf("source blob missing after Copy()")
+		}
+	}
+}
+
+func Test_fs_Rename(t *testing.T) {
+	ctx := context.Background()
+	server, svc := newServer(t)
+	f := newFS(t, server)
+
+	svc.createContainer("container")
+	svc.createBlob("container", "old.txt", []byte("content"))
+
+	if err := f.Rename(ctx, "az://devstoreaccount1/container/old.txt", "az://devstoreaccount1/container/new.txt"); err != nil {
+		t.Fatalf("Rename() error = %v, want nil", err)
+	}
+	if got, _ := svc.getBlob("container", "new.txt"); string(got) != "content" {
+		t.Errorf("renamed blob content = %q, want %q", got, "content")
+	}
+	if _, ok := svc.getBlob("container", "old.txt"); ok {
+		t.Errorf("old blob exists after Rename()")
+	}
+}
@@ -0,0 +1,222 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+// Azurite, the Azure Storage emulator, uses a well-known account and key.
+const (
+	devStoreAccount  = "devstoreaccount1"
+	devStoreKey      = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	devStoreEndpoint = "http://127.0.0.1:10000/" + devStoreAccount
+)
+
+// account holds the endpoint and credentials of a storage account.
+type account struct {
+	name string
+	// endpoint is the blob service endpoint of the account, without a
+	// trailing slash.
+	endpoint string
+	// key is the base64 encoded shared key of the account, or empty if
+	// requests aren't signed.
+	key string
+	// sas is a shared access signature appended to the query of requests.
+	sas url.Values
+}
+
+// accountFromEnv returns the account configured with environment variables,
+// or nil if there is none. A connection string in
+// AZURE_STORAGE_CONNECTION_STRING takes precedence over AZURE_STORAGE_ACCOUNT
+// with AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN.
+func accountFromEnv() (*account, error) {
+	if cs := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); cs != "" {
+		return parseConnectionString(cs)
+	}
+	name := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if name == "" {
+		return nil, nil
+	}
+	return newAccount(name, "", os.Getenv("AZURE_STORAGE_KEY"), os.Getenv("AZURE_STORAGE_SAS_TOKEN"))
+}
+
+// parseConnectionString parses an Azure Storage connection string, such as
+// "DefaultEndpointsProtocol=https;AccountName=name;AccountKey=key" or
+// "UseDevelopmentStorage=true" for Azurite, which the Azure SDK doesn't
+// support.
+func parseConnectionString(cs string) (*account, error) {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(cs, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid connection string field %q", k)
+		}
+		fields[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	if strings.EqualFold(fields["usedevelopmentstorage"], "true") {
+		return newAccount(devStoreAccount, devStoreEndpoint, devStoreKey, "")
+	}
+
+	name := fields["accountname"]
+	endpoint := fields["blobendpoint"]
+	if endpoint == "" {
+		if name == "" {
+			return nil, errors.New("connection string must contain AccountName or BlobEndpoint")
+		}
+		protocol, suffix := fields["defaultendpointsprotocol"], fields["endpointsuffix"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, name, suffix)
+	}
+	if name == "" {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BlobEndpoint %q: %v", endpoint, err)
+		}
+		name, _, _ = strings.Cut(u.Hostname(), ".")
+	}
+	return newAccount(name, endpoint, fields["accountkey"], fields["sharedaccesssignature"])
+}
+
+// newAccount creates an account with the given endpoint, or the default
+// endpoint of the account if empty, and the given base64 encoded shared key
+// or SAS token, if not empty.
+func newAccount(name, endpoint, key, sas string) (*account, error) {
+	a := &account{name: name, endpoint: strings.TrimSuffix(endpoint, "/"), key: key}
+	if a.endpoint == "" {
+		a.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", name)
+	}
+	if key != "" {
+		if _, err := base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("invalid key for account %v: %v", name, err)
+		}
+	}
+	if sas != "" {
+		v, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SAS token for account %v: %v", name, err)
+		}
+		a.sas = v
+	}
+	return a, nil
+}
+
+// client creates Azure SDK clients for the blobs of storage accounts.
+type client struct {
+	// options are used for all clients, and may be nil.
+	options *service.ClientOptions
+	// account is the configured account, or nil. Requests to other accounts
+	// are anonymous, which only works for public containers.
+	account *account
+
+	mu sync.Mutex
+	// services maps account endpoints to the clients of their Blob service.
+	services map[string]*service.Client
+}
+
+func newClient(a *account, options *service.ClientOptions) *client {
+	return &client{options: options, account: a, services: make(map[string]*service.Client)}
+}
+
+// accountFor returns the configuration of the account of p.
+func (c *client) accountFor(p blobPath) *account {
+	if c.account != nil && c.account.name == p.account {
+		return c.account
+	}
+	endpoint := fmt.Sprintf("https://%s.blob.core.windows.net", p.account)
+	if p.host != "" {
+		endpoint = "https://" + p.host
+	}
+	return &account{name: p.account, endpoint: endpoint}
+}
+
+// service returns the client of the Blob service of the account of p.
+func (c *client) service(p blobPath) (*service.Client, error) {
+	a := c.accountFor(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.services[a.endpoint]; ok {
+		return s, nil
+	}
+
+	serviceURL := a.endpoint + "/"
+	if len(a.sas) > 0 {
+		serviceURL += "?" + a.sas.Encode()
+	}
+	var s *service.Client
+	var err error
+	if a.key != "" {
+		var cred *service.SharedKeyCredential
+		if cred, err = service.NewSharedKeyCredential(a.name, a.key); err != nil {
+			return nil, fmt.Errorf("invalid key for account %v: %v", a.name, err)
+		}
+		s, err = service.NewClientWithSharedKeyCredential(serviceURL, cred, c.options)
+	} else {
+		s, err = service.NewClientWithNoCredential(serviceURL, c.options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for account %v: %v", a.name, err)
+	}
+	c.services[a.endpoint] = s
+	return s, nil
+}
+
+// container returns the client of the container of p.
+func (c *client) container(p blobPath) (*container.Client, error) {
+	s, err := c.service(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.NewContainerClient(p.container), nil
+}
+
+// blob returns the client of the blob of p.
+func (c *client) blob(p blobPath) (*blob.Client, error) {
+	cc, err := c.container(p)
+	if err != nil {
+		return nil, err
+	}
+	return cc.NewBlobClient(p.blob), nil
+}
+
+// blockBlob returns the client of the blob of p as a block blob.
+func (c *client) blockBlob(p blobPath) (*blockblob.Client, error) {
+	cc, err := c.container(p)
+	if err != nil {
+		return nil, err
+	}
+	return cc.NewBlockBlobClient(p.blob), nil
+}
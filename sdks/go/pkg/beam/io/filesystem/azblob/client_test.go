@@ -0,0 +1,122 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func Test_parseConnectionString(t *testing.T) {
+	tests := []struct {
+		name         string
+		cs           string
+		wantName     string
+		wantEndpoint string
+		wantKey      bool
+		wantSAS      string
+		wantErr      bool
+	}{
+		{
+			name:         "Account key",
+			cs:           "DefaultEndpointsProtocol=https;AccountName=account;AccountKey=a2V5;EndpointSuffix=core.windows.net",
+			wantName:     "account",
+			wantEndpoint: "https://account.blob.core.windows.net",
+			wantKey:      true,
+		},
+		{
+			name:         "Custom endpoint suffix",
+			cs:           "AccountName=account;AccountKey=a2V5;EndpointSuffix=core.chinacloudapi.cn",
+			wantName:     "account",
+			wantEndpoint: "https://account.blob.core.chinacloudapi.cn",
+			wantKey:      true,
+		},
+		{
+			name:         "SAS with blob endpoint",
+			cs:           "BlobEndpoint=https://account.blob.core.windows.net/;SharedAccessSignature=sv=2021-12-02&sig=abc%3D",
+			wantName:     "account",
+			wantEndpoint: "https://account.blob.core.windows.net",
+			wantSAS:      "sig=abc%3D&sv=2021-12-02",
+		},
+		{
+			name:         "Development storage",
+			cs:           "UseDevelopmentStorage=true",
+			wantName:     devStoreAccount,
+			wantEndpoint: devStoreEndpoint,
+			wantKey:      true,
+		},
+		{
+			name:    "Invalid key",
+			cs:      "AccountName=account;AccountKey=not base64",
+			wantErr: true,
+		},
+		{
+			name:    "Missing account",
+			cs:      "AccountKey=a2V5",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConnectionString(tt.cs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConnectionString() err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.name != tt.wantName || got.endpoint != tt.wantEndpoint {
+				t.Errorf("parseConnectionString() = %v at %v, want %v at %v", got.name, got.endpoint, tt.wantName, tt.wantEndpoint)
+			}
+			if (got.key != "") != tt.wantKey {
+				t.Errorf("parseConnectionString() key = %v, want key %v", got.key, tt.wantKey)
+			}
+			if got.sas.Encode() != tt.wantSAS {
+				t.Errorf("parseConnectionString() SAS = %v, want %v", got.sas.Encode(), tt.wantSAS)
+			}
+		})
+	}
+}
+
+func Test_client_sas(t *testing.T) {
+	var query url.Values
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("request with SAS token has Authorization header")
+		}
+	})
+	a, err := newAccount("account", server.URL, "", "?sv=2021-12-02&sig=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(a, testClientOptions(server))
+
+	p, _ := parseURI("az://account/container/blob")
+	bc, err := c.blob(p)
+	if err != nil {
+		t.Fatalf("blob() error = %v, want nil", err)
+	}
+	if _, err := bc.GetProperties(context.Background(), nil); err != nil {
+		t.Fatalf("GetProperties() error = %v, want nil", err)
+	}
+	if got, want := query.Get("sig")+" "+query.Get("sv"), "abc 2021-12-02"; got != want {
+		t.Errorf("request query sig and sv = %v, want %v", got, want)
+	}
+}
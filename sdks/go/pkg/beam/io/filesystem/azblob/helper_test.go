@@ -0,0 +1,276 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+// fakeListPageSize is the number of blobs in each page of a List Blobs
+// response of the fake service, to exercise paging.
+const fakeListPageSize = 2
+
+// fakeModTime is the last modification time of all blobs of the fake service.
+var fakeModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fakeService is an in-memory implementation of the subset of the Blob
+// service REST API used by the filesystem, for the devstoreaccount1 account
+// with path-style URLs, as used by Azurite. Requests must be authorized with
+// the account's shared key.
+type fakeService struct {
+	mu sync.Mutex
+	// containers maps container names to the blobs in them.
+	containers map[string]map[string][]byte
+	// blocks maps blob paths to their uncommitted blocks.
+	blocks map[string]map[string][]byte
+	// pendingCopies makes copies asynchronous, with a pending status
+	// returned by the copy request.
+	pendingCopies bool
+	// requests counts the requests by operation, such as "PUT block".
+	requests map[string]int
+}
+
+func newFakeService() *fakeService {
+	return &fakeService{
+		containers: make(map[string]map[string][]byte),
+		blocks:     make(map[string]map[string][]byte),
+		requests:   make(map[string]int),
+	}
+}
+
+// newServer returns a test server for a new fake service.
+func newServer(t *testing.T) (*httptest.Server, *fakeService) {
+	t.Helper()
+	svc := newFakeService()
+	server := httptest.NewServer(svc)
+	t.Cleanup(server.Close)
+	return server, svc
+}
+
+// newFS returns a filesystem configured for the devstoreaccount1 account
+// at the test server.
+func newFS(t *testing.T, server *httptest.Server) *fs {
+	t.Helper()
+	a, err := newAccount(devStoreAccount, server.URL+"/"+devStoreAccount, devStoreKey, "")
+	if err != nil {
+		t.Fatalf("error creating account: %v", err)
+	}
+	return &fs{client: newClient(a, testClientOptions(server))}
+}
+
+// testClientOptions returns client options that send requests to the test
+// server without retries.
+func testClientOptions(server *httptest.Server) *service.ClientOptions {
+	return &service.ClientOptions{ClientOptions: policy.ClientOptions{
+		Transport: server.Client(),
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}}
+}
+
+// blockList is the body of a Put Block List request.
+type blockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+func (s *fakeService) createContainer(container string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.containers[container] = make(map[string][]byte)
+}
+
+func (s *fakeService) createBlob(container, blob string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.containers[container][blob] = data
+}
+
+func (s *fakeService) getBlob(container, blob string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.containers[container][blob]
+	return data, ok
+}
+
+func (s *fakeService) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, code)
+}
+
+func (s *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey "+devStoreAccount+":") {
+		s.writeError(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+devStoreAccount+"/")
+	container, blob, _ := strings.Cut(path, "/")
+	blobs, ok := s.containers[container]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+	query := r.URL.Query()
+	s.requests[r.Method+" "+query.Get("comp")]++
+
+	switch {
+	case r.Method == http.MethodGet && query.Get("comp") == "list":
+		s.list(w, blobs, query.Get("prefix"), query.Get("marker"))
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := blobs[blob]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		w.Header().Set("Last-Modified", fakeModTime.Format(http.TimeFormat))
+		w.Header().Set("x-ms-copy-status", "success")
+		status := http.StatusOK
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			var start int
+			fmt.Sscanf(rng, "bytes=%d-", &start)
+			if start >= len(data) {
+				s.writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+			data = data[start:]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		data, _ := io.ReadAll(r.Body)
+		if s.blocks[path] == nil {
+			s.blocks[path] = make(map[string][]byte)
+		}
+		s.blocks[path][query.Get("blockid")] = data
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list blockList
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			s.writeError(w, http.StatusBadRequest, "InvalidXmlDocument")
+			return
+		}
+		var data []byte
+		for _, id := range list.Latest {
+			block, ok := s.blocks[path][id]
+			if !ok {
+				s.writeError(w, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		blobs[blob] = data
+		delete(s.blocks, path)
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+		src, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "InvalidHeaderValue")
+			return
+		}
+		srcContainer, srcBlob, _ := strings.Cut(strings.TrimPrefix(src.Path, "/"+devStoreAccount+"/"), "/")
+		data, ok := s.containers[srcContainer][srcBlob]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "CannotVerifyCopySource")
+			return
+		}
+		blobs[blob] = data
+		if s.pendingCopies {
+			w.Header().Set("x-ms-copy-status", "pending")
+		} else {
+			w.Header().Set("x-ms-copy-status", "success")
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			s.writeError(w, http.StatusBadRequest, "MissingRequiredHeader")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		blobs[blob] = data
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodDelete:
+		if _, ok := blobs[blob]; !ok {
+			s.writeError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(blobs, blob)
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		s.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+// list writes a page of the blobs with the prefix, starting at the marker.
+func (s *fakeService) list(w http.ResponseWriter, blobs map[string][]byte, prefix, marker string) {
+	var names []string
+	for name := range blobs {
+		if strings.HasPrefix(name, prefix) && name >= marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(xml.Header + "<EnumerationResults><Blobs>")
+	for i, name := range names {
+		if i == fakeListPageSize {
+			fmt.Fprintf(&b, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", name)
+			io.WriteString(w, b.String())
+			return
+		}
+		fmt.Fprintf(&b, "<Blob><Name>%s</Name><Properties><Content-Length>%d</Content-Length></Properties></Blob>", name, len(blobs[name]))
+	}
+	b.WriteString("</Blobs><NextMarker /></EnumerationResults>")
+	io.WriteString(w, b.String())
+}
+
+// newTestServer returns a test server with the given handler.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
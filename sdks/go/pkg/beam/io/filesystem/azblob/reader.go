@@ -0,0 +1,51 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/fsx"
+)
+
+// openRange returns a function that downloads the blob from an offset.
+func openRange(bc *blob.Client) fsx.OpenRangeFn {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		resp, err := bc.DownloadStream(ctx, &blob.DownloadStreamOptions{Range: blob.HTTPRange{Offset: offset}})
+		if bloberror.HasCode(err, bloberror.InvalidRange) {
+			// Reading at or past the end of the blob.
+			return io.NopCloser(strings.NewReader("")), -1, nil
+		}
+		if err != nil {
+			return nil, -1, fmt.Errorf("error getting blob %v: %w", bc.URL(), err)
+		}
+		size := int64(-1)
+		if resp.ContentRange != nil {
+			var start, end int64
+			if _, err := fmt.Sscanf(*resp.ContentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+				size = -1
+			}
+		} else if resp.ContentLength != nil {
+			size = *resp.ContentLength
+		}
+		return resp.Body, size, nil
+	}
+}
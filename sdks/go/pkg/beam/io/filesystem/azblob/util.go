@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// blobPath identifies a blob, or a blob name pattern, in a container of a
+// storage account.
+type blobPath struct {
+	// scheme is the scheme of the URI the path was parsed from, either "az"
+	// or "wasbs".
+	scheme string
+	// host is the blob service host of a wasbs URI, and empty for az URIs.
+	host      string
+	account   string
+	container string
+	blob      string
+}
+
+// parseURI parses a URI of the form az://account/container/blob or
+// wasbs://container@account.blob.core.windows.net/blob. The blob name is
+// taken verbatim, since blob names and patterns may contain characters such
+// as "?" and "#" that have special meanings in URLs.
+func parseURI(uri string) (blobPath, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return blobPath{}, errors.New("uri must have a scheme")
+	}
+	authority, path, _ := strings.Cut(rest, "/")
+
+	p := blobPath{scheme: scheme}
+	switch scheme {
+	case "az":
+		p.account = authority
+		p.container, p.blob, _ = strings.Cut(path, "/")
+	case "wasbs":
+		p.container, p.host, ok = strings.Cut(authority, "@")
+		if !ok {
+			return blobPath{}, errors.New("container must not be empty")
+		}
+		p.account, _, _ = strings.Cut(p.host, ".")
+		p.blob = path
+	default:
+		return blobPath{}, errors.New("scheme must be 'az' or 'wasbs'")
+	}
+	if p.account == "" {
+		return blobPath{}, errors.New("account must not be empty")
+	}
+	if p.container == "" {
+		return blobPath{}, errors.New("container must not be empty")
+	}
+	return p, nil
+}
+
+// uri returns the URI of the blob with the given name in the same container,
+// in the same form as the URI p was parsed from.
+func (p blobPath) uri(blob string) string {
+	if p.scheme == "wasbs" {
+		return fmt.Sprintf("wasbs://%s@%s/%s", p.container, p.host, blob)
+	}
+	return fmt.Sprintf("az://%s/%s/%s", p.account, p.container, blob)
+}
+
+// String returns the URI of the blob.
+func (p blobPath) String() string {
+	return p.uri(p.blob)
+}
+
+// listPrefix returns the part of a blob name pattern before the first wildcard
+// or escape character, which all matching blob names start with.
+func listPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
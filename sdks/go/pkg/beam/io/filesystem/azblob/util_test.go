@@ -0,0 +1,102 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import "testing"
+
+func Test_parseURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    blobPath
+		wantErr bool
+	}{
+		{
+			name: "Valid az uri",
+			uri:  "az://account/container/path/to/blob",
+			want: blobPath{scheme: "az", account: "account", container: "container", blob: "path/to/blob"},
+		},
+		{
+			name: "Valid az uri with empty blob",
+			uri:  "az://account/container",
+			want: blobPath{scheme: "az", account: "account", container: "container"},
+		},
+		{
+			name: "Valid wasbs uri",
+			uri:  "wasbs://container@account.blob.core.windows.net/path/to/blob",
+			want: blobPath{
+				scheme:    "wasbs",
+				host:      "account.blob.core.windows.net",
+				account:   "account",
+				container: "container",
+				blob:      "path/to/blob",
+			},
+		},
+		{
+			name:    "Invalid uri: wrong scheme",
+			uri:     "s3://account/container/blob",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid uri: missing account",
+			uri:     "az:///container/blob",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid uri: missing container",
+			uri:     "az://account",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid uri: missing wasbs container",
+			uri:     "wasbs://account.blob.core.windows.net/blob",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseURI() err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseURI() = %+v, want %+v", got, tt.want)
+			}
+			if err == nil && got.blob != "" && got.String() != tt.uri {
+				t.Errorf("parseURI().String() = %v, want %v", got.String(), tt.uri)
+			}
+		})
+	}
+}
+
+func Test_listPrefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "path/**/*.json", want: "path/"},
+		{pattern: "path/file-?.json", want: "path/file-"},
+		{pattern: "path/[ab].json", want: "path/"},
+		{pattern: "path/a\\*.json", want: "path/a"},
+		{pattern: "path/file.json", want: "path/file.json"},
+	}
+	for _, tt := range tests {
+		if got := listPrefix(tt.pattern); got != tt.want {
+			t.Errorf("listPrefix(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+)
+
+// defaultBlockSize is the size of the blocks uploaded by writers.
+const defaultBlockSize = 8 << 20
+
+// writer uploads a block blob. Data is buffered and staged in blocks, which
+// are committed when the writer is closed. Blobs smaller than a block are
+// uploaded with a single request instead.
+type writer struct {
+	ctx       context.Context
+	client    *blockblob.Client
+	path      blobPath
+	blockSize int
+
+	buf      []byte
+	blockIDs []string
+	closed   bool
+	err      error
+}
+
+func newWriter(ctx context.Context, client *blockblob.Client, path blobPath) *writer {
+	return &writer{ctx: ctx, client: client, path: path, blockSize: defaultBlockSize}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed writer")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		m := min(len(p), w.blockSize-len(w.buf))
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		if len(w.buf) == w.blockSize {
+			if w.err = w.stageBlock(); w.err != nil {
+				return n - len(p), w.err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+
+	if len(w.blockIDs) == 0 {
+		w.err = w.upload()
+		return w.err
+	}
+	if len(w.buf) > 0 {
+		if w.err = w.stageBlock(); w.err != nil {
+			return w.err
+		}
+	}
+	w.err = w.commitBlockList()
+	return w.err
+}
+
+// upload uploads the buffered data as the whole blob.
+func (w *writer) upload() error {
+	if _, err := w.client.Upload(w.ctx, streaming.NopCloser(bytes.NewReader(w.buf)), nil); err != nil {
+		return fmt.Errorf("error uploading blob %v: %w", w.path, err)
+	}
+	return nil
+}
+
+// stageBlock uploads the buffered data as a new uncommitted block.
+func (w *writer) stageBlock() error {
+	// Block IDs must have the same length for all blocks of a blob.
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%08d", len(w.blockIDs))))
+	if _, err := w.client.StageBlock(w.ctx, id, streaming.NopCloser(bytes.NewReader(w.buf)), nil); err != nil {
+		return fmt.Errorf("error staging block of blob %v: %w", w.path, err)
+	}
+	w.blockIDs = append(w.blockIDs, id)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// commitBlockList commits the staged blocks as the content of the blob.
+func (w *writer) commitBlockList() error {
+	if _, err := w.client.CommitBlockList(w.ctx, w.blockIDs, nil); err != nil {
+		return fmt.Errorf("error committing blocks of blob %v: %w", w.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_writer_WriteClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		writes     []string
+		wantBlocks int
+		wantPuts   int
+	}{
+		{name: "Empty blob", writes: nil, wantBlocks: 0, wantPuts: 1},
+		{name: "Single request", writes: []string{"ab", "c"}, wantBlocks: 0, wantPuts: 1},
+		{name: "Exact blocks", writes: []string{"abcd", "efgh"}, wantBlocks: 2, wantPuts: 0},
+		{name: "Partial last block", writes: []string{"abcdefghij"}, wantBlocks: 3, wantPuts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server, svc := newServer(t)
+			f := newFS(t, server)
+			svc.createContainer("container")
+
+			p, err := parseURI("az://devstoreaccount1/container/file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			bbc, err := f.client.blockBlob(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := newWriter(ctx, bbc, p)
+			w.blockSize = 4
+
+			var want string
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %v, %v, want %v, nil", s, n, err, len(s))
+				}
+				want += s
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v, want nil", err)
+			}
+			if _, err := w.Write([]byte("x")); err == nil {
+				t.Errorf("Write() after Close() succeeded, want error")
+			}
+
+			got, ok := svc.getBlob("container", "file.txt")
+			if !ok || string(got) != want {
+				t.Errorf("blob content = %q, %v, want %q, true", got, ok, want)
+			}
+			if got := svc.requests["PUT block"]; got != tt.wantBlocks {
+				t.Errorf("staged %v blocks, want %v", got, tt.wantBlocks)
+			}
+			if got := svc.requests["PUT "]; got != tt.wantPuts {
+				t.Errorf("made %v Put Blob requests, want %v", got, tt.wantPuts)
+			}
+		})
+	}
+}
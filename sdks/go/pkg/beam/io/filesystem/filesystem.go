@@ -42,6 +42,10 @@ var wellKnownSchemeImportPaths = map[string]string{
 	"default": "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local",
 	"gs":      "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/gcs",
 	"s3":      "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/s3",
+	"http":    "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/http",
+	"https":   "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/http",
+	"az":      "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/azblob",
+	"wasbs":   "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/azblob",
 }
 
 // Register registers a file system backend under the given scheme.  For
@@ -141,13 +141,32 @@ func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
 	return ret, nil
 }
 
+// OpenRead returns a new io.ReadCloser to read contents from the file. The
+// returned reader also implements io.Seeker, which reads the object from the
+// new offset with a ranged request.
 func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
 	bucket, object, err := gcsx.ParseObject(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return f.client.Bucket(bucket).UserProject(billingProject).Object(object).NewReader(ctx)
+	obj := f.client.Bucket(bucket).UserProject(billingProject).Object(object)
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fsx.NewOpenedRangeReader(ctx, filename, r.Attrs.Size, openRange(obj), r), nil
+}
+
+// openRange returns a function that reads the object from an offset.
+func openRange(obj *storage.ObjectHandle) fsx.OpenRangeFn {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		r, err := obj.NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			return nil, -1, err
+		}
+		return r, r.Attrs.Size, nil
+	}
 }
 
 // TODO(herohde) 7/12/2017: should we create the bucket in OpenWrite? For now, "no".
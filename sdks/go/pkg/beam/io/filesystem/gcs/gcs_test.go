@@ -20,6 +20,7 @@ import (
 	"io"
 	"sort"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/hooks"
@@ -117,6 +118,16 @@ func testGCS_direct(t *testing.T) {
 	if got, want := string(data), string(buf); got != want {
 		t.Errorf("ReadAll() = %v, want %v", got, want)
 	}
+
+	// The reader can seek to read the object from other offsets.
+	rc, err = c.OpenRead(ctx, filePath)
+	if err != nil {
+		t.Fatalf("OpenRead(ctx, %q) == %v, want nil", filePath, err)
+	}
+	defer rc.Close()
+	if err := iotest.TestReader(rc, data); err != nil {
+		t.Errorf("TestReader() error = %v, want nil", err)
+	}
 }
 
 func TestLocal_util(t *testing.T) {
@@ -0,0 +1,320 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package http contains a read-only HTTP(S) implementation of the Beam file
+// system, registered for the "http" and "https" schemes. It allows reading
+// public datasets directly from URLs, for example with textio.Read.
+//
+// Files are read with range requests, so readers can seek without reading
+// the skipped data, provided the server supports ranges. Sizes and
+// modification times are determined with HEAD requests.
+//
+// HTTP has no standard way to list files, so globs are expanded with a
+// listing of the directory containing the pattern, which is fetched from the
+// directory URL with a trailing slash. The listing is either an HTML page,
+// such as the index pages generated by most web servers, from which all
+// links are taken, or plain text with one link per line. Links are resolved
+// relative to the directory URL and matched against the pattern. Only the
+// last path segment of a pattern may contain wildcards. Since "?" starts the
+// query of a URL, the single character wildcard must be escaped as "%3F".
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	nethttp "net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/fsx"
+)
+
+func init() {
+	filesystem.Register("http", New)
+	filesystem.Register("https", New)
+}
+
+type fs struct {
+	client *nethttp.Client
+}
+
+// New creates a new HTTP filesystem using the default HTTP client.
+func New(_ context.Context) filesystem.Interface {
+	return &fs{client: nethttp.DefaultClient}
+}
+
+// Close closes the filesystem.
+func (f *fs) Close() error {
+	return nil
+}
+
+// List returns the URLs matching the glob pattern. A pattern without
+// wildcards is returned as is if the file exists.
+func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
+	u, err := url.Parse(glob)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL %v: %v", glob, err)
+	}
+	dir, pattern := splitPath(u.Path)
+	if !hasWildcard(pattern) {
+		if hasWildcard(dir) {
+			return nil, fmt.Errorf("wildcards are only supported in the last path segment: %v", glob)
+		}
+		resp, err := f.head(ctx, glob)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode == nethttp.StatusNotFound {
+			return nil, nil
+		}
+		if err := checkStatus(resp, glob); err != nil {
+			return nil, err
+		}
+		return []string{glob}, nil
+	}
+	if hasWildcard(dir) {
+		return nil, fmt.Errorf("wildcards are only supported in the last path segment: %v", glob)
+	}
+
+	base := *u
+	base.Path = dir + "/"
+	base.RawPath, base.RawQuery, base.Fragment = "", "", ""
+	links, err := f.listing(ctx, &base)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, link := range links {
+		ref, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		file := base.ResolveReference(ref)
+		file.Fragment = ""
+		if file.Scheme != base.Scheme || file.Host != base.Host {
+			continue
+		}
+		fileDir, name := splitPath(file.Path)
+		if fileDir != dir || name == "" {
+			continue
+		}
+		match, err := filesystem.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", glob, err)
+		}
+		if match && !seen[file.String()] {
+			seen[file.String()] = true
+			files = append(files, file.String())
+		}
+	}
+	return files, nil
+}
+
+// hrefPattern matches the targets of links in HTML listings.
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+// listing returns the links in the listing of a directory.
+func (f *fs) listing(ctx context.Context, dir *url.URL) ([]string, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, dir.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing %v: %v", dir, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, dir.String()); err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/html" {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading listing %v: %v", dir, err)
+		}
+		var links []string
+		for _, m := range hrefPattern.FindAllSubmatch(data, -1) {
+			links = append(links, unescapeHTML(string(m[1])))
+		}
+		return links, nil
+	}
+
+	var links []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			links = append(links, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading listing %v: %v", dir, err)
+	}
+	return links, nil
+}
+
+// unescapeHTML replaces the character references that may appear in URLs in
+// HTML attributes.
+func unescapeHTML(s string) string {
+	return strings.NewReplacer("&amp;", "&", "&#38;", "&", "&quot;", `"`, "&#39;", "'").Replace(s)
+}
+
+// OpenRead returns a new io.ReadCloser to read contents from the file. The
+// returned reader also implements io.Seeker. The caller must call Close on
+// the returned io.ReadCloser when done reading.
+func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
+	// Fail early for missing files with a HEAD request. The file is only
+	// requested on the first read, from the offset of the reader at that time,
+	// so that seeking before reading doesn't waste a request.
+	resp, err := f.head(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp, filename); err != nil {
+		return nil, err
+	}
+	return fsx.NewRangeReader(ctx, filename, resp.ContentLength, f.openRange(filename)), nil
+}
+
+// OpenWrite returns an error, since the HTTP filesystem is read-only.
+func (f *fs) OpenWrite(_ context.Context, filename string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("can't write %v: the http filesystem is read-only", filename)
+}
+
+// Size returns the size of the file from the Content-Length of a HEAD request.
+func (f *fs) Size(ctx context.Context, filename string) (int64, error) {
+	resp, err := f.head(ctx, filename)
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp, filename); err != nil {
+		return -1, err
+	}
+	if resp.ContentLength < 0 {
+		return -1, fmt.Errorf("size of %v is unknown", filename)
+	}
+	return resp.ContentLength, nil
+}
+
+// LastModified returns the time of the last modification of the file from
+// the Last-Modified header of a HEAD request.
+func (f *fs) LastModified(ctx context.Context, filename string) (time.Time, error) {
+	resp, err := f.head(ctx, filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp, filename); err != nil {
+		return time.Time{}, err
+	}
+	header := resp.Header.Get("Last-Modified")
+	if header == "" {
+		return time.Time{}, fmt.Errorf("last modification time of %v is unknown", filename)
+	}
+	return nethttp.ParseTime(header)
+}
+
+func (f *fs) head(ctx context.Context, filename string) (*nethttp.Response, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodHead, filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %v: %v", filename, err)
+	}
+	return resp, nil
+}
+
+// checkStatus returns an error if the response isn't successful.
+func checkStatus(resp *nethttp.Response, filename string) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error requesting %v: %v", filename, resp.Status)
+	}
+	return nil
+}
+
+// splitPath splits a URL path into its directory and last segment.
+func splitPath(path string) (string, string) {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+func hasWildcard(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// openRange returns a function that requests the file from an offset.
+func (f *fs) openRange(filename string) fsx.OpenRangeFn {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, filename, nil)
+		if err != nil {
+			return nil, -1, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, -1, fmt.Errorf("error requesting %v: %v", filename, err)
+		}
+
+		switch {
+		case resp.StatusCode == nethttp.StatusPartialContent:
+			return resp.Body, parseContentRangeSize(resp.Header.Get("Content-Range")), nil
+		case resp.StatusCode == nethttp.StatusRequestedRangeNotSatisfiable:
+			// Reading at or past the end of the file.
+			resp.Body.Close()
+			return io.NopCloser(strings.NewReader("")), -1, nil
+		case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+			if offset > 0 {
+				// The server ignored the range, so skip to the offset.
+				if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil && err != io.EOF {
+					resp.Body.Close()
+					return nil, -1, fmt.Errorf("error reading %v: %v", filename, err)
+				}
+			}
+			return resp.Body, resp.ContentLength, nil
+		default:
+			resp.Body.Close()
+			return nil, -1, fmt.Errorf("error requesting %v: %v", filename, resp.Status)
+		}
+	}
+}
+
+// parseContentRangeSize returns the total size from a Content-Range header of
+// the form "bytes start-end/size", or -1 if the size is unknown.
+func parseContentRangeSize(header string) int64 {
+	var start, end, size int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,300 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/textio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+var modTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var files = map[string]string{
+	"/data/a.txt":  "a1\na2\n",
+	"/data/b.txt":  "b1\n",
+	"/data/c.csv":  "c1\n",
+	"/text/x.txt":  "x1\n",
+	"/other/y.txt": "y1\n",
+}
+
+// newServer returns a test server serving the test files, with range
+// support, and listings of the /data/ and /text/ directories. Files under
+// /norange/ are served with ranges ignored.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/data/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/data/" {
+			serveFile(w, r, r.URL.Path)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body>
+<a href="../">Parent</a>
+<a href="a.txt">a.txt</a>
+<a HREF='b.txt?x=1&amp;y=2#top'>b.txt</a>
+<a href="/data/c.csv">c.csv</a>
+<a href="a.txt">a.txt again</a>
+<a href="/other/y.txt">y.txt</a>
+<a href="https://example.com/data/d.txt">d.txt</a>
+</body></html>`)
+	})
+	mux.HandleFunc("/text/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/text/" {
+			serveFile(w, r, r.URL.Path)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "# Files\n\nx.txt\n  z.txt\n")
+	})
+	mux.HandleFunc("/norange/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		r.Header.Del("Range")
+		serveFile(w, r, strings.TrimPrefix(r.URL.Path, "/norange"))
+	})
+	mux.HandleFunc("/other/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		serveFile(w, r, r.URL.Path)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func serveFile(w nethttp.ResponseWriter, r *nethttp.Request, path string) {
+	data, ok := files[path]
+	if !ok {
+		nethttp.NotFound(w, r)
+		return
+	}
+	nethttp.ServeContent(w, r, path, modTime, strings.NewReader(data))
+}
+
+func TestHTTP_FilesystemNew(t *testing.T) {
+	for _, path := range []string{"http://example.com/a.txt", "https://example.com/a.txt"} {
+		c, err := filesystem.New(context.Background(), path)
+		if err != nil {
+			t.Fatalf("filesystem.New(ctx, %q) == %v, want nil", path, err)
+		}
+		if _, ok := c.(*fs); !ok {
+			t.Errorf("filesystem.New(ctx, %q) type == %T, want *http.fs", path, c)
+		}
+	}
+}
+
+func TestHTTP_List(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+	c := New(ctx)
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{"/data/*.txt", []string{"/data/a.txt", "/data/b.txt?x=1&y=2"}},
+		{"/data/*", []string{"/data/a.txt", "/data/b.txt?x=1&y=2", "/data/c.csv"}},
+		{"/data/%3F.csv", []string{"/data/c.csv"}},
+		{"/data/*.json", nil},
+		{"/text/*.txt", []string{"/text/x.txt", "/text/z.txt"}},
+		{"/data/a.txt", []string{"/data/a.txt"}},
+		{"/data/missing.txt", nil},
+	}
+	for _, test := range tests {
+		got, err := c.List(ctx, srv.URL+test.glob)
+		if err != nil {
+			t.Fatalf("List(%q) error = %v, want nil", test.glob, err)
+		}
+		var want []string
+		for _, file := range test.want {
+			want = append(want, srv.URL+file)
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("List(%q) = %v, want %v", test.glob, got, want)
+		}
+	}
+}
+
+func TestHTTP_List_errors(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+	c := New(ctx)
+
+	for _, glob := range []string{"/*/a.txt", "/missing/*.txt"} {
+		if got, err := c.List(ctx, srv.URL+glob); err == nil {
+			t.Errorf("List(%q) = %v, want error", glob, got)
+		}
+	}
+}
+
+func TestHTTP_OpenRead(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+	c := New(ctx)
+
+	for _, dir := range []string{"", "/norange"} {
+		t.Run("dir="+dir, func(t *testing.T) {
+			filename := srv.URL + dir + "/data/a.txt"
+			rc, err := c.OpenRead(ctx, filename)
+			if err != nil {
+				t.Fatalf("OpenRead(%q) error = %v, want nil", filename, err)
+			}
+			defer rc.Close()
+			rs := rc.(io.ReadSeeker)
+
+			seeks := []struct {
+				offset int64
+				whence int
+				want   string
+			}{
+				{3, io.SeekStart, "a2\n"},
+				{1, io.SeekStart, "1\na2\n"},
+				{-2, io.SeekEnd, "2\n"},
+				{6, io.SeekStart, ""},
+				{10, io.SeekStart, ""},
+			}
+			for _, s := range seeks {
+				if _, err := rs.Seek(s.offset, s.whence); err != nil {
+					t.Fatalf("Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+				}
+				got, err := io.ReadAll(rs)
+				if err != nil {
+					t.Fatalf("ReadAll() after Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+				}
+				if string(got) != s.want {
+					t.Errorf("ReadAll() after Seek(%v, %v) = %q, want %q", s.offset, s.whence, got, s.want)
+				}
+			}
+
+			// Seeking relative to the current offset doesn't discard read data.
+			rs.Seek(0, io.SeekStart)
+			buf := make([]byte, 1)
+			rs.Read(buf)
+			if pos, err := rs.Seek(1, io.SeekCurrent); err != nil || pos != 2 {
+				t.Errorf("Seek(1, io.SeekCurrent) = %v, %v, want 2, nil", pos, err)
+			}
+			if got, _ := io.ReadAll(rs); !bytes.Equal(got, []byte("\na2\n")) {
+				t.Errorf("ReadAll() after Seek(1, io.SeekCurrent) = %q, want %q", got, "\na2\n")
+			}
+
+			if _, err := rs.Seek(-1, io.SeekStart); err == nil {
+				t.Errorf("Seek(-1, io.SeekStart) succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestHTTP_OpenRead_lazy(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.Header.Get("Range")))
+		serveFile(w, r, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	filename := srv.URL + "/data/a.txt"
+	rc, err := New(ctx).OpenRead(ctx, filename)
+	if err != nil {
+		t.Fatalf("OpenRead(%q) error = %v, want nil", filename, err)
+	}
+	defer rc.Close()
+	if want := []string{"HEAD"}; !cmp.Equal(requests, want) {
+		t.Errorf("OpenRead(%q) made requests %v, want %v", filename, requests, want)
+	}
+
+	rs := rc.(io.ReadSeeker)
+	if _, err := rs.Seek(-3, io.SeekEnd); err != nil {
+		t.Fatalf("Seek(-3, io.SeekEnd) error = %v, want nil", err)
+	}
+	if got, err := io.ReadAll(rs); err != nil || string(got) != "a2\n" {
+		t.Errorf("ReadAll() = %q, %v, want %q, nil", got, err, "a2\n")
+	}
+	if want := []string{"HEAD", "GET bytes=3-"}; !cmp.Equal(requests, want) {
+		t.Errorf("reading %q made requests %v, want %v", filename, requests, want)
+	}
+}
+
+func TestHTTP_OpenRead_missing(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	filename := srv.URL + "/data/missing.txt"
+	if _, err := New(ctx).OpenRead(ctx, filename); err == nil {
+		t.Errorf("OpenRead(%q) succeeded, want error", filename)
+	}
+}
+
+func TestHTTP_OpenWrite(t *testing.T) {
+	ctx := context.Background()
+
+	filename := "http://example.com/a.txt"
+	if _, err := New(ctx).OpenWrite(ctx, filename); err == nil {
+		t.Errorf("OpenWrite(%q) succeeded, want error", filename)
+	}
+}
+
+func TestHTTP_Size(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+	c := New(ctx)
+
+	size, err := c.Size(ctx, srv.URL+"/data/a.txt")
+	if err != nil || size != 6 {
+		t.Errorf("Size() = %v, %v, want 6, nil", size, err)
+	}
+	if _, err := c.Size(ctx, srv.URL+"/data/missing.txt"); err == nil {
+		t.Errorf("Size() of missing file succeeded, want error")
+	}
+}
+
+func TestHTTP_LastModified(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+	c := New(ctx).(filesystem.LastModifiedGetter)
+
+	got, err := c.LastModified(ctx, srv.URL+"/data/a.txt")
+	if err != nil || !got.Equal(modTime) {
+		t.Errorf("LastModified() = %v, %v, want %v, nil", got, err, modTime)
+	}
+}
+
+func TestHTTP_textioReadSdf(t *testing.T) {
+	srv := newServer(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	lines := textio.ReadSdf(s, srv.URL+"/data/*.txt")
+	passert.Equals(s, lines, "a1", "a2", "b1")
+
+	ptest.RunAndValidate(t, p)
+}
@@ -114,24 +114,54 @@ func (f *fs) listObjectKeys(
 	return objects, nil
 }
 
-// OpenRead returns a new io.ReadCloser to read contents from the file. The caller must call Close
-// on the returned io.ReadCloser when done reading.
+// OpenRead returns a new io.ReadCloser to read contents from the file. The returned reader also
+// implements io.Seeker, which reads the object from the new offset with a ranged request. The
+// caller must call Close on the returned io.ReadCloser when done reading.
 func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
 	bucket, key, err := parseURI(filename)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing S3 uri %s: %v", filename, err)
 	}
 
-	params := &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	}
-	output, err := f.client.GetObject(ctx, params)
+	open := f.openRange(filename, bucket, key)
+	body, size, err := open(ctx, 0)
 	if err != nil {
-		return nil, fmt.Errorf("error getting object %s: %v", filename, err)
+		return nil, err
 	}
+	return fsx.NewOpenedRangeReader(ctx, filename, size, open, body), nil
+}
+
+// openRange returns a function that gets the object from an offset.
+func (f *fs) openRange(filename, bucket, key string) fsx.OpenRangeFn {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+		params := &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		}
+		var optFns []func(*s3.Options)
+		if offset > 0 {
+			params.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+			// The checksum of the object doesn't apply to a part of it.
+			optFns = append(optFns, func(o *s3.Options) {
+				o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+			})
+		}
+		output, err := f.client.GetObject(ctx, params, optFns...)
+		if err != nil {
+			return nil, -1, fmt.Errorf("error getting object %s: %v", filename, err)
+		}
 
-	return output.Body, nil
+		size := int64(-1)
+		if output.ContentRange != nil {
+			var start, end int64
+			if _, err := fmt.Sscanf(*output.ContentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+				size = -1
+			}
+		} else if output.ContentLength != nil {
+			size = *output.ContentLength
+		}
+		return output.Body, size, nil
+	}
 }
 
 // OpenWrite returns a new io.WriteCloser to write contents to the file. The caller must call Close
@@ -18,6 +18,7 @@ package s3
 import (
 	"bytes"
 	"context"
+	"io"
 	"testing"
 	"testing/iotest"
 	"time"
@@ -160,6 +161,54 @@ func Test_fs_OpenRead(t *testing.T) {
 	}
 }
 
+func Test_fs_OpenRead_seek(t *testing.T) {
+	ctx := context.Background()
+	server := newServer(t)
+	client := newClient(ctx, t, server.URL)
+
+	bucket := "bucket"
+	key := "file.txt"
+	content := []byte("0123456789")
+	createBucket(ctx, t, client, bucket)
+	createObject(ctx, t, client, bucket, key, content)
+
+	fileSystem := &fs{client: client}
+	reader, err := fileSystem.OpenRead(ctx, "s3://bucket/file.txt")
+	if err != nil {
+		t.Fatalf("OpenRead() error = %v, want nil", err)
+	}
+	defer reader.Close()
+
+	rs, ok := reader.(io.ReadSeeker)
+	if !ok {
+		t.Fatalf("OpenRead() returned %T, want an io.ReadSeeker", reader)
+	}
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(rs, buf); err != nil || string(buf) != "01" {
+		t.Fatalf("ReadFull() = %q, %v, want %q, nil", buf, err, "01")
+	}
+
+	// Seeking reads the object from the new offset with a ranged request.
+	if got, err := rs.Seek(6, io.SeekStart); err != nil || got != 6 {
+		t.Fatalf("Seek(6, io.SeekStart) = %v, %v, want 6, nil", got, err)
+	}
+	if got, err := io.ReadAll(rs); err != nil || string(got) != "6789" {
+		t.Errorf("ReadAll() after Seek(6, io.SeekStart) = %q, %v, want %q, nil", got, err, "6789")
+	}
+	if got, err := rs.Seek(-3, io.SeekEnd); err != nil || got != 7 {
+		t.Fatalf("Seek(-3, io.SeekEnd) = %v, %v, want 7, nil", got, err)
+	}
+	if got, err := io.ReadAll(rs); err != nil || string(got) != "789" {
+		t.Errorf("ReadAll() after Seek(-3, io.SeekEnd) = %q, %v, want %q, nil", got, err, "789")
+	}
+	if got, err := rs.Seek(2, io.SeekStart); err != nil || got != 2 {
+		t.Fatalf("Seek(2, io.SeekStart) = %v, %v, want 2, nil", got, err)
+	}
+	if got, err := io.ReadAll(rs); err != nil || string(got) != "23456789" {
+		t.Errorf("ReadAll() after Seek(2, io.SeekStart) = %q, %v, want %q, nil", got, err, "23456789")
+	}
+}
+
 func Test_fs_OpenWrite(t *testing.T) {
 	tests := []struct {
 		name     string
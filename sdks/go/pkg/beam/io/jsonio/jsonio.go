@@ -0,0 +1,230 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonio contains transforms for reading and writing files of JSON
+// values separated by newlines, also known as JSON Lines.
+package jsonio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn5x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(beam.X), func(ParseError), error](&readFn{})
+	register.Emitter1[beam.X]()
+	register.Emitter1[ParseError]()
+
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
+}
+
+// ParseError is a line that couldn't be read, output to the dead-letter
+// PCollection returned by Read.
+type ParseError = fileio.ParseError
+
+// Read reads a set of JSON Lines files indicated by the glob pattern and
+// returns the values as a PCollection<t>, decoded with encoding/json, along
+// with a PCollection<ParseError> of the lines that couldn't be decoded. Blank
+// lines are skipped.
+//
+// If t is nil, the type is inferred from the first lines of the first file
+// matching the glob when the pipeline is constructed, and the values are
+// returned as schema rows: structs with a field for each key of the objects,
+// with beam and json tags naming them after it. Nested objects are nested
+// rows and arrays are slices. Booleans, numbers and nested objects are
+// pointers if they are null or missing in some lines. Keys with values of
+// different types are typed as strings, so lines with other values for them
+// are sent to the dead-letter output.
+//
+// Uncompressed files are split into blocks of lines, so that large files can
+// be read in parallel. Files compressed as a whole are decompressed based on
+// their extension or first bytes, and are each read by a single worker.
+func Read(s beam.Scope, glob string, t reflect.Type) (beam.PCollection, beam.PCollection) {
+	s = s.Scope("jsonio.Read")
+	filesystem.ValidateScheme(glob)
+
+	if t == nil {
+		var err error
+		if t, err = inferType(context.Background(), glob); err != nil {
+			panic(fmt.Sprintf("jsonio.Read: %v", err))
+		}
+	}
+
+	fn := &readFn{Type: beam.EncodedType{T: t}}
+	matches := fileio.MatchAll(s, beam.Create(s, glob), fileio.MatchEmptyAllow())
+	files := fileio.ReadMatches(s, matches)
+	return beam.ParDo2(s, fn, files, beam.TypeDefinition{Var: beam.XType, T: t})
+}
+
+// readFn is an SDF that reads the lines of JSON Lines files. Its restriction
+// is a range of byte offsets in the file, and it reads the lines beginning in
+// the range. Compressed files aren't split, and are read whole by the
+// restriction starting at 0.
+type readFn struct {
+	Type beam.EncodedType
+}
+
+// CreateInitialRestriction creates an offset range restriction representing
+// the file's size in bytes.
+func (fn *readFn) CreateInitialRestriction(file fileio.ReadableFile) offsetrange.Restriction {
+	return offsetrange.Restriction{
+		Start: 0,
+		End:   file.Metadata.Size,
+	}
+}
+
+// blockSize is the desired size of each block for initial splits.
+var blockSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits the restriction of uncompressed files into blocks
+// of a predetermined size, with some checks to avoid having small remainders.
+func (fn *readFn) SplitRestriction(file fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	if compressed, err := file.IsCompressed(context.Background()); err != nil || compressed {
+		// Files that can't be opened are left whole, for ProcessElement to
+		// report the error.
+		return []offsetrange.Restriction{rest}
+	}
+	splits := rest.SizedSplits(blockSize)
+	numSplits := len(splits)
+	if numSplits > 1 {
+		last := splits[numSplits-1]
+		if last.End-last.Start <= blockSize/4 {
+			// Last restriction is too small, so merge it with previous one.
+			splits[numSplits-2].End = last.End
+			splits = splits[:numSplits-1]
+		}
+	}
+	return splits
+}
+
+// RestrictionSize returns the size of each restriction as its range.
+func (fn *readFn) RestrictionSize(_ fileio.ReadableFile, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping offsetRange.Trackers for
+// each restriction.
+func (fn *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+// ProcessElement reads the lines beginning within the restriction, emitting
+// the lines that can't be decoded to emitErr. Compressed files are read whole
+// by the restriction starting at 0, since the offsets of their lines may lie
+// beyond the size of the file. Restrictions split from it are left empty.
+func (fn *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X), emitErr func(ParseError)) error {
+	log.Infof(ctx, "Reading JSON from %v", file.Metadata.Path)
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+
+	whole, err := file.IsCompressed(ctx)
+	if err != nil {
+		return err
+	}
+	if whole && (rest.Start != 0 || !rt.TryClaim(int64(0))) {
+		rt.TryClaim(rest.End)
+		return nil
+	}
+
+	fd, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	rd, i, err := fileio.SeekLine(fd, rest.Start)
+	if err == io.EOF {
+		rt.TryClaim(rest.End)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+	}
+
+	for whole || rt.TryClaim(i) {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			v := reflect.New(fn.Type.T)
+			if err := json.Unmarshal(trimmed, v.Interface()); err != nil {
+				emitErr(ParseError{File: file.Metadata.Path, Offset: i, Record: string(line), Error: err.Error()})
+			} else {
+				emit(v.Elem().Interface())
+			}
+		}
+		if err == io.EOF {
+			// Finish claiming restriction before returning to avoid errors.
+			rt.TryClaim(rest.End)
+			return nil
+		}
+		i += int64(len(line))
+	}
+	return nil
+}
+
+// Write writes a PCollection<T> to a file, encoding each element as JSON with
+// encoding/json on its own line. It returns a PCollection<string> with the
+// filename once the file has been written, which can be used to sequence
+// later steps with wait.On.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename string, col beam.PCollection) beam.PCollection {
+	s = s.Scope("jsonio.Write")
+
+	return fileio.WriteFiles(s, filename, NewSink(), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
+}
+
+// NewSink returns a fileio.Sink that writes each element as JSON on its own
+// line.
+func NewSink() fileio.Sink {
+	return &sink{}
+}
+
+type sink struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (s *sink) Open(_ context.Context, w io.Writer) error {
+	s.buf = bufio.NewWriterSize(w, 1<<20) // use 1MB buffer
+	s.enc = json.NewEncoder(s.buf)
+	s.enc.SetEscapeHTML(false)
+	return nil
+}
+
+func (s *sink) Write(_ context.Context, elm any) error {
+	// Encode adds a newline after each value.
+	return s.enc.Encode(elm)
+}
+
+func (s *sink) Flush(_ context.Context) error {
+	return s.buf.Flush()
+}
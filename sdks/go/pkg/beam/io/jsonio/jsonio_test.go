@@ -0,0 +1,229 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonio
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	register.Function1x2(formatJSON)
+	register.Function1x1(formatError)
+}
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+type Event struct {
+	User  string   `json:"user"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const events = `{"user": "a", "count": 1, "tags": ["x", "y"]}
+
+{"user": "b", "count": 2}
+{"user": "c", "count": "three"}
+{"user": "d",
+`
+
+func TestRead(t *testing.T) {
+	path := writeFile(t, "events.jsonl", events)
+
+	p, s := beam.NewPipelineWithRoot()
+	records, errors := Read(s, path, reflect.TypeOf(Event{}))
+	passert.Equals(s, records,
+		Event{User: "a", Count: 1, Tags: []string{"x", "y"}},
+		Event{User: "b", Count: 2},
+	)
+	passert.Equals(s, beam.ParDo(s, formatError, errors),
+		fmt.Sprintf(`%d: "{\"user\": \"c\", \"count\": \"three\"}\n"`, strings.Index(events, `{"user": "c"`)),
+		fmt.Sprintf(`%d: "{\"user\": \"d\",\n"`, strings.Index(events, `{"user": "d"`)),
+	)
+	ptest.RunAndValidate(t, p)
+}
+
+func formatError(e ParseError) string {
+	return fmt.Sprintf("%d: %q", e.Offset, e.Record)
+}
+
+func TestRead_rows(t *testing.T) {
+	path := writeFile(t, "rows.jsonl", `{"name": "a", "n": 1, "loc": {"lat": 1.5, "lng": 2}, "tags": ["x"]}
+{"name": "b", "n": null, "loc": {"lat": 1, "lng": 2.5}, "tags": []}
+`)
+
+	p, s := beam.NewPipelineWithRoot()
+	records, _ := Read(s, path, nil)
+	passert.Equals(s, beam.ParDo(s, formatJSON, records),
+		`{"loc":{"lat":1.5,"lng":2},"n":1,"name":"a","tags":["x"]}`,
+		`{"loc":{"lat":1,"lng":2.5},"n":null,"name":"b","tags":[]}`,
+	)
+	ptest.RunAndValidate(t, p)
+}
+
+func formatJSON(row beam.X) (string, error) {
+	b, err := json.Marshal(row)
+	return string(b), err
+}
+
+func TestRowType(t *testing.T) {
+	path := writeFile(t, "rows.jsonl", `{"name": "a", "n": 1, "x": 1, "mixed": 1, "loc": {"lat": 1.5}}
+{"name": "b", "x": 2.5, "mixed": "m", "loc": null, "tags": [true]}
+`)
+	got, err := inferType(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := reflect.TypeOf(struct {
+		Loc *struct {
+			Lat float64 `beam:"lat" json:"lat"`
+		} `beam:"loc" json:"loc"`
+		Mixed string  `beam:"mixed" json:"mixed"`
+		N     *int64  `beam:"n" json:"n"`
+		Name  string  `beam:"name" json:"name"`
+		X     float64 `beam:"x" json:"x"`
+		Tags  []bool  `beam:"tags" json:"tags"`
+	}{})
+	if got != want {
+		t.Errorf("inferType() = %v, want %v", got, want)
+	}
+}
+
+// collect collects the output of readFn.
+type collect struct {
+	records []Event
+	errors  []ParseError
+}
+
+func (c *collect) emit(x beam.X) {
+	c.records = append(c.records, x.(Event))
+}
+
+func (c *collect) emitErr(e ParseError) {
+	c.errors = append(c.errors, e)
+}
+
+func TestReadFn_split(t *testing.T) {
+	defer func(size int64) { blockSize = size }(blockSize)
+	blockSize = 64
+
+	var data strings.Builder
+	var want []Event
+	for i := 0; i < 20; i++ {
+		e := Event{User: fmt.Sprintf("user%d", i), Count: i}
+		b, _ := json.Marshal(e)
+		data.Write(b)
+		data.WriteByte('\n')
+		want = append(want, e)
+	}
+
+	gz := filepath.Join(t.TempDir(), "events.jsonl.gz")
+	f, err := os.Create(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write([]byte(data.String()))
+	w.Close()
+	f.Close()
+
+	for _, path := range []string{writeFile(t, "events.jsonl", data.String()), gz} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: info.Size()}}
+		fn := &readFn{Type: beam.EncodedType{T: reflect.TypeOf(Event{})}}
+		splits := fn.SplitRestriction(file, fn.CreateInitialRestriction(file))
+		// Compressed files are read whole by a single restriction.
+		if compressed := path == gz; compressed != (len(splits) == 1) {
+			t.Errorf("SplitRestriction(%v) = %v, want a single restriction only if compressed", path, splits)
+		}
+		if got, want := splits[len(splits)-1].End, info.Size(); got != want {
+			t.Errorf("SplitRestriction(%v) ends at %v, want %v", path, got, want)
+		}
+
+		var c collect
+		for _, r := range splits {
+			rt := fn.CreateTracker(r)
+			if err := fn.ProcessElement(context.Background(), rt, file, c.emit, c.emitErr); err != nil {
+				t.Fatalf("ProcessElement(%v) failed: %v", r, err)
+			}
+			if !rt.IsDone() {
+				t.Errorf("ProcessElement(%v) didn't finish restriction", r)
+			}
+		}
+		if len(c.errors) != 0 {
+			t.Errorf("%v: got errors %v", path, c.errors)
+		}
+		sort.Slice(c.records, func(i, j int) bool { return c.records[i].Count < c.records[j].Count })
+		if !reflect.DeepEqual(c.records, want) {
+			t.Errorf("%v: got %v, want %v", path, c.records, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s,
+		Event{User: "a", Count: 1, Tags: []string{"<x>"}},
+		Event{User: "b", Count: 2},
+	)
+	Write(s, path, col)
+	ptest.RunAndValidate(t, p)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	sort.Strings(lines)
+	want := []string{
+		`{"user":"a","count":1,"tags":["<x>"]}`,
+		`{"user":"b","count":2,"tags":null}`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Write() wrote %q, want %q", lines, want)
+	}
+}
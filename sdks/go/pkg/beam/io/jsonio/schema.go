@@ -0,0 +1,230 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+)
+
+// inferLines is the number of lines used to infer the type of values.
+const inferLines = 1000
+
+// inferType returns the row type of the first file matching the glob.
+func inferType(ctx context.Context, glob string) (reflect.Type, error) {
+	fs, err := filesystem.New(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %v to infer the schema from", glob)
+	}
+	sort.Strings(files)
+
+	fd, err := (fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: files[0]}}).Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	rd := bufio.NewReader(fd)
+
+	root := &kind{}
+	for n := 0; n < inferLines; {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading %v: %v", files[0], err)
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			n++
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.UseNumber()
+			var v any
+			// Lines that can't be decoded are sent to the dead-letter output
+			// when reading.
+			if dec.Decode(&v) == nil {
+				root.observe(v)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if root.kind != reflect.Struct {
+		return nil, fmt.Errorf("%v doesn't have JSON objects to infer the schema from", files[0])
+	}
+	return root.rowType(), nil
+}
+
+// kind accumulates the kind of the values at a position in JSON objects.
+type kind struct {
+	// kind is Invalid if there were only nulls, and Interface if there were
+	// values of different kinds.
+	kind reflect.Kind
+	// count is the number of values other than nulls.
+	count int
+	null  bool
+
+	// keys are the keys of objects in order of appearance, and in sorted
+	// order within an object.
+	keys   []string
+	fields map[string]*kind
+	// elem is the kind of the elements of arrays.
+	elem *kind
+}
+
+// observe updates the kind with a value decoded with json.Decoder.UseNumber.
+func (k *kind) observe(v any) {
+	if v == nil {
+		k.null = true
+		return
+	}
+	k.count++
+	var vk reflect.Kind
+	switch v := v.(type) {
+	case bool:
+		vk = reflect.Bool
+	case json.Number:
+		vk = reflect.Int64
+		if _, err := v.Int64(); err != nil {
+			vk = reflect.Float64
+		}
+	case string:
+		vk = reflect.String
+	case map[string]any:
+		vk = reflect.Struct
+		if k.fields == nil {
+			k.fields = make(map[string]*kind)
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// Keys are sorted, since the order of the object is lost.
+		sort.Strings(keys)
+		for _, key := range keys {
+			f, ok := k.fields[key]
+			if !ok {
+				f = &kind{}
+				k.fields[key] = f
+				k.keys = append(k.keys, key)
+			}
+			f.observe(v[key])
+		}
+	case []any:
+		vk = reflect.Slice
+		if k.elem == nil {
+			k.elem = &kind{}
+		}
+		for _, e := range v {
+			k.elem.observe(e)
+		}
+	}
+
+	switch {
+	case k.kind == reflect.Invalid || k.kind == vk:
+		k.kind = vk
+	case (k.kind == reflect.Int64 && vk == reflect.Float64) || (k.kind == reflect.Float64 && vk == reflect.Int64):
+		k.kind = reflect.Float64
+	default:
+		k.kind = reflect.Interface
+	}
+}
+
+// goType returns the type of values of the kind. Values that are null or
+// missing in some objects are pointers, unless they are strings or arrays.
+func (k *kind) goType(parent *kind) reflect.Type {
+	var t reflect.Type
+	switch k.kind {
+	case reflect.Bool:
+		t = reflect.TypeOf(false)
+	case reflect.Int64:
+		t = reflect.TypeOf(int64(0))
+	case reflect.Float64:
+		t = reflect.TypeOf(float64(0))
+	case reflect.Struct:
+		t = k.rowType()
+	case reflect.Slice:
+		return reflect.SliceOf(k.elem.goType(nil))
+	default:
+		return reflect.TypeOf("")
+	}
+	if k.null || (parent != nil && k.count < parent.count) {
+		t = reflect.PointerTo(t)
+	}
+	return t
+}
+
+// rowType returns a struct type with a field for each key of the objects,
+// named after it.
+func (k *kind) rowType() reflect.Type {
+	fields := make([]reflect.StructField, len(k.keys))
+	used := make(map[string]bool)
+	for i, key := range k.keys {
+		name := fieldName(key, i)
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		fields[i] = reflect.StructField{
+			Name: name,
+			Type: k.fields[key].goType(k),
+			Tag:  reflect.StructTag(fmt.Sprintf("beam:%q json:%q", key, key)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// fieldName returns an exported Go identifier for the i-th key.
+func fieldName(key string, i int) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" {
+		return fmt.Sprintf("Field%d", i+1)
+	}
+	if !unicode.IsUpper([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
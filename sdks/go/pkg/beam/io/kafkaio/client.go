@@ -0,0 +1,117 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is a header of a Kafka record. Keys of headers may repeat.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// ConsumerRecord is a record read from a partition of a Kafka topic.
+type ConsumerRecord struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+	// LogAppendTime is true if Timestamp is the time the broker appended the
+	// record to the partition, rather than the time the producer created it.
+	LogAppendTime bool
+}
+
+// ProducerRecord is a record to be written to a Kafka topic. The partition
+// it's written to is chosen from its key.
+type ProducerRecord struct {
+	Key     []byte
+	Value   []byte
+	Headers []Header
+	// Timestamp is the creation time of the record. If zero, it is the time
+	// the record is produced.
+	Timestamp time.Time
+}
+
+// Client is a connection to a Kafka cluster. It must be safe for concurrent
+// use.
+type Client interface {
+	// Partitions returns the partitions of a topic.
+	Partitions(ctx context.Context, topic string) ([]int32, error)
+	// Offsets returns the offset of the first record of a partition and its
+	// high watermark, which is the offset the next record will have.
+	Offsets(ctx context.Context, topic string, partition int32) (start, end int64, err error)
+	// OffsetForTime returns the offset of the first record of a partition
+	// with a timestamp at or after t, or its high watermark if there is none.
+	OffsetForTime(ctx context.Context, topic string, partition int32, t time.Time) (int64, error)
+	// Fetch returns up to max records of a partition starting at offset, in
+	// order. It may wait briefly for records to arrive, and returns no records
+	// if none do. It may also return records before offset.
+	Fetch(ctx context.Context, topic string, partition int32, offset int64, max int) ([]ConsumerRecord, error)
+	// CommittedOffset returns the offset committed by a consumer group for a
+	// partition, or -1 if there is none.
+	CommittedOffset(ctx context.Context, group, topic string, partition int32) (int64, error)
+	// Commit commits the offset of the next record a consumer group reads from
+	// a partition.
+	Commit(ctx context.Context, group, topic string, partition int32, offset int64) error
+	// Produce writes records to a topic, returning once they have been
+	// acknowledged.
+	Produce(ctx context.Context, topic string, records []ProducerRecord) error
+	// Close closes the connection.
+	Close() error
+}
+
+// Driver creates a Client connecting to a Kafka cluster with the given
+// bootstrap servers and configuration properties.
+type Driver func(ctx context.Context, servers []string, config map[string]string) (Client, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a driver available by name to Read, Write and Dial. It
+// panics if a driver is already registered with the name.
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if _, ok := drivers[name]; ok {
+		panic(fmt.Sprintf("kafkaio: driver %v already registered", name))
+	}
+	drivers[name] = driver
+}
+
+// Dial creates a Client with the named driver, connecting to the Kafka
+// cluster with the given comma-separated bootstrap servers.
+func Dial(ctx context.Context, driver, servers string, config map[string]string) (Client, error) {
+	driversMu.RLock()
+	dial, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("kafkaio: driver %v not registered", driver)
+	}
+
+	return dial(ctx, strings.Split(servers, ","), config)
+}
@@ -0,0 +1,57 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafkaio contains transforms for reading from and writing to Apache
+// Kafka (https://kafka.apache.org/) natively in Go, without the Java expansion
+// service needed by the cross-language transforms in io/xlang/kafkaio.
+//
+// The transforms connect to Kafka with a Client created by a driver, which is
+// registered by name with Register, in the same way as database/sql drivers.
+// DefaultDriver connects to Kafka clusters and is registered by this package.
+// Package memkafka registers an in-memory cluster for unit tests only.
+package kafkaio
+
+import (
+	"context"
+	"fmt"
+)
+
+type kafkaFn struct {
+	Driver  string
+	Servers string
+	Config  map[string]string
+	client  Client
+}
+
+func (fn *kafkaFn) Setup(ctx context.Context) error {
+	if fn.client != nil {
+		return nil
+	}
+
+	client, err := Dial(ctx, fn.Driver, fn.Servers, fn.Config)
+	if err != nil {
+		return fmt.Errorf("error connecting to Kafka: %v", err)
+	}
+	fn.client = client
+
+	return nil
+}
+
+func (fn *kafkaFn) Teardown() error {
+	if fn.client == nil {
+		return nil
+	}
+	return fn.client.Close()
+}
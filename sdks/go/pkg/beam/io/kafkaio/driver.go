@@ -0,0 +1,342 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// DefaultDriver is the name of the driver registered by this package, which
+// connects to Kafka clusters with the franz-go client
+// (https://github.com/twmb/franz-go).
+//
+// The driver supports the following configuration properties:
+//   - client.id: the client ID sent to the brokers.
+//   - security.protocol: PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL. Defaults to PLAINTEXT.
+//   - sasl.mechanism: PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512. Defaults to PLAIN.
+//   - sasl.username, sasl.password: the credentials for SASL authentication.
+const DefaultDriver = "kafka"
+
+// fetchWait is how long Fetch waits for records to arrive.
+const fetchWait = 500 * time.Millisecond
+
+func init() {
+	Register(DefaultDriver, dialKafka)
+}
+
+// kafkaClient is a Client backed by a franz-go client. Records are fetched by
+// a consumer per partition, which is kept so that fetching the records
+// following the last ones fetched uses the records it has buffered.
+type kafkaClient struct {
+	opts  []kgo.Opt
+	cl    *kgo.Client
+	admin *kadm.Client
+
+	mu        sync.Mutex
+	consumers map[TopicPartition]*consumer
+}
+
+// consumer consumes a single partition.
+type consumer struct {
+	mu   sync.Mutex
+	cl   *kgo.Client
+	next int64
+}
+
+func dialKafka(_ context.Context, servers []string, config map[string]string) (Client, error) {
+	opts, err := kafkaOpts(servers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkaClient{
+		opts:      opts,
+		cl:        cl,
+		admin:     kadm.NewClient(cl),
+		consumers: make(map[TopicPartition]*consumer),
+	}, nil
+}
+
+// kafkaOpts returns the options of the franz-go clients for the bootstrap
+// servers and configuration properties.
+func kafkaOpts(servers []string, config map[string]string) ([]kgo.Opt, error) {
+	opts := []kgo.Opt{kgo.SeedBrokers(servers...)}
+
+	var useTLS, useSASL bool
+	mechanism := "PLAIN"
+	var username, password string
+	for k, v := range config {
+		switch k {
+		case "client.id":
+			opts = append(opts, kgo.ClientID(v))
+		case "security.protocol":
+			switch v {
+			case "PLAINTEXT":
+			case "SSL":
+				useTLS = true
+			case "SASL_PLAINTEXT":
+				useSASL = true
+			case "SASL_SSL":
+				useTLS, useSASL = true, true
+			default:
+				return nil, fmt.Errorf("unsupported security.protocol %v", v)
+			}
+		case "sasl.mechanism":
+			mechanism = v
+		case "sasl.username":
+			username = v
+		case "sasl.password":
+			password = v
+		default:
+			return nil, fmt.Errorf("unsupported configuration property %v", k)
+		}
+	}
+
+	if useTLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{}))
+	}
+	if useSASL {
+		var m sasl.Mechanism
+		switch mechanism {
+		case "PLAIN":
+			m = plain.Auth{User: username, Pass: password}.AsMechanism()
+		case "SCRAM-SHA-256":
+			m = scram.Auth{User: username, Pass: password}.AsSha256Mechanism()
+		case "SCRAM-SHA-512":
+			m = scram.Auth{User: username, Pass: password}.AsSha512Mechanism()
+		default:
+			return nil, fmt.Errorf("unsupported sasl.mechanism %v", mechanism)
+		}
+		opts = append(opts, kgo.SASL(m))
+	}
+
+	return opts, nil
+}
+
+func (c *kafkaClient) Partitions(ctx context.Context, topic string) ([]int32, error) {
+	topics, err := c.admin.ListTopics(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("topic %v not found", topic)
+	}
+	if t.Err != nil {
+		return nil, t.Err
+	}
+	return t.Partitions.Numbers(), nil
+}
+
+func (c *kafkaClient) Offsets(ctx context.Context, topic string, partition int32) (start, end int64, err error) {
+	starts, err := c.admin.ListStartOffsets(ctx, topic)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start, err = listedOffset(starts, topic, partition); err != nil {
+		return 0, 0, err
+	}
+
+	ends, err := c.admin.ListEndOffsets(ctx, topic)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end, err = listedOffset(ends, topic, partition); err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+func (c *kafkaClient) OffsetForTime(ctx context.Context, topic string, partition int32, t time.Time) (int64, error) {
+	offsets, err := c.admin.ListOffsetsAfterMilli(ctx, t.UnixMilli(), topic)
+	if err != nil {
+		return 0, err
+	}
+	return listedOffset(offsets, topic, partition)
+}
+
+// listedOffset returns the offset of a partition listed by the admin client.
+func listedOffset(offsets kadm.ListedOffsets, topic string, partition int32) (int64, error) {
+	o, ok := offsets.Lookup(topic, partition)
+	if !ok {
+		return 0, fmt.Errorf("partition %v of topic %v not found", partition, topic)
+	}
+	if o.Err != nil {
+		return 0, o.Err
+	}
+	return o.Offset, nil
+}
+
+func (c *kafkaClient) Fetch(
+	ctx context.Context,
+	topic string,
+	partition int32,
+	offset int64,
+	max int,
+) ([]ConsumerRecord, error) {
+	cons, err := c.consumer(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	cons.mu.Lock()
+	defer cons.mu.Unlock()
+
+	if cons.next != offset {
+		cons.cl.SetOffsets(map[string]map[int32]kgo.EpochOffset{
+			topic: {partition: {Epoch: -1, Offset: offset}},
+		})
+		cons.next = offset
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, fetchWait)
+	defer cancel()
+	fetches := cons.cl.PollRecords(pollCtx, max)
+	for _, fe := range fetches.Errors() {
+		if errors.Is(fe.Err, context.DeadlineExceeded) && ctx.Err() == nil {
+			continue
+		}
+		return nil, fe.Err
+	}
+
+	var records []ConsumerRecord
+	fetches.EachRecord(func(r *kgo.Record) {
+		records = append(records, toConsumerRecord(r))
+	})
+	if len(records) > 0 {
+		cons.next = records[len(records)-1].Offset + 1
+	}
+	return records, nil
+}
+
+// consumer returns the consumer of a partition, creating it to consume from
+// offset if there is none.
+func (c *kafkaClient) consumer(topic string, partition int32, offset int64) (*consumer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tp := TopicPartition{Topic: topic, Partition: partition}
+	if cons, ok := c.consumers[tp]; ok {
+		return cons, nil
+	}
+
+	opts := append([]kgo.Opt{
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{
+			topic: {partition: kgo.NewOffset().At(offset)},
+		}),
+		kgo.FetchMaxWait(fetchWait),
+	}, c.opts...)
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cons := &consumer{cl: cl, next: offset}
+	c.consumers[tp] = cons
+	return cons, nil
+}
+
+func toConsumerRecord(r *kgo.Record) ConsumerRecord {
+	var headers []Header
+	for _, h := range r.Headers {
+		headers = append(headers, Header{Key: h.Key, Value: h.Value})
+	}
+
+	return ConsumerRecord{
+		Topic:         r.Topic,
+		Partition:     r.Partition,
+		Offset:        r.Offset,
+		Key:           r.Key,
+		Value:         r.Value,
+		Headers:       headers,
+		Timestamp:     r.Timestamp,
+		LogAppendTime: r.Attrs.TimestampType() == 1,
+	}
+}
+
+func (c *kafkaClient) CommittedOffset(ctx context.Context, group, topic string, partition int32) (int64, error) {
+	offsets, err := c.admin.FetchOffsets(ctx, group)
+	if errors.Is(err, kerr.GroupIDNotFound) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	o, ok := offsets.Lookup(topic, partition)
+	if !ok {
+		return -1, nil
+	}
+	if o.Err != nil {
+		return 0, o.Err
+	}
+	return o.At, nil
+}
+
+func (c *kafkaClient) Commit(ctx context.Context, group, topic string, partition int32, offset int64) error {
+	var offsets kadm.Offsets
+	offsets.AddOffset(topic, partition, offset, -1)
+	return c.admin.CommitAllOffsets(ctx, group, offsets)
+}
+
+func (c *kafkaClient) Produce(ctx context.Context, topic string, records []ProducerRecord) error {
+	rs := make([]*kgo.Record, len(records))
+	for i, r := range records {
+		var headers []kgo.RecordHeader
+		for _, h := range r.Headers {
+			headers = append(headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+		rs[i] = &kgo.Record{
+			Topic:     topic,
+			Key:       r.Key,
+			Value:     r.Value,
+			Headers:   headers,
+			Timestamp: r.Timestamp,
+		}
+	}
+
+	return c.cl.ProduceSync(ctx, rs...).FirstErr()
+}
+
+func (c *kafkaClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cons := range c.consumers {
+		cons.cl.Close()
+	}
+	c.consumers = nil
+	c.cl.Close()
+	return nil
+}
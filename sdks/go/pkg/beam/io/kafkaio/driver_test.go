@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"testing"
+)
+
+func Test_kafkaOpts(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "no properties",
+			want: 1,
+		},
+		{
+			name:   "plaintext",
+			config: map[string]string{"client.id": "beam", "security.protocol": "PLAINTEXT"},
+			want:   2,
+		},
+		{
+			name:   "SSL",
+			config: map[string]string{"security.protocol": "SSL"},
+			want:   2,
+		},
+		{
+			name: "SASL over SSL",
+			config: map[string]string{
+				"security.protocol": "SASL_SSL",
+				"sasl.mechanism":    "SCRAM-SHA-512",
+				"sasl.username":     "user",
+				"sasl.password":     "password",
+			},
+			want: 3,
+		},
+		{
+			name:    "unsupported protocol",
+			config:  map[string]string{"security.protocol": "KERBEROS"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported mechanism",
+			config:  map[string]string{"security.protocol": "SASL_PLAINTEXT", "sasl.mechanism": "GSSAPI"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported property",
+			config:  map[string]string{"auto.offset.reset": "earliest"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := kafkaOpts([]string{"localhost:9092"}, test.config)
+			if test.wantErr {
+				if err == nil {
+					t.Error("kafkaOpts() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("kafkaOpts() failed: %v", err)
+			}
+			if len(opts) != test.want {
+				t.Errorf("kafkaOpts() returned %d options, want %d", len(opts), test.want)
+			}
+		})
+	}
+}
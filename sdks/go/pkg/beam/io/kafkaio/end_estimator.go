@@ -0,0 +1,52 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"sync"
+)
+
+// endEstimator estimates the end of the offsets of a partition as its high
+// watermark.
+type endEstimator struct {
+	client    Client
+	topic     string
+	partition int32
+
+	mu  sync.Mutex
+	end int64
+}
+
+func newEndEstimator(client Client, topic string, partition int32) *endEstimator {
+	return &endEstimator{
+		client:    client,
+		topic:     topic,
+		partition: partition,
+	}
+}
+
+// Estimate returns the high watermark of the partition, or the last one
+// known if it can't be retrieved.
+func (e *endEstimator) Estimate() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, end, err := e.client.Offsets(context.Background(), e.topic, e.partition); err == nil {
+		e.end = end
+	}
+	return e.end
+}
@@ -0,0 +1,296 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memkafka provides an in-memory Kafka cluster for testing, registered
+// as the kafkaio driver "memkafka". Clusters are identified by their bootstrap
+// servers, so that tests using different servers don't share topics. It is
+// meant for unit tests; pipelines connect to Kafka with kafkaio.DefaultDriver.
+package memkafka
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+)
+
+func init() {
+	kafkaio.Register("memkafka", dial)
+}
+
+var (
+	mu       sync.Mutex
+	clusters = make(map[string]*cluster)
+)
+
+type topic struct {
+	partitions    [][]kafkaio.ConsumerRecord
+	logAppendTime bool
+	// next is the partition of the next record without a key.
+	next int
+}
+
+type commitKey struct {
+	group     string
+	topic     string
+	partition int32
+}
+
+type cluster struct {
+	mu        sync.Mutex
+	topics    map[string]*topic
+	committed map[commitKey]int64
+}
+
+func get(servers string) *cluster {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := clusters[servers]
+	if !ok {
+		c = &cluster{
+			topics:    make(map[string]*topic),
+			committed: make(map[commitKey]int64),
+		}
+		clusters[servers] = c
+	}
+	return c
+}
+
+// CreateTopic creates a topic with a number of partitions in the cluster with
+// the given bootstrap servers. If logAppendTime is true, records are
+// timestamped with the time they're appended rather than their create time.
+func CreateTopic(servers, name string, partitions int, logAppendTime bool) error {
+	if partitions <= 0 {
+		return fmt.Errorf("topic %v must have at least one partition", name)
+	}
+
+	c := get(servers)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[name]; ok {
+		return fmt.Errorf("topic %v already exists", name)
+	}
+	c.topics[name] = &topic{
+		partitions:    make([][]kafkaio.ConsumerRecord, partitions),
+		logAppendTime: logAppendTime,
+	}
+	return nil
+}
+
+// Produce appends records to a topic in the cluster with the given bootstrap
+// servers.
+func Produce(servers, topic string, records ...kafkaio.ProducerRecord) error {
+	return get(servers).produce(topic, records)
+}
+
+// Records returns the records of a partition of a topic in the cluster with
+// the given bootstrap servers.
+func Records(servers, topic string, partition int32) []kafkaio.ConsumerRecord {
+	c := get(servers)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.topics[topic]
+	if !ok || int(partition) >= len(t.partitions) {
+		return nil
+	}
+	return append([]kafkaio.ConsumerRecord(nil), t.partitions[partition]...)
+}
+
+// Committed returns the offset committed by a consumer group for a partition
+// of a topic in the cluster with the given bootstrap servers, or -1 if there
+// is none.
+func Committed(servers, group, topic string, partition int32) int64 {
+	c := get(servers)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if offset, ok := c.committed[commitKey{group, topic, partition}]; ok {
+		return offset
+	}
+	return -1
+}
+
+// Reset removes all topics and committed offsets from the cluster with the
+// given bootstrap servers.
+func Reset(servers string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(clusters, servers)
+}
+
+func (c *cluster) topic(name string) (*topic, error) {
+	t, ok := c.topics[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown topic %v", name)
+	}
+	return t, nil
+}
+
+func (c *cluster) partition(name string, partition int32) ([]kafkaio.ConsumerRecord, error) {
+	t, err := c.topic(name)
+	if err != nil {
+		return nil, err
+	}
+	if partition < 0 || int(partition) >= len(t.partitions) {
+		return nil, fmt.Errorf("unknown partition %v of topic %v", partition, name)
+	}
+	return t.partitions[partition], nil
+}
+
+func (c *cluster) produce(name string, records []kafkaio.ProducerRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := c.topic(name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, r := range records {
+		p := t.next
+		if r.Key == nil {
+			t.next = (t.next + 1) % len(t.partitions)
+		} else {
+			h := fnv.New32a()
+			h.Write(r.Key)
+			p = int(h.Sum32() % uint32(len(t.partitions)))
+		}
+
+		ts := r.Timestamp
+		if t.logAppendTime || ts.IsZero() {
+			ts = now
+		}
+		t.partitions[p] = append(t.partitions[p], kafkaio.ConsumerRecord{
+			Topic:         name,
+			Partition:     int32(p),
+			Offset:        int64(len(t.partitions[p])),
+			Key:           r.Key,
+			Value:         r.Value,
+			Headers:       r.Headers,
+			Timestamp:     ts,
+			LogAppendTime: t.logAppendTime,
+		})
+	}
+	return nil
+}
+
+// client is a kafkaio.Client of an in-memory cluster.
+type client struct {
+	c *cluster
+}
+
+func dial(_ context.Context, servers []string, _ map[string]string) (kafkaio.Client, error) {
+	return &client{c: get(strings.Join(servers, ","))}, nil
+}
+
+func (cl *client) Partitions(_ context.Context, name string) ([]int32, error) {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	t, err := cl.c.topic(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []int32
+	for p := range t.partitions {
+		partitions = append(partitions, int32(p))
+	}
+	return partitions, nil
+}
+
+func (cl *client) Offsets(_ context.Context, name string, partition int32) (start, end int64, err error) {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	records, err := cl.c.partition(name, partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	return 0, int64(len(records)), nil
+}
+
+func (cl *client) OffsetForTime(_ context.Context, name string, partition int32, t time.Time) (int64, error) {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	records, err := cl.c.partition(name, partition)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		if !r.Timestamp.Before(t) {
+			return r.Offset, nil
+		}
+	}
+	return int64(len(records)), nil
+}
+
+func (cl *client) Fetch(_ context.Context, name string, partition int32, offset int64, max int) ([]kafkaio.ConsumerRecord, error) {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	records, err := cl.c.partition(name, partition)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset >= int64(len(records)) {
+		return nil, nil
+	}
+
+	records = records[offset:]
+	if len(records) > max {
+		records = records[:max]
+	}
+	return append([]kafkaio.ConsumerRecord(nil), records...), nil
+}
+
+func (cl *client) CommittedOffset(_ context.Context, group, name string, partition int32) (int64, error) {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	if offset, ok := cl.c.committed[commitKey{group, name, partition}]; ok {
+		return offset, nil
+	}
+	return -1, nil
+}
+
+func (cl *client) Commit(_ context.Context, group, name string, partition int32, offset int64) error {
+	cl.c.mu.Lock()
+	defer cl.c.mu.Unlock()
+
+	if _, err := cl.c.partition(name, partition); err != nil {
+		return err
+	}
+	cl.c.committed[commitKey{group, name, partition}] = offset
+	return nil
+}
+
+func (cl *client) Produce(_ context.Context, name string, records []kafkaio.ProducerRecord) error {
+	return cl.c.produce(name, records)
+}
+
+func (cl *client) Close() error {
+	return nil
+}
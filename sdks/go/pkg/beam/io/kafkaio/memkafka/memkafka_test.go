@@ -0,0 +1,97 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memkafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	servers := "TestClient"
+	defer Reset(servers)
+	if err := CreateTopic(servers, "topic", 2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := kafkaio.Dial(ctx, "memkafka", servers, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var records []kafkaio.ProducerRecord
+	for i := 0; i < 4; i++ {
+		records = append(records, kafkaio.ProducerRecord{Key: []byte("key"), Value: []byte{byte(i)}, Timestamp: created.Add(time.Duration(i) * time.Second)})
+	}
+	if err := client.Produce(ctx, "topic", records); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records with the same key are written to the same partition.
+	p := int32(0)
+	if len(Records(servers, "topic", p)) == 0 {
+		p = 1
+	}
+	if got := len(Records(servers, "topic", p)); got != 4 {
+		t.Fatalf("partition %v has %v records, want 4", p, got)
+	}
+
+	if start, end, err := client.Offsets(ctx, "topic", p); err != nil || start != 0 || end != 4 {
+		t.Errorf("Offsets() = %v, %v, %v, want 0, 4, nil", start, end, err)
+	}
+	if offset, err := client.OffsetForTime(ctx, "topic", p, created.Add(1500*time.Millisecond)); err != nil || offset != 2 {
+		t.Errorf("OffsetForTime() = %v, %v, want 2, nil", offset, err)
+	}
+
+	got, err := client.Fetch(ctx, "topic", p, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Offset != 1 || got[1].Offset != 2 || !got[0].Timestamp.Equal(created.Add(time.Second)) {
+		t.Errorf("Fetch() = %v, want records at offsets 1 and 2", got)
+	}
+
+	if err := client.Commit(ctx, "group", "topic", p, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := Committed(servers, "group", "topic", p); got != 3 {
+		t.Errorf("Committed() = %v, want 3", got)
+	}
+}
+
+func TestCreateTopic_logAppendTime(t *testing.T) {
+	servers := "TestCreateTopic_logAppendTime"
+	defer Reset(servers)
+	if err := CreateTopic(servers, "topic", 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if err := Produce(servers, "topic", kafkaio.ProducerRecord{Value: []byte("v"), Timestamp: before.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Records(servers, "topic", 0)[0]
+	if !r.LogAppendTime || r.Timestamp.Before(before) {
+		t.Errorf("record timestamp = %v, log append time %v, want log append time after %v", r.Timestamp, r.LogAppendTime, before)
+	}
+}
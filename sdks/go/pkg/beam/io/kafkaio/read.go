@@ -0,0 +1,396 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn3x1[context.Context, []byte, func(TopicPartition), error](&partitionsFn{})
+	register.DoFn6x2[
+		context.Context, *watermarkEstimator, beam.BundleFinalization, *sdf.LockRTracker,
+		TopicPartition, func(beam.EventTime, ConsumerRecord), sdf.ProcessContinuation, error,
+	](
+		&readFn{},
+	)
+	register.Emitter1[TopicPartition]()
+	register.Emitter2[beam.EventTime, ConsumerRecord]()
+	beam.RegisterType(reflect.TypeOf((*TopicPartition)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*ConsumerRecord)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*partitionRestriction)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*partitionTracker)(nil)))
+}
+
+const (
+	defaultFetchSize   = 500
+	assumedLag         = 1 * time.Second
+	resumeDelay        = 1 * time.Second
+	checkpointInterval = 10 * time.Second
+	commitTimeout      = 5 * time.Minute
+)
+
+// TopicPartition identifies a partition of a Kafka topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// Read reads records from the partitions of Kafka topics and returns a
+// PCollection<ConsumerRecord>. Each partition is read by a splittable DoFn,
+// which claims the offsets of its records. The partitions are listed when the
+// pipeline starts.
+//
+// The driver is usually DefaultDriver, or must have been registered with
+// Register, and servers is a comma-separated list of bootstrap servers.
+//
+// Read takes a variable number of ReadOptionFn to configure the read operation:
+//   - ConsumerConfigs: properties passed to the driver. Defaults to none.
+//   - ProcessingTimePolicy, LogAppendTimePolicy, CreateTimePolicy: which time to use as the
+//     event time of the records. Defaults to the processing time.
+//   - FetchSize: the maximum number of records to retrieve at a time. Defaults to 500.
+//   - FromEarliest: whether to read partitions without a committed offset from their first
+//     record rather than from their high watermark. Defaults to false.
+//   - StartTime: the timestamp of the first records to read. Defaults to none.
+//   - StopTime: the timestamp at which to stop reading, making the read bounded. Defaults to
+//     none.
+//   - ConsumerGroup: the consumer group whose committed offsets to start reading from.
+//     Defaults to none.
+//   - CommitOffsetsInFinalize: whether to commit the offsets of the records read once their
+//     bundles are finalized. Defaults to false.
+func Read(
+	s beam.Scope,
+	driver string,
+	servers string,
+	topics []string,
+	opts ...ReadOptionFn,
+) beam.PCollection {
+	s = s.Scope("kafkaio.Read")
+
+	option := &readOption{
+		TimePolicy: processingTimePolicy,
+		FetchSize:  defaultFetchSize,
+	}
+
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("kafkaio.Read: invalid option: %v", err))
+		}
+	}
+	if option.CommitOffsets && option.Group == "" {
+		panic("kafkaio.Read: committing offsets requires a consumer group")
+	}
+	if len(topics) == 0 {
+		panic("kafkaio.Read: no topics to read from")
+	}
+
+	fn := kafkaFn{Driver: driver, Servers: servers, Config: option.Config}
+	imp := beam.Impulse(s)
+	partitions := beam.ParDo(s, &partitionsFn{kafkaFn: fn, Topics: topics}, imp)
+	partitions = beam.Reshuffle(s, partitions)
+	return beam.ParDo(s, newReadFn(fn, option), partitions)
+}
+
+// partitionsFn emits the partitions of topics.
+type partitionsFn struct {
+	kafkaFn
+	Topics []string
+}
+
+func (fn *partitionsFn) ProcessElement(ctx context.Context, _ []byte, emit func(TopicPartition)) error {
+	for _, topic := range fn.Topics {
+		partitions, err := fn.client.Partitions(ctx, topic)
+		if err != nil {
+			return fmt.Errorf("error listing partitions of %v: %v", topic, err)
+		}
+
+		for _, p := range partitions {
+			emit(TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+
+	return nil
+}
+
+type readFn struct {
+	kafkaFn
+	TimePolicy    timePolicy
+	MaxDelay      time.Duration
+	FetchSize     int
+	Earliest      bool
+	StartTime     time.Time
+	StopTime      time.Time
+	Group         string
+	CommitOffsets bool
+}
+
+func newReadFn(fn kafkaFn, option *readOption) *readFn {
+	return &readFn{
+		kafkaFn:       fn,
+		TimePolicy:    option.TimePolicy,
+		MaxDelay:      option.MaxDelay,
+		FetchSize:     option.FetchSize,
+		Earliest:      option.Earliest,
+		StartTime:     option.StartTime,
+		StopTime:      option.StopTime,
+		Group:         option.Group,
+		CommitOffsets: option.CommitOffsets,
+	}
+}
+
+func (fn *readFn) CreateInitialRestriction(
+	ctx context.Context,
+	tp TopicPartition,
+) (partitionRestriction, error) {
+	if err := fn.Setup(ctx); err != nil {
+		return partitionRestriction{}, err
+	}
+
+	start, err := fn.startOffset(ctx, tp)
+	if err != nil {
+		return partitionRestriction{}, fmt.Errorf("error getting start offset of %v: %v", tp, err)
+	}
+
+	return partitionRestriction{
+		Topic:     tp.Topic,
+		Partition: tp.Partition,
+		Start:     start,
+		End:       math.MaxInt64,
+	}, nil
+}
+
+// startOffset returns the offset of the start time if set, or else the offset
+// committed by the consumer group if any, or else the first offset or the
+// high watermark of the partition.
+func (fn *readFn) startOffset(ctx context.Context, tp TopicPartition) (int64, error) {
+	if !fn.StartTime.IsZero() {
+		return fn.client.OffsetForTime(ctx, tp.Topic, tp.Partition, fn.StartTime)
+	}
+
+	if fn.Group != "" {
+		offset, err := fn.client.CommittedOffset(ctx, fn.Group, tp.Topic, tp.Partition)
+		if err != nil {
+			return 0, err
+		}
+		if offset >= 0 {
+			return offset, nil
+		}
+	}
+
+	start, end, err := fn.client.Offsets(ctx, tp.Topic, tp.Partition)
+	if err != nil {
+		return 0, err
+	}
+	if fn.Earliest {
+		return start, nil
+	}
+	return end, nil
+}
+
+func (fn *readFn) SplitRestriction(
+	_ TopicPartition,
+	rest partitionRestriction,
+) []partitionRestriction {
+	return []partitionRestriction{rest}
+}
+
+func (fn *readFn) RestrictionSize(_ TopicPartition, rest partitionRestriction) (float64, error) {
+	if err := fn.kafkaFn.Setup(context.Background()); err != nil {
+		return -1, err
+	}
+
+	rt, err := fn.createRTracker(rest)
+	if err != nil {
+		return -1, err
+	}
+
+	_, remaining := rt.GetProgress()
+	return remaining, nil
+}
+
+func (fn *readFn) CreateTracker(rest partitionRestriction) (*sdf.LockRTracker, error) {
+	rt, err := fn.createRTracker(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdf.NewLockRTracker(rt), nil
+}
+
+func (fn *readFn) TruncateRestriction(rt *sdf.LockRTracker, _ TopicPartition) partitionRestriction {
+	rest := rt.GetRestriction().(partitionRestriction)
+	rest.End = rest.Start
+	return rest
+}
+
+func (fn *readFn) InitialWatermarkEstimatorState(
+	et beam.EventTime,
+	_ partitionRestriction,
+	_ TopicPartition,
+) int64 {
+	return et.Milliseconds()
+}
+
+func (fn *readFn) CreateWatermarkEstimator(ms int64) *watermarkEstimator {
+	return &watermarkEstimator{state: ms}
+}
+
+func (fn *readFn) WatermarkEstimatorState(we *watermarkEstimator) int64 {
+	return we.state
+}
+
+// ProcessElement reads records of a partition until it has read all of them,
+// or for up to checkpointInterval, and then checkpoints. If StopTime is set,
+// it stops at the first record at or after it, or once it has read all
+// records after StopTime has passed. Offsets are committed once the bundle is
+// finalized.
+func (fn *readFn) ProcessElement(
+	ctx context.Context,
+	we *watermarkEstimator,
+	bf beam.BundleFinalization,
+	rt *sdf.LockRTracker,
+	tp TopicPartition,
+	emit func(beam.EventTime, ConsumerRecord),
+) (sdf.ProcessContinuation, error) {
+	start := rt.GetRestriction().(partitionRestriction).Start
+	next := start
+	defer func() {
+		fn.commit(bf, tp, start, next)
+	}()
+
+	deadline := time.Now().Add(checkpointInterval)
+	for time.Now().Before(deadline) {
+		records, err := fn.client.Fetch(ctx, tp.Topic, tp.Partition, next, fn.FetchSize)
+		if err != nil {
+			return sdf.StopProcessing(), fmt.Errorf("error fetching records of %v: %v", tp, err)
+		}
+
+		if len(records) == 0 {
+			done, err := fn.reachedEnd(ctx, rt, tp)
+			if err != nil || done {
+				return sdf.StopProcessing(), err
+			}
+			if fn.reachedStopTime(time.Now()) {
+				return fn.stop(rt)
+			}
+
+			we.advance(fn.TimePolicy.idleWatermark(time.Now(), fn.MaxDelay))
+			return sdf.ResumeProcessingIn(resumeDelay), nil
+		}
+
+		for _, r := range records {
+			if r.Offset < next {
+				continue
+			}
+			if fn.reachedStopTime(r.Timestamp) {
+				return fn.stop(rt)
+			}
+			if !rt.TryClaim(r.Offset) {
+				return sdf.StopProcessing(), rt.GetError()
+			}
+
+			ts, err := fn.TimePolicy.timestamp(r)
+			if err != nil {
+				return sdf.StopProcessing(), err
+			}
+			emit(mtime.FromTime(ts), r)
+			we.advance(fn.TimePolicy.watermark(ts, fn.MaxDelay))
+			next = r.Offset + 1
+		}
+	}
+
+	return sdf.ResumeProcessingIn(0), nil
+}
+
+// reachedEnd returns whether all records of a bounded restriction have been
+// read, claiming its end if so. Offsets before the end may have no records,
+// if they were compacted or are transaction markers.
+func (fn *readFn) reachedEnd(
+	ctx context.Context,
+	rt *sdf.LockRTracker,
+	tp TopicPartition,
+) (bool, error) {
+	end := rt.GetRestriction().(partitionRestriction).End
+	if end == math.MaxInt64 {
+		return false, nil
+	}
+
+	_, high, err := fn.client.Offsets(ctx, tp.Topic, tp.Partition)
+	if err != nil {
+		return false, fmt.Errorf("error getting offsets of %v: %v", tp, err)
+	}
+	if high < end {
+		return false, nil
+	}
+
+	rt.TryClaim(end)
+	return true, rt.GetError()
+}
+
+// reachedStopTime returns whether t is at or after StopTime, if set.
+func (fn *readFn) reachedStopTime(t time.Time) bool {
+	return !fn.StopTime.IsZero() && !t.Before(fn.StopTime)
+}
+
+// stop ends the restriction after the last offset claimed, so that the
+// records after it are not read.
+func (fn *readFn) stop(rt *sdf.LockRTracker) (sdf.ProcessContinuation, error) {
+	if _, _, err := rt.TrySplit(0); err != nil {
+		return sdf.StopProcessing(), err
+	}
+	return sdf.StopProcessing(), rt.GetError()
+}
+
+// commit commits the offset of the next record to read for the consumer group
+// once the bundle is finalized.
+func (fn *readFn) commit(bf beam.BundleFinalization, tp TopicPartition, start, next int64) {
+	if !fn.CommitOffsets || next == start {
+		return
+	}
+
+	bf.RegisterCallback(commitTimeout, func() error {
+		if err := fn.client.Commit(context.Background(), fn.Group, tp.Topic, tp.Partition, next); err != nil {
+			return fmt.Errorf("error committing offset %v of %v: %v", next, tp, err)
+		}
+		return nil
+	})
+}
+
+func (fn *readFn) createRTracker(rest partitionRestriction) (sdf.RTracker, error) {
+	rt := &partitionTracker{topic: rest.Topic, partition: rest.Partition}
+	if rest.End < math.MaxInt64 {
+		rt.BoundableRTracker = offsetrange.NewTracker(rest.offsets())
+		return rt, nil
+	}
+
+	estimator := newEndEstimator(fn.client, rest.Topic, rest.Partition)
+	growable, err := offsetrange.NewGrowableTracker(rest.offsets(), estimator)
+	if err != nil {
+		return nil, fmt.Errorf("error creating growable tracker: %v", err)
+	}
+	rt.BoundableRTracker = growable
+	return rt, nil
+}
@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	errInvalidFetchSize = errors.New("fetch size must be greater than 0")
+	errInvalidMaxDelay  = errors.New("max delay must not be negative")
+	errInvalidGroup     = errors.New("consumer group must not be empty")
+)
+
+type readOption struct {
+	Config        map[string]string
+	TimePolicy    timePolicy
+	MaxDelay      time.Duration
+	FetchSize     int
+	Earliest      bool
+	StartTime     time.Time
+	StopTime      time.Time
+	Group         string
+	CommitOffsets bool
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for reading
+// from Kafka.
+type ReadOptionFn func(option *readOption) error
+
+// ReadConsumerConfigs adds properties to the configuration passed to the driver.
+func ReadConsumerConfigs(cfgs map[string]string) ReadOptionFn {
+	return func(o *readOption) error {
+		if o.Config == nil {
+			o.Config = make(map[string]string)
+		}
+		for k, v := range cfgs {
+			o.Config[k] = v
+		}
+		return nil
+	}
+}
+
+// ReadProcessingTimePolicy specifies that the pipeline processing time of the records should
+// be used as their event time and to compute the watermark estimate. This is the default.
+func ReadProcessingTimePolicy() ReadOptionFn {
+	return func(o *readOption) error {
+		o.TimePolicy = processingTimePolicy
+		return nil
+	}
+}
+
+// ReadLogAppendTimePolicy specifies that the time the broker appended the records should be
+// used as their event time and to compute the watermark estimate. The topics must set
+// message.timestamp.type=LogAppendTime.
+func ReadLogAppendTimePolicy() ReadOptionFn {
+	return func(o *readOption) error {
+		o.TimePolicy = logAppendTimePolicy
+		return nil
+	}
+}
+
+// ReadCreateTimePolicy specifies that the time the producer created the records should be
+// used as their event time. The watermark estimate trails the latest create time by maxDelay,
+// the most that records may be out of order.
+func ReadCreateTimePolicy(maxDelay time.Duration) ReadOptionFn {
+	return func(o *readOption) error {
+		if maxDelay < 0 {
+			return errInvalidMaxDelay
+		}
+
+		o.TimePolicy = createTimePolicy
+		o.MaxDelay = maxDelay
+		return nil
+	}
+}
+
+// ReadFetchSize sets the maximum number of records to retrieve at a time.
+func ReadFetchSize(size int) ReadOptionFn {
+	return func(o *readOption) error {
+		if size <= 0 {
+			return errInvalidFetchSize
+		}
+
+		o.FetchSize = size
+		return nil
+	}
+}
+
+// ReadFromEarliest specifies that partitions without a committed offset should be read from
+// their first record rather than from their high watermark.
+func ReadFromEarliest() ReadOptionFn {
+	return func(o *readOption) error {
+		o.Earliest = true
+		return nil
+	}
+}
+
+// ReadStartTime sets the timestamp of the first records to read from each partition. It takes
+// precedence over committed offsets.
+func ReadStartTime(t time.Time) ReadOptionFn {
+	return func(o *readOption) error {
+		o.StartTime = t
+		return nil
+	}
+}
+
+// ReadStopTime sets the timestamp at which to stop reading each partition, making the read
+// bounded. Each partition is read until its first record at or after the stop time, or until
+// all of its records are read once the stop time has passed.
+func ReadStopTime(t time.Time) ReadOptionFn {
+	return func(o *readOption) error {
+		o.StopTime = t
+		return nil
+	}
+}
+
+// ReadConsumerGroup sets the consumer group whose committed offsets are the offsets to start
+// reading from.
+func ReadConsumerGroup(group string) ReadOptionFn {
+	return func(o *readOption) error {
+		if group == "" {
+			return errInvalidGroup
+		}
+
+		o.Group = group
+		return nil
+	}
+}
+
+// ReadCommitOffsetsInFinalize specifies that the offsets of records read should be committed
+// for the consumer group once the bundles that read them are finalized. It requires
+// ReadConsumerGroup.
+func ReadCommitOffsetsInFinalize() ReadOptionFn {
+	return func(o *readOption) error {
+		o.CommitOffsets = true
+		return nil
+	}
+}
@@ -0,0 +1,221 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio/memkafka"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func init() {
+	register.Function2x1(formatRecord)
+	register.Function1x1(recordValue)
+	register.Function1x2(keyValue)
+}
+
+// formatRecord formats a record read with its event time.
+func formatRecord(et beam.EventTime, r kafkaio.ConsumerRecord) string {
+	return fmt.Sprintf("%s=%s@%d", r.Key, r.Value, et.Milliseconds())
+}
+
+func recordValue(r kafkaio.ConsumerRecord) string {
+	return string(r.Value)
+}
+
+// newTopic creates a topic with two partitions in a new cluster, returning
+// the servers of the cluster.
+func newTopic(t *testing.T, topic string, records ...kafkaio.ProducerRecord) string {
+	t.Helper()
+
+	servers := t.Name()
+	t.Cleanup(func() { memkafka.Reset(servers) })
+	if err := memkafka.CreateTopic(servers, topic, 2, false); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	if err := memkafka.Produce(servers, topic, records...); err != nil {
+		t.Fatalf("Failed to produce records: %v", err)
+	}
+
+	return servers
+}
+
+func TestRead(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var records []kafkaio.ProducerRecord
+	var want []any
+	for i := 0; i < 6; i++ {
+		ts := created.Add(time.Duration(i) * time.Second)
+		records = append(records, kafkaio.ProducerRecord{
+			Key:       []byte(fmt.Sprintf("k%d", i)),
+			Value:     []byte(fmt.Sprintf("v%d", i)),
+			Timestamp: ts,
+		})
+		want = append(want, fmt.Sprintf("k%d=v%d@%d", i, i, ts.UnixMilli()))
+	}
+
+	tests := []struct {
+		name string
+		opts []kafkaio.ReadOptionFn
+		want []any
+	}{
+		{
+			name: "Read from earliest until the stop time",
+			opts: []kafkaio.ReadOptionFn{
+				kafkaio.ReadFromEarliest(),
+				kafkaio.ReadStopTime(time.Now()),
+			},
+			want: want,
+		},
+		{
+			name: "Read from the start time until the stop time",
+			opts: []kafkaio.ReadOptionFn{
+				kafkaio.ReadStartTime(created.Add(2 * time.Second)),
+				kafkaio.ReadStopTime(created.Add(5 * time.Second)),
+			},
+			want: want[2:5],
+		},
+		{
+			name: "Read from the high watermark",
+			opts: []kafkaio.ReadOptionFn{
+				kafkaio.ReadStopTime(time.Now()),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := newTopic(t, "topic", records...)
+
+			p, s := beam.NewPipelineWithRoot()
+			opts := append(tt.opts, kafkaio.ReadCreateTimePolicy(0), kafkaio.ReadFetchSize(2))
+			col := kafkaio.Read(s, "memkafka", servers, []string{"topic"}, opts...)
+			passert.Equals(s, beam.ParDo(s, formatRecord, col), tt.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestRead_commitOffsets(t *testing.T) {
+	var records []kafkaio.ProducerRecord
+	for i := 0; i < 6; i++ {
+		records = append(records, kafkaio.ProducerRecord{Value: []byte(fmt.Sprintf("v%d", i))})
+	}
+	servers := newTopic(t, "topic", records...)
+
+	ctx := context.Background()
+	client, err := kafkaio.Dial(ctx, "memkafka", servers, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Commit(ctx, "group", "topic", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []any
+	for p := int32(0); p < 2; p++ {
+		for _, r := range memkafka.Records(servers, "topic", p) {
+			if p == 0 && r.Offset < 1 {
+				continue
+			}
+			want = append(want, string(r.Value))
+		}
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	col := kafkaio.Read(s, "memkafka", servers, []string{"topic"},
+		kafkaio.ReadConsumerGroup("group"),
+		kafkaio.ReadCommitOffsetsInFinalize(),
+		kafkaio.ReadFromEarliest(),
+		kafkaio.ReadStopTime(time.Now()),
+	)
+	values := beam.ParDo(s, recordValue, col)
+	passert.Equals(s, values, want...)
+	ptest.RunAndValidate(t, p)
+
+	for p := int32(0); p < 2; p++ {
+		if got, want := memkafka.Committed(servers, "group", "topic", p), int64(3); got != want {
+			t.Errorf("committed offset of partition %v = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestRead_futureStopTime(t *testing.T) {
+	servers := t.Name()
+	t.Cleanup(func() { memkafka.Reset(servers) })
+	if err := memkafka.CreateTopic(servers, "topic", 1, false); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+
+	now := time.Now()
+	stop := now.Add(time.Hour)
+	produce := func(ts time.Time, values ...string) {
+		for _, v := range values {
+			if err := memkafka.Produce(servers, "topic", kafkaio.ProducerRecord{Value: []byte(v), Timestamp: ts}); err != nil {
+				t.Errorf("Failed to produce records: %v", err)
+			}
+		}
+	}
+	produce(now, "v0", "v1")
+
+	// Records produced once the read has started are read until the first
+	// one at or after the stop time.
+	go func() {
+		time.Sleep(2 * time.Second)
+		produce(now.Add(time.Minute), "v2", "v3")
+		produce(stop, "v4")
+		produce(now.Add(2*time.Minute), "v5")
+	}()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := kafkaio.Read(s, "memkafka", servers, []string{"topic"},
+		kafkaio.ReadFromEarliest(),
+		kafkaio.ReadStopTime(stop),
+		kafkaio.ReadCreateTimePolicy(0),
+	)
+	passert.Equals(s, beam.ParDo(s, recordValue, col), "v0", "v1", "v2", "v3")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_invalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []kafkaio.ReadOptionFn
+	}{
+		{name: "Invalid fetch size", opts: []kafkaio.ReadOptionFn{kafkaio.ReadFetchSize(0)}},
+		{name: "Commit without a consumer group", opts: []kafkaio.ReadOptionFn{kafkaio.ReadCommitOffsetsInFinalize()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Read() didn't panic")
+				}
+			}()
+
+			s := beam.NewPipeline().Root()
+			kafkaio.Read(s, "memkafka", "servers", []string{"topic"}, tt.opts...)
+		})
+	}
+}
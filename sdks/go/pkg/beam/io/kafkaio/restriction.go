@@ -0,0 +1,66 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+// partitionRestriction is a range of offsets of a topic partition to read.
+// The range is unbounded if End is math.MaxInt64.
+type partitionRestriction struct {
+	Topic     string
+	Partition int32
+	Start     int64
+	End       int64
+}
+
+func (r partitionRestriction) offsets() offsetrange.Restriction {
+	return offsetrange.Restriction{Start: r.Start, End: r.End}
+}
+
+// partitionTracker tracks a partitionRestriction with an offset range
+// tracker, which grows to the high watermark of the partition if the
+// restriction is unbounded.
+type partitionTracker struct {
+	sdf.BoundableRTracker
+	topic     string
+	partition int32
+}
+
+func (t *partitionTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	primary, residual, err = t.BoundableRTracker.TrySplit(fraction)
+	return t.wrap(primary), t.wrap(residual), err
+}
+
+func (t *partitionTracker) GetRestriction() any {
+	return t.wrap(t.BoundableRTracker.GetRestriction())
+}
+
+func (t *partitionTracker) wrap(rest any) any {
+	r, ok := rest.(offsetrange.Restriction)
+	if !ok {
+		return rest
+	}
+
+	return partitionRestriction{
+		Topic:     t.topic,
+		Partition: t.partition,
+		Start:     r.Start,
+		End:       r.End,
+	}
+}
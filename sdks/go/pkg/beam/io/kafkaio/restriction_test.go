@@ -0,0 +1,63 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// offsetsClient is a Client whose partitions have a fixed high watermark.
+type offsetsClient struct {
+	Client
+	end int64
+}
+
+func (c *offsetsClient) Offsets(context.Context, string, int32) (start, end int64, err error) {
+	return 0, c.end, nil
+}
+
+func Test_partitionTracker_growable(t *testing.T) {
+	fn := &readFn{kafkaFn: kafkaFn{client: &offsetsClient{end: 10}}}
+	rt, err := fn.createRTracker(partitionRestriction{Topic: "topic", Partition: 1, Start: 2, End: math.MaxInt64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for offset := int64(2); offset < 5; offset++ {
+		if !rt.TryClaim(offset) {
+			t.Fatalf("TryClaim(%v) = false, want true", offset)
+		}
+	}
+	if done, remaining := rt.GetProgress(); done != 3 || remaining != 6 {
+		t.Errorf("GetProgress() = %v, %v, want 3, 6", done, remaining)
+	}
+
+	primary, residual, err := rt.TrySplit(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (partitionRestriction{Topic: "topic", Partition: 1, Start: 2, End: 5}); primary != want {
+		t.Errorf("TrySplit(0) primary = %v, want %v", primary, want)
+	}
+	if want := (partitionRestriction{Topic: "topic", Partition: 1, Start: 5, End: math.MaxInt64}); residual != want {
+		t.Errorf("TrySplit(0) residual = %v, want %v", residual, want)
+	}
+	if !rt.IsDone() {
+		t.Error("IsDone() = false after checkpointing, want true")
+	}
+}
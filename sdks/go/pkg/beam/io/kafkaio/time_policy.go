@@ -0,0 +1,65 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"fmt"
+	"time"
+)
+
+type timePolicy int
+
+const (
+	processingTimePolicy timePolicy = iota
+	logAppendTimePolicy
+	createTimePolicy
+)
+
+// timestamp returns the event time of a record.
+func (p timePolicy) timestamp(r ConsumerRecord) (time.Time, error) {
+	switch p {
+	case processingTimePolicy:
+		return time.Now(), nil
+	case logAppendTimePolicy:
+		if !r.LogAppendTime {
+			return time.Time{}, fmt.Errorf("record %v of %v/%v has no log append time; the topic must set message.timestamp.type=LogAppendTime", r.Offset, r.Topic, r.Partition)
+		}
+		return r.Timestamp, nil
+	case createTimePolicy:
+		return r.Timestamp, nil
+	default:
+		panic("unsupported time policy")
+	}
+}
+
+// watermark returns the watermark after reading a record with the given
+// event time, given the most a record's create time may be out of order.
+func (p timePolicy) watermark(ts time.Time, maxDelay time.Duration) time.Time {
+	if p == createTimePolicy {
+		return ts.Add(-maxDelay)
+	}
+	return ts
+}
+
+// idleWatermark returns the watermark when all records of a partition have
+// been read. Records appended later have later processing and log append
+// times, and create times no more than maxDelay earlier.
+func (p timePolicy) idleWatermark(now time.Time, maxDelay time.Duration) time.Time {
+	if p == createTimePolicy {
+		return now.Add(-maxDelay)
+	}
+	return now.Add(-assumedLag)
+}
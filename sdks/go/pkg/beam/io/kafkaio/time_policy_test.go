@@ -0,0 +1,76 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_timePolicy_timestamp(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC)
+
+	t.Run("processingTime", func(t *testing.T) {
+		t1 := time.Now()
+		got, err := processingTimePolicy.timestamp(ConsumerRecord{Timestamp: ts})
+		t2 := time.Now()
+
+		if err != nil || got.Before(t1) || got.After(t2) {
+			t.Errorf("timestamp = %v, %v, want between %v and %v", got, err, t1, t2)
+		}
+	})
+
+	t.Run("logAppendTime", func(t *testing.T) {
+		got, err := logAppendTimePolicy.timestamp(ConsumerRecord{Timestamp: ts, LogAppendTime: true})
+		if err != nil || !got.Equal(ts) {
+			t.Errorf("timestamp = %v, %v, want %v", got, err, ts)
+		}
+
+		if _, err := logAppendTimePolicy.timestamp(ConsumerRecord{Timestamp: ts}); err == nil {
+			t.Error("timestamp of record with create time succeeded, want error")
+		}
+	})
+
+	t.Run("createTime", func(t *testing.T) {
+		got, err := createTimePolicy.timestamp(ConsumerRecord{Timestamp: ts})
+		if err != nil || !got.Equal(ts) {
+			t.Errorf("timestamp = %v, %v, want %v", got, err, ts)
+		}
+	})
+}
+
+func Test_timePolicy_watermark(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	maxDelay := time.Minute
+
+	tests := []struct {
+		policy timePolicy
+		want   time.Time
+		idle   time.Time
+	}{
+		{policy: processingTimePolicy, want: ts, idle: ts.Add(-assumedLag)},
+		{policy: logAppendTimePolicy, want: ts, idle: ts.Add(-assumedLag)},
+		{policy: createTimePolicy, want: ts.Add(-maxDelay), idle: ts.Add(-maxDelay)},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.watermark(ts, maxDelay); !got.Equal(tt.want) {
+			t.Errorf("%v: watermark = %v, want %v", tt.policy, got, tt.want)
+		}
+		if got := tt.policy.idleWatermark(ts, maxDelay); !got.Equal(tt.idle) {
+			t.Errorf("%v: idleWatermark = %v, want %v", tt.policy, got, tt.idle)
+		}
+	}
+}
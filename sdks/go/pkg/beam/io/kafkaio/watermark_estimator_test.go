@@ -0,0 +1,37 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_watermarkEstimator_advance(t *testing.T) {
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	t2 := time.Date(2020, 1, 2, 3, 4, 5, 7e6, time.UTC)
+
+	we := &watermarkEstimator{state: t1.UnixMilli()}
+	we.advance(t2)
+	if got := we.CurrentWatermark(); !got.Equal(t2) {
+		t.Errorf("CurrentWatermark() = %v, want %v", got, t2)
+	}
+
+	we.advance(t1)
+	if got := we.CurrentWatermark(); !got.Equal(t2) {
+		t.Errorf("CurrentWatermark() after going back = %v, want %v", got, t2)
+	}
+}
@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn2x1[context.Context, ProducerRecord, error](&writeFn{})
+	register.Function2x1(toProducerRecord)
+	beam.RegisterType(reflect.TypeOf((*ProducerRecord)(nil)).Elem())
+}
+
+const defaultBatchSize = 500
+
+var producerRecordT = reflect.TypeOf((*ProducerRecord)(nil)).Elem()
+
+// Write writes a PCollection<ProducerRecord> or PCollection<KV<[]byte, []byte>> of keys and
+// values to a Kafka topic. Records are produced in batches, all of which are acknowledged
+// by the end of each bundle.
+//
+// The driver is usually DefaultDriver, or must have been registered with
+// Register, and servers is a comma-separated list of bootstrap servers.
+//
+// Write takes a variable number of WriteOptionFn to configure the write operation:
+//   - ProducerConfigs: properties passed to the driver. Defaults to none.
+//   - BatchSize: the maximum number of records to produce at a time. Defaults to 500.
+func Write(
+	s beam.Scope,
+	driver string,
+	servers string,
+	topic string,
+	col beam.PCollection,
+	opts ...WriteOptionFn,
+) {
+	s = s.Scope("kafkaio.Write")
+
+	option := &writeOption{
+		BatchSize: defaultBatchSize,
+	}
+
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("kafkaio.Write: invalid option: %v", err))
+		}
+	}
+
+	t := col.Type()
+	if typex.IsKV(t) && t.Components()[0].Type() == reflectx.ByteSlice && t.Components()[1].Type() == reflectx.ByteSlice {
+		col = beam.ParDo(s, toProducerRecord, col)
+	} else if t.Type() != producerRecordT {
+		panic(fmt.Sprintf("kafkaio.Write: input must be a PCollection of %v or KV<[]byte, []byte>, got %v", producerRecordT, t))
+	}
+
+	fn := &writeFn{
+		kafkaFn:   kafkaFn{Driver: driver, Servers: servers, Config: option.Config},
+		Topic:     topic,
+		BatchSize: option.BatchSize,
+	}
+	beam.ParDo0(s, fn, col)
+}
+
+func toProducerRecord(key, value []byte) ProducerRecord {
+	return ProducerRecord{Key: key, Value: value}
+}
+
+type writeFn struct {
+	kafkaFn
+	Topic     string
+	BatchSize int
+	batch     []ProducerRecord
+}
+
+func (fn *writeFn) ProcessElement(ctx context.Context, elem ProducerRecord) error {
+	fn.batch = append(fn.batch, elem)
+	if len(fn.batch) >= fn.BatchSize {
+		return fn.flush(ctx)
+	}
+
+	return nil
+}
+
+func (fn *writeFn) FinishBundle(ctx context.Context) error {
+	return fn.flush(ctx)
+}
+
+func (fn *writeFn) flush(ctx context.Context) error {
+	if len(fn.batch) == 0 {
+		return nil
+	}
+
+	if err := fn.client.Produce(ctx, fn.Topic, fn.batch); err != nil {
+		return fmt.Errorf("error producing records to %v: %v", fn.Topic, err)
+	}
+	fn.batch = nil
+
+	return nil
+}
@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import "errors"
+
+var errInvalidBatchSize = errors.New("batch size must be greater than 0")
+
+type writeOption struct {
+	Config    map[string]string
+	BatchSize int
+}
+
+// WriteOptionFn is a function that can be passed to Write to configure options for
+// writing records.
+type WriteOptionFn func(option *writeOption) error
+
+// WriteProducerConfigs adds properties to the configuration passed to the driver.
+func WriteProducerConfigs(cfgs map[string]string) WriteOptionFn {
+	return func(o *writeOption) error {
+		if o.Config == nil {
+			o.Config = make(map[string]string)
+		}
+		for k, v := range cfgs {
+			o.Config[k] = v
+		}
+		return nil
+	}
+}
+
+// WriteBatchSize sets the maximum number of records to produce at a time.
+func WriteBatchSize(size int) WriteOptionFn {
+	return func(o *writeOption) error {
+		if size <= 0 {
+			return errInvalidBatchSize
+		}
+
+		o.BatchSize = size
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio/memkafka"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func keyValue(v string) ([]byte, []byte) {
+	return []byte("key-" + v), []byte(v)
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		input func(s beam.Scope) beam.PCollection
+	}{
+		{
+			name: "Write keys and values",
+			input: func(s beam.Scope) beam.PCollection {
+				kvs := beam.Create(s, "a", "b", "c")
+				return beam.ParDo(s, keyValue, kvs)
+			},
+		},
+		{
+			name: "Write producer records",
+			input: func(s beam.Scope) beam.PCollection {
+				return beam.Create(s,
+					kafkaio.ProducerRecord{Key: []byte("key-a"), Value: []byte("a")},
+					kafkaio.ProducerRecord{Key: []byte("key-b"), Value: []byte("b")},
+					kafkaio.ProducerRecord{Key: []byte("key-c"), Value: []byte("c")},
+				)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := newTopic(t, "topic")
+
+			p, s := beam.NewPipelineWithRoot()
+			kafkaio.Write(s, "memkafka", servers, "topic", tt.input(s), kafkaio.WriteBatchSize(2))
+			ptest.RunAndValidate(t, p)
+
+			var got []string
+			for p := int32(0); p < 2; p++ {
+				for _, r := range memkafka.Records(servers, "topic", p) {
+					got = append(got, string(r.Key)+"="+string(r.Value))
+				}
+			}
+			sort.Strings(got)
+			if want := []string{"key-a=a", "key-b=b", "key-c=c"}; !cmp.Equal(got, want) {
+				t.Errorf("records = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWrite_invalidInput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Write() didn't panic")
+		}
+	}()
+
+	s := beam.NewPipeline().Root()
+	kafkaio.Write(s, "memkafka", "servers", "topic", beam.Create(s, "a"))
+}
@@ -0,0 +1,431 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	changeStreamResumeDelay = 5 * time.Second
+)
+
+func init() {
+	register.DoFn5x2[
+		context.Context, *watermarkEstimator, *sdf.LockRTracker, []byte,
+		func(beam.EventTime, string, beam.Y), sdf.ProcessContinuation, error,
+	](
+		&changeStreamFn{},
+	)
+	register.Emitter3[beam.EventTime, string, beam.Y]()
+}
+
+// ReadChangeStream reads the change stream of a MongoDB collection and returns a
+// PCollection<KV<string, T>> for a given type T, where the key is the operation type of a change
+// event and the value is the changed document. T must be a struct with exported fields that should
+// have a "bson" tag. The collection must be part of a replica set or sharded cluster, as change
+// streams are not available on standalone servers.
+//
+// Only change events of documents are emitted, which have the operation type "insert", "update",
+// "replace" or "delete". The value of insert and replace events is the full document, and of update
+// events the current version of the document, looked up when the event is read. The value of
+// delete events, and of update events of documents which have since been deleted, only has the
+// fields of the document key, such as _id, set. The event time of each element is the cluster time
+// of its change event.
+//
+// The read is unbounded by default. The resume token of the last read change event is used to
+// checkpoint the read, and the watermark advances to the cluster time of the last read change
+// event, or to the current cluster time when the change stream is idle.
+//
+// The ReadChangeStream transform has the required parameters:
+//   - s: the scope of the pipeline
+//   - uri: the MongoDB connection string
+//   - database: the MongoDB database to read from
+//   - collection: the MongoDB collection whose change stream to read
+//   - t: the type of the documents in the collection
+//
+// The ReadChangeStream transform takes a variadic number of ChangeStreamOptionFn which can set the
+// ChangeStreamOption fields:
+//   - OperationTypes: the operation types of the change events to read. Defaults to nil, which
+//     means change events of all operation types are read
+//   - Filter: a bson.M map that is used to filter the change events. Defaults to nil, which means
+//     no filter is applied
+//   - StartTime: the cluster time to start reading change events from. Defaults to the current
+//     cluster time when the pipeline starts
+//   - EndTime: the cluster time to stop reading change events at (exclusive), which makes the read
+//     bounded. Defaults to the zero time, which means the read is unbounded
+func ReadChangeStream(
+	s beam.Scope,
+	uri string,
+	database string,
+	collection string,
+	t reflect.Type,
+	opts ...ChangeStreamOptionFn,
+) beam.PCollection {
+	s = s.Scope("mongodbio.ReadChangeStream")
+
+	option := &ChangeStreamOption{}
+
+	for _, opt := range opts {
+		if err := opt(option); err != nil {
+			panic(fmt.Sprintf("mongodbio.ReadChangeStream: invalid option: %v", err))
+		}
+	}
+
+	if !option.StartTime.IsZero() && !option.EndTime.IsZero() && !option.StartTime.Before(option.EndTime) {
+		panic("mongodbio.ReadChangeStream: invalid option: start time must be before end time")
+	}
+
+	imp := beam.Impulse(s)
+
+	return beam.ParDo(
+		s,
+		newChangeStreamFn(uri, database, collection, t, option),
+		imp,
+		beam.TypeDefinition{Var: beam.YType, T: t},
+	)
+}
+
+type changeStreamFn struct {
+	mongoDBFn
+	OperationTypes []string
+	Filter         []byte
+	StartTime      time.Time
+	EndTime        time.Time
+	Type           beam.EncodedType
+	pipeline       mongo.Pipeline
+}
+
+func newChangeStreamFn(
+	uri string,
+	database string,
+	collection string,
+	t reflect.Type,
+	option *ChangeStreamOption,
+) *changeStreamFn {
+	filter, err := encodeBSON[bson.M](option.Filter)
+	if err != nil {
+		panic(fmt.Sprintf("mongodbio.newChangeStreamFn: %v", err))
+	}
+
+	return &changeStreamFn{
+		mongoDBFn: mongoDBFn{
+			URI:        uri,
+			Database:   database,
+			Collection: collection,
+		},
+		OperationTypes: option.OperationTypes,
+		Filter:         filter,
+		StartTime:      option.StartTime,
+		EndTime:        option.EndTime,
+		Type:           beam.EncodedType{T: t},
+	}
+}
+
+func (fn *changeStreamFn) Setup(ctx context.Context) error {
+	if err := fn.mongoDBFn.Setup(ctx); err != nil {
+		return err
+	}
+
+	filter, err := decodeBSON[bson.M](fn.Filter)
+	if err != nil {
+		return err
+	}
+
+	fn.pipeline = changeStreamPipeline(fn.OperationTypes, filter)
+
+	return nil
+}
+
+// changeStreamPipeline returns the aggregation pipeline matching the change events with the
+// provided operation types and filter.
+func changeStreamPipeline(operationTypes []string, filter bson.M) mongo.Pipeline {
+	pipeline := mongo.Pipeline{}
+
+	if len(operationTypes) > 0 {
+		match := bson.M{"operationType": bson.M{"$in": operationTypes}}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
+	}
+
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: filter}})
+	}
+
+	return pipeline
+}
+
+func (fn *changeStreamFn) CreateInitialRestriction(
+	ctx context.Context,
+	_ []byte,
+) (changeStreamRestriction, error) {
+	rest := changeStreamRestriction{
+		StartTime: toTimestamp(fn.StartTime),
+		EndTime:   toTimestamp(fn.EndTime),
+	}
+
+	if !rest.StartTime.IsZero() {
+		return rest, nil
+	}
+
+	// Pin the start of the change stream, so that reading starts from the same position if the
+	// restriction is processed again.
+	if err := fn.Setup(ctx); err != nil {
+		return changeStreamRestriction{}, err
+	}
+
+	startTime, err := clusterTime(ctx, fn.client)
+	if err != nil {
+		return changeStreamRestriction{}, err
+	}
+
+	rest.StartTime = startTime
+
+	return rest, nil
+}
+
+func (fn *changeStreamFn) SplitRestriction(
+	_ []byte,
+	rest changeStreamRestriction,
+) []changeStreamRestriction {
+	return []changeStreamRestriction{rest}
+}
+
+func (fn *changeStreamFn) RestrictionSize(_ []byte, rest changeStreamRestriction) float64 {
+	_, remaining := newChangeStreamTracker(rest).GetProgress()
+	return remaining
+}
+
+func (fn *changeStreamFn) CreateTracker(rest changeStreamRestriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(newChangeStreamTracker(rest))
+}
+
+func (fn *changeStreamFn) TruncateRestriction(
+	rt *sdf.LockRTracker,
+	_ []byte,
+) changeStreamRestriction {
+	start := rt.GetRestriction().(changeStreamRestriction).StartTime
+	return changeStreamRestriction{
+		StartTime: start,
+		EndTime:   start,
+	}
+}
+
+func (fn *changeStreamFn) InitialWatermarkEstimatorState(
+	_ beam.EventTime,
+	rest changeStreamRestriction,
+	_ []byte,
+) int64 {
+	return fromTimestamp(rest.StartTime).Milliseconds()
+}
+
+func (fn *changeStreamFn) CreateWatermarkEstimator(ms int64) *watermarkEstimator {
+	return &watermarkEstimator{state: ms}
+}
+
+func (fn *changeStreamFn) WatermarkEstimatorState(we *watermarkEstimator) int64 {
+	return we.state
+}
+
+// changeEvent is a change event of a MongoDB change stream.
+type changeEvent struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   bson.Raw            `bson:"documentKey"`
+	FullDocument  bson.Raw            `bson:"fullDocument"`
+}
+
+func (fn *changeStreamFn) ProcessElement(
+	ctx context.Context,
+	we *watermarkEstimator,
+	rt *sdf.LockRTracker,
+	_ []byte,
+	emit func(beam.EventTime, string, beam.Y),
+) (cont sdf.ProcessContinuation, err error) {
+	rest := rt.GetRestriction().(changeStreamRestriction)
+	if rest.IsEmpty() {
+		return sdf.StopProcessing(), nil
+	}
+
+	stream, err := fn.watch(ctx, rest)
+	if err != nil {
+		return sdf.StopProcessing(), err
+	}
+
+	defer func() {
+		closeErr := stream.Close(ctx)
+
+		if err != nil {
+			if closeErr != nil {
+				log.Errorf(ctx, "error closing change stream: %v", closeErr)
+			}
+			return
+		}
+
+		err = closeErr
+	}()
+
+	var idleTime primitive.Timestamp
+
+	for {
+		if stream.TryNext(ctx) {
+			idleTime = primitive.Timestamp{}
+
+			var event changeEvent
+			if err := stream.Decode(&event); err != nil {
+				return sdf.StopProcessing(), fmt.Errorf("error decoding change event: %w", err)
+			}
+
+			result := changeStreamResult{
+				resumeToken: stream.ResumeToken(),
+				clusterTime: event.ClusterTime,
+			}
+			if !rt.TryClaim(result) {
+				return sdf.StopProcessing(), rt.GetError()
+			}
+
+			// The change stream is closed after an invalidate event, such as when the collection
+			// is dropped, and is reopened after it when processing resumes.
+			if event.OperationType == "invalidate" {
+				return sdf.ResumeProcessingIn(changeStreamResumeDelay), nil
+			}
+
+			value, ok, err := decodeChangedDocument(event, fn.Type.T)
+			if err != nil {
+				return sdf.StopProcessing(), err
+			}
+
+			if ok {
+				emit(fromTimestamp(event.ClusterTime), event.OperationType, value)
+			}
+
+			continue
+		}
+
+		if err := stream.Err(); err != nil {
+			return sdf.StopProcessing(), fmt.Errorf("error reading change stream: %w", err)
+		}
+
+		if idleTime.IsZero() {
+			// Read the cluster time before polling the change stream again, so that all change
+			// events before it have been read if the change stream is still idle.
+			if idleTime, err = clusterTime(ctx, fn.client); err != nil {
+				return sdf.StopProcessing(), err
+			}
+
+			continue
+		}
+
+		result := changeStreamResult{
+			resumeToken: stream.ResumeToken(),
+			clusterTime: idleTime,
+		}
+		if !rt.TryClaim(result) {
+			return sdf.StopProcessing(), rt.GetError()
+		}
+
+		we.ObserveTimestamp(fromTimestamp(idleTime).ToTime())
+
+		return sdf.ResumeProcessingIn(changeStreamResumeDelay), nil
+	}
+}
+
+func (fn *changeStreamFn) watch(
+	ctx context.Context,
+	rest changeStreamRestriction,
+) (*mongo.ChangeStream, error) {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	if len(rest.ResumeToken) > 0 {
+		opts.SetStartAfter(rest.ResumeToken)
+	} else {
+		startTime := rest.StartTime
+		opts.SetStartAtOperationTime(&startTime)
+	}
+
+	stream, err := fn.collection.Watch(ctx, fn.pipeline, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error opening change stream: %w", err)
+	}
+
+	return stream, nil
+}
+
+// decodeChangedDocument decodes the changed document of a change event into a value of type t,
+// from the full document if available and otherwise from the document key. Returns false if the
+// change event is not a change of a document.
+func decodeChangedDocument(event changeEvent, t reflect.Type) (value any, ok bool, err error) {
+	doc := event.FullDocument
+	if len(doc) == 0 {
+		doc = event.DocumentKey
+	}
+
+	if len(doc) == 0 {
+		return nil, false, nil
+	}
+
+	out := reflect.New(t).Interface()
+	if err := bson.Unmarshal(doc, out); err != nil {
+		return nil, false, fmt.Errorf("error decoding document: %w", err)
+	}
+
+	return reflect.ValueOf(out).Elem().Interface(), true, nil
+}
+
+// clusterTime returns the current cluster time of the deployment the client is connected to.
+func clusterTime(ctx context.Context, client *mongo.Client) (primitive.Timestamp, error) {
+	var result struct {
+		OperationTime primitive.Timestamp `bson:"operationTime"`
+	}
+
+	cmd := bson.D{{Key: "hello", Value: 1}}
+	if err := client.Database("admin").RunCommand(ctx, cmd).Decode(&result); err != nil {
+		return primitive.Timestamp{}, fmt.Errorf("error reading cluster time: %w", err)
+	}
+
+	if result.OperationTime.IsZero() {
+		return primitive.Timestamp{}, errors.New(
+			"error reading cluster time: change streams require a replica set or sharded cluster",
+		)
+	}
+
+	return result.OperationTime, nil
+}
+
+func toTimestamp(t time.Time) primitive.Timestamp {
+	if t.IsZero() {
+		return primitive.Timestamp{}
+	}
+
+	return primitive.Timestamp{T: uint32(t.Unix())}
+}
+
+func fromTimestamp(ts primitive.Timestamp) mtime.Time {
+	return mtime.FromTime(time.Unix(int64(ts.T), 0))
+}
@@ -0,0 +1,82 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ChangeStreamOption represents options for reading change streams from MongoDB.
+type ChangeStreamOption struct {
+	OperationTypes []string
+	Filter         bson.M
+	StartTime      time.Time
+	EndTime        time.Time
+}
+
+// ChangeStreamOptionFn is a function that configures a ChangeStreamOption.
+type ChangeStreamOptionFn func(option *ChangeStreamOption) error
+
+// WithChangeStreamOperationTypes configures the ChangeStreamOption to only read change events
+// with the provided operation types, such as "insert", "update", "replace" and "delete".
+func WithChangeStreamOperationTypes(operationTypes ...string) ChangeStreamOptionFn {
+	return func(o *ChangeStreamOption) error {
+		if len(operationTypes) == 0 {
+			return errors.New("operation types must not be empty")
+		}
+
+		o.OperationTypes = operationTypes
+		return nil
+	}
+}
+
+// WithChangeStreamFilter configures the ChangeStreamOption to use the provided filter on the
+// change events, such as bson.M{"fullDocument.status": "active"}.
+func WithChangeStreamFilter(filter bson.M) ChangeStreamOptionFn {
+	return func(o *ChangeStreamOption) error {
+		o.Filter = filter
+		return nil
+	}
+}
+
+// WithChangeStreamStartTime configures the ChangeStreamOption to read change events from the
+// provided cluster time, truncated to seconds.
+func WithChangeStreamStartTime(startTime time.Time) ChangeStreamOptionFn {
+	return func(o *ChangeStreamOption) error {
+		if startTime.IsZero() {
+			return errors.New("start time must not be zero")
+		}
+
+		o.StartTime = startTime
+		return nil
+	}
+}
+
+// WithChangeStreamEndTime configures the ChangeStreamOption to stop reading change events at the
+// provided cluster time (exclusive), truncated to seconds, which makes the read bounded.
+func WithChangeStreamEndTime(endTime time.Time) ChangeStreamOptionFn {
+	return func(o *ChangeStreamOption) error {
+		if endTime.IsZero() {
+			return errors.New("end time must not be zero")
+		}
+
+		o.EndTime = endTime
+		return nil
+	}
+}
@@ -0,0 +1,159 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWithChangeStreamOperationTypes(t *testing.T) {
+	tests := []struct {
+		name           string
+		operationTypes []string
+		want           []string
+		wantErr        bool
+	}{
+		{
+			name:           "Set operation types to insert and delete",
+			operationTypes: []string{"insert", "delete"},
+			want:           []string{"insert", "delete"},
+			wantErr:        false,
+		},
+		{
+			name:           "Error - operation types must not be empty",
+			operationTypes: nil,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var option ChangeStreamOption
+
+			if err := WithChangeStreamOperationTypes(tt.operationTypes...)(&option); (err != nil) != tt.wantErr {
+				t.Fatalf("WithChangeStreamOperationTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !cmp.Equal(option.OperationTypes, tt.want) {
+				t.Errorf("option.OperationTypes = %v, want %v", option.OperationTypes, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithChangeStreamFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  bson.M
+		want    bson.M
+		wantErr bool
+	}{
+		{
+			name:    "Set filter to {\"fullDocument.key\": \"value\"}",
+			filter:  bson.M{"fullDocument.key": "value"},
+			want:    bson.M{"fullDocument.key": "value"},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var option ChangeStreamOption
+
+			if err := WithChangeStreamFilter(tt.filter)(&option); (err != nil) != tt.wantErr {
+				t.Fatalf("WithChangeStreamFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !cmp.Equal(option.Filter, tt.want) {
+				t.Errorf("option.Filter = %v, want %v", option.Filter, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithChangeStreamStartTime(t *testing.T) {
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		startTime time.Time
+		want      time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "Set start time to 2023-01-01",
+			startTime: startTime,
+			want:      startTime,
+			wantErr:   false,
+		},
+		{
+			name:      "Error - start time must not be zero",
+			startTime: time.Time{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var option ChangeStreamOption
+
+			if err := WithChangeStreamStartTime(tt.startTime)(&option); (err != nil) != tt.wantErr {
+				t.Fatalf("WithChangeStreamStartTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !option.StartTime.Equal(tt.want) {
+				t.Errorf("option.StartTime = %v, want %v", option.StartTime, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithChangeStreamEndTime(t *testing.T) {
+	endTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		endTime time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "Set end time to 2023-01-01",
+			endTime: endTime,
+			want:    endTime,
+			wantErr: false,
+		},
+		{
+			name:    "Error - end time must not be zero",
+			endTime: time.Time{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var option ChangeStreamOption
+
+			if err := WithChangeStreamEndTime(tt.endTime)(&option); (err != nil) != tt.wantErr {
+				t.Fatalf("WithChangeStreamEndTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !option.EndTime.Equal(tt.want) {
+				t.Errorf("option.EndTime = %v, want %v", option.EndTime, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_changeStreamPipeline(t *testing.T) {
+	tests := []struct {
+		name           string
+		operationTypes []string
+		filter         bson.M
+		want           mongo.Pipeline
+	}{
+		{
+			name: "Empty pipeline without operation types and filter",
+			want: mongo.Pipeline{},
+		},
+		{
+			name:           "Match operation types and filter",
+			operationTypes: []string{"insert", "update"},
+			filter:         bson.M{"fullDocument.field1": bson.M{"$gt": 0}},
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": []string{"insert", "update"}}}}},
+				{{Key: "$match", Value: bson.M{"fullDocument.field1": bson.M{"$gt": 0}}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changeStreamPipeline(tt.operationTypes, tt.filter)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("changeStreamPipeline() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_decodeChangedDocument(t *testing.T) {
+	type doc struct {
+		ID     string `bson:"_id"`
+		Field1 int32  `bson:"field1"`
+	}
+
+	marshal := func(val any) bson.Raw {
+		raw, err := bson.Marshal(val)
+		if err != nil {
+			t.Fatalf("error marshaling document: %v", err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name    string
+		event   changeEvent
+		want    any
+		wantOk  bool
+		wantErr bool
+	}{
+		{
+			name: "Decode full document",
+			event: changeEvent{
+				OperationType: "insert",
+				DocumentKey:   marshal(bson.M{"_id": "id01"}),
+				FullDocument:  marshal(bson.M{"_id": "id01", "field1": int32(1)}),
+			},
+			want:   doc{ID: "id01", Field1: 1},
+			wantOk: true,
+		},
+		{
+			name: "Decode document key when there is no full document",
+			event: changeEvent{
+				OperationType: "delete",
+				DocumentKey:   marshal(bson.M{"_id": "id01"}),
+			},
+			want:   doc{ID: "id01"},
+			wantOk: true,
+		},
+		{
+			name:   "Skip change event without document",
+			event:  changeEvent{OperationType: "drop"},
+			wantOk: false,
+		},
+		{
+			name: "Error - document does not match type",
+			event: changeEvent{
+				OperationType: "insert",
+				FullDocument:  marshal(bson.M{"_id": "id01", "field1": "one"}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk, err := decodeChangedDocument(tt.event, reflect.TypeOf(doc{}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeChangedDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotOk != tt.wantOk {
+				t.Errorf("decodeChangedDocument() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decodeChangedDocument() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_toTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want primitive.Timestamp
+	}{
+		{
+			name: "Truncate time to seconds",
+			t:    time.Date(2023, 1, 1, 0, 0, 0, 5e8, time.UTC),
+			want: primitive.Timestamp{T: 1672531200},
+		},
+		{
+			name: "Zero timestamp for zero time",
+			t:    time.Time{},
+			want: primitive.Timestamp{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toTimestamp(tt.t); got != tt.want {
+				t.Errorf("toTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*changeStreamRestriction)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*changeStreamTracker)(nil)))
+}
+
+// changeStreamRestriction represents the change events to read from a MongoDB change stream.
+// ResumeToken is the resume token of the last read position in the change stream, from which
+// reading resumes if set. StartTime is the cluster time to start reading from if there is no
+// resume token, and otherwise the cluster time of the last read position. EndTime is the cluster
+// time to stop reading at (exclusive), or zero if the change stream is read until stopped.
+type changeStreamRestriction struct {
+	ResumeToken bson.Raw            `bson:"resumeToken,omitempty"`
+	StartTime   primitive.Timestamp `bson:"startTime"`
+	EndTime     primitive.Timestamp `bson:"endTime"`
+}
+
+// IsBounded returns whether the restriction has an end time.
+func (r changeStreamRestriction) IsBounded() bool {
+	return !r.EndTime.IsZero()
+}
+
+// IsEmpty returns whether the restriction is bounded and starts at or after its end time.
+func (r changeStreamRestriction) IsEmpty() bool {
+	return r.IsBounded() && r.StartTime.Compare(r.EndTime) >= 0
+}
+
+// changeStreamTracker is a tracker of a changeStreamRestriction.
+type changeStreamTracker struct {
+	rest    changeStreamRestriction
+	claimed primitive.Timestamp
+	stopped bool
+	err     error
+}
+
+// newChangeStreamTracker creates a new changeStreamTracker tracking the provided
+// changeStreamRestriction.
+func newChangeStreamTracker(rest changeStreamRestriction) *changeStreamTracker {
+	return &changeStreamTracker{
+		rest:    rest,
+		claimed: rest.StartTime,
+	}
+}
+
+// changeStreamResult holds information about the next position to process in a MongoDB change
+// stream. resumeToken is the resume token of the position, which may be empty for an idle change
+// stream without a post batch resume token. clusterTime is the cluster time of the change event,
+// or for an idle change stream the cluster time before which all change events have been read.
+type changeStreamResult struct {
+	resumeToken bson.Raw
+	clusterTime primitive.Timestamp
+}
+
+// TryClaim accepts a position representing a changeStreamResult of a change event or idle change
+// stream. The position is successfully claimed if the tracker has not yet completed the work
+// within its restriction and the cluster time of the position is before the end time of the
+// restriction, if any. A claimed position becomes the position reading resumes from.
+func (rt *changeStreamTracker) TryClaim(pos any) (ok bool) {
+	result, ok := pos.(changeStreamResult)
+	if !ok {
+		rt.err = fmt.Errorf("invalid pos type: %T", pos)
+		return false
+	}
+
+	if rt.IsDone() {
+		return false
+	}
+
+	if rt.rest.IsBounded() && result.clusterTime.Compare(rt.rest.EndTime) >= 0 {
+		rt.stopped = true
+		return false
+	}
+
+	if len(result.resumeToken) > 0 {
+		rt.rest.ResumeToken = result.resumeToken
+	}
+	if result.clusterTime.After(rt.claimed) {
+		rt.claimed = result.clusterTime
+	}
+
+	return true
+}
+
+// GetError returns the error associated with the tracker, if any.
+func (rt *changeStreamTracker) GetError() error {
+	return rt.err
+}
+
+// TrySplit splits the underlying restriction into a primary and residual restriction. Only
+// checkpointing is supported, as a change stream cannot be split by position. If the fraction is
+// 0, stops the tracker and returns the restriction read so far as the primary and a residual
+// resuming from the last claimed position. Otherwise, returns the full restriction as the primary
+// and nil as the residual.
+func (rt *changeStreamTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	if fraction < 0 || fraction > 1 {
+		return nil, nil, errors.New("fraction must be between 0 and 1")
+	}
+
+	if fraction > 0 || rt.IsDone() {
+		return rt.rest, nil, nil
+	}
+
+	resid := changeStreamRestriction{
+		ResumeToken: rt.rest.ResumeToken,
+		StartTime:   rt.claimed,
+		EndTime:     rt.rest.EndTime,
+	}
+
+	rt.stopped = true
+
+	return rt.rest, resid, nil
+}
+
+// GetProgress returns the amount of done and remaining work, represented by seconds of cluster
+// time. The remaining work of an unbounded restriction is reported as 1.
+func (rt *changeStreamTracker) GetProgress() (done float64, remaining float64) {
+	done = float64(rt.claimed.T) - float64(rt.rest.StartTime.T)
+
+	if !rt.rest.IsBounded() {
+		return done, 1
+	}
+
+	if rt.IsDone() {
+		return done, 0
+	}
+
+	remaining = float64(rt.rest.EndTime.T) - float64(rt.claimed.T)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return done, remaining
+}
+
+// IsDone returns true if all work within the tracker's restriction has been completed.
+func (rt *changeStreamTracker) IsDone() bool {
+	return rt.stopped || rt.rest.IsEmpty()
+}
+
+// GetRestriction returns a copy of the restriction the tracker is tracking.
+func (rt *changeStreamTracker) GetRestriction() any {
+	return rt.rest
+}
+
+// IsBounded returns whether the tracker is tracking a restriction with a finite amount of work.
+func (rt *changeStreamTracker) IsBounded() bool {
+	return rt.rest.IsBounded()
+}
@@ -0,0 +1,306 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodbio
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func resumeToken(t *testing.T, data string) bson.Raw {
+	t.Helper()
+
+	token, err := bson.Marshal(bson.M{"_data": data})
+	if err != nil {
+		t.Fatalf("error marshaling resume token: %v", err)
+	}
+
+	return token
+}
+
+func Test_changeStreamTracker_TryClaim(t *testing.T) {
+	token1 := resumeToken(t, "token1")
+	token2 := resumeToken(t, "token2")
+
+	tests := []struct {
+		name        string
+		tracker     *changeStreamTracker
+		pos         any
+		wantOk      bool
+		wantToken   bson.Raw
+		wantClaimed primitive.Timestamp
+		wantDone    bool
+		wantErr     bool
+	}{
+		{
+			name: "Return true and update resume token when restriction is unbounded",
+			tracker: &changeStreamTracker{
+				rest:    changeStreamRestriction{ResumeToken: token1, StartTime: primitive.Timestamp{T: 10}},
+				claimed: primitive.Timestamp{T: 10},
+			},
+			pos:         changeStreamResult{resumeToken: token2, clusterTime: primitive.Timestamp{T: 20}},
+			wantOk:      true,
+			wantToken:   token2,
+			wantClaimed: primitive.Timestamp{T: 20},
+			wantDone:    false,
+		},
+		{
+			name: "Return true and keep resume token when position has none",
+			tracker: &changeStreamTracker{
+				rest:    changeStreamRestriction{ResumeToken: token1, StartTime: primitive.Timestamp{T: 10}},
+				claimed: primitive.Timestamp{T: 10},
+			},
+			pos:         changeStreamResult{clusterTime: primitive.Timestamp{T: 20}},
+			wantOk:      true,
+			wantToken:   token1,
+			wantClaimed: primitive.Timestamp{T: 20},
+			wantDone:    false,
+		},
+		{
+			name: "Return true when cluster time is before end time",
+			tracker: &changeStreamTracker{
+				rest: changeStreamRestriction{
+					StartTime: primitive.Timestamp{T: 10},
+					EndTime:   primitive.Timestamp{T: 30},
+				},
+				claimed: primitive.Timestamp{T: 10},
+			},
+			pos:         changeStreamResult{resumeToken: token1, clusterTime: primitive.Timestamp{T: 29, I: 5}},
+			wantOk:      true,
+			wantToken:   token1,
+			wantClaimed: primitive.Timestamp{T: 29, I: 5},
+			wantDone:    false,
+		},
+		{
+			name: "Return false and set to done when cluster time is not before end time",
+			tracker: &changeStreamTracker{
+				rest: changeStreamRestriction{
+					StartTime: primitive.Timestamp{T: 10},
+					EndTime:   primitive.Timestamp{T: 30},
+				},
+				claimed: primitive.Timestamp{T: 10},
+			},
+			pos:         changeStreamResult{resumeToken: token1, clusterTime: primitive.Timestamp{T: 30}},
+			wantOk:      false,
+			wantClaimed: primitive.Timestamp{T: 10},
+			wantDone:    true,
+		},
+		{
+			name: "Return false when tracker is stopped",
+			tracker: &changeStreamTracker{
+				rest:    changeStreamRestriction{StartTime: primitive.Timestamp{T: 10}},
+				claimed: primitive.Timestamp{T: 10},
+				stopped: true,
+			},
+			pos:         changeStreamResult{resumeToken: token1, clusterTime: primitive.Timestamp{T: 20}},
+			wantOk:      false,
+			wantClaimed: primitive.Timestamp{T: 10},
+			wantDone:    true,
+		},
+		{
+			name: "Return false and set error when pos is of invalid type",
+			tracker: &changeStreamTracker{
+				rest:    changeStreamRestriction{StartTime: primitive.Timestamp{T: 10}},
+				claimed: primitive.Timestamp{T: 10},
+			},
+			pos:         "invalid",
+			wantOk:      false,
+			wantClaimed: primitive.Timestamp{T: 10},
+			wantDone:    false,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotOk := tt.tracker.TryClaim(tt.pos); gotOk != tt.wantOk {
+				t.Errorf("TryClaim() = %v, want %v", gotOk, tt.wantOk)
+			}
+			if got := tt.tracker.rest.ResumeToken; !cmp.Equal(got, tt.wantToken) {
+				t.Errorf("rest.ResumeToken = %v, want %v", got, tt.wantToken)
+			}
+			if got := tt.tracker.claimed; got != tt.wantClaimed {
+				t.Errorf("claimed = %v, want %v", got, tt.wantClaimed)
+			}
+			if got := tt.tracker.IsDone(); got != tt.wantDone {
+				t.Errorf("IsDone() = %v, want %v", got, tt.wantDone)
+			}
+			if gotErr := tt.tracker.GetError(); (gotErr != nil) != tt.wantErr {
+				t.Errorf("GetError() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_changeStreamTracker_TrySplit(t *testing.T) {
+	token := resumeToken(t, "token")
+	rest := changeStreamRestriction{
+		ResumeToken: token,
+		StartTime:   primitive.Timestamp{T: 10},
+		EndTime:     primitive.Timestamp{T: 100},
+	}
+
+	tests := []struct {
+		name         string
+		tracker      *changeStreamTracker
+		fraction     float64
+		wantPrimary  any
+		wantResidual any
+		wantDone     bool
+		wantErr      bool
+	}{
+		{
+			name:        "Checkpoint from the last claimed position when fraction is 0",
+			tracker:     &changeStreamTracker{rest: rest, claimed: primitive.Timestamp{T: 50, I: 2}},
+			fraction:    0,
+			wantPrimary: rest,
+			wantResidual: changeStreamRestriction{
+				ResumeToken: token,
+				StartTime:   primitive.Timestamp{T: 50, I: 2},
+				EndTime:     primitive.Timestamp{T: 100},
+			},
+			wantDone: true,
+		},
+		{
+			name:         "Return full restriction as primary when fraction is greater than 0",
+			tracker:      &changeStreamTracker{rest: rest, claimed: primitive.Timestamp{T: 50}},
+			fraction:     0.5,
+			wantPrimary:  rest,
+			wantResidual: nil,
+			wantDone:     false,
+		},
+		{
+			name:         "Return full restriction as primary when tracker is done",
+			tracker:      &changeStreamTracker{rest: rest, claimed: primitive.Timestamp{T: 50}, stopped: true},
+			fraction:     0,
+			wantPrimary:  rest,
+			wantResidual: nil,
+			wantDone:     true,
+		},
+		{
+			name:     "Error - fraction is less than 0",
+			tracker:  &changeStreamTracker{rest: rest},
+			fraction: -0.1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPrimary, gotResidual, err := tt.tracker.TrySplit(tt.fraction)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TrySplit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.wantPrimary, gotPrimary); diff != "" {
+				t.Errorf("TrySplit() primary mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantResidual, gotResidual); diff != "" {
+				t.Errorf("TrySplit() residual mismatch (-want +got):\n%s", diff)
+			}
+			if got := tt.tracker.IsDone(); got != tt.wantDone {
+				t.Errorf("IsDone() = %v, want %v", got, tt.wantDone)
+			}
+		})
+	}
+}
+
+func Test_changeStreamTracker_GetProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		tracker       *changeStreamTracker
+		wantDone      float64
+		wantRemaining float64
+	}{
+		{
+			name: "Progress in seconds of cluster time when restriction is bounded",
+			tracker: &changeStreamTracker{
+				rest: changeStreamRestriction{
+					StartTime: primitive.Timestamp{T: 10},
+					EndTime:   primitive.Timestamp{T: 100},
+				},
+				claimed: primitive.Timestamp{T: 40},
+			},
+			wantDone:      30,
+			wantRemaining: 60,
+		},
+		{
+			name: "Remaining work of 1 when restriction is unbounded",
+			tracker: &changeStreamTracker{
+				rest:    changeStreamRestriction{StartTime: primitive.Timestamp{T: 10}},
+				claimed: primitive.Timestamp{T: 40},
+			},
+			wantDone:      30,
+			wantRemaining: 1,
+		},
+		{
+			name: "No remaining work when tracker is done",
+			tracker: &changeStreamTracker{
+				rest: changeStreamRestriction{
+					StartTime: primitive.Timestamp{T: 10},
+					EndTime:   primitive.Timestamp{T: 100},
+				},
+				claimed: primitive.Timestamp{T: 40},
+				stopped: true,
+			},
+			wantDone:      30,
+			wantRemaining: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDone, gotRemaining := tt.tracker.GetProgress()
+			if gotDone != tt.wantDone {
+				t.Errorf("GetProgress() done = %v, want %v", gotDone, tt.wantDone)
+			}
+			if gotRemaining != tt.wantRemaining {
+				t.Errorf("GetProgress() remaining = %v, want %v", gotRemaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func Test_changeStreamTracker_IsDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracker *changeStreamTracker
+		want    bool
+	}{
+		{
+			name:    "Not done when restriction is unbounded",
+			tracker: newChangeStreamTracker(changeStreamRestriction{StartTime: primitive.Timestamp{T: 10}}),
+			want:    false,
+		},
+		{
+			name: "Done when restriction starts at its end time",
+			tracker: newChangeStreamTracker(changeStreamRestriction{
+				StartTime: primitive.Timestamp{T: 10},
+				EndTime:   primitive.Timestamp{T: 10},
+			}),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tracker.IsDone(); got != tt.want {
+				t.Errorf("IsDone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -98,6 +98,48 @@ func Test_encodeDecodeIDRangeRestriction(t *testing.T) {
 	}
 }
 
+func Test_encodeDecodeChangeStreamRestriction(t *testing.T) {
+	token, err := bson.Marshal(bson.M{"_data": "8263A1B2C3000000012B"})
+	if err != nil {
+		t.Fatalf("error marshaling resume token: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		rest changeStreamRestriction
+	}{
+		{
+			name: "Encode/decode changeStreamRestriction",
+			rest: changeStreamRestriction{
+				ResumeToken: token,
+				StartTime:   primitive.Timestamp{T: 1672531200, I: 1},
+				EndTime:     primitive.Timestamp{T: 1672534800},
+			},
+		},
+		{
+			name: "Encode/decode empty changeStreamRestriction",
+			rest: changeStreamRestriction{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeChangeStreamRestriction(tt.rest)
+			if err != nil {
+				t.Fatalf("encodeChangeStreamRestriction() error = %v", err)
+			}
+
+			decoded, err := decodeChangeStreamRestriction(encoded)
+			if err != nil {
+				t.Fatalf("decodeChangeStreamRestriction() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.rest, decoded); diff != "" {
+				t.Errorf("encode/decode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func Test_encodeDecodeObjectID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -80,6 +80,32 @@ func ExampleRead_options() {
 	}
 }
 
+func ExampleReadChangeStream() {
+	type Event struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		Timestamp int64              `bson:"timestamp"`
+		EventType int32              `bson:"event_type"`
+	}
+
+	beam.Init()
+	p, s := beam.NewPipelineWithRoot()
+
+	col := mongodbio.ReadChangeStream(
+		s,
+		"mongodb://localhost:27017/?replicaSet=rs0",
+		"demo",
+		"events",
+		reflect.TypeOf(Event{}),
+		mongodbio.WithChangeStreamOperationTypes("insert", "update", "replace"),
+		mongodbio.WithChangeStreamStartTime(time.Now().Add(-1*time.Hour)),
+	)
+	debug.Print(s, col)
+
+	if err := beamx.Run(context.Background(), p); err != nil {
+		log.Fatalf("Failed to execute job: %v", err)
+	}
+}
+
 func ExampleWrite_default() {
 	type Event struct {
 		ID        primitive.ObjectID `bson:"_id"`
@@ -71,3 +71,85 @@ func ExampleWrite() {
 		log.Fatalf("Failed to execute job: %v", err)
 	}
 }
+
+func ExampleReadKV() {
+	beam.Init()
+
+	p, s := beam.NewPipelineWithRoot()
+
+	uri := "nats://localhost:4222"
+	bucket := "CONFIGS"
+
+	col := natsio.ReadKV(s, uri, bucket, natsio.ReadKVKeys("service.>"), natsio.ReadKVWatch())
+	debug.Print(s, col)
+
+	if err := beamx.Run(context.Background(), p); err != nil {
+		log.Fatalf("Failed to execute job: %v", err)
+	}
+}
+
+func ExampleWriteKV() {
+	beam.Init()
+
+	p, s := beam.NewPipelineWithRoot()
+
+	uri := "nats://localhost:4222"
+	bucket := "CONFIGS"
+	mutations := []natsio.KVMutation{
+		{
+			Key:   "service.a",
+			Value: []byte(`{"replicas": 3}`),
+		},
+		{
+			Key:      "service.b",
+			Delete:   true,
+			Revision: 12,
+		},
+	}
+
+	input := beam.CreateList(s, mutations)
+	natsio.WriteKV(s, uri, bucket, input)
+
+	if err := beamx.Run(context.Background(), p); err != nil {
+		log.Fatalf("Failed to execute job: %v", err)
+	}
+}
+
+func ExampleReadObjects() {
+	beam.Init()
+
+	p, s := beam.NewPipelineWithRoot()
+
+	uri := "nats://localhost:4222"
+	bucket := "FILES"
+
+	col := natsio.ReadObjects(s, uri, bucket)
+	debug.Print(s, col)
+
+	if err := beamx.Run(context.Background(), p); err != nil {
+		log.Fatalf("Failed to execute job: %v", err)
+	}
+}
+
+func ExampleWriteObjects() {
+	beam.Init()
+
+	p, s := beam.NewPipelineWithRoot()
+
+	uri := "nats://localhost:4222"
+	bucket := "FILES"
+	objects := []natsio.Object{
+		{
+			Name:        "config.yaml",
+			Description: "service configuration",
+			Data:        []byte("replicas: 3"),
+		},
+	}
+
+	input := beam.CreateList(s, objects)
+	natsio.WriteObjects(s, uri, bucket, input)
+
+	if err := beamx.Run(context.Background(), p); err != nil {
+		log.Fatalf("Failed to execute job: %v", err)
+	}
+}
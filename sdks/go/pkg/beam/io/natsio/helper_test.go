@@ -17,6 +17,7 @@ package natsio
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -172,3 +173,104 @@ func messageTimestamp(t *testing.T, msg jetstream.Msg) time.Time {
 
 	return metadata.Timestamp
 }
+
+func createKeyValue(
+	ctx context.Context,
+	t *testing.T,
+	js jetstream.JetStream,
+	bucket string,
+) jetstream.KeyValue {
+	t.Helper()
+
+	cfg := jetstream.KeyValueConfig{
+		Bucket:  bucket,
+		History: 5,
+	}
+	kv, err := js.CreateKeyValue(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create key-value bucket: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := js.DeleteKeyValue(ctx, bucket); err != nil {
+			t.Fatalf("Failed to delete key-value bucket: %v", err)
+		}
+	})
+
+	return kv
+}
+
+// kvOp is a put of a value to a key, or a delete of the key if the value is nil.
+type kvOp struct {
+	key   string
+	value []byte
+}
+
+func applyKVOps(ctx context.Context, t *testing.T, kv jetstream.KeyValue, ops []kvOp) {
+	t.Helper()
+
+	for _, op := range ops {
+		var err error
+		if op.value == nil {
+			err = kv.Delete(ctx, op.key)
+		} else {
+			_, err = kv.Put(ctx, op.key, op.value)
+		}
+		if err != nil {
+			t.Fatalf("Failed to apply operation on key %q: %v", op.key, err)
+		}
+	}
+}
+
+// kvHistory returns the entries of all revisions of the keys, indexed by revision.
+func kvHistory(
+	ctx context.Context,
+	t *testing.T,
+	kv jetstream.KeyValue,
+	keys []string,
+) map[uint64]KVEntry {
+	t.Helper()
+
+	entries := make(map[uint64]KVEntry)
+
+	for _, key := range keys {
+		history, err := kv.History(ctx, key)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to get history of key %q: %v", key, err)
+		}
+
+		for _, entry := range history {
+			entries[entry.Revision()] = createKVEntry(entry)
+		}
+	}
+
+	return entries
+}
+
+func createObjectStore(
+	ctx context.Context,
+	t *testing.T,
+	js jetstream.JetStream,
+	bucket string,
+) jetstream.ObjectStore {
+	t.Helper()
+
+	cfg := jetstream.ObjectStoreConfig{
+		Bucket: bucket,
+	}
+	store, err := js.CreateObjectStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create object store: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := js.DeleteObjectStore(ctx, bucket); err != nil {
+			t.Fatalf("Failed to delete object store: %v", err)
+		}
+	})
+
+	return store
+}
@@ -0,0 +1,410 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func init() {
+	register.DoFn5x2[
+		context.Context, *watermarkEstimator, *sdf.LockRTracker, []byte,
+		func(beam.EventTime, KVEntry), sdf.ProcessContinuation, error,
+	](
+		&readKVFn{},
+	)
+	register.Emitter2[beam.EventTime, KVEntry]()
+	register.DoFn2x1[context.Context, KVMutation, error](&writeKVFn{})
+
+	beam.RegisterType(reflect.TypeOf((*KVEntry)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*KVMutation)(nil)).Elem())
+}
+
+// Operations of KVEntry.
+const (
+	KVPut    = "PUT"
+	KVDelete = "DEL"
+	KVPurge  = "PURGE"
+)
+
+// KVEntry represents an entry of a NATS key-value bucket. Operation is KVPut for entries setting
+// the value of a key, and KVDelete or KVPurge for entries deleting a key.
+type KVEntry struct {
+	Bucket    string
+	Key       string
+	Value     []byte
+	Revision  uint64
+	Created   time.Time
+	Operation string
+}
+
+// KVMutation represents a change to a key of a NATS key-value bucket. The value of the key is set
+// to Value, or the key is deleted if Delete is true. If Revision is greater than 0, the change is
+// only applied if Revision is the latest revision of the key.
+type KVMutation struct {
+	Key      string
+	Value    []byte
+	Delete   bool
+	Revision uint64
+}
+
+// ReadKV reads the entries of a NATS key-value bucket and returns a PCollection<KVEntry>. By
+// default, the latest entries of the keys with values are read, and the read is bounded. With
+// ReadKVWatch, the bucket is watched for changes after reading the latest entries, which are read
+// as they occur, including deletes. The revision of the last read entry is used to checkpoint the
+// read, and a watch resumed from a checkpoint also reads the entries that were replaced in the
+// meantime, if the bucket keeps them in its history.
+// ReadKV takes a variable number of ReadKVOptionFn to configure the read operation:
+//   - UserCredentials: path to the user credentials file. Defaults to empty.
+//   - ProcessingTimePolicy: whether to use the pipeline processing time of the entries as the event
+//     time. Defaults to true.
+//   - PublishingTimePolicy: whether to use the creation time of the entries as the event time.
+//     Defaults to false.
+//   - Keys: the keys to read, which may contain wildcards. Defaults to all keys.
+//   - Watch: whether to watch the bucket for changes. Defaults to false.
+func ReadKV(s beam.Scope, uri string, bucket string, opts ...ReadKVOptionFn) beam.PCollection {
+	s = s.Scope("natsio.ReadKV")
+
+	option := &readKVOption{
+		TimePolicy: processingTimePolicy,
+	}
+
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	imp := beam.Impulse(s)
+	return beam.ParDo(s, newReadKVFn(uri, bucket, option), imp)
+}
+
+type readKVFn struct {
+	natsFn
+	Bucket      string
+	Keys        []string
+	Watch       bool
+	TimePolicy  timePolicy
+	kv          jetstream.KeyValue
+	timestampFn timestampFn
+}
+
+func newReadKVFn(uri string, bucket string, option *readKVOption) *readKVFn {
+	return &readKVFn{
+		natsFn: natsFn{
+			URI:       uri,
+			CredsFile: option.CredsFile,
+		},
+		Bucket:     bucket,
+		Keys:       option.Keys,
+		Watch:      option.Watch,
+		TimePolicy: option.TimePolicy,
+	}
+}
+
+func (fn *readKVFn) Setup(ctx context.Context) error {
+	if err := fn.natsFn.Setup(); err != nil {
+		return err
+	}
+
+	kv, err := fn.js.KeyValue(ctx, fn.Bucket)
+	if err != nil {
+		return fmt.Errorf("error getting key-value bucket: %v", err)
+	}
+	fn.kv = kv
+
+	fn.timestampFn = fn.TimePolicy.TimestampFn()
+	return nil
+}
+
+// CreateInitialRestriction returns a restriction of the revisions to read. The revisions of a
+// bucket are the sequence numbers of its stream, which a bounded read reads up to the last one.
+func (fn *readKVFn) CreateInitialRestriction(
+	ctx context.Context,
+	_ []byte,
+) (offsetrange.Restriction, error) {
+	if fn.Watch {
+		return offsetrange.Restriction{Start: defaultStartSeqNo, End: defaultEndSeqNo}, nil
+	}
+
+	if err := fn.natsFn.Setup(); err != nil {
+		return offsetrange.Restriction{}, err
+	}
+
+	str, err := fn.js.Stream(ctx, kvStream(fn.Bucket))
+	if err != nil {
+		return offsetrange.Restriction{}, fmt.Errorf("error getting stream: %v", err)
+	}
+
+	end := int64(str.CachedInfo().State.LastSeq) + 1
+	return offsetrange.Restriction{Start: defaultStartSeqNo, End: end}, nil
+}
+
+func (fn *readKVFn) SplitRestriction(
+	_ []byte,
+	rest offsetrange.Restriction,
+) []offsetrange.Restriction {
+	return []offsetrange.Restriction{rest}
+}
+
+func (fn *readKVFn) RestrictionSize(_ []byte, rest offsetrange.Restriction) (float64, error) {
+	if err := fn.natsFn.Setup(); err != nil {
+		return -1, err
+	}
+
+	rt, err := fn.createRTracker(rest)
+	if err != nil {
+		return -1, err
+	}
+
+	_, remaining := rt.GetProgress()
+	return remaining, nil
+}
+
+func (fn *readKVFn) CreateTracker(rest offsetrange.Restriction) (*sdf.LockRTracker, error) {
+	rt, err := fn.createRTracker(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdf.NewLockRTracker(rt), nil
+}
+
+func (fn *readKVFn) TruncateRestriction(rt *sdf.LockRTracker, _ []byte) offsetrange.Restriction {
+	start := rt.GetRestriction().(offsetrange.Restriction).Start
+	return offsetrange.Restriction{
+		Start: start,
+		End:   start,
+	}
+}
+
+func (fn *readKVFn) InitialWatermarkEstimatorState(
+	et beam.EventTime,
+	_ offsetrange.Restriction,
+	_ []byte,
+) int64 {
+	return et.Milliseconds()
+}
+
+func (fn *readKVFn) CreateWatermarkEstimator(ms int64) *watermarkEstimator {
+	return &watermarkEstimator{state: ms}
+}
+
+func (fn *readKVFn) WatermarkEstimatorState(we *watermarkEstimator) int64 {
+	return we.state
+}
+
+func (fn *readKVFn) ProcessElement(
+	ctx context.Context,
+	we *watermarkEstimator,
+	rt *sdf.LockRTracker,
+	_ []byte,
+	emit func(beam.EventTime, KVEntry),
+) (sdf.ProcessContinuation, error) {
+	start := rt.GetRestriction().(offsetrange.Restriction).Start
+
+	// A read from the first revision starts with the latest entries of the keys, of which deletes
+	// are skipped, and a resumed watch continues from the next revision. A resumed or split bounded
+	// read starts with the latest entries too, skipping those before its first revision, so that
+	// it doesn't read the entries replaced in the meantime.
+	initial := start <= defaultStartSeqNo || !fn.Watch
+	var opts []jetstream.WatchOpt
+	if !initial {
+		opts = append(opts, jetstream.IncludeHistory(), jetstream.ResumeFromRevision(uint64(start)))
+	}
+
+	watcher, err := fn.kv.WatchFiltered(ctx, append([]string(nil), fn.Keys...), opts...)
+	if err != nil {
+		return sdf.StopProcessing(), fmt.Errorf("error watching bucket: %v", err)
+	}
+	defer watcher.Stop()
+
+	timer := time.NewTimer(fetchTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return sdf.StopProcessing(), errors.New("watcher closed unexpectedly")
+			}
+
+			// A nil entry marks that the entries up to the start of the watch have been read.
+			if entry == nil {
+				initial = false
+				if !fn.Watch {
+					rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).End)
+					return sdf.StopProcessing(), nil
+				}
+				continue
+			}
+
+			if int64(entry.Revision()) < start {
+				continue
+			}
+			if !rt.TryClaim(int64(entry.Revision())) {
+				return sdf.StopProcessing(), nil
+			}
+
+			if initial && entry.Operation() != jetstream.KeyValuePut {
+				continue
+			}
+
+			et := fn.timestampFn(entry.Created())
+			emit(et, createKVEntry(entry))
+
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(fetchTimeout)
+		case <-timer.C:
+			fn.updateWatermarkManually(we)
+			return sdf.ResumeProcessingIn(resumeDelay), nil
+		}
+	}
+}
+
+func (fn *readKVFn) createRTracker(rest offsetrange.Restriction) (sdf.RTracker, error) {
+	if rest.End < math.MaxInt64 {
+		return offsetrange.NewTracker(rest), nil
+	}
+
+	estimator := newEndEstimator(fn.js, kvStream(fn.Bucket), kvSubject(fn.Bucket, ">"))
+	rt, err := offsetrange.NewGrowableTracker(rest, estimator)
+	if err != nil {
+		return nil, fmt.Errorf("error creating growable tracker: %v", err)
+	}
+
+	return rt, nil
+}
+
+func (fn *readKVFn) updateWatermarkManually(we *watermarkEstimator) {
+	t := time.Now().Add(-1 * assumedLag)
+	et := fn.timestampFn(t)
+	we.ObserveTimestamp(et.ToTime())
+}
+
+// kvStream returns the name of the stream backing a key-value bucket.
+func kvStream(bucket string) string {
+	return "KV_" + bucket
+}
+
+// kvSubject returns the subject of a key in the stream backing a key-value bucket.
+func kvSubject(bucket string, key string) string {
+	return "$KV." + bucket + "." + key
+}
+
+func createKVEntry(entry jetstream.KeyValueEntry) KVEntry {
+	op := KVPut
+	switch entry.Operation() {
+	case jetstream.KeyValueDelete:
+		op = KVDelete
+	case jetstream.KeyValuePurge:
+		op = KVPurge
+	}
+
+	return KVEntry{
+		Bucket:    entry.Bucket(),
+		Key:       entry.Key(),
+		Value:     entry.Value(),
+		Revision:  entry.Revision(),
+		Created:   entry.Created(),
+		Operation: op,
+	}
+}
+
+// WriteKV writes a PCollection<KVMutation> to a NATS key-value bucket, which must exist. The
+// mutations setting values put the values to the keys, and the others delete the keys. Mutations
+// with a revision are only applied if it is the latest revision of the key, and fail the write
+// otherwise.
+// WriteKV takes a variable number of WriteOptionFn to configure the write operation:
+//   - UserCredentials: path to the user credentials file. Defaults to empty.
+func WriteKV(s beam.Scope, uri string, bucket string, col beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("natsio.WriteKV")
+
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	beam.ParDo0(s, newWriteKVFn(uri, bucket, option), col)
+}
+
+type writeKVFn struct {
+	natsFn
+	Bucket string
+	kv     jetstream.KeyValue
+}
+
+func newWriteKVFn(uri string, bucket string, option *writeOption) *writeKVFn {
+	return &writeKVFn{
+		natsFn: natsFn{
+			URI:       uri,
+			CredsFile: option.CredsFile,
+		},
+		Bucket: bucket,
+	}
+}
+
+func (fn *writeKVFn) Setup(ctx context.Context) error {
+	if err := fn.natsFn.Setup(); err != nil {
+		return err
+	}
+
+	kv, err := fn.js.KeyValue(ctx, fn.Bucket)
+	if err != nil {
+		return fmt.Errorf("error getting key-value bucket: %v", err)
+	}
+	fn.kv = kv
+
+	return nil
+}
+
+func (fn *writeKVFn) ProcessElement(ctx context.Context, elem KVMutation) error {
+	if elem.Delete {
+		var opts []jetstream.KVDeleteOpt
+		if elem.Revision > 0 {
+			opts = append(opts, jetstream.LastRevision(elem.Revision))
+		}
+
+		if err := fn.kv.Delete(ctx, elem.Key, opts...); err != nil {
+			return fmt.Errorf("error deleting key %q: %v", elem.Key, err)
+		}
+
+		return nil
+	}
+
+	var err error
+	if elem.Revision > 0 {
+		_, err = fn.kv.Update(ctx, elem.Key, elem.Value, elem.Revision)
+	} else {
+		_, err = fn.kv.Put(ctx, elem.Key, elem.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("error putting key %q: %v", elem.Key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+type readKVOption struct {
+	CredsFile  string
+	TimePolicy timePolicy
+	Keys       []string
+	Watch      bool
+}
+
+// ReadKVOptionFn is a function that can be passed to ReadKV to configure options for reading
+// from a NATS key-value bucket.
+type ReadKVOptionFn func(option *readKVOption)
+
+// ReadKVUserCredentials sets the user credentials when connecting to NATS.
+func ReadKVUserCredentials(credsFile string) ReadKVOptionFn {
+	return func(o *readKVOption) {
+		o.CredsFile = credsFile
+	}
+}
+
+// ReadKVProcessingTimePolicy specifies that the pipeline processing time of the entries should be
+// used to compute the watermark estimate.
+func ReadKVProcessingTimePolicy() ReadKVOptionFn {
+	return func(o *readKVOption) {
+		o.TimePolicy = processingTimePolicy
+	}
+}
+
+// ReadKVPublishingTimePolicy specifies that the creation time of the entries should be used to
+// compute the watermark estimate.
+func ReadKVPublishingTimePolicy() ReadKVOptionFn {
+	return func(o *readKVOption) {
+		o.TimePolicy = publishingTimePolicy
+	}
+}
+
+// ReadKVKeys sets the keys to read, which may contain the wildcards "*" and ">".
+func ReadKVKeys(keys ...string) ReadKVOptionFn {
+	return func(o *readKVOption) {
+		o.Keys = keys
+	}
+}
+
+// ReadKVWatch specifies that the bucket should be watched for changes after reading its current
+// entries, which makes the read unbounded.
+func ReadKVWatch() ReadKVOptionFn {
+	return func(o *readKVOption) {
+		o.Watch = true
+	}
+}
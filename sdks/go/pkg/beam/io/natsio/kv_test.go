@@ -0,0 +1,249 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+var testKVOps = []kvOp{
+	{key: "a", value: []byte("a1")},
+	{key: "b", value: []byte("b1")},
+	{key: "a", value: []byte("a2")},
+	{key: "b"},
+	{key: "c", value: []byte("c1")},
+}
+
+func TestReadKV(t *testing.T) {
+	tests := []struct {
+		name     string
+		ops      []kvOp
+		opts     []ReadKVOptionFn
+		wantRevs []uint64
+	}{
+		{
+			name:     "Read latest entries of all keys",
+			ops:      testKVOps,
+			wantRevs: []uint64{3, 5},
+		},
+		{
+			name: "Read latest entries of filtered keys",
+			ops:  testKVOps,
+			opts: []ReadKVOptionFn{
+				ReadKVKeys("a", "b"),
+			},
+			wantRevs: []uint64{3},
+		},
+		{
+			name: "Read empty bucket",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := newServer(t)
+			uri := srv.ClientURL()
+			conn := newConn(t, uri)
+			js := newJetStream(t, conn)
+
+			bucket := fmt.Sprintf("BUCKET-%d", i)
+			kv := createKeyValue(ctx, t, js, bucket)
+			applyKVOps(ctx, t, kv, tt.ops)
+			history := kvHistory(ctx, t, kv, []string{"a", "b", "c"})
+
+			p, s := beam.NewPipelineWithRoot()
+			got := ReadKV(s, uri, bucket, tt.opts...)
+
+			var want []any
+			for _, rev := range tt.wantRevs {
+				want = append(want, history[rev])
+			}
+			passert.Equals(s, got, want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func Test_readKVFn_ProcessElement(t *testing.T) {
+	tests := []struct {
+		name       string
+		watch      bool
+		rest       offsetrange.Restriction
+		wantRevs   []uint64
+		wantResume bool
+	}{
+		{
+			name:       "Resume from revision and read latest entries",
+			rest:       offsetrange.Restriction{Start: 2, End: 6},
+			wantRevs:   []uint64{3, 5},
+			wantResume: false,
+		},
+		{
+			name:       "Resume from revision and read latest entries up to the end",
+			rest:       offsetrange.Restriction{Start: 1, End: 4},
+			wantRevs:   []uint64{3},
+			wantResume: false,
+		},
+		{
+			name:       "Resume watch from revision and read all later entries",
+			watch:      true,
+			rest:       offsetrange.Restriction{Start: 2, End: math.MaxInt64},
+			wantRevs:   []uint64{2, 3, 4, 5},
+			wantResume: true,
+		},
+		{
+			name:       "Watch bucket after reading latest entries",
+			watch:      true,
+			rest:       offsetrange.Restriction{Start: 1, End: math.MaxInt64},
+			wantRevs:   []uint64{3, 5},
+			wantResume: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := newServer(t)
+			uri := srv.ClientURL()
+			conn := newConn(t, uri)
+			js := newJetStream(t, conn)
+
+			bucket := fmt.Sprintf("BUCKET-%d", i)
+			kv := createKeyValue(ctx, t, js, bucket)
+			applyKVOps(ctx, t, kv, testKVOps)
+			history := kvHistory(ctx, t, kv, []string{"a", "b", "c"})
+
+			option := &readKVOption{TimePolicy: publishingTimePolicy, Watch: tt.watch}
+			fn := newReadKVFn(uri, bucket, option)
+			if err := fn.Setup(ctx); err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			t.Cleanup(fn.Teardown)
+
+			rt, err := fn.CreateTracker(tt.rest)
+			if err != nil {
+				t.Fatalf("CreateTracker() error = %v", err)
+			}
+			we := fn.CreateWatermarkEstimator(0)
+
+			var got []KVEntry
+			emit := func(et beam.EventTime, entry KVEntry) {
+				if want := entry.Created.UnixMilli(); et.Milliseconds() != want {
+					t.Errorf("event time of revision %d = %v, want %v", entry.Revision, et.Milliseconds(), want)
+				}
+				got = append(got, entry)
+			}
+
+			cont, err := fn.ProcessElement(ctx, we, rt, nil, emit)
+			if err != nil {
+				t.Fatalf("ProcessElement() error = %v", err)
+			}
+
+			var want []KVEntry
+			for _, rev := range tt.wantRevs {
+				want = append(want, history[rev])
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ProcessElement() entries mismatch (-want +got):\n%s", diff)
+			}
+			if cont.ShouldResume() != tt.wantResume {
+				t.Errorf("ShouldResume() = %v, want %v", cont.ShouldResume(), tt.wantResume)
+			}
+			if !tt.wantResume && !rt.IsDone() {
+				t.Errorf("IsDone() = false, want true")
+			}
+			if tt.wantResume && we.state == 0 {
+				t.Errorf("watermark was not advanced while idle")
+			}
+		})
+	}
+}
+
+func TestWriteKV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []any
+		wantErr   bool
+		wantState map[string]string
+	}{
+		{
+			name: "Put and delete keys with and without revision checks",
+			input: []any{
+				KVMutation{Key: "a", Value: []byte("a2"), Revision: 1},
+				KVMutation{Key: "b", Delete: true, Revision: 2},
+				KVMutation{Key: "c", Value: []byte("c1")},
+			},
+			wantState: map[string]string{"a": "a2", "c": "c1"},
+		},
+		{
+			name: "Fail when revision is not the latest revision of the key",
+			input: []any{
+				KVMutation{Key: "a", Value: []byte("a2"), Revision: 2},
+			},
+			wantErr:   true,
+			wantState: map[string]string{"a": "a1", "b": "b1"},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := newServer(t)
+			uri := srv.ClientURL()
+			conn := newConn(t, uri)
+			js := newJetStream(t, conn)
+
+			bucket := fmt.Sprintf("BUCKET-%d", i)
+			kv := createKeyValue(ctx, t, js, bucket)
+			applyKVOps(ctx, t, kv, []kvOp{
+				{key: "a", value: []byte("a1")},
+				{key: "b", value: []byte("b1")},
+			})
+
+			p, s := beam.NewPipelineWithRoot()
+			col := beam.Create(s, tt.input...)
+			WriteKV(s, uri, bucket, col)
+
+			if err := ptest.Run(p); (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			gotState := make(map[string]string)
+			for _, key := range []string{"a", "b", "c"} {
+				entry, err := kv.Get(ctx, key)
+				if errors.Is(err, jetstream.ErrKeyNotFound) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Failed to get key %q: %v", key, err)
+				}
+				gotState[key] = string(entry.Value())
+			}
+			if diff := cmp.Diff(tt.wantState, gotState); diff != "" {
+				t.Errorf("bucket state mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
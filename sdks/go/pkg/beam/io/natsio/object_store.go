@@ -0,0 +1,210 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func init() {
+	register.DoFn3x1[context.Context, []byte, func(string), error](&listObjectsFn{})
+	register.DoFn3x1[context.Context, string, func(Object), error](&readObjectFn{})
+	register.DoFn2x1[context.Context, Object, error](&writeObjectFn{})
+	register.Emitter1[string]()
+	register.Emitter1[Object]()
+
+	beam.RegisterType(reflect.TypeOf((*Object)(nil)).Elem())
+}
+
+// Object represents an object of a NATS object store. Bucket, ModTime and Digest are set when
+// reading objects, and ignored when writing them.
+type Object struct {
+	Bucket      string
+	Name        string
+	Description string
+	Headers     map[string][]string
+	Metadata    map[string]string
+	ModTime     time.Time
+	Digest      string
+	Data        []byte
+}
+
+// ReadObjects reads the objects of a NATS object store and returns a PCollection<Object>. The
+// objects are read in parallel, each in full.
+// ReadObjects takes a variable number of ReadObjectsOptionFn to configure the read operation:
+//   - UserCredentials: path to the user credentials file. Defaults to empty.
+//   - Names: the names of the objects to read. Defaults to all objects, listed when the pipeline
+//     runs.
+func ReadObjects(s beam.Scope, uri string, bucket string, opts ...ReadObjectsOptionFn) beam.PCollection {
+	s = s.Scope("natsio.ReadObjects")
+
+	option := &readObjectsOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	fn := objectStoreFn{
+		natsFn: natsFn{
+			URI:       uri,
+			CredsFile: option.CredsFile,
+		},
+		Bucket: bucket,
+	}
+
+	var names beam.PCollection
+	if len(option.Names) > 0 {
+		names = beam.CreateList(s, option.Names)
+	} else {
+		imp := beam.Impulse(s)
+		names = beam.ParDo(s, &listObjectsFn{objectStoreFn: fn}, imp)
+	}
+
+	names = beam.Reshuffle(s, names)
+	return beam.ParDo(s, &readObjectFn{objectStoreFn: fn}, names)
+}
+
+type objectStoreFn struct {
+	natsFn
+	Bucket string
+	store  jetstream.ObjectStore
+}
+
+func (fn *objectStoreFn) Setup(ctx context.Context) error {
+	if err := fn.natsFn.Setup(); err != nil {
+		return err
+	}
+
+	store, err := fn.js.ObjectStore(ctx, fn.Bucket)
+	if err != nil {
+		return fmt.Errorf("error getting object store: %v", err)
+	}
+	fn.store = store
+
+	return nil
+}
+
+type listObjectsFn struct {
+	objectStoreFn
+}
+
+func (fn *listObjectsFn) ProcessElement(ctx context.Context, _ []byte, emit func(string)) error {
+	infos, err := fn.store.List(ctx)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrNoObjectsFound) {
+			return nil
+		}
+
+		return fmt.Errorf("error listing objects: %v", err)
+	}
+
+	for _, info := range infos {
+		emit(info.Name)
+	}
+
+	return nil
+}
+
+type readObjectFn struct {
+	objectStoreFn
+}
+
+func (fn *readObjectFn) ProcessElement(ctx context.Context, name string, emit func(Object)) error {
+	res, err := fn.store.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error getting object %q: %v", name, err)
+	}
+	defer res.Close()
+
+	info, err := res.Info()
+	if err != nil {
+		return fmt.Errorf("error getting info of object %q: %v", name, err)
+	}
+
+	data, err := io.ReadAll(res)
+	if err != nil {
+		return fmt.Errorf("error reading object %q: %v", name, err)
+	}
+
+	emit(createObject(info, data))
+
+	return nil
+}
+
+func createObject(info *jetstream.ObjectInfo, data []byte) Object {
+	return Object{
+		Bucket:      info.Bucket,
+		Name:        info.Name,
+		Description: info.Description,
+		Headers:     info.Headers,
+		Metadata:    info.Metadata,
+		ModTime:     info.ModTime,
+		Digest:      info.Digest,
+		Data:        data,
+	}
+}
+
+// WriteObjects writes a PCollection<Object> to a NATS object store, which must exist. Objects
+// replace existing objects with the same name.
+// WriteObjects takes a variable number of WriteOptionFn to configure the write operation:
+//   - UserCredentials: path to the user credentials file. Defaults to empty.
+func WriteObjects(s beam.Scope, uri string, bucket string, col beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("natsio.WriteObjects")
+
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	fn := &writeObjectFn{
+		objectStoreFn: objectStoreFn{
+			natsFn: natsFn{
+				URI:       uri,
+				CredsFile: option.CredsFile,
+			},
+			Bucket: bucket,
+		},
+	}
+	beam.ParDo0(s, fn, col)
+}
+
+type writeObjectFn struct {
+	objectStoreFn
+}
+
+func (fn *writeObjectFn) ProcessElement(ctx context.Context, elem Object) error {
+	meta := jetstream.ObjectMeta{
+		Name:        elem.Name,
+		Description: elem.Description,
+		Headers:     elem.Headers,
+		Metadata:    elem.Metadata,
+	}
+
+	if _, err := fn.store.Put(ctx, meta, bytes.NewReader(elem.Data)); err != nil {
+		return fmt.Errorf("error putting object %q: %v", elem.Name, err)
+	}
+
+	return nil
+}
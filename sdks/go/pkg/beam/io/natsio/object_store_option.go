@@ -0,0 +1,39 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+type readObjectsOption struct {
+	CredsFile string
+	Names     []string
+}
+
+// ReadObjectsOptionFn is a function that can be passed to ReadObjects to configure options for
+// reading from a NATS object store.
+type ReadObjectsOptionFn func(option *readObjectsOption)
+
+// ReadObjectsUserCredentials sets the user credentials when connecting to NATS.
+func ReadObjectsUserCredentials(credsFile string) ReadObjectsOptionFn {
+	return func(o *readObjectsOption) {
+		o.CredsFile = credsFile
+	}
+}
+
+// ReadObjectsNames sets the names of the objects to read instead of all objects in the bucket.
+func ReadObjectsNames(names ...string) ReadObjectsOptionFn {
+	return func(o *readObjectsOption) {
+		o.Names = names
+	}
+}
@@ -0,0 +1,154 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package natsio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestReadObjects(t *testing.T) {
+	objects := map[string][]byte{
+		"config.json": []byte(`{"key": "val"}`),
+		"config.yaml": []byte("key: val"),
+		"empty":       {},
+	}
+
+	tests := []struct {
+		name      string
+		objects   map[string][]byte
+		opts      []ReadObjectsOptionFn
+		wantNames []string
+	}{
+		{
+			name:      "Read all objects",
+			objects:   objects,
+			wantNames: []string{"config.json", "config.yaml", "empty"},
+		},
+		{
+			name:    "Read objects with provided names",
+			objects: objects,
+			opts: []ReadObjectsOptionFn{
+				ReadObjectsNames("config.yaml"),
+			},
+			wantNames: []string{"config.yaml"},
+		},
+		{
+			name: "Read empty object store",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := newServer(t)
+			uri := srv.ClientURL()
+			conn := newConn(t, uri)
+			js := newJetStream(t, conn)
+
+			bucket := fmt.Sprintf("OBJECTS-%d", i)
+			store := createObjectStore(ctx, t, js, bucket)
+
+			for name, data := range tt.objects {
+				meta := jetstream.ObjectMeta{
+					Name:        name,
+					Description: "description of " + name,
+					Metadata:    map[string]string{"key": "val"},
+				}
+				if _, err := store.Put(ctx, meta, bytes.NewReader(data)); err != nil {
+					t.Fatalf("Failed to put object %q: %v", name, err)
+				}
+			}
+
+			var want []any
+			for _, name := range tt.wantNames {
+				info, err := store.GetInfo(ctx, name)
+				if err != nil {
+					t.Fatalf("Failed to get info of object %q: %v", name, err)
+				}
+				want = append(want, createObject(info, tt.objects[name]))
+			}
+
+			p, s := beam.NewPipelineWithRoot()
+			got := ReadObjects(s, uri, bucket, tt.opts...)
+
+			passert.Equals(s, got, want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestWriteObjects(t *testing.T) {
+	bucket := "OBJECTS"
+
+	input := []any{
+		Object{
+			Name:        "config.json",
+			Description: "configuration",
+			Headers:     map[string][]string{"key": {"val"}},
+			Data:        []byte(`{"key": "val"}`),
+		},
+		Object{
+			Name: "config.yaml",
+			Data: []byte("key: val"),
+		},
+	}
+
+	ctx := context.Background()
+	srv := newServer(t)
+	uri := srv.ClientURL()
+	conn := newConn(t, uri)
+	js := newJetStream(t, conn)
+
+	store := createObjectStore(ctx, t, js, bucket)
+	if _, err := store.PutBytes(ctx, "config.yaml", []byte("old")); err != nil {
+		t.Fatalf("Failed to put object: %v", err)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, input...)
+	WriteObjects(s, uri, bucket, col)
+	ptest.RunAndValidate(t, p)
+
+	for _, elem := range input {
+		want := elem.(Object)
+
+		info, err := store.GetInfo(ctx, want.Name)
+		if err != nil {
+			t.Fatalf("Failed to get info of object %q: %v", want.Name, err)
+		}
+		data, err := store.GetBytes(ctx, want.Name)
+		if err != nil {
+			t.Fatalf("Failed to get object %q: %v", want.Name, err)
+		}
+
+		got := createObject(info, data)
+		got.Bucket, got.ModTime, got.Digest = "", want.ModTime, ""
+		if len(got.Headers) == 0 {
+			got.Headers = nil
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("object %q mismatch (-want +got):\n%s", want.Name, diff)
+		}
+	}
+}
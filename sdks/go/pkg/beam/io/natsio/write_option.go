@@ -19,8 +19,8 @@ type writeOption struct {
 	CredsFile string
 }
 
-// WriteOptionFn is a function that can be passed to Write to configure options for
-// writing messages.
+// WriteOptionFn is a function that can be passed to Write, WriteKV and WriteObjects to configure
+// options for writing to NATS.
 type WriteOptionFn func(option *writeOption)
 
 // WriteUserCredentials sets the user credentials when connecting to NATS.
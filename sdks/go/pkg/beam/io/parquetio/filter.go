@@ -0,0 +1,433 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquetio
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/schema"
+)
+
+// Predicate is a comparison of a column with a constant value, used to filter
+// the rows read by Read. Predicates are created with Eq, Ne, Lt, Le, Gt and
+// Ge, with the name of a column in the parquet schema, using dots to separate
+// the fields of nested columns, and a bool, integer, float or string value.
+type Predicate struct {
+	Column string    `json:"column"`
+	Op     compareOp `json:"op"`
+	Value  value     `json:"value"`
+}
+
+type compareOp string
+
+const (
+	opEq compareOp = "="
+	opNe compareOp = "!="
+	opLt compareOp = "<"
+	opLe compareOp = "<="
+	opGt compareOp = ">"
+	opGe compareOp = ">="
+)
+
+// Eq returns a Predicate that is true if the column is equal to v.
+func Eq(column string, v any) Predicate {
+	return newPredicate(column, opEq, v)
+}
+
+// Ne returns a Predicate that is true if the column isn't equal to v.
+func Ne(column string, v any) Predicate {
+	return newPredicate(column, opNe, v)
+}
+
+// Lt returns a Predicate that is true if the column is less than v.
+func Lt(column string, v any) Predicate {
+	return newPredicate(column, opLt, v)
+}
+
+// Le returns a Predicate that is true if the column is less than or equal to v.
+func Le(column string, v any) Predicate {
+	return newPredicate(column, opLe, v)
+}
+
+// Gt returns a Predicate that is true if the column is greater than v.
+func Gt(column string, v any) Predicate {
+	return newPredicate(column, opGt, v)
+}
+
+// Ge returns a Predicate that is true if the column is greater than or equal to v.
+func Ge(column string, v any) Predicate {
+	return newPredicate(column, opGe, v)
+}
+
+func newPredicate(column string, op compareOp, v any) Predicate {
+	val, ok := valueOf(reflect.ValueOf(v))
+	if !ok {
+		panic(fmt.Sprintf("parquetio: unsupported predicate value %v of type %T for column %v", v, v, column))
+	}
+	return Predicate{Column: column, Op: op, Value: val}
+}
+
+func (p Predicate) String() string {
+	return fmt.Sprintf("%v %v %v", p.Column, p.Op, p.Value)
+}
+
+// matches returns whether a value of the column satisfies the predicate. Null
+// values never satisfy a predicate.
+func (p Predicate) matches(v value) (bool, error) {
+	if v.Kind == kindNull {
+		return false, nil
+	}
+	c, ok := compare(v, p.Value)
+	if !ok {
+		return false, fmt.Errorf("can't compare column %v value %v with %v", p.Column, v, p.Value)
+	}
+	switch p.Op {
+	case opEq:
+		return c == 0, nil
+	case opNe:
+		return c != 0, nil
+	case opLt:
+		return c < 0, nil
+	case opLe:
+		return c <= 0, nil
+	case opGt:
+		return c > 0, nil
+	case opGe:
+		return c >= 0, nil
+	default:
+		return false, fmt.Errorf("invalid predicate operator %q", p.Op)
+	}
+}
+
+// mayMatch returns whether any value between min and max, inclusive, may
+// satisfy the predicate.
+func (p Predicate) mayMatch(min, max value) bool {
+	cmin, ok1 := compare(min, p.Value)
+	cmax, ok2 := compare(max, p.Value)
+	if !ok1 || !ok2 {
+		return true
+	}
+	switch p.Op {
+	case opEq:
+		return cmin <= 0 && cmax >= 0
+	case opNe:
+		return cmin != 0 || cmax != 0
+	case opLt:
+		return cmin < 0
+	case opLe:
+		return cmin <= 0
+	case opGt:
+		return cmax > 0
+	case opGe:
+		return cmax >= 0
+	default:
+		return true
+	}
+}
+
+type valueKind int
+
+const (
+	kindNull valueKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindString
+	// kindUint is used for unsigned integers greater than math.MaxInt64, and
+	// kindInt for all other integers.
+	kindUint
+)
+
+// value is a serializable column or predicate value.
+type value struct {
+	Kind   valueKind `json:"kind"`
+	Bool   bool      `json:"bool,omitempty"`
+	Int    int64     `json:"int,omitempty"`
+	Uint   uint64    `json:"uint,omitempty"`
+	Float  float64   `json:"float,omitempty"`
+	String string    `json:"string,omitempty"`
+}
+
+// uintValue returns the value of an unsigned integer.
+func uintValue(u uint64) value {
+	if u > math.MaxInt64 {
+		return value{Kind: kindUint, Uint: u}
+	}
+	return value{Kind: kindInt, Int: int64(u)}
+}
+
+func (v value) Format(f fmt.State, _ rune) {
+	switch v.Kind {
+	case kindBool:
+		fmt.Fprint(f, v.Bool)
+	case kindInt:
+		fmt.Fprint(f, v.Int)
+	case kindUint:
+		fmt.Fprint(f, v.Uint)
+	case kindFloat:
+		fmt.Fprint(f, v.Float)
+	case kindString:
+		fmt.Fprintf(f, "%q", v.String)
+	default:
+		fmt.Fprint(f, "null")
+	}
+}
+
+// valueOf converts a Go value to a value, dereferencing pointers.
+func valueOf(rv reflect.Value) (value, bool) {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return value{}, true
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return value{Kind: kindBool, Bool: rv.Bool()}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value{Kind: kindInt, Int: rv.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintValue(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value{Kind: kindFloat, Float: rv.Float()}, true
+	case reflect.String:
+		return value{Kind: kindString, String: rv.String()}, true
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			return value{}, false
+		}
+		return value{Kind: kindString, String: string(rv.Bytes())}, true
+	default:
+		return value{}, false
+	}
+}
+
+// compare compares two values. Integers and floats are comparable with each
+// other, and false is less than true. It returns false if the values aren't
+// comparable.
+func compare(a, b value) (int, bool) {
+	switch {
+	case a.Kind == kindInt && b.Kind == kindInt:
+		return cmp.Compare(a.Int, b.Int), true
+	case a.Kind == kindUint && b.Kind == kindUint:
+		return cmp.Compare(a.Uint, b.Uint), true
+	case a.Kind == kindUint && b.Kind == kindInt:
+		// Only unsigned integers greater than any int64 are kindUint.
+		return 1, true
+	case a.Kind == kindInt && b.Kind == kindUint:
+		return -1, true
+	case isNumber(a) && isNumber(b):
+		return cmp.Compare(a.number(), b.number()), true
+	case a.Kind == kindString && b.Kind == kindString:
+		return strings.Compare(a.String, b.String), true
+	case a.Kind == kindBool && b.Kind == kindBool:
+		if a.Bool == b.Bool {
+			return 0, true
+		}
+		if b.Bool {
+			return -1, true
+		}
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+func isNumber(v value) bool {
+	return v.Kind == kindInt || v.Kind == kindUint || v.Kind == kindFloat
+}
+
+func (v value) number() float64 {
+	switch v.Kind {
+	case kindInt:
+		return float64(v.Int)
+	case kindUint:
+		return float64(v.Uint)
+	default:
+		return v.Float
+	}
+}
+
+// statsValue decodes a plain encoded minimum or maximum from the statistics
+// of a column chunk. It returns false for types whose statistics can't be
+// used for filtering.
+func statsValue(data []byte, typ parquet.Type, converted *parquet.ConvertedType) (value, bool) {
+	if converted != nil {
+		switch *converted {
+		case parquet.ConvertedType_DECIMAL, parquet.ConvertedType_INTERVAL:
+			// Ordered or encoded differently from the physical type.
+			return value{}, false
+		}
+	}
+	switch {
+	case isUnsigned(converted) && typ == parquet.Type_INT32 && len(data) == 4:
+		return uintValue(uint64(binary.LittleEndian.Uint32(data))), true
+	case isUnsigned(converted) && typ == parquet.Type_INT64 && len(data) == 8:
+		return uintValue(binary.LittleEndian.Uint64(data)), true
+	case typ == parquet.Type_BOOLEAN && len(data) == 1:
+		return value{Kind: kindBool, Bool: data[0] != 0}, true
+	case typ == parquet.Type_INT32 && len(data) == 4:
+		return value{Kind: kindInt, Int: int64(int32(binary.LittleEndian.Uint32(data)))}, true
+	case typ == parquet.Type_INT64 && len(data) == 8:
+		return value{Kind: kindInt, Int: int64(binary.LittleEndian.Uint64(data))}, true
+	case typ == parquet.Type_FLOAT && len(data) == 4:
+		return value{Kind: kindFloat, Float: float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))}, true
+	case typ == parquet.Type_DOUBLE && len(data) == 8:
+		return value{Kind: kindFloat, Float: math.Float64frombits(binary.LittleEndian.Uint64(data))}, true
+	case typ == parquet.Type_BYTE_ARRAY || typ == parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return value{Kind: kindString, String: string(data)}, true
+	default:
+		return value{}, false
+	}
+}
+
+// isUnsigned returns whether a converted type is an unsigned integer type.
+func isUnsigned(converted *parquet.ConvertedType) bool {
+	if converted == nil {
+		return false
+	}
+	switch *converted {
+	case parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32,
+		parquet.ConvertedType_UINT_64:
+		return true
+	default:
+		return false
+	}
+}
+
+// chunkMayMatch returns whether the statistics of a column chunk, which
+// holds numRows rows, show that some of its values may satisfy the predicate.
+func chunkMayMatch(p Predicate, meta *parquet.ColumnMetaData, converted *parquet.ConvertedType, numRows int64) bool {
+	stats := meta.GetStatistics()
+	if stats == nil {
+		return true
+	}
+	if stats.NullCount != nil && *stats.NullCount >= numRows {
+		// Only nulls, which never satisfy a predicate.
+		return false
+	}
+	minData, maxData := stats.MinValue, stats.MaxValue
+	if minData == nil || maxData == nil {
+		if meta.Type == parquet.Type_BYTE_ARRAY || meta.Type == parquet.Type_FIXED_LEN_BYTE_ARRAY || isUnsigned(converted) {
+			// The deprecated min and max use signed order.
+			return true
+		}
+		minData, maxData = stats.Min, stats.Max
+	}
+	if minData == nil || maxData == nil {
+		return true
+	}
+	min, ok1 := statsValue(minData, meta.Type, converted)
+	max, ok2 := statsValue(maxData, meta.Type, converted)
+	if !ok1 || !ok2 {
+		return true
+	}
+	return p.mayMatch(min, max)
+}
+
+// filter is a Predicate resolved against the schema of a parquet reader.
+type filter struct {
+	Predicate
+	// inPath is the internal path of the column, including the root.
+	inPath string
+	// fields are the names of the struct fields holding the column.
+	fields    []string
+	converted *parquet.ConvertedType
+}
+
+// resolveFilters resolves the predicates against a schema. It returns an
+// error if a column isn't in the schema, or isn't a primitive column outside
+// of repeated fields.
+func resolveFilters(sh *schema.SchemaHandler, preds []Predicate) ([]filter, error) {
+	var filters []filter
+	for _, p := range preds {
+		exPath := append([]string{sh.GetRootExName()}, strings.Split(p.Column, ".")...)
+		inPath, ok := sh.ExPathToInPath[common.PathToStr(exPath)]
+		if !ok {
+			return nil, fmt.Errorf("filter column %v isn't in the schema", p.Column)
+		}
+		parts := common.StrToPath(inPath)
+		for i := 2; i <= len(parts); i++ {
+			el := sh.SchemaElements[sh.MapIndex[common.PathToStr(parts[:i])]]
+			if el.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+				return nil, fmt.Errorf("filter column %v is repeated", p.Column)
+			}
+		}
+		el := sh.SchemaElements[sh.MapIndex[inPath]]
+		if el.GetNumChildren() > 0 {
+			return nil, fmt.Errorf("filter column %v isn't a primitive column", p.Column)
+		}
+		filters = append(filters, filter{Predicate: p, inPath: inPath, fields: parts[1:], converted: el.ConvertedType})
+	}
+	return filters, nil
+}
+
+// rowGroupMayMatch returns whether the column chunk statistics of a row group
+// show that some of its rows may satisfy the filter. rootInName is the
+// internal name of the schema root.
+func (f filter) rowGroupMayMatch(rg *parquet.RowGroup, rootInName string) bool {
+	for _, c := range rg.GetColumns() {
+		meta := c.GetMetaData()
+		if meta == nil {
+			continue
+		}
+		if common.PathToStr(append([]string{rootInName}, meta.GetPathInSchema()...)) == f.inPath {
+			return chunkMayMatch(f.Predicate, meta, f.converted, rg.GetNumRows())
+		}
+	}
+	return true
+}
+
+// rowMatches returns whether a row, a struct read by a parquet reader,
+// satisfies the filter.
+func (f filter) rowMatches(row reflect.Value) (bool, error) {
+	v := row
+	for _, name := range f.fields {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return false, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return false, fmt.Errorf("filter column %v isn't a struct field", f.Column)
+		}
+		if v = v.FieldByName(name); !v.IsValid() {
+			return false, fmt.Errorf("filter column %v has no field %v", f.Column, name)
+		}
+	}
+	val, ok := valueOf(v)
+	if !ok {
+		return false, fmt.Errorf("filter column %v has unsupported type %v", f.Column, v.Type())
+	}
+	if isUnsigned(f.converted) {
+		// Unsigned columns are read into signed fields of the same size.
+		switch v.Kind() {
+		case reflect.Int32:
+			val = uintValue(uint64(uint32(v.Int())))
+		case reflect.Int64:
+			val = uintValue(uint64(v.Int()))
+		}
+	}
+	return f.matches(val)
+}
@@ -18,25 +18,51 @@ package parquetio
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"reflect"
+	"sort"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
-	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
 	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
 	"github.com/xitongsys/parquet-go/writer"
 )
 
 func init() {
 	register.Emitter1[string]()
 
-	register.DoFn3x1[context.Context, fileio.ReadableFile, func(beam.X), error](&parquetReadFn{})
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(beam.X), error](&readFn{})
 	register.Emitter1[beam.X]()
 
-	register.DoFn3x1[context.Context, int, func(*beam.X) bool, error](&parquetWriteFn{})
-	register.Iter1[beam.X]()
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
+}
+
+type readOption struct {
+	Filters []Predicate
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for reading
+// parquet files.
+type ReadOptionFn func(*readOption)
+
+// ReadFilter specifies predicates that the rows read must all satisfy. Row groups whose
+// column statistics show that none of their rows can satisfy a predicate are skipped
+// without being read, and the remaining rows are filtered after reading. Null values
+// never satisfy a predicate. The columns of the predicates must be read, so with a
+// struct type they must have a field in it.
+func ReadFilter(preds ...Predicate) ReadOptionFn {
+	return func(o *readOption) {
+		o.Filters = append(o.Filters, preds...)
+	}
 }
 
 // Read reads a set of files and returns lines as a PCollection<elem>
@@ -52,49 +78,308 @@ func init() {
 //	  Day     int32   `parquet:"name=day, type=INT32, convertedtype=DATE"`
 //	  Ignored int32   //without parquet tag and won't write
 //	}
-func Read(s beam.Scope, glob string, t reflect.Type) beam.PCollection {
+//
+// Only the columns with a field in the struct are read, so a struct with a subset of the
+// columns of the files can be used to read only those columns.
+//
+// If t is nil, the type is inferred from the schema of the first file matching the glob
+// when the pipeline is constructed, and the rows are returned as schema rows: structs
+// with a field for each top-level column, named after it. All files must have the same
+// schema.
+//
+// Files are split by row group, so that the row groups of large files can be read in
+// parallel. Read accepts a variadic number of ReadOptionFn to filter the rows read.
+func Read(s beam.Scope, glob string, t reflect.Type, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("parquetio.Read")
 	filesystem.ValidateScheme(glob)
-	return read(s, t, beam.Create(s, glob))
+
+	option := &readOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	fn := &readFn{Filters: option.Filters}
+	var sh *schema.SchemaHandler
+	var err error
+	if t == nil {
+		t, sh, err = inferType(context.Background(), glob)
+		fn.Rows = true
+	} else {
+		sh, err = schema.NewSchemaHandlerFromStruct(reflect.New(t).Interface())
+	}
+	if err != nil {
+		panic(fmt.Sprintf("parquetio.Read: %v", err))
+	}
+	if _, err := resolveFilters(sh, option.Filters); err != nil {
+		panic(fmt.Sprintf("parquetio.Read: %v", err))
+	}
+	fn.Type = beam.EncodedType{T: t}
+	return read(s, fn, beam.Create(s, glob))
 }
 
-func read(s beam.Scope, t reflect.Type, col beam.PCollection) beam.PCollection {
+func read(s beam.Scope, fn *readFn, col beam.PCollection) beam.PCollection {
 	matches := fileio.MatchAll(s, col, fileio.MatchEmptyAllow())
 	files := fileio.ReadMatches(s, matches, fileio.ReadUncompressed())
 	return beam.ParDo(s,
-		&parquetReadFn{Type: beam.EncodedType{T: t}},
+		fn,
 		files,
-		beam.TypeDefinition{Var: beam.XType, T: t},
+		beam.TypeDefinition{Var: beam.XType, T: fn.Type.T},
 	)
 }
 
-type parquetReadFn struct {
-	Type beam.EncodedType
+// inferType returns the row type and schema of the first file matching the glob.
+func inferType(ctx context.Context, glob string) (reflect.Type, *schema.SchemaHandler, error) {
+	fs, err := filesystem.New(ctx, glob)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fs.Close()
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no files match %v to infer the schema from", glob)
+	}
+	sort.Strings(files)
+
+	size, err := fs.Size(ctx, files[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	src, err := openSource(ctx, fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: files[0], Size: size}})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+	pr, err := newFooterReader(src, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading schema of %v: %v", files[0], err)
+	}
+
+	t, err := rowType(pr.SchemaHandler)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error inferring type of %v: %v", files[0], err)
+	}
+	return t, pr.SchemaHandler, nil
 }
 
-func (a *parquetReadFn) ProcessElement(ctx context.Context, file fileio.ReadableFile, emit func(beam.X)) error {
-	data, err := file.Read(ctx)
+// rowType returns the struct type of the rows of a schema, with beam tags naming the
+// fields after the columns.
+func rowType(sh *schema.SchemaHandler) (reflect.Type, error) {
+	t, err := sh.GetType(sh.GetRootInName())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		exPath := sh.InPathToExPath[common.PathToStr([]string{sh.GetRootInName(), f.Name})]
+		f.Tag = reflect.StructTag(fmt.Sprintf("beam:%q", common.StrToPath(exPath)[1]))
+		fields[i] = f
 	}
+	return reflect.StructOf(fields), nil
+}
+
+// parallelism is the number of columns read concurrently.
+const parallelism = 4
+
+// readFn is an SDF that reads the rows of parquet files. Its restriction is a range of
+// byte offsets in the file, and it reads the row groups starting in the range.
+type readFn struct {
+	Type beam.EncodedType
+	// Rows is true if Type was inferred from the schema of the files.
+	Rows    bool
+	Filters []Predicate
+}
 
-	bufferReader := buffer.NewBufferFileFromBytes(data)
-	parquetReader, err := reader.NewParquetReader(bufferReader, reflect.New(a.Type.T).Interface(), 4)
+// CreateInitialRestriction creates an offset range restriction representing
+// the file's size in bytes.
+func (fn *readFn) CreateInitialRestriction(file fileio.ReadableFile) offsetrange.Restriction {
+	return offsetrange.Restriction{
+		Start: 0,
+		End:   file.Metadata.Size,
+	}
+}
+
+// blockSize is the desired size of each block for initial splits.
+const blockSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits each file restriction into blocks of a predetermined size.
+func (fn *readFn) SplitRestriction(_ fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return rest.SizedSplits(blockSize)
+}
+
+// RestrictionSize returns the size of each restriction as its range.
+func (fn *readFn) RestrictionSize(_ fileio.ReadableFile, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// CreateTracker creates sdf.LockRTrackers wrapping offsetRange.Trackers for
+// each restriction.
+func (fn *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+// ProcessElement reads the row groups of the file starting within the restriction,
+// skipping those that the filters rule out.
+func (fn *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, emit func(beam.X)) error {
+	src, err := openSource(ctx, file)
 	if err != nil {
 		return err
 	}
+	defer src.Close()
 
-	vals, err := parquetReader.ReadByNumber(int(parquetReader.GetNumRows()))
+	var obj any
+	if !fn.Rows {
+		obj = reflect.New(fn.Type.T).Interface()
+	}
+	pr, err := newFooterReader(src, obj)
 	if err != nil {
-		return err
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+	}
+
+	if fn.Rows {
+		t, err := rowType(pr.SchemaHandler)
+		if err != nil {
+			return fmt.Errorf("error inferring type of %v: %v", file.Metadata.Path, err)
+		}
+		if t != fn.Type.T {
+			return fmt.Errorf("schema of %v doesn't match the inferred type %v", file.Metadata.Path, fn.Type.T)
+		}
+		pr.ObjType = t
 	}
-	for _, v := range vals {
-		emit(v)
+	filters, err := resolveFilters(pr.SchemaHandler, fn.Filters)
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
 	}
 
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+	for _, rg := range pr.Footer.GetRowGroups() {
+		offset := rowGroupOffset(rg)
+		if offset < rest.Start {
+			continue
+		}
+		if !rt.TryClaim(offset) {
+			return nil
+		}
+		if !rowGroupMayMatch(filters, rg, pr.SchemaHandler.GetRootInName()) {
+			continue
+		}
+
+		rows, err := readRowGroup(pr, rg)
+		if err != nil {
+			return fmt.Errorf("error reading %v: %v", file.Metadata.Path, err)
+		}
+		for _, row := range rows {
+			ok, err := rowMatches(filters, reflect.ValueOf(row))
+			if err != nil {
+				return err
+			}
+			if ok {
+				emit(row)
+			}
+		}
+	}
+	// Finish claiming restriction to avoid errors.
+	rt.TryClaim(rest.End)
 	return nil
 }
 
+// rowGroupOffset returns the offset of the first page of a row group.
+func rowGroupOffset(rg *parquet.RowGroup) int64 {
+	offset := int64(-1)
+	for _, c := range rg.GetColumns() {
+		meta := c.GetMetaData()
+		if meta == nil {
+			continue
+		}
+		o := meta.GetDataPageOffset()
+		if meta.IsSetDictionaryPageOffset() && meta.GetDictionaryPageOffset() > 0 {
+			o = min(o, meta.GetDictionaryPageOffset())
+		}
+		if offset < 0 || o < offset {
+			offset = o
+		}
+	}
+	return max(offset, 0)
+}
+
+// newFooterReader returns a parquet reader of the footer of a file, like
+// reader.NewParquetReader with a struct or nil obj, but without opening the
+// column chunks of the file. Row groups are then read with readRowGroup.
+func newFooterReader(src source.ParquetFile, obj any) (*reader.ParquetReader, error) {
+	pr := &reader.ParquetReader{NP: parallelism, PFile: src}
+	if err := pr.ReadFooter(); err != nil {
+		return nil, err
+	}
+	if obj != nil {
+		sh, err := schema.NewSchemaHandlerFromStruct(obj)
+		if err != nil {
+			return nil, err
+		}
+		pr.SchemaHandler = sh
+		pr.ObjType = reflect.TypeOf(obj).Elem()
+	} else {
+		pr.SchemaHandler = schema.NewSchemaHandlerFromSchemaList(pr.Footer.Schema)
+	}
+	pr.RenameSchema()
+	return pr, nil
+}
+
+// readRowGroup reads the rows of a row group of the file whose footer was
+// read by pr. Only the column chunks of the row group are read, from their
+// offsets in the footer, by a reader whose footer holds only the row group.
+func readRowGroup(pr *reader.ParquetReader, rg *parquet.RowGroup) ([]any, error) {
+	footer := *pr.Footer
+	footer.RowGroups = []*parquet.RowGroup{rg}
+	footer.NumRows = rg.GetNumRows()
+
+	rgr := &reader.ParquetReader{
+		SchemaHandler: pr.SchemaHandler,
+		NP:            pr.NP,
+		Footer:        &footer,
+		PFile:         pr.PFile,
+		ColumnBuffers: make(map[string]*reader.ColumnBufferType),
+		ObjType:       pr.ObjType,
+	}
+	defer rgr.ReadStop()
+	for i, se := range pr.SchemaHandler.SchemaElements {
+		if se.GetNumChildren() != 0 {
+			continue
+		}
+		path := pr.SchemaHandler.IndexMap[int32(i)]
+		cb, err := reader.NewColumnBuffer(pr.PFile, &footer, pr.SchemaHandler, path)
+		if err != nil {
+			return nil, err
+		}
+		rgr.ColumnBuffers[path] = cb
+	}
+	return rgr.ReadByNumber(int(rg.GetNumRows()))
+}
+
+// rowGroupMayMatch returns whether some rows of the row group may satisfy all filters.
+func rowGroupMayMatch(filters []filter, rg *parquet.RowGroup, rootInName string) bool {
+	for _, f := range filters {
+		if !f.rowGroupMayMatch(rg, rootInName) {
+			return false
+		}
+	}
+	return true
+}
+
+// rowMatches returns whether the row satisfies all filters.
+func rowMatches(filters []filter, row reflect.Value) (bool, error) {
+	for _, f := range filters {
+		ok, err := f.rowMatches(row)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 // Write writes a PCollection<parquetStruct> to .parquet file.
 // Write expects elements of a struct type with parquet tags
 // For example:
@@ -108,43 +393,43 @@ func (a *parquetReadFn) ProcessElement(ctx context.Context, file fileio.Readable
 //	  Day     int32   `parquet:"name=day, type=INT32, convertedtype=DATE"`
 //	  Ignored int32   //without parquet tag and won't write
 //	}
-func Write(s beam.Scope, filename string, col beam.PCollection) {
-	t := col.Type().Type()
+//
+// It returns a PCollection<string> with the filename once the file has been
+// written.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename string, col beam.PCollection) beam.PCollection {
 	s = s.Scope("parquetio.Write")
-	filesystem.ValidateScheme(filename)
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &parquetWriteFn{Filename: filename, Type: beam.EncodedType{T: t}}, post)
+	return fileio.WriteFiles(s, filename, NewSink(col.Type().Type()), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
 }
 
-type parquetWriteFn struct {
-	Type     beam.EncodedType
-	Filename string `json:"filename"`
+// NewSink returns a fileio.Sink that writes elements of the given struct
+// type with parquet tags to a parquet file. See Write for an example type.
+func NewSink(t reflect.Type) fileio.Sink {
+	return &sink{Type: beam.EncodedType{T: t}}
 }
 
-func (a *parquetWriteFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
-	fs, err := filesystem.New(ctx, a.Filename)
-	if err != nil {
-		return err
-	}
-	defer fs.Close()
+type sink struct {
+	Type beam.EncodedType
 
-	fd, err := fs.OpenWrite(ctx, a.Filename)
-	if err != nil {
-		return err
-	}
+	pw *writer.ParquetWriter
+}
 
-	defer fd.Close()
-	pw, err := writer.NewParquetWriterFromWriter(fd, reflect.New(a.Type.T).Interface(), 4)
+func (s *sink) Open(_ context.Context, w io.Writer) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, reflect.New(s.Type.T).Interface(), 4)
 	if err != nil {
 		return err
 	}
+	s.pw = pw
+	return nil
+}
 
-	var val beam.X
-	for iter(&val) {
-		if err := pw.Write(val); err != nil {
-			return err
-		}
-	}
-	return pw.WriteStop()
+func (s *sink) Write(_ context.Context, elm any) error {
+	return s.pw.Write(elm)
+}
+
+func (s *sink) Flush(_ context.Context) error {
+	return s.pw.WriteStop()
 }
@@ -16,19 +16,34 @@
 package parquetio
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/memfs"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
 	"github.com/xitongsys/parquet-go-source/local"
 	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
 )
 
+func init() {
+	register.Function1x1(formatRow)
+}
+
 func TestMain(m *testing.M) {
 	ptest.Main(m)
 }
@@ -95,7 +110,8 @@ func TestWrite(t *testing.T) {
 	}
 	p, s, sequence := ptest.CreateList(studentList)
 	parquetFile := "./write_student.parquet"
-	Write(s, parquetFile, sequence)
+	written := Write(s, parquetFile, sequence)
+	passert.Equals(s, written, parquetFile)
 	t.Cleanup(func() {
 		os.Remove(parquetFile)
 	})
@@ -124,3 +140,348 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("students differs from studentList. got %+v, expected %+v", students, studentList)
 	}
 }
+
+// students returns n students with consecutive ages and ids starting at 20 and 0.
+func students(n int) []Student {
+	var list []Student
+	for i := 0; i < n; i++ {
+		list = append(list, Student{
+			Name:   fmt.Sprintf("Student%d", i),
+			Age:    int32(20 + i),
+			Id:     int64(i),
+			Weight: 50,
+			Sex:    i%2 == 0,
+			Day:    19089,
+		})
+	}
+	return list
+}
+
+// writeRowGroups writes a parquet file with a row group for each group of
+// students, and returns its path.
+func writeRowGroups(t *testing.T, groups ...[]Student) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "students.parquet")
+	fw, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		t.Fatalf("Failed to create file %v. err: %v", path, err)
+	}
+	pw, err := writer.NewParquetWriter(fw, new(Student), 1)
+	if err != nil {
+		t.Fatalf("Failed to create parquet writer. err: %v", err)
+	}
+	for _, group := range groups {
+		for _, student := range group {
+			if err := pw.Write(student); err != nil {
+				t.Fatalf("Failed to write student. err: %v", err)
+			}
+		}
+		if err := pw.Flush(true); err != nil {
+			t.Fatalf("Failed to flush row group. err: %v", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		t.Fatalf("Failed to write footer. err: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Failed to close file. err: %v", err)
+	}
+	return path
+}
+
+func toAny(list []Student) []any {
+	var out []any
+	for _, s := range list {
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestRead_rowGroups(t *testing.T) {
+	list := students(6)
+	path := writeRowGroups(t, list[:2], list[2:4], list[4:])
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, path, reflect.TypeOf(Student{}))
+	passert.Equals(s, rows, toAny(list)...)
+
+	ptest.RunAndValidate(t, p)
+}
+
+// StudentAge has a subset of the columns of Student.
+type StudentAge struct {
+	Name string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Age  int32  `parquet:"name=age, type=INT32"`
+}
+
+func TestRead_projection(t *testing.T) {
+	list := students(4)
+	path := writeRowGroups(t, list[:2], list[2:])
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, path, reflect.TypeOf(StudentAge{}))
+	passert.Equals(s, rows,
+		StudentAge{Name: "Student0", Age: 20},
+		StudentAge{Name: "Student1", Age: 21},
+		StudentAge{Name: "Student2", Age: 22},
+		StudentAge{Name: "Student3", Age: 23})
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_filter(t *testing.T) {
+	list := students(6)
+	path := writeRowGroups(t, list[:2], list[2:4], list[4:])
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, path, reflect.TypeOf(StudentAge{}), ReadFilter(Ge("age", 21), Ne("name", "Student4")))
+	passert.Equals(s, rows,
+		StudentAge{Name: "Student1", Age: 21},
+		StudentAge{Name: "Student2", Age: 22},
+		StudentAge{Name: "Student3", Age: 23},
+		StudentAge{Name: "Student5", Age: 25})
+
+	ptest.RunAndValidate(t, p)
+}
+
+// Counter has an unsigned 64-bit column, read into a signed field.
+type Counter struct {
+	Name  string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Count int64  `parquet:"name=count, type=INT64, convertedtype=UINT_64"`
+}
+
+func TestRead_uintFilter(t *testing.T) {
+	// The large counts are math.MaxUint64-1 and math.MaxUint64.
+	counters := []Counter{{"small", 1}, {"large", -2}, {"max", -1}}
+	path := filepath.Join(t.TempDir(), "counters.parquet")
+	fw, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		t.Fatalf("Failed to create file %v. err: %v", path, err)
+	}
+	pw, err := writer.NewParquetWriter(fw, new(Counter), 1)
+	if err != nil {
+		t.Fatalf("Failed to create parquet writer. err: %v", err)
+	}
+	for _, c := range counters {
+		if err := pw.Write(c); err != nil {
+			t.Fatalf("Failed to write counter. err: %v", err)
+		}
+		if err := pw.Flush(true); err != nil {
+			t.Fatalf("Failed to flush row group. err: %v", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		t.Fatalf("Failed to write footer. err: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Failed to close file. err: %v", err)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	large := Read(s, path, reflect.TypeOf(Counter{}), ReadFilter(Gt("count", uint64(math.MaxInt64)), Ne("count", uint64(math.MaxUint64))))
+	passert.Equals(s, large, counters[1])
+	all := Read(s, path, reflect.TypeOf(Counter{}), ReadFilter(Gt("count", -1)))
+	passert.Equals(s, all, counters[0], counters[1], counters[2])
+	small := Read(s, path, reflect.TypeOf(Counter{}), ReadFilter(Lt("count", 1.5)))
+	passert.Equals(s, small, counters[0])
+
+	ptest.RunAndValidate(t, p)
+}
+
+func formatRow(row beam.X) string {
+	return fmt.Sprintf("%+v", row)
+}
+
+func TestRead_rows(t *testing.T) {
+	list := students(2)
+	path := writeRowGroups(t, list)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, path, nil, ReadFilter(Eq("sex", true)))
+	passert.Equals(s, beam.ParDo(s, formatRow, rows),
+		"{Name:Student0 Age:20 Id:0 Weight:50 Sex:true Day:19089}")
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRowType(t *testing.T) {
+	path := writeRowGroups(t, students(1))
+	got, _, err := inferType(context.Background(), path)
+	if err != nil {
+		t.Fatalf("inferType(%v) failed: %v", path, err)
+	}
+	var names []string
+	for i := 0; i < got.NumField(); i++ {
+		names = append(names, got.Field(i).Tag.Get("beam"))
+	}
+	if want := []string{"name", "age", "id", "weight", "sex", "day"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("inferType(%v) field names = %v, want %v", path, names, want)
+	}
+}
+
+func TestRead_invalidFilter(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Read with a filter on a missing column didn't panic")
+		}
+	}()
+	s := beam.NewPipeline().Root()
+	Read(s, "../../../../data/student.parquet", reflect.TypeOf(StudentAge{}), ReadFilter(Eq("id", 1)))
+}
+
+// collect emits the rows to a slice.
+type collect struct {
+	rows []Student
+}
+
+func (c *collect) emit(row beam.X) {
+	c.rows = append(c.rows, row.(Student))
+}
+
+func TestReadFn_split(t *testing.T) {
+	list := students(6)
+	path := writeRowGroups(t, list[:2], list[2:4], list[4:])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Readers of memfs files can't seek, so they are read by skipping data.
+	memPath := "memfs://parquetio/students.parquet"
+	memfs.Write(memPath, data)
+
+	for _, path := range []string{path, memPath} {
+		file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(len(data))}}
+		fn := &readFn{Type: beam.EncodedType{T: reflect.TypeOf(Student{})}}
+		rest := fn.CreateInitialRestriction(file)
+
+		// Every split point must read each row exactly once.
+		for split := int64(0); split <= rest.End; split += 16 {
+			var c collect
+			for _, r := range []offsetrange.Restriction{{Start: 0, End: split}, {Start: split, End: rest.End}} {
+				rt := fn.CreateTracker(r)
+				if err := fn.ProcessElement(context.Background(), rt, file, c.emit); err != nil {
+					t.Fatalf("ProcessElement(%v, %v) failed: %v", path, r, err)
+				}
+				if !rt.IsDone() {
+					t.Errorf("ProcessElement(%v, %v) didn't finish restriction", path, r)
+				}
+			}
+			sort.Slice(c.rows, func(i, j int) bool { return c.rows[i].Id < c.rows[j].Id })
+			if !reflect.DeepEqual(c.rows, list) {
+				t.Errorf("%v split at %v: got %+v, want %+v", path, split, c.rows, list)
+			}
+		}
+	}
+}
+
+// recordingFile is a source.ParquetFile that records the ranges of the file
+// read through it and through the files it opens.
+type recordingFile struct {
+	source.ParquetFile
+	offset int64
+	reads  *[][2]int64
+}
+
+func (f *recordingFile) Open(name string) (source.ParquetFile, error) {
+	pf, err := f.ParquetFile.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingFile{ParquetFile: pf, reads: f.reads}, nil
+}
+
+func (f *recordingFile) Seek(offset int64, whence int) (int64, error) {
+	o, err := f.ParquetFile.Seek(offset, whence)
+	f.offset = o
+	return o, err
+}
+
+func (f *recordingFile) Read(p []byte) (int, error) {
+	n, err := f.ParquetFile.Read(p)
+	*f.reads = append(*f.reads, [2]int64{f.offset, f.offset + int64(n)})
+	f.offset += int64(n)
+	return n, err
+}
+
+func TestReadRowGroup(t *testing.T) {
+	list := students(6)
+	path := writeRowGroups(t, list[:2], list[2:4], list[4:])
+	lf, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+
+	var reads [][2]int64
+	pr, err := newFooterReader(&recordingFile{ParquetFile: lf, reads: &reads}, new(Student))
+	if err != nil {
+		t.Fatalf("newFooterReader() failed: %v", err)
+	}
+
+	// Only the column chunks of the row group are read, and not those of the
+	// row groups before it.
+	reads = nil
+	rgs := pr.Footer.GetRowGroups()
+	rows, err := readRowGroup(pr, rgs[1])
+	if err != nil {
+		t.Fatalf("readRowGroup() failed: %v", err)
+	}
+	if want := toAny(list[2:4]); !reflect.DeepEqual(rows, want) {
+		t.Errorf("readRowGroup() = %+v, want %+v", rows, want)
+	}
+	// Each column chunk is read through a buffered reader, which may read
+	// past the end of the chunk to fill its buffer.
+	const bufSize = 4096
+	start, end := rowGroupOffset(rgs[1]), rowGroupOffset(rgs[2])
+	for _, r := range reads {
+		if r[0] < start || r[0] >= end || r[1]-r[0] > bufSize {
+			t.Errorf("readRowGroup() read bytes %v, want reads of the row group bytes [%v, %v)", r, start, end)
+		}
+	}
+}
+
+func TestRowGroupMayMatch(t *testing.T) {
+	list := students(6)
+	path := writeRowGroups(t, list[:2], list[2:4], list[4:])
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path}}
+	src, err := openSource(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	pr, err := reader.NewParquetReader(src, new(Student), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+
+	tests := []struct {
+		pred Predicate
+		want []bool
+	}{
+		{Eq("age", 22), []bool{false, true, false}},
+		{Ne("age", 22), []bool{true, true, true}},
+		{Lt("age", 22), []bool{true, false, false}},
+		{Le("age", 22), []bool{true, true, false}},
+		{Gt("age", 23), []bool{false, false, true}},
+		{Ge("age", 23.5), []bool{false, false, true}},
+		{Eq("name", "Student3"), []bool{false, true, false}},
+		{Gt("id", 3), []bool{false, false, true}},
+		{Eq("id", uint64(5)), []bool{false, false, true}},
+		{Lt("weight", 50), []bool{false, false, false}},
+		{Eq("sex", false), []bool{true, true, true}},
+	}
+	for _, test := range tests {
+		filters, err := resolveFilters(pr.SchemaHandler, []Predicate{test.pred})
+		if err != nil {
+			t.Fatalf("resolveFilters(%v) failed: %v", test.pred, err)
+		}
+		var got []bool
+		for _, rg := range pr.Footer.GetRowGroups() {
+			got = append(got, rowGroupMayMatch(filters, rg, pr.SchemaHandler.GetRootInName()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("rowGroupMayMatch(%v) = %v, want %v", test.pred, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquetio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// fileSource is a read-only source.ParquetFile for a fileio.ReadableFile.
+// The parquet reader opens the file once per column, and each of them opens
+// the file again, so that only the footer and the needed column chunks are
+// read. File systems whose readers can't seek are read from the needed
+// offsets by reopening the file and skipping the data before the offset.
+type fileSource struct {
+	ctx  context.Context
+	file fileio.ReadableFile
+
+	rs io.ReadSeekCloser
+}
+
+var _ source.ParquetFile = (*fileSource)(nil)
+
+// openSource opens a file for reading with a parquet reader.
+func openSource(ctx context.Context, file fileio.ReadableFile) (*fileSource, error) {
+	rs, err := file.OpenSeekable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{ctx: ctx, file: file, rs: rs}, nil
+}
+
+// Open opens the file again, with an independent offset. Column chunks in
+// other files aren't supported.
+func (f *fileSource) Open(name string) (source.ParquetFile, error) {
+	if name != "" {
+		return nil, fmt.Errorf("column chunks in external file %v aren't supported", name)
+	}
+	return openSource(f.ctx, f.file)
+}
+
+func (f *fileSource) Create(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("parquetio sources are read-only")
+}
+
+func (f *fileSource) Read(p []byte) (int, error) {
+	return f.rs.Read(p)
+}
+
+func (f *fileSource) Seek(offset int64, whence int) (int64, error) {
+	return f.rs.Seek(offset, whence)
+}
+
+func (f *fileSource) Write(_ []byte) (int, error) {
+	return 0, errors.New("parquetio sources are read-only")
+}
+
+func (f *fileSource) Close() error {
+	return f.rs.Close()
+}
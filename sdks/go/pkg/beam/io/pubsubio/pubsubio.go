@@ -13,20 +13,28 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package pubsubio provides access to Pub/Sub on Dataflow streaming.
+// Package pubsubio provides access to Pub/Sub in streaming pipelines.
 //
-// This implementation only functions on the Dataflow runner.
-//
-// See https://cloud.google.com/dataflow/docs/concepts/streaming-with-cloud-pubsub
+// Read and Write are composite transforms with the Pub/Sub read and write
+// URNs, so runners that have native Pub/Sub transforms, such as Dataflow,
+// replace them. See
+// https://cloud.google.com/dataflow/docs/concepts/streaming-with-cloud-pubsub
 // for details on using Pub/Sub with Dataflow.
+//
+// On other runners, Read receives messages with streaming pull in a
+// splittable DoFn, and acknowledges them once the bundle that read them is
+// finalized. Write publishes messages in batches. Both use the Pub/Sub
+// emulator if PUBSUB_EMULATOR_HOST is set.
 package pubsubio
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
-	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/protox"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
@@ -45,7 +53,6 @@ func init() {
 	register.Function2x1(unmarshalMessageFn)
 	register.Function2x1(marshalMessageFn)
 	register.Function2x0(wrapInMessage)
-	register.Function2x0(wrapInMessage)
 	register.Emitter1[[]byte]()
 	register.Emitter1[*pb.PubsubMessage]()
 }
@@ -64,6 +71,15 @@ type ReadOptions struct {
 // if WithAttributes is set, or an unbounded PCollection<[]byte>.
 //
 // The topic or subscription is required and must be set with ReadOptions.
+// When reading from a topic on runners other than Dataflow, a subscription
+// is created on it for the job, and deleted when the pipeline is drained. If
+// the pipeline is cancelled instead, the subscription expires after a day
+// without being read.
+//
+// Elements are timestamped with the publish time of their messages, or with
+// the TimestampAttribute if set, which holds milliseconds since the Unix
+// epoch or an RFC 3339 timestamp. If IDAttribute is set, messages with the
+// same value of it are deduplicated.
 func Read(s beam.Scope, project string, opts ReadOptions) beam.PCollection {
 	s = s.Scope("pubsubio.Read")
 
@@ -84,19 +100,36 @@ func Read(s beam.Scope, project string, opts ReadOptions) beam.PCollection {
 	payload.TimestampAttribute = opts.TimestampAttribute
 	payload.WithAttributes = opts.WithAttributes
 
-	out := beam.External(
-		s,
-		readURN,
-		protox.MustEncode(payload),
-		nil,
-		[]beam.FullType{typex.New(reflectx.ByteSlice)},
-		false,
-	)
-
+	out := readSDF(withPayload(s, "PubSubRead", readURN, payload), project, opts)
 	if opts.WithAttributes {
-		return beam.ParDo(s, unmarshalMessageFn, out[0])
+		return beam.ParDo(s, unmarshalMessageFn, out)
+	}
+	return out
+}
+
+// readSDF reads messages with readFn. It emits the serialized messages if
+// WithAttributes is set, like the runner's Pub/Sub read.
+func readSDF(s beam.Scope, project string, opts ReadOptions) beam.PCollection {
+	fn := &readFn{
+		Project:            project,
+		Subscription:       opts.Subscription,
+		IDAttribute:        opts.IDAttribute,
+		TimestampAttribute: opts.TimestampAttribute,
+		WithAttributes:     opts.WithAttributes,
+	}
+	if opts.Topic != "" {
+		fn.Topic = opts.Topic
+		fn.Subscription = fmt.Sprintf("%v.beam_%v", opts.Topic, time.Now().UnixNano())
 	}
-	return out[0]
+	imp := beam.Impulse(s)
+	return beam.ParDo(s, fn, imp)
+}
+
+// withPayload returns a subscope for a composite transform with the given URN
+// and payload, which runners may replace with their own implementation.
+func withPayload(s beam.Scope, name, urn string, payload proto.Message) beam.Scope {
+	ctx := graph.WithCompositePayload(context.Background(), &graph.Payload{URN: urn, Data: protox.MustEncode(payload)})
+	return s.WithContext(ctx, name)
 }
 
 func unmarshalMessageFn(raw []byte, emit func(*pb.PubsubMessage)) error {
@@ -125,17 +158,37 @@ func marshalMessageFn(in *pb.PubsubMessage, emit func([]byte)) error {
 
 var pubSubMessageT = reflect.TypeOf((*pb.PubsubMessage)(nil))
 
+type writeOption struct {
+	Ordering bool
+}
+
+// WriteOptionFn is a function that can be passed to Write to configure options
+// for publishing messages.
+type WriteOptionFn func(*writeOption)
+
+// WriteMessageOrdering specifies that messages with the same ordering key are
+// published in the order they are written, which is required to write
+// messages with ordering keys on runners other than Dataflow. If publishing a
+// message fails, the bundle is retried, which may publish the messages of its
+// ordering key again.
+func WriteMessageOrdering() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Ordering = true
+	}
+}
+
 // Write writes PubSubMessages or []bytes to the given pubsub topic.
 // Panics if the input pcollection type is not one of those two types.
 //
 // When given []bytes, they are first wrapped in PubSubMessages.
 //
-// Note: Doesn't function in batch pipelines.
-func Write(s beam.Scope, project, topic string, col beam.PCollection) {
+// Note: Doesn't function in batch pipelines on Dataflow.
+func Write(s beam.Scope, project, topic string, col beam.PCollection, opts ...WriteOptionFn) {
 	s = s.Scope("pubsubio.Write")
 
-	payload := &pipepb.PubSubWritePayload{
-		Topic: pubsubx.MakeQualifiedTopicName(project, topic),
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
 	}
 
 	out := col
@@ -145,6 +198,13 @@ func Write(s beam.Scope, project, topic string, col beam.PCollection) {
 	if out.Type().Type() != pubSubMessageT {
 		panic(fmt.Sprintf("pubsubio.Write only accepts PCollections of %v and %v, received %v", pubSubMessageT, reflectx.ByteSlice, col.Type().Type()))
 	}
+
+	payload := &pipepb.PubSubWritePayload{
+		Topic: pubsubx.MakeQualifiedTopicName(project, topic),
+	}
 	marshaled := beam.ParDo(s, marshalMessageFn, out)
-	beam.External(s, writeURN, protox.MustEncode(payload), []beam.PCollection{marshaled}, nil, false)
+
+	s = withPayload(s, "PubSubWrite", writeURN, payload)
+	msgs := beam.ParDo(s, unmarshalMessageFn, marshaled)
+	beam.ParDo0(s, &writeFn{Project: project, Topic: topic, Ordering: option.Ordering}, msgs)
 }
@@ -16,10 +16,27 @@
 package pubsubio
 
 import (
-	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"context"
+	"sort"
 	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/pubsubx"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/protobuf/proto"
 )
 
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
 func TestRead_BothTopicAndSubscriptionPanics(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -27,8 +44,6 @@ func TestRead_BothTopicAndSubscriptionPanics(t *testing.T) {
 		}
 	}()
 
-	beam.Init()
-
 	p := beam.NewPipeline()
 	s := p.Root()
 
@@ -46,11 +61,360 @@ func TestRead_NeitherTopicNorSubscriptionPanics(t *testing.T) {
 		}
 	}()
 
-	beam.Init()
-
 	p := beam.NewPipeline()
 	s := p.Root()
 
 	opts := ReadOptions{}
 	Read(s, "test-project", opts)
 }
+
+func TestRead_composite(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	Read(s, "test-project", ReadOptions{Topic: "topic", IDAttribute: "id", WithAttributes: true})
+
+	want := &pipepb.PubSubReadPayload{
+		Topic:          "projects/test-project/topics/topic",
+		IdAttribute:    "id",
+		WithAttributes: true,
+	}
+	got := &pipepb.PubSubReadPayload{}
+	if err := proto.Unmarshal(compositePayload(t, p, readURN), got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Read() payload = %v, want %v", got, want)
+	}
+}
+
+func TestWrite_composite(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	Write(s, "test-project", "topic", beam.Create(s, []byte("a")))
+
+	want := &pipepb.PubSubWritePayload{Topic: "projects/test-project/topics/topic"}
+	got := &pipepb.PubSubWritePayload{}
+	if err := proto.Unmarshal(compositePayload(t, p, writeURN), got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Write() payload = %v, want %v", got, want)
+	}
+}
+
+// compositePayload returns the payload of the only transform of p with the
+// given URN, failing the test unless it's a composite.
+func compositePayload(t *testing.T, p *beam.Pipeline, urn string) []byte {
+	t.Helper()
+	edges, _, err := p.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe, err := graphx.Marshal(edges, &graphx.Options{Environment: &pipepb.Environment{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []*pipepb.PTransform
+	for _, pt := range pipe.GetComponents().GetTransforms() {
+		if pt.GetSpec().GetUrn() == urn {
+			found = append(found, pt)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("got %d transforms with URN %v, want 1", len(found), urn)
+	}
+	if len(found[0].GetSubtransforms()) == 0 {
+		t.Fatalf("transform %v with URN %v has no expansion", found[0].GetUniqueName(), urn)
+	}
+	return found[0].GetSpec().GetPayload()
+}
+
+const project = "test-project"
+
+// startEmulator starts a fake Pub/Sub server that clients connect to, and
+// returns a client of it.
+func startEmulator(t *testing.T) (*pstest.Server, *pubsub.Client) {
+	t.Helper()
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	client, err := pubsub.NewClient(context.Background(), project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+// finalization records the callbacks registered by a bundle.
+type finalization struct {
+	callbacks []func() error
+}
+
+func (f *finalization) RegisterCallback(_ time.Duration, callback func() error) {
+	f.callbacks = append(f.callbacks, callback)
+}
+
+func (f *finalization) finalize(t *testing.T) {
+	t.Helper()
+	for _, callback := range f.callbacks {
+		if err := callback(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.callbacks = nil
+}
+
+type message struct {
+	ts   beam.EventTime
+	data []byte
+}
+
+func TestReadFn(t *testing.T) {
+	defer func(d time.Duration) { receiveIdle = d }(receiveIdle)
+	receiveIdle = 500 * time.Millisecond
+
+	srv, client := startEmulator(t)
+	ctx := context.Background()
+	topic, err := pubsubx.EnsureTopic(ctx, client, "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pubsubx.EnsureSubscription(ctx, client, "topic", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*pubsub.Message{
+		{Data: []byte("a"), Attributes: map[string]string{"id": "1", "ts": "1000"}},
+		{Data: []byte("b"), Attributes: map[string]string{"id": "2", "ts": "1970-01-01T00:00:02Z"}},
+		{Data: []byte("a"), Attributes: map[string]string{"id": "1", "ts": "1000"}},
+	} {
+		if _, err := topic.Publish(ctx, m).Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	topic.Stop()
+
+	fn := &readFn{Project: project, Subscription: "sub", IDAttribute: "id", TimestampAttribute: "ts", WithAttributes: true}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fn.Teardown()
+
+	var got []message
+	emit := func(ts beam.EventTime, b []byte) { got = append(got, message{ts, b}) }
+	var bf finalization
+	we := fn.CreateWatermarkEstimator()
+	rt := fn.CreateTracker(fn.CreateInitialRestriction(nil))
+	pc, err := fn.ProcessElement(ctx, we, &bf, rt, nil, emit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pc.ShouldResume() {
+		t.Error("ProcessElement() stopped, want it to resume")
+	}
+
+	sort.Slice(got, func(i, j int) bool { return got[i].ts < got[j].ts })
+	if len(got) != 2 {
+		t.Fatalf("ProcessElement() emitted %v messages, want 2", len(got))
+	}
+	for i, want := range []struct {
+		ts   beam.EventTime
+		data string
+	}{{1000, "a"}, {2000, "b"}} {
+		var m pb.PubsubMessage
+		if err := proto.Unmarshal(got[i].data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if got[i].ts != want.ts || string(m.GetData()) != want.data || m.GetMessageId() == "" {
+			t.Errorf("message %v = %v at %v, want data %q at %v", i, &m, got[i].ts, want.data, want.ts)
+		}
+	}
+	if got, want := we.CurrentWatermark(), time.UnixMilli(1000); !got.Equal(want) {
+		t.Errorf("watermark = %v, want %v", got, want)
+	}
+
+	for _, m := range srv.Messages() {
+		if m.Acks != 0 {
+			t.Errorf("message %v acknowledged before finalization", m.ID)
+		}
+	}
+	bf.finalize(t)
+	// Acknowledgements are sent asynchronously.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		acked := 0
+		for _, m := range srv.Messages() {
+			acked += m.Acks
+		}
+		if acked == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%v messages acknowledged after finalization, want 3", acked)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReadFn_topic(t *testing.T) {
+	defer func(d time.Duration) { receiveIdle = d }(receiveIdle)
+	receiveIdle = 100 * time.Millisecond
+
+	_, client := startEmulator(t)
+	ctx := context.Background()
+	if _, err := pubsubx.EnsureTopic(ctx, client, "topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := &readFn{Project: project, Topic: "topic", Subscription: "topic.beam_1"}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fn.Teardown()
+	sub := client.Subscription(fn.Subscription)
+	if exists, err := sub.Exists(ctx); err != nil || exists {
+		t.Errorf("subscription exists after Setup() = %v, %v, want false", exists, err)
+	}
+
+	rt := fn.CreateTracker(fn.CreateInitialRestriction(nil))
+	if _, err := fn.ProcessElement(ctx, fn.CreateWatermarkEstimator(), &finalization{}, rt, nil, func(beam.EventTime, []byte) {}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatalf("subscription wasn't created: %v", err)
+	}
+	if cfg.Topic.ID() != "topic" || cfg.ExpirationPolicy != subscriptionExpiry {
+		t.Errorf("subscription is on %v and expires after %v, want topic and %v", cfg.Topic.ID(), cfg.ExpirationPolicy, subscriptionExpiry)
+	}
+
+	// Draining deletes the subscription.
+	if rest := fn.TruncateRestriction(ctx, rt, nil); rest != "" {
+		t.Errorf("TruncateRestriction() = %q, want \"\"", rest)
+	}
+	if exists, err := sub.Exists(ctx); err != nil || exists {
+		t.Errorf("subscription exists after TruncateRestriction() = %v, %v, want false", exists, err)
+	}
+}
+
+func TestSubscriptionTracker(t *testing.T) {
+	rt := newSubscriptionTracker("sub")
+	if !rt.TryClaim("sub") {
+		t.Fatal("TryClaim() = false, want true")
+	}
+	if p, r, err := rt.TrySplit(0.5); err != nil || p != "sub" || r != nil {
+		t.Errorf("TrySplit(0.5) = %v, %v, %v, want sub, nil, nil", p, r, err)
+	}
+	if p, r, err := rt.TrySplit(0); err != nil || p != "" || r != "sub" {
+		t.Errorf("TrySplit(0) = %v, %v, %v, want \"\", sub, nil", p, r, err)
+	}
+	if !rt.IsDone() || rt.TryClaim("sub") {
+		t.Error("tracker not done after checkpointing")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	srv, client := startEmulator(t)
+	if _, err := pubsubx.EnsureTopic(context.Background(), client, "topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	Write(s, project, "topic", beam.Create(s, []byte("a"), []byte("b"), []byte("c")))
+	ptest.RunAndValidate(t, p)
+
+	var got []string
+	for _, m := range srv.Messages() {
+		got = append(got, string(m.Data))
+	}
+	sort.Strings(got)
+	if want := []string{"a", "b", "c"}; !equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestWrite_messages(t *testing.T) {
+	srv, client := startEmulator(t)
+	if _, err := pubsubx.EnsureTopic(context.Background(), client, "topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	msgs := beam.Create(s, &pb.PubsubMessage{Data: []byte("a"), Attributes: map[string]string{"k": "v"}})
+	Write(s, project, "topic", msgs)
+	ptest.RunAndValidate(t, p)
+
+	got := srv.Messages()
+	if len(got) != 1 || string(got[0].Data) != "a" || got[0].Attributes["k"] != "v" {
+		t.Errorf("published %v, want one message with data a and attribute k=v", got)
+	}
+}
+
+func TestWriteFn_ordering(t *testing.T) {
+	srv, client := startEmulator(t)
+	ctx := context.Background()
+
+	fn := &writeFn{Project: project, Topic: "topic", Ordering: true}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fn.Teardown()
+
+	// Publishing fails while the topic doesn't exist, which pauses the key.
+	if err := fn.ProcessElement(ctx, &pb.PubsubMessage{Data: []byte("a"), OrderingKey: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fn.FinishBundle(ctx); err == nil {
+		t.Fatal("FinishBundle() succeeded publishing to a missing topic, want an error")
+	}
+
+	// The retried bundle publishes with the key again.
+	if _, err := pubsubx.EnsureTopic(ctx, client, "topic"); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"a", "b"} {
+		if err := fn.ProcessElement(ctx, &pb.PubsubMessage{Data: []byte(data), OrderingKey: "k"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fn.FinishBundle(ctx); err != nil {
+		t.Fatalf("FinishBundle() failed after the topic was created: %v", err)
+	}
+	var got []string
+	for _, m := range srv.Messages() {
+		if m.OrderingKey != "k" {
+			t.Errorf("message %q has ordering key %q, want k", m.Data, m.OrderingKey)
+		}
+		got = append(got, string(m.Data))
+	}
+	if want := []string{"a", "b"}; !equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestWriteFn_orderingKeyWithoutOrdering(t *testing.T) {
+	startEmulator(t)
+	ctx := context.Background()
+
+	fn := &writeFn{Project: project, Topic: "topic"}
+	if err := fn.Setup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer fn.Teardown()
+	if err := fn.ProcessElement(ctx, &pb.PubsubMessage{Data: []byte("a"), OrderingKey: "k"}); err == nil {
+		t.Error("ProcessElement() accepted a message with an ordering key without WriteMessageOrdering")
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var _ sdf.RTracker = (*subscriptionTracker)(nil)
@@ -0,0 +1,382 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	register.DoFn6x2[context.Context, *sdf.ManualWatermarkEstimator, beam.BundleFinalization, *sdf.LockRTracker, []byte, func(beam.EventTime, []byte), sdf.ProcessContinuation, error](&readFn{})
+	register.Emitter2[beam.EventTime, []byte]()
+	beam.RegisterType(reflect.TypeOf((*subscriptionTracker)(nil)))
+}
+
+var (
+	// receiveIdle is how long a bundle waits for a message before it
+	// checkpoints.
+	receiveIdle = 5 * time.Second
+	// receiveDuration is the longest a bundle receives messages before it
+	// checkpoints, so that they are acknowledged.
+	receiveDuration = 30 * time.Second
+	// maxExtension is the longest the ack deadline of received messages is
+	// extended while they wait for their bundle to be finalized. Messages of
+	// bundles that fail are redelivered after it.
+	maxExtension = 10 * time.Minute
+	// dedupWindow is how long IDs of messages are remembered to drop
+	// duplicates.
+	dedupWindow = 10 * time.Minute
+	// subscriptionExpiry is how long a subscription created for a topic is
+	// kept without being read, in case the pipeline is cancelled rather than
+	// drained. It's the shortest that Pub/Sub allows.
+	subscriptionExpiry = 24 * time.Hour
+)
+
+// readFn is an unbounded SDF that receives messages from a subscription with
+// streaming pull. Messages are acknowledged once the bundle that emitted them
+// is finalized, so that they are redelivered if it fails. It emits the data
+// of the messages, or the serialized messages if WithAttributes is true.
+//
+// If Topic is set, the subscription is created on it when reading starts, and
+// deleted when the pipeline is drained.
+type readFn struct {
+	Project            string
+	Topic              string
+	Subscription       string
+	IDAttribute        string
+	TimestampAttribute string
+	WithAttributes     bool
+
+	client *pubsub.Client
+	// subscribed is whether the subscription for the topic exists.
+	subscribed bool
+	// seen holds when each message ID was last received, for deduplication.
+	seen map[string]time.Time
+}
+
+func (fn *readFn) Setup(ctx context.Context) error {
+	if fn.client == nil {
+		client, err := pubsub.NewClient(ctx, fn.Project)
+		if err != nil {
+			return err
+		}
+		fn.client = client
+	}
+	fn.seen = make(map[string]time.Time)
+	return nil
+}
+
+// subscribe creates the subscription for the topic if it doesn't exist. It
+// isn't created in Setup, which also runs while the pipeline is drained, after
+// the subscription is deleted.
+func (fn *readFn) subscribe(ctx context.Context) error {
+	if fn.Topic == "" || fn.subscribed {
+		return nil
+	}
+	sub := fn.client.Subscription(fn.Subscription)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription %v on %v: %v", fn.Subscription, fn.Topic, err)
+	}
+	if !exists {
+		_, err := fn.client.CreateSubscription(ctx, fn.Subscription, pubsub.SubscriptionConfig{
+			Topic:            fn.client.Topic(fn.Topic),
+			ExpirationPolicy: subscriptionExpiry,
+		})
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return fmt.Errorf("failed to create subscription %v on %v: %v", fn.Subscription, fn.Topic, err)
+		}
+	}
+	fn.subscribed = true
+	return nil
+}
+
+func (fn *readFn) Teardown() error {
+	if fn.client == nil {
+		return nil
+	}
+	return fn.client.Close()
+}
+
+// CreateInitialRestriction returns the subscription as the restriction.
+func (fn *readFn) CreateInitialRestriction(_ []byte) string {
+	return fn.Subscription
+}
+
+// SplitRestriction is a no-op as the restriction cannot be split.
+func (fn *readFn) SplitRestriction(_ []byte, rest string) []string {
+	return []string{rest}
+}
+
+// RestrictionSize always returns 1, as the restriction is a single
+// subscription.
+func (fn *readFn) RestrictionSize(_ []byte, _ string) float64 {
+	return 1
+}
+
+func (fn *readFn) CreateTracker(rest string) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(newSubscriptionTracker(rest))
+}
+
+// TruncateRestriction stops reading when the pipeline is drained, deleting
+// the subscription if it was created for the topic. Failing to delete it
+// doesn't fail the drain, since it expires anyway.
+func (fn *readFn) TruncateRestriction(ctx context.Context, _ *sdf.LockRTracker, _ []byte) string {
+	if fn.Topic != "" {
+		err := fn.client.Subscription(fn.Subscription).Delete(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			log.Warnf(ctx, "Unable to delete subscription %v: %v", fn.Subscription, err)
+		}
+	}
+	return ""
+}
+
+func (fn *readFn) CreateWatermarkEstimator() *sdf.ManualWatermarkEstimator {
+	return &sdf.ManualWatermarkEstimator{}
+}
+
+// ProcessElement receives messages until none arrive for receiveIdle or it
+// has received them for receiveDuration, and then checkpoints.
+//
+// The watermark advances to the earliest timestamp of the messages received,
+// since earlier messages are usually delivered first. If no messages arrive
+// and messages are timestamped by publish time, it advances to the time
+// receiving started, since messages published later can't have earlier
+// timestamps.
+func (fn *readFn) ProcessElement(
+	ctx context.Context,
+	we *sdf.ManualWatermarkEstimator,
+	bf beam.BundleFinalization,
+	rt *sdf.LockRTracker,
+	_ []byte,
+	emit func(beam.EventTime, []byte),
+) (sdf.ProcessContinuation, error) {
+	if !rt.TryClaim(fn.Subscription) {
+		return sdf.StopProcessing(), rt.GetError()
+	}
+	if err := fn.subscribe(ctx); err != nil {
+		return sdf.StopProcessing(), err
+	}
+
+	sub := fn.client.Subscription(fn.Subscription)
+	sub.ReceiveSettings.MaxExtension = maxExtension
+
+	start := time.Now()
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgs := make(chan *pubsub.Message)
+	errs := make(chan error, 1)
+	// Receive keeps extending the ack deadline of the messages until they
+	// are acknowledged when the bundle is finalized, so it returns after
+	// that.
+	go func() {
+		errs <- sub.Receive(rctx, func(ctx context.Context, m *pubsub.Message) {
+			select {
+			case msgs <- m:
+			case <-ctx.Done():
+				m.Nack()
+			}
+		})
+	}()
+
+	var received []*pubsub.Message
+	bf.RegisterCallback(maxExtension, func() error {
+		for _, m := range received {
+			m.Ack()
+		}
+		return nil
+	})
+
+	var low time.Time
+	idle := time.NewTimer(receiveIdle)
+	defer idle.Stop()
+	deadline := time.NewTimer(receiveDuration)
+	defer deadline.Stop()
+loop:
+	for {
+		select {
+		case m := <-msgs:
+			received = append(received, m)
+			ts, ok, err := fn.process(ctx, m, emit)
+			if err != nil {
+				return sdf.StopProcessing(), err
+			}
+			if ok && (low.IsZero() || ts.Before(low)) {
+				low = ts
+			}
+			idle.Reset(receiveIdle)
+		case <-idle.C:
+			break loop
+		case <-deadline.C:
+			break loop
+		case err := <-errs:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return sdf.StopProcessing(), fmt.Errorf("error receiving from %v: %v", fn.Subscription, err)
+			}
+			break loop
+		}
+	}
+
+	watermark := we.CurrentWatermark()
+	switch {
+	case !low.IsZero():
+		watermark = maxTime(watermark, low)
+	case fn.TimestampAttribute == "":
+		watermark = maxTime(watermark, start)
+	}
+	we.UpdateWatermark(watermark)
+	return sdf.ResumeProcessingIn(0), nil
+}
+
+// process emits a message unless it's a duplicate, returning its timestamp
+// and whether it was emitted.
+func (fn *readFn) process(ctx context.Context, m *pubsub.Message, emit func(beam.EventTime, []byte)) (time.Time, bool, error) {
+	now := time.Now()
+	if fn.IDAttribute != "" {
+		if id, ok := m.Attributes[fn.IDAttribute]; ok {
+			if last, ok := fn.seen[id]; ok && now.Sub(last) < dedupWindow {
+				return time.Time{}, false, nil
+			}
+			fn.seen[id] = now
+			// Forget IDs outside the window every so often.
+			if len(fn.seen)%1000 == 0 {
+				for id, last := range fn.seen {
+					if now.Sub(last) >= dedupWindow {
+						delete(fn.seen, id)
+					}
+				}
+			}
+		}
+	}
+
+	ts := m.PublishTime
+	if fn.TimestampAttribute != "" {
+		var err error
+		if ts, err = parseTimestamp(m.Attributes[fn.TimestampAttribute]); err != nil {
+			log.Warnf(ctx, "Message %v has an invalid timestamp attribute %v, using its publish time: %v", m.ID, fn.TimestampAttribute, err)
+			ts = m.PublishTime
+		}
+	}
+
+	data := m.Data
+	if fn.WithAttributes {
+		var err error
+		data, err = proto.Marshal(&pb.PubsubMessage{
+			Data:        m.Data,
+			Attributes:  m.Attributes,
+			MessageId:   m.ID,
+			PublishTime: timestamppb.New(m.PublishTime),
+			OrderingKey: m.OrderingKey,
+		})
+		if err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	emit(beam.EventTime(ts.UnixMilli()), data)
+	return ts, true, nil
+}
+
+// parseTimestamp parses a timestamp attribute, which is either a number of
+// milliseconds since the Unix epoch or in RFC 3339 format.
+func parseTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("attribute is missing")
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// subscriptionTracker tracks a restriction that is a single subscription,
+// which is read until the pipeline is drained. It can only be split by
+// checkpointing.
+type subscriptionTracker struct {
+	subscription string
+	done         bool
+}
+
+func newSubscriptionTracker(subscription string) *subscriptionTracker {
+	return &subscriptionTracker{subscription: subscription, done: subscription == ""}
+}
+
+// TryClaim returns true if the position is the subscription and the tracker
+// hasn't been checkpointed.
+func (t *subscriptionTracker) TryClaim(pos any) bool {
+	s, ok := pos.(string)
+	return ok && !t.done && s == t.subscription
+}
+
+// TrySplit moves the subscription to the residual when checkpointing, and
+// doesn't split otherwise.
+func (t *subscriptionTracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	if fraction < 0 || fraction > 1 {
+		return nil, nil, errors.New("fraction must be between 0 and 1")
+	}
+	if fraction > 0 || t.done {
+		return t.subscription, nil, nil
+	}
+	t.done = true
+	return "", t.subscription, nil
+}
+
+func (t *subscriptionTracker) GetError() error {
+	return nil
+}
+
+// GetProgress reports the restriction as done, so that runners don't try to
+// split it.
+func (t *subscriptionTracker) GetProgress() (done, remaining float64) {
+	return 1, 0
+}
+
+func (t *subscriptionTracker) IsDone() bool {
+	return t.done
+}
+
+func (t *subscriptionTracker) IsBounded() bool {
+	return t.done
+}
+
+func (t *subscriptionTracker) GetRestriction() any {
+	if t.done {
+		return ""
+	}
+	return t.subscription
+}
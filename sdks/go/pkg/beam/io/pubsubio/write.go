@@ -0,0 +1,127 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+)
+
+func init() {
+	register.DoFn2x1[context.Context, *pb.PubsubMessage, error](&writeFn{})
+}
+
+var (
+	// publishBatchSize is the most messages published in one request.
+	publishBatchSize = 1000
+	// publishDelay is the longest messages wait to be batched before they're
+	// published.
+	publishDelay = 50 * time.Millisecond
+	// maxPending is the most messages waiting to be published before
+	// ProcessElement blocks on them.
+	maxPending = 10000
+)
+
+// writeFn publishes messages to a topic in batches. Messages are published by
+// the end of the bundle, so that they are published again if it fails.
+type writeFn struct {
+	Project string
+	Topic   string
+	// Ordering is whether messages with the same ordering key are published
+	// in order.
+	Ordering bool
+
+	client  *pubsub.Client
+	topic   *pubsub.Topic
+	pending []pendingMessage
+}
+
+// pendingMessage is a message that is being published.
+type pendingMessage struct {
+	result      *pubsub.PublishResult
+	orderingKey string
+}
+
+func (fn *writeFn) Setup(ctx context.Context) error {
+	if fn.client == nil {
+		client, err := pubsub.NewClient(ctx, fn.Project)
+		if err != nil {
+			return err
+		}
+		fn.client = client
+	}
+	fn.topic = fn.client.Topic(fn.Topic)
+	fn.topic.PublishSettings.CountThreshold = publishBatchSize
+	fn.topic.PublishSettings.DelayThreshold = publishDelay
+	fn.topic.EnableMessageOrdering = fn.Ordering
+	return nil
+}
+
+func (fn *writeFn) ProcessElement(ctx context.Context, m *pb.PubsubMessage) error {
+	key := m.GetOrderingKey()
+	if key != "" && !fn.Ordering {
+		return fmt.Errorf("message to %v has ordering key %q, but message ordering isn't enabled with WriteMessageOrdering", fn.Topic, key)
+	}
+	result := fn.topic.Publish(ctx, &pubsub.Message{
+		Data:        m.GetData(),
+		Attributes:  m.GetAttributes(),
+		OrderingKey: key,
+	})
+	fn.pending = append(fn.pending, pendingMessage{result: result, orderingKey: key})
+	if len(fn.pending) >= maxPending {
+		return fn.wait(ctx)
+	}
+	return nil
+}
+
+func (fn *writeFn) FinishBundle(ctx context.Context) error {
+	return fn.wait(ctx)
+}
+
+// wait waits for the pending messages to be published. Publishing messages
+// with an ordering key stops once one of them fails, so publishing with the
+// keys of failed messages is resumed for the retry of the bundle.
+func (fn *writeFn) wait(ctx context.Context) error {
+	pending := fn.pending
+	fn.pending = nil
+	var first error
+	for _, m := range pending {
+		if _, err := m.result.Get(ctx); err != nil {
+			if m.orderingKey != "" {
+				fn.topic.ResumePublish(m.orderingKey)
+			}
+			if first == nil {
+				first = fmt.Errorf("failed to publish to %v: %v", fn.Topic, err)
+			}
+		}
+	}
+	return first
+}
+
+func (fn *writeFn) Teardown() error {
+	if fn.topic != nil {
+		fn.topic.Stop()
+	}
+	if fn.client == nil {
+		return nil
+	}
+	return fn.client.Close()
+}
@@ -0,0 +1,315 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bytekeyrange defines a restriction and restriction tracker for
+// ranges of byte string keys, ordered lexicographically, such as the row keys
+// of a Bigtable or HBase table. Positions within a range are interpolated by
+// treating keys as fractions in base 256, which is used to split ranges and to
+// report progress.
+package bytekeyrange
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime"
+)
+
+func init() {
+	runtime.RegisterType(reflect.TypeOf((*Tracker)(nil)))
+	runtime.RegisterType(reflect.TypeOf((*Restriction)(nil)).Elem())
+	runtime.RegisterFunction(restEnc)
+	runtime.RegisterFunction(restDec)
+	coder.RegisterCoder(reflect.TypeOf((*Restriction)(nil)).Elem(), restEnc, restDec)
+}
+
+func restEnc(in Restriction) ([]byte, error) {
+	buf := binary.AppendUvarint(nil, uint64(len(in.Start)))
+	buf = append(buf, in.Start...)
+	buf = binary.AppendUvarint(buf, uint64(len(in.End)))
+	return append(buf, in.End...), nil
+}
+
+func restDec(in []byte) (Restriction, error) {
+	var rest Restriction
+	var err error
+	if rest.Start, in, err = decodeKey(in); err != nil {
+		return rest, err
+	}
+	if rest.End, _, err = decodeKey(in); err != nil {
+		return rest, err
+	}
+	return rest, nil
+}
+
+func decodeKey(in []byte) ([]byte, []byte, error) {
+	n, i := binary.Uvarint(in)
+	if i <= 0 || uint64(len(in)-i) < n {
+		return nil, nil, errors.New("invalid encoded byte key range")
+	}
+	if n == 0 {
+		return nil, in[i:], nil
+	}
+	return in[i : i+int(n)], in[i+int(n):], nil
+}
+
+// Restriction is a byte key range restriction, which represents a range of
+// keys as a half-closed interval with boundaries [start, end). An empty start
+// is the beginning of the key space, and an empty end is the end of the key
+// space, so the zero Restriction contains every key.
+type Restriction struct {
+	Start, End []byte
+}
+
+// bounded returns whether the restriction ends before the end of the key
+// space.
+func (r Restriction) bounded() bool {
+	return len(r.End) > 0
+}
+
+// empty returns whether the restriction contains no keys.
+func (r Restriction) empty() bool {
+	return r.bounded() && bytes.Compare(r.Start, r.End) >= 0
+}
+
+// SplitAt splits a restriction at the given keys, which must be sorted, in
+// ascending order. Keys outside of the restriction, at its start, or equal to
+// the previous key are ignored, so that each split restriction is guaranteed
+// to not be empty.
+func (r Restriction) SplitAt(keys [][]byte) (splits []Restriction) {
+	start := r.Start
+	for _, k := range keys {
+		if len(k) == 0 || bytes.Compare(k, start) <= 0 {
+			continue
+		}
+		if r.bounded() && bytes.Compare(k, r.End) >= 0 {
+			break
+		}
+		splits = append(splits, Restriction{Start: start, End: k})
+		start = k
+	}
+	return append(splits, Restriction{Start: start, End: r.End})
+}
+
+// Size returns the restriction's size as the fraction of the key space that
+// it covers.
+func (r Restriction) Size() float64 {
+	if r.empty() {
+		return 0
+	}
+	n := width(r.Start, r.End)
+	size := new(big.Rat).SetFrac(new(big.Int).Sub(endInt(r.End, n), keyInt(r.Start, n)), endInt(nil, n))
+	f, _ := size.Float64()
+	return f
+}
+
+// width returns the number of bytes keys are padded to for interpolating
+// between them. It exceeds the longest key, so that there are positions
+// between consecutive keys.
+func width(keys ...[]byte) int {
+	n := 0
+	for _, k := range keys {
+		if len(k) > n {
+			n = len(k)
+		}
+	}
+	return n + 2
+}
+
+// keyInt returns the position of a key in the key space as an integer of n
+// bytes, padding it with zeros.
+func keyInt(key []byte, n int) *big.Int {
+	b := make([]byte, n)
+	copy(b, key)
+	return new(big.Int).SetBytes(b)
+}
+
+// endInt returns the position of the end of a range as an integer of n bytes,
+// which is 256^n for the end of the key space.
+func endInt(end []byte, n int) *big.Int {
+	if len(end) == 0 {
+		return new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	}
+	return keyInt(end, n)
+}
+
+// next returns the smallest key greater than key.
+func next(key []byte) []byte {
+	return append(append(make([]byte, 0, len(key)+1), key...), 0)
+}
+
+// Tracker tracks a restriction that can be represented as a range of byte
+// string keys, for example the row keys of a table. Note that this tracker
+// makes no assumptions about the keys within the range, so users must handle
+// validation of keys if needed.
+type Tracker struct {
+	rest      Restriction
+	claimed   []byte // Tracks the last claimed key, nil until a key is claimed.
+	done      bool   // Tracks whether the end of the restriction has been claimed.
+	stopped   bool   // Tracks whether TryClaim has indicated to stop processing elements.
+	attempted []byte // Tracks the last attempted key to claim.
+	err       error
+}
+
+func (tracker *Tracker) String() string {
+	return fmt.Sprintf("[%q,%q) c: %q, a.: %q, stopped: %v, err: %v", tracker.rest.Start, tracker.rest.End, tracker.claimed, tracker.attempted, tracker.stopped, tracker.err)
+}
+
+// NewTracker is a constructor for a Tracker given a start and end range.
+func NewTracker(rest Restriction) *Tracker {
+	return &Tracker{rest: rest}
+}
+
+// TryClaim accepts a []byte key representing the starting position of a block
+// of work. It successfully claims it if the key is greater than the previously
+// claimed key and within the restriction. Claiming a key at or beyond the end
+// of the restriction, or an empty key, signals that the entire restriction has
+// been processed and is now done, at which point this method signals to end
+// processing. Claiming an empty key is the only way to finish a restriction
+// ending at the end of the key space.
+//
+// The tracker stops with an error if a claim is attempted after the tracker
+// has signalled to stop, if a key is claimed before the start of the
+// restriction, or if a key is claimed before the latest successfully claimed.
+func (tracker *Tracker) TryClaim(rawPos any) bool {
+	if tracker.stopped {
+		tracker.err = errors.New("cannot claim work after restriction tracker returns false")
+		return false
+	}
+
+	pos := rawPos.([]byte)
+	tracker.attempted = pos
+	if len(pos) == 0 {
+		tracker.stopped, tracker.done = true, true
+		return false
+	}
+	if bytes.Compare(pos, tracker.rest.Start) < 0 {
+		tracker.stopped = true
+		tracker.err = errors.New("key claimed is out of bounds of the restriction")
+		return false
+	}
+	if tracker.claimed != nil && bytes.Compare(pos, tracker.claimed) <= 0 {
+		tracker.stopped = true
+		tracker.err = errors.New("cannot claim a key lower than the previously claimed key")
+		return false
+	}
+
+	tracker.claimed = pos
+	if tracker.rest.bounded() && bytes.Compare(pos, tracker.rest.End) >= 0 {
+		tracker.stopped, tracker.done = true, true
+		return false
+	}
+	return true
+}
+
+// GetError returns the error that caused the tracker to stop, if there is one.
+func (tracker *Tracker) GetError() error {
+	return tracker.err
+}
+
+// TrySplit splits at the key the given fraction of the way through the
+// remainder of the restriction, interpolating between the keys. If the
+// fraction given is outside of the [0, 1] range, it is clamped to 0 or 1.
+func (tracker *Tracker) TrySplit(fraction float64) (primary, residual any, err error) {
+	if tracker.stopped || tracker.IsDone() {
+		return tracker.rest, nil, nil
+	}
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+
+	// Claimed work belongs to the primary, so the residual starts after it.
+	lo := tracker.rest.Start
+	if tracker.claimed != nil {
+		lo = next(tracker.claimed)
+	}
+	n := width(lo, tracker.rest.End)
+	start, end := keyInt(lo, n), endInt(tracker.rest.End, n)
+
+	// Use the ceiling of the offset, so that the split is exact for
+	// fractions of 0 and 1.
+	offset := new(big.Rat).SetFloat64(fraction)
+	offset.Mul(offset, new(big.Rat).SetInt(new(big.Int).Sub(end, start)))
+	q, m := new(big.Int).QuoRem(offset.Num(), offset.Denom(), new(big.Int))
+	if m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	pt := q.Add(q, start)
+	if pt.Cmp(end) >= 0 {
+		return tracker.rest, nil, nil
+	}
+
+	// Trim the padding of the split key, as long as it stays in the
+	// remainder. It can't be empty, which would mean the end of the key space.
+	splitPt := pt.FillBytes(make([]byte, n))
+	for len(splitPt) > 1 && splitPt[len(splitPt)-1] == 0 && bytes.Compare(splitPt[:len(splitPt)-1], lo) >= 0 {
+		splitPt = splitPt[:len(splitPt)-1]
+	}
+	residual = Restriction{Start: splitPt, End: tracker.rest.End}
+	tracker.rest.End = splitPt
+	return tracker.rest, residual, nil
+}
+
+// GetProgress reports progress as the fractions of the restriction before
+// and after the last claimed key.
+func (tracker *Tracker) GetProgress() (done, remaining float64) {
+	switch {
+	case tracker.done:
+		return 1, 0
+	case tracker.rest.empty():
+		return 0, 0
+	case tracker.claimed == nil:
+		return 0, 1
+	}
+	n := width(tracker.rest.Start, tracker.rest.End, tracker.claimed)
+	start := keyInt(tracker.rest.Start, n)
+	size := new(big.Int).Sub(endInt(tracker.rest.End, n), start)
+	frac := new(big.Rat).SetFrac(new(big.Int).Sub(keyInt(tracker.claimed, n), start), size)
+	done, _ = frac.Float64()
+	return done, 1 - done
+}
+
+// IsDone returns true if the end of the restriction has been claimed, or if
+// no keys remain after the most recent claimed key.
+func (tracker *Tracker) IsDone() bool {
+	if tracker.err != nil {
+		return false
+	}
+	if tracker.done || tracker.rest.empty() {
+		return true
+	}
+	return tracker.claimed != nil && tracker.rest.bounded() && bytes.Compare(next(tracker.claimed), tracker.rest.End) >= 0
+}
+
+// GetRestriction returns a copy of the tracker's underlying
+// bytekeyrange.Restriction.
+func (tracker *Tracker) GetRestriction() any {
+	return tracker.rest
+}
+
+// IsBounded returns whether or not the restriction tracker is tracking a
+// bounded restriction that has a set maximum value or an unbounded one which
+// can grow indefinitely. Byte key ranges are always bounded, even when they
+// end at the end of the key space.
+func (tracker *Tracker) IsBounded() bool {
+	return true
+}
@@ -0,0 +1,278 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bytekeyrange
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func keys(ks ...string) [][]byte {
+	var bs [][]byte
+	for _, k := range ks {
+		bs = append(bs, []byte(k))
+	}
+	return bs
+}
+
+func rest(start, end string) Restriction {
+	r := Restriction{}
+	if start != "" {
+		r.Start = []byte(start)
+	}
+	if end != "" {
+		r.End = []byte(end)
+	}
+	return r
+}
+
+// TestRestriction_Coder tests that restrictions are unchanged by encoding and
+// decoding them.
+func TestRestriction_Coder(t *testing.T) {
+	tests := []Restriction{
+		rest("", ""),
+		rest("a", ""),
+		rest("", "z"),
+		rest("\x00\xff", "\x01"),
+	}
+	for _, test := range tests {
+		b, err := restEnc(test)
+		if err != nil {
+			t.Fatalf("restEnc(%v) failed: %v", test, err)
+		}
+		got, err := restDec(b)
+		if err != nil {
+			t.Fatalf("restDec(restEnc(%v)) failed: %v", test, err)
+		}
+		if !cmp.Equal(got, test) {
+			t.Errorf("restDec(restEnc(%v)) = %v, want %v", test, got, test)
+		}
+	}
+	if _, err := restDec([]byte{5, 'a'}); err == nil {
+		t.Error("restDec of a truncated restriction succeeded, want error")
+	}
+}
+
+// TestRestriction_SplitAt tests that splitting at keys covers the
+// restriction with non-empty restrictions.
+func TestRestriction_SplitAt(t *testing.T) {
+	tests := []struct {
+		rest Restriction
+		keys [][]byte
+		want []Restriction
+	}{
+		{
+			rest: rest("", ""),
+			keys: keys("c", "f"),
+			want: []Restriction{rest("", "c"), rest("c", "f"), rest("f", "")},
+		},
+		{
+			rest: rest("b", "e"),
+			keys: keys("a", "b", "c", "c", "e", "f"),
+			want: []Restriction{rest("b", "c"), rest("c", "e")},
+		},
+		{
+			rest: rest("b", "e"),
+			keys: nil,
+			want: []Restriction{rest("b", "e")},
+		},
+	}
+	for _, test := range tests {
+		if got := test.rest.SplitAt(test.keys); !cmp.Equal(got, test.want) {
+			t.Errorf("%v.SplitAt(%q) = %v, want %v", test.rest, test.keys, got, test.want)
+		}
+	}
+}
+
+// TestRestriction_Size tests that sizes are the fractions of the key space
+// covered by restrictions.
+func TestRestriction_Size(t *testing.T) {
+	tests := []struct {
+		rest Restriction
+		want float64
+	}{
+		{rest: rest("", ""), want: 1},
+		{rest: rest("\x80", ""), want: 0.5},
+		{rest: rest("\x40", "\x80"), want: 0.25},
+		{rest: rest("b", "a"), want: 0},
+	}
+	for _, test := range tests {
+		if got := test.rest.Size(); got != test.want {
+			t.Errorf("%v.Size() = %v, want %v", test.rest, got, test.want)
+		}
+	}
+}
+
+// TestTracker_TryClaim validates both success and failure cases for TryClaim.
+func TestTracker_TryClaim(t *testing.T) {
+	// Test that TryClaim works as expected when called correctly.
+	t.Run("Correctness", func(t *testing.T) {
+		tests := []struct {
+			rest   Restriction
+			claims [][]byte
+		}{
+			{rest: rest("a", "d"), claims: keys("a", "b", "c", "d")},
+			{rest: rest("b", "z"), claims: keys("c", "ca", "zz")},
+			{rest: rest("", ""), claims: keys("a", "b", "")},
+			{rest: rest("a", "d"), claims: keys("")},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(fmt.Sprintf("(rest%v, claims = %q)", test.rest, test.claims), func(t *testing.T) {
+				rt := NewTracker(test.rest)
+				for _, pos := range test.claims {
+					// If TryClaim returns false, check if there was an error.
+					if !rt.TryClaim(pos) && !rt.IsDone() {
+						t.Fatalf("tracker claiming %q failed, error: %v", pos, rt.GetError())
+					}
+				}
+				if !rt.IsDone() {
+					t.Errorf("tracker not done after claiming %q: %v", test.claims, rt)
+				}
+			})
+		}
+	})
+
+	// Test that each invalid error case actually results in an error.
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			rest   Restriction
+			claims [][]byte
+		}{
+			// Claiming backwards.
+			{rest: rest("a", "d"), claims: keys("a", "c", "b")},
+			// Claiming the same key twice.
+			{rest: rest("a", "d"), claims: keys("b", "b")},
+			// Claiming before start of restriction.
+			{rest: rest("b", "d"), claims: keys("a")},
+			// Claiming after tracker signalled to stop.
+			{rest: rest("a", "d"), claims: keys("e", "f")},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(fmt.Sprintf("(rest%v, claims = %q)", test.rest, test.claims), func(t *testing.T) {
+				rt := NewTracker(test.rest)
+				for _, pos := range test.claims {
+					// Finish successfully if we got an error.
+					if !rt.TryClaim(pos) && !rt.IsDone() && rt.GetError() != nil {
+						return
+					}
+				}
+				t.Fatal("tracker did not fail on invalid claim")
+			})
+		}
+	})
+}
+
+// TestTracker_TrySplit tests that TrySplit follows its contract, meaning that
+// splits don't lose any keys, split fractions are clamped to 0 or 1, and that
+// the split key is interpolated after the last claimed key.
+func TestTracker_TrySplit(t *testing.T) {
+	tests := []struct {
+		rest     Restriction
+		claimed  []byte
+		fraction float64
+		// Key where we want the split to happen, or nil for no split. This
+		// will be the end (exclusive) of the primary and the start of the
+		// residual.
+		splitPt []byte
+	}{
+		{
+			rest:     rest("a", "c"),
+			fraction: 0.5,
+			splitPt:  []byte("b"),
+		},
+		{
+			rest:     rest("", ""),
+			fraction: 0.5,
+			splitPt:  []byte("\x80"),
+		},
+		{
+			rest:     rest("a", "b"),
+			claimed:  []byte("a"),
+			fraction: 0.5,
+			splitPt:  []byte("a\x80"),
+		},
+		{
+			rest:     rest("a", "c"),
+			claimed:  []byte("a"),
+			fraction: -0.5,
+			splitPt:  []byte("a\x00"),
+		},
+		{
+			rest:     rest("a", "c"),
+			fraction: 0,
+			splitPt:  []byte("a"),
+		},
+		{
+			rest:     rest("a", "c"),
+			claimed:  []byte("b"),
+			fraction: 1.5,
+			splitPt:  nil,
+		},
+		{
+			rest:     rest("a", "b"),
+			claimed:  []byte("a\xff\xff"),
+			fraction: 0.5,
+			splitPt:  []byte("a\xff\xff\x80"),
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("(split at %v of rest%v after %q)", test.fraction, test.rest, test.claimed), func(t *testing.T) {
+			rt := NewTracker(test.rest)
+			if test.claimed != nil && !rt.TryClaim(test.claimed) {
+				t.Fatalf("tracker failed on initial claim: %q", test.claimed)
+			}
+			gotP, gotR, err := rt.TrySplit(test.fraction)
+			if err != nil {
+				t.Fatalf("tracker failed on split: %v", err)
+			}
+			var wantP any = test.rest
+			var wantR any
+			if test.splitPt != nil {
+				wantP = Restriction{Start: test.rest.Start, End: test.splitPt}
+				wantR = Restriction{Start: test.splitPt, End: test.rest.End}
+			}
+			if !cmp.Equal(gotP, wantP, cmpopts.EquateEmpty()) {
+				t.Errorf("split got incorrect primary: got: %v, want: %v", gotP, wantP)
+			}
+			if !cmp.Equal(gotR, wantR, cmpopts.EquateEmpty()) {
+				t.Errorf("split got incorrect residual: got: %v, want: %v", gotR, wantR)
+			}
+		})
+	}
+}
+
+// TestTracker_GetProgress tests that progress is reported as the fractions of
+// the restriction before and after the last claimed key.
+func TestTracker_GetProgress(t *testing.T) {
+	rt := NewTracker(rest("\x40", "\xc0"))
+	if done, remaining := rt.GetProgress(); done != 0 || remaining != 1 {
+		t.Errorf("GetProgress() before claiming = (%v, %v), want (0, 1)", done, remaining)
+	}
+	rt.TryClaim([]byte("\x80"))
+	if done, remaining := rt.GetProgress(); done != 0.5 || remaining != 0.5 {
+		t.Errorf("GetProgress() after claiming the middle key = (%v, %v), want (0.5, 0.5)", done, remaining)
+	}
+	rt.TryClaim([]byte(nil))
+	if done, remaining := rt.GetProgress(); done != 1 || remaining != 0 {
+		t.Errorf("GetProgress() after claiming the end = (%v, %v), want (1, 0)", done, remaining)
+	}
+}
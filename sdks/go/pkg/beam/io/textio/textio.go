@@ -26,7 +26,6 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
-	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
@@ -41,9 +40,7 @@ func init() {
 	register.DoFn4x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(string, string), error](&readWNameFn{})
 	register.Emitter2[string, string]()
 
-	beam.RegisterType(reflect.TypeOf((*writeFileFn)(nil)).Elem())
-	register.DoFn3x1[context.Context, int, func(*string) bool, error](&writeFileFn{})
-	register.Iter1[string]()
+	beam.RegisterType(reflect.TypeOf((*sink)(nil)).Elem())
 }
 
 type readOption struct {
@@ -68,6 +65,34 @@ func ReadGzip() ReadOptionFn {
 	}
 }
 
+// ReadZstd specifies that files have been compressed using zstd.
+func ReadZstd() ReadOptionFn {
+	return func(o *readOption) {
+		o.FileOpts = append(o.FileOpts, fileio.ReadZstd())
+	}
+}
+
+// ReadBzip2 specifies that files have been compressed using bzip2.
+func ReadBzip2() ReadOptionFn {
+	return func(o *readOption) {
+		o.FileOpts = append(o.FileOpts, fileio.ReadBzip2())
+	}
+}
+
+// ReadSnappy specifies that files have been compressed using the snappy framing format.
+func ReadSnappy() ReadOptionFn {
+	return func(o *readOption) {
+		o.FileOpts = append(o.FileOpts, fileio.ReadSnappy())
+	}
+}
+
+// ReadDeflate specifies that files have been compressed using deflate with a zlib header.
+func ReadDeflate() ReadOptionFn {
+	return func(o *readOption) {
+		o.FileOpts = append(o.FileOpts, fileio.ReadDeflate())
+	}
+}
+
 // ReadUncompressed specifies that files have not been compressed.
 func ReadUncompressed() ReadOptionFn {
 	return func(o *readOption) {
@@ -78,7 +103,8 @@ func ReadUncompressed() ReadOptionFn {
 // Read reads a set of files indicated by the glob pattern and returns
 // the lines as a PCollection<string>. The newlines are not part of the lines.
 // Read accepts a variadic number of ReadOptionFn that can be used to configure the compression
-// type of the file. By default, the compression type is determined by the file extension.
+// type of the file. By default, the compression type is determined by the file extension or,
+// failing that, the first bytes of the file.
 func Read(s beam.Scope, glob string, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("textio.Read")
 
@@ -90,7 +116,8 @@ func Read(s beam.Scope, glob string, opts ...ReadOptionFn) beam.PCollection {
 // PCollection<string>. It returns the lines of all files as a single
 // PCollection<string>. The newlines are not part of the lines.
 // ReadAll accepts a variadic number of ReadOptionFn that can be used to configure the compression
-// type of the files. By default, the compression type is determined by the file extension.
+// type of the files. By default, the compression type is determined by the file extension or,
+// failing that, the first bytes of the file.
 func ReadAll(s beam.Scope, col beam.PCollection, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("textio.ReadAll")
 	return read(s, &readFn{}, col, opts...)
@@ -99,7 +126,8 @@ func ReadAll(s beam.Scope, col beam.PCollection, opts ...ReadOptionFn) beam.PCol
 // ReadWithFilename reads a set of files indicated by the glob pattern and returns
 // a PCollection<KV<string, string>> of each filename and line. The newlines are not part of the lines.
 // ReadWithFilename accepts a variadic number of ReadOptionFn that can be used to configure the compression
-// type of the files. By default, the compression type is determined by the file extension.
+// type of the files. By default, the compression type is determined by the file extension or,
+// failing that, the first bytes of the file.
 func ReadWithFilename(s beam.Scope, glob string, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("textio.ReadWithFilename")
 
@@ -179,23 +207,25 @@ func (fn *readBaseFn) CreateInitialRestriction(file fileio.ReadableFile) offsetr
 	}
 }
 
-const (
-	// blockSize is the desired size of each block for initial splits.
-	blockSize int64 = 64 * 1024 * 1024 // 64 MB
-	// tooSmall is the size limit for a block. If the last block is smaller than
-	// this, it gets merged with the previous block.
-	tooSmall = blockSize / 4
-)
-
-// SplitRestriction splits each file restriction into blocks of a predetermined
-// size, with some checks to avoid having small remainders.
-func (fn *readBaseFn) SplitRestriction(_ fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+// blockSize is the desired size of each block for initial splits.
+var blockSize int64 = 64 * 1024 * 1024 // 64 MB
+
+// SplitRestriction splits the restriction of uncompressed files into blocks of
+// a predetermined size, with some checks to avoid having small remainders.
+// Compressed files can't be split, since the offsets of their lines don't
+// correspond to offsets in the file.
+func (fn *readBaseFn) SplitRestriction(file fileio.ReadableFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	if compressed, err := file.IsCompressed(context.Background()); err != nil || compressed {
+		// Files that can't be opened are left whole, for ProcessElement to
+		// report the error.
+		return []offsetrange.Restriction{rest}
+	}
 	splits := rest.SizedSplits(blockSize)
 	numSplits := len(splits)
 	if numSplits > 1 {
 		last := splits[numSplits-1]
-		if last.End-last.Start <= tooSmall {
-			// Last restriction is too small, so merge it with previous one.
+		// If the last block is too small, merge it with the previous one.
+		if last.End-last.Start <= blockSize/4 {
 			splits[numSplits-2].End = last.End
 			splits = splits[:numSplits-1]
 		}
@@ -222,8 +252,22 @@ func (fn *readBaseFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTrac
 // begin within the restriction and past the restriction (those are entirely
 // output, including the portion outside the restriction). In some cases a
 // valid restriction might not output any lines.
+//
+// Compressed files are read whole by the restriction starting at 0, since the
+// offsets of their lines may lie beyond the size of the file. Restrictions
+// split from it are left empty.
 func (fn *readBaseFn) process(ctx context.Context, rt *sdf.LockRTracker, file fileio.ReadableFile, consumer consumer) error {
 	log.Infof(ctx, "Reading from %v", file.Metadata.Path)
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+
+	whole, err := file.IsCompressed(ctx)
+	if err != nil {
+		return err
+	}
+	if whole && (rest.Start != 0 || !rt.TryClaim(int64(0))) {
+		rt.TryClaim(rest.End)
+		return nil
+	}
 
 	fd, err := file.Open(ctx)
 	if err != nil {
@@ -231,47 +275,29 @@ func (fn *readBaseFn) process(ctx context.Context, rt *sdf.LockRTracker, file fi
 	}
 	defer fd.Close()
 
-	rd := bufio.NewReader(fd)
-
-	i := rt.GetRestriction().(offsetrange.Restriction).Start
-	if i > 0 {
-		// If restriction's starts after 0, we cannot assume a new line starts
-		// at the beginning of the restriction, so we must search for the first
-		// line beginning at or after restriction.Start. This is done by
-		// scanning to the byte just before the restriction and then reading
-		// until the next newline, leaving the reader at the start of a new
-		// line past restriction.Start.
-		i--
-		n, err := rd.Discard(int(i)) // Scan to just before restriction.
-		if err == io.EOF {
-			return errors.Errorf("TextIO restriction lies outside the file being read. "+
-				"Restriction begins at %v bytes, but file is only %v bytes.", i+1, n)
-		}
-		if err != nil {
-			return err
-		}
-		line, err := rd.ReadString('\n') // Read until the first line within the restriction.
-		if err == io.EOF {
-			// No lines start in the restriction but it's still valid, so
-			// finish claiming before returning to avoid errors.
-			rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).End)
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		i += int64(len(line))
+	// If the restriction starts after 0, we cannot assume a new line starts at
+	// the beginning of the restriction, so we must search for the first line
+	// beginning at or after restriction.Start.
+	rd, i, err := fileio.SeekLine(fd, rest.Start)
+	if err == io.EOF {
+		// No lines start in the restriction but it's still valid, so finish
+		// claiming before returning to avoid errors.
+		rt.TryClaim(rest.End)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
 	// Claim each line until we claim a line outside the restriction.
-	for rt.TryClaim(i) {
+	for whole || rt.TryClaim(i) {
 		line, err := rd.ReadString('\n')
 		if err == io.EOF {
 			if len(line) != 0 {
 				consumer.Consume(strings.TrimSuffix(line, "\n"))
 			}
 			// Finish claiming restriction before breaking to avoid errors.
-			rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).End)
+			rt.TryClaim(rest.End)
 			break
 		}
 		if err != nil {
@@ -302,60 +328,45 @@ func (fn *readWNameFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker,
 	return fn.process(ctx, rt, file, &kvEmitter{Key: file.Metadata.Path, Emit: emit})
 }
 
-// TODO(herohde) 7/12/2017: extend Write to write to a series of files
-// as well as allow sharding.
-
 // Write writes a PCollection<string> to a file as separate lines. The
-// writer add a newline after each element.
-func Write(s beam.Scope, filename string, col beam.PCollection) {
+// writer add a newline after each element. It returns a PCollection<string>
+// with the filename once the file has been written, which can be used to
+// sequence later steps with wait.On. The file is written to a temporary
+// location first and renamed once complete.
+//
+// Write produces a single file. Use fileio.WriteFiles with NewSink to write
+// sharded or windowed output.
+func Write(s beam.Scope, filename string, col beam.PCollection) beam.PCollection {
 	s = s.Scope("textio.Write")
 
-	filesystem.ValidateScheme(filename)
-
-	// NOTE(BEAM-3579): We may never call Teardown for non-local runners and
-	// FinishBundle doesn't have the right granularity. We therefore
-	// perform a GBK with a fixed key to get all values in a single invocation.
-
-	// TODO(BEAM-3860) 3/15/2018: use side input instead of GBK.
+	return fileio.WriteFiles(s, filename, NewSink(), col,
+		fileio.WriteNumShards(1), fileio.WriteShardTemplate(""))
+}
 
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFileFn{Filename: filename}, post)
+// NewSink returns a fileio.Sink that writes a PCollection<string> as
+// separate lines. The sink adds a newline after each element.
+func NewSink() fileio.Sink {
+	return &sink{}
 }
 
-type writeFileFn struct {
-	Filename string `json:"filename"`
+type sink struct {
+	buf *bufio.Writer
 }
 
-func (w *writeFileFn) ProcessElement(ctx context.Context, _ int, lines func(*string) bool) error {
-	fs, err := filesystem.New(ctx, w.Filename)
-	if err != nil {
-		return err
-	}
-	defer fs.Close()
+func (s *sink) Open(_ context.Context, w io.Writer) error {
+	s.buf = bufio.NewWriterSize(w, 1<<20) // use 1MB buffer
+	return nil
+}
 
-	fd, err := fs.OpenWrite(ctx, w.Filename)
-	if err != nil {
+func (s *sink) Write(_ context.Context, elm any) error {
+	if _, err := s.buf.WriteString(elm.(string)); err != nil {
 		return err
 	}
-	buf := bufio.NewWriterSize(fd, 1<<20) // use 1MB buffer
-
-	log.Infof(ctx, "Writing to %v", w.Filename)
-
-	var line string
-	for lines(&line) {
-		if _, err := buf.WriteString(line); err != nil {
-			return err
-		}
-		if _, err := buf.Write([]byte{'\n'}); err != nil {
-			return err
-		}
-	}
+	return s.buf.WriteByte('\n')
+}
 
-	if err := buf.Flush(); err != nil {
-		return err
-	}
-	return fd.Close()
+func (s *sink) Flush(_ context.Context) error {
+	return s.buf.Flush()
 }
 
 // Immediate reads a local file at pipeline construction-time and embeds the
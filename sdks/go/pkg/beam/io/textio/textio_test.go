@@ -17,16 +17,23 @@
 package textio
 
 import (
+	"compress/gzip"
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestMain(m *testing.M) {
@@ -104,7 +111,8 @@ func TestWrite(t *testing.T) {
 	out := "text.txt"
 	p, s := beam.NewPipelineWithRoot()
 	lines := Read(s, testFilePath)
-	Write(s, out, lines)
+	written := Write(s, out, lines)
+	passert.Equals(s, written, out)
 
 	ptest.RunAndValidate(t, p)
 
@@ -122,6 +130,33 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestNewSink(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "text")
+	p, s := beam.NewPipelineWithRoot()
+	lines := beam.Create(s, "a", "b", "c")
+	written := fileio.WriteFiles(s, prefix, NewSink(), lines, fileio.WriteNumShards(2))
+	passert.Count(s, written, "written", 2)
+
+	ptest.RunAndValidate(t, p)
+
+	p, s = beam.NewPipelineWithRoot()
+	passert.Equals(s, Read(s, prefix+"-*-of-00002"), "a", "b", "c")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_compressed(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "text")
+	p, s := beam.NewPipelineWithRoot()
+	lines := beam.Create(s, "a", "b")
+	fileio.WriteFiles(s, prefix, NewSink(), lines, fileio.WriteNumShards(1), fileio.WriteZstd())
+
+	ptest.RunAndValidate(t, p)
+
+	p, s = beam.NewPipelineWithRoot()
+	passert.Equals(s, Read(s, prefix+"-*"), "a", "b")
+	ptest.RunAndValidate(t, p)
+}
+
 func TestImmediate(t *testing.T) {
 	f, err := os.CreateTemp("", "test2.txt")
 	if err != nil {
@@ -163,3 +198,93 @@ func TestReadAllSdf(t *testing.T) {
 
 	ptest.RunAndValidate(t, p)
 }
+
+// lines is a consumer that collects the consumed lines.
+type lines []string
+
+func (l *lines) Consume(value string) {
+	*l = append(*l, value)
+}
+
+// TestReadBaseFn_process_split tests that every line is read exactly once
+// when a file is split into two restrictions at any offset.
+func TestReadBaseFn_process_split(t *testing.T) {
+	data := "a\nbb\nccc\ndddd"
+	path := filepath.Join(t.TempDir(), "split.txt")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: int64(len(data))}}
+	want := []string{"a", "bb", "ccc", "dddd"}
+
+	fn := &readBaseFn{}
+	for i := int64(0); i <= int64(len(data)); i++ {
+		var got lines
+		for _, rest := range []offsetrange.Restriction{{Start: 0, End: i}, {Start: i, End: int64(len(data))}} {
+			if err := fn.process(context.Background(), fn.CreateTracker(rest), file, &got); err != nil {
+				t.Fatalf("process(%v) error = %v, want nil", rest, err)
+			}
+		}
+		if !cmp.Equal([]string(got), want) {
+			t.Errorf("process() split at %v read %v, want %v", i, got, want)
+		}
+	}
+}
+
+// TestReadBaseFn_compressed tests that compressed files larger than a block
+// aren't split, and that their lines are read exactly once.
+func TestReadBaseFn_compressed(t *testing.T) {
+	defer func(size int64) { blockSize = size }(blockSize)
+	blockSize = 64
+
+	var data strings.Builder
+	var want []string
+	for i := 0; i < 100; i++ {
+		line := fmt.Sprintf("line %d", i*i)
+		data.WriteString(line + "\n")
+		want = append(want, line)
+	}
+	path := filepath.Join(t.TempDir(), "lines.txt.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	if _, err := w.Write([]byte(data.String())); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() <= 2*blockSize {
+		t.Fatalf("compressed file has %v bytes, want more than %v", info.Size(), 2*blockSize)
+	}
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: info.Size()}}
+
+	fn := &readBaseFn{}
+	rest := fn.CreateInitialRestriction(file)
+	if splits := fn.SplitRestriction(file, rest); len(splits) != 1 || splits[0] != rest {
+		t.Errorf("SplitRestriction() = %v, want [%v]", splits, rest)
+	}
+
+	// Restrictions split from the whole file at any offset read nothing.
+	for _, split := range []int64{rest.End, blockSize, 1} {
+		var got lines
+		for _, r := range []offsetrange.Restriction{{Start: 0, End: split}, {Start: split, End: rest.End}} {
+			rt := fn.CreateTracker(r)
+			if err := fn.process(context.Background(), rt, file, &got); err != nil {
+				t.Fatalf("process(%v) error = %v, want nil", r, err)
+			}
+		}
+		if !cmp.Equal([]string(got), want) {
+			t.Errorf("process() split at %v read %d lines, want %d", split, len(got), len(want))
+		}
+	}
+}
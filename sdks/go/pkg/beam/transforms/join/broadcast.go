@@ -0,0 +1,103 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn4x0[beam.X, beam.Y, func(beam.X) func(*beam.Z) bool, func(beam.T)]((*broadcastFn)(nil))
+}
+
+// BroadcastInnerJoin joins a PCollection<KV<K,L>> with a small
+// PCollection<KV<K,R>> and invokes the join function fn : (K, L, R) -> O for
+// every pair of elements with the same key. It returns a PCollection<O>.
+//
+// Unlike InnerJoin, the right input is not shuffled with the left input but
+// is provided to every worker as a side input. This avoids grouping the left
+// input, but the right input must be small enough to be distributed to and
+// looked up on every worker. Since the left input isn't grouped, the
+// HotKeyFanout option doesn't apply and can't be used with broadcast joins.
+func BroadcastInnerJoin(s beam.Scope, left, small beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return broadcastJoin(s, innerJoin, left, small, fn, opts)
+}
+
+// BroadcastLeftOuterJoin is like BroadcastInnerJoin, except that left
+// elements without a matching right element are joined with the right null
+// value.
+func BroadcastLeftOuterJoin(s beam.Scope, left, small beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return broadcastJoin(s, leftOuterJoin, left, small, fn, opts)
+}
+
+func broadcastJoin(s beam.Scope, kind joinKind, left, small beam.PCollection, fn any, opts []OptionFn) beam.PCollection {
+	s = s.Scope("join.Broadcast" + kind.String())
+
+	option := &option{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	k, l, r := validateInputs(left, small)
+	out := validateJoinFn(fn, k, l, r)
+	if option.Fanout != 0 {
+		panic("join: HotKeyFanout cannot be used with broadcast joins")
+	}
+
+	bfn := &broadcastFn{
+		Fn:        beam.EncodedFunc{Fn: reflectx.MakeFunc(fn)},
+		Outer:     kind == leftOuterJoin,
+		RightType: beam.EncodedType{T: r.Type()},
+		RightNull: encodeNull(r.Type(), option.RightNull, option.HasRightNull),
+	}
+	return beam.ParDo(s, bfn, left, beam.SideInput{Input: small}, beam.TypeDefinition{Var: beam.TType, T: out})
+}
+
+// broadcastFn joins every left element with the values for its key in the
+// multimap side input.
+type broadcastFn struct {
+	// Fn is the encoded join function.
+	Fn beam.EncodedFunc `json:"fn"`
+	// Outer indicates whether unmatched left elements are preserved.
+	Outer bool `json:"outer"`
+	// RightType is the right value type.
+	RightType beam.EncodedType `json:"rightType"`
+	// RightNull is the encoded right null value, if any.
+	RightNull []byte `json:"rightNull"`
+
+	fn        reflectx.Func3x1
+	rightNull any
+}
+
+func (f *broadcastFn) Setup() {
+	f.fn = reflectx.ToFunc3x1(f.Fn.Fn)
+	f.rightNull = decodeNull(f.RightType.T, f.RightNull)
+}
+
+func (f *broadcastFn) ProcessElement(key beam.X, val beam.Y, lookup func(beam.X) func(*beam.Z) bool, emit func(beam.T)) {
+	iter := lookup(key)
+	matched := false
+	var r beam.Z
+	for iter(&r) {
+		matched = true
+		emit(f.fn.Call3x1(key, val, r))
+	}
+	if !matched && f.Outer {
+		emit(f.fn.Call3x1(key, val, f.rightNull))
+	}
+}
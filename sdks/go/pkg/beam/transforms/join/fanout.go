@@ -0,0 +1,106 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*saltedKey)(nil)).Elem())
+	register.DoFn2x2[beam.X, beam.Y, saltedKey, beam.Y]((*saltFn)(nil))
+	register.DoFn3x0[beam.X, beam.Y, func(saltedKey, beam.Y)]((*replicateFn)(nil))
+	register.Emitter2[saltedKey, beam.Y]()
+}
+
+// saltedKey is a key extended with a sub-key, used to spread hot keys over
+// several workers. The original key is kept in its encoded form, so that a
+// single concrete key type can be used for all key types.
+type saltedKey struct {
+	Key  []byte
+	Salt int
+}
+
+func unsalt(dec beam.ElementDecoder, key saltedKey) any {
+	k, err := dec.Decode(bytes.NewReader(key.Key))
+	if err != nil {
+		panic(fmt.Sprintf("join: failed to decode salted key: %v", err))
+	}
+	return k
+}
+
+func encodeKey(enc beam.ElementEncoder, key any) []byte {
+	var buf bytes.Buffer
+	if err := enc.Encode(key, &buf); err != nil {
+		panic(fmt.Sprintf("join: failed to encode key %v: %v", key, err))
+	}
+	return buf.Bytes()
+}
+
+// saltKeys assigns every element of a PCollection<KV<K,V>> a random sub-key
+// in [0, n). It returns a PCollection<KV<saltedKey,V>>.
+func saltKeys(s beam.Scope, col beam.PCollection, k reflect.Type, n int) beam.PCollection {
+	return beam.ParDo(s.Scope("SaltKeys"), &saltFn{KeyType: beam.EncodedType{T: k}, N: n}, col)
+}
+
+// replicateKeys replicates every element of a PCollection<KV<K,V>> to all
+// sub-keys in [0, n). It returns a PCollection<KV<saltedKey,V>>.
+func replicateKeys(s beam.Scope, col beam.PCollection, k reflect.Type, n int) beam.PCollection {
+	return beam.ParDo(s.Scope("ReplicateKeys"), &replicateFn{KeyType: beam.EncodedType{T: k}, N: n}, col)
+}
+
+type saltFn struct {
+	// KeyType is the type of the unsalted key.
+	KeyType beam.EncodedType `json:"keyType"`
+	// N is the number of sub-keys.
+	N int `json:"n"`
+
+	enc beam.ElementEncoder
+}
+
+func (f *saltFn) Setup() {
+	f.enc = beam.NewElementEncoder(f.KeyType.T)
+}
+
+func (f *saltFn) ProcessElement(key beam.X, val beam.Y) (saltedKey, beam.Y) {
+	return saltedKey{Key: encodeKey(f.enc, key), Salt: rand.Intn(f.N)}, val
+}
+
+type replicateFn struct {
+	// KeyType is the type of the unsalted key.
+	KeyType beam.EncodedType `json:"keyType"`
+	// N is the number of sub-keys.
+	N int `json:"n"`
+
+	enc beam.ElementEncoder
+}
+
+func (f *replicateFn) Setup() {
+	f.enc = beam.NewElementEncoder(f.KeyType.T)
+}
+
+func (f *replicateFn) ProcessElement(key beam.X, val beam.Y, emit func(saltedKey, beam.Y)) {
+	k := encodeKey(f.enc, key)
+	for i := 0; i < f.N; i++ {
+		emit(saltedKey{Key: k, Salt: i}, val)
+	}
+}
@@ -0,0 +1,337 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package join contains relational join transformations over keyed
+// PCollections, built on top of CoGroupByKey and side inputs.
+//
+// All joins take a PCollection<KV<K,L>> as the left input, a
+// PCollection<KV<K,R>> as the right input and a join function of the form
+// (K, L, R) -> O, which is invoked once per joined pair. The join returns a
+// PCollection<O>. For example:
+//
+//	func formatFn(id int, name string, order Order) string {
+//		return fmt.Sprintf("%v: %v ordered %v", id, name, order.Item)
+//	}
+//
+//	// Join functions must be registered with Beam, and must not be closures.
+//	func init() { register.Function3x1(formatFn) }
+//
+//	customers := ... // PCollection<KV<int,string>>
+//	orders := ...    // PCollection<KV<int,Order>>
+//	joined := join.InnerJoin(s, customers, orders, formatFn)
+//
+// For outer joins, the missing side is replaced by the zero value of its
+// type, unless a null value is configured with LeftNullValue or
+// RightNullValue.
+package join
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn4x0[beam.X, func(*beam.Y) bool, func(*beam.Z) bool, func(beam.T)]((*joinFn)(nil))
+	register.Iter1[beam.Y]()
+	register.Iter1[beam.Z]()
+	register.Emitter1[beam.T]()
+}
+
+// joinKind identifies which unmatched elements a join preserves.
+type joinKind int
+
+const (
+	innerJoin joinKind = iota
+	leftOuterJoin
+	rightOuterJoin
+	fullOuterJoin
+)
+
+func (k joinKind) String() string {
+	switch k {
+	case innerJoin:
+		return "InnerJoin"
+	case leftOuterJoin:
+		return "LeftOuterJoin"
+	case rightOuterJoin:
+		return "RightOuterJoin"
+	case fullOuterJoin:
+		return "FullOuterJoin"
+	default:
+		return fmt.Sprintf("joinKind(%d)", int(k))
+	}
+}
+
+func (k joinKind) keepsLeft() bool {
+	return k == leftOuterJoin || k == fullOuterJoin
+}
+
+func (k joinKind) keepsRight() bool {
+	return k == rightOuterJoin || k == fullOuterJoin
+}
+
+type option struct {
+	LeftNull     any
+	HasLeftNull  bool
+	RightNull    any
+	HasRightNull bool
+	Fanout       int
+}
+
+// OptionFn is a function that can be passed to the join transforms to
+// configure them.
+type OptionFn func(*option)
+
+// LeftNullValue specifies the value passed to the join function in place of
+// a missing left element. It applies to RightOuterJoin and FullOuterJoin. The
+// value must be of the left value type. By default, the zero value is used.
+func LeftNullValue(v any) OptionFn {
+	return func(o *option) {
+		o.LeftNull = v
+		o.HasLeftNull = true
+	}
+}
+
+// RightNullValue specifies the value passed to the join function in place of
+// a missing right element. It applies to LeftOuterJoin, FullOuterJoin and
+// BroadcastLeftOuterJoin. The value must be of the right value type. By
+// default, the zero value is used.
+func RightNullValue(v any) OptionFn {
+	return func(o *option) {
+		o.RightNull = v
+		o.HasRightNull = true
+	}
+}
+
+// HotKeyFanout spreads the elements of every key over n sub-keys before
+// grouping, which reduces the load on workers processing very frequent keys.
+// The elements of the preserved side (the left side for InnerJoin and
+// LeftOuterJoin, the right side for RightOuterJoin) are assigned a random
+// sub-key, while the elements of the other side are replicated to all n
+// sub-keys. The replicated side should therefore be the smaller one.
+// HotKeyFanout cannot be used with FullOuterJoin or the broadcast joins.
+func HotKeyFanout(n int) OptionFn {
+	return func(o *option) {
+		o.Fanout = n
+	}
+}
+
+// InnerJoin joins a PCollection<KV<K,L>> with a PCollection<KV<K,R>> and
+// invokes the join function fn : (K, L, R) -> O for every pair of elements
+// with the same key. It returns a PCollection<O>. Keys present on only one
+// side are dropped.
+func InnerJoin(s beam.Scope, left, right beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return join(s, innerJoin, left, right, fn, opts)
+}
+
+// LeftOuterJoin joins a PCollection<KV<K,L>> with a PCollection<KV<K,R>> and
+// invokes the join function fn : (K, L, R) -> O for every pair of elements
+// with the same key. Left elements without a matching right element are
+// joined with the right null value. It returns a PCollection<O>.
+func LeftOuterJoin(s beam.Scope, left, right beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return join(s, leftOuterJoin, left, right, fn, opts)
+}
+
+// RightOuterJoin joins a PCollection<KV<K,L>> with a PCollection<KV<K,R>> and
+// invokes the join function fn : (K, L, R) -> O for every pair of elements
+// with the same key. Right elements without a matching left element are
+// joined with the left null value. It returns a PCollection<O>.
+func RightOuterJoin(s beam.Scope, left, right beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return join(s, rightOuterJoin, left, right, fn, opts)
+}
+
+// FullOuterJoin joins a PCollection<KV<K,L>> with a PCollection<KV<K,R>> and
+// invokes the join function fn : (K, L, R) -> O for every pair of elements
+// with the same key. Elements without a match on the other side are joined
+// with the corresponding null value. It returns a PCollection<O>.
+func FullOuterJoin(s beam.Scope, left, right beam.PCollection, fn any, opts ...OptionFn) beam.PCollection {
+	return join(s, fullOuterJoin, left, right, fn, opts)
+}
+
+func join(s beam.Scope, kind joinKind, left, right beam.PCollection, fn any, opts []OptionFn) beam.PCollection {
+	s = s.Scope("join." + kind.String())
+
+	option := &option{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
+	k, l, r := validateInputs(left, right)
+	out := validateJoinFn(fn, k, l, r)
+	if option.Fanout < 0 {
+		panic(fmt.Sprintf("join: fanout must be >= 0, got %v", option.Fanout))
+	}
+	if option.Fanout > 1 && kind == fullOuterJoin {
+		panic("join: HotKeyFanout cannot be used with FullOuterJoin")
+	}
+
+	jfn := &joinFn{
+		Fn:        beam.EncodedFunc{Fn: reflectx.MakeFunc(fn)},
+		Kind:      kind,
+		KeyType:   beam.EncodedType{T: k.Type()},
+		LeftType:  beam.EncodedType{T: l.Type()},
+		RightType: beam.EncodedType{T: r.Type()},
+		LeftNull:  encodeNull(l.Type(), option.LeftNull, option.HasLeftNull),
+		RightNull: encodeNull(r.Type(), option.RightNull, option.HasRightNull),
+	}
+
+	if option.Fanout > 1 {
+		jfn.Salted = true
+		if kind == rightOuterJoin {
+			right = saltKeys(s, right, k.Type(), option.Fanout)
+			left = replicateKeys(s, left, k.Type(), option.Fanout)
+		} else {
+			left = saltKeys(s, left, k.Type(), option.Fanout)
+			right = replicateKeys(s, right, k.Type(), option.Fanout)
+		}
+	}
+
+	grouped := beam.CoGroupByKey(s, left, right)
+	return beam.ParDo(s, jfn, grouped, beam.TypeDefinition{Var: beam.TType, T: out})
+}
+
+// validateInputs panics if left and right are not KV PCollections with the
+// same key type. It returns the key, left value and right value types.
+func validateInputs(left, right beam.PCollection) (typex.FullType, typex.FullType, typex.FullType) {
+	lk, l := beam.ValidateKVType(left)
+	rk, r := beam.ValidateKVType(right)
+	if !typex.IsEqual(lk, rk) {
+		panic(fmt.Sprintf("join: key types must match: left %v, right %v", lk, rk))
+	}
+	return lk, l, r
+}
+
+// validateJoinFn panics if fn is not of the form (K, L, R) -> O. It returns
+// the output type O.
+func validateJoinFn(fn any, k, l, r typex.FullType) reflect.Type {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		panic(fmt.Sprintf("join: join function must be a function, got %v", t))
+	}
+	if t.NumOut() != 1 {
+		panic(fmt.Sprintf("join: join function must have exactly one return value: %v", t))
+	}
+	sig := &funcx.Signature{
+		Args:   []reflect.Type{k.Type(), l.Type(), r.Type()},
+		Return: []reflect.Type{t.Out(0)},
+	}
+	funcx.MustSatisfy(fn, sig)
+	return t.Out(0)
+}
+
+// encodeNull encodes the given null value, if present, with the coder for t.
+func encodeNull(t reflect.Type, v any, ok bool) []byte {
+	if !ok {
+		return nil
+	}
+	if v == nil || reflect.TypeOf(v) != t {
+		panic(fmt.Sprintf("join: null value %v must be of type %v", v, t))
+	}
+	var buf bytes.Buffer
+	if err := beam.NewElementEncoder(t).Encode(v, &buf); err != nil {
+		panic(fmt.Sprintf("join: failed to encode null value %v: %v", v, err))
+	}
+	return buf.Bytes()
+}
+
+// decodeNull returns the decoded null value for t, or the zero value if no
+// null value was provided.
+func decodeNull(t reflect.Type, data []byte) any {
+	if data == nil {
+		return reflect.Zero(t).Interface()
+	}
+	v, err := beam.NewElementDecoder(t).Decode(bytes.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("join: failed to decode null value: %v", err))
+	}
+	return v
+}
+
+// joinFn joins the grouped values of a CoGBK<K,L,R>. The right values for a
+// key are buffered in memory, so the right side should be the one with fewer
+// values per key.
+type joinFn struct {
+	// Fn is the encoded join function.
+	Fn beam.EncodedFunc `json:"fn"`
+	// Kind is the kind of join.
+	Kind joinKind `json:"kind"`
+	// Salted indicates whether the keys are salted by HotKeyFanout.
+	Salted bool `json:"salted"`
+	// KeyType is the unsalted key type.
+	KeyType beam.EncodedType `json:"keyType"`
+	// LeftType is the left value type.
+	LeftType beam.EncodedType `json:"leftType"`
+	// RightType is the right value type.
+	RightType beam.EncodedType `json:"rightType"`
+	// LeftNull is the encoded left null value, if any.
+	LeftNull []byte `json:"leftNull"`
+	// RightNull is the encoded right null value, if any.
+	RightNull []byte `json:"rightNull"`
+
+	fn        reflectx.Func3x1
+	keyDec    beam.ElementDecoder
+	leftNull  any
+	rightNull any
+}
+
+func (f *joinFn) Setup() {
+	f.fn = reflectx.ToFunc3x1(f.Fn.Fn)
+	if f.Salted {
+		f.keyDec = beam.NewElementDecoder(f.KeyType.T)
+	}
+	f.leftNull = decodeNull(f.LeftType.T, f.LeftNull)
+	f.rightNull = decodeNull(f.RightType.T, f.RightNull)
+}
+
+func (f *joinFn) ProcessElement(key beam.X, left func(*beam.Y) bool, right func(*beam.Z) bool, emit func(beam.T)) {
+	k := any(key)
+	if f.Salted {
+		k = unsalt(f.keyDec, key.(saltedKey))
+	}
+
+	var rights []beam.Z
+	var r beam.Z
+	for right(&r) {
+		rights = append(rights, r)
+	}
+
+	var l beam.Y
+	matched := false
+	for left(&l) {
+		matched = true
+		if len(rights) == 0 {
+			if f.Kind.keepsLeft() {
+				emit(f.fn.Call3x1(k, l, f.rightNull))
+			}
+			continue
+		}
+		for _, r := range rights {
+			emit(f.fn.Call3x1(k, l, r))
+		}
+	}
+
+	if !matched && f.Kind.keepsRight() {
+		for _, r := range rights {
+			emit(f.fn.Call3x1(k, f.leftNull, r))
+		}
+	}
+}
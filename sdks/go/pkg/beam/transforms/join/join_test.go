@@ -0,0 +1,207 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func init() {
+	register.Function3x1(formatFn)
+	register.Function1x2(splitFn)
+}
+
+type kv struct {
+	K string
+	V int
+}
+
+func splitFn(e kv) (string, int) {
+	return e.K, e.V
+}
+
+func formatFn(k string, l, r int) string {
+	return fmt.Sprintf("%v:%v:%v", k, l, r)
+}
+
+var (
+	leftInput  = []kv{{"a", 1}, {"a", 2}, {"b", 3}, {"c", 4}}
+	rightInput = []kv{{"a", 10}, {"b", 20}, {"b", 21}, {"d", 30}}
+)
+
+func createInputs(s beam.Scope) (beam.PCollection, beam.PCollection) {
+	left := beam.ParDo(s, splitFn, beam.CreateList(s, leftInput))
+	right := beam.ParDo(s, splitFn, beam.CreateList(s, rightInput))
+	return left, right
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		join func(beam.Scope, beam.PCollection, beam.PCollection, any, ...OptionFn) beam.PCollection
+		opts []OptionFn
+		want []any
+	}{
+		{
+			name: "InnerJoin",
+			join: InnerJoin,
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21"},
+		},
+		{
+			name: "LeftOuterJoin",
+			join: LeftOuterJoin,
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "c:4:0"},
+		},
+		{
+			name: "LeftOuterJoin_nullValue",
+			join: LeftOuterJoin,
+			opts: []OptionFn{RightNullValue(-1)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "c:4:-1"},
+		},
+		{
+			name: "RightOuterJoin",
+			join: RightOuterJoin,
+			opts: []OptionFn{LeftNullValue(-1)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "d:-1:30"},
+		},
+		{
+			name: "FullOuterJoin",
+			join: FullOuterJoin,
+			opts: []OptionFn{LeftNullValue(-1), RightNullValue(-2)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "c:4:-2", "d:-1:30"},
+		},
+		{
+			name: "InnerJoin_fanout",
+			join: InnerJoin,
+			opts: []OptionFn{HotKeyFanout(4)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21"},
+		},
+		{
+			name: "LeftOuterJoin_fanout",
+			join: LeftOuterJoin,
+			opts: []OptionFn{HotKeyFanout(3)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "c:4:0"},
+		},
+		{
+			name: "RightOuterJoin_fanout",
+			join: RightOuterJoin,
+			opts: []OptionFn{HotKeyFanout(3)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "d:0:30"},
+		},
+		{
+			name: "BroadcastInnerJoin",
+			join: BroadcastInnerJoin,
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21"},
+		},
+		{
+			name: "BroadcastLeftOuterJoin",
+			join: BroadcastLeftOuterJoin,
+			opts: []OptionFn{RightNullValue(-1)},
+			want: []any{"a:1:10", "a:2:10", "b:3:20", "b:3:21", "c:4:-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, s := beam.NewPipelineWithRoot()
+			left, right := createInputs(s)
+			joined := test.join(s, left, right, formatFn, test.opts...)
+			passert.Equals(s, joined, test.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestJoin_bad(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   any
+		opts []OptionFn
+	}{
+		{
+			name: "wrongKeyType",
+			fn:   func(k int, l, r int) string { return "" },
+		},
+		{
+			name: "wrongValueType",
+			fn:   func(k string, l string, r int) string { return "" },
+		},
+		{
+			name: "noReturn",
+			fn:   func(k string, l, r int) {},
+		},
+		{
+			name: "notAFunction",
+			fn:   "formatFn",
+		},
+		{
+			name: "wrongNullType",
+			fn:   formatFn,
+			opts: []OptionFn{RightNullValue("none")},
+		},
+		{
+			name: "fanoutFullOuter",
+			fn:   formatFn,
+			opts: []OptionFn{HotKeyFanout(2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("FullOuterJoin(%v) succeeded, want panic", test.name)
+				}
+			}()
+			_, s := beam.NewPipelineWithRoot()
+			left, right := createInputs(s)
+			FullOuterJoin(s, left, right, test.fn, test.opts...)
+		})
+	}
+}
+
+func TestJoin_mismatchedKeys(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("InnerJoin with mismatched key types succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	left, _ := createInputs(s)
+	right := beam.ParDo(s, func(v int) (int, int) { return v, v }, beam.Create(s, 1, 2))
+	InnerJoin(s, left, right, formatFn)
+}
+
+func TestBroadcastJoin_fanout(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("BroadcastInnerJoin with HotKeyFanout succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	left, right := createInputs(s)
+	BroadcastInnerJoin(s, left, right, formatFn, HotKeyFanout(2))
+}
@@ -0,0 +1,315 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package regex contains transformations for matching, extracting and
+// replacing text in PCollection<string> using regular expressions. Patterns
+// use the syntax of the Go regexp package and are compiled once per DoFn
+// instance.
+package regex
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn2x0[string, func(string)]((*matchFn)(nil))
+	register.DoFn2x0[string, func(string, string)]((*matchKVFn)(nil))
+	register.DoFn2x0[string, func(string)]((*findFn)(nil))
+	register.DoFn2x0[string, func([]string)]((*findAllFn)(nil))
+	register.DoFn1x1[string, string]((*replaceAllFn)(nil))
+	register.DoFn1x1[string, string]((*replaceFirstFn)(nil))
+	register.DoFn2x0[string, func(string)]((*splitFn)(nil))
+	register.Emitter1[string]()
+	register.Emitter1[[]string]()
+	register.Emitter2[string, string]()
+}
+
+// Matches returns the given capture group of every element of a
+// PCollection<string> that matches the pattern in its entirety. Group 0 is
+// the whole element. Elements that do not match are dropped. For example:
+//
+//	words := beam.Create(s, "aj", "xj", "yj", "zj")
+//	out := regex.Matches(s, words, "([xyz])j", 1)
+//
+// Here, "out" will contain "x", "y" and "z" at runtime.
+func Matches(s beam.Scope, col beam.PCollection, pattern string, group int) beam.PCollection {
+	s = s.Scope("regex.Matches")
+
+	re := mustCompile(anchored(pattern))
+	validateGroup(re, group)
+	return beam.ParDo(s, &matchFn{Pattern: anchored(pattern), Group: group}, col)
+}
+
+// MatchesName is like Matches, but selects the capture group by name.
+func MatchesName(s beam.Scope, col beam.PCollection, pattern, name string) beam.PCollection {
+	s = s.Scope("regex.MatchesName")
+
+	re := mustCompile(anchored(pattern))
+	return beam.ParDo(s, &matchFn{Pattern: anchored(pattern), Group: groupIndex(re, name)}, col)
+}
+
+// MatchesKV returns a PCollection<KV<string,string>> with the key and value
+// capture groups, selected by name, of every element of a PCollection<string>
+// that matches the pattern in its entirety. Elements that do not match are
+// dropped. For example:
+//
+//	lines := beam.Create(s, "a=1", "b=2", "c")
+//	out := regex.MatchesKV(s, lines, "(?P<key>\\w+)=(?P<value>\\w+)", "key", "value")
+//
+// Here, "out" will contain KV("a","1") and KV("b","2") at runtime.
+func MatchesKV(s beam.Scope, col beam.PCollection, pattern, keyGroup, valueGroup string) beam.PCollection {
+	s = s.Scope("regex.MatchesKV")
+
+	re := mustCompile(anchored(pattern))
+	return beam.ParDo(s, &matchKVFn{
+		Pattern:    anchored(pattern),
+		KeyGroup:   groupIndex(re, keyGroup),
+		ValueGroup: groupIndex(re, valueGroup),
+	}, col)
+}
+
+// Find returns the given capture group of the first match of the pattern in
+// every element of a PCollection<string>. Group 0 is the whole match.
+// Elements without a match are dropped.
+func Find(s beam.Scope, col beam.PCollection, pattern string, group int) beam.PCollection {
+	s = s.Scope("regex.Find")
+
+	re := mustCompile(pattern)
+	validateGroup(re, group)
+	return beam.ParDo(s, &findFn{Pattern: pattern, Group: group}, col)
+}
+
+// FindName is like Find, but selects the capture group by name.
+func FindName(s beam.Scope, col beam.PCollection, pattern, name string) beam.PCollection {
+	s = s.Scope("regex.FindName")
+
+	re := mustCompile(pattern)
+	return beam.ParDo(s, &findFn{Pattern: pattern, Group: groupIndex(re, name)}, col)
+}
+
+// FindAll returns a PCollection<[]string> with one element for every match
+// of the pattern in every element of a PCollection<string>. Each output
+// element holds the whole match followed by all capture groups, in order.
+func FindAll(s beam.Scope, col beam.PCollection, pattern string) beam.PCollection {
+	s = s.Scope("regex.FindAll")
+
+	mustCompile(pattern)
+	return beam.ParDo(s, &findAllFn{Pattern: pattern}, col)
+}
+
+// ReplaceAll replaces all matches of the pattern in every element of a
+// PCollection<string> with the replacement. Inside the replacement, $ signs
+// are interpreted as in regexp.Regexp.Expand, so $1 or ${name} refer to
+// capture groups.
+func ReplaceAll(s beam.Scope, col beam.PCollection, pattern, replacement string) beam.PCollection {
+	s = s.Scope("regex.ReplaceAll")
+
+	mustCompile(pattern)
+	return beam.ParDo(s, &replaceAllFn{Pattern: pattern, Replacement: replacement}, col)
+}
+
+// ReplaceFirst is like ReplaceAll, but only replaces the first match in
+// every element.
+func ReplaceFirst(s beam.Scope, col beam.PCollection, pattern, replacement string) beam.PCollection {
+	s = s.Scope("regex.ReplaceFirst")
+
+	mustCompile(pattern)
+	return beam.ParDo(s, &replaceFirstFn{Pattern: pattern, Replacement: replacement}, col)
+}
+
+// Split splits every element of a PCollection<string> around the matches of
+// the pattern and returns a PCollection<string> of the substrings. Empty
+// substrings are dropped unless outputEmpty is true. For example:
+//
+//	lines := beam.Create(s, "a,b,,c")
+//	out := regex.Split(s, lines, ",", false)
+//
+// Here, "out" will contain "a", "b" and "c" at runtime.
+func Split(s beam.Scope, col beam.PCollection, pattern string, outputEmpty bool) beam.PCollection {
+	s = s.Scope("regex.Split")
+
+	mustCompile(pattern)
+	return beam.ParDo(s, &splitFn{Pattern: pattern, OutputEmpty: outputEmpty}, col)
+}
+
+// anchored returns a pattern that only matches the whole input.
+func anchored(pattern string) string {
+	return `^(?:` + pattern + `)$`
+}
+
+func mustCompile(pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("regex: invalid pattern %q: %v", pattern, err))
+	}
+	return re
+}
+
+func validateGroup(re *regexp.Regexp, group int) {
+	if group < 0 || group > re.NumSubexp() {
+		panic(fmt.Sprintf("regex: group %v out of range for pattern %q with %v groups", group, re, re.NumSubexp()))
+	}
+}
+
+func groupIndex(re *regexp.Regexp, name string) int {
+	i := re.SubexpIndex(name)
+	if i < 0 {
+		panic(fmt.Sprintf("regex: no group named %q in pattern %q", name, re))
+	}
+	return i
+}
+
+type matchFn struct {
+	// Pattern is the anchored regular expression.
+	Pattern string `json:"pattern"`
+	// Group is the index of the capture group to emit.
+	Group int `json:"group"`
+
+	re *regexp.Regexp
+}
+
+func (f *matchFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *matchFn) ProcessElement(elm string, emit func(string)) {
+	if m := f.re.FindStringSubmatch(elm); m != nil {
+		emit(m[f.Group])
+	}
+}
+
+type matchKVFn struct {
+	// Pattern is the anchored regular expression.
+	Pattern string `json:"pattern"`
+	// KeyGroup is the index of the capture group to use as key.
+	KeyGroup int `json:"keyGroup"`
+	// ValueGroup is the index of the capture group to use as value.
+	ValueGroup int `json:"valueGroup"`
+
+	re *regexp.Regexp
+}
+
+func (f *matchKVFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *matchKVFn) ProcessElement(elm string, emit func(string, string)) {
+	if m := f.re.FindStringSubmatch(elm); m != nil {
+		emit(m[f.KeyGroup], m[f.ValueGroup])
+	}
+}
+
+type findFn struct {
+	// Pattern is the regular expression.
+	Pattern string `json:"pattern"`
+	// Group is the index of the capture group to emit.
+	Group int `json:"group"`
+
+	re *regexp.Regexp
+}
+
+func (f *findFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *findFn) ProcessElement(elm string, emit func(string)) {
+	if m := f.re.FindStringSubmatch(elm); m != nil {
+		emit(m[f.Group])
+	}
+}
+
+type findAllFn struct {
+	// Pattern is the regular expression.
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+func (f *findAllFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *findAllFn) ProcessElement(elm string, emit func([]string)) {
+	for _, m := range f.re.FindAllStringSubmatch(elm, -1) {
+		emit(m)
+	}
+}
+
+type replaceAllFn struct {
+	// Pattern is the regular expression.
+	Pattern string `json:"pattern"`
+	// Replacement is the replacement template.
+	Replacement string `json:"replacement"`
+
+	re *regexp.Regexp
+}
+
+func (f *replaceAllFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *replaceAllFn) ProcessElement(elm string) string {
+	return f.re.ReplaceAllString(elm, f.Replacement)
+}
+
+type replaceFirstFn struct {
+	// Pattern is the regular expression.
+	Pattern string `json:"pattern"`
+	// Replacement is the replacement template.
+	Replacement string `json:"replacement"`
+
+	re *regexp.Regexp
+}
+
+func (f *replaceFirstFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *replaceFirstFn) ProcessElement(elm string) string {
+	loc := f.re.FindStringSubmatchIndex(elm)
+	if loc == nil {
+		return elm
+	}
+	var out []byte
+	out = append(out, elm[:loc[0]]...)
+	out = f.re.ExpandString(out, f.Replacement, elm, loc)
+	out = append(out, elm[loc[1]:]...)
+	return string(out)
+}
+
+type splitFn struct {
+	// Pattern is the regular expression.
+	Pattern string `json:"pattern"`
+	// OutputEmpty indicates whether empty substrings are emitted.
+	OutputEmpty bool `json:"outputEmpty"`
+
+	re *regexp.Regexp
+}
+
+func (f *splitFn) Setup() {
+	f.re = regexp.MustCompile(f.Pattern)
+}
+
+func (f *splitFn) ProcessElement(elm string, emit func(string)) {
+	for _, part := range f.re.Split(elm, -1) {
+		if part != "" || f.OutputEmpty {
+			emit(part)
+		}
+	}
+}
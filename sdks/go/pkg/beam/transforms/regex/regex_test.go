@@ -0,0 +1,183 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regex_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/regex"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func init() {
+	register.Function2x1(joinKV)
+	register.Function1x1(joinSlice)
+}
+
+func joinKV(k, v string) string {
+	return k + "=" + v
+}
+
+func joinSlice(s []string) string {
+	return strings.Join(s, "|")
+}
+
+func TestStringTransforms(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		fn   func(s beam.Scope, col beam.PCollection) beam.PCollection
+		exp  []string
+	}{
+		{
+			name: "Matches",
+			in:   []string{"aj", "xj", "yj", "zj", "xjx"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.Matches(s, col, "([xyz])j", 0)
+			},
+			exp: []string{"xj", "yj", "zj"},
+		},
+		{
+			name: "Matches_group",
+			in:   []string{"aj", "xj", "yj", "zj", "xjx"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.Matches(s, col, "([xyz])j", 1)
+			},
+			exp: []string{"x", "y", "z"},
+		},
+		{
+			name: "MatchesName",
+			in:   []string{"a1", "b22", "c"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.MatchesName(s, col, `[a-z](?P<num>\d+)`, "num")
+			},
+			exp: []string{"1", "22"},
+		},
+		{
+			name: "MatchesKV",
+			in:   []string{"a=1", "b=2", "c", "d=4=4"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				kvs := regex.MatchesKV(s, col, `(?P<key>\w+)=(?P<value>\w+)`, "key", "value")
+				return beam.ParDo(s, joinKV, kvs)
+			},
+			exp: []string{"a=1", "b=2"},
+		},
+		{
+			name: "Find",
+			in:   []string{"aj", "xj", "yjy", "zzz"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.Find(s, col, "([xyz])j", 1)
+			},
+			exp: []string{"x", "y"},
+		},
+		{
+			name: "FindName",
+			in:   []string{"id: 12, id: 13", "none"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.FindName(s, col, `id: (?P<id>\d+)`, "id")
+			},
+			exp: []string{"12"},
+		},
+		{
+			name: "FindAll",
+			in:   []string{"a1 b2", "c3", "none"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return beam.ParDo(s, joinSlice, regex.FindAll(s, col, `([a-z])(\d)`))
+			},
+			exp: []string{"a1|a|1", "b2|b|2", "c3|c|3"},
+		},
+		{
+			name: "ReplaceAll",
+			in:   []string{"abc", "xjx", "yjzj"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.ReplaceAll(s, col, "([xyz])j", "${1}y")
+			},
+			exp: []string{"abc", "xyx", "yyzy"},
+		},
+		{
+			name: "ReplaceFirst",
+			in:   []string{"abc", "xjx", "yjzj"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.ReplaceFirst(s, col, "([xyz])j", "${1}y")
+			},
+			exp: []string{"abc", "xyx", "yyzj"},
+		},
+		{
+			name: "Split",
+			in:   []string{"a,b,,c", "d"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.Split(s, col, ",", false)
+			},
+			exp: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "Split_outputEmpty",
+			in:   []string{"a,b,,c"},
+			fn: func(s beam.Scope, col beam.PCollection) beam.PCollection {
+				return regex.Split(s, col, ",", true)
+			},
+			exp: []string{"a", "b", "", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, s, in, exp := ptest.CreateList2(test.in, test.exp)
+			passert.Equals(s, test.fn(s, in), exp)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestBadPatterns(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(s beam.Scope, col beam.PCollection)
+	}{
+		{
+			name: "invalidPattern",
+			fn:   func(s beam.Scope, col beam.PCollection) { regex.Find(s, col, "(", 0) },
+		},
+		{
+			name: "groupOutOfRange",
+			fn:   func(s beam.Scope, col beam.PCollection) { regex.Matches(s, col, "(a)", 2) },
+		},
+		{
+			name: "unknownGroupName",
+			fn:   func(s beam.Scope, col beam.PCollection) { regex.MatchesKV(s, col, "(?P<k>a)(?P<v>b)", "k", "value") },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%v succeeded, want panic", test.name)
+				}
+			}()
+			_, s, col := ptest.Create([]any{"a"})
+			test.fn(s, col)
+		})
+	}
+}
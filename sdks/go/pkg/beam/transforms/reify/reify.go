@@ -0,0 +1,182 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reify contains transformations that make the implicit metadata of
+// elements, such as their event timestamp, window and pane, explicit as part
+// of the data. This is mostly useful for testing and debugging windowing and
+// triggers.
+//
+// Since the element type of a PCollection is only known at pipeline
+// construction time, the metadata is attached as the value of a KV, with the
+// original element as the key. For example, Timestamps turns a
+// PCollection<T> into a PCollection<KV<T,TimestampInfo>>.
+package reify
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*TimestampInfo)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*WindowInfo)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*PaneInfo)(nil)).Elem())
+
+	register.Function2x2(timestampsFn)
+	register.Function4x2(windowsFn)
+	register.Function2x2(panesFn)
+	register.Function2x2(withTimestampFn)
+	register.Function2x2(withWindowTimestampFn)
+}
+
+// TimestampInfo holds the event timestamp of an element. The timestamp is
+// stored in milliseconds since the epoch, since mtime.Time is reserved for
+// the event time parameter of DoFns and can't be part of an element type.
+type TimestampInfo struct {
+	Timestamp int64
+}
+
+// EventTime returns the timestamp as an mtime.Time.
+func (i TimestampInfo) EventTime() mtime.Time {
+	return mtime.Time(i.Timestamp)
+}
+
+// PaneInfo describes the pane an element was emitted in. It mirrors
+// typex.PaneInfo, which can't be used as an element type, since DoFns
+// receive parameters of that type as the pane of the current element.
+type PaneInfo struct {
+	Timing                     typex.PaneTiming
+	IsFirst, IsLast            bool
+	Index, NonSpeculativeIndex int64
+}
+
+func (p PaneInfo) String() string {
+	return fmt.Sprintf("{timing: %v, first: %v, last: %v, index: %v, nonSpeculativeIndex: %v}",
+		p.Timing, p.IsFirst, p.IsLast, p.Index, p.NonSpeculativeIndex)
+}
+
+// WindowInfo describes the timestamp, window and pane of an element. For
+// elements in the global window, Global is true and Start and End hold the
+// bounds of the global window. As for TimestampInfo, all times are in
+// milliseconds since the epoch.
+type WindowInfo struct {
+	Timestamp  int64
+	Global     bool
+	Start, End int64
+	Pane       PaneInfo
+}
+
+// EventTime returns the timestamp as an mtime.Time.
+func (i WindowInfo) EventTime() mtime.Time {
+	return mtime.Time(i.Timestamp)
+}
+
+// Timestamps returns a PCollection<KV<T,TimestampInfo>> with the event
+// timestamp of every element of a PCollection<T>. The timestamps and windows
+// of the elements are unchanged.
+func Timestamps(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("reify.Timestamps")
+
+	beam.ValidateNonCompositeType(col)
+	return beam.ParDo(s, timestampsFn, col)
+}
+
+// Windows returns a PCollection<KV<T,WindowInfo>> with the event timestamp,
+// window and pane of every element of a PCollection<T>. An element in
+// multiple windows is output once per window. The timestamps and windows of
+// the elements are unchanged.
+func Windows(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("reify.Windows")
+
+	beam.ValidateNonCompositeType(col)
+	return beam.ParDo(s, windowsFn, col)
+}
+
+// Panes returns a PCollection<KV<T,PaneInfo>> with the pane of every element
+// of a PCollection<T>. The timestamps and windows of the elements are
+// unchanged.
+func Panes(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("reify.Panes")
+
+	beam.ValidateNonCompositeType(col)
+	return beam.ParDo(s, panesFn, col)
+}
+
+// WithTimestamps is the inverse of Timestamps and Windows. It takes a
+// PCollection<KV<T,TimestampInfo>> or PCollection<KV<T,WindowInfo>> and
+// returns a PCollection<T>, where every element has the reified timestamp as
+// its event timestamp. The elements keep their current windows, so the
+// result typically needs to be re-windowed with beam.WindowInto.
+func WithTimestamps(s beam.Scope, col beam.PCollection) beam.PCollection {
+	s = s.Scope("reify.WithTimestamps")
+
+	_, v := beam.ValidateKVType(col)
+	switch v.Type() {
+	case reflect.TypeOf(TimestampInfo{}):
+		return beam.ParDo(s, withTimestampFn, col)
+	case reflect.TypeOf(WindowInfo{}):
+		return beam.ParDo(s, withWindowTimestampFn, col)
+	default:
+		panic(fmt.Sprintf("reify.WithTimestamps: value type must be TimestampInfo or WindowInfo, got %v", v))
+	}
+}
+
+func toPaneInfo(p typex.PaneInfo) PaneInfo {
+	return PaneInfo{
+		Timing:              p.Timing,
+		IsFirst:             p.IsFirst,
+		IsLast:              p.IsLast,
+		Index:               p.Index,
+		NonSpeculativeIndex: p.NonSpeculativeIndex,
+	}
+}
+
+func toWindowInfo(et typex.EventTime, w typex.Window, p typex.PaneInfo) WindowInfo {
+	info := WindowInfo{Timestamp: et.Milliseconds(), Pane: toPaneInfo(p)}
+	switch w := w.(type) {
+	case window.IntervalWindow:
+		info.Start, info.End = w.Start.Milliseconds(), w.End.Milliseconds()
+	default:
+		info.Global = true
+		info.Start, info.End = mtime.MinTimestamp.Milliseconds(), w.MaxTimestamp().Milliseconds()
+	}
+	return info
+}
+
+func timestampsFn(et typex.EventTime, elm beam.T) (beam.T, TimestampInfo) {
+	return elm, TimestampInfo{Timestamp: et.Milliseconds()}
+}
+
+func windowsFn(p typex.PaneInfo, w typex.Window, et typex.EventTime, elm beam.T) (beam.T, WindowInfo) {
+	return elm, toWindowInfo(et, w, p)
+}
+
+func panesFn(p typex.PaneInfo, elm beam.T) (beam.T, PaneInfo) {
+	return elm, toPaneInfo(p)
+}
+
+func withTimestampFn(elm beam.T, info TimestampInfo) (typex.EventTime, beam.T) {
+	return info.EventTime(), elm
+}
+
+func withWindowTimestampFn(elm beam.T, info WindowInfo) (typex.EventTime, beam.T) {
+	return info.EventTime(), elm
+}
@@ -0,0 +1,136 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reify
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func init() {
+	register.Function1x2(stampFn)
+	register.Function2x1(formatTimestampFn)
+	register.Function2x1(formatWindowFn)
+	register.Function2x1(formatPaneFn)
+	register.Function2x1(observeTimestampFn)
+	register.Function2x1(sumFn)
+	register.Function2x1(observePaneFn)
+}
+
+// stampFn sets the event timestamp of every element to its value in seconds.
+func stampFn(v int) (typex.EventTime, int) {
+	return mtime.FromMilliseconds(int64(v) * 1000), v
+}
+
+func formatTimestampFn(v int, info TimestampInfo) string {
+	return fmt.Sprintf("%v@%v", v, info.Timestamp)
+}
+
+func formatWindowFn(v int, info WindowInfo) string {
+	return fmt.Sprintf("%v@%v[%v,%v) global=%v first=%v", v, info.Timestamp,
+		info.Start, info.End, info.Global, info.Pane.IsFirst)
+}
+
+func formatPaneFn(v int, info PaneInfo) string {
+	return fmt.Sprintf("%v:%v:%v", v, info.Timing, info.IsLast)
+}
+
+func observePaneFn(p typex.PaneInfo, v int) string {
+	return fmt.Sprintf("%v:%v:%v", v, p.Timing, p.IsLast)
+}
+
+func sumFn(a, b int) int {
+	return a + b
+}
+
+func observeTimestampFn(et typex.EventTime, v int) string {
+	return fmt.Sprintf("%v@%v", v, et.Milliseconds())
+}
+
+func TestTimestamps(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 2))
+
+	passert.Equals(s, beam.ParDo(s, formatTimestampFn, Timestamps(s, col)), "1@1000", "2@2000")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWindows(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 12))
+	windowed := beam.WindowInto(s, window.NewFixedWindows(10*time.Second), col)
+
+	formatted := beam.ParDo(s, formatWindowFn, Windows(s, windowed))
+	passert.Equals(s, beam.WindowInto(s, window.NewGlobalWindows(), formatted),
+		"1@1000[0,10000) global=false first=false",
+		"12@12000[10000,20000) global=false first=false")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWindows_global(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1))
+
+	end := window.GlobalWindow{}.MaxTimestamp().Milliseconds()
+	passert.Equals(s, beam.ParDo(s, formatWindowFn, Windows(s, col)),
+		fmt.Sprintf("1@1000[%v,%v) global=true first=false", mtime.MinTimestamp.Milliseconds(), end))
+	ptest.RunAndValidate(t, p)
+}
+
+func TestPanes(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 2))
+	summed := beam.DropKey(s, beam.CombinePerKey(s, sumFn, beam.AddFixedKey(s, col)))
+
+	// Runners differ in the panes they assign, so compare with the pane seen
+	// by a DoFn directly.
+	passert.Equals(s, beam.ParDo(s, formatPaneFn, Panes(s, summed)), beam.ParDo(s, observePaneFn, summed))
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWithTimestamps(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, stampFn, beam.Create(s, 1, 2))
+
+	fromTimestamps := WithTimestamps(s, Timestamps(s, col))
+	fromWindows := WithTimestamps(s, Windows(s, col))
+	passert.Equals(s, beam.ParDo(s, observeTimestampFn, fromTimestamps), "1@1000", "2@2000")
+	passert.Equals(s, beam.ParDo(s, observeTimestampFn, fromWindows), "1@1000", "2@2000")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWithTimestamps_badType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("WithTimestamps() on KV<int,int> succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	WithTimestamps(s, beam.AddFixedKey(s, beam.Create(s, 1)))
+}
@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wait contains transformations for sequencing pipeline steps, such
+// as running a side-effecting step only after a write has completed.
+package wait
+
+import (
+	"fmt"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.Function1x1(signalFn)
+	register.Function2x1(signalKVFn)
+	register.Function2x1(mergeFn)
+	register.Function2x1(passFn)
+	register.Function3x2(passKVFn)
+	register.Iter1[bool]()
+}
+
+// On returns a PCollection with the same elements as main, where the
+// elements of each window are held back until every signal PCollection has
+// completed that window. The signals are typically the results of writes,
+// such as textio.Write or databaseio.Write, and On is used to run a step only
+// once the write has finished. For example:
+//
+//	written := textio.Write(s, "/tmp/data.txt", lines)
+//	marker := beam.Create(s, "/tmp/_SUCCESS")
+//	textio.Write(s, "/tmp/marker.txt", wait.On(s, marker, written))
+//
+// Here, the marker is only written after "/tmp/data.txt" has been written.
+//
+// The signals are consumed as side inputs of main, so a window of main is
+// released once the corresponding signal windows are ready. Signal windows
+// are mapped to main windows as for any side input. The signal contents are
+// combined into a single value per window before being used, so large
+// signals are not materialized.
+func On(s beam.Scope, main beam.PCollection, signals ...beam.PCollection) beam.PCollection {
+	s = s.Scope("wait.On")
+
+	if len(signals) == 0 {
+		panic("wait.On: need at least 1 signal")
+	}
+	for i, signal := range signals {
+		if !signal.IsValid() {
+			panic(fmt.Sprintf("wait.On: invalid signal pcollection: index %v", i))
+		}
+		done := completion(s.Scope(fmt.Sprintf("Signal%v", i)), signal)
+		if typex.IsKV(main.Type()) {
+			main = beam.ParDo(s, passKVFn, main, beam.SideInput{Input: done})
+		} else {
+			main = beam.ParDo(s, passFn, main, beam.SideInput{Input: done})
+		}
+	}
+	return main
+}
+
+// completion reduces a signal PCollection to at most a single element per
+// window.
+func completion(s beam.Scope, signal beam.PCollection) beam.PCollection {
+	var done beam.PCollection
+	if typex.IsKV(signal.Type()) {
+		done = beam.ParDo(s, signalKVFn, signal)
+	} else {
+		done = beam.ParDo(s, signalFn, signal)
+	}
+	return beam.Combine(s, mergeFn, done)
+}
+
+func signalFn(_ beam.T) bool {
+	return true
+}
+
+func signalKVFn(_ beam.X, _ beam.Y) bool {
+	return true
+}
+
+func mergeFn(a, b bool) bool {
+	return a || b
+}
+
+func passFn(elm beam.T, _ func(*bool) bool) beam.T {
+	return elm
+}
+
+func passKVFn(k beam.X, v beam.Y, _ func(*bool) bool) (beam.X, beam.Y) {
+	return k, v
+}
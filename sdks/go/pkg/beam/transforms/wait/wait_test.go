@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func init() {
+	register.Function1x1(countFn)
+	register.Function1x1(observeFn)
+	register.Function1x2(keyFn)
+}
+
+// written counts the elements processed by countFn. The test pipelines run
+// in process, so the count is visible to observeFn.
+var written atomic.Int64
+
+func countFn(v int) int {
+	written.Add(1)
+	return v
+}
+
+func observeFn(_ string) int64 {
+	return written.Load()
+}
+
+func keyFn(v int) (int, int) {
+	return v, v
+}
+
+func TestOn(t *testing.T) {
+	written.Store(0)
+
+	p, s := beam.NewPipelineWithRoot()
+	signal := beam.ParDo(s, countFn, beam.Create(s, 1, 2, 3))
+	kvSignal := beam.ParDo(s, keyFn, beam.Create(s, 4))
+	main := beam.Create(s, "a", "b")
+
+	waited := On(s, main, signal, kvSignal)
+	passert.Equals(s, waited, "a", "b")
+	passert.Equals(s, beam.ParDo(s, observeFn, waited), int64(3), int64(3))
+	ptest.RunAndValidate(t, p)
+}
+
+func TestOn_kvMain(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	main := beam.ParDo(s, keyFn, beam.Create(s, 1, 2))
+	signal := beam.Create(s, "done")
+
+	waited := On(s, main, signal)
+	passert.Equals(s, beam.DropValue(s, waited), 1, 2)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestOn_emptySignal(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	signal := beam.CreateList(s, []int{})
+	main := beam.Create(s, "a")
+
+	passert.Equals(s, On(s, main, signal), "a")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestOn_noSignals(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("On() without signals succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	On(s, beam.Create(s, "a"))
+}
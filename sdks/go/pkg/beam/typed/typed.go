@@ -0,0 +1,200 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typed is an opt-in, type-safe layer over the beam package. It uses
+// Go generics to check the element types of PCollections and the signatures
+// of user functions at compile time, rather than at pipeline construction
+// time. All transforms lower to the regular graph API, so typed and untyped
+// code can be freely mixed. For example:
+//
+//	func splitFn(line string, emit func(string)) {
+//		for _, w := range strings.Fields(line) {
+//			emit(w)
+//		}
+//	}
+//
+//	func pairFn(w string) (string, int) { return w, 1 }
+//	func sumFn(a, b int) int             { return a + b }
+//
+//	func init() {
+//		register.Function2x0(splitFn)
+//		register.Emitter1[string]()
+//		register.Function1x2(pairFn)
+//		register.Function2x1(sumFn)
+//	}
+//
+//	lines := typed.Wrap[string](textio.Read(s, "input.txt"))
+//	words := typed.FlatMap(s, splitFn, lines)
+//	counts := typed.CombinePerKey(s, sumFn, typed.MapToKV(s, pairFn, words))
+//
+// As with the untyped API, user functions must be registered and must not
+// be closures.
+package typed
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/filter"
+)
+
+// PCollection is a beam.PCollection with elements of type T. Use KV[K,V] as
+// T for PCollections of key-value pairs and Grouped[K,V] for the result of a
+// GroupByKey. The underlying beam.PCollection is embedded, so a
+// PCollection[T] can be passed to the untyped API through its PCollection
+// field.
+type PCollection[T any] struct {
+	beam.PCollection
+}
+
+// KV is a marker type for the elements of a PCollection<KV<K,V>>. It is only
+// used as a type argument of PCollection and never holds data.
+type KV[K, V any] struct{}
+
+func (KV[K, V]) fullType() typex.FullType {
+	return typex.NewKV(fullTypeOf[K](), fullTypeOf[V]())
+}
+
+// Grouped is a marker type for the elements of a PCollection<CoGBK<K,V>>,
+// which is the result of GroupByKey. It is only used as a type argument of
+// PCollection and never holds data.
+type Grouped[K, V any] struct{}
+
+func (Grouped[K, V]) fullType() typex.FullType {
+	return typex.NewCoGBK(fullTypeOf[K](), fullTypeOf[V]())
+}
+
+// composite is implemented by the marker types.
+type composite interface {
+	fullType() typex.FullType
+}
+
+// fullTypeOf returns the Beam full type corresponding to T.
+func fullTypeOf[T any]() typex.FullType {
+	var zero T
+	if c, ok := any(zero).(composite); ok {
+		return c.fullType()
+	}
+	return typex.New(reflect.TypeFor[T]())
+}
+
+// TryWrap returns a PCollection[T] for the given beam.PCollection. It
+// returns an error if the element type of the PCollection isn't T.
+func TryWrap[T any](col beam.PCollection) (PCollection[T], error) {
+	if !col.IsValid() {
+		return PCollection[T]{}, errors.New("invalid pcollection")
+	}
+	want := fullTypeOf[T]()
+	if got := col.Type(); !typex.IsEqual(got, want) {
+		return PCollection[T]{}, errors.Errorf("pcollection type mismatch: got %v, want %v", got, want)
+	}
+	return PCollection[T]{PCollection: col}, nil
+}
+
+// Wrap returns a PCollection[T] for the given beam.PCollection. It panics if
+// the element type of the PCollection isn't T.
+func Wrap[T any](col beam.PCollection) PCollection[T] {
+	ret, err := TryWrap[T](col)
+	if err != nil {
+		panic(fmt.Sprintf("typed.Wrap: %v", err))
+	}
+	return ret
+}
+
+// Create inserts a fixed set of values into the pipeline and returns them
+// as a PCollection[T].
+func Create[T any](s beam.Scope, values ...T) PCollection[T] {
+	return Wrap[T](beam.CreateList(s, values))
+}
+
+// ParDo applies fn : In -> Out to every element of a PCollection[In] and
+// returns a PCollection[Out].
+func ParDo[In, Out any](s beam.Scope, fn func(In) Out, col PCollection[In]) PCollection[Out] {
+	return Wrap[Out](beam.ParDo(s, fn, col.PCollection))
+}
+
+// FlatMap applies fn : (In, emit) to every element of a PCollection[In] and
+// returns a PCollection[Out] of all emitted values. The emitter type must be
+// registered with register.Emitter1.
+func FlatMap[In, Out any](s beam.Scope, fn func(In, func(Out)), col PCollection[In]) PCollection[Out] {
+	return Wrap[Out](beam.ParDo(s, fn, col.PCollection))
+}
+
+// MapToKV applies fn : In -> (K, V) to every element of a PCollection[In]
+// and returns a PCollection[KV[K,V]].
+func MapToKV[In, K, V any](s beam.Scope, fn func(In) (K, V), col PCollection[In]) PCollection[KV[K, V]] {
+	return Wrap[KV[K, V]](beam.ParDo(s, fn, col.PCollection))
+}
+
+// MapKV applies fn : (K, V) -> Out to every element of a PCollection[KV[K,V]]
+// and returns a PCollection[Out].
+func MapKV[K, V, Out any](s beam.Scope, fn func(K, V) Out, col PCollection[KV[K, V]]) PCollection[Out] {
+	return Wrap[Out](beam.ParDo(s, fn, col.PCollection))
+}
+
+// Filter returns a PCollection[T] with the elements of col for which fn
+// returns true.
+func Filter[T any](s beam.Scope, fn func(T) bool, col PCollection[T]) PCollection[T] {
+	return Wrap[T](filter.Include(s, col.PCollection, fn))
+}
+
+// Keys returns the keys of a PCollection[KV[K,V]].
+func Keys[K, V any](s beam.Scope, col PCollection[KV[K, V]]) PCollection[K] {
+	return Wrap[K](beam.DropValue(s, col.PCollection))
+}
+
+// Values returns the values of a PCollection[KV[K,V]].
+func Values[K, V any](s beam.Scope, col PCollection[KV[K, V]]) PCollection[V] {
+	return Wrap[V](beam.DropKey(s, col.PCollection))
+}
+
+// GroupByKey groups the values of a PCollection[KV[K,V]] by key and returns
+// a PCollection[Grouped[K,V]], which is typically consumed with MapGrouped.
+func GroupByKey[K, V any](s beam.Scope, col PCollection[KV[K, V]]) PCollection[Grouped[K, V]] {
+	return Wrap[Grouped[K, V]](beam.GroupByKey(s, col.PCollection))
+}
+
+// MapGrouped applies fn : (K, iter) -> Out to every key and its values in a
+// PCollection[Grouped[K,V]] and returns a PCollection[Out]. The iterator type
+// must be registered with register.Iter1.
+func MapGrouped[K, V, Out any](s beam.Scope, fn func(K, func(*V) bool) Out, col PCollection[Grouped[K, V]]) PCollection[Out] {
+	return Wrap[Out](beam.ParDo(s, fn, col.PCollection))
+}
+
+// Combine combines all elements of a PCollection[T] with the associative and
+// commutative function fn : (T, T) -> T and returns a single-element
+// PCollection[T].
+func Combine[T any](s beam.Scope, fn func(T, T) T, col PCollection[T]) PCollection[T] {
+	return Wrap[T](beam.Combine(s, fn, col.PCollection))
+}
+
+// CombinePerKey combines the values for each key of a PCollection[KV[K,V]]
+// with the associative and commutative function fn : (V, V) -> V and returns
+// a PCollection[KV[K,V]] with one element per key.
+func CombinePerKey[K, V any](s beam.Scope, fn func(V, V) V, col PCollection[KV[K, V]]) PCollection[KV[K, V]] {
+	return Wrap[KV[K, V]](beam.CombinePerKey(s, fn, col.PCollection))
+}
+
+// Flatten merges PCollection[T]s into a single PCollection[T].
+func Flatten[T any](s beam.Scope, cols ...PCollection[T]) PCollection[T] {
+	untyped := make([]beam.PCollection, len(cols))
+	for i, col := range cols {
+		untyped[i] = col.PCollection
+	}
+	return Wrap[T](beam.Flatten(s, untyped...))
+}
@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typed
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func init() {
+	register.Function1x1(lengthFn)
+	register.Function2x0(splitFn)
+	register.Emitter1[string]()
+	register.Function1x2(pairFn)
+	register.Function2x1(sumFn)
+	register.Function2x1(formatKVFn)
+	register.Function2x1(countGroupFn)
+	register.Iter1[int]()
+	register.Function1x1(isLongFn)
+}
+
+func lengthFn(s string) int {
+	return len(s)
+}
+
+func splitFn(line string, emit func(string)) {
+	for _, w := range strings.Fields(line) {
+		emit(w)
+	}
+}
+
+func pairFn(w string) (string, int) {
+	return w, 1
+}
+
+func sumFn(a, b int) int {
+	return a + b
+}
+
+func formatKVFn(k string, v int) string {
+	return fmt.Sprintf("%v:%v", k, v)
+}
+
+func countGroupFn(k string, iter func(*int) bool) string {
+	var v, n int
+	for iter(&v) {
+		n += v
+	}
+	return fmt.Sprintf("%v:%v", k, n)
+}
+
+func isLongFn(w string) bool {
+	return len(w) > 1
+}
+
+func TestWrap(t *testing.T) {
+	_, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, "a")
+
+	if _, err := TryWrap[string](col); err != nil {
+		t.Errorf("TryWrap[string](PCollection<string>) failed: %v", err)
+	}
+	if _, err := TryWrap[int](col); err == nil {
+		t.Errorf("TryWrap[int](PCollection<string>) succeeded, want error")
+	}
+	if _, err := TryWrap[KV[string, int]](beam.ParDo(s, pairFn, col)); err != nil {
+		t.Errorf("TryWrap[KV[string, int]](PCollection<KV<string,int>>) failed: %v", err)
+	}
+	if _, err := TryWrap[KV[int, string]](beam.ParDo(s, pairFn, col)); err == nil {
+		t.Errorf("TryWrap[KV[int, string]](PCollection<KV<string,int>>) succeeded, want error")
+	}
+	if _, err := TryWrap[string](beam.PCollection{}); err == nil {
+		t.Errorf("TryWrap[string](invalid) succeeded, want error")
+	}
+}
+
+func TestWrap_mismatch(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Wrap[int](PCollection<string>) succeeded, want panic")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	Wrap[int](beam.Create(s, "a"))
+}
+
+func TestParDo(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	lengths := ParDo(s, lengthFn, Create(s, "a", "bb", "ccc"))
+
+	passert.Equals(s, lengths.PCollection, 1, 2, 3)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestWordCount(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	words := FlatMap(s, splitFn, Create(s, "a bb a", "bb a"))
+	counts := CombinePerKey(s, sumFn, MapToKV(s, pairFn, words))
+
+	passert.Equals(s, MapKV(s, formatKVFn, counts).PCollection, "a:3", "bb:2")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupByKey(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	kvs := MapToKV(s, pairFn, Create(s, "a", "bb", "a"))
+	grouped := MapGrouped(s, countGroupFn, GroupByKey(s, kvs))
+
+	passert.Equals(s, grouped.PCollection, "a:2", "bb:1")
+	ptest.RunAndValidate(t, p)
+}
+
+func TestKeysValues(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	kvs := MapToKV(s, pairFn, Create(s, "a", "bb"))
+
+	passert.Equals(s, Keys(s, kvs).PCollection, "a", "bb")
+	passert.Equals(s, Values(s, kvs).PCollection, 1, 1)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestFilterFlattenCombine(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	words := Flatten(s, Create(s, "a", "bb"), Create(s, "ccc"))
+	long := Filter(s, isLongFn, words)
+
+	passert.Equals(s, long.PCollection, "bb", "ccc")
+	passert.Equals(s, Combine(s, sumFn, ParDo(s, lengthFn, words)).PCollection, 6)
+	ptest.RunAndValidate(t, p)
+}
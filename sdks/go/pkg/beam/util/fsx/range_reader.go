@@ -0,0 +1,118 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OpenRangeFn opens a file for reading from the given offset. It returns the
+// contents of the file from the offset and the size of the file, or -1 if the
+// size is unknown. Opening at or past the end of the file must return an empty
+// reader rather than an error.
+type OpenRangeFn func(ctx context.Context, offset int64) (io.ReadCloser, int64, error)
+
+// errInvalidSeek is returned for seeks to negative offsets.
+var errInvalidSeek = errors.New("seek to negative offset")
+
+// RangeReader is an io.ReadSeekCloser for files that are read with ranged
+// requests, such as files on HTTP servers and in object stores. The file is
+// opened on the first read and reopened on the first read after seeking to
+// another offset, so seeking itself doesn't make any requests.
+type RangeReader struct {
+	ctx  context.Context
+	name string
+	open OpenRangeFn
+
+	offset int64
+	size   int64
+	body   io.ReadCloser
+}
+
+// NewRangeReader returns a RangeReader for the named file, which is opened
+// with open. The size of the file is needed to seek relative to its end, and
+// may be -1 if unknown, in which case it is taken from the first open.
+func NewRangeReader(ctx context.Context, name string, size int64, open OpenRangeFn) *RangeReader {
+	return &RangeReader{ctx: ctx, name: name, open: open, size: size}
+}
+
+// NewOpenedRangeReader returns a RangeReader like NewRangeReader, whose body
+// from offset 0 has already been opened. This lets file systems report
+// missing files when opening them, without an additional request. The body
+// is closed once the reader seeks to another offset.
+func NewOpenedRangeReader(ctx context.Context, name string, size int64, open OpenRangeFn, body io.ReadCloser) *RangeReader {
+	return &RangeReader{ctx: ctx, name: name, open: open, size: size, body: body}
+}
+
+// Read reads from the file at the current offset. If the size of the file is
+// known, reading at or past its end returns io.EOF without opening it.
+func (r *RangeReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		if r.size >= 0 && r.offset >= r.size {
+			return 0, io.EOF
+		}
+		body, size, err := r.open(r.ctx, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		if size >= 0 {
+			r.size = size
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek sets the offset of the next Read. Seeking relative to the end of the
+// file requires the size of the file to be known.
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		if r.size < 0 {
+			return r.offset, fmt.Errorf("can't seek relative to the end of %v: size is unknown", r.name)
+		}
+		offset += r.size
+	default:
+		return r.offset, fmt.Errorf("invalid whence: %v", whence)
+	}
+	if offset < 0 {
+		return r.offset, errInvalidSeek
+	}
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Close closes the current body, if any.
+func (r *RangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
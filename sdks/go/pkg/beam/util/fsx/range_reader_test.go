@@ -0,0 +1,123 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeFile returns an OpenRangeFn for data, which records the offsets it is
+// opened at.
+func fakeFile(data string, opens *[]int64) OpenRangeFn {
+	return func(_ context.Context, offset int64) (io.ReadCloser, int64, error) {
+		*opens = append(*opens, offset)
+		if offset >= int64(len(data)) {
+			return io.NopCloser(strings.NewReader("")), int64(len(data)), nil
+		}
+		return io.NopCloser(strings.NewReader(data[offset:])), int64(len(data)), nil
+	}
+}
+
+func TestRangeReader(t *testing.T) {
+	var opens []int64
+	r := NewRangeReader(context.Background(), "file", -1, fakeFile("0123456789", &opens))
+	defer r.Close()
+
+	if _, err := r.Seek(-1, io.SeekEnd); err == nil {
+		t.Errorf("Seek(-1, io.SeekEnd) with unknown size succeeded, want error")
+	}
+	seeks := []struct {
+		offset int64
+		whence int
+		want   string
+	}{
+		{4, io.SeekStart, "456789"},
+		{-3, io.SeekEnd, "789"},
+		{10, io.SeekStart, ""},
+	}
+	for _, s := range seeks {
+		if _, err := r.Seek(s.offset, s.whence); err != nil {
+			t.Fatalf("Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() after Seek(%v, %v) error = %v, want nil", s.offset, s.whence, err)
+		}
+		if string(got) != s.want {
+			t.Errorf("ReadAll() after Seek(%v, %v) = %q, want %q", s.offset, s.whence, got, s.want)
+		}
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seek(-1, io.SeekStart) succeeded, want error")
+	}
+	// Seeking to the end after reading to it keeps the open body.
+	if got, want := fmt.Sprint(opens), "[4 7]"; got != want {
+		t.Errorf("RangeReader opened the file at %v, want %v", got, want)
+	}
+}
+
+func TestRangeReader_seekCurrent(t *testing.T) {
+	var opens []int64
+	r := NewRangeReader(context.Background(), "file", 10, fakeFile("0123456789", &opens))
+	defer r.Close()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("ReadFull() error = %v, want nil", err)
+	}
+	// Seeking to the current offset keeps the open body.
+	if pos, err := r.Seek(0, io.SeekCurrent); err != nil || pos != 2 {
+		t.Errorf("Seek(0, io.SeekCurrent) = %v, %v, want 2, nil", pos, err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "23456789" {
+		t.Errorf("ReadAll() = %q, want %q", got, "23456789")
+	}
+	if len(opens) != 1 {
+		t.Errorf("RangeReader opened the file %v times, want 1", len(opens))
+	}
+}
+
+func TestRangeReader_opened(t *testing.T) {
+	var opens []int64
+	body := io.NopCloser(strings.NewReader("0123456789"))
+	r := NewOpenedRangeReader(context.Background(), "file", 10, fakeFile("0123456789", &opens), body)
+	defer r.Close()
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil || string(buf) != "0123" {
+		t.Fatalf("ReadFull() = %q, %v, want %q, nil", buf, err, "0123")
+	}
+	if _, err := r.Seek(8, io.SeekStart); err != nil {
+		t.Fatalf("Seek(8, io.SeekStart) error = %v, want nil", err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "89" {
+		t.Errorf("ReadAll() = %q, want %q", got, "89")
+	}
+	// Reading past the end of a file of known size doesn't open it.
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		t.Fatalf("Seek(12, io.SeekStart) error = %v, want nil", err)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read() past the end = %v, %v, want 0, io.EOF", n, err)
+	}
+	if got, want := fmt.Sprint(opens), "[8]"; got != want {
+		t.Errorf("RangeReader opened the file at %v, want %v", got, want)
+	}
+}
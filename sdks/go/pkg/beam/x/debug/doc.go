@@ -18,5 +18,5 @@
 package debug
 
 //go:generate go install github.com/apache/beam/sdks/v2/go/cmd/starcgen
-//go:generate starcgen --package=debug --identifiers=headFn,headKVFn,discardFn,printFn,printKVFn,printGBKFn
+//go:generate starcgen --package=debug --identifiers=headFn,headKVFn,discardFn,printFn,printKVFn,printGBKFn,printMetadataFn,printKVMetadataFn,printGBKMetadataFn
 //go:generate go fmt
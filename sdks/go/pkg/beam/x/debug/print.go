@@ -24,21 +24,48 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
 )
 
+type printOption struct {
+	Metadata bool
+}
+
+// PrintOptionFn is a function that can be passed to Print or Printf to
+// configure the printed output.
+type PrintOptionFn func(*printOption)
+
+// WithMetadata specifies that the event timestamp, window and pane of each
+// element are printed along with the element.
+func WithMetadata() PrintOptionFn {
+	return func(o *printOption) {
+		o.Metadata = true
+	}
+}
+
 // Print prints out all data. Use with care.
-func Print(s beam.Scope, col beam.PCollection) beam.PCollection {
-	return Printf(s, "Elm: %v", col)
+func Print(s beam.Scope, col beam.PCollection, opts ...PrintOptionFn) beam.PCollection {
+	return Printf(s, "Elm: %v", col, opts...)
 }
 
 // Printf prints out all data with custom formatting. The given format string
 // is used as log.Printf(format, elm) for each element. Use with care.
-func Printf(s beam.Scope, format string, col beam.PCollection) beam.PCollection {
+func Printf(s beam.Scope, format string, col beam.PCollection, opts ...PrintOptionFn) beam.PCollection {
 	s = s.Scope("debug.Print")
 
+	option := &printOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+
 	switch {
+	case typex.IsKV(col.Type()) && option.Metadata:
+		return beam.ParDo(s, &printKVMetadataFn{Format: format}, col)
 	case typex.IsKV(col.Type()):
 		return beam.ParDo(s, &printKVFn{Format: format}, col)
+	case typex.IsCoGBK(col.Type()) && option.Metadata:
+		return beam.ParDo(s, &printGBKMetadataFn{Format: format}, col)
 	case typex.IsCoGBK(col.Type()):
 		return beam.ParDo(s, &printGBKFn{Format: format}, col)
+	case option.Metadata:
+		return beam.ParDo(s, &printMetadataFn{Format: format}, col)
 	default:
 		return beam.ParDo(s, &printFn{Format: format}, col)
 	}
@@ -69,12 +96,52 @@ type printGBKFn struct {
 }
 
 func (f *printGBKFn) ProcessElement(ctx context.Context, x beam.X, iter func(*beam.Y) bool) beam.X {
+	log.Infof(ctx, f.Format, fmt.Sprintf("(%v,%v)", x, formatValues(iter)))
+	return x
+}
+
+func formatValues(iter func(*beam.Y) bool) []string {
 	var ys []string
 	var y beam.Y
 	for iter(&y) {
 		ys = append(ys, fmt.Sprintf("%v", y))
 	}
-	log.Infof(ctx, f.Format, fmt.Sprintf("(%v,%v)", x, ys))
+	return ys
+}
+
+// The metadata variants take the window of each element, so they are only
+// used if requested, since that processes elements in multiple windows once
+// per window.
+
+// logMetadata logs the formatted element followed by its metadata.
+func logMetadata(ctx context.Context, format string, elm any, p typex.PaneInfo, w typex.Window, et typex.EventTime) {
+	log.Infof(ctx, format+" [timestamp: %v, window: %v, pane: %+v]", elm, et, w, p)
+}
+
+type printMetadataFn struct {
+	Format string `json:"format"`
+}
+
+func (f *printMetadataFn) ProcessElement(ctx context.Context, p typex.PaneInfo, w typex.Window, et typex.EventTime, t beam.T) beam.T {
+	logMetadata(ctx, f.Format, t, p, w, et)
+	return t
+}
+
+type printKVMetadataFn struct {
+	Format string `json:"format"`
+}
+
+func (f *printKVMetadataFn) ProcessElement(ctx context.Context, p typex.PaneInfo, w typex.Window, et typex.EventTime, x beam.X, y beam.Y) (beam.X, beam.Y) {
+	logMetadata(ctx, f.Format, fmt.Sprintf("(%v,%v)", x, y), p, w, et)
+	return x, y
+}
+
+type printGBKMetadataFn struct {
+	Format string `json:"format"`
+}
+
+func (f *printGBKMetadataFn) ProcessElement(ctx context.Context, p typex.PaneInfo, w typex.Window, et typex.EventTime, x beam.X, iter func(*beam.Y) bool) beam.X {
+	logMetadata(ctx, f.Format, fmt.Sprintf("(%v,%v)", x, formatValues(iter)), p, w, et)
 	return x
 }
 
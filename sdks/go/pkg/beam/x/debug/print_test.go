@@ -88,6 +88,19 @@ func TestPrint_CoGBK(t *testing.T) {
 	}
 }
 
+func TestPrint_WithMetadata(t *testing.T) {
+	p, s, sequence := ptest.CreateList([]string{"abc"})
+	Print(s, sequence, WithMetadata())
+
+	output := captureRunLogging(p)
+	if !strings.Contains(output, "Elm: abc [timestamp: ") {
+		t.Errorf("Print() should contain \"Elm: abc [timestamp: \", got: %v", output)
+	}
+	if !strings.Contains(output, "window: [*]") {
+		t.Errorf("Print() should contain the global window \"window: [*]\", got: %v", output)
+	}
+}
+
 func captureRunLogging(p *beam.Pipeline) string {
 	// Pipe output to out
 	var out bytes.Buffer
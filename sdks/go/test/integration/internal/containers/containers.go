@@ -29,6 +29,12 @@ import (
 
 type ContainerOptionFn func(*testcontainers.ContainerRequest)
 
+func WithCmd(cmd []string) ContainerOptionFn {
+	return func(option *testcontainers.ContainerRequest) {
+		option.Cmd = cmd
+	}
+}
+
 func WithEnv(env map[string]string) ContainerOptionFn {
 	return func(option *testcontainers.ContainerRequest) {
 		option.Env = env
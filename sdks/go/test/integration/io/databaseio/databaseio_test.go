@@ -0,0 +1,332 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/databaseio"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/dataflow"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/flink"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/samza"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/spark"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/test/integration"
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*order)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*orderKey)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*shipment)(nil)).Elem())
+}
+
+type order struct {
+	ID        int64
+	Customer  string
+	Amount    float64
+	CreatedAt time.Time
+}
+
+type orderKey struct {
+	Customer string
+	MinID    int64
+}
+
+type shipment struct {
+	ID     int64
+	Weight *int64
+}
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// createOrders creates a table of orders where pairs of orders share an ID,
+// amounts are fractional and creation times are days apart.
+func createOrders(ctx context.Context, t *testing.T, dsn, table string, n int) []order {
+	t.Helper()
+
+	db := openDB(t, dsn)
+	exec(ctx, t, db, fmt.Sprintf("CREATE TABLE %s (id BIGINT, customer TEXT, amount DOUBLE PRECISION, created_at TIMESTAMP)", table))
+
+	var orders []order
+	var values []string
+	for i := 0; i < n; i++ {
+		o := order{
+			ID:        int64(i / 2),
+			Customer:  fmt.Sprintf("customer%d", i%3),
+			Amount:    float64(i) * 1.25,
+			CreatedAt: start.Add(time.Duration(i) * 24 * time.Hour),
+		}
+		orders = append(orders, o)
+		values = append(values, fmt.Sprintf("(%d, '%s', %v, '%s')", o.ID, o.Customer, o.Amount, o.CreatedAt.Format("2006-01-02 15:04:05")))
+	}
+	exec(ctx, t, db, fmt.Sprintf("INSERT INTO %s VALUES %s", table, strings.Join(values, ",")))
+
+	return orders
+}
+
+func toAny(orders []order, keep func(order) bool) []any {
+	var out []any
+	for _, o := range orders {
+		if keep == nil || keep(o) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func TestDatabaseIO_ReadPartitioned(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	orders := createOrders(ctx, t, dsn, "orders", 100)
+
+	tests := []struct {
+		name    string
+		column  string
+		options []databaseio.ReadOptionFn
+		want    []any
+	}{
+		{
+			name:   "Read partitioned by an integer column with duplicate values",
+			column: "id",
+			options: []databaseio.ReadOptionFn{
+				databaseio.ReadPartitions(7),
+			},
+			want: toAny(orders, nil),
+		},
+		{
+			name:   "Read partitioned by a fractional column",
+			column: "amount",
+			want:   toAny(orders, nil),
+		},
+		{
+			name:   "Read partitioned by a timestamp column",
+			column: "created_at",
+			options: []databaseio.ReadOptionFn{
+				databaseio.ReadPartitions(4),
+			},
+			want: toAny(orders, nil),
+		},
+		{
+			name:   "Read partitioned where a condition matches",
+			column: "id",
+			options: []databaseio.ReadOptionFn{
+				databaseio.ReadWhere("customer = 'customer1'"),
+			},
+			want: toAny(orders, func(o order) bool { return o.Customer == "customer1" }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, s := beam.NewPipelineWithRoot()
+
+			got := databaseio.ReadPartitioned(s, "postgres", dsn, "orders", tt.column, reflect.TypeOf(order{}), tt.options...)
+
+			passert.Equals(s, got, tt.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestDatabaseIO_ReadPartitioned_Empty(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	createOrders(ctx, t, dsn, "empty_orders", 0)
+
+	p, s := beam.NewPipelineWithRoot()
+
+	got := databaseio.ReadPartitioned(s, "postgres", dsn, "empty_orders", "id", reflect.TypeOf(order{}))
+
+	passert.Empty(s, got)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestDatabaseIO_ReadPartitioned_Nulls(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	db := openDB(t, dsn)
+	exec(ctx, t, db,
+		"CREATE TABLE shipments (id BIGINT, weight BIGINT)",
+		"INSERT INTO shipments VALUES (1, 10), (2, NULL), (3, 30), (4, NULL)",
+	)
+
+	weight := func(w int64) *int64 { return &w }
+	shipments := []shipment{{1, weight(10)}, {2, nil}, {3, weight(30)}, {4, nil}}
+
+	tests := []struct {
+		name    string
+		options []databaseio.ReadOptionFn
+		want    []any
+	}{
+		{
+			name: "Read rows where the column is null",
+			want: []any{shipments[0], shipments[1], shipments[2], shipments[3]},
+		},
+		{
+			name: "Read rows where the column is null and a condition matches",
+			options: []databaseio.ReadOptionFn{
+				databaseio.ReadWhere("id > 1"),
+			},
+			want: []any{shipments[1], shipments[2], shipments[3]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, s := beam.NewPipelineWithRoot()
+
+			got := databaseio.ReadPartitioned(s, "postgres", dsn, "shipments", "weight", reflect.TypeOf(shipment{}), tt.options...)
+
+			passert.Equals(s, got, tt.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestDatabaseIO_ReadAll(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	orders := createOrders(ctx, t, dsn, "orders", 30)
+
+	p, s := beam.NewPipelineWithRoot()
+
+	keys := beam.Create(s, orderKey{Customer: "customer0", MinID: 10}, orderKey{Customer: "customer2", MinID: 12})
+	got := databaseio.ReadAll(s, "postgres", dsn, "SELECT * FROM orders WHERE customer = $1 AND id >= $2", reflect.TypeOf(order{}), keys)
+
+	want := toAny(orders, func(o order) bool {
+		return o.Customer == "customer0" && o.ID >= 10 || o.Customer == "customer2" && o.ID >= 12
+	})
+	passert.Equals(s, got, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+// readOrders returns the orders of a table by ID.
+func readOrders(ctx context.Context, t *testing.T, dsn, table string) map[int64]order {
+	t.Helper()
+
+	db := openDB(t, dsn)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT id, customer, amount, created_at FROM %s", table))
+	if err != nil {
+		t.Fatalf("error reading %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	orders := make(map[int64]order)
+	for rows.Next() {
+		var o order
+		if err := rows.Scan(&o.ID, &o.Customer, &o.Amount, &o.CreatedAt); err != nil {
+			t.Fatalf("error scanning %s: %v", table, err)
+		}
+		orders[o.ID] = o
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("error reading %s: %v", table, err)
+	}
+	return orders
+}
+
+func TestDatabaseIO_Write_Upsert(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	db := openDB(t, dsn)
+	exec(ctx, t, db,
+		"CREATE TABLE orders (id BIGINT PRIMARY KEY, customer TEXT, amount DOUBLE PRECISION, created_at TIMESTAMP)",
+		"INSERT INTO orders VALUES (1, 'old', 1, '2024-01-01'), (2, 'old', 2, '2024-01-01')",
+	)
+
+	var input []any
+	want := make(map[int64]order)
+	for i := int64(1); i <= 4; i++ {
+		o := order{ID: i, Customer: "new", Amount: float64(i) * 10, CreatedAt: start}
+		input = append(input, o)
+		want[i] = o
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+
+	written, failed := databaseio.WriteWithOptions(s, "postgres", dsn, "orders", nil, beam.Create(s, input...),
+		databaseio.WriteUpsert("id"),
+		databaseio.WriteBatchSize(3),
+		databaseio.WriteRetries(2, 100*time.Millisecond),
+	)
+
+	passert.Equals(s, written, 4)
+	passert.Empty(s, failed)
+	ptest.RunAndValidate(t, p)
+
+	got := readOrders(ctx, t, dsn, "orders")
+	for id, o := range want {
+		if g := got[id]; g.Customer != o.Customer || g.Amount != o.Amount || !g.CreatedAt.Equal(o.CreatedAt) {
+			t.Errorf("order %d = %v, want %v", id, g, o)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d orders, want %d", len(got), len(want))
+	}
+}
+
+func TestDatabaseIO_Write_FailedRows(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	dsn := setUpTestContainer(ctx, t)
+	db := openDB(t, dsn)
+	exec(ctx, t, db,
+		"CREATE TABLE orders (id BIGINT PRIMARY KEY, customer TEXT, amount DOUBLE PRECISION CHECK (amount >= 0), created_at TIMESTAMP)",
+	)
+
+	valid := []any{
+		order{ID: 1, Customer: "a", Amount: 1, CreatedAt: start},
+		order{ID: 2, Customer: "b", Amount: 2, CreatedAt: start},
+	}
+	invalid := order{ID: 3, Customer: "c", Amount: -3, CreatedAt: start}
+
+	p, s := beam.NewPipelineWithRoot()
+
+	written, failed := databaseio.WriteWithOptions(s, "postgres", dsn, "orders", nil, beam.Create(s, append(valid, invalid)...))
+
+	passert.Equals(s, written, 2)
+	passert.Equals(s, beam.DropValue(s, failed), invalid)
+	ptest.RunAndValidate(t, p)
+
+	if got := readOrders(ctx, t, dsn, "orders"); len(got) != len(valid) {
+		t.Errorf("got %d orders, want %d", len(got), len(valid))
+	}
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	beam.Init()
+
+	ptest.MainRet(m)
+}
@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/test/integration/internal/containers"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	postgresImage    = "postgres"
+	postgresPort     = "5432/tcp"
+	postgresUser     = "user"
+	postgresPassword = "password"
+	postgresDatabase = "db"
+	maxRetries       = 5
+)
+
+// setUpTestContainer starts a Postgres container and returns the data source
+// name of its database.
+func setUpTestContainer(ctx context.Context, t *testing.T) string {
+	t.Helper()
+
+	env := map[string]string{
+		"POSTGRES_PASSWORD": postgresPassword,
+		"POSTGRES_USER":     postgresUser,
+		"POSTGRES_DB":       postgresDatabase,
+	}
+	dsn := func(host string, port nat.Port) string {
+		return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", postgresUser, postgresPassword, host, port.Port(), postgresDatabase)
+	}
+	waitStrategy := wait.ForSQL(postgresPort, "postgres", dsn).WithStartupTimeout(time.Second * 30)
+
+	container := containers.NewContainer(
+		ctx,
+		t,
+		postgresImage,
+		maxRetries,
+		containers.WithPorts([]string{postgresPort}),
+		containers.WithEnv(env),
+		containers.WithWaitStrategy(waitStrategy),
+	)
+
+	port := containers.Port(ctx, t, container, postgresPort)
+	return dsn("localhost", nat.Port(port))
+}
+
+func openDB(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("error closing database: %v", err)
+		}
+	})
+
+	return db
+}
+
+func exec(ctx context.Context, t *testing.T, db *sql.DB, stmts ...string) {
+	t.Helper()
+
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("error executing %q: %v", stmt, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/datastoreio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/dataflow"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/flink"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/samza"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/spark"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/test/integration"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	project = "test-project"
+	kind    = "Item"
+)
+
+var itemTypeKey string
+
+func init() {
+	itemTypeKey = runtime.RegisterType(reflect.TypeOf((*item)(nil)).Elem())
+	register.Function1x2(keyItem)
+}
+
+type item struct {
+	Name  string
+	Count int64
+}
+
+func keyItem(it item) (string, item) {
+	return datastore.NameKey(kind, it.Name, nil).Encode(), it
+}
+
+func newItems(n int) ([]*datastore.Key, []item) {
+	var keys []*datastore.Key
+	var items []item
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("item%02d", i)
+		keys = append(keys, datastore.NameKey(kind, name, nil))
+		items = append(items, item{Name: name, Count: int64(i)})
+	}
+	return keys, items
+}
+
+func itemNames(items []item) []string {
+	var names []string
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	return names
+}
+
+// setUpEmulator starts a Datastore emulator, which the clients created by the
+// test connect to, and returns a client.
+func setUpEmulator(ctx context.Context, t *testing.T) *datastore.Client {
+	t.Helper()
+
+	t.Setenv("DATASTORE_EMULATOR_HOST", setUpTestContainer(ctx, t))
+	return newClient(ctx, t, project)
+}
+
+func TestDatastoreIO_Write(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	client := setUpEmulator(ctx, t)
+	_, items := newItems(20)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyItem, beam.CreateList(s, items))
+	datastoreio.Write(s, project, col, datastoreio.WriteMaxBatchSize(7))
+	ptest.RunAndValidate(t, p)
+
+	got := readKeyNames(ctx, t, client, kind)
+	sort.Strings(got)
+	if want := itemNames(items); !cmp.Equal(got, want) {
+		t.Errorf("got entities %v, want %v", got, want)
+	}
+}
+
+func TestDatastoreIO_Read_Filter(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	client := setUpEmulator(ctx, t)
+	keys, items := newItems(10)
+	putEntities(ctx, t, client, keys, items)
+
+	p, s := beam.NewPipelineWithRoot()
+	// The inequality filter can't be combined with the key ranges of shards,
+	// so the read isn't sharded.
+	col := datastoreio.Read(s, project, kind, 4, reflect.TypeOf(item{}), itemTypeKey,
+		datastoreio.ReadFilter("Count", ">=", 5))
+	var want []any
+	for _, it := range items[5:] {
+		want = append(want, it)
+	}
+	passert.Equals(s, col, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestDatastoreIO_Delete(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	client := setUpEmulator(ctx, t)
+	keys, items := newItems(10)
+	putEntities(ctx, t, client, keys, items)
+
+	p, s := beam.NewPipelineWithRoot()
+	var encoded []string
+	for _, k := range keys[:3] {
+		encoded = append(encoded, k.Encode())
+	}
+	datastoreio.DeleteKeys(s, project, beam.CreateList(s, encoded))
+	datastoreio.DeleteEntities(s, project, beam.ParDo(s, keyItem, beam.CreateList(s, items[3:6])))
+	ptest.RunAndValidate(t, p)
+
+	got := readKeyNames(ctx, t, client, kind)
+	sort.Strings(got)
+	if want := itemNames(items[6:]); !cmp.Equal(got, want) {
+		t.Errorf("got entities %v after deletes, want %v", got, want)
+	}
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	beam.Init()
+
+	ptest.MainRet(m)
+}
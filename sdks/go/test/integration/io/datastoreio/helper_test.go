@@ -0,0 +1,97 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoreio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/apache/beam/sdks/v2/go/test/integration/internal/containers"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	emulatorImage = "gcr.io/google.com/cloudsdktool/google-cloud-cli:emulators"
+	emulatorPort  = "8081/tcp"
+	maxRetries    = 5
+)
+
+// setUpTestContainer starts a Datastore emulator container and returns its
+// host and port, to set as DATASTORE_EMULATOR_HOST.
+func setUpTestContainer(ctx context.Context, t *testing.T) string {
+	t.Helper()
+
+	cmd := []string{
+		"gcloud", "beta", "emulators", "datastore", "start",
+		"--host-port=0.0.0.0:8081", "--no-store-on-disk", "--consistency=1.0",
+	}
+	waitStrategy := wait.ForLog("Dev App Server is now running").WithStartupTimeout(time.Minute)
+
+	container := containers.NewContainer(
+		ctx,
+		t,
+		emulatorImage,
+		maxRetries,
+		containers.WithPorts([]string{emulatorPort}),
+		containers.WithCmd(cmd),
+		containers.WithWaitStrategy(waitStrategy),
+	)
+
+	return fmt.Sprintf("localhost:%s", containers.Port(ctx, t, container, emulatorPort))
+}
+
+func newClient(ctx context.Context, t *testing.T, project string) *datastore.Client {
+	t.Helper()
+
+	client, err := datastore.NewClient(ctx, project)
+	if err != nil {
+		t.Fatalf("error creating Datastore client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Fatalf("error closing Datastore client: %v", err)
+		}
+	})
+
+	return client
+}
+
+func putEntities(ctx context.Context, t *testing.T, client *datastore.Client, keys []*datastore.Key, entities any) {
+	t.Helper()
+
+	if _, err := client.PutMulti(ctx, keys, entities); err != nil {
+		t.Fatalf("error putting entities: %v", err)
+	}
+}
+
+func readKeyNames(ctx context.Context, t *testing.T, client *datastore.Client, kind string) []string {
+	t.Helper()
+
+	keys, err := client.GetAll(ctx, datastore.NewQuery(kind).KeysOnly(), nil)
+	if err != nil {
+		t.Fatalf("error reading keys: %v", err)
+	}
+
+	var names []string
+	for _, k := range keys {
+		names = append(names, k.Name)
+	}
+	return names
+}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+	"github.com/testcontainers/testcontainers-go/modules/kafka"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const kafkaImage = "confluentinc/confluent-local:7.5.0"
+
+// setUpTestContainer starts a Kafka broker container and returns its
+// bootstrap servers.
+func setUpTestContainer(ctx context.Context, t *testing.T) string {
+	t.Helper()
+
+	container, err := kafka.Run(ctx, kafkaImage)
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("error terminating container: %v", err)
+		}
+	})
+
+	brokers, err := container.Brokers(ctx)
+	if err != nil {
+		t.Fatalf("error getting brokers: %v", err)
+	}
+
+	return strings.Join(brokers, ",")
+}
+
+// createTopic creates a topic with a number of partitions.
+func createTopic(ctx context.Context, t *testing.T, servers, topic string, partitions int32) {
+	t.Helper()
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers(strings.Split(servers, ",")...))
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	defer cl.Close()
+
+	if _, err := kadm.NewClient(cl).CreateTopic(ctx, partitions, 1, nil, topic); err != nil {
+		t.Fatalf("error creating topic %v: %v", topic, err)
+	}
+}
+
+// dial connects to the broker with the default driver.
+func dial(ctx context.Context, t *testing.T, servers string) kafkaio.Client {
+	t.Helper()
+
+	client, err := kafkaio.Dial(ctx, kafkaio.DefaultDriver, servers, nil)
+	if err != nil {
+		t.Fatalf("error connecting to Kafka: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("error closing client: %v", err)
+		}
+	})
+
+	return client
+}
@@ -0,0 +1,176 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaio
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/kafkaio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/dataflow"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/flink"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/samza"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/spark"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/test/integration"
+)
+
+func init() {
+	register.Function1x1(recordValue)
+}
+
+func recordValue(r kafkaio.ConsumerRecord) string {
+	return string(r.Value)
+}
+
+// fetch fetches records of a partition, waiting for the consumer to connect.
+func fetch(ctx context.Context, t *testing.T, client kafkaio.Client, topic string, offset int64, max int) []kafkaio.ConsumerRecord {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		records, err := client.Fetch(ctx, topic, 0, offset, max)
+		if err != nil {
+			t.Fatalf("error fetching records from offset %v: %v", offset, err)
+		}
+		if len(records) > 0 {
+			return records
+		}
+	}
+	t.Fatalf("no records fetched from offset %v", offset)
+	return nil
+}
+
+func TestKafkaDriver_Client(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	servers := setUpTestContainer(ctx, t)
+	createTopic(ctx, t, servers, "topic", 1)
+	client := dial(ctx, t, servers)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var records []kafkaio.ProducerRecord
+	for i := 0; i < 4; i++ {
+		records = append(records, kafkaio.ProducerRecord{
+			Key:       []byte(fmt.Sprintf("k%d", i)),
+			Value:     []byte(fmt.Sprintf("v%d", i)),
+			Headers:   []kafkaio.Header{{Key: "h", Value: []byte(fmt.Sprintf("%d", i))}},
+			Timestamp: created.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	if err := client.Produce(ctx, "topic", records); err != nil {
+		t.Fatalf("error producing records: %v", err)
+	}
+
+	partitions, err := client.Partitions(ctx, "topic")
+	if err != nil || len(partitions) != 1 || partitions[0] != 0 {
+		t.Errorf("Partitions() = %v, %v, want [0]", partitions, err)
+	}
+	if start, end, err := client.Offsets(ctx, "topic", 0); err != nil || start != 0 || end != 4 {
+		t.Errorf("Offsets() = %v, %v, %v, want 0, 4", start, end, err)
+	}
+	if got, err := client.OffsetForTime(ctx, "topic", 0, created.Add(90*time.Second)); err != nil || got != 2 {
+		t.Errorf("OffsetForTime() = %v, %v, want 2", got, err)
+	}
+	if got, err := client.OffsetForTime(ctx, "topic", 0, created.Add(time.Hour)); err != nil || got != 4 {
+		t.Errorf("OffsetForTime() after last record = %v, %v, want 4", got, err)
+	}
+
+	got := fetch(ctx, t, client, "topic", 1, 2)
+	if len(got) != 2 || got[0].Offset != 1 || got[1].Offset != 2 {
+		t.Fatalf("Fetch() from offset 1 returned %v, want records 1 and 2", got)
+	}
+	r := got[1]
+	if string(r.Key) != "k2" || string(r.Value) != "v2" || len(r.Headers) != 1 || string(r.Headers[0].Value) != "2" ||
+		!r.Timestamp.Equal(records[2].Timestamp) || r.LogAppendTime {
+		t.Errorf("Fetch() returned record %+v, want %+v", r, records[2])
+	}
+	if got := fetch(ctx, t, client, "topic", 3, 10); got[0].Offset != 3 {
+		t.Errorf("Fetch() from offset 3 returned offset %v", got[0].Offset)
+	}
+	if got := fetch(ctx, t, client, "topic", 0, 10); got[0].Offset != 0 {
+		t.Errorf("Fetch() from offset 0 after reading offset 3 returned offset %v", got[0].Offset)
+	}
+
+	if got, err := client.CommittedOffset(ctx, "group", "topic", 0); err != nil || got != -1 {
+		t.Errorf("CommittedOffset() before commit = %v, %v, want -1", got, err)
+	}
+	if err := client.Commit(ctx, "group", "topic", 0, 3); err != nil {
+		t.Fatalf("error committing offset: %v", err)
+	}
+	if got, err := client.CommittedOffset(ctx, "group", "topic", 0); err != nil || got != 3 {
+		t.Errorf("CommittedOffset() = %v, %v, want 3", got, err)
+	}
+}
+
+func TestKafkaDriver_WriteRead(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	servers := setUpTestContainer(ctx, t)
+	createTopic(ctx, t, servers, "topic", 2)
+
+	var kvs []any
+	var want []any
+	for i := 0; i < 10; i++ {
+		value := fmt.Sprintf("v%d", i)
+		kvs = append(kvs, kafkaio.ProducerRecord{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte(value)})
+		want = append(want, value)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	kafkaio.Write(s, kafkaio.DefaultDriver, servers, "topic", beam.Create(s, kvs...))
+	ptest.RunAndValidate(t, p)
+
+	p, s = beam.NewPipelineWithRoot()
+	col := kafkaio.Read(s, kafkaio.DefaultDriver, servers, []string{"topic"},
+		kafkaio.ReadConsumerGroup("group"),
+		kafkaio.ReadCommitOffsetsInFinalize(),
+		kafkaio.ReadFromEarliest(),
+		kafkaio.ReadStopTime(time.Now()),
+	)
+	passert.Equals(s, beam.ParDo(s, recordValue, col), want...)
+	ptest.RunAndValidate(t, p)
+
+	client := dial(ctx, t, servers)
+	var committed int64
+	for p := int32(0); p < 2; p++ {
+		offset, err := client.CommittedOffset(ctx, "group", "topic", p)
+		if err != nil {
+			t.Fatalf("error getting committed offset of partition %v: %v", p, err)
+		}
+		if offset > 0 {
+			committed += offset
+		}
+	}
+	if committed != int64(len(want)) {
+		t.Errorf("committed offsets sum to %v, want %v", committed, len(want))
+	}
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	beam.Init()
+
+	ptest.MainRet(m)
+}
@@ -18,6 +18,7 @@ package mongodbio
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/test/integration/internal/containers"
 	"go.mongodb.org/mongo-driver/bson"
@@ -31,6 +32,7 @@ const (
 	mongoImage = "mongo:6.0.3"
 	mongoPort  = "27017/tcp"
 	maxRetries = 5
+	replicaSet = "rs0"
 )
 
 func setUpTestContainer(ctx context.Context, t *testing.T) string {
@@ -47,6 +49,51 @@ func setUpTestContainer(ctx context.Context, t *testing.T) string {
 	return containers.Port(ctx, t, container, mongoPort)
 }
 
+// setUpReplicaSetContainer starts a single-node replica set, which change streams require, and
+// returns its port. Clients must connect to it directly, as the replica set member is only known by
+// its hostname within the container.
+func setUpReplicaSetContainer(ctx context.Context, t *testing.T) string {
+	t.Helper()
+
+	container := containers.NewContainer(
+		ctx,
+		t,
+		mongoImage,
+		maxRetries,
+		containers.WithPorts([]string{mongoPort}),
+		containers.WithCmd([]string{"--replSet", replicaSet}),
+	)
+
+	return containers.Port(ctx, t, container, mongoPort)
+}
+
+func initiateReplicaSet(ctx context.Context, t *testing.T, client *mongo.Client) {
+	t.Helper()
+
+	admin := client.Database("admin")
+	if err := admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: bson.M{}}}).Err(); err != nil {
+		t.Fatalf("error initiating replica set: %v", err)
+	}
+
+	for i := 0; i < 30; i++ {
+		var result struct {
+			IsWritablePrimary bool `bson:"isWritablePrimary"`
+		}
+
+		if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&result); err != nil {
+			t.Fatalf("error checking replica set status: %v", err)
+		}
+
+		if result.IsWritablePrimary {
+			return
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	t.Fatalf("replica set member did not become primary")
+}
+
 func objectIDFromHex(t *testing.T, hex string) primitive.ObjectID {
 	t.Helper()
 
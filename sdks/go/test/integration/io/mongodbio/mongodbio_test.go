@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/mongodbio"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/dataflow"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/flink"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/runners/samza"
@@ -39,6 +41,7 @@ import (
 func init() {
 	beam.RegisterType(reflect.TypeOf((*docWithObjectID)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*docWithStringID)(nil)).Elem())
+	register.Function2x1(formatChange)
 }
 
 type docWithObjectID struct {
@@ -51,6 +54,10 @@ type docWithStringID struct {
 	Field1 int32  `bson:"field1"`
 }
 
+func formatChange(operationType string, doc docWithStringID) string {
+	return fmt.Sprintf("%s %s %d", operationType, doc.ID, doc.Field1)
+}
+
 func TestMongoDBIO_Read(t *testing.T) {
 	integration.CheckFilters(t)
 
@@ -229,6 +236,106 @@ func TestMongoDBIO_Write(t *testing.T) {
 	}
 }
 
+func TestMongoDBIO_ReadChangeStream(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	port := setUpReplicaSetContainer(ctx, t)
+	uri := fmt.Sprintf("mongodb://%s:%s/?directConnection=true", "localhost", port)
+
+	initiateReplicaSet(ctx, t, newClient(ctx, t, uri))
+
+	tests := []struct {
+		name    string
+		options []mongodbio.ChangeStreamOptionFn
+		want    []any
+	}{
+		{
+			name: "Read change events of all operation types",
+			want: []any{
+				"insert id01 0",
+				"insert id02 1",
+				"update id01 10",
+				"delete id02 0",
+			},
+		},
+		{
+			name: "Read change events of the provided operation types",
+			options: []mongodbio.ChangeStreamOptionFn{
+				mongodbio.WithChangeStreamOperationTypes("insert"),
+			},
+			want: []any{
+				"insert id01 0",
+				"insert id02 1",
+			},
+		},
+		{
+			name: "Read change events where filter matches",
+			options: []mongodbio.ChangeStreamOptionFn{
+				mongodbio.WithChangeStreamFilter(bson.M{"documentKey._id": "id02"}),
+			},
+			want: []any{
+				"insert id02 1",
+				"delete id02 0",
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := "db"
+			// Change events of the collections of previous tests may share cluster times with
+			// change events of this test.
+			collection := fmt.Sprintf("coll%d", i)
+
+			client := newClient(ctx, t, uri)
+			mongoCollection := client.Database(database).Collection(collection)
+
+			t.Cleanup(func() {
+				dropCollection(ctx, t, mongoCollection)
+			})
+
+			startTime := time.Now()
+
+			writeDocuments(ctx, t, mongoCollection, []any{
+				bson.M{"_id": "id01", "field1": int32(0)},
+				bson.M{"_id": "id02", "field1": int32(1)},
+			})
+			update := bson.M{"$set": bson.M{"field1": int32(10)}}
+			if _, err := mongoCollection.UpdateByID(ctx, "id01", update); err != nil {
+				t.Fatalf("error updating document: %v", err)
+			}
+			if _, err := mongoCollection.DeleteOne(ctx, bson.M{"_id": "id02"}); err != nil {
+				t.Fatalf("error deleting document: %v", err)
+			}
+
+			// Advance the cluster time past the end time with a write to another collection.
+			endTime := time.Now().Add(time.Second)
+			time.Sleep(time.Until(endTime) + time.Second)
+			writeDocuments(ctx, t, client.Database(database).Collection("other"), []any{bson.M{}})
+
+			opts := append([]mongodbio.ChangeStreamOptionFn{
+				mongodbio.WithChangeStreamStartTime(startTime),
+				mongodbio.WithChangeStreamEndTime(endTime),
+			}, tt.options...)
+
+			p, s := beam.NewPipelineWithRoot()
+
+			changes := mongodbio.ReadChangeStream(
+				s,
+				uri,
+				database,
+				collection,
+				reflect.TypeOf(docWithStringID{}),
+				opts...,
+			)
+			got := beam.ParDo(s, formatChange, changes)
+
+			passert.Equals(s, got, tt.want...)
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	beam.Init()